@@ -0,0 +1,1847 @@
+// Protocol buffer schema of the bngo inference service. Regenerate the Go
+// code with `buf generate` from the api directory.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: bngopb/bngo.proto
+
+package bngopb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type VariableType int32
+
+const (
+	VariableType_VARIABLE_TYPE_UNSPECIFIED VariableType = 0
+	VariableType_VARIABLE_TYPE_DISCRETE    VariableType = 1
+	VariableType_VARIABLE_TYPE_CONTINUOUS  VariableType = 2
+)
+
+// Enum value maps for VariableType.
+var (
+	VariableType_name = map[int32]string{
+		0: "VARIABLE_TYPE_UNSPECIFIED",
+		1: "VARIABLE_TYPE_DISCRETE",
+		2: "VARIABLE_TYPE_CONTINUOUS",
+	}
+	VariableType_value = map[string]int32{
+		"VARIABLE_TYPE_UNSPECIFIED": 0,
+		"VARIABLE_TYPE_DISCRETE":    1,
+		"VARIABLE_TYPE_CONTINUOUS":  2,
+	}
+)
+
+func (x VariableType) Enum() *VariableType {
+	p := new(VariableType)
+	*p = x
+	return p
+}
+
+func (x VariableType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (VariableType) Descriptor() protoreflect.EnumDescriptor {
+	return file_bngopb_bngo_proto_enumTypes[0].Descriptor()
+}
+
+func (VariableType) Type() protoreflect.EnumType {
+	return &file_bngopb_bngo_proto_enumTypes[0]
+}
+
+func (x VariableType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use VariableType.Descriptor instead.
+func (VariableType) EnumDescriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{0}
+}
+
+type Variable struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string       `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type VariableType `protobuf:"varint,2,opt,name=type,proto3,enum=bngo.v1.VariableType" json:"type,omitempty"`
+	// Number of states of a discrete variable
+	Cardinality int32 `protobuf:"varint,3,opt,name=cardinality,proto3" json:"cardinality,omitempty"`
+	// Optional state labels of a discrete variable, in state order
+	States []string `protobuf:"bytes,4,rep,name=states,proto3" json:"states,omitempty"`
+}
+
+func (x *Variable) Reset() {
+	*x = Variable{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Variable) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variable) ProtoMessage() {}
+
+func (x *Variable) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variable.ProtoReflect.Descriptor instead.
+func (*Variable) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Variable) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Variable) GetType() VariableType {
+	if x != nil {
+		return x.Type
+	}
+	return VariableType_VARIABLE_TYPE_UNSPECIFIED
+}
+
+func (x *Variable) GetCardinality() int32 {
+	if x != nil {
+		return x.Cardinality
+	}
+	return 0
+}
+
+func (x *Variable) GetStates() []string {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+type Edge struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Parent string `protobuf:"bytes,1,opt,name=parent,proto3" json:"parent,omitempty"`
+	Child  string `protobuf:"bytes,2,opt,name=child,proto3" json:"child,omitempty"`
+}
+
+func (x *Edge) Reset() {
+	*x = Edge{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Edge) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Edge) ProtoMessage() {}
+
+func (x *Edge) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Edge.ProtoReflect.Descriptor instead.
+func (*Edge) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Edge) GetParent() string {
+	if x != nil {
+		return x.Parent
+	}
+	return ""
+}
+
+func (x *Edge) GetChild() string {
+	if x != nil {
+		return x.Child
+	}
+	return ""
+}
+
+// TabularCPD is P(variable | evidence) as rows flattened into one list: one
+// row per evidence configuration, the last evidence variable varying
+// fastest, and the variable's states within each row
+type TabularCPD struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Variable string    `protobuf:"bytes,1,opt,name=variable,proto3" json:"variable,omitempty"`
+	Evidence []string  `protobuf:"bytes,2,rep,name=evidence,proto3" json:"evidence,omitempty"`
+	Values   []float64 `protobuf:"fixed64,3,rep,packed,name=values,proto3" json:"values,omitempty"`
+}
+
+func (x *TabularCPD) Reset() {
+	*x = TabularCPD{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TabularCPD) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TabularCPD) ProtoMessage() {}
+
+func (x *TabularCPD) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TabularCPD.ProtoReflect.Descriptor instead.
+func (*TabularCPD) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *TabularCPD) GetVariable() string {
+	if x != nil {
+		return x.Variable
+	}
+	return ""
+}
+
+func (x *TabularCPD) GetEvidence() []string {
+	if x != nil {
+		return x.Evidence
+	}
+	return nil
+}
+
+func (x *TabularCPD) GetValues() []float64 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type GaussianParams struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Mean         float64            `protobuf:"fixed64,1,opt,name=mean,proto3" json:"mean,omitempty"`
+	Variance     float64            `protobuf:"fixed64,2,opt,name=variance,proto3" json:"variance,omitempty"`
+	Coefficients map[string]float64 `protobuf:"bytes,3,rep,name=coefficients,proto3" json:"coefficients,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+}
+
+func (x *GaussianParams) Reset() {
+	*x = GaussianParams{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GaussianParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GaussianParams) ProtoMessage() {}
+
+func (x *GaussianParams) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GaussianParams.ProtoReflect.Descriptor instead.
+func (*GaussianParams) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GaussianParams) GetMean() float64 {
+	if x != nil {
+		return x.Mean
+	}
+	return 0
+}
+
+func (x *GaussianParams) GetVariance() float64 {
+	if x != nil {
+		return x.Variance
+	}
+	return 0
+}
+
+func (x *GaussianParams) GetCoefficients() map[string]float64 {
+	if x != nil {
+		return x.Coefficients
+	}
+	return nil
+}
+
+// GaussianCPD is a linear Gaussian CPD. With discrete parents, states holds
+// one parameter set per configuration, keyed by the comma-joined discrete
+// parent states in parent order; otherwise intercept, coefficients and
+// variance apply.
+type GaussianCPD struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Variable     string                     `protobuf:"bytes,1,opt,name=variable,proto3" json:"variable,omitempty"`
+	Parents      []string                   `protobuf:"bytes,2,rep,name=parents,proto3" json:"parents,omitempty"`
+	Intercept    float64                    `protobuf:"fixed64,3,opt,name=intercept,proto3" json:"intercept,omitempty"`
+	Coefficients map[string]float64         `protobuf:"bytes,4,rep,name=coefficients,proto3" json:"coefficients,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+	Variance     float64                    `protobuf:"fixed64,5,opt,name=variance,proto3" json:"variance,omitempty"`
+	States       map[string]*GaussianParams `protobuf:"bytes,6,rep,name=states,proto3" json:"states,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *GaussianCPD) Reset() {
+	*x = GaussianCPD{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GaussianCPD) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GaussianCPD) ProtoMessage() {}
+
+func (x *GaussianCPD) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GaussianCPD.ProtoReflect.Descriptor instead.
+func (*GaussianCPD) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GaussianCPD) GetVariable() string {
+	if x != nil {
+		return x.Variable
+	}
+	return ""
+}
+
+func (x *GaussianCPD) GetParents() []string {
+	if x != nil {
+		return x.Parents
+	}
+	return nil
+}
+
+func (x *GaussianCPD) GetIntercept() float64 {
+	if x != nil {
+		return x.Intercept
+	}
+	return 0
+}
+
+func (x *GaussianCPD) GetCoefficients() map[string]float64 {
+	if x != nil {
+		return x.Coefficients
+	}
+	return nil
+}
+
+func (x *GaussianCPD) GetVariance() float64 {
+	if x != nil {
+		return x.Variance
+	}
+	return 0
+}
+
+func (x *GaussianCPD) GetStates() map[string]*GaussianParams {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+type Model struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Variables    []*Variable    `protobuf:"bytes,1,rep,name=variables,proto3" json:"variables,omitempty"`
+	Edges        []*Edge        `protobuf:"bytes,2,rep,name=edges,proto3" json:"edges,omitempty"`
+	Cpds         []*TabularCPD  `protobuf:"bytes,3,rep,name=cpds,proto3" json:"cpds,omitempty"`
+	GaussianCpds []*GaussianCPD `protobuf:"bytes,4,rep,name=gaussian_cpds,json=gaussianCpds,proto3" json:"gaussian_cpds,omitempty"`
+}
+
+func (x *Model) Reset() {
+	*x = Model{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Model) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Model) ProtoMessage() {}
+
+func (x *Model) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Model.ProtoReflect.Descriptor instead.
+func (*Model) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Model) GetVariables() []*Variable {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+func (x *Model) GetEdges() []*Edge {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+func (x *Model) GetCpds() []*TabularCPD {
+	if x != nil {
+		return x.Cpds
+	}
+	return nil
+}
+
+func (x *Model) GetGaussianCpds() []*GaussianCPD {
+	if x != nil {
+		return x.GaussianCpds
+	}
+	return nil
+}
+
+// Evidence observes discrete variables by state index or label, and
+// continuous variables by value
+type Evidence struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Discrete   map[string]int32   `protobuf:"bytes,1,rep,name=discrete,proto3" json:"discrete,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Labels     map[string]string  `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Continuous map[string]float64 `protobuf:"bytes,3,rep,name=continuous,proto3" json:"continuous,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+}
+
+func (x *Evidence) Reset() {
+	*x = Evidence{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Evidence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Evidence) ProtoMessage() {}
+
+func (x *Evidence) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Evidence.ProtoReflect.Descriptor instead.
+func (*Evidence) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Evidence) GetDiscrete() map[string]int32 {
+	if x != nil {
+		return x.Discrete
+	}
+	return nil
+}
+
+func (x *Evidence) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Evidence) GetContinuous() map[string]float64 {
+	if x != nil {
+		return x.Continuous
+	}
+	return nil
+}
+
+type LoadModelRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Model *Model `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+}
+
+func (x *LoadModelRequest) Reset() {
+	*x = LoadModelRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LoadModelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoadModelRequest) ProtoMessage() {}
+
+func (x *LoadModelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoadModelRequest.ProtoReflect.Descriptor instead.
+func (*LoadModelRequest) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *LoadModelRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *LoadModelRequest) GetModel() *Model {
+	if x != nil {
+		return x.Model
+	}
+	return nil
+}
+
+type LoadModelResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version int64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (x *LoadModelResponse) Reset() {
+	*x = LoadModelResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LoadModelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoadModelResponse) ProtoMessage() {}
+
+func (x *LoadModelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoadModelResponse.ProtoReflect.Descriptor instead.
+func (*LoadModelResponse) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *LoadModelResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// GetModelRequest names a network; version 0 selects the current version
+type GetModelRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version int64  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (x *GetModelRequest) Reset() {
+	*x = GetModelRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetModelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetModelRequest) ProtoMessage() {}
+
+func (x *GetModelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetModelRequest.ProtoReflect.Descriptor instead.
+func (*GetModelRequest) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetModelRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetModelRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type ListModelsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListModelsRequest) Reset() {
+	*x = ListModelsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListModelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsRequest) ProtoMessage() {}
+
+func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsRequest.ProtoReflect.Descriptor instead.
+func (*ListModelsRequest) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{10}
+}
+
+// ModelInfo describes one stored version of a network
+type ModelInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version int64  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	// Whether this version serves queries that do not ask for a version
+	Current bool `protobuf:"varint,3,opt,name=current,proto3" json:"current,omitempty"`
+	// File the version was loaded from, empty when loaded over RPC or in code
+	Source              string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	LoadedAt            *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=loaded_at,json=loadedAt,proto3" json:"loaded_at,omitempty"`
+	DiscreteVariables   int32                  `protobuf:"varint,6,opt,name=discrete_variables,json=discreteVariables,proto3" json:"discrete_variables,omitempty"`
+	ContinuousVariables int32                  `protobuf:"varint,7,opt,name=continuous_variables,json=continuousVariables,proto3" json:"continuous_variables,omitempty"`
+	Edges               int32                  `protobuf:"varint,8,opt,name=edges,proto3" json:"edges,omitempty"`
+}
+
+func (x *ModelInfo) Reset() {
+	*x = ModelInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ModelInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModelInfo) ProtoMessage() {}
+
+func (x *ModelInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModelInfo.ProtoReflect.Descriptor instead.
+func (*ModelInfo) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ModelInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ModelInfo) GetCurrent() bool {
+	if x != nil {
+		return x.Current
+	}
+	return false
+}
+
+func (x *ModelInfo) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetLoadedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LoadedAt
+	}
+	return nil
+}
+
+func (x *ModelInfo) GetDiscreteVariables() int32 {
+	if x != nil {
+		return x.DiscreteVariables
+	}
+	return 0
+}
+
+func (x *ModelInfo) GetContinuousVariables() int32 {
+	if x != nil {
+		return x.ContinuousVariables
+	}
+	return 0
+}
+
+func (x *ModelInfo) GetEdges() int32 {
+	if x != nil {
+		return x.Edges
+	}
+	return 0
+}
+
+type ListModelsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Models []*ModelInfo `protobuf:"bytes,1,rep,name=models,proto3" json:"models,omitempty"`
+}
+
+func (x *ListModelsResponse) Reset() {
+	*x = ListModelsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListModelsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsResponse) ProtoMessage() {}
+
+func (x *ListModelsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsResponse.ProtoReflect.Descriptor instead.
+func (*ListModelsResponse) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListModelsResponse) GetModels() []*ModelInfo {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+// QueryRequest names a network; version 0 selects the current version
+type QueryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Model     string    `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	Variables []string  `protobuf:"bytes,2,rep,name=variables,proto3" json:"variables,omitempty"`
+	Evidence  *Evidence `protobuf:"bytes,3,opt,name=evidence,proto3" json:"evidence,omitempty"`
+	Version   int64     `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	// Whether to report the cost of the query in the response
+	Stats bool `protobuf:"varint,5,opt,name=stats,proto3" json:"stats,omitempty"`
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *QueryRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetVariables() []string {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetEvidence() *Evidence {
+	if x != nil {
+		return x.Evidence
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetStats() bool {
+	if x != nil {
+		return x.Stats
+	}
+	return false
+}
+
+// DiscreteDistribution is a joint table over variables, the last variable
+// varying fastest
+type DiscreteDistribution struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Variables   []string  `protobuf:"bytes,1,rep,name=variables,proto3" json:"variables,omitempty"`
+	Cardinality []int32   `protobuf:"varint,2,rep,packed,name=cardinality,proto3" json:"cardinality,omitempty"`
+	Values      []float64 `protobuf:"fixed64,3,rep,packed,name=values,proto3" json:"values,omitempty"`
+}
+
+func (x *DiscreteDistribution) Reset() {
+	*x = DiscreteDistribution{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DiscreteDistribution) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiscreteDistribution) ProtoMessage() {}
+
+func (x *DiscreteDistribution) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiscreteDistribution.ProtoReflect.Descriptor instead.
+func (*DiscreteDistribution) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DiscreteDistribution) GetVariables() []string {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+func (x *DiscreteDistribution) GetCardinality() []int32 {
+	if x != nil {
+		return x.Cardinality
+	}
+	return nil
+}
+
+func (x *DiscreteDistribution) GetValues() []float64 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+// MixtureComponent is the Gaussian posterior of the continuous query
+// variables for one assignment of the discrete ones
+type MixtureComponent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Discrete  map[string]int32 `protobuf:"bytes,1,rep,name=discrete,proto3" json:"discrete,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Weight    float64          `protobuf:"fixed64,2,opt,name=weight,proto3" json:"weight,omitempty"`
+	Variables []string         `protobuf:"bytes,3,rep,name=variables,proto3" json:"variables,omitempty"`
+	Mean      []float64        `protobuf:"fixed64,4,rep,packed,name=mean,proto3" json:"mean,omitempty"`
+	// Row-major covariance matrix over variables
+	Covariance []float64 `protobuf:"fixed64,5,rep,packed,name=covariance,proto3" json:"covariance,omitempty"`
+}
+
+func (x *MixtureComponent) Reset() {
+	*x = MixtureComponent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MixtureComponent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MixtureComponent) ProtoMessage() {}
+
+func (x *MixtureComponent) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MixtureComponent.ProtoReflect.Descriptor instead.
+func (*MixtureComponent) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *MixtureComponent) GetDiscrete() map[string]int32 {
+	if x != nil {
+		return x.Discrete
+	}
+	return nil
+}
+
+func (x *MixtureComponent) GetWeight() float64 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *MixtureComponent) GetVariables() []string {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+func (x *MixtureComponent) GetMean() []float64 {
+	if x != nil {
+		return x.Mean
+	}
+	return nil
+}
+
+func (x *MixtureComponent) GetCovariance() []float64 {
+	if x != nil {
+		return x.Covariance
+	}
+	return nil
+}
+
+// QueryStats reports the work done by one query
+type QueryStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Factor products computed
+	Multiplications int64 `protobuf:"varint,1,opt,name=multiplications,proto3" json:"multiplications,omitempty"`
+	// Entries in the largest intermediate factor
+	MaxFactorSize int64 `protobuf:"varint,2,opt,name=max_factor_size,json=maxFactorSize,proto3" json:"max_factor_size,omitempty"`
+	// Variables summed or integrated out, in order
+	EliminationOrder []string `protobuf:"bytes,3,rep,name=elimination_order,json=eliminationOrder,proto3" json:"elimination_order,omitempty"`
+	// Discrete configurations enumerated by a mixed query
+	Configurations int64                `protobuf:"varint,4,opt,name=configurations,proto3" json:"configurations,omitempty"`
+	WallTime       *durationpb.Duration `protobuf:"bytes,5,opt,name=wall_time,json=wallTime,proto3" json:"wall_time,omitempty"`
+}
+
+func (x *QueryStats) Reset() {
+	*x = QueryStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QueryStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryStats) ProtoMessage() {}
+
+func (x *QueryStats) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryStats.ProtoReflect.Descriptor instead.
+func (*QueryStats) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *QueryStats) GetMultiplications() int64 {
+	if x != nil {
+		return x.Multiplications
+	}
+	return 0
+}
+
+func (x *QueryStats) GetMaxFactorSize() int64 {
+	if x != nil {
+		return x.MaxFactorSize
+	}
+	return 0
+}
+
+func (x *QueryStats) GetEliminationOrder() []string {
+	if x != nil {
+		return x.EliminationOrder
+	}
+	return nil
+}
+
+func (x *QueryStats) GetConfigurations() int64 {
+	if x != nil {
+		return x.Configurations
+	}
+	return 0
+}
+
+func (x *QueryStats) GetWallTime() *durationpb.Duration {
+	if x != nil {
+		return x.WallTime
+	}
+	return nil
+}
+
+// QueryResponse holds a distribution for purely discrete queries and
+// mixture components when a continuous variable is queried or observed
+type QueryResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Distribution *DiscreteDistribution `protobuf:"bytes,1,opt,name=distribution,proto3" json:"distribution,omitempty"`
+	Components   []*MixtureComponent   `protobuf:"bytes,2,rep,name=components,proto3" json:"components,omitempty"`
+	// Version of the network that answered the query
+	Version int64 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	// Cost of the query, when requested
+	Stats *QueryStats `protobuf:"bytes,4,opt,name=stats,proto3" json:"stats,omitempty"`
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bngopb_bngo_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bngopb_bngo_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_bngopb_bngo_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *QueryResponse) GetDistribution() *DiscreteDistribution {
+	if x != nil {
+		return x.Distribution
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetComponents() []*MixtureComponent {
+	if x != nil {
+		return x.Components
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *QueryResponse) GetStats() *QueryStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+var File_bngopb_bngo_proto protoreflect.FileDescriptor
+
+var file_bngopb_bngo_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x62, 0x6e, 0x67, 0x6f, 0x70, 0x62, 0x2f, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x07, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x1a, 0x1e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x75,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x83, 0x01,
+	0x0a, 0x08, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x29,
+	0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x62,
+	0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x54,
+	0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x61, 0x72,
+	0x64, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b,
+	0x63, 0x61, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x73,
+	0x74, 0x61, 0x74, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x73, 0x22, 0x34, 0x0a, 0x04, 0x45, 0x64, 0x67, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x70,
+	0x61, 0x72, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x61, 0x72,
+	0x65, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x22, 0x5c, 0x0a, 0x0a, 0x54, 0x61, 0x62,
+	0x75, 0x6c, 0x61, 0x72, 0x43, 0x50, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61,
+	0x62, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61,
+	0x62, 0x6c, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x65, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x65, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x01, 0x52,
+	0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x22, 0xd0, 0x01, 0x0a, 0x0e, 0x47, 0x61, 0x75, 0x73,
+	0x73, 0x69, 0x61, 0x6e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x65,
+	0x61, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x6d, 0x65, 0x61, 0x6e, 0x12, 0x1a,
+	0x0a, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x4d, 0x0a, 0x0c, 0x63, 0x6f,
+	0x65, 0x66, 0x66, 0x69, 0x63, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x29, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x61, 0x75, 0x73, 0x73,
+	0x69, 0x61, 0x6e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x2e, 0x43, 0x6f, 0x65, 0x66, 0x66, 0x69,
+	0x63, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0c, 0x63, 0x6f, 0x65,
+	0x66, 0x66, 0x69, 0x63, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x1a, 0x3f, 0x0a, 0x11, 0x43, 0x6f, 0x65,
+	0x66, 0x66, 0x69, 0x63, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x98, 0x03, 0x0a, 0x0b, 0x47,
+	0x61, 0x75, 0x73, 0x73, 0x69, 0x61, 0x6e, 0x43, 0x50, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x76, 0x61,
+	0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x76, 0x61,
+	0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x73,
+	0x12, 0x1c, 0x0a, 0x09, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x09, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x12, 0x4a,
+	0x0a, 0x0c, 0x63, 0x6f, 0x65, 0x66, 0x66, 0x69, 0x63, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x04,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x47,
+	0x61, 0x75, 0x73, 0x73, 0x69, 0x61, 0x6e, 0x43, 0x50, 0x44, 0x2e, 0x43, 0x6f, 0x65, 0x66, 0x66,
+	0x69, 0x63, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0c, 0x63, 0x6f,
+	0x65, 0x66, 0x66, 0x69, 0x63, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x76, 0x61,
+	0x72, 0x69, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x76, 0x61,
+	0x72, 0x69, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x38, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73,
+	0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31,
+	0x2e, 0x47, 0x61, 0x75, 0x73, 0x73, 0x69, 0x61, 0x6e, 0x43, 0x50, 0x44, 0x2e, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73,
+	0x1a, 0x3f, 0x0a, 0x11, 0x43, 0x6f, 0x65, 0x66, 0x66, 0x69, 0x63, 0x69, 0x65, 0x6e, 0x74, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x1a, 0x52, 0x0a, 0x0b, 0x53, 0x74, 0x61, 0x74, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x2d, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x17, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x61, 0x75, 0x73,
+	0x73, 0x69, 0x61, 0x6e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xc1, 0x01, 0x0a, 0x05, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12,
+	0x2f, 0x0a, 0x09, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x11, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x72,
+	0x69, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x09, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73,
+	0x12, 0x23, 0x0a, 0x05, 0x65, 0x64, 0x67, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x0d, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x64, 0x67, 0x65, 0x52, 0x05,
+	0x65, 0x64, 0x67, 0x65, 0x73, 0x12, 0x27, 0x0a, 0x04, 0x63, 0x70, 0x64, 0x73, 0x18, 0x03, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61,
+	0x62, 0x75, 0x6c, 0x61, 0x72, 0x43, 0x50, 0x44, 0x52, 0x04, 0x63, 0x70, 0x64, 0x73, 0x12, 0x39,
+	0x0a, 0x0d, 0x67, 0x61, 0x75, 0x73, 0x73, 0x69, 0x61, 0x6e, 0x5f, 0x63, 0x70, 0x64, 0x73, 0x18,
+	0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e,
+	0x47, 0x61, 0x75, 0x73, 0x73, 0x69, 0x61, 0x6e, 0x43, 0x50, 0x44, 0x52, 0x0c, 0x67, 0x61, 0x75,
+	0x73, 0x73, 0x69, 0x61, 0x6e, 0x43, 0x70, 0x64, 0x73, 0x22, 0xf8, 0x02, 0x0a, 0x08, 0x45, 0x76,
+	0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x3b, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x63, 0x72, 0x65,
+	0x74, 0x65, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e,
+	0x76, 0x31, 0x2e, 0x45, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x2e, 0x44, 0x69, 0x73, 0x63,
+	0x72, 0x65, 0x74, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x64, 0x69, 0x73, 0x63, 0x72,
+	0x65, 0x74, 0x65, 0x12, 0x35, 0x0a, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x76,
+	0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x52, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x12, 0x41, 0x0a, 0x0a, 0x63, 0x6f,
+	0x6e, 0x74, 0x69, 0x6e, 0x75, 0x6f, 0x75, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21,
+	0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63,
+	0x65, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x69, 0x6e, 0x75, 0x6f, 0x75, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x74, 0x69, 0x6e, 0x75, 0x6f, 0x75, 0x73, 0x1a, 0x3b, 0x0a,
+	0x0d, 0x44, 0x69, 0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x39, 0x0a, 0x0b, 0x4c, 0x61,
+	0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3d, 0x0a, 0x0f, 0x43, 0x6f, 0x6e, 0x74, 0x69, 0x6e, 0x75,
+	0x6f, 0x75, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x22, 0x4c, 0x0a, 0x10, 0x4c, 0x6f, 0x61, 0x64, 0x4d, 0x6f, 0x64, 0x65,
+	0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x24, 0x0a, 0x05,
+	0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x62, 0x6e,
+	0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x05, 0x6d, 0x6f, 0x64,
+	0x65, 0x6c, 0x22, 0x2d, 0x0a, 0x11, 0x4c, 0x6f, 0x61, 0x64, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x22, 0x3f, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x22, 0x13, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x9c, 0x02, 0x0a, 0x09, 0x4d, 0x6f, 0x64, 0x65,
+	0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x37, 0x0a, 0x09, 0x6c, 0x6f, 0x61, 0x64, 0x65, 0x64, 0x5f,
+	0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x52, 0x08, 0x6c, 0x6f, 0x61, 0x64, 0x65, 0x64, 0x41, 0x74, 0x12, 0x2d,
+	0x0a, 0x12, 0x64, 0x69, 0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x5f, 0x76, 0x61, 0x72, 0x69, 0x61,
+	0x62, 0x6c, 0x65, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x11, 0x64, 0x69, 0x73, 0x63,
+	0x72, 0x65, 0x74, 0x65, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x12, 0x31, 0x0a,
+	0x14, 0x63, 0x6f, 0x6e, 0x74, 0x69, 0x6e, 0x75, 0x6f, 0x75, 0x73, 0x5f, 0x76, 0x61, 0x72, 0x69,
+	0x61, 0x62, 0x6c, 0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x13, 0x63, 0x6f, 0x6e,
+	0x74, 0x69, 0x6e, 0x75, 0x6f, 0x75, 0x73, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73,
+	0x12, 0x14, 0x0a, 0x05, 0x65, 0x64, 0x67, 0x65, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x05, 0x65, 0x64, 0x67, 0x65, 0x73, 0x22, 0x40, 0x0a, 0x12, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x6f,
+	0x64, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2a, 0x0a, 0x06,
+	0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x62,
+	0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x06, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x22, 0xa1, 0x01, 0x0a, 0x0c, 0x51, 0x75, 0x65,
+	0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x6f, 0x64,
+	0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x12,
+	0x1c, 0x0a, 0x09, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x09, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x12, 0x2d, 0x0a,
+	0x08, 0x65, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x11, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x76, 0x69, 0x64, 0x65, 0x6e,
+	0x63, 0x65, 0x52, 0x08, 0x65, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x73, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x73, 0x22, 0x6e, 0x0a, 0x14,
+	0x44, 0x69, 0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x44, 0x69, 0x73, 0x74, 0x72, 0x69, 0x62, 0x75,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c,
+	0x65, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x61, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74,
+	0x79, 0x18, 0x02, 0x20, 0x03, 0x28, 0x05, 0x52, 0x0b, 0x63, 0x61, 0x72, 0x64, 0x69, 0x6e, 0x61,
+	0x6c, 0x69, 0x74, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x03,
+	0x20, 0x03, 0x28, 0x01, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x22, 0xfe, 0x01, 0x0a,
+	0x10, 0x4d, 0x69, 0x78, 0x74, 0x75, 0x72, 0x65, 0x43, 0x6f, 0x6d, 0x70, 0x6f, 0x6e, 0x65, 0x6e,
+	0x74, 0x12, 0x43, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x69,
+	0x78, 0x74, 0x75, 0x72, 0x65, 0x43, 0x6f, 0x6d, 0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x2e, 0x44,
+	0x69, 0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x64, 0x69,
+	0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1c,
+	0x0a, 0x09, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x09, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x12, 0x12, 0x0a, 0x04,
+	0x6d, 0x65, 0x61, 0x6e, 0x18, 0x04, 0x20, 0x03, 0x28, 0x01, 0x52, 0x04, 0x6d, 0x65, 0x61, 0x6e,
+	0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x05,
+	0x20, 0x03, 0x28, 0x01, 0x52, 0x0a, 0x63, 0x6f, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x63, 0x65,
+	0x1a, 0x3b, 0x0a, 0x0d, 0x44, 0x69, 0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xeb, 0x01,
+	0x0a, 0x0a, 0x51, 0x75, 0x65, 0x72, 0x79, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x28, 0x0a, 0x0f,
+	0x6d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0f, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c, 0x69, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x26, 0x0a, 0x0f, 0x6d, 0x61, 0x78, 0x5f, 0x66, 0x61,
+	0x63, 0x74, 0x6f, 0x72, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0d, 0x6d, 0x61, 0x78, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x2b,
+	0x0a, 0x11, 0x65, 0x6c, 0x69, 0x6d, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6f, 0x72,
+	0x64, 0x65, 0x72, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x10, 0x65, 0x6c, 0x69, 0x6d, 0x69,
+	0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x12, 0x26, 0x0a, 0x0e, 0x63,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0e, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x12, 0x36, 0x0a, 0x09, 0x77, 0x61, 0x6c, 0x6c, 0x5f, 0x74, 0x69, 0x6d, 0x65,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x52, 0x08, 0x77, 0x61, 0x6c, 0x6c, 0x54, 0x69, 0x6d, 0x65, 0x22, 0xd2, 0x01, 0x0a, 0x0d,
+	0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x41, 0x0a,
+	0x0c, 0x64, 0x69, 0x73, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x69,
+	0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x44, 0x69, 0x73, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x0c, 0x64, 0x69, 0x73, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x39, 0x0a, 0x0a, 0x63, 0x6f, 0x6d, 0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x4d,
+	0x69, 0x78, 0x74, 0x75, 0x72, 0x65, 0x43, 0x6f, 0x6d, 0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x52,
+	0x0a, 0x63, 0x6f, 0x6d, 0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x29, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x73, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x51,
+	0x75, 0x65, 0x72, 0x79, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x73,
+	0x2a, 0x67, 0x0a, 0x0c, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x54, 0x79, 0x70, 0x65,
+	0x12, 0x1d, 0x0a, 0x19, 0x56, 0x41, 0x52, 0x49, 0x41, 0x42, 0x4c, 0x45, 0x5f, 0x54, 0x59, 0x50,
+	0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12,
+	0x1a, 0x0a, 0x16, 0x56, 0x41, 0x52, 0x49, 0x41, 0x42, 0x4c, 0x45, 0x5f, 0x54, 0x59, 0x50, 0x45,
+	0x5f, 0x44, 0x49, 0x53, 0x43, 0x52, 0x45, 0x54, 0x45, 0x10, 0x01, 0x12, 0x1c, 0x0a, 0x18, 0x56,
+	0x41, 0x52, 0x49, 0x41, 0x42, 0x4c, 0x45, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x43, 0x4f, 0x4e,
+	0x54, 0x49, 0x4e, 0x55, 0x4f, 0x55, 0x53, 0x10, 0x02, 0x32, 0x8b, 0x02, 0x0a, 0x10, 0x49, 0x6e,
+	0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x42,
+	0x0a, 0x09, 0x4c, 0x6f, 0x61, 0x64, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12, 0x19, 0x2e, 0x62, 0x6e,
+	0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x61, 0x64, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31,
+	0x2e, 0x4c, 0x6f, 0x61, 0x64, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x34, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12, 0x18,
+	0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x4d, 0x6f, 0x64, 0x65,
+	0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e,
+	0x76, 0x31, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12, 0x45, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74,
+	0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x12, 0x1a, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31,
+	0x2e, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73,
+	0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x36, 0x0a, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x15, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e,
+	0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x16, 0x2e, 0x62, 0x6e, 0x67, 0x6f, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x28, 0x5a, 0x26, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x4a, 0x6f, 0x68, 0x6e, 0x50, 0x69, 0x65, 0x72, 0x6d, 0x61,
+	0x6e, 0x2f, 0x62, 0x6e, 0x67, 0x6f, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x62, 0x6e, 0x67, 0x6f, 0x70,
+	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_bngopb_bngo_proto_rawDescOnce sync.Once
+	file_bngopb_bngo_proto_rawDescData = file_bngopb_bngo_proto_rawDesc
+)
+
+func file_bngopb_bngo_proto_rawDescGZIP() []byte {
+	file_bngopb_bngo_proto_rawDescOnce.Do(func() {
+		file_bngopb_bngo_proto_rawDescData = protoimpl.X.CompressGZIP(file_bngopb_bngo_proto_rawDescData)
+	})
+	return file_bngopb_bngo_proto_rawDescData
+}
+
+var file_bngopb_bngo_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_bngopb_bngo_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_bngopb_bngo_proto_goTypes = []any{
+	(VariableType)(0),             // 0: bngo.v1.VariableType
+	(*Variable)(nil),              // 1: bngo.v1.Variable
+	(*Edge)(nil),                  // 2: bngo.v1.Edge
+	(*TabularCPD)(nil),            // 3: bngo.v1.TabularCPD
+	(*GaussianParams)(nil),        // 4: bngo.v1.GaussianParams
+	(*GaussianCPD)(nil),           // 5: bngo.v1.GaussianCPD
+	(*Model)(nil),                 // 6: bngo.v1.Model
+	(*Evidence)(nil),              // 7: bngo.v1.Evidence
+	(*LoadModelRequest)(nil),      // 8: bngo.v1.LoadModelRequest
+	(*LoadModelResponse)(nil),     // 9: bngo.v1.LoadModelResponse
+	(*GetModelRequest)(nil),       // 10: bngo.v1.GetModelRequest
+	(*ListModelsRequest)(nil),     // 11: bngo.v1.ListModelsRequest
+	(*ModelInfo)(nil),             // 12: bngo.v1.ModelInfo
+	(*ListModelsResponse)(nil),    // 13: bngo.v1.ListModelsResponse
+	(*QueryRequest)(nil),          // 14: bngo.v1.QueryRequest
+	(*DiscreteDistribution)(nil),  // 15: bngo.v1.DiscreteDistribution
+	(*MixtureComponent)(nil),      // 16: bngo.v1.MixtureComponent
+	(*QueryStats)(nil),            // 17: bngo.v1.QueryStats
+	(*QueryResponse)(nil),         // 18: bngo.v1.QueryResponse
+	nil,                           // 19: bngo.v1.GaussianParams.CoefficientsEntry
+	nil,                           // 20: bngo.v1.GaussianCPD.CoefficientsEntry
+	nil,                           // 21: bngo.v1.GaussianCPD.StatesEntry
+	nil,                           // 22: bngo.v1.Evidence.DiscreteEntry
+	nil,                           // 23: bngo.v1.Evidence.LabelsEntry
+	nil,                           // 24: bngo.v1.Evidence.ContinuousEntry
+	nil,                           // 25: bngo.v1.MixtureComponent.DiscreteEntry
+	(*timestamppb.Timestamp)(nil), // 26: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 27: google.protobuf.Duration
+}
+var file_bngopb_bngo_proto_depIdxs = []int32{
+	0,  // 0: bngo.v1.Variable.type:type_name -> bngo.v1.VariableType
+	19, // 1: bngo.v1.GaussianParams.coefficients:type_name -> bngo.v1.GaussianParams.CoefficientsEntry
+	20, // 2: bngo.v1.GaussianCPD.coefficients:type_name -> bngo.v1.GaussianCPD.CoefficientsEntry
+	21, // 3: bngo.v1.GaussianCPD.states:type_name -> bngo.v1.GaussianCPD.StatesEntry
+	1,  // 4: bngo.v1.Model.variables:type_name -> bngo.v1.Variable
+	2,  // 5: bngo.v1.Model.edges:type_name -> bngo.v1.Edge
+	3,  // 6: bngo.v1.Model.cpds:type_name -> bngo.v1.TabularCPD
+	5,  // 7: bngo.v1.Model.gaussian_cpds:type_name -> bngo.v1.GaussianCPD
+	22, // 8: bngo.v1.Evidence.discrete:type_name -> bngo.v1.Evidence.DiscreteEntry
+	23, // 9: bngo.v1.Evidence.labels:type_name -> bngo.v1.Evidence.LabelsEntry
+	24, // 10: bngo.v1.Evidence.continuous:type_name -> bngo.v1.Evidence.ContinuousEntry
+	6,  // 11: bngo.v1.LoadModelRequest.model:type_name -> bngo.v1.Model
+	26, // 12: bngo.v1.ModelInfo.loaded_at:type_name -> google.protobuf.Timestamp
+	12, // 13: bngo.v1.ListModelsResponse.models:type_name -> bngo.v1.ModelInfo
+	7,  // 14: bngo.v1.QueryRequest.evidence:type_name -> bngo.v1.Evidence
+	25, // 15: bngo.v1.MixtureComponent.discrete:type_name -> bngo.v1.MixtureComponent.DiscreteEntry
+	27, // 16: bngo.v1.QueryStats.wall_time:type_name -> google.protobuf.Duration
+	15, // 17: bngo.v1.QueryResponse.distribution:type_name -> bngo.v1.DiscreteDistribution
+	16, // 18: bngo.v1.QueryResponse.components:type_name -> bngo.v1.MixtureComponent
+	17, // 19: bngo.v1.QueryResponse.stats:type_name -> bngo.v1.QueryStats
+	4,  // 20: bngo.v1.GaussianCPD.StatesEntry.value:type_name -> bngo.v1.GaussianParams
+	8,  // 21: bngo.v1.InferenceService.LoadModel:input_type -> bngo.v1.LoadModelRequest
+	10, // 22: bngo.v1.InferenceService.GetModel:input_type -> bngo.v1.GetModelRequest
+	11, // 23: bngo.v1.InferenceService.ListModels:input_type -> bngo.v1.ListModelsRequest
+	14, // 24: bngo.v1.InferenceService.Query:input_type -> bngo.v1.QueryRequest
+	9,  // 25: bngo.v1.InferenceService.LoadModel:output_type -> bngo.v1.LoadModelResponse
+	6,  // 26: bngo.v1.InferenceService.GetModel:output_type -> bngo.v1.Model
+	13, // 27: bngo.v1.InferenceService.ListModels:output_type -> bngo.v1.ListModelsResponse
+	18, // 28: bngo.v1.InferenceService.Query:output_type -> bngo.v1.QueryResponse
+	25, // [25:29] is the sub-list for method output_type
+	21, // [21:25] is the sub-list for method input_type
+	21, // [21:21] is the sub-list for extension type_name
+	21, // [21:21] is the sub-list for extension extendee
+	0,  // [0:21] is the sub-list for field type_name
+}
+
+func init() { file_bngopb_bngo_proto_init() }
+func file_bngopb_bngo_proto_init() {
+	if File_bngopb_bngo_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_bngopb_bngo_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*Variable); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*Edge); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*TabularCPD); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*GaussianParams); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*GaussianCPD); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*Model); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*Evidence); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*LoadModelRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*LoadModelResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*GetModelRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*ListModelsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*ModelInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[12].Exporter = func(v any, i int) any {
+			switch v := v.(*ListModelsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[13].Exporter = func(v any, i int) any {
+			switch v := v.(*QueryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[14].Exporter = func(v any, i int) any {
+			switch v := v.(*DiscreteDistribution); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[15].Exporter = func(v any, i int) any {
+			switch v := v.(*MixtureComponent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[16].Exporter = func(v any, i int) any {
+			switch v := v.(*QueryStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bngopb_bngo_proto_msgTypes[17].Exporter = func(v any, i int) any {
+			switch v := v.(*QueryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_bngopb_bngo_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   25,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_bngopb_bngo_proto_goTypes,
+		DependencyIndexes: file_bngopb_bngo_proto_depIdxs,
+		EnumInfos:         file_bngopb_bngo_proto_enumTypes,
+		MessageInfos:      file_bngopb_bngo_proto_msgTypes,
+	}.Build()
+	File_bngopb_bngo_proto = out.File
+	file_bngopb_bngo_proto_rawDesc = nil
+	file_bngopb_bngo_proto_goTypes = nil
+	file_bngopb_bngo_proto_depIdxs = nil
+}
@@ -0,0 +1,414 @@
+// Command bench runs the inference algorithms over benchmark networks and
+// sample sizes, and reports accuracy against exact variable elimination and
+// wall time as JSON lines or CSV, to guide the choice of algorithm. The exact
+// engines are variable elimination, compiled variable elimination and the
+// arithmetic circuit; the library has no junction tree, so the circuit
+// stands in for it. The samplers are rejection, likelihood weighting and
+// blocked Gibbs sampling.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JohnPierman/bngo/examples"
+	"github.com/JohnPierman/bngo/inference"
+	"github.com/JohnPierman/bngo/models"
+	"github.com/JohnPierman/bngo/utils"
+)
+
+// networks are the bundled benchmark networks
+var networks = map[string]func() (*models.BayesianNetwork, error){
+	"student":   examples.GetStudentModel,
+	"alarm":     examples.GetAlarmModel,
+	"cancer":    examples.GetCancerModel,
+	"sprinkler": examples.GetSprinklerModel,
+}
+
+// marginals maps each query variable to its posterior
+type marginals map[string][]float64
+
+// algorithm answers the single-variable posteriors of a workload. Sampling
+// algorithms draw n samples from seed and report how many were used.
+type algorithm struct {
+	name    string
+	sampled bool
+	run     func(bn *models.BayesianNetwork, w workload, n int, seed int64) (marginals, int, error)
+}
+
+var algorithms = []algorithm{
+	{name: "ve", run: runVE},
+	{name: "compiled-ve", run: runCompiledVE},
+	{name: "circuit", run: runCircuit},
+	{name: "rejection", sampled: true, run: runRejection},
+	{name: "likelihood-weighting", sampled: true, run: runLikelihoodWeighting},
+	{name: "gibbs", sampled: true, run: runGibbs},
+}
+
+// workload is the posterior of every unobserved variable given one leaf
+type workload struct {
+	queries  []string
+	evidence map[string]int
+}
+
+// record is one line of the report
+type record struct {
+	Network      string   `json:"network"`
+	Algorithm    string   `json:"algorithm"`
+	Samples      int      `json:"samples"`
+	Rep          int      `json:"rep"`
+	Queries      int      `json:"queries"`
+	Seconds      float64  `json:"seconds"`
+	Accepted     int      `json:"accepted"`
+	MaxAbsError  *float64 `json:"max_abs_error"`  // nil when no sample was accepted
+	MeanAbsError *float64 `json:"mean_abs_error"` // nil when no sample was accepted
+}
+
+func main() {
+	names := flag.String("networks", "student,alarm,cancer,sprinkler", "comma-separated bundled networks")
+	modelPaths := flag.String("model", "", "comma-separated extra discrete models (.bif or .json)")
+	sizes := flag.String("sizes", "100,1000,10000,100000", "comma-separated sample sizes for sampling algorithms")
+	reps := flag.Int("reps", 3, "repetitions of each run")
+	seed := flag.Int64("seed", 1, "random seed of the first repetition")
+	format := flag.String("format", "json", "report format: json (JSON lines) or csv")
+	out := flag.String("out", "", "report file; standard output if empty")
+	flag.Parse()
+
+	if err := run(*names, *modelPaths, *sizes, *reps, *seed, *format, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(names, modelPaths, sizeList string, reps int, seed int64, format, out string) error {
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("unknown format %q, expected json or csv", format)
+	}
+	var sizes []int
+	for _, s := range splitList(sizeList) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid sample size %q", s)
+		}
+		sizes = append(sizes, n)
+	}
+
+	type named struct {
+		name string
+		bn   *models.BayesianNetwork
+	}
+	var nets []named
+	for _, name := range splitList(names) {
+		build, ok := networks[name]
+		if !ok {
+			return fmt.Errorf("unknown network %q", name)
+		}
+		bn, err := build()
+		if err != nil {
+			return fmt.Errorf("network %s: %w", name, err)
+		}
+		nets = append(nets, named{name, bn})
+	}
+	for _, path := range splitList(modelPaths) {
+		bn, err := utils.LoadModel(path)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, named{strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), bn})
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+		w = file
+	}
+	emit, flush := jsonWriter(w)
+	if format == "csv" {
+		emit, flush = csvWriter(w)
+	}
+
+	for _, net := range nets {
+		work, err := newWorkload(net.bn)
+		if err != nil {
+			return fmt.Errorf("network %s: %w", net.name, err)
+		}
+		reference, _, err := runVE(net.bn, work, 0, 0)
+		if err != nil {
+			return fmt.Errorf("network %s: %w", net.name, err)
+		}
+
+		for _, alg := range algorithms {
+			runSizes := []int{0}
+			if alg.sampled {
+				runSizes = sizes
+			}
+			for _, n := range runSizes {
+				for rep := 0; rep < reps; rep++ {
+					start := time.Now()
+					got, accepted, err := alg.run(net.bn, work, n, seed+int64(rep))
+					elapsed := time.Since(start)
+					if err != nil {
+						return fmt.Errorf("network %s, %s: %w", net.name, alg.name, err)
+					}
+
+					rec := record{
+						Network:   net.name,
+						Algorithm: alg.name,
+						Samples:   n,
+						Rep:       rep,
+						Queries:   len(work.queries),
+						Seconds:   elapsed.Seconds(),
+						Accepted:  accepted,
+					}
+					rec.MaxAbsError, rec.MeanAbsError = compare(reference, got)
+					if err := emit(rec); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return flush()
+}
+
+// newWorkload observes the last leaf in topological order in its first state
+// and queries every other variable
+func newWorkload(bn *models.BayesianNetwork) (workload, error) {
+	order, err := bn.DAG.TopologicalSort()
+	if err != nil {
+		return workload{}, err
+	}
+	for _, node := range order {
+		if bn.IsContinuous(node) {
+			return workload{}, fmt.Errorf("variable %s: %w", node, models.ErrContinuousVariables)
+		}
+	}
+
+	leaf := ""
+	for _, node := range order {
+		if len(bn.DAG.Children(node)) == 0 {
+			leaf = node
+		}
+	}
+	w := workload{evidence: map[string]int{leaf: 0}}
+	for _, node := range order {
+		if node != leaf {
+			w.queries = append(w.queries, node)
+		}
+	}
+	return w, nil
+}
+
+func runVE(bn *models.BayesianNetwork, w workload, _ int, _ int64) (marginals, int, error) {
+	ve, err := inference.NewVariableElimination(bn)
+	if err != nil {
+		return nil, 0, err
+	}
+	return queryAll(ve, w)
+}
+
+// runCompiledVE includes the cost of compiling, which serving amortizes
+// over many queries
+func runCompiledVE(bn *models.BayesianNetwork, w workload, _ int, _ int64) (marginals, int, error) {
+	cm, err := bn.Compile()
+	if err != nil {
+		return nil, 0, err
+	}
+	return queryAll(inference.NewCompiledVariableElimination(cm), w)
+}
+
+func queryAll(ve *inference.VariableElimination, w workload) (marginals, int, error) {
+	result := make(marginals, len(w.queries))
+	for _, v := range w.queries {
+		f, err := ve.Query([]string{v}, w.evidence)
+		if err != nil {
+			return nil, 0, err
+		}
+		result[v] = f.Values
+	}
+	return result, 0, nil
+}
+
+// runCircuit includes the cost of compiling the circuit, and answers every
+// query from one forward and backward pass
+func runCircuit(bn *models.BayesianNetwork, w workload, _ int, _ int64) (marginals, int, error) {
+	ac, err := inference.CompileCircuit(bn)
+	if err != nil {
+		return nil, 0, err
+	}
+	all, err := ac.Marginals(w.evidence)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make(marginals, len(w.queries))
+	for _, v := range w.queries {
+		result[v] = all[v]
+	}
+	return result, 0, nil
+}
+
+// runRejection forward-samples n rows and estimates the posteriors from the
+// rows that agree with the evidence
+func runRejection(bn *models.BayesianNetwork, w workload, n int, seed int64) (marginals, int, error) {
+	cols, err := bn.SimulateColumns(n, seed)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(marginals, len(w.queries))
+	for _, v := range w.queries {
+		counts[v] = make([]float64, bn.Cardinality[v])
+	}
+	accepted := 0
+	for i := 0; i < n; i++ {
+		keep := true
+		for v, state := range w.evidence {
+			keep = keep && cols.Discrete[v][i] == state
+		}
+		if !keep {
+			continue
+		}
+		accepted++
+		for _, v := range w.queries {
+			counts[v][cols.Discrete[v][i]]++
+		}
+	}
+	if accepted == 0 {
+		return marginals{}, 0, nil
+	}
+	for _, c := range counts {
+		for s := range c {
+			c[s] /= float64(accepted)
+		}
+	}
+	return counts, accepted, nil
+}
+
+// runLikelihoodWeighting draws n weighted samples and reports how many have
+// positive weight
+func runLikelihoodWeighting(bn *models.BayesianNetwork, w workload, n int, seed int64) (marginals, int, error) {
+	samples, err := bn.LikelihoodWeighting(n, models.Sample{Discrete: w.evidence}, seed)
+	if err != nil {
+		return nil, 0, err
+	}
+	accepted := 0
+	for _, s := range samples {
+		if s.Weight > 0 {
+			accepted++
+		}
+	}
+	if accepted == 0 {
+		return marginals{}, 0, nil
+	}
+
+	result := make(marginals, len(w.queries))
+	for _, v := range w.queries {
+		if result[v], err = models.WeightedMarginal(samples, v, bn.Cardinality[v]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return result, accepted, nil
+}
+
+// runGibbs keeps n sweeps of one chain after a burn-in of n/10 and reports
+// the Rao-Blackwellized marginals
+func runGibbs(bn *models.BayesianNetwork, w workload, n int, seed int64) (marginals, int, error) {
+	ve, err := inference.NewVariableElimination(bn)
+	if err != nil {
+		return nil, 0, err
+	}
+	res, err := ve.GibbsSample(models.Sample{Discrete: w.evidence}, inference.GibbsOptions{
+		Samples: n,
+		BurnIn:  n / 10,
+		Seed:    seed,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make(marginals, len(w.queries))
+	for _, v := range w.queries {
+		result[v] = res.Marginals[v]
+	}
+	return result, len(res.Samples), nil
+}
+
+// compare returns the largest and mean absolute difference between the
+// probabilities of two sets of marginals, or nil for both when got is empty
+// because a sampler accepted nothing
+func compare(reference, got marginals) (*float64, *float64) {
+	if len(got) == 0 {
+		return nil, nil
+	}
+	maxErr, sum, count := 0.0, 0.0, 0
+	for v, want := range reference {
+		for s, p := range want {
+			d := math.Abs(p - got[v][s])
+			maxErr = math.Max(maxErr, d)
+			sum += d
+			count++
+		}
+	}
+	meanErr := 0.0
+	if count > 0 {
+		meanErr = sum / float64(count)
+	}
+	return &maxErr, &meanErr
+}
+
+func jsonWriter(w io.Writer) (func(record) error, func() error) {
+	enc := json.NewEncoder(w)
+	return func(r record) error { return enc.Encode(r) }, func() error { return nil }
+}
+
+func csvWriter(w io.Writer) (func(record) error, func() error) {
+	writer := csv.NewWriter(w)
+	header := []string{"network", "algorithm", "samples", "rep", "queries", "seconds", "accepted", "max_abs_error", "mean_abs_error"}
+	wroteHeader := false
+	optional := func(x *float64) string {
+		if x == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*x, 'g', -1, 64)
+	}
+	emit := func(r record) error {
+		if !wroteHeader {
+			wroteHeader = true
+			if err := writer.Write(header); err != nil {
+				return err
+			}
+		}
+		return writer.Write([]string{
+			r.Network, r.Algorithm, strconv.Itoa(r.Samples), strconv.Itoa(r.Rep), strconv.Itoa(r.Queries),
+			strconv.FormatFloat(r.Seconds, 'g', -1, 64), strconv.Itoa(r.Accepted),
+			optional(r.MaxAbsError), optional(r.MeanAbsError),
+		})
+	}
+	flush := func() error {
+		writer.Flush()
+		return writer.Error()
+	}
+	return emit, flush
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.jsonl")
+	if err := run("sprinkler", "", "50,2000", 2, 1, "json", path); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open report: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	runs := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("Invalid record %q: %v", scanner.Text(), err)
+		}
+		if rec.Network != "sprinkler" || rec.Queries != 3 || rec.Seconds < 0 {
+			t.Errorf("Unexpected record %+v", rec)
+		}
+		runs[rec.Algorithm]++
+
+		if rec.Samples == 0 {
+			// Exact engines agree with variable elimination
+			if rec.MaxAbsError == nil || *rec.MaxAbsError > 1e-9 {
+				t.Errorf("%s: max error %v, want 0", rec.Algorithm, rec.MaxAbsError)
+			}
+			continue
+		}
+		if rec.Accepted > rec.Samples {
+			t.Errorf("%s accepted %d of %d samples", rec.Algorithm, rec.Accepted, rec.Samples)
+		}
+		if rec.Accepted == 0 {
+			if rec.MaxAbsError != nil || rec.MeanAbsError != nil {
+				t.Errorf("%s accepted nothing but reports errors", rec.Algorithm)
+			}
+			continue
+		}
+		if *rec.MeanAbsError > *rec.MaxAbsError {
+			t.Errorf("%s: mean error %f above max %f", rec.Algorithm, *rec.MeanAbsError, *rec.MaxAbsError)
+		}
+		if rec.Samples == 2000 && *rec.MaxAbsError > 0.1 {
+			t.Errorf("%s with 2000 samples: max error %f", rec.Algorithm, *rec.MaxAbsError)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+
+	// Exact engines run once per repetition, samplers once per size too
+	for _, alg := range algorithms {
+		want := 2
+		if alg.sampled {
+			want = 4
+		}
+		if runs[alg.name] != want {
+			t.Errorf("%s: %d records, want %d", alg.name, runs[alg.name], want)
+		}
+	}
+}
+
+func TestRunCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.csv")
+	if err := run("student", "", "100", 1, 1, "csv", path); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open report: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV: %v", err)
+	}
+	if len(rows) != len(algorithms)+1 || rows[0][0] != "network" {
+		t.Errorf("Expected a header and %d rows, got %v", len(algorithms), rows)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for name, args := range map[string][3]string{
+		"format":  {"student", "100", "xml"},
+		"size":    {"student", "0", "json"},
+		"network": {"unknown", "100", "json"},
+	} {
+		if err := run(args[0], "", args[1], 1, 1, args[2], ""); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	reference := marginals{"A": {0.2, 0.8}, "B": {0.5, 0.25, 0.25}}
+	got := marginals{"A": {0.3, 0.7}, "B": {0.5, 0.5, 0}}
+
+	maxErr, meanErr := compare(reference, got)
+	if maxErr == nil || meanErr == nil {
+		t.Fatal("Expected errors for non-empty marginals")
+	}
+	if math.Abs(*maxErr-0.25) > 1e-12 {
+		t.Errorf("max error = %f, want 0.25", *maxErr)
+	}
+	if want := (0.1 + 0.1 + 0 + 0.25 + 0.25) / 5; math.Abs(*meanErr-want) > 1e-12 {
+		t.Errorf("mean error = %f, want %f", *meanErr, want)
+	}
+
+	if maxErr, meanErr := compare(reference, reference); *maxErr != 0 || *meanErr != 0 {
+		t.Errorf("identical marginals: errors %f, %f", *maxErr, *meanErr)
+	}
+	if maxErr, meanErr := compare(reference, marginals{}); maxErr != nil || meanErr != nil {
+		t.Error("Expected nil errors when nothing was accepted")
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JohnPierman/bngo/inference"
+	"github.com/JohnPierman/bngo/models"
+	"github.com/JohnPierman/bngo/utils"
+)
+
+// loadData reads samples from CSV or JSON lines. CSV columns take their type
+// from types, or else the type inferred from their values, with labels mapped
+// through states when given.
+// It returns the state labels of every discrete column.
+func loadData(path string, types map[string]models.VariableType, states map[string][]string) ([]models.Sample, map[string][]string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl":
+		samples, err := utils.LoadJSONL(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return samples, integerStates(samples, states), nil
+	case ".csv":
+		schema, err := utils.InferMixedSchema(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for col := range schema.Types {
+			if vtype, ok := types[col]; ok && vtype != "" && vtype != schema.Types[col] {
+				schema.Types[col] = vtype
+				delete(schema.States, col)
+			}
+			if labels, ok := states[col]; ok && schema.Types[col] == models.Discrete {
+				schema.States[col] = labels
+			}
+		}
+		samples, err := utils.LoadMixedCSV(path, schema)
+		if err != nil {
+			return nil, nil, err
+		}
+		return samples, schema.States, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown data format for %s, expected .csv or .jsonl", path)
+	}
+}
+
+// integerStates labels the integer codes of JSON-lines data, keeping any
+// labels already known
+func integerStates(samples []models.Sample, known map[string][]string) map[string][]string {
+	states := make(map[string][]string)
+	for v, labels := range known {
+		states[v] = labels
+	}
+	for _, s := range samples {
+		for v, code := range s.Discrete {
+			for len(states[v]) <= code {
+				states[v] = append(states[v], strconv.Itoa(len(states[v])))
+			}
+		}
+	}
+	return states
+}
+
+// modelSchema returns the variable types and state labels of a model, so data
+// is parsed the way the model expects
+func modelSchema(bn *models.BayesianNetwork) (map[string]models.VariableType, map[string][]string) {
+	types := make(map[string]models.VariableType)
+	states := make(map[string][]string)
+	for _, node := range bn.Nodes() {
+		types[node] = bn.VariableType[node]
+		if labels := bn.StateNames(node); labels != nil {
+			states[node] = labels
+		}
+	}
+	return types, states
+}
+
+// labelStates attaches data labels to freshly fitted CPDs
+func labelStates(bn *models.BayesianNetwork, states map[string][]string) {
+	for _, cpd := range bn.CPDs {
+		for _, v := range append([]string{cpd.Variable}, cpd.Evidence...) {
+			if labels, ok := states[v]; ok {
+				_ = cpd.SetStateNames(v, labels) // Skipped if the fitted cardinality differs
+			}
+		}
+	}
+}
+
+// saveData writes simulated columns as CSV, or as JSON lines for .jsonl.
+// Discrete values are written as state labels in CSV.
+func saveData(path string, bn *models.BayesianNetwork, cols *models.SampleColumns) error {
+	if strings.ToLower(filepath.Ext(path)) == ".jsonl" {
+		return utils.SaveJSONL(path, cols.Samples())
+	}
+
+	header := bn.Nodes()
+	labels := make(map[string][]string, len(cols.Discrete))
+	for v := range cols.Discrete {
+		labels[v] = bn.StateNames(v)
+	}
+	return writeCSV(path, header, cols.Len(), func(i int, record []string) {
+		for j, v := range header {
+			if col, ok := cols.Discrete[v]; ok {
+				if s := col[i]; s < len(labels[v]) {
+					record[j] = labels[v][s]
+				} else {
+					record[j] = strconv.Itoa(s)
+				}
+			} else {
+				record[j] = strconv.FormatFloat(cols.Continuous[v][i], 'g', -1, 64)
+			}
+		}
+	})
+}
+
+// saveSamples writes samples as JSON lines or CSV by extension. Missing
+// values are left empty in CSV.
+func saveSamples(path string, samples []models.Sample, states map[string][]string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl":
+		return utils.SaveJSONL(path, samples)
+	case ".csv":
+	default:
+		return fmt.Errorf("unknown data format for %s, expected .csv or .jsonl", path)
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range samples {
+		for v := range s.Discrete {
+			seen[v] = true
+		}
+		for v := range s.Continuous {
+			seen[v] = true
+		}
+	}
+	header := make([]string, 0, len(seen))
+	for v := range seen {
+		header = append(header, v)
+	}
+	sort.Strings(header)
+
+	return writeCSV(path, header, len(samples), func(i int, record []string) {
+		for j, v := range header {
+			record[j] = ""
+			if code, ok := samples[i].Discrete[v]; ok {
+				record[j] = strconv.Itoa(code)
+				if labels := states[v]; code < len(labels) {
+					record[j] = labels[code]
+				}
+			} else if x, ok := samples[i].Continuous[v]; ok {
+				record[j] = strconv.FormatFloat(x, 'g', -1, 64)
+			}
+		}
+	})
+}
+
+// writeCSV writes n rows filled in by row, to standard output if path is empty
+func writeCSV(path string, header []string, n int, row func(i int, record []string)) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+		w = file
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(header))
+	for i := 0; i < n; i++ {
+		row(i, record)
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// parseEvidence parses "A=high,X=1.5" into a sample. Discrete values may be
+// state labels or indices.
+func parseEvidence(bn *models.BayesianNetwork, s string) (models.Sample, error) {
+	evidence := models.Sample{Discrete: make(map[string]int), Continuous: make(map[string]float64)}
+	for _, item := range splitList(s) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return evidence, fmt.Errorf("evidence %q is not of the form variable=value", item)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+
+		switch {
+		case bn.IsContinuous(name):
+			x, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return evidence, fmt.Errorf("invalid value %q for continuous variable %s", value, name)
+			}
+			evidence.Continuous[name] = x
+		case bn.IsDiscrete(name):
+			state, err := parseState(bn, name, value)
+			if err != nil {
+				return evidence, err
+			}
+			evidence.Discrete[name] = state
+		default:
+			return evidence, fmt.Errorf("evidence variable %s: %w", name, models.ErrUnknownVariable)
+		}
+	}
+	return evidence, nil
+}
+
+func parseState(bn *models.BayesianNetwork, variable, value string) (int, error) {
+	for i, label := range bn.StateNames(variable) {
+		if label == value {
+			return i, nil
+		}
+	}
+	state, err := strconv.Atoi(value)
+	if err != nil || state < 0 || state >= bn.Cardinality[variable] {
+		return 0, fmt.Errorf("unknown state %q of %s", value, variable)
+	}
+	return state, nil
+}
+
+func stateLabel(bn *models.BayesianNetwork, variable string, state int) string {
+	if labels := bn.StateNames(variable); state < len(labels) {
+		return labels[state]
+	}
+	return strconv.Itoa(state)
+}
+
+// printDiscrete prints one line per assignment of a factor, last variable fastest
+func printDiscrete(bn *models.BayesianNetwork, variables []string, cardinality map[string]int, values []float64) {
+	labels := make([]string, len(variables))
+	for idx, p := range values {
+		rem := idx
+		for j := len(variables) - 1; j >= 0; j-- {
+			v := variables[j]
+			labels[j] = fmt.Sprintf("%s=%s", v, stateLabel(bn, v, rem%cardinality[v]))
+			rem /= cardinality[v]
+		}
+		fmt.Printf("%s\t%.6f\n", strings.Join(labels, ", "), p)
+	}
+}
+
+// modelStates returns the state labels of variables, nil for unlabeled ones
+func modelStates(bn *models.BayesianNetwork, variables []string) map[string][]string {
+	states := make(map[string][]string, len(variables))
+	for _, v := range variables {
+		states[v] = bn.StateNames(v)
+	}
+	return states
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printStats(stats *inference.QueryStats) {
+	fmt.Fprintf(os.Stderr, "multiplications: %d\n", stats.Multiplications)
+	fmt.Fprintf(os.Stderr, "largest factor: %d entries\n", stats.MaxFactorSize)
+	fmt.Fprintf(os.Stderr, "elimination order: %s\n", strings.Join(stats.EliminationOrder, ", "))
+	if stats.Configurations > 0 {
+		fmt.Fprintf(os.Stderr, "discrete configurations: %d\n", stats.Configurations)
+	}
+	fmt.Fprintf(os.Stderr, "wall time: %v\n", stats.Duration)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func isModelFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".bif" || ext == ".dne" || ext == ".json"
+}
@@ -0,0 +1,302 @@
+// Command bngo learns, fits, samples and queries Bayesian networks stored in
+// BIF or JSON files, using data in CSV or JSON-lines files
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/JohnPierman/bngo/estimators"
+	"github.com/JohnPierman/bngo/inference"
+	"github.com/JohnPierman/bngo/models"
+	"github.com/JohnPierman/bngo/utils"
+)
+
+const usage = `Usage: bngo <command> [flags]
+
+Commands:
+  learn-structure  learn a network structure from discrete data with PC
+  fit              fit the CPDs of a model to data
+  simulate         draw samples from a model
+  query            compute a posterior distribution
+  convert          convert a model between BIF and JSON, or data between CSV and JSONL
+  serve            serve models over gRPC
+
+Models are read as .bif, .dne (Netica) or .json and written as .bif or .json;
+data as .csv or .jsonl.
+Run 'bngo <command> -h' for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func([]string) error{
+		"learn-structure": learnStructure,
+		"fit":             fit,
+		"simulate":        simulate,
+		"query":           query,
+		"convert":         convert,
+		"serve":           serve,
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "-h" && os.Args[1] != "help" {
+			fmt.Fprintf(os.Stderr, "bngo: unknown command %q\n\n", os.Args[1])
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := run(os.Args[2:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "bngo %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func learnStructure(args []string) error {
+	fs := flag.NewFlagSet("learn-structure", flag.ContinueOnError)
+	data := fs.String("data", "", "discrete training data (.csv or .jsonl)")
+	alpha := fs.Float64("alpha", 0.05, "significance level of the independence tests")
+	out := fs.String("out", "", "output model (.json); prints the edges if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *data == "" {
+		return fmt.Errorf("-data is required")
+	}
+
+	samples, states, err := loadData(*data, nil, nil)
+	if err != nil {
+		return err
+	}
+	rows := make([]map[string]int, len(samples))
+	for i, s := range samples {
+		if len(s.Continuous) > 0 {
+			return fmt.Errorf("row %d: PC needs discrete data: %w", i+1, models.ErrContinuousVariables)
+		}
+		rows[i] = s.Discrete
+	}
+
+	pc := estimators.NewPC(rows)
+	pc.SetAlpha(*alpha)
+	dag, err := pc.Estimate()
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		edges := dag.Edges()
+		sort.Slice(edges, func(i, j int) bool {
+			return edges[i][0]+"\x00"+edges[i][1] < edges[j][0]+"\x00"+edges[j][1]
+		})
+		for _, e := range edges {
+			fmt.Printf("%s -> %s\n", e[0], e[1])
+		}
+		return nil
+	}
+
+	bn, err := models.NewBayesianNetwork(dag.Edges())
+	if err != nil {
+		return err
+	}
+	for _, node := range dag.Nodes() {
+		bn.DAG.AddNode(node)
+		bn.VariableType[node] = models.Discrete
+		bn.Cardinality[node] = len(states[node])
+	}
+	return utils.SaveModel(*out, bn)
+}
+
+func fit(args []string) error {
+	fs := flag.NewFlagSet("fit", flag.ContinueOnError)
+	modelPath := fs.String("model", "", "model whose structure is fitted (.bif or .json)")
+	data := fs.String("data", "", "training data (.csv or .jsonl)")
+	out := fs.String("out", "", "output model (.bif or .json)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *modelPath == "" || *data == "" || *out == "" {
+		return fmt.Errorf("-model, -data and -out are required")
+	}
+
+	bn, err := utils.LoadModel(*modelPath)
+	if err != nil {
+		return err
+	}
+	types, states := modelSchema(bn)
+	samples, states, err := loadData(*data, types, states)
+	if err != nil {
+		return err
+	}
+
+	continuous := false
+	for _, node := range bn.Nodes() {
+		continuous = continuous || bn.IsContinuous(node)
+	}
+	if continuous {
+		err = bn.FitMixed(samples)
+	} else {
+		rows := make([]map[string]int, len(samples))
+		for i, s := range samples {
+			rows[i] = s.Discrete
+		}
+		err = bn.Fit(rows)
+	}
+	if err != nil {
+		return err
+	}
+
+	labelStates(bn, states)
+	return utils.SaveModel(*out, bn)
+}
+
+func simulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	modelPath := fs.String("model", "", "model to sample from (.bif or .json)")
+	n := fs.Int("n", 1000, "number of samples")
+	seed := fs.Int64("seed", 42, "random seed")
+	out := fs.String("out", "", "output data (.csv or .jsonl); CSV on standard output if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *modelPath == "" {
+		return fmt.Errorf("-model is required")
+	}
+
+	bn, err := utils.LoadModel(*modelPath)
+	if err != nil {
+		return err
+	}
+	cols, err := bn.SimulateColumns(*n, *seed)
+	if err != nil {
+		return err
+	}
+	return saveData(*out, bn, cols)
+}
+
+func query(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	modelPath := fs.String("model", "", "model to query (.bif or .json)")
+	vars := fs.String("vars", "", "comma-separated query variables")
+	evidence := fs.String("evidence", "", "comma-separated observations, e.g. A=high,X=1.5")
+	showStats := fs.Bool("stats", false, "print the cost of the query to standard error")
+	asJSON := fs.Bool("json", false, "print the marginal of each discrete query variable as JSON keyed by state name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *modelPath == "" || *vars == "" {
+		return fmt.Errorf("-model and -vars are required")
+	}
+
+	bn, err := utils.LoadModel(*modelPath)
+	if err != nil {
+		return err
+	}
+	observed, err := parseEvidence(bn, *evidence)
+	if err != nil {
+		return err
+	}
+	variables := splitList(*vars)
+
+	ve, err := inference.NewVariableElimination(bn)
+	if err != nil {
+		return err
+	}
+
+	mixed := len(observed.Continuous) > 0
+	for _, v := range variables {
+		mixed = mixed || bn.IsContinuous(v)
+	}
+	if !mixed {
+		result, stats, err := ve.QueryWithStats(variables, observed.Discrete)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			if err := printJSON(result.LabeledMarginals(modelStates(bn, result.Variables))); err != nil {
+				return err
+			}
+		} else {
+			printDiscrete(bn, result.Variables, result.Cardinality, result.Values)
+		}
+		if *showStats {
+			printStats(stats)
+		}
+		return nil
+	}
+
+	if *asJSON {
+		return fmt.Errorf("-json supports discrete queries only")
+	}
+	result, stats, err := ve.QueryMixedWithStats(variables, observed)
+	if err != nil {
+		return err
+	}
+	if *showStats {
+		defer printStats(stats)
+	}
+	for _, c := range result.Components {
+		var labels []string
+		for _, v := range result.DiscreteVariables {
+			labels = append(labels, fmt.Sprintf("%s=%s", v, stateLabel(bn, v, c.Discrete[v])))
+		}
+		if len(labels) > 0 {
+			fmt.Printf("%s\t%.6f\n", strings.Join(labels, ", "), c.Weight)
+		}
+		if c.Gaussian == nil {
+			continue
+		}
+		for _, v := range result.ContinuousVariables {
+			fmt.Printf("  %s: mean %.6f, variance %.6f\n", v, c.Gaussian.MeanOf(v), c.Gaussian.CovarianceOf(v, v))
+		}
+	}
+	return nil
+}
+
+func convert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	in := fs.String("in", "", "input model (.bif, .json) or data (.csv, .jsonl)")
+	out := fs.String("out", "", "output file of the same kind")
+	continuous := fs.String("continuous", "", "comma-separated continuous columns when reading CSV data")
+	pgmpy := fs.Bool("pgmpy", false, "write the model as JSON in pgmpy's layout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *out == "" {
+		return fmt.Errorf("-in and -out are required")
+	}
+
+	if isModelFile(*in) {
+		bn, err := utils.LoadModel(*in)
+		if err != nil {
+			return err
+		}
+		if *pgmpy {
+			return utils.SavePgmpyJSON(*out, bn)
+		}
+		return utils.SaveModel(*out, bn)
+	}
+
+	var types map[string]models.VariableType
+	if *continuous != "" {
+		types = make(map[string]models.VariableType)
+		for _, c := range splitList(*continuous) {
+			types[c] = models.Continuous
+		}
+	}
+	samples, states, err := loadData(*in, types, nil)
+	if err != nil {
+		return err
+	}
+	return saveSamples(*out, samples, states)
+}
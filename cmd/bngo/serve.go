@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/JohnPierman/bngo/serving"
+)
+
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	modelPaths := fs.String("model", "", "comma-separated models to load (.bif or .json), named by file name")
+	addr := fs.String("addr", ":50051", "listen address")
+	watch := fs.Duration("watch", 0, "poll interval for reloading changed model files; 0 disables reloading")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s := serving.NewServer()
+	for _, path := range splitList(*modelPaths) {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		var err error
+		if *watch > 0 {
+			err = s.WatchFile(context.Background(), name, path, *watch)
+		} else {
+			_, err = s.LoadFile(name, path)
+		}
+		if err != nil {
+			return fmt.Errorf("model %s: %w", path, err)
+		}
+	}
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	g := grpc.NewServer()
+	s.Register(g)
+	fmt.Printf("serving on %s\n", lis.Addr())
+	return g.Serve(lis)
+}
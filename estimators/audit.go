@@ -0,0 +1,81 @@
+package estimators
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// Independence is a conditional independence X ⊥ Y | Given
+type Independence struct {
+	X, Y  string
+	Given []string
+}
+
+// IndependenceTest is an implied independence tested against data
+type IndependenceTest struct {
+	Independence
+	PValue   float64
+	Violated bool // PValue is below the significance level
+}
+
+// ImpliedIndependencies returns the local Markov basis of the conditional
+// independencies dag implies: each node is independent of every earlier,
+// non-adjacent node in topological order given its parents. Every other
+// independence the DAG implies by d-separation follows from these, so
+// testing them audits all of the model's assumptions with one test per
+// pair of non-adjacent nodes.
+func ImpliedIndependencies(dag *graph.DAG) ([]Independence, error) {
+	order, err := dag.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+	var result []Independence
+	for i, x := range order {
+		parents := dag.Parents(x)
+		sort.Strings(parents)
+		earlier := append([]string(nil), order[:i]...)
+		sort.Strings(earlier)
+		for _, y := range earlier {
+			if dag.HasEdge(y, x) || !dag.IsDSeparated(x, y, parents) {
+				continue
+			}
+			result = append(result, Independence{X: x, Y: y, Given: parents})
+		}
+	}
+	return result, nil
+}
+
+// AuditIndependencies tests every independence in the ImpliedIndependencies
+// of dag with the estimator's test on its data, flagging as violated those
+// rejected at Alpha. Each is tested at Alpha on its own, so with many
+// independencies some are flagged by chance; compare the p-values with a
+// corrected level, such as Alpha divided by their number, for a strict
+// audit. Every node of dag must be a variable of the data.
+func (pc *PCEstimator) AuditIndependencies(dag *graph.DAG) ([]IndependenceTest, error) {
+	known := make(map[string]bool, len(pc.Variables))
+	for _, v := range pc.Variables {
+		known[v] = true
+	}
+	for _, node := range dag.Nodes() {
+		if !known[node] {
+			return nil, fmt.Errorf("node %s is not a variable of the data", node)
+		}
+	}
+
+	implied, err := ImpliedIndependencies(dag)
+	if err != nil {
+		return nil, err
+	}
+	test, err := pc.independenceTest()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]IndependenceTest, len(implied))
+	for i, ind := range implied {
+		pValue := test(ind.X, ind.Y, ind.Given)
+		results[i] = IndependenceTest{Independence: ind, PValue: pValue, Violated: pValue < pc.Alpha}
+	}
+	return results, nil
+}
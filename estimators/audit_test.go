@@ -0,0 +1,59 @@
+package estimators
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JohnPierman/bngo/graph"
+)
+
+func TestImpliedIndependencies(t *testing.T) {
+	chain := graph.NewDAG()
+	_ = chain.AddEdge("A", "B")
+	_ = chain.AddEdge("B", "C")
+	implied, err := ImpliedIndependencies(chain)
+	if err != nil {
+		t.Fatalf("ImpliedIndependencies failed: %v", err)
+	}
+	want := []Independence{{X: "C", Y: "A", Given: []string{"B"}}}
+	if !reflect.DeepEqual(implied, want) {
+		t.Errorf("Expected a chain to imply only C ⊥ A | B, got %v", implied)
+	}
+
+	collider := graph.NewDAG()
+	_ = collider.AddEdge("A", "C")
+	_ = collider.AddEdge("B", "C")
+	implied, _ = ImpliedIndependencies(collider)
+	if len(implied) != 1 || len(implied[0].Given) != 0 {
+		t.Errorf("Expected a collider to imply only the marginal independence of A and B, got %v", implied)
+	}
+}
+
+func TestAuditIndependencies(t *testing.T) {
+	// The data come from A -> C <- B, C -> D, but the model chains A -> C -> B
+	pc := NewPC(colliderData(2000, 1))
+	wrong := graph.NewDAG()
+	_ = wrong.AddEdge("A", "C")
+	_ = wrong.AddEdge("C", "B")
+	_ = wrong.AddEdge("C", "D")
+	results, err := pc.AuditIndependencies(wrong)
+	if err != nil {
+		t.Fatalf("AuditIndependencies failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 implied independencies, got %v", results)
+	}
+	for _, r := range results {
+		pair := []string{r.X, r.Y}
+		violated := reflect.DeepEqual(pair, []string{"B", "A"})
+		if r.Violated != violated {
+			t.Errorf("%s ⊥ %s | %v: violated %v with p = %g, expected %v", r.X, r.Y, r.Given, r.Violated, r.PValue, violated)
+		}
+	}
+
+	unknown := graph.NewDAG()
+	_ = unknown.AddEdge("A", "Z")
+	if _, err := pc.AuditIndependencies(unknown); err == nil {
+		t.Error("Expected an error for a node missing from the data")
+	}
+}
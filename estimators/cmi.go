@@ -0,0 +1,344 @@
+package estimators
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/mathext"
+)
+
+// CMIMethod selects how ConditionalMutualInformation estimates from samples
+type CMIMethod string
+
+const (
+	CMIKNN    CMIMethod = "knn"    // k-nearest-neighbor estimator, for continuous and mixed variables
+	CMIBinned CMIMethod = "binned" // Plug-in estimator on equal-frequency bins
+)
+
+// CMIOptions configures ConditionalMutualInformation and CMITest. Zero fields
+// take their defaults.
+type CMIOptions struct {
+	Method       CMIMethod // k-NN by default
+	Neighbors    int       // k of the k-NN estimator, 5 by default
+	Bins         int       // Bins per variable of the binned estimator, 5 by default
+	Permutations int       // Permutations drawn for the k-NN test's null distribution, 100 by default
+	MaxSamples   int       // Samples kept, evenly spaced through the data, 300 by default
+	Seed         int64     // Seed of the permutations
+
+	// Weights, if set, hold one weight per row; the samples kept are then
+	// drawn in proportion to them instead of evenly spaced. Invalid weights
+	// make the estimate and p-value NaN.
+	Weights []float64
+}
+
+func (opts *CMIOptions) setDefaults() {
+	if opts.Method == "" {
+		opts.Method = CMIKNN
+	}
+	if opts.Neighbors <= 0 {
+		opts.Neighbors = 5
+	}
+	if opts.Bins <= 0 {
+		opts.Bins = 5
+	}
+	if opts.Permutations <= 0 {
+		opts.Permutations = 100
+	}
+	if opts.MaxSamples <= 0 {
+		opts.MaxSamples = 300
+	}
+}
+
+// ConditionalMutualInformation estimates I(X; Y | Z) in nats, where data rows
+// hold the variables by column index and discrete variables hold their state
+// numbers. The k-NN estimator of Mesner and Shalizi handles continuous,
+// discrete and mixed variables alike, treating tied values as discrete
+// atoms; it is nearly unbiased but can be slightly negative. The binned
+// estimator cuts each variable with more distinct values than Bins into
+// equal-frequency bins and is biased upward by the binning.
+func ConditionalMutualInformation(data [][]float64, xIdx, yIdx int, zIdxs []int, opts CMIOptions) float64 {
+	opts.setDefaults()
+	data, err := sampleRows(data, opts.Weights, opts.MaxSamples)
+	if err != nil {
+		return math.NaN()
+	}
+	if len(data) < 2 {
+		return 0
+	}
+	if opts.Method == CMIBinned {
+		cmi, _ := binnedCMI(data, xIdx, yIdx, zIdxs, opts.Bins)
+		return cmi
+	}
+	dx, dy, dz := distanceMatrices(data, xIdx, yIdx, zIdxs)
+	return knnCMI(dx, dy, dz, nil, opts.Neighbors)
+}
+
+// CMITest tests X independent of Y given Z with the conditional mutual
+// information, returning the estimate and its p-value. The binned estimate
+// uses the G-test, with 2n·CMI chi-square distributed under independence;
+// the k-NN estimate is compared against local permutations that shuffle X
+// among samples with nearby values of Z, which keep its dependence on Z.
+// The k-NN test costs O(n²) per permutation in the number of samples kept.
+func CMITest(data [][]float64, xIdx, yIdx int, zIdxs []int, opts CMIOptions) (float64, float64) {
+	opts.setDefaults()
+	data, err := sampleRows(data, opts.Weights, opts.MaxSamples)
+	if err != nil {
+		return math.NaN(), math.NaN()
+	}
+	n := len(data)
+	if n < opts.Neighbors+2 {
+		return 0, 1
+	}
+	if opts.Method == CMIBinned {
+		cmi, df := binnedCMI(data, xIdx, yIdx, zIdxs, opts.Bins)
+		return cmi, chiSquarePValue(2*float64(n)*cmi, df)
+	}
+
+	dx, dy, dz := distanceMatrices(data, xIdx, yIdx, zIdxs)
+	observed := knnCMI(dx, dy, dz, nil, opts.Neighbors)
+	rng := rand.New(rand.NewSource(opts.Seed))
+	neighbors := zNeighborhoods(dz, len(zIdxs) > 0, opts.Neighbors)
+	exceed := 0
+	for b := 0; b < opts.Permutations; b++ {
+		if knnCMI(dx, dy, dz, localPermutation(neighbors, rng), opts.Neighbors) >= observed {
+			exceed++
+		}
+	}
+	return observed, float64(exceed+1) / float64(opts.Permutations+1)
+}
+
+// evenSubset keeps at most limit rows, evenly spaced through the data
+func evenSubset(data [][]float64, limit int) [][]float64 {
+	n := len(data)
+	if n <= limit {
+		return data
+	}
+	kept := make([][]float64, limit)
+	for i := range kept {
+		kept[i] = data[i*n/limit]
+	}
+	return kept
+}
+
+// distanceMatrices returns the pairwise max-norm distances between samples
+// in X, Y and Z, each variable standardized, as flat row-major n×n slices
+func distanceMatrices(data [][]float64, xIdx, yIdx int, zIdxs []int) ([]float64, []float64, []float64) {
+	distances := func(cols []int) []float64 {
+		rows := standardizedColumns(data, cols)
+		n := len(rows)
+		d := make([]float64, n*n)
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				dist := 0.0
+				for k := range rows[i] {
+					dist = math.Max(dist, math.Abs(rows[i][k]-rows[j][k]))
+				}
+				d[i*n+j], d[j*n+i] = dist, dist
+			}
+		}
+		return d
+	}
+	return distances([]int{xIdx}), distances([]int{yIdx}), distances(zIdxs)
+}
+
+// knnCMI is the Mesner-Shalizi estimate: the mean over samples of
+// ψ(k̃) - ψ(n_xz) - ψ(n_yz) + ψ(n_z), where ρ is the max-norm distance to
+// the k-th neighbor in the joint space, k̃ is k or, when ρ is zero, the
+// number of samples tied with this one, and the n count the samples closer
+// than ρ in each subspace, or tied when ρ is zero, this one included. A
+// non-nil perm reorders the samples of X.
+func knnCMI(dx, dy, dz []float64, perm []int, k int) float64 {
+	n := int(math.Sqrt(float64(len(dx))))
+	if k >= n {
+		k = n - 1
+	}
+	if perm == nil {
+		perm = make([]int, n)
+		for i := range perm {
+			perm[i] = i
+		}
+	}
+	xRow := make([]float64, n)       // Distances in X from sample i, in sample order
+	nearest := make([]float64, 0, k) // The k smallest joint distances to other samples, ascending
+	total := 0.0
+	for i := 0; i < n; i++ {
+		px := dx[perm[i]*n : (perm[i]+1)*n]
+		for j, pj := range perm {
+			xRow[j] = px[pj]
+		}
+		yRow, zRow := dy[i*n:(i+1)*n], dz[i*n:(i+1)*n]
+
+		nearest = nearest[:0]
+		ties := 0
+		for j := 0; j < n; j++ {
+			if j == i {
+				continue
+			}
+			d := math.Max(xRow[j], math.Max(yRow[j], zRow[j]))
+			if d == 0 {
+				ties++
+			}
+			if len(nearest) == k && d >= nearest[k-1] {
+				continue
+			}
+			if len(nearest) < k {
+				nearest = append(nearest, d)
+			} else {
+				nearest[k-1] = d
+			}
+			for m := len(nearest) - 1; m > 0 && nearest[m] < nearest[m-1]; m-- {
+				nearest[m], nearest[m-1] = nearest[m-1], nearest[m]
+			}
+		}
+		rho := nearest[k-1]
+		kTilde := k
+		if rho == 0 {
+			kTilde = ties
+		}
+
+		within := func(d float64) bool { return d < rho || d == 0 }
+		nxz, nyz, nz := 1, 1, 1
+		for j := 0; j < n; j++ {
+			if j == i || !within(zRow[j]) {
+				continue
+			}
+			nz++
+			if within(xRow[j]) {
+				nxz++
+			}
+			if within(yRow[j]) {
+				nyz++
+			}
+		}
+		total += mathext.Digamma(float64(kTilde)) - mathext.Digamma(float64(nxz)) - mathext.Digamma(float64(nyz)) + mathext.Digamma(float64(nz))
+	}
+	return total / float64(n)
+}
+
+// zNeighborhoods returns the k nearest samples to each sample in Z,
+// including itself, from the Z distances. With no conditioning variables
+// every sample is a neighbor of every other, so permutations are
+// unrestricted.
+func zNeighborhoods(dz []float64, conditioned bool, k int) [][]int {
+	n := int(math.Sqrt(float64(len(dz))))
+	neighbors := make([][]int, n)
+	if !conditioned {
+		return neighbors
+	}
+	order := make([]int, n)
+	for i := range neighbors {
+		for j := range order {
+			order[j] = j
+		}
+		row := dz[i*n : (i+1)*n]
+		sort.SliceStable(order, func(a, b int) bool { return row[order[a]] < row[order[b]] })
+		neighbors[i] = append([]int(nil), order[:min(k, n)]...)
+	}
+	return neighbors
+}
+
+// localPermutation returns a permutation that maps each sample to one of its
+// Z-neighbors, drawing unused neighbors where possible as in Runge's
+// conditional permutation scheme. Samples whose neighbors are all taken
+// reuse one.
+func localPermutation(neighbors [][]int, rng *rand.Rand) []int {
+	n := len(neighbors)
+	if neighbors[0] == nil {
+		return rng.Perm(n)
+	}
+	perm := make([]int, n)
+	used := make([]bool, n)
+	for _, i := range rng.Perm(n) {
+		candidates := neighbors[i]
+		choice := candidates[rng.Intn(len(candidates))]
+		for _, c := range rng.Perm(len(candidates)) {
+			if !used[candidates[c]] {
+				choice = candidates[c]
+				break
+			}
+		}
+		used[choice] = true
+		perm[i] = choice
+	}
+	return perm
+}
+
+// binnedCMI returns the plug-in CMI of the binned variables and the degrees
+// of freedom of its G-test, (|X| - 1)(|Y| - 1) per observed configuration of Z
+func binnedCMI(data [][]float64, xIdx, yIdx int, zIdxs []int, bins int) (float64, float64) {
+	x := binColumn(data, xIdx, bins)
+	y := binColumn(data, yIdx, bins)
+	zCols := make([][]int, len(zIdxs))
+	for k, c := range zIdxs {
+		zCols[k] = binColumn(data, c, bins)
+	}
+
+	// Configurations of Z are numbered with the last variable changing fastest
+	z := make([]int, len(data))
+	stride := 1
+	for k := len(zCols) - 1; k >= 0; k-- {
+		states := 0
+		for i, v := range zCols[k] {
+			z[i] += v * stride
+			states = max(states, v+1)
+		}
+		stride *= states
+	}
+
+	xyz := make(map[[3]int]float64)
+	xz := make(map[[2]int]float64)
+	yz := make(map[[2]int]float64)
+	zc := make(map[int]float64)
+	xStates, yStates := make(map[int]bool), make(map[int]bool)
+	for i := range data {
+		xyz[[3]int{z[i], x[i], y[i]}]++
+		xz[[2]int{z[i], x[i]}]++
+		yz[[2]int{z[i], y[i]}]++
+		zc[z[i]]++
+		xStates[x[i]] = true
+		yStates[y[i]] = true
+	}
+
+	n := float64(len(data))
+	cmi := 0.0
+	for c, count := range xyz {
+		cmi += count / n * math.Log(count*zc[c[0]]/(xz[[2]int{c[0], c[1]}]*yz[[2]int{c[0], c[2]}]))
+	}
+	df := float64((len(xStates) - 1) * (len(yStates) - 1) * len(zc))
+	return math.Max(cmi, 0), df
+}
+
+// binColumn returns the bin of each value of a column: its rank among the
+// distinct values when there are at most bins of them, otherwise its
+// equal-frequency bin
+func binColumn(data [][]float64, col, bins int) []int {
+	values := make([]float64, len(data))
+	for i, row := range data {
+		values[i] = row[col]
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	distinct := sorted[:0:0]
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			distinct = append(distinct, v)
+		}
+	}
+
+	out := make([]int, len(values))
+	if len(distinct) <= bins {
+		for i, v := range values {
+			out[i] = sort.SearchFloat64s(distinct, v)
+		}
+		return out
+	}
+	cuts := make([]float64, bins-1)
+	for b := range cuts {
+		cuts[b] = sorted[(b+1)*len(sorted)/bins]
+	}
+	for i, v := range values {
+		out[i] = sort.Search(len(cuts), func(b int) bool { return v < cuts[b] })
+	}
+	return out
+}
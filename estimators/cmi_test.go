@@ -0,0 +1,103 @@
+package estimators
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// sampleGaussianCMI draws columns X, Y with correlation rho, an independent
+// N, and a discrete D in {0, 1, 2} with U and V each D plus noise
+func sampleGaussianCMI(n int, rho float64, seed int64) [][]float64 {
+	r := rand.New(rand.NewSource(seed))
+	data := make([][]float64, n)
+	for i := range data {
+		x := r.NormFloat64()
+		d := float64(r.Intn(3))
+		data[i] = []float64{
+			x,
+			rho*x + math.Sqrt(1-rho*rho)*r.NormFloat64(),
+			r.NormFloat64(),
+			d,
+			d + 0.3*r.NormFloat64(),
+			d + 0.3*r.NormFloat64(),
+		}
+	}
+	return data
+}
+
+func TestConditionalMutualInformation(t *testing.T) {
+	const rho = 0.8
+	data := sampleGaussianCMI(1000, rho, 1)
+	knn := CMIOptions{MaxSamples: 1000}
+	binned := CMIOptions{Method: CMIBinned, MaxSamples: 1000}
+
+	// I(X; Y) = -½ log(1 - ρ²) for a bivariate Gaussian
+	want := -0.5 * math.Log(1-rho*rho)
+	if got := ConditionalMutualInformation(data, 0, 1, nil, knn); math.Abs(got-want) > 0.05 {
+		t.Errorf("k-NN I(X; Y) = %f, closed form %f", got, want)
+	}
+	// Binning loses information, so the plug-in estimate falls short
+	if got := ConditionalMutualInformation(data, 0, 1, nil, binned); got > want || got < want/2 {
+		t.Errorf("Binned I(X; Y) = %f, expected somewhat below %f", got, want)
+	}
+
+	for name, opts := range map[string]CMIOptions{"k-NN": knn, "binned": binned} {
+		if got := ConditionalMutualInformation(data, 0, 2, nil, opts); math.Abs(got) > 0.02 {
+			t.Errorf("%s I(X; N) = %f, expected near 0", name, got)
+		}
+		// U and V share only D, so they are independent given it
+		if got := ConditionalMutualInformation(data, 4, 5, nil, opts); got < 0.3 {
+			t.Errorf("%s I(U; V) = %f, expected well above 0", name, got)
+		}
+		if got := ConditionalMutualInformation(data, 4, 5, []int{3}, opts); math.Abs(got) > 0.03 {
+			t.Errorf("%s I(U; V | D) = %f, expected near 0", name, got)
+		}
+	}
+}
+
+func TestCMITest(t *testing.T) {
+	data := sampleGaussianCMI(300, 0.5, 2)
+	for name, opts := range map[string]CMIOptions{"k-NN": {Seed: 1}, "binned": {Method: CMIBinned}} {
+		if _, p := CMITest(data, 0, 1, nil, opts); p >= 0.01 {
+			t.Errorf("%s: expected X and Y to be dependent, got p = %f", name, p)
+		}
+		if _, p := CMITest(data, 0, 2, nil, opts); p < 0.05 {
+			t.Errorf("%s: expected X and N to be independent, got p = %f", name, p)
+		}
+		if _, p := CMITest(data, 4, 5, []int{3}, opts); p < 0.05 {
+			t.Errorf("%s: expected U and V to be independent given D, got p = %f", name, p)
+		}
+	}
+}
+
+func TestBinColumn(t *testing.T) {
+	data := [][]float64{{3}, {1}, {3}, {2}}
+	// Few distinct values keep their ranks
+	if got := binColumn(data, 0, 5); !equalInts(got, []int{2, 0, 2, 1}) {
+		t.Errorf("Expected ranks [2 0 2 1], got %v", got)
+	}
+	data = make([][]float64, 100)
+	for i := range data {
+		data[i] = []float64{float64(i)}
+	}
+	counts := make([]int, 4)
+	for _, b := range binColumn(data, 0, 4) {
+		counts[b]++
+	}
+	if !equalInts(counts, []int{25, 25, 25, 25}) {
+		t.Errorf("Expected equal-frequency bins, got counts %v", counts)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package estimators
+
+import (
+	"fmt"
+
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// dagState is the mutable graph of a local search, with parent and child
+// sets for constant-time edge tests
+type dagState struct {
+	variables []string
+	parents   map[string]map[string]bool
+	children  map[string]map[string]bool
+}
+
+// newDAGState starts from start, or the empty graph if start is nil
+func newDAGState(variables []string, start *graph.DAG) (*dagState, error) {
+	g := &dagState{
+		variables: variables,
+		parents:   make(map[string]map[string]bool, len(variables)),
+		children:  make(map[string]map[string]bool, len(variables)),
+	}
+	for _, v := range variables {
+		g.parents[v] = make(map[string]bool)
+		g.children[v] = make(map[string]bool)
+	}
+	if start != nil {
+		for _, e := range start.Edges() {
+			if g.parents[e[0]] == nil || g.parents[e[1]] == nil {
+				return nil, fmt.Errorf("start edge %s -> %s is not between variables of the data", e[0], e[1])
+			}
+			g.add(e[0], e[1])
+		}
+	}
+	return g, nil
+}
+
+func (g *dagState) add(parent, child string) {
+	g.parents[child][parent] = true
+	g.children[parent][child] = true
+}
+
+func (g *dagState) remove(parent, child string) {
+	delete(g.parents[child], parent)
+	delete(g.children[parent], child)
+}
+
+func (g *dagState) reverse(parent, child string) {
+	g.remove(parent, child)
+	g.add(child, parent)
+}
+
+// parentList returns the parents of v with add included and drop left out;
+// empty strings add or drop nothing
+func (g *dagState) parentList(v, add, drop string) []string {
+	list := make([]string, 0, len(g.parents[v])+1)
+	for p := range g.parents[v] {
+		if p != drop {
+			list = append(list, p)
+		}
+	}
+	if add != "" {
+		list = append(list, add)
+	}
+	return list
+}
+
+// reachable reports a directed path from src to dst, ignoring the edge
+// skipFrom -> skipTo
+func (g *dagState) reachable(src, dst, skipFrom, skipTo string) bool {
+	visited := map[string]bool{src: true}
+	stack := []string{src}
+	for len(stack) > 0 {
+		u := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for c := range g.children[u] {
+			if u == skipFrom && c == skipTo {
+				continue
+			}
+			if c == dst {
+				return true
+			}
+			if !visited[c] {
+				visited[c] = true
+				stack = append(stack, c)
+			}
+		}
+	}
+	return false
+}
+
+// canAdd reports whether parent -> child keeps the graph acyclic
+func (g *dagState) canAdd(parent, child string) bool {
+	return !g.reachable(child, parent, "", "")
+}
+
+// canReverse reports whether turning parent -> child around keeps the graph
+// acyclic
+func (g *dagState) canReverse(parent, child string) bool {
+	return !g.reachable(parent, child, parent, child)
+}
+
+// dag copies the state into a graph.DAG
+func (g *dagState) dag() *graph.DAG {
+	dag := graph.NewDAG()
+	for _, v := range g.variables {
+		dag.AddNode(v)
+	}
+	for _, v := range g.variables {
+		for p := range g.parents[v] {
+			_ = dag.AddEdge(p, v) // The state is kept acyclic
+		}
+	}
+	return dag
+}
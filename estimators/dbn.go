@@ -0,0 +1,215 @@
+package estimators
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// DBNEstimator learns the structure of a two-slice temporal Bayesian network
+// (2-TBN) from discrete time series. Each time step is joined with the MaxLag
+// steps before it into one row, and hill climbing searches for the parents of
+// the current slice among the current and lagged variables. Lagged copies
+// never take parents, so edges only run forward in time or within the current
+// slice. The initial network is learned separately from the first step of
+// each sequence.
+type DBNEstimator struct {
+	Sequences [][]map[string]int // Time series, one map per step
+	Variables []string
+	MaxLag    int // Number of earlier steps a variable may depend on
+
+	// NewScore builds the structure score from the unrolled rows; BIC by
+	// default
+	NewScore    func(data []map[string]int) Score
+	MaxIndegree int // Maximum parents per current-slice node, 0 for no limit
+
+	// Progress, if set, is called after each move of the transition search
+	// with the DAG score
+	Progress ProgressFunc
+}
+
+// LaggedEdge is an edge from a variable lag steps back into the current slice
+type LaggedEdge struct {
+	Parent string
+	Lag    int
+	Child  string
+}
+
+// DBNStructure is a learned 2-TBN
+type DBNStructure struct {
+	Variables []string
+	MaxLag    int
+	Initial   *graph.DAG   // Network of the first step
+	Intra     [][2]string  // Edges within the current slice
+	Inter     []LaggedEdge // Edges from earlier steps
+	Unrolled  *graph.DAG   // Transition DAG over the current and lagged nodes, named by LagName
+	Score     float64      // Score of the transition DAG
+}
+
+// NewDBNEstimator creates a DBN structure learner with a maximum lag of 1
+func NewDBNEstimator(sequences [][]map[string]int) *DBNEstimator {
+	seen := make(map[string]bool)
+	var variables []string
+	for _, sequence := range sequences {
+		for _, step := range sequence {
+			for v := range step {
+				if !seen[v] {
+					seen[v] = true
+					variables = append(variables, v)
+				}
+			}
+		}
+	}
+	sort.Strings(variables)
+
+	return &DBNEstimator{
+		Sequences: sequences,
+		Variables: variables,
+		MaxLag:    1,
+		NewScore: func(data []map[string]int) Score {
+			return NewBICScore(data)
+		},
+	}
+}
+
+// SetMaxLag sets the number of earlier steps a variable may depend on
+func (e *DBNEstimator) SetMaxLag(lag int) {
+	e.MaxLag = lag
+}
+
+// SetMaxIndegree limits the number of parents of each current-slice node
+func (e *DBNEstimator) SetMaxIndegree(n int) {
+	e.MaxIndegree = n
+}
+
+// SetProgress sets the hook called after each move of the transition search
+func (e *DBNEstimator) SetProgress(hook ProgressFunc) {
+	e.Progress = hook
+}
+
+// LagName is the node name of variable lag steps back in an unrolled DAG;
+// lag 0 is the variable itself
+func LagName(variable string, lag int) string {
+	if lag == 0 {
+		return variable
+	}
+	return fmt.Sprintf("%s[t-%d]", variable, lag)
+}
+
+// parseLagName splits a node name made by LagName
+func parseLagName(name string) (string, int) {
+	i := strings.LastIndex(name, "[t-")
+	if i < 0 || !strings.HasSuffix(name, "]") {
+		return name, 0
+	}
+	lag, err := strconv.Atoi(name[i+3 : len(name)-1])
+	if err != nil {
+		return name, 0
+	}
+	return name[:i], lag
+}
+
+// Estimate learns the initial and transition networks
+func (e *DBNEstimator) Estimate() (*DBNStructure, error) {
+	if len(e.Variables) == 0 {
+		return nil, fmt.Errorf("no variables to learn a structure over")
+	}
+	if e.MaxLag < 1 {
+		return nil, fmt.Errorf("max lag must be at least 1, got %d", e.MaxLag)
+	}
+
+	isCurrent := make(map[string]bool, len(e.Variables))
+	for _, v := range e.Variables {
+		isCurrent[v] = true
+	}
+	var first, rows []map[string]int
+	for _, sequence := range e.Sequences {
+		if len(sequence) > 0 {
+			first = append(first, sequence[0])
+		}
+		for t := e.MaxLag; t < len(sequence); t++ {
+			row := make(map[string]int, len(e.Variables)*(e.MaxLag+1))
+			for lag := 0; lag <= e.MaxLag; lag++ {
+				for v, value := range sequence[t-lag] {
+					row[LagName(v, lag)] = value
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no sequence is longer than the max lag of %d", e.MaxLag)
+	}
+
+	initial := &HillClimbEstimator{
+		Data:        first,
+		Variables:   e.Variables,
+		Cardinality: dataCardinality(first),
+		Score:       e.NewScore(first),
+		MaxIndegree: e.MaxIndegree,
+		Epsilon:     1e-8,
+	}
+	initialDAG, err := initial.Estimate()
+	if err != nil {
+		return nil, fmt.Errorf("initial network: %w", err)
+	}
+
+	nodes := make([]string, 0, len(e.Variables)*(e.MaxLag+1))
+	for lag := 0; lag <= e.MaxLag; lag++ {
+		for _, v := range e.Variables {
+			nodes = append(nodes, LagName(v, lag))
+		}
+	}
+	transition := &HillClimbEstimator{
+		Data:        rows,
+		Variables:   nodes,
+		Cardinality: dataCardinality(rows),
+		Score:       NewScoreCache(e.NewScore(rows)),
+		MaxIndegree: e.MaxIndegree,
+		Epsilon:     1e-8,
+		Progress:    e.Progress,
+		allow: func(parent, child string) bool {
+			return isCurrent[child]
+		},
+	}
+	unrolled, err := transition.Estimate()
+	if err != nil {
+		return nil, fmt.Errorf("transition network: %w", err)
+	}
+
+	result := &DBNStructure{
+		Variables: append([]string(nil), e.Variables...),
+		MaxLag:    e.MaxLag,
+		Initial:   initialDAG,
+		Unrolled:  unrolled,
+		Score:     DAGScore(transition.Score, unrolled),
+	}
+	for _, edge := range unrolled.Edges() {
+		parent, lag := parseLagName(edge[0])
+		if lag == 0 {
+			result.Intra = append(result.Intra, edge)
+		} else {
+			result.Inter = append(result.Inter, LaggedEdge{Parent: parent, Lag: lag, Child: edge[1]})
+		}
+	}
+	sort.Slice(result.Intra, func(a, b int) bool {
+		if result.Intra[a][0] != result.Intra[b][0] {
+			return result.Intra[a][0] < result.Intra[b][0]
+		}
+		return result.Intra[a][1] < result.Intra[b][1]
+	})
+	sort.Slice(result.Inter, func(a, b int) bool {
+		x, y := result.Inter[a], result.Inter[b]
+		if x.Lag != y.Lag {
+			return x.Lag < y.Lag
+		}
+		if x.Parent != y.Parent {
+			return x.Parent < y.Parent
+		}
+		return x.Child < y.Child
+	})
+	return result, nil
+}
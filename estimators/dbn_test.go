@@ -0,0 +1,63 @@
+package estimators
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestDBNEstimator(t *testing.T) {
+	// X persists from step to step and drives Y one step later; W is noise
+	r := rand.New(rand.NewSource(1))
+	flip := func(v int) int {
+		if r.Float64() < 0.1 {
+			return 1 - v
+		}
+		return v
+	}
+	sequences := make([][]map[string]int, 40)
+	for i := range sequences {
+		step := map[string]int{"X": r.Intn(2), "Y": r.Intn(2), "W": r.Intn(2)}
+		sequence := []map[string]int{step}
+		for len(sequence) < 100 {
+			prev := sequence[len(sequence)-1]
+			sequence = append(sequence, map[string]int{"X": flip(prev["X"]), "Y": flip(prev["X"]), "W": r.Intn(2)})
+		}
+		sequences[i] = sequence
+	}
+
+	structure, err := NewDBNEstimator(sequences).Estimate()
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	want := []LaggedEdge{{Parent: "X", Lag: 1, Child: "X"}, {Parent: "X", Lag: 1, Child: "Y"}}
+	if !reflect.DeepEqual(structure.Inter, want) {
+		t.Errorf("Expected transition edges %v, got %v", want, structure.Inter)
+	}
+	if len(structure.Intra) != 0 {
+		t.Errorf("Expected no edges within a slice, got %v", structure.Intra)
+	}
+	for _, e := range structure.Unrolled.Edges() {
+		if _, lag := parseLagName(e[1]); lag != 0 {
+			t.Errorf("Lagged node %s took parent %s", e[1], e[0])
+		}
+	}
+
+	e := NewDBNEstimator(sequences)
+	e.SetMaxLag(0)
+	if _, err := e.Estimate(); err == nil {
+		t.Error("Expected an error for a max lag of 0")
+	}
+}
+
+func TestLagName(t *testing.T) {
+	for _, c := range []struct {
+		variable string
+		lag      int
+	}{{"X", 0}, {"X", 1}, {"Rain[t-3]x", 2}, {"Y", 12}} {
+		name := LagName(c.variable, c.lag)
+		if v, lag := parseLagName(name); v != c.variable || lag != c.lag {
+			t.Errorf("parseLagName(%q) = %q, %d; expected %q, %d", name, v, lag, c.variable, c.lag)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package estimators
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// HillClimbEstimator learns a DAG by greedy local search over edge
+// additions, removals and reversals, taking the best-scoring move until none
+// improves the score. With a tabu list it takes the best move that does not
+// undo a recent one even when the score drops, to walk out of local optima,
+// and returns the best DAG seen. Family scores are cached, so each move only
+// scores the one or two families it changes.
+type HillClimbEstimator struct {
+	Data        []map[string]int
+	Variables   []string
+	Cardinality map[string]int
+	Score       Score // Decomposable score; BIC on Data by default
+
+	MaxIndegree   int     // Maximum parents per node, 0 for no limit
+	MaxIterations int     // Maximum moves, 0 for no limit
+	Epsilon       float64 // Minimum score improvement of a move
+	TabuLength    int     // Number of recent moves that may not be undone, 0 for plain hill climbing
+	Patience      int     // Moves without a new best score before a tabu search stops, TabuLength if 0
+
+	// Progress, if set, is called after each move with the DAG score
+	Progress ProgressFunc
+
+	// allow, if set, restricts the edges the search may add or reverse into
+	allow func(parent, child string) bool
+}
+
+// NewHillClimb creates a hill-climbing estimator scoring with BIC
+func NewHillClimb(data []map[string]int) *HillClimbEstimator {
+	cardinality := dataCardinality(data)
+	variables := make([]string, 0, len(cardinality))
+	for v := range cardinality {
+		variables = append(variables, v)
+	}
+	sort.Strings(variables)
+
+	return &HillClimbEstimator{
+		Data:        data,
+		Variables:   variables,
+		Cardinality: cardinality,
+		Score:       &BICScore{Data: data, Cardinality: cardinality},
+		Epsilon:     1e-8,
+	}
+}
+
+// SetScore sets the structure score. Pass a shared *ScoreCache to reuse
+// family scores across runs.
+func (hc *HillClimbEstimator) SetScore(score Score) {
+	hc.Score = score
+}
+
+// SetWeights weights the samples of the score, one weight per sample, for
+// bootstrap aggregation, importance weighting or class rebalancing. The
+// score must be a BICScore or BDeuScore, so call it after SetScore.
+func (hc *HillClimbEstimator) SetWeights(weights []float64) error {
+	return weightScore(hc.Score, weights)
+}
+
+// SetMaxIndegree limits the number of parents of each node
+func (hc *HillClimbEstimator) SetMaxIndegree(n int) {
+	hc.MaxIndegree = n
+}
+
+// SetTabuLength sets how many recent moves may not be undone
+func (hc *HillClimbEstimator) SetTabuLength(n int) {
+	hc.TabuLength = n
+}
+
+// SetPatience sets how many moves a tabu search makes without improving on
+// the best score before it stops
+func (hc *HillClimbEstimator) SetPatience(n int) {
+	hc.Patience = n
+}
+
+// SetProgress sets the hook called after each move
+func (hc *HillClimbEstimator) SetProgress(hook ProgressFunc) {
+	hc.Progress = hook
+}
+
+// Estimate learns a DAG starting from the empty graph
+func (hc *HillClimbEstimator) Estimate() (*graph.DAG, error) {
+	return hc.EstimateFrom(nil)
+}
+
+// move is one local change to the graph; for reversals the edge is the one
+// removed
+type move struct {
+	op           byte // '+' add, '-' remove, 'r' reverse
+	parent, node string
+}
+
+// inverse returns the move that undoes m
+func (m move) inverse() move {
+	switch m.op {
+	case '+':
+		return move{'-', m.parent, m.node}
+	case '-':
+		return move{'+', m.parent, m.node}
+	default:
+		return move{'r', m.node, m.parent}
+	}
+}
+
+// EstimateFrom learns a DAG starting from start, or from the empty graph if
+// start is nil. Nodes of start that are not variables of the data are an
+// error.
+func (hc *HillClimbEstimator) EstimateFrom(start *graph.DAG) (*graph.DAG, error) {
+	dag, _, _, err := hc.climb(start)
+	return dag, err
+}
+
+// climb runs the search from start and returns the best DAG seen and its
+// score, with the score before the first move and after each move
+func (hc *HillClimbEstimator) climb(start *graph.DAG) (*graph.DAG, float64, []float64, error) {
+	if len(hc.Variables) == 0 {
+		return nil, 0, nil, fmt.Errorf("no variables to learn a structure over")
+	}
+	score, ok := hc.Score.(*ScoreCache)
+	if !ok {
+		score = NewScoreCache(hc.Score)
+	}
+
+	g, err := newDAGState(hc.Variables, start)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	local := func(v string) float64 { return score.LocalScore(v, g.parentList(v, "", "")) }
+	fits := func(v string, extra int) bool {
+		return hc.MaxIndegree <= 0 || len(g.parents[v])+extra <= hc.MaxIndegree
+	}
+	allowed := func(parent, child string) bool {
+		return hc.allow == nil || hc.allow(parent, child)
+	}
+
+	current := make(map[string]float64, len(hc.Variables))
+	total := 0.0
+	for _, v := range hc.Variables {
+		current[v] = local(v)
+		total += current[v]
+	}
+	trajectory := []float64{total}
+	best, bestTotal := g.dag(), total
+
+	reporter := newProgressReporter(hc.Progress)
+	patience := hc.Patience
+	if patience <= 0 {
+		patience = hc.TabuLength
+	}
+	stale := 0
+
+	var tabu []move
+	isTabu := func(m move) bool {
+		for _, t := range tabu {
+			if t == m {
+				return true
+			}
+		}
+		return false
+	}
+
+	for iter := 1; hc.MaxIterations <= 0 || iter <= hc.MaxIterations; iter++ {
+		// A tabu search takes the best allowed move even when it worsens
+		// the score
+		var chosen move
+		bestDelta := hc.Epsilon
+		if hc.TabuLength > 0 {
+			bestDelta = math.Inf(-1)
+		}
+		found := false
+		consider := func(m move, delta float64) {
+			if delta > bestDelta && !isTabu(m) {
+				chosen, bestDelta, found = m, delta, true
+			}
+		}
+
+		for _, x := range hc.Variables {
+			for _, y := range hc.Variables {
+				if x == y {
+					continue
+				}
+				switch {
+				case g.parents[y][x]:
+					removeDelta := score.LocalScore(y, g.parentList(y, "", x)) - current[y]
+					consider(move{'-', x, y}, removeDelta)
+					if fits(x, 1) && allowed(y, x) && g.canReverse(x, y) {
+						consider(move{'r', x, y}, removeDelta+score.LocalScore(x, g.parentList(x, y, ""))-current[x])
+					}
+				case !g.parents[x][y]:
+					if fits(y, 1) && allowed(x, y) && g.canAdd(x, y) {
+						consider(move{'+', x, y}, score.LocalScore(y, g.parentList(y, x, ""))-current[y])
+					}
+				}
+			}
+		}
+		if !found {
+			break
+		}
+
+		switch chosen.op {
+		case '+':
+			g.add(chosen.parent, chosen.node)
+		case '-':
+			g.remove(chosen.parent, chosen.node)
+		case 'r':
+			g.reverse(chosen.parent, chosen.node)
+			current[chosen.parent] = local(chosen.parent)
+		}
+		current[chosen.node] = local(chosen.node)
+		total += bestDelta
+		trajectory = append(trajectory, total)
+		reporter.report(iter, total)
+
+		if hc.TabuLength == 0 {
+			bestTotal = total
+			continue
+		}
+		tabu = append(tabu, chosen.inverse())
+		if len(tabu) > hc.TabuLength {
+			tabu = tabu[1:]
+		}
+		if total > bestTotal+hc.Epsilon {
+			best, bestTotal, stale = g.dag(), total, 0
+			continue
+		}
+		stale++
+		if stale >= patience {
+			break
+		}
+	}
+
+	if hc.TabuLength == 0 {
+		best = g.dag()
+	}
+	return best, bestTotal, trajectory, nil
+}
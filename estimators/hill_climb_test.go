@@ -0,0 +1,202 @@
+package estimators
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// sampleNoisyOR draws binary samples in which roots are fair coins and every
+// other node is the OR of its parents, flipped with probability noise. Nodes
+// must be listed parents first.
+func sampleNoisyOR(n int, noise float64, seed int64, order []string, parents map[string][]string) []map[string]int {
+	r := rand.New(rand.NewSource(seed))
+	data := make([]map[string]int, n)
+	for i := range data {
+		s := make(map[string]int, len(order))
+		for _, v := range order {
+			value := 0
+			if len(parents[v]) == 0 {
+				value = r.Intn(2)
+			} else {
+				for _, p := range parents[v] {
+					value |= s[p]
+				}
+				if r.Float64() < noise {
+					value = 1 - value
+				}
+			}
+			s[v] = value
+		}
+		data[i] = s
+	}
+	return data
+}
+
+// colliderData samples A -> C <- B, C -> D
+func colliderData(n int, seed int64) []map[string]int {
+	return sampleNoisyOR(n, 0.1, seed, []string{"A", "B", "C", "D"},
+		map[string][]string{"C": {"A", "B"}, "D": {"C"}})
+}
+
+// edgeSet lists the edges of a DAG as sorted "X->Y" strings
+func edgeSet(dag *graph.DAG) []string {
+	var edges []string
+	for _, e := range dag.Edges() {
+		edges = append(edges, e[0]+"->"+e[1])
+	}
+	sort.Strings(edges)
+	return edges
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHillClimbRecoversStructure(t *testing.T) {
+	// The collider is the only DAG in its equivalence class, so the search
+	// must find it edge for edge
+	dag, err := NewHillClimb(colliderData(3000, 2)).Estimate()
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	want := []string{"A->C", "B->C", "C->D"}
+	if got := edgeSet(dag); !equalStrings(got, want) {
+		t.Errorf("Expected edges %v, got %v", want, got)
+	}
+}
+
+func TestScoreCache(t *testing.T) {
+	data := colliderData(500, 2)
+	families := [][]string{{}, {"A"}, {"A", "B"}, {"B", "A"}, {"A", "B", "D"}}
+	for _, score := range []Score{NewBICScore(data), NewBDeuScore(data, 1)} {
+		cache := NewScoreCache(score)
+		for _, parents := range families {
+			want := score.LocalScore("C", parents)
+			if got := cache.LocalScore("C", parents); math.Abs(got-want) > 1e-9 {
+				t.Errorf("Cached score of C | %v = %f, uncached %f", parents, got, want)
+			}
+		}
+		// Both orders of {A, B} share one entry
+		if cache.Evaluations() != len(families)-1 {
+			t.Errorf("Expected %d evaluations, got %d", len(families)-1, cache.Evaluations())
+		}
+	}
+
+	hc := NewHillClimb(data)
+	dag, _ := hc.Estimate()
+	if cached, plain := DAGScore(NewScoreCache(hc.Score), dag), DAGScore(hc.Score, dag); math.Abs(cached-plain) > 1e-9 {
+		t.Errorf("Cached DAG score %f, uncached %f", cached, plain)
+	}
+}
+
+func TestHillClimbMaxIndegree(t *testing.T) {
+	hc := NewHillClimb(colliderData(3000, 3))
+	hc.SetMaxIndegree(1)
+	dag, err := hc.Estimate()
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	for _, v := range dag.Nodes() {
+		if n := len(dag.Parents(v)); n > 1 {
+			t.Errorf("%s has %d parents, limit is 1", v, n)
+		}
+	}
+	if len(dag.Edges()) == 0 {
+		t.Error("Expected the limited search to still add edges")
+	}
+}
+
+func TestHillClimbAllow(t *testing.T) {
+	hc := NewHillClimb(colliderData(3000, 4))
+	hc.allow = func(parent, child string) bool {
+		return !(parent == "C" && child == "D") && !(parent == "D" && child == "C")
+	}
+	dag, err := hc.Estimate()
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	if dag.HasEdge("C", "D") || dag.HasEdge("D", "C") {
+		t.Errorf("Blocked edge between C and D was added: %v", edgeSet(dag))
+	}
+}
+
+func TestHillClimbTabu(t *testing.T) {
+	// Plain hill climbing stops at a local optimum on this sample
+	data := colliderData(3000, 1)
+	want := []string{"A->C", "B->C", "C->D"}
+	plain, _ := NewHillClimb(data).Estimate()
+	if equalStrings(edgeSet(plain), want) {
+		t.Fatalf("Expected plain hill climbing to miss the collider, got %v", edgeSet(plain))
+	}
+
+	hc := NewHillClimb(data)
+	hc.SetTabuLength(10)
+	hc.SetPatience(20)
+	dag, best, trajectory, err := hc.climb(nil)
+	if err != nil {
+		t.Fatalf("climb failed: %v", err)
+	}
+	if got := edgeSet(dag); !equalStrings(got, want) {
+		t.Errorf("Expected tabu search to find %v, got %v", want, got)
+	}
+
+	worsened := false
+	highest := trajectory[0]
+	for i := 1; i < len(trajectory); i++ {
+		worsened = worsened || trajectory[i] < trajectory[i-1]
+		highest = math.Max(highest, trajectory[i])
+	}
+	if !worsened {
+		t.Error("Expected the tabu search to take worsening moves")
+	}
+	if math.Abs(best-highest) > 1e-9 {
+		t.Errorf("Returned score %f, best along the trajectory %f", best, highest)
+	}
+	if got := DAGScore(hc.Score, dag); math.Abs(got-best) > 1e-6 {
+		t.Errorf("Returned DAG scores %f, reported %f", got, best)
+	}
+	// Patience stops the search after a run of moves without a new best
+	if len(trajectory) > 100 {
+		t.Errorf("Expected patience to stop the search, took %d moves", len(trajectory)-1)
+	}
+}
+
+func TestDAGState(t *testing.T) {
+	g, err := newDAGState([]string{"A", "B", "C"}, nil)
+	if err != nil {
+		t.Fatalf("newDAGState failed: %v", err)
+	}
+	g.add("A", "B")
+	g.add("B", "C")
+	if g.canAdd("C", "A") {
+		t.Error("C -> A closes a cycle")
+	}
+	if !g.canAdd("A", "C") {
+		t.Error("A -> C keeps the graph acyclic")
+	}
+	if !g.canReverse("A", "B") {
+		t.Error("Reversing A -> B keeps the graph acyclic")
+	}
+	g.add("A", "C")
+	if g.canReverse("A", "C") {
+		t.Error("Reversing A -> C closes the cycle A -> B -> C -> A")
+	}
+
+	start := graph.NewDAG()
+	_ = start.AddEdge("A", "Z")
+	if _, err := newDAGState([]string{"A", "B"}, start); err == nil {
+		t.Error("Expected an error for a start edge outside the variables")
+	}
+}
@@ -2,12 +2,22 @@
 package estimators
 
 import (
+	"fmt"
 	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
 )
 
 // ChiSquareTest performs a chi-square test for conditional independence
 // Tests if X is independent of Y given Z in the data
 func ChiSquareTest(data []map[string]int, x, y string, z []string, cardinality map[string]int) (float64, float64) {
+	return WeightedChiSquareTest(data, nil, x, y, z, cardinality)
+}
+
+// WeightedChiSquareTest is ChiSquareTest with each sample counted by its
+// weight, one per sample; nil weights count every sample once
+func WeightedChiSquareTest(data []map[string]int, weights []float64, x, y string, z []string, cardinality map[string]int) (float64, float64) {
 	// Build contingency table
 	// Dimensions: [x_states][y_states][z_states_combination]
 
@@ -30,7 +40,7 @@ func ChiSquareTest(data []map[string]int, x, y string, z []string, cardinality m
 
 	totalCounts := make([]float64, zCard)
 
-	for _, sample := range data {
+	for s, sample := range data {
 		xVal, xOk := sample[x]
 		yVal, yOk := sample[y]
 
@@ -57,8 +67,9 @@ func ChiSquareTest(data []map[string]int, x, y string, z []string, cardinality m
 			continue
 		}
 
-		counts[xVal][yVal][zIdx]++
-		totalCounts[zIdx]++
+		w := sampleWeight(weights, s)
+		counts[xVal][yVal][zIdx] += w
+		totalCounts[zIdx] += w
 	}
 
 	// Calculate chi-square statistic
@@ -386,3 +397,158 @@ func normalCDF(x float64) float64 {
 	// Using error function approximation
 	return 0.5 * (1 + math.Erf(x/math.Sqrt(2)))
 }
+
+// CorrelationType selects the correlation behind a continuous independence
+// test
+type CorrelationType string
+
+const (
+	CorrelationPearson  CorrelationType = "pearson"  // Linear correlation, for Gaussian data
+	CorrelationSpearman CorrelationType = "spearman" // Correlation of ranks, for monotone relationships
+	CorrelationKendall  CorrelationType = "kendall"  // Kendall's tau mapped to sin(πτ/2), for monotone relationships
+)
+
+// SpearmanCorrelation calculates Spearman's rank correlation coefficient, the
+// Pearson correlation of the ranks with ties given their average rank
+func SpearmanCorrelation(x, y []float64) float64 {
+	if len(x) != len(y) || len(x) == 0 {
+		return 0.0
+	}
+	return PearsonCorrelation(ranks(x), ranks(y))
+}
+
+// KendallTau calculates Kendall's tau-b, the excess of concordant over
+// discordant pairs corrected for ties. It compares every pair, so it costs
+// O(n²) in the number of samples.
+func KendallTau(x, y []float64) float64 {
+	return kendallTau(x, y, nil)
+}
+
+// kendallTau is KendallTau with each pair counted by the product of its
+// samples' weights
+func kendallTau(x, y, weights []float64) float64 {
+	if len(x) != len(y) || len(x) < 2 {
+		return 0.0
+	}
+	var concordant, discordant, tiedX, tiedY float64
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			w := sampleWeight(weights, i) * sampleWeight(weights, j)
+			dx, dy := x[i]-x[j], y[i]-y[j]
+			switch {
+			case dx == 0 && dy == 0:
+			case dx == 0:
+				tiedX += w
+			case dy == 0:
+				tiedY += w
+			case (dx > 0) == (dy > 0):
+				concordant += w
+			default:
+				discordant += w
+			}
+		}
+	}
+	denom := math.Sqrt((concordant + discordant + tiedX) * (concordant + discordant + tiedY))
+	if denom == 0 {
+		return 0.0
+	}
+	return (concordant - discordant) / denom
+}
+
+// ranks returns the 1-based rank of each value, averaging tied ranks
+func ranks(values []float64) []float64 {
+	return weightedRanks(values, nil)
+}
+
+// weightedRanks returns the rank of each value among weighted samples: the
+// total weight below it plus half the weight of its ties, plus ½ so that
+// unit weights give the 1-based ranks
+func weightedRanks(values, weights []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+	r := make([]float64, len(values))
+	below := 0.0
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		tied := 0.0
+		for k := i; k <= j; k++ {
+			tied += sampleWeight(weights, order[k])
+		}
+		avg := below + tied/2 + 0.5
+		for k := i; k <= j; k++ {
+			r[order[k]] = avg
+		}
+		below += tied
+		i = j + 1
+	}
+	return r
+}
+
+// correlationMatrix returns the correlations between the columns of x. Rank
+// correlations are mapped to the Pearson scale they estimate under a Gaussian
+// copula, 2sin(πρₛ/6) for Spearman and sin(πτ/2) for Kendall, so partial
+// correlations can be taken from them as from Pearson correlations. Non-nil
+// weights weight each row.
+func correlationMatrix(x *mat.Dense, kind CorrelationType, weights []float64) (*mat.SymDense, error) {
+	n, d := x.Dims()
+	if n < 2 {
+		return nil, fmt.Errorf("need at least 2 samples, got %d", n)
+	}
+	cols := make([][]float64, d)
+	for j := range cols {
+		cols[j] = mat.Col(nil, j, x)
+	}
+	if kind == CorrelationSpearman {
+		for j := range cols {
+			cols[j] = weightedRanks(cols[j], weights)
+		}
+	}
+	r := mat.NewSymDense(d, nil)
+	for i := 0; i < d; i++ {
+		r.SetSym(i, i, 1)
+		for j := i + 1; j < d; j++ {
+			var rho float64
+			switch kind {
+			case CorrelationPearson, "":
+				rho = weightedPearson(cols[i], cols[j], weights)
+			case CorrelationSpearman:
+				rho = 2 * math.Sin(math.Pi*weightedPearson(cols[i], cols[j], weights)/6)
+			case CorrelationKendall:
+				rho = math.Sin(math.Pi * kendallTau(cols[i], cols[j], weights) / 2)
+			default:
+				return nil, fmt.Errorf("unknown correlation type %q", kind)
+			}
+			r.SetSym(i, j, rho)
+		}
+	}
+	return r, nil
+}
+
+// matrixPartialCorrelation returns the partial correlation of variables x and
+// y given z from a correlation matrix, read off the inverse of its submatrix
+// over x, y and z
+func matrixPartialCorrelation(r *mat.SymDense, x, y int, z []int) float64 {
+	idx := append([]int{x, y}, z...)
+	sub := mat.NewSymDense(len(idx), nil)
+	for i, a := range idx {
+		for j := i; j < len(idx); j++ {
+			sub.SetSym(i, j, r.At(a, idx[j]))
+		}
+	}
+	var precision mat.Dense
+	if err := precision.Inverse(sub); err != nil {
+		// Singular: some variables are exact functions of the others
+		return 0.0
+	}
+	denom := math.Sqrt(precision.At(0, 0) * precision.At(1, 1))
+	if denom == 0 || math.IsNaN(denom) {
+		return 0.0
+	}
+	return math.Max(-1, math.Min(1, -precision.At(0, 1)/denom))
+}
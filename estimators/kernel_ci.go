@@ -0,0 +1,209 @@
+package estimators
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// KernelCIOptions configures KernelCITest. Zero fields take their defaults.
+type KernelCIOptions struct {
+	Permutations int     // Permutations drawn for the null distribution, 200 by default
+	MaxSamples   int     // Samples kept, evenly spaced through the data, 300 by default
+	Ridge        float64 // Kernel ridge penalty per sample when regressing out Z, 0.01 by default
+	Seed         int64   // Seed of the permutations
+
+	// Weights, if set, hold one weight per row; the samples kept are then
+	// drawn in proportion to them instead of evenly spaced. Invalid weights
+	// make the statistic and p-value NaN.
+	Weights []float64
+}
+
+// KernelCITest tests X independent of Y given Z, where data rows hold the
+// variables by column index, with the Hilbert-Schmidt independence criterion
+// (HSIC) under Gaussian kernels. Conditioning variables are removed by kernel
+// ridge regression of X and Y on Z before their residuals are tested, so
+// nonlinear dependence that partial correlation misses is detected. It
+// returns the HSIC statistic and a permutation p-value. Kernels cost O(n²)
+// memory and the regressions O(n³) time in the number of samples kept.
+func KernelCITest(data [][]float64, xIdx, yIdx int, zIdxs []int, opts KernelCIOptions) (float64, float64) {
+	if opts.Permutations <= 0 {
+		opts.Permutations = 200
+	}
+	if opts.MaxSamples <= 0 {
+		opts.MaxSamples = 300
+	}
+	if opts.Ridge <= 0 {
+		opts.Ridge = 0.01
+	}
+	data, err := sampleRows(data, opts.Weights, opts.MaxSamples)
+	if err != nil {
+		return math.NaN(), math.NaN()
+	}
+	n := len(data)
+	if n < 4 {
+		return 0, 1
+	}
+
+	x := standardizedColumns(data, []int{xIdx})
+	y := standardizedColumns(data, []int{yIdx})
+	if len(zIdxs) > 0 {
+		kz := centredGram(gaussianGram(standardizedColumns(data, zIdxs)))
+		x = ridgeResiduals(kz, x, opts.Ridge*float64(n))
+		y = ridgeResiduals(kz, y, opts.Ridge*float64(n))
+	}
+	kx := centredGram(gaussianGram(x))
+	ky := centredGram(gaussianGram(y))
+
+	observed := hsic(kx, ky, nil)
+	rng := rand.New(rand.NewSource(opts.Seed))
+	exceed := 0
+	for b := 0; b < opts.Permutations; b++ {
+		if hsic(kx, ky, rng.Perm(n)) >= observed {
+			exceed++
+		}
+	}
+	return observed, float64(exceed+1) / float64(opts.Permutations+1)
+}
+
+// standardizedColumns returns the given columns of data as rows, each
+// column shifted and scaled to mean 0 and variance 1
+func standardizedColumns(data [][]float64, cols []int) [][]float64 {
+	rows := make([][]float64, len(data))
+	for i := range rows {
+		rows[i] = make([]float64, len(cols))
+	}
+	for j, c := range cols {
+		column := make([]float64, len(data))
+		for i, row := range data {
+			column[i] = row[c]
+		}
+		mean, sd := 0.0, 0.0
+		for _, v := range column {
+			mean += v
+		}
+		mean /= float64(len(column))
+		for _, v := range column {
+			sd += (v - mean) * (v - mean)
+		}
+		sd = math.Sqrt(sd / float64(len(column)))
+		if sd == 0 {
+			sd = 1
+		}
+		for i, v := range column {
+			rows[i][j] = (v - mean) / sd
+		}
+	}
+	return rows
+}
+
+// gaussianGram returns the Gaussian kernel matrix of the rows, with the
+// bandwidth set to the median distance between rows
+func gaussianGram(rows [][]float64) *mat.SymDense {
+	n := len(rows)
+	dist := make([]float64, 0, n*(n-1)/2)
+	sq := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			d := 0.0
+			for k := range rows[i] {
+				diff := rows[i][k] - rows[j][k]
+				d += diff * diff
+			}
+			sq.SetSym(i, j, d)
+			dist = append(dist, d)
+		}
+	}
+	sort.Float64s(dist)
+	bandwidth := dist[len(dist)/2]
+	if bandwidth == 0 {
+		bandwidth = 1
+	}
+	k := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		k.SetSym(i, i, 1)
+		for j := i + 1; j < n; j++ {
+			k.SetSym(i, j, math.Exp(-sq.At(i, j)/(2*bandwidth)))
+		}
+	}
+	return k
+}
+
+// centredGram returns HKH with H = I - 11ᵀ/n, the kernel matrix of the
+// features centred on their mean
+func centredGram(k *mat.SymDense) *mat.SymDense {
+	n := k.SymmetricDim()
+	rowMeans := make([]float64, n)
+	total := 0.0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			rowMeans[i] += k.At(i, j)
+		}
+		total += rowMeans[i]
+		rowMeans[i] /= float64(n)
+	}
+	total /= float64(n * n)
+	c := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			c.SetSym(i, j, k.At(i, j)-rowMeans[i]-rowMeans[j]+total)
+		}
+	}
+	return c
+}
+
+// ridgeResiduals returns the residuals of a kernel ridge regression of each
+// column of the rows on the centred kernel kz: r = λ(Kz + λI)⁻¹v
+func ridgeResiduals(kz *mat.SymDense, rows [][]float64, lambda float64) [][]float64 {
+	n := kz.SymmetricDim()
+	a := mat.NewSymDense(n, nil)
+	a.CopySym(kz)
+	for i := 0; i < n; i++ {
+		a.SetSym(i, i, a.At(i, i)+lambda)
+	}
+	var chol mat.Cholesky
+	if !chol.Factorize(a) {
+		return rows
+	}
+	residuals := make([][]float64, n)
+	for i := range residuals {
+		residuals[i] = make([]float64, len(rows[0]))
+	}
+	v := mat.NewVecDense(n, nil)
+	var solved mat.VecDense
+	for j := range rows[0] {
+		for i, row := range rows {
+			v.SetVec(i, row[j])
+		}
+		if err := chol.SolveVecTo(&solved, v); err != nil {
+			return rows
+		}
+		for i := range residuals {
+			residuals[i][j] = lambda * solved.AtVec(i)
+		}
+	}
+	return residuals
+}
+
+// hsic returns the biased HSIC estimate tr(K̃x K̃y)/n² for centred kernel
+// matrices, with the samples of Y reordered by perm when it is non-nil
+func hsic(kx, ky *mat.SymDense, perm []int) float64 {
+	n := kx.SymmetricDim()
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		pi := i
+		if perm != nil {
+			pi = perm[i]
+		}
+		for j := 0; j < n; j++ {
+			pj := j
+			if perm != nil {
+				pj = perm[j]
+			}
+			sum += kx.At(i, j) * ky.At(pi, pj)
+		}
+	}
+	return sum / float64(n*n)
+}
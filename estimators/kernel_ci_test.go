@@ -0,0 +1,39 @@
+package estimators
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestKernelCITest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	// Columns: X, Y = X² + ε and an independent N
+	data := make([][]float64, 300)
+	for i := range data {
+		x := r.NormFloat64()
+		data[i] = []float64{
+			x,
+			x*x + 0.3*r.NormFloat64(),
+			r.NormFloat64(),
+		}
+	}
+	column := func(j int) []float64 {
+		c := make([]float64, len(data))
+		for i, row := range data {
+			c[i] = row[j]
+		}
+		return c
+	}
+
+	// Y = X² is uncorrelated with X, so Fisher's Z misses the dependence
+	if p := FisherZ(PearsonCorrelation(column(0), column(1)), len(data), 0); p < 0.05 {
+		t.Fatalf("Expected Pearson to miss the quadratic dependence, got p = %f", p)
+	}
+	if _, p := KernelCITest(data, 0, 1, nil, KernelCIOptions{Seed: 1}); p >= 0.01 {
+		t.Errorf("Expected the kernel test to detect Y = X², got p = %f", p)
+	}
+
+	if _, p := KernelCITest(data, 0, 2, nil, KernelCIOptions{Seed: 1}); p < 0.1 {
+		t.Errorf("Expected a large p-value for independent data, got %f", p)
+	}
+}
@@ -0,0 +1,95 @@
+package estimators
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// sampleLassoData draws Y = 2X + ε and Z = -Y + ε with independent N1 and N2
+// that no variable depends on, all offset from zero
+func sampleLassoData(n int, seed int64) []map[string]float64 {
+	r := rand.New(rand.NewSource(seed))
+	data := make([]map[string]float64, n)
+	for i := range data {
+		x := 3 + r.NormFloat64()
+		y := 1 + 2*x + r.NormFloat64()
+		data[i] = map[string]float64{
+			"X":  x,
+			"N1": r.NormFloat64(),
+			"Y":  y,
+			"N2": 5 * r.NormFloat64(),
+			"Z":  -y + r.NormFloat64(),
+		}
+	}
+	return data
+}
+
+func TestLassoSelectsParents(t *testing.T) {
+	l := NewLasso(sampleLassoData(2000, 1))
+	l.SetOrder([]string{"X", "N1", "Y", "N2", "Z"})
+	result, err := l.Estimate()
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	if !result.Converged {
+		t.Error("Expected every regression to converge")
+	}
+	for _, e := range [][2]string{{"X", "Y"}, {"Y", "Z"}} {
+		if !result.DAG.HasEdge(e[0], e[1]) {
+			t.Errorf("Expected edge %s -> %s", e[0], e[1])
+		}
+	}
+	// The irrelevant candidates get coefficients of exactly zero
+	for _, e := range [][2]string{{"N1", "Y"}, {"N1", "N2"}, {"N1", "Z"}, {"N2", "Z"}, {"X", "Z"}, {"Y", "N2"}} {
+		if w, ok := result.Weights[e[0]][e[1]]; ok {
+			t.Errorf("Expected no %s -> %s, got weight %f", e[0], e[1], w)
+		}
+		if c := result.CPDs[e[1]].Coefficients[e[0]]; c != 0 {
+			t.Errorf("Expected a zero coefficient of %s in the CPD of %s, got %f", e[0], e[1], c)
+		}
+	}
+	// Without refitting, the penalty shrinks the coefficients towards zero
+	if w := result.Weights["X"]["Y"]; w >= 2 || w < 1.5 {
+		t.Errorf("Expected a shrunken X -> Y weight below 2, got %f", w)
+	}
+}
+
+func TestLassoRefit(t *testing.T) {
+	data := sampleLassoData(2000, 2)
+	l := NewLasso(data)
+	l.SetOrder([]string{"X", "N1", "Y", "N2", "Z"})
+	l.SetRefit(true)
+	result, err := l.Estimate()
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+
+	// Ordinary least squares of Y on [1, X] from the raw samples
+	design := mat.NewDense(len(data), 2, nil)
+	target := mat.NewVecDense(len(data), nil)
+	for i, s := range data {
+		design.Set(i, 0, 1)
+		design.Set(i, 1, s["X"])
+		target.SetVec(i, s["Y"])
+	}
+	var ols mat.VecDense
+	if err := ols.SolveVec(design, target); err != nil {
+		t.Fatalf("Least squares failed: %v", err)
+	}
+	cpd := result.CPDs["Y"]
+	if math.Abs(cpd.Intercept-ols.AtVec(0)) > 1e-6 || math.Abs(cpd.Coefficients["X"]-ols.AtVec(1)) > 1e-6 {
+		t.Errorf("Refitted Y = %f + %f X, least squares gives %f + %f X",
+			cpd.Intercept, cpd.Coefficients["X"], ols.AtVec(0), ols.AtVec(1))
+	}
+}
+
+func TestLassoRejectsDuplicateOrder(t *testing.T) {
+	l := NewLasso(sampleLassoData(100, 3))
+	l.SetOrder([]string{"X", "Y", "X", "Z"})
+	if _, err := l.Estimate(); err == nil {
+		t.Error("Expected an error for a variable repeated in the order")
+	}
+}
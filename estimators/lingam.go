@@ -0,0 +1,373 @@
+package estimators
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/JohnPierman/bngo/graph"
+	"gonum.org/v1/gonum/mat"
+)
+
+// LiNGAMEstimator learns a linear causal model from continuous data with
+// non-Gaussian noise (Shimizu et al., 2006). FastICA recovers the mixing of
+// the independent noise terms, whose rows are matched to variables and
+// permuted into a causal order; each variable is then regressed on its
+// predecessors. Unlike PC or score-based search the result is a single DAG
+// with every edge oriented, not a Markov equivalence class. At most one noise
+// term may be Gaussian.
+type LiNGAMEstimator struct {
+	Data      []map[string]float64
+	Variables []string
+
+	Threshold     float64 // Regression weights smaller in magnitude are dropped
+	MaxIterations int     // Maximum FastICA iterations
+	Tolerance     float64 // FastICA convergence tolerance
+
+	// Progress, if set, is called after each FastICA iteration with the
+	// largest change of an unmixing direction as the score
+	Progress ProgressFunc
+}
+
+// LiNGAMResult is a learned linear causal model
+type LiNGAMResult struct {
+	Variables []string
+	Order     []string                      // Causal order, exogenous variables first
+	Weights   map[string]map[string]float64 // Weights[parent][child]
+	DAG       *graph.DAG
+	Converged bool // Whether FastICA met the tolerance
+}
+
+// NewLiNGAM creates a LiNGAM estimator
+func NewLiNGAM(data []map[string]float64) *LiNGAMEstimator {
+	return &LiNGAMEstimator{
+		Data:          data,
+		Variables:     continuousVariables(data),
+		Threshold:     0.1,
+		MaxIterations: 1000,
+		Tolerance:     1e-6,
+	}
+}
+
+// SetThreshold sets the magnitude below which weights are dropped
+func (l *LiNGAMEstimator) SetThreshold(threshold float64) {
+	l.Threshold = threshold
+}
+
+// SetProgress sets the hook called after each FastICA iteration
+func (l *LiNGAMEstimator) SetProgress(hook ProgressFunc) {
+	l.Progress = hook
+}
+
+// Estimate learns the causal order, edge weights and DAG. The seed sets the
+// FastICA starting point.
+func (l *LiNGAMEstimator) Estimate(seed int64) (*LiNGAMResult, error) {
+	d := len(l.Variables)
+	if d == 0 {
+		return nil, fmt.Errorf("no variables to learn a structure over")
+	}
+	x, err := centredData(l.Data, l.Variables)
+	if err != nil {
+		return nil, err
+	}
+	n, _ := x.Dims()
+	cov := mat.NewDense(d, d, nil)
+	cov.Mul(x.T(), x)
+	cov.Scale(1/float64(n), cov)
+
+	// Whiten: z = K x with K = D^-½ Eᵀ, so z has identity covariance
+	var eig mat.EigenSym
+	if !eig.Factorize(mat.NewSymDense(d, cov.RawMatrix().Data), true) {
+		return nil, fmt.Errorf("eigendecomposition of the covariance failed")
+	}
+	values := eig.Values(nil)
+	var vectors mat.Dense
+	eig.VectorsTo(&vectors)
+	whitening := mat.NewDense(d, d, nil)
+	for i := 0; i < d; i++ {
+		if values[i] <= 1e-12 {
+			return nil, fmt.Errorf("covariance is singular; variables are linearly dependent")
+		}
+		for j := 0; j < d; j++ {
+			whitening.Set(i, j, vectors.At(j, i)/math.Sqrt(values[i]))
+		}
+	}
+	z := mat.NewDense(n, d, nil)
+	z.Mul(x, whitening.T())
+
+	unmixing, converged := l.fastICA(z, seed)
+	w := mat.NewDense(d, d, nil)
+	w.Mul(unmixing, whitening)
+
+	// Match each independent component to the variable its noise enters,
+	// keeping the diagonal of the unmixing matrix as far from zero as possible
+	cost := make([][]float64, d)
+	for i := range cost {
+		cost[i] = make([]float64, d)
+		for j := range cost[i] {
+			cost[i][j] = 1 / math.Max(math.Abs(w.At(i, j)), 1e-12)
+		}
+	}
+	assignment := minCostAssignment(cost) // assignment[component] = variable
+	permuted := mat.NewDense(d, d, nil)
+	for i, j := range assignment {
+		for k := 0; k < d; k++ {
+			permuted.Set(j, k, w.At(i, k)/w.At(i, j))
+		}
+	}
+	b := mat.NewDense(d, d, nil)
+	b.Sub(eye(d), permuted)
+
+	order := causalOrder(b)
+	result := &LiNGAMResult{
+		Variables: append([]string(nil), l.Variables...),
+		Order:     make([]string, d),
+		Weights:   make(map[string]map[string]float64, d),
+		DAG:       graph.NewDAG(),
+		Converged: converged,
+	}
+	for i, v := range order {
+		result.Order[i] = l.Variables[v]
+	}
+	for _, v := range l.Variables {
+		result.Weights[v] = make(map[string]float64)
+		result.DAG.AddNode(v)
+	}
+
+	// Refit each variable on its predecessors by least squares
+	for pos := 1; pos < d; pos++ {
+		child := order[pos]
+		coef, err := regress(cov, order[:pos], child)
+		if err != nil {
+			return nil, fmt.Errorf("regressing %s on its predecessors: %w", l.Variables[child], err)
+		}
+		for k, parent := range order[:pos] {
+			if math.Abs(coef[k]) >= l.Threshold {
+				result.Weights[l.Variables[parent]][l.Variables[child]] = coef[k]
+				_ = result.DAG.AddEdge(l.Variables[parent], l.Variables[child]) // Follows the causal order, so acyclic
+			}
+		}
+	}
+	return result, nil
+}
+
+// fastICA runs symmetric FastICA with the log-cosh contrast on whitened data
+// and returns the unmixing matrix with one component per row
+func (l *LiNGAMEstimator) fastICA(z *mat.Dense, seed int64) (*mat.Dense, bool) {
+	n, d := z.Dims()
+	r := rand.New(rand.NewSource(seed))
+	w := mat.NewDense(d, d, nil)
+	for i := 0; i < d; i++ {
+		for j := 0; j < d; j++ {
+			w.Set(i, j, r.NormFloat64())
+		}
+	}
+	w = decorrelate(w)
+
+	reporter := newProgressReporter(l.Progress)
+	projected := mat.NewDense(n, d, nil)
+	for iter := 1; iter <= l.MaxIterations; iter++ {
+		// w ← E[z g(wᵀz)] - E[g'(wᵀz)] w with g = tanh
+		projected.Mul(z, w.T())
+		g := mat.NewDense(n, d, nil)
+		derivative := make([]float64, d)
+		for s := 0; s < n; s++ {
+			for i := 0; i < d; i++ {
+				t := math.Tanh(projected.At(s, i))
+				g.Set(s, i, t)
+				derivative[i] += 1 - t*t
+			}
+		}
+		next := mat.NewDense(d, d, nil)
+		next.Mul(g.T(), z)
+		next.Scale(1/float64(n), next)
+		for i := 0; i < d; i++ {
+			for j := 0; j < d; j++ {
+				next.Set(i, j, next.At(i, j)-derivative[i]/float64(n)*w.At(i, j))
+			}
+		}
+		next = decorrelate(next)
+
+		// Converged when every direction is unchanged up to sign
+		change := 0.0
+		for i := 0; i < d; i++ {
+			dot := mat.Dot(next.RowView(i), w.RowView(i))
+			change = math.Max(change, math.Abs(math.Abs(dot)-1))
+		}
+		w = next
+		reporter.report(iter, change)
+		if change < l.Tolerance {
+			return w, true
+		}
+	}
+	return w, false
+}
+
+// decorrelate returns (W Wᵀ)^-½ W, the closest matrix to W with orthonormal
+// rows
+func decorrelate(w *mat.Dense) *mat.Dense {
+	d, _ := w.Dims()
+	wwt := mat.NewSymDense(d, nil)
+	wwt.SymOuterK(1, w)
+	var eig mat.EigenSym
+	eig.Factorize(wwt, true)
+	values := eig.Values(nil)
+	var vectors mat.Dense
+	eig.VectorsTo(&vectors)
+	scaled := mat.NewDense(d, d, nil)
+	for i := 0; i < d; i++ {
+		for j := 0; j < d; j++ {
+			scaled.Set(i, j, vectors.At(i, j)/math.Sqrt(math.Max(values[j], 1e-300)))
+		}
+	}
+	inverseRoot := mat.NewDense(d, d, nil)
+	inverseRoot.Mul(scaled, vectors.T())
+	out := mat.NewDense(d, d, nil)
+	out.Mul(inverseRoot, w)
+	return out
+}
+
+// causalOrder orders the variables so that b, with b[i][j] the weight of j in
+// the equation of i, is as close to strictly lower triangular as possible.
+// The smallest weights are treated as zero, more at a time, until such an
+// order exists.
+func causalOrder(b *mat.Dense) []int {
+	d, _ := b.Dims()
+	type entry struct {
+		i, j int
+		size float64
+	}
+	entries := make([]entry, 0, d*d)
+	for i := 0; i < d; i++ {
+		for j := 0; j < d; j++ {
+			if i != j {
+				entries = append(entries, entry{i, j, math.Abs(b.At(i, j))})
+			}
+		}
+	}
+	sort.Slice(entries, func(a, c int) bool { return entries[a].size < entries[c].size })
+
+	zero := make([][]bool, d)
+	for i := range zero {
+		zero[i] = make([]bool, d)
+		zero[i][i] = true
+	}
+	// A strictly lower triangular matrix has d(d-1)/2 zeros off the diagonal
+	k := d * (d - 1) / 2
+	for _, e := range entries[:k] {
+		zero[e.i][e.j] = true
+	}
+	for _, e := range entries[k:] {
+		if order := triangularOrder(zero); order != nil {
+			return order
+		}
+		zero[e.i][e.j] = true
+	}
+	return triangularOrder(zero)
+}
+
+// triangularOrder returns an order in which every variable depends only on
+// earlier ones, or nil if there is none. zero[i][j] marks j as absent from
+// the equation of i.
+func triangularOrder(zero [][]bool) []int {
+	d := len(zero)
+	placed := make([]bool, d)
+	order := make([]int, 0, d)
+	for len(order) < d {
+		found := -1
+		for i := 0; i < d && found < 0; i++ {
+			if placed[i] {
+				continue
+			}
+			exogenous := true
+			for j := 0; j < d; j++ {
+				if !placed[j] && !zero[i][j] {
+					exogenous = false
+					break
+				}
+			}
+			if exogenous {
+				found = i
+			}
+		}
+		if found < 0 {
+			return nil
+		}
+		placed[found] = true
+		order = append(order, found)
+	}
+	return order
+}
+
+// regress returns the least-squares coefficients of child on parents from
+// the covariance matrix
+func regress(cov *mat.Dense, parents []int, child int) ([]float64, error) {
+	k := len(parents)
+	sxx := mat.NewDense(k, k, nil)
+	sxy := mat.NewVecDense(k, nil)
+	for a, p := range parents {
+		for c, q := range parents {
+			sxx.Set(a, c, cov.At(p, q))
+		}
+		sxy.SetVec(a, cov.At(p, child))
+	}
+	var coef mat.VecDense
+	if err := coef.SolveVec(sxx, sxy); err != nil {
+		return nil, err
+	}
+	return coef.RawVector().Data, nil
+}
+
+// minCostAssignment solves the square assignment problem by the Hungarian
+// algorithm and returns the column assigned to each row
+func minCostAssignment(cost [][]float64) []int {
+	n := len(cost)
+	u := make([]float64, n+1)
+	v := make([]float64, n+1)
+	match := make([]int, n+1) // match[column] = row, 1-based with 0 for none
+	way := make([]int, n+1)
+	for row := 1; row <= n; row++ {
+		match[0] = row
+		col := 0
+		minv := make([]float64, n+1)
+		used := make([]bool, n+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for match[col] != 0 {
+			used[col] = true
+			i, delta, next := match[col], math.Inf(1), 0
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				if c := cost[i-1][j-1] - u[i] - v[j]; c < minv[j] {
+					minv[j], way[j] = c, col
+				}
+				if minv[j] < delta {
+					delta, next = minv[j], j
+				}
+			}
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[match[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			col = next
+		}
+		for col != 0 {
+			prev := way[col]
+			match[col] = match[prev]
+			col = prev
+		}
+	}
+	assignment := make([]int, n)
+	for col := 1; col <= n; col++ {
+		assignment[match[col]-1] = col - 1
+	}
+	return assignment
+}
@@ -0,0 +1,64 @@
+package estimators
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestLiNGAMOrder(t *testing.T) {
+	// The chain Q -> M -> Z -> B, named so that sorting does not give the order
+	order := []string{"Q", "M", "Z", "B"}
+	noises := map[string]func(r *rand.Rand) float64{
+		"uniform": func(r *rand.Rand) float64 { return (2*r.Float64() - 1) * math.Sqrt(3) },
+		"laplace": func(r *rand.Rand) float64 { return r.ExpFloat64() - r.ExpFloat64() },
+	}
+	for name, noise := range noises {
+		r := rand.New(rand.NewSource(1))
+		data := make([]map[string]float64, 3000)
+		for i := range data {
+			s := make(map[string]float64, len(order))
+			prev := 0.0
+			for _, v := range order {
+				prev = 0.9*prev + noise(r)
+				s[v] = prev
+			}
+			data[i] = s
+		}
+
+		result, err := NewLiNGAM(data).Estimate(1)
+		if err != nil {
+			t.Fatalf("%s noise: Estimate failed: %v", name, err)
+		}
+		if !result.Converged {
+			t.Errorf("%s noise: FastICA did not converge", name)
+		}
+		if !reflect.DeepEqual(result.Order, order) {
+			t.Errorf("%s noise: expected order %v, got %v", name, order, result.Order)
+		}
+		for i := 1; i < len(order); i++ {
+			if w := result.Weights[order[i-1]][order[i]]; math.Abs(w-0.9) > 0.1 {
+				t.Errorf("%s noise: weight %s -> %s = %f, expected 0.9", name, order[i-1], order[i], w)
+			}
+		}
+		if n := len(result.DAG.Edges()); n != len(order)-1 {
+			t.Errorf("%s noise: expected %d edges, got %d", name, len(order)-1, n)
+		}
+	}
+}
+
+func TestMinCostAssignment(t *testing.T) {
+	cost := [][]float64{
+		{4, 1, 3},
+		{2, 0, 5},
+		{3, 2, 2},
+	}
+	// Row 0 -> 1, row 1 -> 0, row 2 -> 2 costs 5, the minimum
+	if got, want := minCostAssignment(cost), []int{1, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected assignment %v, got %v", want, got)
+	}
+	if got := minCostAssignment([][]float64{{7}}); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("Expected the single row assigned to column 0, got %v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package estimators
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MarkovBlanketOptions configures SelectMarkovBlanket. Zero fields take
+// their defaults.
+type MarkovBlanketOptions struct {
+	Alpha   float64   // Significance level of the chi-square tests, 0.05 if zero
+	MaxSize int       // Largest blanket grown, unlimited if zero
+	Weights []float64 // Per-sample frequency weights, nil to count each sample once
+}
+
+// SelectMarkovBlanket estimates the Markov blanket of target, its parents,
+// children and the children's other parents, from discrete data without
+// learning a network, by the IAMB algorithm. The growing phase repeatedly
+// adds the variable most dependent on target given the blanket so far, as
+// measured by the chi-square p-value, until none is dependent at Alpha; the
+// shrinking phase then removes every member independent of target given the
+// others. The blanket is returned sorted. Large blankets make the tests
+// condition on many variables and lose power, which MaxSize guards against.
+func SelectMarkovBlanket(data []map[string]int, target string, opts MarkovBlanketOptions) ([]string, error) {
+	if opts.Alpha == 0 {
+		opts.Alpha = 0.05
+	}
+	if opts.Alpha < 0 || opts.Alpha >= 1 {
+		return nil, fmt.Errorf("significance level must be in [0, 1), got %f", opts.Alpha)
+	}
+	if opts.Weights != nil {
+		if err := checkWeights(opts.Weights, len(data)); err != nil {
+			return nil, err
+		}
+	}
+	pc := NewPC(data)
+	if _, ok := pc.Cardinality[target]; !ok {
+		return nil, fmt.Errorf("target %s does not appear in the data", target)
+	}
+	test := func(x string, given []string) (float64, float64) {
+		return WeightedChiSquareTest(data, opts.Weights, x, target, given, pc.Cardinality)
+	}
+
+	// Growing phase
+	var blanket []string
+	in := map[string]bool{target: true}
+	for opts.MaxSize <= 0 || len(blanket) < opts.MaxSize {
+		best, bestP, bestStatistic := "", 1.0, 0.0
+		for _, v := range pc.Variables {
+			if in[v] {
+				continue
+			}
+			statistic, p := test(v, blanket)
+			if best == "" || p < bestP || (p == bestP && statistic > bestStatistic) {
+				best, bestP, bestStatistic = v, p, statistic
+			}
+		}
+		if best == "" || bestP >= opts.Alpha {
+			break
+		}
+		blanket = append(blanket, best)
+		in[best] = true
+	}
+
+	// Shrinking phase
+	for i := 0; i < len(blanket); {
+		others := make([]string, 0, len(blanket)-1)
+		others = append(others, blanket[:i]...)
+		others = append(others, blanket[i+1:]...)
+		if _, p := test(blanket[i], others); p >= opts.Alpha {
+			blanket = others
+			continue
+		}
+		i++
+	}
+
+	sort.Strings(blanket)
+	return blanket, nil
+}
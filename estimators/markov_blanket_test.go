@@ -0,0 +1,38 @@
+package estimators
+
+import "testing"
+
+func TestSelectMarkovBlanket(t *testing.T) {
+	// G -> P -> T -> C <- S, C -> D: the blanket of T is its parent P, its
+	// child C and the child's other parent S
+	data := sampleNoisyOR(3000, 0.1, 1, []string{"G", "P", "T", "S", "C", "D"},
+		map[string][]string{"P": {"G"}, "T": {"P"}, "C": {"T", "S"}, "D": {"C"}})
+
+	blanket, err := SelectMarkovBlanket(data, "T", MarkovBlanketOptions{})
+	if err != nil {
+		t.Fatalf("SelectMarkovBlanket failed: %v", err)
+	}
+	if want := []string{"C", "P", "S"}; !equalStrings(blanket, want) {
+		t.Errorf("Expected blanket %v, got %v", want, blanket)
+	}
+
+	limited, err := SelectMarkovBlanket(data, "T", MarkovBlanketOptions{MaxSize: 1})
+	if err != nil {
+		t.Fatalf("SelectMarkovBlanket failed: %v", err)
+	}
+	if len(limited) != 1 {
+		t.Errorf("Expected a blanket of at most 1, got %v", limited)
+	}
+
+	for _, alpha := range []float64{-0.1, 1} {
+		if _, err := SelectMarkovBlanket(data, "T", MarkovBlanketOptions{Alpha: alpha}); err == nil {
+			t.Errorf("Expected an error for significance level %v", alpha)
+		}
+	}
+	if _, err := SelectMarkovBlanket(data, "Z", MarkovBlanketOptions{}); err == nil {
+		t.Error("Expected an error for a target missing from the data")
+	}
+	if _, err := SelectMarkovBlanket(data, "T", MarkovBlanketOptions{Weights: []float64{1}}); err == nil {
+		t.Error("Expected an error for the wrong number of weights")
+	}
+}
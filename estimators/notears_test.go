@@ -0,0 +1,54 @@
+package estimators
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// sampleLinearSEM draws X -> Y -> Z with X -> Z and unit-variance noise,
+// drawing the noise with next
+func sampleLinearSEM(n int, seed int64, next func(r *rand.Rand) float64) []map[string]float64 {
+	r := rand.New(rand.NewSource(seed))
+	data := make([]map[string]float64, n)
+	for i := range data {
+		x := next(r)
+		y := 1.5*x + next(r)
+		z := -1.0*y + 0.8*x + next(r)
+		data[i] = map[string]float64{"X": x, "Y": y, "Z": z}
+	}
+	return data
+}
+
+func TestNOTEARS(t *testing.T) {
+	data := sampleLinearSEM(2000, 1, (*rand.Rand).NormFloat64)
+	nt := NewNOTEARS(data)
+	nt.SetLambda(0.01)
+	result, err := nt.Estimate()
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	if result.Acyclicity > nt.Tolerance {
+		t.Errorf("Acyclicity %g above the tolerance %g", result.Acyclicity, nt.Tolerance)
+	}
+
+	want := map[[2]string]float64{{"X", "Y"}: 1.5, {"Y", "Z"}: -1.0, {"X", "Z"}: 0.8}
+	for e, w := range want {
+		if got := result.Weights[e[0]][e[1]]; math.Abs(got-w) > 0.1 {
+			t.Errorf("Weight %s -> %s = %f, expected %f", e[0], e[1], got, w)
+		}
+		if !result.DAG.HasEdge(e[0], e[1]) {
+			t.Errorf("Expected edge %s -> %s", e[0], e[1])
+		}
+	}
+	if n := len(result.DAG.Edges()); n != len(want) {
+		t.Errorf("Expected %d edges, got %d", len(want), n)
+	}
+
+	if err := nt.SetWeights(make([]float64, len(data)-1)); err == nil {
+		t.Error("Expected an error for too few weights")
+	}
+	if nt.Weights != nil {
+		t.Error("Rejected weights should not be kept")
+	}
+}
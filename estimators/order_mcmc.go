@@ -0,0 +1,313 @@
+package estimators
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// OrderMCMC samples topological orders of the variables by Metropolis-Hastings
+// (Friedman & Koller, 2003). Given an order, the parent set of each node is
+// summed out exactly over every subset of its predecessors up to MaxIndegree,
+// so the sampler estimates posterior edge probabilities rather than a single
+// DAG. The score must be a log marginal likelihood such as BDeu for the
+// probabilities to be posteriors.
+type OrderMCMC struct {
+	Data        []map[string]int
+	Variables   []string
+	Cardinality map[string]int
+	Score       Score // Decomposable log marginal likelihood; BDeu with sample size 1 by default
+
+	MaxIndegree int // Maximum parents per node
+	Iterations  int // Proposals after burn-in
+	BurnIn      int // Proposals discarded before sampling
+	Thin        int // Proposals between recorded samples
+
+	// Progress, if set, is called after each sweep of len(Variables)
+	// proposals with the log score of the current order
+	Progress ProgressFunc
+}
+
+// EdgePosterior holds posterior edge probabilities estimated by OrderMCMC or
+// StructureMCMC
+type EdgePosterior struct {
+	Variables      []string
+	Probability    map[string]map[string]float64 // Probability[parent][child]
+	Samples        int                           // Orders or DAGs the estimate averages over
+	AcceptanceRate float64                       // Fraction of proposals accepted
+}
+
+// NewOrderMCMC creates an order sampler scoring with BDeu
+func NewOrderMCMC(data []map[string]int) *OrderMCMC {
+	cardinality := dataCardinality(data)
+	variables := make([]string, 0, len(cardinality))
+	for v := range cardinality {
+		variables = append(variables, v)
+	}
+	sort.Strings(variables)
+
+	return &OrderMCMC{
+		Data:        data,
+		Variables:   variables,
+		Cardinality: cardinality,
+		Score:       &BDeuScore{Data: data, Cardinality: cardinality, EquivalentSampleSize: 1},
+		MaxIndegree: 3,
+		Iterations:  10000,
+		BurnIn:      1000,
+		Thin:        10,
+	}
+}
+
+// SetScore sets the structure score
+func (m *OrderMCMC) SetScore(score Score) {
+	m.Score = score
+}
+
+// SetWeights weights the samples of the score, one weight per sample, for
+// bootstrap aggregation, importance weighting or class rebalancing. The
+// score must be a BICScore or BDeuScore, so call it after SetScore.
+func (m *OrderMCMC) SetWeights(weights []float64) error {
+	return weightScore(m.Score, weights)
+}
+
+// SetMaxIndegree limits the number of parents of each node. The cost of each
+// proposal grows with the number of parent sets, about n^k for k parents.
+func (m *OrderMCMC) SetMaxIndegree(k int) {
+	m.MaxIndegree = k
+}
+
+// SetIterations sets the number of proposals kept and discarded
+func (m *OrderMCMC) SetIterations(iterations, burnIn int) {
+	m.Iterations = iterations
+	m.BurnIn = burnIn
+}
+
+// SetProgress sets the hook called after each sweep
+func (m *OrderMCMC) SetProgress(hook ProgressFunc) {
+	m.Progress = hook
+}
+
+// parentSet is a candidate parent set of one node as a bit mask over the
+// variable indices, with its local score
+type parentSet struct {
+	mask  uint64
+	score float64
+}
+
+// Estimate runs the sampler and returns the edge probabilities averaged over
+// the recorded orders
+func (m *OrderMCMC) Estimate(seed int64) (*EdgePosterior, error) {
+	n := len(m.Variables)
+	if n == 0 {
+		return nil, fmt.Errorf("no variables to learn a structure over")
+	}
+	if n > 64 {
+		return nil, fmt.Errorf("order MCMC supports at most 64 variables, got %d", n)
+	}
+	if m.Iterations < 1 || m.Thin < 1 || m.BurnIn < 0 {
+		return nil, fmt.Errorf("invalid schedule: %d iterations, %d burn-in, thin %d", m.Iterations, m.BurnIn, m.Thin)
+	}
+
+	// Score every parent set of every node once
+	score, ok := m.Score.(*ScoreCache)
+	if !ok {
+		score = NewScoreCache(m.Score)
+	}
+	candidates := make([][]parentSet, n)
+	for v := 0; v < n; v++ {
+		others := make([]int, 0, n-1)
+		for u := 0; u < n; u++ {
+			if u != v {
+				others = append(others, u)
+			}
+		}
+		for k := 0; k <= min(m.MaxIndegree, n-1); k++ {
+			forEachSubset(others, k, func(subset []int) {
+				var mask uint64
+				names := make([]string, len(subset))
+				for i, u := range subset {
+					mask |= 1 << uint(u)
+					names[i] = m.Variables[u]
+				}
+				candidates[v] = append(candidates[v], parentSet{mask, score.LocalScore(m.Variables[v], names)})
+			})
+		}
+	}
+
+	// nodeScore sums out the parent sets of v within its predecessors
+	nodeScore := func(v int, pred uint64) float64 {
+		best := math.Inf(-1)
+		for _, c := range candidates[v] {
+			if c.mask&^pred == 0 && c.score > best {
+				best = c.score
+			}
+		}
+		sum := 0.0
+		for _, c := range candidates[v] {
+			if c.mask&^pred == 0 {
+				sum += math.Exp(c.score - best)
+			}
+		}
+		return best + math.Log(sum)
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	order := r.Perm(n)
+	pred := make([]uint64, n) // Predecessor mask of the node at each position
+	local := make([]float64, n)
+	recompute := func(from, to int) {
+		var mask uint64
+		if from > 0 {
+			mask = pred[from-1] | 1<<uint(order[from-1])
+		}
+		for pos := from; pos <= to; pos++ {
+			pred[pos] = mask
+			local[pos] = nodeScore(order[pos], mask)
+			mask |= 1 << uint(order[pos])
+		}
+	}
+	recompute(0, n-1)
+	total := 0.0
+	for _, s := range local {
+		total += s
+	}
+
+	counts := make([][]float64, n) // counts[parent][child]
+	for u := range counts {
+		counts[u] = make([]float64, n)
+	}
+	record := func() {
+		for pos, v := range order {
+			logZ := local[pos]
+			for _, c := range candidates[v] {
+				if c.mask&^pred[pos] != 0 {
+					continue
+				}
+				w := math.Exp(c.score - logZ)
+				for u := 0; u < n; u++ {
+					if c.mask&(1<<uint(u)) != 0 {
+						counts[u][v] += w
+					}
+				}
+			}
+		}
+	}
+
+	reporter := newProgressReporter(m.Progress)
+	accepted, samples := 0, 0
+	saved := make([]float64, n)
+	savedPred := make([]uint64, n)
+	steps := m.BurnIn + m.Iterations
+	for step := 1; step <= steps; step++ {
+		if n > 1 {
+			i, j := r.Intn(n), r.Intn(n-1)
+			if j >= i {
+				j++
+			}
+			if i > j {
+				i, j = j, i
+			}
+			copy(saved[i:j+1], local[i:j+1])
+			copy(savedPred[i:j+1], pred[i:j+1])
+			before := 0.0
+			for pos := i; pos <= j; pos++ {
+				before += local[pos]
+			}
+
+			order[i], order[j] = order[j], order[i]
+			recompute(i, j)
+			after := 0.0
+			for pos := i; pos <= j; pos++ {
+				after += local[pos]
+			}
+
+			if delta := after - before; delta >= 0 || r.Float64() < math.Exp(delta) {
+				total += delta
+				accepted++
+			} else {
+				order[i], order[j] = order[j], order[i]
+				copy(local[i:j+1], saved[i:j+1])
+				copy(pred[i:j+1], savedPred[i:j+1])
+			}
+		}
+
+		if step > m.BurnIn && (step-m.BurnIn)%m.Thin == 0 {
+			record()
+			samples++
+		}
+		if step%n == 0 {
+			reporter.report(step/n, total)
+		}
+	}
+	if samples == 0 {
+		record()
+		samples = 1
+	}
+
+	posterior := &EdgePosterior{
+		Variables:      append([]string(nil), m.Variables...),
+		Probability:    make(map[string]map[string]float64, n),
+		Samples:        samples,
+		AcceptanceRate: float64(accepted) / float64(steps),
+	}
+	for u, parent := range m.Variables {
+		posterior.Probability[parent] = make(map[string]float64, n)
+		for v, child := range m.Variables {
+			if u != v {
+				posterior.Probability[parent][child] = math.Min(counts[u][v]/float64(samples), 1) // Rounding can overshoot
+			}
+		}
+	}
+	return posterior, nil
+}
+
+// Edges returns the edges with posterior probability at least threshold,
+// most probable first
+func (p *EdgePosterior) Edges(threshold float64) [][2]string {
+	var edges [][2]string
+	for _, parent := range p.Variables {
+		for _, child := range p.Variables {
+			if parent != child && p.Probability[parent][child] >= threshold {
+				edges = append(edges, [2]string{parent, child})
+			}
+		}
+	}
+	sort.SliceStable(edges, func(a, b int) bool {
+		return p.Probability[edges[a][0]][edges[a][1]] > p.Probability[edges[b][0]][edges[b][1]]
+	})
+	return edges
+}
+
+// DAG adds edges in order of decreasing probability down to threshold,
+// skipping any that would close a cycle
+func (p *EdgePosterior) DAG(threshold float64) *graph.DAG {
+	dag := graph.NewDAG()
+	for _, v := range p.Variables {
+		dag.AddNode(v)
+	}
+	for _, e := range p.Edges(threshold) {
+		_ = dag.AddEdge(e[0], e[1]) // Rejected if it would create a cycle
+	}
+	return dag
+}
+
+// forEachSubset calls fn with every k-element subset of elements, reusing
+// the slice between calls
+func forEachSubset(elements []int, k int, fn func([]int)) {
+	subset := make([]int, k)
+	var rec func(start, depth int)
+	rec = func(start, depth int) {
+		if depth == k {
+			fn(subset)
+			return
+		}
+		for i := start; i <= len(elements)-(k-depth); i++ {
+			subset[depth] = elements[i]
+			rec(i+1, depth+1)
+		}
+	}
+	rec(0, 0)
+}
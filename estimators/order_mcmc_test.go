@@ -0,0 +1,50 @@
+package estimators
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderMCMC(t *testing.T) {
+	m := NewOrderMCMC(colliderData(2000, 1))
+	m.SetIterations(3000, 500)
+	posterior, err := m.Estimate(7)
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+
+	// Strongly dependent pairs are adjacent in almost every sampled DAG
+	adjacent := func(x, y string) float64 {
+		return posterior.Probability[x][y] + posterior.Probability[y][x]
+	}
+	for _, pair := range [][2]string{{"A", "C"}, {"B", "C"}, {"C", "D"}} {
+		if p := adjacent(pair[0], pair[1]); p < 0.99 {
+			t.Errorf("P(%s - %s) = %f, expected near 1", pair[0], pair[1], p)
+		}
+	}
+	// The collider's edges are compelled, so their direction is identified
+	for _, parent := range []string{"A", "B"} {
+		if p := posterior.Probability[parent]["C"]; p < 0.75 {
+			t.Errorf("P(%s -> C) = %f, expected most of the mass", parent, p)
+		}
+	}
+	if p := adjacent("A", "D"); p > 0.05 {
+		t.Errorf("P(A - D) = %f, expected near 0", p)
+	}
+	if r := posterior.AcceptanceRate; r <= 0 || r > 1 {
+		t.Errorf("Acceptance rate %f is not in (0, 1]", r)
+	}
+	if posterior.Samples != 3000/m.Thin {
+		t.Errorf("Expected %d samples, got %d", 3000/m.Thin, posterior.Samples)
+	}
+
+	again, _ := m.Estimate(7)
+	if !reflect.DeepEqual(posterior, again) {
+		t.Error("Expected the same seed to give the same posterior")
+	}
+
+	dag := posterior.DAG(0.5)
+	if !dag.HasEdge("A", "C") || !dag.HasEdge("B", "C") {
+		t.Errorf("Expected the thresholded DAG to keep the collider, got %v", edgeSet(dag))
+	}
+}
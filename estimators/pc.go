@@ -1,17 +1,31 @@
 package estimators
 
 import (
+	"fmt"
+	"math"
 	"sort"
 
 	"github.com/JohnPierman/bngo/graph"
 )
 
-// PCEstimator implements the PC (Peter-Clark) algorithm for structure learning
+// PCEstimator implements the PC (Peter-Clark) algorithm for structure
+// learning. Discrete data are tested with chi-square tests; continuous data,
+// given to NewContinuousPC, with Fisher's Z on partial correlations.
 type PCEstimator struct {
 	Data        []map[string]int
 	Variables   []string
 	Cardinality map[string]int
 	Alpha       float64 // Significance level for independence tests
+
+	ContinuousData []map[string]float64 // Continuous samples, used in place of Data when set
+	Correlation    CorrelationType      // Correlation tested on ContinuousData, Pearson by default
+	KernelCI       *KernelCIOptions     // Kernel CI test on ContinuousData in place of partial correlations, when set
+	CMI            *CMIOptions          // Conditional mutual information test in place of the others, when set
+	Weights        []float64            // Per-sample weights, set by SetWeights
+
+	// Progress, if set, is called after each conditioning-set size with the
+	// number of edges remaining in the skeleton as the score
+	Progress ProgressFunc
 }
 
 // NewPC creates a new PC estimator
@@ -43,13 +57,73 @@ func NewPC(data []map[string]int) *PCEstimator {
 	}
 }
 
+// NewContinuousPC creates a PC estimator for continuous data. Every sample
+// must hold every variable.
+func NewContinuousPC(data []map[string]float64) *PCEstimator {
+	return &PCEstimator{
+		ContinuousData: data,
+		Variables:      continuousVariables(data),
+		Alpha:          0.05,
+		Correlation:    CorrelationPearson,
+	}
+}
+
 // SetAlpha sets the significance level for independence tests
 func (pc *PCEstimator) SetAlpha(alpha float64) {
 	pc.Alpha = alpha
 }
 
+// SetCorrelation sets the correlation tested on continuous data. Pearson
+// correlations suit Gaussian data; the rank-based Spearman and Kendall
+// correlations keep the tests valid when relationships are monotone but
+// non-Gaussian, as in the rank PC algorithm of Harris and Drton.
+func (pc *PCEstimator) SetCorrelation(correlation CorrelationType) {
+	pc.Correlation = correlation
+}
+
+// SetKernelTest makes PC test continuous data with KernelCITest, which
+// detects nonlinear dependence that partial correlations miss at the cost of
+// permutation tests on kernel matrices
+func (pc *PCEstimator) SetKernelTest(opts KernelCIOptions) {
+	pc.KernelCI = &opts
+	pc.CMI = nil
+}
+
+// SetCMITest makes PC test with CMITest, on discrete data or on continuous
+// data, which may mix in discrete variables as their state numbers
+func (pc *PCEstimator) SetCMITest(opts CMIOptions) {
+	pc.CMI = &opts
+	pc.KernelCI = nil
+}
+
+// SetWeights weights the samples in every independence test, one weight per
+// sample of the data. Chi-square and correlation tests count each sample by
+// its weight; the kernel and CMI tests draw the samples they keep in
+// proportion to it.
+func (pc *PCEstimator) SetWeights(weights []float64) error {
+	n := len(pc.Data)
+	if pc.ContinuousData != nil {
+		n = len(pc.ContinuousData)
+	}
+	if err := checkWeights(weights, n); err != nil {
+		return err
+	}
+	pc.Weights = weights
+	return nil
+}
+
+// SetProgress sets the hook called as edge removal advances
+func (pc *PCEstimator) SetProgress(hook ProgressFunc) {
+	pc.Progress = hook
+}
+
 // Estimate learns the graph structure using the PC algorithm
 func (pc *PCEstimator) Estimate() (*graph.DAG, error) {
+	test, err := pc.independenceTest()
+	if err != nil {
+		return nil, err
+	}
+
 	// Start with complete undirected graph
 	ug := graph.NewUndirectedGraph()
 	for _, v := range pc.Variables {
@@ -70,6 +144,7 @@ func (pc *PCEstimator) Estimate() (*graph.DAG, error) {
 	}
 
 	// Phase 1: Edge removal
+	reporter := newProgressReporter(pc.Progress)
 	maxCondSetSize := len(pc.Variables) - 2
 	for condSetSize := 0; condSetSize <= maxCondSetSize; condSetSize++ {
 		changed := false
@@ -91,7 +166,7 @@ func (pc *PCEstimator) Estimate() (*graph.DAG, error) {
 
 				for _, condSet := range condSets {
 					// Test conditional independence
-					_, pValue := ChiSquareTest(pc.Data, x, y, condSet, pc.Cardinality)
+					pValue := test(x, y, condSet)
 
 					if pValue > pc.Alpha {
 						// X and Y are conditionally independent given condSet
@@ -109,6 +184,8 @@ func (pc *PCEstimator) Estimate() (*graph.DAG, error) {
 			}
 		}
 
+		reporter.report(condSetSize+1, float64(len(ug.Edges())))
+
 		if !changed && condSetSize > 0 {
 			break
 		}
@@ -120,6 +197,89 @@ func (pc *PCEstimator) Estimate() (*graph.DAG, error) {
 	return dag, nil
 }
 
+// independenceTest returns the p-value of X independent of Y given Z under
+// the estimator's test: the CMI test if set, otherwise chi-square on discrete
+// data, and on continuous data the kernel test if set, otherwise Fisher's Z
+// on partial correlations, computed once as a correlation matrix
+func (pc *PCEstimator) independenceTest() (func(x, y string, z []string) float64, error) {
+	if pc.CMI != nil && pc.ContinuousData == nil {
+		opts := *pc.CMI
+		opts.Weights = pc.Weights
+		rows := make([][]float64, 0, len(pc.Data))
+		for s, sample := range pc.Data {
+			row := make([]float64, len(pc.Variables))
+			for j, v := range pc.Variables {
+				value, ok := sample[v]
+				if !ok {
+					return nil, fmt.Errorf("sample %d has no value for %s", s, v)
+				}
+				row[j] = float64(value)
+			}
+			rows = append(rows, row)
+		}
+		return pc.rowTest(rows, func(rows [][]float64, x, y int, z []int) float64 {
+			_, pValue := CMITest(rows, x, y, z, opts)
+			return pValue
+		}), nil
+	}
+	if pc.ContinuousData == nil {
+		return func(x, y string, z []string) float64 {
+			_, pValue := WeightedChiSquareTest(pc.Data, pc.Weights, x, y, z, pc.Cardinality)
+			return pValue
+		}, nil
+	}
+
+	data, err := centredData(pc.ContinuousData, pc.Variables)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]float64, len(pc.ContinuousData))
+	for i := range rows {
+		rows[i] = data.RawRowView(i)
+	}
+	if pc.CMI != nil {
+		opts := *pc.CMI
+		opts.Weights = pc.Weights
+		return pc.rowTest(rows, func(rows [][]float64, x, y int, z []int) float64 {
+			_, pValue := CMITest(rows, x, y, z, opts)
+			return pValue
+		}), nil
+	}
+	if pc.KernelCI != nil {
+		opts := *pc.KernelCI
+		opts.Weights = pc.Weights
+		return pc.rowTest(rows, func(rows [][]float64, x, y int, z []int) float64 {
+			_, pValue := KernelCITest(rows, x, y, z, opts)
+			return pValue
+		}), nil
+	}
+
+	r, err := correlationMatrix(data, pc.Correlation, pc.Weights)
+	if err != nil {
+		return nil, err
+	}
+	n := int(math.Round(totalWeight(pc.Weights, len(rows))))
+	return pc.rowTest(rows, func(rows [][]float64, x, y int, z []int) float64 {
+		return FisherZ(matrixPartialCorrelation(r, x, y, z), n, len(z))
+	}), nil
+}
+
+// rowTest adapts a test on rows holding the variables in Variables order to
+// take variable names
+func (pc *PCEstimator) rowTest(rows [][]float64, test func(rows [][]float64, x, y int, z []int) float64) func(x, y string, z []string) float64 {
+	index := make(map[string]int, len(pc.Variables))
+	for i, v := range pc.Variables {
+		index[v] = i
+	}
+	return func(x, y string, z []string) float64 {
+		zIdx := make([]int, len(z))
+		for i, v := range z {
+			zIdx[i] = index[v]
+		}
+		return test(rows, index[x], index[y], zIdx)
+	}
+}
+
 // orientEdges converts undirected graph to PDAG/DAG using v-structures and Meek's rules
 func (pc *PCEstimator) orientEdges(ug *graph.UndirectedGraph, sepSets map[string]map[string][]string) *graph.DAG {
 	dag := graph.NewDAG()
@@ -0,0 +1,57 @@
+package estimators
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// sampleContinuousCollider draws X -> Z <- Y, Z -> W with Gaussian noise
+func sampleContinuousCollider(n int, seed int64) []map[string]float64 {
+	r := rand.New(rand.NewSource(seed))
+	data := make([]map[string]float64, n)
+	for i := range data {
+		x, y := r.NormFloat64(), r.NormFloat64()
+		z := x + y + 0.5*r.NormFloat64()
+		data[i] = map[string]float64{"X": x, "Y": y, "Z": z, "W": z + 0.5*r.NormFloat64()}
+	}
+	return data
+}
+
+func TestContinuousPCCollider(t *testing.T) {
+	data := sampleContinuousCollider(1000, 1)
+	want := []string{"X->Z", "Y->Z", "Z->W"}
+	for _, correlation := range []CorrelationType{CorrelationPearson, CorrelationSpearman, CorrelationKendall} {
+		pc := NewContinuousPC(data)
+		pc.SetCorrelation(correlation)
+		dag, err := pc.Estimate()
+		if err != nil {
+			t.Fatalf("%s: Estimate failed: %v", correlation, err)
+		}
+		// The v-structure orients X and Y into Z, and Meek's first rule
+		// orients Z -> W
+		if got := edgeSet(dag); !equalStrings(got, want) {
+			t.Errorf("%s: expected edges %v, got %v", correlation, want, got)
+		}
+	}
+
+	// Ranks do not change under monotone transforms of the variables
+	skewed := make([]map[string]float64, len(data))
+	for i, sample := range data {
+		skewed[i] = make(map[string]float64, len(sample))
+		for v, value := range sample {
+			skewed[i][v] = math.Exp(2 * value)
+		}
+	}
+	for _, correlation := range []CorrelationType{CorrelationSpearman, CorrelationKendall} {
+		pc := NewContinuousPC(skewed)
+		pc.SetCorrelation(correlation)
+		dag, err := pc.Estimate()
+		if err != nil {
+			t.Fatalf("%s: Estimate failed: %v", correlation, err)
+		}
+		if got := edgeSet(dag); !equalStrings(got, want) {
+			t.Errorf("%s on transformed data: expected edges %v, got %v", correlation, want, got)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package estimators
+
+import "time"
+
+// Progress describes the state of a long-running learner or sampler
+type Progress struct {
+	Iteration int           // Iteration, level or sweep number, starting at 1
+	Score     float64       // Current score, log-likelihood or other progress measure
+	Elapsed   time.Duration // Time since the run started
+}
+
+// ProgressFunc receives progress reports. It is called synchronously, so
+// slow hooks slow down the run.
+type ProgressFunc func(Progress)
+
+// progressReporter tracks elapsed time and forwards reports to an optional hook
+type progressReporter struct {
+	hook  ProgressFunc
+	start time.Time
+}
+
+func newProgressReporter(hook ProgressFunc) *progressReporter {
+	return &progressReporter{hook: hook, start: time.Now()}
+}
+
+func (r *progressReporter) report(iteration int, score float64) {
+	if r.hook == nil {
+		return
+	}
+	r.hook(Progress{Iteration: iteration, Score: score, Elapsed: time.Since(r.start)})
+}
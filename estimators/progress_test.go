@@ -0,0 +1,81 @@
+package estimators
+
+import (
+	"math"
+	"testing"
+)
+
+// recordProgress returns a hook collecting reports and a check that their
+// iterations count up from 1 and their elapsed times never decrease
+func recordProgress(t *testing.T) (ProgressFunc, *[]Progress) {
+	var reports []Progress
+	return func(p Progress) {
+		if n := len(reports); n > 0 && p.Elapsed < reports[n-1].Elapsed {
+			t.Errorf("Elapsed time went back from %v to %v", reports[n-1].Elapsed, p.Elapsed)
+		}
+		if p.Iteration != len(reports)+1 {
+			t.Errorf("Report %d has iteration %d", len(reports)+1, p.Iteration)
+		}
+		reports = append(reports, p)
+	}, &reports
+}
+
+func TestPCProgress(t *testing.T) {
+	hook, reports := recordProgress(t)
+	pc := NewPC(colliderData(2000, 1))
+	pc.SetProgress(hook)
+	dag, err := pc.Estimate()
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	if len(*reports) == 0 {
+		t.Fatal("Expected progress reports")
+	}
+	// The score is the number of skeleton edges left, which only falls
+	for i := 1; i < len(*reports); i++ {
+		if (*reports)[i].Score > (*reports)[i-1].Score {
+			t.Errorf("Edges remaining rose from %v to %v", (*reports)[i-1].Score, (*reports)[i].Score)
+		}
+	}
+	last := (*reports)[len(*reports)-1]
+	if int(last.Score) != len(dag.Edges()) {
+		t.Errorf("Last report has %v edges, the result %d", last.Score, len(dag.Edges()))
+	}
+}
+
+func TestHillClimbProgress(t *testing.T) {
+	hook, reports := recordProgress(t)
+	hc := NewHillClimb(colliderData(2000, 2))
+	hc.SetProgress(hook)
+	dag, _, trajectory, err := hc.climb(nil)
+	if err != nil {
+		t.Fatalf("climb failed: %v", err)
+	}
+	// One report per move with the DAG score after it
+	if len(*reports) != len(trajectory)-1 {
+		t.Fatalf("Expected %d reports, got %d", len(trajectory)-1, len(*reports))
+	}
+	for i, p := range *reports {
+		if p.Score != trajectory[i+1] {
+			t.Errorf("Report %d has score %f, the trajectory %f", i+1, p.Score, trajectory[i+1])
+		}
+	}
+	last := (*reports)[len(*reports)-1]
+	if got := DAGScore(hc.Score, dag); math.Abs(last.Score-got) > 1e-6 {
+		t.Errorf("Last report has score %f, the result scores %f", last.Score, got)
+	}
+}
+
+func TestOrderMCMCProgress(t *testing.T) {
+	hook, reports := recordProgress(t)
+	m := NewOrderMCMC(colliderData(500, 3))
+	m.SetIterations(400, 100)
+	m.SetProgress(hook)
+	if _, err := m.Estimate(1); err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	// One report per sweep of len(Variables) proposals
+	if want := 500 / len(m.Variables); len(*reports) != want {
+		t.Errorf("Expected %d reports, got %d", want, len(*reports))
+	}
+}
@@ -0,0 +1,86 @@
+package estimators
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// RestartRun is one local search of a random-restart run
+type RestartRun struct {
+	Start      *graph.DAG // Random initial DAG
+	DAG        *graph.DAG // Local optimum reached from Start, or the best DAG a tabu search saw
+	Score      float64    // Score of DAG
+	Trajectory []float64  // Score at the start and after each move
+}
+
+// RestartResult is the outcome of a random-restart search
+type RestartResult struct {
+	Best      *graph.DAG // Highest-scoring local optimum, the first on ties
+	BestScore float64
+	Runs      []RestartRun
+}
+
+// EstimateWithRestarts runs the search from restarts random DAGs and returns
+// the best local optimum with every run's trajectory. Initial DAGs add each
+// edge consistent with a random variable order with probability edgeProb,
+// within the in-degree limit. Runs share one score cache, so later runs mostly
+// reuse family scores.
+func (hc *HillClimbEstimator) EstimateWithRestarts(restarts int, edgeProb float64, seed int64) (*RestartResult, error) {
+	if restarts < 1 {
+		return nil, fmt.Errorf("restarts must be at least 1, got %d", restarts)
+	}
+	if edgeProb < 0 || edgeProb > 1 {
+		return nil, fmt.Errorf("edge probability %v is not in [0, 1]", edgeProb)
+	}
+
+	saved := hc.Score
+	cache, ok := hc.Score.(*ScoreCache)
+	if !ok {
+		cache = NewScoreCache(hc.Score)
+		hc.Score = cache
+		defer func() { hc.Score = saved }()
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	result := &RestartResult{Runs: make([]RestartRun, 0, restarts)}
+	for i := 0; i < restarts; i++ {
+		start := randomDAG(hc.Variables, edgeProb, hc.MaxIndegree, r)
+		dag, score, trajectory, err := hc.climb(start)
+		if err != nil {
+			return nil, fmt.Errorf("restart %d: %w", i, err)
+		}
+		run := RestartRun{Start: start, DAG: dag, Score: score, Trajectory: trajectory}
+		result.Runs = append(result.Runs, run)
+		if i == 0 || run.Score > result.BestScore {
+			result.Best, result.BestScore = dag, run.Score
+		}
+	}
+	return result, nil
+}
+
+// randomDAG orders the variables at random and adds each forward edge with
+// probability p, skipping edges into nodes at the in-degree limit
+func randomDAG(variables []string, p float64, maxIndegree int, r *rand.Rand) *graph.DAG {
+	order := append([]string(nil), variables...)
+	r.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+
+	dag := graph.NewDAG()
+	for _, v := range order {
+		dag.AddNode(v)
+	}
+	for j, child := range order {
+		indegree := 0
+		for _, parent := range order[:j] {
+			if maxIndegree > 0 && indegree >= maxIndegree {
+				break
+			}
+			if r.Float64() < p {
+				_ = dag.AddEdge(parent, child) // Forward in the order, so acyclic
+				indegree++
+			}
+		}
+	}
+	return dag
+}
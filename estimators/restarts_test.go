@@ -0,0 +1,42 @@
+package estimators
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateWithRestarts(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		hc := NewHillClimb(colliderData(2000, seed))
+		single, err := hc.EstimateWithRestarts(1, 0.3, seed)
+		if err != nil {
+			t.Fatalf("EstimateWithRestarts failed: %v", err)
+		}
+		result, err := hc.EstimateWithRestarts(10, 0.3, seed)
+		if err != nil {
+			t.Fatalf("EstimateWithRestarts failed: %v", err)
+		}
+		if result.BestScore < single.BestScore-1e-9 {
+			t.Errorf("Seed %d: 10 restarts scored %f, a single climb %f", seed, result.BestScore, single.BestScore)
+		}
+		if len(result.Runs) != 10 {
+			t.Fatalf("Expected 10 runs, got %d", len(result.Runs))
+		}
+		for i, run := range result.Runs {
+			if run.Score > result.BestScore {
+				t.Errorf("Seed %d: run %d scored %f, above the best %f", seed, i, run.Score, result.BestScore)
+			}
+			if got := DAGScore(hc.Score, run.DAG); math.Abs(got-run.Score) > 1e-6 {
+				t.Errorf("Seed %d: run %d reports %f, its DAG scores %f", seed, i, run.Score, got)
+			}
+		}
+	}
+
+	hc := NewHillClimb(colliderData(100, 1))
+	if _, err := hc.EstimateWithRestarts(0, 0.3, 1); err == nil {
+		t.Error("Expected an error for zero restarts")
+	}
+	if _, err := hc.EstimateWithRestarts(1, 1.5, 1); err == nil {
+		t.Error("Expected an error for an edge probability above 1")
+	}
+}
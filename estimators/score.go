@@ -0,0 +1,191 @@
+package estimators
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// Score is a decomposable structure score: the score of a DAG is the sum of
+// the local scores of its families, so a move that changes one node's parents
+// only changes that node's term. Higher is better.
+type Score interface {
+	LocalScore(variable string, parents []string) float64
+}
+
+// BICScore is the Bayesian information criterion of discrete data, the
+// log-likelihood of each family less ½ log N per free parameter
+type BICScore struct {
+	Data        []map[string]int
+	Cardinality map[string]int
+	Weights     []float64 // Per-sample weights, one per sample; every sample counts once when nil
+}
+
+// NewBICScore creates a BIC score, reading cardinalities from the data
+func NewBICScore(data []map[string]int) *BICScore {
+	return &BICScore{Data: data, Cardinality: dataCardinality(data)}
+}
+
+// LocalScore returns the BIC of variable given parents. Samples missing any
+// of the family's variables are skipped.
+func (s *BICScore) LocalScore(variable string, parents []string) float64 {
+	counts := familyCounts(s.Data, s.Weights, variable, parents, s.Cardinality)
+
+	ll := 0.0
+	for _, row := range counts {
+		total := 0.0
+		for _, n := range row {
+			total += n
+		}
+		for _, n := range row {
+			if n > 0 {
+				ll += n * math.Log(n/total)
+			}
+		}
+	}
+
+	q := 1
+	for _, p := range parents {
+		q *= s.Cardinality[p]
+	}
+	params := float64((s.Cardinality[variable] - 1) * q)
+	return ll - 0.5*math.Log(math.Max(totalWeight(s.Weights, len(s.Data)), 1))*params
+}
+
+// BDeuScore is the Bayesian Dirichlet equivalent uniform score, the log
+// marginal likelihood of each family under a Dirichlet prior spreading an
+// equivalent sample size evenly over its parameters
+type BDeuScore struct {
+	Data                 []map[string]int
+	Cardinality          map[string]int
+	EquivalentSampleSize float64
+	Weights              []float64 // Per-sample weights, one per sample; every sample counts once when nil
+}
+
+// NewBDeuScore creates a BDeu score with the given equivalent sample size,
+// reading cardinalities from the data
+func NewBDeuScore(data []map[string]int, equivalentSampleSize float64) *BDeuScore {
+	return &BDeuScore{Data: data, Cardinality: dataCardinality(data), EquivalentSampleSize: equivalentSampleSize}
+}
+
+// LocalScore returns the log marginal likelihood of variable given parents.
+// Parent configurations that never occur contribute nothing.
+func (s *BDeuScore) LocalScore(variable string, parents []string) float64 {
+	counts := familyCounts(s.Data, s.Weights, variable, parents, s.Cardinality)
+
+	q := 1.0
+	for _, p := range parents {
+		q *= float64(s.Cardinality[p])
+	}
+	r := float64(s.Cardinality[variable])
+	alphaJ := s.EquivalentSampleSize / q
+	alphaJK := alphaJ / r
+	lgAlphaJ, _ := math.Lgamma(alphaJ)
+	lgAlphaJK, _ := math.Lgamma(alphaJK)
+
+	score := 0.0
+	for _, row := range counts {
+		total := 0.0
+		for _, n := range row {
+			total += n
+			if n > 0 {
+				lg, _ := math.Lgamma(alphaJK + n)
+				score += lg - lgAlphaJK
+			}
+		}
+		lg, _ := math.Lgamma(alphaJ + total)
+		score += lgAlphaJ - lg
+	}
+	return score
+}
+
+// familyCounts counts the states of variable for each observed configuration
+// of parents, adding each sample's weight. Rows come in the order their
+// configurations first occur, so scores summed over them are reproducible.
+func familyCounts(data []map[string]int, weights []float64, variable string, parents []string, cardinality map[string]int) [][]float64 {
+	var counts [][]float64
+	rows := make(map[int]int) // Configuration index, last parent fastest, to row
+	for s, sample := range data {
+		val, ok := sample[variable]
+		if !ok {
+			continue
+		}
+		idx, stride := 0, 1
+		for i := len(parents) - 1; i >= 0 && ok; i-- {
+			var pVal int
+			pVal, ok = sample[parents[i]]
+			idx += pVal * stride
+			stride *= cardinality[parents[i]]
+		}
+		if !ok {
+			continue
+		}
+		row, found := rows[idx]
+		if !found {
+			row = len(counts)
+			rows[idx] = row
+			counts = append(counts, make([]float64, cardinality[variable]))
+		}
+		counts[row][val] += sampleWeight(weights, s)
+	}
+	return counts
+}
+
+// dataCardinality returns one more than the largest state seen of each
+// variable
+func dataCardinality(data []map[string]int) map[string]int {
+	cardinality := make(map[string]int)
+	for _, sample := range data {
+		for v, value := range sample {
+			if value+1 > cardinality[v] {
+				cardinality[v] = value + 1
+			}
+		}
+	}
+	return cardinality
+}
+
+// ScoreCache memoizes the local scores of another score by family, so local
+// search only scores the families an operator changes, and only once. It is
+// not safe for concurrent use.
+type ScoreCache struct {
+	score  Score
+	scores map[string]float64
+	misses int
+}
+
+// NewScoreCache wraps a score with a family cache
+func NewScoreCache(score Score) *ScoreCache {
+	return &ScoreCache{score: score, scores: make(map[string]float64)}
+}
+
+// LocalScore returns the cached score of the family, computing it on first use.
+// The order of parents does not matter.
+func (c *ScoreCache) LocalScore(variable string, parents []string) float64 {
+	sorted := append([]string(nil), parents...)
+	sort.Strings(sorted)
+	key := variable + "|" + strings.Join(sorted, ",")
+	if s, ok := c.scores[key]; ok {
+		return s
+	}
+	c.misses++
+	s := c.score.LocalScore(variable, sorted)
+	c.scores[key] = s
+	return s
+}
+
+// Evaluations returns the number of families scored by the wrapped score
+func (c *ScoreCache) Evaluations() int {
+	return c.misses
+}
+
+// DAGScore returns the score of a DAG, the sum of its families' local scores
+func DAGScore(score Score, dag *graph.DAG) float64 {
+	total := 0.0
+	for _, v := range dag.Nodes() {
+		total += score.LocalScore(v, dag.Parents(v))
+	}
+	return total
+}
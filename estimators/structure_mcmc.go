@@ -0,0 +1,230 @@
+package estimators
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// StructureMCMC samples DAGs by Metropolis-Hastings with edge addition,
+// removal and reversal proposals (Madigan & York, 1995). Each proposal picks
+// an ordered pair of variables at random, which keeps proposals symmetric, so
+// a move is accepted with probability min(1, exp(score change)). The score
+// must be a log marginal likelihood such as BDeu for the samples to follow
+// the structure posterior.
+type StructureMCMC struct {
+	Data        []map[string]int
+	Variables   []string
+	Cardinality map[string]int
+	Score       Score // Decomposable log marginal likelihood; BDeu with sample size 1 by default
+
+	MaxIndegree int // Maximum parents per node, 0 for no limit
+	Iterations  int // Proposals after burn-in
+	BurnIn      int // Proposals discarded before sampling
+	Thin        int // Proposals between recorded samples
+
+	// Progress, if set, is called after each sweep of len(Variables)
+	// proposals with the score of the current DAG
+	Progress ProgressFunc
+}
+
+// StructurePosterior holds the DAGs sampled by StructureMCMC and the edge
+// probabilities they give
+type StructurePosterior struct {
+	EdgePosterior
+	DAGs   []*graph.DAG // Recorded samples, in order
+	Scores []float64    // Score of each recorded DAG
+}
+
+// NewStructureMCMC creates a structure sampler scoring with BDeu
+func NewStructureMCMC(data []map[string]int) *StructureMCMC {
+	cardinality := dataCardinality(data)
+	variables := make([]string, 0, len(cardinality))
+	for v := range cardinality {
+		variables = append(variables, v)
+	}
+	sort.Strings(variables)
+
+	return &StructureMCMC{
+		Data:        data,
+		Variables:   variables,
+		Cardinality: cardinality,
+		Score:       &BDeuScore{Data: data, Cardinality: cardinality, EquivalentSampleSize: 1},
+		Iterations:  50000,
+		BurnIn:      5000,
+		Thin:        50,
+	}
+}
+
+// SetScore sets the structure score
+func (m *StructureMCMC) SetScore(score Score) {
+	m.Score = score
+}
+
+// SetWeights weights the samples of the score, one weight per sample, for
+// bootstrap aggregation, importance weighting or class rebalancing. The
+// score must be a BICScore or BDeuScore, so call it after SetScore.
+func (m *StructureMCMC) SetWeights(weights []float64) error {
+	return weightScore(m.Score, weights)
+}
+
+// SetMaxIndegree limits the number of parents of each node
+func (m *StructureMCMC) SetMaxIndegree(k int) {
+	m.MaxIndegree = k
+}
+
+// SetIterations sets the number of proposals kept and discarded
+func (m *StructureMCMC) SetIterations(iterations, burnIn int) {
+	m.Iterations = iterations
+	m.BurnIn = burnIn
+}
+
+// SetProgress sets the hook called after each sweep
+func (m *StructureMCMC) SetProgress(hook ProgressFunc) {
+	m.Progress = hook
+}
+
+// Estimate runs the sampler from the empty graph
+func (m *StructureMCMC) Estimate(seed int64) (*StructurePosterior, error) {
+	return m.EstimateFrom(nil, seed)
+}
+
+// EstimateFrom runs the sampler from start, or from the empty graph if start
+// is nil. Starting from a good DAG, such as a hill-climbing result, shortens
+// the burn-in.
+func (m *StructureMCMC) EstimateFrom(start *graph.DAG, seed int64) (*StructurePosterior, error) {
+	n := len(m.Variables)
+	if n < 2 {
+		return nil, fmt.Errorf("structure MCMC needs at least 2 variables, got %d", n)
+	}
+	if m.Iterations < 1 || m.Thin < 1 || m.BurnIn < 0 {
+		return nil, fmt.Errorf("invalid schedule: %d iterations, %d burn-in, thin %d", m.Iterations, m.BurnIn, m.Thin)
+	}
+	score, ok := m.Score.(*ScoreCache)
+	if !ok {
+		score = NewScoreCache(m.Score)
+	}
+	g, err := newDAGState(m.Variables, start)
+	if err != nil {
+		return nil, err
+	}
+	if m.MaxIndegree > 0 {
+		for _, v := range m.Variables {
+			if len(g.parents[v]) > m.MaxIndegree {
+				return nil, fmt.Errorf("start DAG gives %s %d parents, more than the limit of %d", v, len(g.parents[v]), m.MaxIndegree)
+			}
+		}
+	}
+
+	current := make(map[string]float64, n)
+	total := 0.0
+	for _, v := range m.Variables {
+		current[v] = score.LocalScore(v, g.parentList(v, "", ""))
+		total += current[v]
+	}
+	fits := func(v string) bool {
+		return m.MaxIndegree <= 0 || len(g.parents[v]) < m.MaxIndegree
+	}
+
+	index := make(map[string]int, n)
+	for i, v := range m.Variables {
+		index[v] = i
+	}
+	counts := make([][]float64, n)
+	for u := range counts {
+		counts[u] = make([]float64, n)
+	}
+
+	posterior := &StructurePosterior{}
+	record := func() {
+		for child, ps := range g.parents {
+			for parent := range ps {
+				counts[index[parent]][index[child]]++
+			}
+		}
+		posterior.DAGs = append(posterior.DAGs, g.dag())
+		posterior.Scores = append(posterior.Scores, total)
+	}
+	r := rand.New(rand.NewSource(seed))
+	reporter := newProgressReporter(m.Progress)
+	accepted := 0
+	steps := m.BurnIn + m.Iterations
+	for step := 1; step <= steps; step++ {
+		i, j := r.Intn(n), r.Intn(n-1)
+		if j >= i {
+			j++
+		}
+		x, y := m.Variables[i], m.Variables[j]
+		half := r.Intn(2) == 0
+
+		// Each ordered pair proposes one of two moves with probability ½, so
+		// every move and its inverse are proposed with the same probability
+		switch {
+		case g.parents[y][x] && half:
+			newY := score.LocalScore(y, g.parentList(y, "", x))
+			if delta := newY - current[y]; accept(delta, r) {
+				g.remove(x, y)
+				current[y] = newY
+				total += delta
+				accepted++
+			}
+		case g.parents[y][x]:
+			if fits(x) && g.canReverse(x, y) {
+				newY := score.LocalScore(y, g.parentList(y, "", x))
+				newX := score.LocalScore(x, g.parentList(x, y, ""))
+				if delta := newY - current[y] + newX - current[x]; accept(delta, r) {
+					g.reverse(x, y)
+					current[x], current[y] = newX, newY
+					total += delta
+					accepted++
+				}
+			}
+		case !g.parents[x][y] && half:
+			if fits(y) && g.canAdd(x, y) {
+				newY := score.LocalScore(y, g.parentList(y, x, ""))
+				if delta := newY - current[y]; accept(delta, r) {
+					g.add(x, y)
+					current[y] = newY
+					total += delta
+					accepted++
+				}
+			}
+		}
+
+		if step > m.BurnIn && (step-m.BurnIn)%m.Thin == 0 {
+			record()
+		}
+		if step%n == 0 {
+			reporter.report(step/n, total)
+		}
+	}
+
+	if len(posterior.DAGs) == 0 {
+		record()
+	}
+	samples := len(posterior.DAGs)
+	posterior.EdgePosterior = EdgePosterior{
+		Variables:      append([]string(nil), m.Variables...),
+		Probability:    make(map[string]map[string]float64, n),
+		Samples:        samples,
+		AcceptanceRate: float64(accepted) / float64(steps),
+	}
+	for u, parent := range m.Variables {
+		posterior.Probability[parent] = make(map[string]float64, n)
+		for v, child := range m.Variables {
+			if u != v {
+				posterior.Probability[parent][child] = counts[u][v] / float64(samples)
+			}
+		}
+	}
+	return posterior, nil
+}
+
+// accept is the Metropolis test for a symmetric proposal changing the log
+// score by delta
+func accept(delta float64, r *rand.Rand) bool {
+	return delta >= 0 || r.Float64() < math.Exp(delta)
+}
@@ -0,0 +1,65 @@
+package estimators
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStructureMCMC(t *testing.T) {
+	m := NewStructureMCMC(colliderData(2000, 1))
+	m.SetIterations(20000, 2000)
+	posterior, err := m.Estimate(3)
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+
+	adjacent := func(x, y string) float64 {
+		return posterior.Probability[x][y] + posterior.Probability[y][x]
+	}
+	for _, pair := range [][2]string{{"A", "C"}, {"B", "C"}, {"C", "D"}} {
+		if p := adjacent(pair[0], pair[1]); p < 0.99 {
+			t.Errorf("P(%s - %s) = %f, expected near 1", pair[0], pair[1], p)
+		}
+	}
+	for _, parent := range []string{"A", "B"} {
+		if p := posterior.Probability[parent]["C"]; p < 0.75 {
+			t.Errorf("P(%s -> C) = %f, expected most of the mass", parent, p)
+		}
+	}
+	if p := adjacent("A", "D"); p > 0.05 {
+		t.Errorf("P(A - D) = %f, expected near 0", p)
+	}
+	if r := posterior.AcceptanceRate; r <= 0 || r > 1 {
+		t.Errorf("Acceptance rate %f is not in (0, 1]", r)
+	}
+	if len(posterior.DAGs) != posterior.Samples || len(posterior.Scores) != posterior.Samples {
+		t.Errorf("Expected %d DAGs and scores, got %d and %d", posterior.Samples, len(posterior.DAGs), len(posterior.Scores))
+	}
+
+	again, _ := m.Estimate(3)
+	if !reflect.DeepEqual(posterior.EdgePosterior, again.EdgePosterior) || !reflect.DeepEqual(posterior.Scores, again.Scores) {
+		t.Error("Expected the same seed to give the same samples")
+	}
+}
+
+func TestStructureMCMCMaxIndegree(t *testing.T) {
+	m := NewStructureMCMC(colliderData(2000, 2))
+	m.SetIterations(5000, 500)
+	m.SetMaxIndegree(1)
+	posterior, err := m.Estimate(4)
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	for i, dag := range posterior.DAGs {
+		for _, v := range dag.Nodes() {
+			if n := len(dag.Parents(v)); n > 1 {
+				t.Fatalf("Sample %d gives %s %d parents, limit is 1", i, v, n)
+			}
+		}
+	}
+
+	start, _ := NewHillClimb(colliderData(2000, 2)).Estimate()
+	if _, err := m.EstimateFrom(start, 4); err == nil {
+		t.Error("Expected an error for a start DAG over the in-degree limit")
+	}
+}
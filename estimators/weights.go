@@ -0,0 +1,124 @@
+package estimators
+
+import (
+	"fmt"
+	"math"
+)
+
+// Sample weights throughout the package are frequency weights: a sample of
+// weight 2 counts as two copies of it, and sample sizes are the total weight.
+// Importance weights should be scaled to sum to the effective sample size.
+
+// checkWeights reports whether weights hold one non-negative, finite weight
+// per sample with a positive total
+func checkWeights(weights []float64, n int) error {
+	if len(weights) != n {
+		return fmt.Errorf("got %d weights for %d samples", len(weights), n)
+	}
+	total := 0.0
+	for i, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("sample %d has invalid weight %v", i, w)
+		}
+		total += w
+	}
+	if total <= 0 {
+		return fmt.Errorf("weights sum to zero")
+	}
+	return nil
+}
+
+// sampleWeight returns the weight of sample i, 1 when weights is nil
+func sampleWeight(weights []float64, i int) float64 {
+	if weights == nil {
+		return 1
+	}
+	return weights[i]
+}
+
+// totalWeight returns the sum of the weights of n samples
+func totalWeight(weights []float64, n int) float64 {
+	if weights == nil {
+		return float64(n)
+	}
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	return total
+}
+
+// weightScore sets the sample weights of a count-based score
+func weightScore(score Score, weights []float64) error {
+	switch s := score.(type) {
+	case *BICScore:
+		if err := checkWeights(weights, len(s.Data)); err != nil {
+			return err
+		}
+		s.Weights = weights
+	case *BDeuScore:
+		if err := checkWeights(weights, len(s.Data)); err != nil {
+			return err
+		}
+		s.Weights = weights
+	case *ScoreCache:
+		return fmt.Errorf("cannot weight a cached score; weight the score it wraps before caching")
+	default:
+		return fmt.Errorf("score %T does not take sample weights", score)
+	}
+	return nil
+}
+
+// sampleRows keeps at most limit rows: evenly spaced through the data
+// without weights, otherwise drawn by systematic resampling with
+// probability proportional to weight, so that heavier rows may repeat
+func sampleRows(data [][]float64, weights []float64, limit int) ([][]float64, error) {
+	if weights == nil {
+		return evenSubset(data, limit), nil
+	}
+	if err := checkWeights(weights, len(data)); err != nil {
+		return nil, err
+	}
+	m := min(len(data), limit)
+	step := totalWeight(weights, len(data)) / float64(m)
+	kept := make([][]float64, 0, m)
+	cumulative, i := weights[0], 0
+	for k := 0; k < m; k++ {
+		target := (float64(k) + 0.5) * step
+		for cumulative < target && i < len(data)-1 {
+			i++
+			cumulative += weights[i]
+		}
+		kept = append(kept, data[i])
+	}
+	return kept, nil
+}
+
+// weightedPearson is PearsonCorrelation with sample weights
+func weightedPearson(x, y, weights []float64) float64 {
+	if weights == nil {
+		return PearsonCorrelation(x, y)
+	}
+	total, meanX, meanY := 0.0, 0.0, 0.0
+	for i, w := range weights {
+		total += w
+		meanX += w * x[i]
+		meanY += w * y[i]
+	}
+	if total == 0 {
+		return 0.0
+	}
+	meanX /= total
+	meanY /= total
+	var numerator, denomX, denomY float64
+	for i, w := range weights {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		numerator += w * dx * dy
+		denomX += w * dx * dx
+		denomY += w * dy * dy
+	}
+	if denomX == 0 || denomY == 0 {
+		return 0.0
+	}
+	return numerator / math.Sqrt(denomX*denomY)
+}
@@ -0,0 +1,150 @@
+package estimators
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// duplicated repeats each sample by its integer weight
+func duplicated[T any](data []T, weights []float64) []T {
+	var out []T
+	for i, sample := range data {
+		for k := 0; k < int(weights[i]); k++ {
+			out = append(out, sample)
+		}
+	}
+	return out
+}
+
+func TestIntegerWeightsMatchDuplicates(t *testing.T) {
+	data := colliderData(300, 1)
+	r := rand.New(rand.NewSource(2))
+	weights := make([]float64, len(data))
+	for i := range weights {
+		weights[i] = float64(r.Intn(4)) // Some samples drop out with weight 0
+	}
+	copies := duplicated(data, weights)
+
+	for _, parents := range [][]string{{}, {"A"}, {"A", "B"}} {
+		weighted, plain := NewBICScore(data), NewBICScore(copies)
+		if err := weightScore(weighted, weights); err != nil {
+			t.Fatalf("weightScore failed: %v", err)
+		}
+		if got, want := weighted.LocalScore("C", parents), plain.LocalScore("C", parents); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Weighted BIC of C | %v = %f, on duplicated rows %f", parents, got, want)
+		}
+		bdeu, plainBDeu := NewBDeuScore(data, 1), NewBDeuScore(copies, 1)
+		if err := weightScore(bdeu, weights); err != nil {
+			t.Fatalf("weightScore failed: %v", err)
+		}
+		if got, want := bdeu.LocalScore("C", parents), plainBDeu.LocalScore("C", parents); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Weighted BDeu of C | %v = %f, on duplicated rows %f", parents, got, want)
+		}
+	}
+
+	cardinality := dataCardinality(data)
+	for _, z := range [][]string{nil, {"C"}} {
+		statistic, p := WeightedChiSquareTest(data, weights, "A", "D", z, cardinality)
+		wantStatistic, wantP := ChiSquareTest(copies, "A", "D", z, cardinality)
+		if math.Abs(statistic-wantStatistic) > 1e-9 || math.Abs(p-wantP) > 1e-9 {
+			t.Errorf("Weighted chi-square of A, D | %v = (%f, %f), on duplicated rows (%f, %f)", z, statistic, p, wantStatistic, wantP)
+		}
+	}
+
+	// Fisher's Z on weighted correlations
+	continuous := sampleContinuousCollider(200, 3)
+	pc := NewContinuousPC(continuous)
+	if err := pc.SetWeights(weights[:len(continuous)]); err != nil {
+		t.Fatalf("SetWeights failed: %v", err)
+	}
+	weightedTest, err := pc.independenceTest()
+	if err != nil {
+		t.Fatalf("independenceTest failed: %v", err)
+	}
+	plainTest, err := NewContinuousPC(duplicated(continuous, weights)).independenceTest()
+	if err != nil {
+		t.Fatalf("independenceTest failed: %v", err)
+	}
+	for _, z := range [][]string{nil, {"Z"}} {
+		if got, want := weightedTest("X", "W", z), plainTest("X", "W", z); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Weighted Fisher's Z p-value of X, W | %v = %g, on duplicated rows %g", z, got, want)
+		}
+	}
+}
+
+func TestCheckWeights(t *testing.T) {
+	if err := checkWeights([]float64{1, 0, 2.5}, 3); err != nil {
+		t.Errorf("Expected valid weights, got %v", err)
+	}
+	invalid := map[string][]float64{
+		"too few":  {1, 1},
+		"negative": {1, -1, 1},
+		"NaN":      {1, math.NaN(), 1},
+		"infinite": {1, math.Inf(1), 1},
+		"all zero": {0, 0, 0},
+	}
+	for name, weights := range invalid {
+		if err := checkWeights(weights, 3); err == nil {
+			t.Errorf("Expected %s weights to be rejected", name)
+		}
+	}
+}
+
+func TestSampleRows(t *testing.T) {
+	data := [][]float64{{0}, {1}, {2}, {3}}
+	if _, err := sampleRows(data, []float64{1, 1}, 10); err == nil {
+		t.Error("Expected an error for too few weights")
+	}
+	if _, err := sampleRows(data, []float64{0, 0, 0, 0}, 10); err == nil {
+		t.Error("Expected an error for weights summing to zero")
+	}
+
+	kept, err := sampleRows(data, []float64{0, 3, 0, 1}, 4)
+	if err != nil {
+		t.Fatalf("sampleRows failed: %v", err)
+	}
+	counts := make([]int, len(data))
+	for _, row := range kept {
+		counts[int(row[0])]++
+	}
+	if counts[0] != 0 || counts[2] != 0 || counts[1] != 3 || counts[3] != 1 {
+		t.Errorf("Expected rows kept in proportion to weight, got counts %v", counts)
+	}
+
+	// Invalid weights make the tests that sample rows return NaN
+	rows := sampleGaussianCMI(50, 0.5, 1)
+	if _, p := CMITest(rows, 0, 1, nil, CMIOptions{Weights: []float64{1}}); !math.IsNaN(p) {
+		t.Errorf("Expected a NaN CMI p-value for invalid weights, got %f", p)
+	}
+	if _, p := KernelCITest(rows, 0, 1, nil, KernelCIOptions{Weights: []float64{1}}); !math.IsNaN(p) {
+		t.Errorf("Expected a NaN kernel p-value for invalid weights, got %f", p)
+	}
+}
+
+func TestWeightScore(t *testing.T) {
+	data := colliderData(10, 1)
+	if err := weightScore(NewBICScore(data), make([]float64, len(data)+1)); err == nil {
+		t.Error("Expected an error for the wrong number of weights")
+	}
+	if err := weightScore(NewScoreCache(NewBICScore(data)), make([]float64, len(data))); err == nil {
+		t.Error("Expected an error for weighting a cached score")
+	}
+	hc := NewHillClimb(data)
+	hc.SetScore(NewScoreCache(hc.Score))
+	weights := make([]float64, len(data))
+	for i := range weights {
+		weights[i] = 1
+	}
+	if err := hc.SetWeights(weights); err == nil {
+		t.Error("Expected SetWeights to reject a cached score")
+	}
+	if err := weightScore(constantScore{}, weights); err == nil {
+		t.Error("Expected an error for a score without sample weights")
+	}
+}
+
+// constantScore is a Score that does not look at the data
+type constantScore struct{}
+
+func (constantScore) LocalScore(string, []string) float64 { return 0 }
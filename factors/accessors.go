@@ -0,0 +1,125 @@
+package factors
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// StateProbability is one assignment of a factor's variables and its value
+type StateProbability struct {
+	Assignment  map[string]int
+	Probability float64
+}
+
+// checkAssignment reports variables outside the factor and states out of range
+func (f *DiscreteFactor) checkAssignment(assignment map[string]int) error {
+	vars := make([]string, 0, len(assignment))
+	for v := range assignment {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	for _, v := range vars {
+		if !f.hasVariable(v) {
+			return fmt.Errorf("variable %s is not in the factor", v)
+		}
+		if s := assignment[v]; s < 0 || s >= f.Cardinality[v] {
+			return fmt.Errorf("state %d of %s is out of range [0, %d)", s, v, f.Cardinality[v])
+		}
+	}
+	return nil
+}
+
+// ProbabilityOf returns the value of an assignment. Variables left out of the
+// assignment are summed over, so on a joint posterior P(A, B) the assignment
+// {A: 1} gives P(A = 1).
+func (f *DiscreteFactor) ProbabilityOf(assignment map[string]int) (float64, error) {
+	if err := f.checkAssignment(assignment); err != nil {
+		return 0, err
+	}
+	reduced, err := f.Reduce(assignment)
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	for _, v := range reduced.Values {
+		total += v
+	}
+	return total, nil
+}
+
+// MarginalFor returns the values of one variable by state, summing out the
+// rest of the factor
+func (f *DiscreteFactor) MarginalFor(variable string) ([]float64, error) {
+	if !f.hasVariable(variable) {
+		return nil, fmt.Errorf("variable %s is not in the factor", variable)
+	}
+	others := make([]string, 0, len(f.Variables)-1)
+	for _, v := range f.Variables {
+		if v != variable {
+			others = append(others, v)
+		}
+	}
+	marginal, err := f.Marginalize(others)
+	if err != nil {
+		return nil, err
+	}
+	return marginal.Values, nil
+}
+
+// TopStates returns the n most probable assignments, highest first, with ties
+// in index order. n <= 0 returns every assignment.
+func (f *DiscreteFactor) TopStates(n int) []StateProbability {
+	order := make([]int, len(f.Values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return f.Values[order[a]] > f.Values[order[b]]
+	})
+	if n <= 0 || n > len(order) {
+		n = len(order)
+	}
+
+	top := make([]StateProbability, n)
+	for i, idx := range order[:n] {
+		top[i] = StateProbability{Assignment: f.indexToAssignment(idx), Probability: f.Values[idx]}
+	}
+	return top
+}
+
+// indexToAssignment inverts projectAssignmentToIndex
+func (f *DiscreteFactor) indexToAssignment(idx int) map[string]int {
+	assignment := make(map[string]int, len(f.Variables))
+	for i := len(f.Variables) - 1; i >= 0; i-- {
+		v := f.Variables[i]
+		assignment[v] = idx % f.Cardinality[v]
+		idx /= f.Cardinality[v]
+	}
+	return assignment
+}
+
+// LabeledMarginals returns the marginal of each variable keyed by state name,
+// ready for JSON encoding. States takes the names of each variable's states;
+// variables without names, or with the wrong number of them, are keyed by
+// state index.
+func (f *DiscreteFactor) LabeledMarginals(states map[string][]string) map[string]map[string]float64 {
+	marginals := make(map[string]map[string]float64, len(f.Variables))
+	for _, v := range f.Variables {
+		values, _ := f.MarginalFor(v) // v is in the factor
+		labels := states[v]
+		if len(labels) != len(values) {
+			labels = nil
+		}
+		marginal := make(map[string]float64, len(values))
+		for s, p := range values {
+			if labels != nil {
+				marginal[labels[s]] = p
+			} else {
+				marginal[strconv.Itoa(s)] = p
+			}
+		}
+		marginals[v] = marginal
+	}
+	return marginals
+}
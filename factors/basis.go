@@ -0,0 +1,207 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// BasisKind names the form of a basis term
+type BasisKind string
+
+const (
+	BasisProduct BasisKind = "product" // Product of parents, a repeated parent giving a power
+	BasisHinge   BasisKind = "hinge"   // Truncated power max(0, parent - knot)^degree, a spline piece
+)
+
+// BasisTerm is a fixed nonlinear feature of the continuous parents that
+// enters a linear Gaussian CPD's mean with its own coefficient. The mean
+// stays linear in the coefficients, so they are learned by least squares.
+type BasisTerm struct {
+	Kind    BasisKind
+	Parents []string // Factors of a product; the single parent of a hinge
+	Knot    float64  // Hinge only
+	Degree  int      // Hinge only
+}
+
+// Power returns the term parent^degree
+func Power(parent string, degree int) BasisTerm {
+	parents := make([]string, degree)
+	for i := range parents {
+		parents[i] = parent
+	}
+	return BasisTerm{Kind: BasisProduct, Parents: parents}
+}
+
+// Interaction returns the product of the parents
+func Interaction(parents ...string) BasisTerm {
+	return BasisTerm{Kind: BasisProduct, Parents: append([]string(nil), parents...)}
+}
+
+// Hinge returns the term max(0, parent - knot)^degree
+func Hinge(parent string, knot float64, degree int) BasisTerm {
+	return BasisTerm{Kind: BasisHinge, Parents: []string{parent}, Knot: knot, Degree: degree}
+}
+
+// SplineBasis returns the truncated power basis of a regression spline of the
+// given degree in parent, less the linear term the CPD already has:
+// parent², ..., parent^degree and a hinge at each knot. With degree 3 the
+// mean is a cubic spline of the parent, smooth at the knots.
+func SplineBasis(parent string, knots []float64, degree int) []BasisTerm {
+	terms := make([]BasisTerm, 0, degree-1+len(knots))
+	for d := 2; d <= degree; d++ {
+		terms = append(terms, Power(parent, d))
+	}
+	for _, knot := range knots {
+		terms = append(terms, Hinge(parent, knot, degree))
+	}
+	return terms
+}
+
+// String returns a readable form such as X^2, X*Z or max(0, X-1.5)^3
+func (t BasisTerm) String() string {
+	if t.Kind == BasisHinge && len(t.Parents) == 1 {
+		return fmt.Sprintf("max(0, %s-%s)^%d", t.Parents[0], strconv.FormatFloat(t.Knot, 'g', -1, 64), t.Degree)
+	}
+	if len(t.Parents) > 1 {
+		same := true
+		for _, p := range t.Parents[1:] {
+			same = same && p == t.Parents[0]
+		}
+		if same {
+			return fmt.Sprintf("%s^%d", t.Parents[0], len(t.Parents))
+		}
+	}
+	return strings.Join(t.Parents, "*")
+}
+
+// validate checks the term's form and that it reads only continuous parents
+func (t BasisTerm) validate(parentTypes map[string]string) error {
+	switch t.Kind {
+	case BasisProduct:
+		if len(t.Parents) == 0 {
+			return fmt.Errorf("basis term has no parents")
+		}
+	case BasisHinge:
+		if len(t.Parents) != 1 || t.Degree < 1 {
+			return fmt.Errorf("hinge term %v needs one parent and a positive degree", t)
+		}
+	default:
+		return fmt.Errorf("unknown basis kind %q", t.Kind)
+	}
+	for _, p := range t.Parents {
+		if parentTypes[p] != "continuous" {
+			return fmt.Errorf("basis term %v reads %s, which is not a continuous parent", t, p)
+		}
+	}
+	return nil
+}
+
+// Eval computes the term from the parent values
+func (t BasisTerm) Eval(parentValues map[string]interface{}) (float64, error) {
+	values := make([]float64, len(t.Parents))
+	for i, p := range t.Parents {
+		floatVal, ok := parentValues[p].(float64)
+		if !ok {
+			return 0, fmt.Errorf("parent %s value must be float64", p)
+		}
+		values[i] = floatVal
+	}
+	return t.Value(values), nil
+}
+
+// Value computes the term from its parents' values in Parents order
+func (t BasisTerm) Value(values []float64) float64 {
+	if t.Kind == BasisHinge {
+		return math.Pow(math.Max(0, values[0]-t.Knot), float64(t.Degree))
+	}
+	product := 1.0
+	for _, v := range values {
+		product *= v
+	}
+	return product
+}
+
+// NewBasisGaussianCPD creates a linear Gaussian CPD whose mean also has a
+// term for each basis feature of the continuous parents:
+// X | y ~ N(β₀ + Σᵢ βᵢyᵢ + Σₖ cₖφₖ(y), σ²)
+// basisCoefficients[k] is the coefficient cₖ of basis[k]
+func NewBasisGaussianCPD(variable string, parents []string, intercept float64, coefficients map[string]float64,
+	basis []BasisTerm, basisCoefficients []float64, variance float64) (*LinearGaussianCPD, error) {
+	cpd, err := NewLinearGaussianCPD(variable, parents, intercept, coefficients, variance)
+	if err != nil {
+		return nil, err
+	}
+	if err := cpd.SetBasis(basis); err != nil {
+		return nil, err
+	}
+	if len(basisCoefficients) != len(basis) {
+		return nil, fmt.Errorf("got %d basis coefficients for %d terms", len(basisCoefficients), len(basis))
+	}
+	cpd.BasisCoefficients = append([]float64(nil), basisCoefficients...)
+	return cpd, nil
+}
+
+// SetBasis sets the basis terms of the mean. Each term must read only
+// continuous parents; the coefficients, in BasisCoefficients or in each
+// discrete state's parameters, are set separately.
+func (cpd *LinearGaussianCPD) SetBasis(basis []BasisTerm) error {
+	for _, t := range basis {
+		if err := t.validate(cpd.ParentTypes); err != nil {
+			return fmt.Errorf("CPD of %s: %w", cpd.Variable, err)
+		}
+	}
+	cpd.Basis = copyBasis(basis)
+	return nil
+}
+
+// CheckBasis reports basis terms that read no continuous parent and
+// coefficient lists that do not match the terms
+func (cpd *LinearGaussianCPD) CheckBasis() error {
+	for _, t := range cpd.Basis {
+		if err := t.validate(cpd.ParentTypes); err != nil {
+			return err
+		}
+	}
+	if len(cpd.DiscreteParents()) == 0 {
+		if len(cpd.BasisCoefficients) != len(cpd.Basis) {
+			return fmt.Errorf("got %d basis coefficients for %d terms", len(cpd.BasisCoefficients), len(cpd.Basis))
+		}
+		return nil
+	}
+	for key, params := range cpd.DiscreteStates {
+		if len(params.BasisCoefficients) != len(cpd.Basis) {
+			return fmt.Errorf("state %s has %d basis coefficients for %d terms", key, len(params.BasisCoefficients), len(cpd.Basis))
+		}
+	}
+	return nil
+}
+
+// basisMean returns Σₖ cₖφₖ(parents)
+func (cpd *LinearGaussianCPD) basisMean(coefficients []float64, parentValues map[string]interface{}) (float64, error) {
+	if len(coefficients) != len(cpd.Basis) {
+		return 0, fmt.Errorf("got %d basis coefficients for %d terms", len(coefficients), len(cpd.Basis))
+	}
+	sum := 0.0
+	for k, t := range cpd.Basis {
+		value, err := t.Eval(parentValues)
+		if err != nil {
+			return 0, err
+		}
+		sum += coefficients[k] * value
+	}
+	return sum, nil
+}
+
+func copyBasis(basis []BasisTerm) []BasisTerm {
+	if basis == nil {
+		return nil
+	}
+	out := make([]BasisTerm, len(basis))
+	for i, t := range basis {
+		t.Parents = append([]string(nil), t.Parents...)
+		out[i] = t
+	}
+	return out
+}
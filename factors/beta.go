@@ -0,0 +1,113 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// BetaCPD represents a proportion in (0, 1) with a Beta distribution whose
+// mean is logit-linear in continuous parents:
+// X | y ~ Beta(μ(y)φ, (1 - μ(y))φ), logit μ(y) = β₀ + Σᵢ βᵢyᵢ
+// The precision φ sets the variance μ(1 - μ)/(1 + φ).
+type BetaCPD struct {
+	Variable     string
+	Parents      []string
+	Intercept    float64            // β₀
+	Coefficients map[string]float64 // βᵢ for each parent
+	Precision    float64            // φ
+}
+
+// NewBetaCPD creates a Beta CPD with a logit-linear mean
+func NewBetaCPD(variable string, parents []string, intercept float64, coefficients map[string]float64, precision float64) (*BetaCPD, error) {
+	if precision <= 0 || math.IsNaN(precision) {
+		return nil, fmt.Errorf("precision of %s must be positive, got %v: %w", variable, precision, ErrInvalidDistribution)
+	}
+	for _, p := range parents {
+		if _, ok := coefficients[p]; !ok {
+			return nil, fmt.Errorf("missing coefficient for parent %s", p)
+		}
+	}
+	coefs := make(map[string]float64, len(coefficients))
+	for p, c := range coefficients {
+		coefs[p] = c
+	}
+	return &BetaCPD{
+		Variable:     variable,
+		Parents:      append([]string(nil), parents...),
+		Intercept:    intercept,
+		Coefficients: coefs,
+		Precision:    precision,
+	}, nil
+}
+
+// GetVariable returns the child variable
+func (cpd *BetaCPD) GetVariable() string {
+	return cpd.Variable
+}
+
+// GetParents returns a copy of the CPD's parents
+func (cpd *BetaCPD) GetParents() []string {
+	return append([]string(nil), cpd.Parents...)
+}
+
+// GetMean returns the conditional mean μ given the parent values
+func (cpd *BetaCPD) GetMean(parentValues map[string]interface{}) (float64, error) {
+	eta, err := linearPredictor(cpd.Intercept, cpd.Coefficients, cpd.Parents, parentValues)
+	if err != nil {
+		return 0, err
+	}
+	return 1 / (1 + math.Exp(-eta)), nil
+}
+
+// Distribution returns Beta(μφ, (1-μ)φ) for the conditional mean μ given
+// the parent values and the precision φ, drawing from rng. rng may be nil
+// when the result is not sampled.
+func (cpd *BetaCPD) Distribution(parentValues map[string]interface{}, rng *rand.Rand) (Distribution, error) {
+	mean, err := cpd.GetMean(parentValues)
+	if err != nil {
+		return nil, err
+	}
+	return distuv.Beta{Alpha: mean * cpd.Precision, Beta: (1 - mean) * cpd.Precision, Src: source(rng)}, nil
+}
+
+// Sample draws a proportion given the parent values
+func (cpd *BetaCPD) Sample(parentValues map[string]interface{}, rng *rand.Rand) (float64, error) {
+	d, err := cpd.Distribution(parentValues, rng)
+	if err != nil {
+		return 0, err
+	}
+	x := d.Rand()
+	// Tiny shapes can underflow both Gamma draws behind a Beta draw to zero;
+	// keep the result inside (0, 1)
+	if math.IsNaN(x) {
+		return cpd.GetMean(parentValues)
+	}
+	return math.Min(math.Max(x, math.SmallestNonzeroFloat64), math.Nextafter(1, 0)), nil
+}
+
+// LogPDF evaluates the log density at x. Values outside (0, 1) have zero
+// density.
+func (cpd *BetaCPD) LogPDF(x float64, parentValues map[string]interface{}) (float64, error) {
+	d, err := cpd.Distribution(parentValues, nil)
+	if err != nil {
+		return 0, err
+	}
+	if x <= 0 || x >= 1 {
+		return math.Inf(-1), nil
+	}
+	return d.LogProb(x), nil
+}
+
+// Copy creates a deep copy
+func (cpd *BetaCPD) Copy() *BetaCPD {
+	copied, _ := NewBetaCPD(cpd.Variable, cpd.Parents, cpd.Intercept, cpd.Coefficients, cpd.Precision)
+	return copied
+}
+
+// String returns a string representation
+func (cpd *BetaCPD) String() string {
+	return fmt.Sprintf("BetaCPD(%s | %v, precision %g)", cpd.Variable, cpd.Parents, cpd.Precision)
+}
@@ -0,0 +1,331 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// CanonicalFactor is a Gaussian potential in canonical (information) form:
+// φ(x) = exp(-0.5 x^T K x + h^T x + g)
+// Unlike GaussianFactor it can represent conditionals such as P(X | Y), whose
+// precision matrix K is singular, so it is the factor type used when
+// eliminating variables in linear-Gaussian networks.
+type CanonicalFactor struct {
+	Variables []string       // Variable names, in matrix index order
+	K         *mat.SymDense  // Precision-like matrix
+	H         *mat.VecDense  // Information vector
+	G         float64        // Log normalization constant
+	index     map[string]int // Variable name -> matrix index
+}
+
+// NewCanonicalFactor creates a canonical factor. With no variables it
+// represents the constant exp(g).
+func NewCanonicalFactor(variables []string, k *mat.SymDense, h *mat.VecDense, g float64) (*CanonicalFactor, error) {
+	n := len(variables)
+	if n == 0 {
+		return &CanonicalFactor{Variables: []string{}, G: g, index: map[string]int{}}, nil
+	}
+	if k.SymmetricDim() != n || h.Len() != n {
+		return nil, fmt.Errorf("K size %d and h length %d do not match %d variables",
+			k.SymmetricDim(), h.Len(), n)
+	}
+
+	index := make(map[string]int, n)
+	for i, v := range variables {
+		if _, dup := index[v]; dup {
+			return nil, fmt.Errorf("duplicate variable %s", v)
+		}
+		index[v] = i
+	}
+
+	return &CanonicalFactor{Variables: variables, K: k, H: h, G: g, index: index}, nil
+}
+
+// NewCanonicalFromGaussian converts a moment-form Gaussian into canonical form
+func NewCanonicalFromGaussian(gf *GaussianFactor) (*CanonicalFactor, error) {
+	chol, err := cholesky(gf.Covariance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to invert covariance: %w", err)
+	}
+
+	n := len(gf.Variables)
+	k := mat.NewSymDense(n, nil)
+	if err := chol.InverseTo(k); err != nil {
+		return nil, fmt.Errorf("failed to invert covariance: %w", err)
+	}
+	h := mat.NewVecDense(n, nil)
+	h.MulVec(k, gf.Mean)
+
+	// g = -0.5 μ^T K μ - 0.5 log|2πΣ|
+	g := -0.5*mat.Dot(gf.Mean, h) - 0.5*(float64(n)*math.Log(2*math.Pi)+chol.LogDet())
+
+	vars := make([]string, n)
+	copy(vars, gf.Variables)
+	return NewCanonicalFactor(vars, k, h, g)
+}
+
+// Copy creates a deep copy of the canonical factor
+func (cf *CanonicalFactor) Copy() *CanonicalFactor {
+	varsCopy := make([]string, len(cf.Variables))
+	copy(varsCopy, cf.Variables)
+
+	if len(varsCopy) == 0 {
+		result, _ := NewCanonicalFactor(varsCopy, nil, nil, cf.G)
+		return result
+	}
+
+	k := mat.NewSymDense(len(varsCopy), nil)
+	k.CopySym(cf.K)
+	result, _ := NewCanonicalFactor(varsCopy, k, mat.VecDenseCopyOf(cf.H), cf.G)
+	return result
+}
+
+// Multiply multiplies this factor with another by adding their parameters
+// over the union of their scopes
+func (cf *CanonicalFactor) Multiply(other *CanonicalFactor) (*CanonicalFactor, error) {
+	// Find union of variables
+	varSet := make(map[string]bool)
+	for _, v := range cf.Variables {
+		varSet[v] = true
+	}
+	for _, v := range other.Variables {
+		varSet[v] = true
+	}
+
+	newVars := make([]string, 0, len(varSet))
+	for v := range varSet {
+		newVars = append(newVars, v)
+	}
+	sort.Strings(newVars)
+
+	n := len(newVars)
+	if n == 0 {
+		return NewCanonicalFactor(newVars, nil, nil, cf.G+other.G)
+	}
+
+	position := make(map[string]int, n)
+	for i, v := range newVars {
+		position[v] = i
+	}
+
+	k := mat.NewSymDense(n, nil)
+	h := mat.NewVecDense(n, nil)
+	for _, f := range []*CanonicalFactor{cf, other} {
+		for i, vi := range f.Variables {
+			pi := position[vi]
+			h.SetVec(pi, h.AtVec(pi)+f.H.AtVec(i))
+			for j := i; j < len(f.Variables); j++ {
+				pj := position[f.Variables[j]]
+				k.SetSym(pi, pj, k.At(pi, pj)+f.K.At(i, j))
+			}
+		}
+	}
+
+	return NewCanonicalFactor(newVars, k, h, cf.G+other.G)
+}
+
+// split returns the indices of kept and removed variables
+func (cf *CanonicalFactor) split(remove map[string]bool) ([]string, []int, []int) {
+	kept := make([]string, 0, len(cf.Variables))
+	keepIdx := make([]int, 0, len(cf.Variables))
+	removeIdx := make([]int, 0)
+	for i, v := range cf.Variables {
+		if remove[v] {
+			removeIdx = append(removeIdx, i)
+		} else {
+			kept = append(kept, v)
+			keepIdx = append(keepIdx, i)
+		}
+	}
+	return kept, keepIdx, removeIdx
+}
+
+func (cf *CanonicalFactor) subK(rows, cols []int) *mat.Dense {
+	sub := mat.NewDense(len(rows), len(cols), nil)
+	for i, r := range rows {
+		for j, c := range cols {
+			sub.Set(i, j, cf.K.At(r, c))
+		}
+	}
+	return sub
+}
+
+func (cf *CanonicalFactor) subKSym(idx []int) *mat.SymDense {
+	sub := mat.NewSymDense(len(idx), nil)
+	for i, r := range idx {
+		for j := i; j < len(idx); j++ {
+			sub.SetSym(i, j, cf.K.At(r, idx[j]))
+		}
+	}
+	return sub
+}
+
+func (cf *CanonicalFactor) subH(idx []int) *mat.VecDense {
+	sub := mat.NewVecDense(len(idx), nil)
+	for i, r := range idx {
+		sub.SetVec(i, cf.H.AtVec(r))
+	}
+	return sub
+}
+
+// Marginalize integrates out the specified variables. The block of K over the
+// removed variables must be positive definite.
+func (cf *CanonicalFactor) Marginalize(variables []string) (*CanonicalFactor, error) {
+	remove := make(map[string]bool)
+	for _, v := range variables {
+		remove[v] = true
+	}
+	kept, keepIdx, removeIdx := cf.split(remove)
+	if len(removeIdx) == 0 {
+		return cf.Copy(), nil
+	}
+
+	// With X kept and Y removed:
+	// K' = K_XX - K_XY K_YY^(-1) K_YX
+	// h' = h_X - K_XY K_YY^(-1) h_Y
+	// g' = g + 0.5 (|Y| log 2π - log|K_YY| + h_Y^T K_YY^(-1) h_Y)
+	chol, err := cholesky(cf.subKSym(removeIdx))
+	if err != nil {
+		return nil, fmt.Errorf("cannot integrate out %v: %w", variables, err)
+	}
+
+	hy := cf.subH(removeIdx)
+	var solvedH mat.VecDense
+	if err := chol.SolveVecTo(&solvedH, hy); err != nil {
+		return nil, err
+	}
+	g := cf.G + 0.5*(float64(len(removeIdx))*math.Log(2*math.Pi)-chol.LogDet()+mat.Dot(hy, &solvedH))
+
+	if len(kept) == 0 {
+		return NewCanonicalFactor(kept, nil, nil, g)
+	}
+
+	kxy := cf.subK(keepIdx, removeIdx)
+	var solvedK mat.Dense
+	if err := chol.SolveTo(&solvedK, kxy.T()); err != nil {
+		return nil, err
+	}
+	var correction mat.Dense
+	correction.Mul(kxy, &solvedK)
+
+	kxx := cf.subKSym(keepIdx)
+	k := mat.NewSymDense(len(kept), nil)
+	for i := range kept {
+		for j := i; j < len(kept); j++ {
+			k.SetSym(i, j, kxx.At(i, j)-0.5*(correction.At(i, j)+correction.At(j, i)))
+		}
+	}
+
+	h := cf.subH(keepIdx)
+	var hCorrection mat.VecDense
+	hCorrection.MulVec(kxy, &solvedH)
+	h.SubVec(h, &hCorrection)
+
+	return NewCanonicalFactor(kept, k, h, g)
+}
+
+// Reduce fixes observed variables to the given values
+func (cf *CanonicalFactor) Reduce(evidence map[string]float64) (*CanonicalFactor, error) {
+	remove := make(map[string]bool)
+	for _, v := range cf.Variables {
+		if _, ok := evidence[v]; ok {
+			remove[v] = true
+		}
+	}
+	kept, keepIdx, removeIdx := cf.split(remove)
+	if len(removeIdx) == 0 {
+		return cf.Copy(), nil
+	}
+
+	// With Y = y observed:
+	// K' = K_XX, h' = h_X - K_XY y, g' = g + h_Y^T y - 0.5 y^T K_YY y
+	y := mat.NewVecDense(len(removeIdx), nil)
+	for i, r := range removeIdx {
+		y.SetVec(i, evidence[cf.Variables[r]])
+	}
+	var kyyY mat.VecDense
+	kyyY.MulVec(cf.subKSym(removeIdx), y)
+	g := cf.G + mat.Dot(cf.subH(removeIdx), y) - 0.5*mat.Dot(y, &kyyY)
+
+	if len(kept) == 0 {
+		return NewCanonicalFactor(kept, nil, nil, g)
+	}
+
+	h := cf.subH(keepIdx)
+	var shift mat.VecDense
+	shift.MulVec(cf.subK(keepIdx, removeIdx), y)
+	h.SubVec(h, &shift)
+
+	return NewCanonicalFactor(kept, cf.subKSym(keepIdx), h, g)
+}
+
+// ToGaussian converts the factor to moment form, Σ = K^(-1) and μ = Σ h.
+// K must be positive definite, i.e. the factor must be a normalizable density.
+func (cf *CanonicalFactor) ToGaussian() (*GaussianFactor, error) {
+	if len(cf.Variables) == 0 {
+		return nil, fmt.Errorf("cannot convert constant factor to Gaussian")
+	}
+
+	chol, err := cholesky(cf.K)
+	if err != nil {
+		return nil, fmt.Errorf("factor is not a normalizable Gaussian: %w", err)
+	}
+
+	n := len(cf.Variables)
+	cov := mat.NewSymDense(n, nil)
+	if err := chol.InverseTo(cov); err != nil {
+		return nil, err
+	}
+	mean := mat.NewVecDense(n, nil)
+	if err := chol.SolveVecTo(mean, cf.H); err != nil {
+		return nil, err
+	}
+
+	vars := make([]string, n)
+	copy(vars, cf.Variables)
+	return NewGaussianFactorFromMatrix(vars, mean, cov)
+}
+
+// LogValue evaluates log φ(x) at a full assignment of the factor's variables
+func (cf *CanonicalFactor) LogValue(values map[string]float64) (float64, error) {
+	n := len(cf.Variables)
+	if n == 0 {
+		return cf.G, nil
+	}
+
+	x := mat.NewVecDense(n, nil)
+	for i, v := range cf.Variables {
+		val, ok := values[v]
+		if !ok {
+			return 0, fmt.Errorf("missing value for variable %s", v)
+		}
+		x.SetVec(i, val)
+	}
+
+	var kx mat.VecDense
+	kx.MulVec(cf.K, x)
+	return -0.5*mat.Dot(x, &kx) + mat.Dot(cf.H, x) + cf.G, nil
+}
+
+// String returns a string representation
+func (cf *CanonicalFactor) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("CanonicalFactor(%s)\n", strings.Join(cf.Variables, ", ")))
+	sb.WriteString("h: ")
+	for i, v := range cf.Variables {
+		sb.WriteString(fmt.Sprintf("%s=%.4f ", v, cf.H.AtVec(i)))
+	}
+	sb.WriteString(fmt.Sprintf("\ng: %.4f\nK:\n", cf.G))
+	for i := range cf.Variables {
+		sb.WriteString("  ")
+		for j := range cf.Variables {
+			sb.WriteString(fmt.Sprintf("%.4f ", cf.K.At(i, j)))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,400 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// MarginalType names the family of a copula marginal
+type MarginalType string
+
+const (
+	MarginalEmpirical MarginalType = "empirical" // Gaussian kernel density estimate of the data
+	MarginalNormal    MarginalType = "normal"    // Normal distribution
+	MarginalLogNormal MarginalType = "lognormal" // Log-normal distribution, for positive values
+	MarginalGamma     MarginalType = "gamma"     // Gamma distribution, for positive values
+)
+
+// Marginal is a univariate continuous distribution. Copula CPDs map values
+// through their marginals' CDFs to normal scores and back through their
+// quantile functions.
+type Marginal interface {
+	// CDF returns P(X ≤ x)
+	CDF(x float64) float64
+	// Quantile returns the x with CDF(x) = p for p in (0, 1)
+	Quantile(p float64) float64
+	// LogPDF returns the log density at x
+	LogPDF(x float64) float64
+}
+
+// NormalMarginal is the N(Mean, StdDev²) distribution
+type NormalMarginal struct {
+	Mean   float64
+	StdDev float64
+}
+
+// Distribution returns the marginal as a distuv distribution
+func (m NormalMarginal) Distribution() Distribution {
+	return distuv.Normal{Mu: m.Mean, Sigma: m.StdDev}
+}
+
+// CDF returns P(X ≤ x)
+func (m NormalMarginal) CDF(x float64) float64 {
+	return m.Distribution().CDF(x)
+}
+
+// Quantile returns the x with CDF(x) = p
+func (m NormalMarginal) Quantile(p float64) float64 {
+	return m.Distribution().Quantile(p)
+}
+
+// LogPDF returns the log density at x
+func (m NormalMarginal) LogPDF(x float64) float64 {
+	return m.Distribution().LogProb(x)
+}
+
+// LogNormalMarginal is the distribution of exp(Y) for Y ~ N(Mu, Sigma²)
+type LogNormalMarginal struct {
+	Mu    float64
+	Sigma float64
+}
+
+// Distribution returns the marginal as a distuv distribution
+func (m LogNormalMarginal) Distribution() Distribution {
+	return distuv.LogNormal{Mu: m.Mu, Sigma: m.Sigma}
+}
+
+// CDF returns P(X ≤ x)
+func (m LogNormalMarginal) CDF(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return m.Distribution().CDF(x)
+}
+
+// Quantile returns the x with CDF(x) = p
+func (m LogNormalMarginal) Quantile(p float64) float64 {
+	return m.Distribution().Quantile(p)
+}
+
+// LogPDF returns the log density at x, -Inf for x ≤ 0
+func (m LogNormalMarginal) LogPDF(x float64) float64 {
+	if x <= 0 {
+		return math.Inf(-1)
+	}
+	return m.Distribution().LogProb(x)
+}
+
+// GammaMarginal is the Gamma distribution with the given shape and rate
+type GammaMarginal struct {
+	Shape float64
+	Rate  float64
+}
+
+// Distribution returns the marginal as a distuv distribution
+func (m GammaMarginal) Distribution() Distribution {
+	return distuv.Gamma{Alpha: m.Shape, Beta: m.Rate}
+}
+
+// CDF returns P(X ≤ x)
+func (m GammaMarginal) CDF(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return m.Distribution().CDF(x)
+}
+
+// Quantile returns the x with CDF(x) = p
+func (m GammaMarginal) Quantile(p float64) float64 {
+	return m.Distribution().Quantile(p)
+}
+
+// LogPDF returns the log density at x, -Inf for x ≤ 0
+func (m GammaMarginal) LogPDF(x float64) float64 {
+	if x <= 0 {
+		return math.Inf(-1)
+	}
+	return m.Distribution().LogProb(x)
+}
+
+// kernelReach is how many bandwidths from a point its kernel is evaluated
+const kernelReach = 8
+
+// EmpiricalMarginal is a Gaussian kernel density estimate: a weighted mixture
+// of normals of standard deviation Bandwidth centred on the observed values.
+// Evaluating it costs up to O(n) in the number of values. Build it with
+// NewEmpiricalMarginal and do not modify its fields.
+type EmpiricalMarginal struct {
+	Values    []float64 // Observed values in ascending order
+	Weights   []float64 // Weight of each value, summing to 1
+	Bandwidth float64
+
+	cumulative []float64 // cumulative[i] is the sum of Weights[:i+1]
+}
+
+// NewEmpiricalMarginal builds a kernel density estimate from values, with
+// nil weights weighting every value equally. The bandwidth follows
+// Silverman's rule of thumb using the effective sample size of the weights.
+func NewEmpiricalMarginal(values, weights []float64) (*EmpiricalMarginal, error) {
+	if len(values) < 2 {
+		return nil, fmt.Errorf("empirical marginal needs at least 2 values, got %d", len(values))
+	}
+	if weights != nil && len(weights) != len(values) {
+		return nil, fmt.Errorf("got %d weights for %d values", len(weights), len(values))
+	}
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+
+	m := &EmpiricalMarginal{
+		Values:     make([]float64, len(values)),
+		Weights:    make([]float64, len(values)),
+		cumulative: make([]float64, len(values)),
+	}
+	total := 0.0
+	for i, idx := range order {
+		w := 1.0
+		if weights != nil {
+			w = weights[idx]
+		}
+		if w < 0 || math.IsNaN(w) {
+			return nil, fmt.Errorf("weight %v of value %d: %w", w, idx, ErrInvalidDistribution)
+		}
+		m.Values[i] = values[idx]
+		m.Weights[i] = w
+		total += w
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("empirical marginal weights sum to zero: %w", ErrInvalidDistribution)
+	}
+
+	mean, sumSq, acc := 0.0, 0.0, 0.0
+	for i := range m.Weights {
+		m.Weights[i] /= total
+		sumSq += m.Weights[i] * m.Weights[i]
+		mean += m.Weights[i] * m.Values[i]
+		acc += m.Weights[i]
+		m.cumulative[i] = acc
+	}
+	variance := 0.0
+	for i, v := range m.Values {
+		variance += m.Weights[i] * (v - mean) * (v - mean)
+	}
+	if variance <= 0 {
+		return nil, fmt.Errorf("empirical marginal values are all equal: %w", ErrInvalidDistribution)
+	}
+	m.Bandwidth = 1.06 * math.Sqrt(variance) * math.Pow(1/sumSq, -0.2)
+	return m, nil
+}
+
+// CDF returns P(X ≤ x)
+func (m *EmpiricalMarginal) CDF(x float64) float64 {
+	lo, hi := m.window(x)
+	// Every kernel left of the window already contributes its full weight
+	p := 0.0
+	if lo > 0 {
+		p = m.cumulative[lo-1]
+	}
+	for i := lo; i < hi; i++ {
+		p += m.Weights[i] * normalCDF((x-m.Values[i])/m.Bandwidth)
+	}
+	return math.Min(p, 1)
+}
+
+// LogPDF returns the log density at x
+func (m *EmpiricalMarginal) LogPDF(x float64) float64 {
+	lo, hi := m.window(x)
+	density := 0.0
+	for i := lo; i < hi; i++ {
+		density += m.Weights[i] * normalDensity((x-m.Values[i])/m.Bandwidth)
+	}
+	return math.Log(density / m.Bandwidth)
+}
+
+// Quantile returns the x with CDF(x) = p, found by safeguarded Newton steps
+// from the weighted quantile of the values
+func (m *EmpiricalMarginal) Quantile(p float64) float64 {
+	if p <= 0 {
+		return math.Inf(-1)
+	}
+	if p >= 1 {
+		return math.Inf(1)
+	}
+	lo := m.Values[0] - 40*m.Bandwidth
+	hi := m.Values[len(m.Values)-1] + 40*m.Bandwidth
+	i := sort.SearchFloat64s(m.cumulative, p)
+	x := m.Values[min(i, len(m.Values)-1)]
+	for iter := 0; iter < 100; iter++ {
+		f := m.CDF(x) - p
+		if math.Abs(f) < 1e-12 {
+			break
+		}
+		if f > 0 {
+			hi = x
+		} else {
+			lo = x
+		}
+		next := x - f/math.Exp(m.LogPDF(x))
+		if next <= lo || next >= hi || math.IsNaN(next) {
+			next = (lo + hi) / 2
+		}
+		if math.Abs(next-x) < 1e-12*math.Max(1, math.Abs(x)) {
+			return next
+		}
+		x = next
+	}
+	return x
+}
+
+// window returns the index range of the values whose kernels matter at x
+func (m *EmpiricalMarginal) window(x float64) (int, int) {
+	reach := kernelReach * m.Bandwidth
+	lo := sort.SearchFloat64s(m.Values, x-reach)
+	hi := sort.SearchFloat64s(m.Values, x+reach)
+	return lo, hi
+}
+
+// CopulaCPD is a conditional density of a continuous variable given
+// continuous parents under a Gaussian copula. Each value is mapped to its
+// normal score z = Φ⁻¹(F(x)) through its marginal, and the scores are linear
+// Gaussian:
+// Z | zᵢ ~ N(β₀ + Σᵢ βᵢzᵢ, σ²)
+// so the variable keeps its marginal, empirical or parametric, while the
+// coefficients carry its dependence on the parents.
+type CopulaCPD struct {
+	Variable        string
+	Parents         []string
+	Marginal        Marginal            // F of the variable
+	ParentMarginals map[string]Marginal // Fᵢ of each parent
+	Intercept       float64             // β₀
+	Coefficients    map[string]float64  // βᵢ for each parent's score
+	Variance        float64             // σ² of the score given the parents' scores
+}
+
+// NewCopulaCPD creates a Gaussian copula CPD
+func NewCopulaCPD(variable string, parents []string, marginal Marginal, parentMarginals map[string]Marginal,
+	intercept float64, coefficients map[string]float64, variance float64) (*CopulaCPD, error) {
+	if marginal == nil {
+		return nil, fmt.Errorf("copula CPD of %s needs a marginal", variable)
+	}
+	if variance <= 0 || math.IsNaN(variance) {
+		return nil, fmt.Errorf("variance of %s must be positive, got %v: %w", variable, variance, ErrInvalidDistribution)
+	}
+	margins := make(map[string]Marginal, len(parents))
+	coefs := make(map[string]float64, len(parents))
+	for _, p := range parents {
+		m, ok := parentMarginals[p]
+		if !ok || m == nil {
+			return nil, fmt.Errorf("missing marginal for parent %s", p)
+		}
+		c, ok := coefficients[p]
+		if !ok {
+			return nil, fmt.Errorf("missing coefficient for parent %s", p)
+		}
+		margins[p] = m
+		coefs[p] = c
+	}
+	return &CopulaCPD{
+		Variable:        variable,
+		Parents:         append([]string(nil), parents...),
+		Marginal:        marginal,
+		ParentMarginals: margins,
+		Intercept:       intercept,
+		Coefficients:    coefs,
+		Variance:        variance,
+	}, nil
+}
+
+// GetVariable returns the child variable
+func (cpd *CopulaCPD) GetVariable() string {
+	return cpd.Variable
+}
+
+// GetParents returns a copy of the CPD's parents
+func (cpd *CopulaCPD) GetParents() []string {
+	return append([]string(nil), cpd.Parents...)
+}
+
+// scoreMean returns the conditional mean of the variable's normal score
+func (cpd *CopulaCPD) scoreMean(parentValues map[string]interface{}) (float64, error) {
+	mean := cpd.Intercept
+	for _, p := range cpd.Parents {
+		val, ok := parentValues[p]
+		if !ok {
+			return 0, fmt.Errorf("missing parent value for %s", p)
+		}
+		y, ok := val.(float64)
+		if !ok {
+			return 0, fmt.Errorf("parent %s value must be float64", p)
+		}
+		mean += cpd.Coefficients[p] * NormalScore(cpd.ParentMarginals[p], y)
+	}
+	return mean, nil
+}
+
+// Sample draws a value given the parent values by drawing its normal score
+// and mapping it back through the marginal's quantile function
+func (cpd *CopulaCPD) Sample(parentValues map[string]interface{}, rng *rand.Rand) (float64, error) {
+	mean, err := cpd.scoreMean(parentValues)
+	if err != nil {
+		return 0, err
+	}
+	z := mean + math.Sqrt(cpd.Variance)*rng.NormFloat64()
+	return cpd.Marginal.Quantile(clampUnit(normalCDF(z))), nil
+}
+
+// LogPDF evaluates the log density at x, the score density less the log
+// standard normal density of the score plus the marginal log density
+func (cpd *CopulaCPD) LogPDF(x float64, parentValues map[string]interface{}) (float64, error) {
+	mean, err := cpd.scoreMean(parentValues)
+	if err != nil {
+		return 0, err
+	}
+	logMarginal := cpd.Marginal.LogPDF(x)
+	if math.IsInf(logMarginal, -1) {
+		return logMarginal, nil
+	}
+	z := NormalScore(cpd.Marginal, x)
+	r := z - mean
+	return -0.5*r*r/cpd.Variance - 0.5*math.Log(cpd.Variance) + 0.5*z*z + logMarginal, nil
+}
+
+// Copy creates a copy of the CPD sharing its marginals, which are not
+// modified after construction
+func (cpd *CopulaCPD) Copy() *CopulaCPD {
+	copied, _ := NewCopulaCPD(cpd.Variable, cpd.Parents, cpd.Marginal, cpd.ParentMarginals,
+		cpd.Intercept, cpd.Coefficients, cpd.Variance)
+	return copied
+}
+
+// String returns a string representation
+func (cpd *CopulaCPD) String() string {
+	return fmt.Sprintf("CopulaCPD(%s | %v, variance %g)", cpd.Variable, cpd.Parents, cpd.Variance)
+}
+
+// NormalScore maps x to Φ⁻¹(F(x)) under the marginal F, clamping the CDF
+// away from 0 and 1 so values in the far tails keep finite scores
+func NormalScore(m Marginal, x float64) float64 {
+	return normalQuantile(clampUnit(m.CDF(x)))
+}
+
+// clampUnit keeps a probability within the range normal scores resolve
+func clampUnit(p float64) float64 {
+	return math.Min(math.Max(p, 1e-15), 1-1e-15)
+}
+
+// normalCDF returns the standard normal CDF Φ(z)
+func normalCDF(z float64) float64 {
+	return 0.5 * math.Erfc(-z/math.Sqrt2)
+}
+
+// normalQuantile returns Φ⁻¹(p)
+func normalQuantile(p float64) float64 {
+	return -math.Sqrt2 * math.Erfcinv(2*p)
+}
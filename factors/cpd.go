@@ -3,6 +3,8 @@ package factors
 import (
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 // TabularCPD represents a Conditional Probability Distribution in tabular form
@@ -12,6 +14,10 @@ type TabularCPD struct {
 	Evidence     []string
 	EvidenceCard map[string]int
 	Values       [][]float64 // [evidence_combination][variable_state]
+
+	// StateNames optionally labels the states of the variable and its
+	// evidence for display; unlabeled variables show state numbers
+	StateNames map[string][]string
 }
 
 // NewTabularCPD creates a new tabular CPD
@@ -25,13 +31,13 @@ func NewTabularCPD(variable string, variableCard int, values [][]float64,
 	}
 
 	if len(values) != expectedRows {
-		return nil, fmt.Errorf("values has %d rows, expected %d", len(values), expectedRows)
+		return nil, fmt.Errorf("values has %d rows, expected %d: %w", len(values), expectedRows, ErrCardinalityMismatch)
 	}
 
 	// Check each row
 	for i, row := range values {
 		if len(row) != variableCard {
-			return nil, fmt.Errorf("row %d has %d columns, expected %d", i, len(row), variableCard)
+			return nil, fmt.Errorf("row %d has %d columns, expected %d: %w", i, len(row), variableCard, ErrCardinalityMismatch)
 		}
 
 		// Check if probabilities sum to 1
@@ -40,7 +46,7 @@ func NewTabularCPD(variable string, variableCard int, values [][]float64,
 			sum += p
 		}
 		if sum < 0.999 || sum > 1.001 {
-			return nil, fmt.Errorf("row %d probabilities sum to %f, expected 1.0", i, sum)
+			return nil, fmt.Errorf("row %d probabilities sum to %f, expected 1.0: %w", i, sum, ErrInvalidDistribution)
 		}
 	}
 
@@ -80,7 +86,7 @@ func (cpd *TabularCPD) ToFactor() (*DiscreteFactor, error) {
 	assignment := make(map[string]int)
 	cpd.toFactorHelper(0, allVars, assignment, card, values)
 
-	return NewDiscreteFactor(allVars, card, values)
+	return newCompiledFactor(allVars, card, values)
 }
 
 func (cpd *TabularCPD) toFactorHelper(depth int, vars []string, assignment map[string]int,
@@ -140,9 +146,107 @@ func (cpd *TabularCPD) GetValue(varState int, evidenceValues map[string]int) (fl
 	return cpd.Values[rowIdx][varState], nil
 }
 
-// String returns a string representation of the CPD
+// SetStateNames labels the states of the CPD's variable or one of its evidence variables
+func (cpd *TabularCPD) SetStateNames(variable string, names []string) error {
+	card, ok := cpd.EvidenceCard[variable]
+	if variable == cpd.Variable {
+		card, ok = cpd.VariableCard, true
+	}
+	if !ok {
+		return fmt.Errorf("variable %s not in CPD for %s", variable, cpd.Variable)
+	}
+	if len(names) != card {
+		return fmt.Errorf("got %d state names for %s, expected %d: %w", len(names), variable, card, ErrCardinalityMismatch)
+	}
+
+	if cpd.StateNames == nil {
+		cpd.StateNames = make(map[string][]string)
+	}
+	cpd.StateNames[variable] = append([]string(nil), names...)
+	return nil
+}
+
+// stateName returns the display label of a state
+func (cpd *TabularCPD) stateName(variable string, state int) string {
+	if names, ok := cpd.StateNames[variable]; ok && state < len(names) {
+		return names[state]
+	}
+	return strconv.Itoa(state)
+}
+
+// Table renders the CPD as an aligned grid with one row per evidence
+// configuration and one column per state of the variable
+func (cpd *TabularCPD) Table() string {
+	header := make([]string, 0, len(cpd.Evidence)+cpd.VariableCard)
+	header = append(header, cpd.Evidence...)
+	for s := 0; s < cpd.VariableCard; s++ {
+		header = append(header, fmt.Sprintf("%s=%s", cpd.Variable, cpd.stateName(cpd.Variable, s)))
+	}
+
+	rows := make([][]string, len(cpd.Values))
+	for r, probs := range cpd.Values {
+		row := make([]string, len(cpd.Evidence), len(header))
+
+		// Decode the row index, last evidence variable fastest
+		rem := r
+		for i := len(cpd.Evidence) - 1; i >= 0; i-- {
+			e := cpd.Evidence[i]
+			row[i] = cpd.stateName(e, rem%cpd.EvidenceCard[e])
+			rem /= cpd.EvidenceCard[e]
+		}
+		for _, p := range probs {
+			row = append(row, fmt.Sprintf("%.4f", p))
+		}
+		rows[r] = row
+	}
+
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	separator := func() {
+		sb.WriteString("+")
+		for _, w := range widths {
+			sb.WriteString(strings.Repeat("-", w+2))
+			sb.WriteString("+")
+		}
+		sb.WriteString("\n")
+	}
+	line := func(cells []string) {
+		sb.WriteString("|")
+		for i, cell := range cells {
+			// Evidence labels align left, probabilities align right
+			if i < len(cpd.Evidence) {
+				sb.WriteString(fmt.Sprintf(" %-*s |", widths[i], cell))
+			} else {
+				sb.WriteString(fmt.Sprintf(" %*s |", widths[i], cell))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	separator()
+	line(header)
+	separator()
+	for _, row := range rows {
+		line(row)
+	}
+	separator()
+	return sb.String()
+}
+
+// String returns a string representation of the CPD as a table
 func (cpd *TabularCPD) String() string {
-	return fmt.Sprintf("CPD(%s | %v)", cpd.Variable, cpd.Evidence)
+	return fmt.Sprintf("CPD(%s | %v)\n%s", cpd.Variable, cpd.Evidence, cpd.Table())
 }
 
 // Copy creates a deep copy of the CPD
@@ -161,11 +265,20 @@ func (cpd *TabularCPD) Copy() *TabularCPD {
 		copy(valuesCopy[i], row)
 	}
 
+	var stateNamesCopy map[string][]string
+	if cpd.StateNames != nil {
+		stateNamesCopy = make(map[string][]string, len(cpd.StateNames))
+		for k, v := range cpd.StateNames {
+			stateNamesCopy[k] = append([]string(nil), v...)
+		}
+	}
+
 	return &TabularCPD{
 		Variable:     cpd.Variable,
 		VariableCard: cpd.VariableCard,
 		Evidence:     evidenceCopy,
 		EvidenceCard: evidenceCardCopy,
 		Values:       valuesCopy,
+		StateNames:   stateNamesCopy,
 	}
 }
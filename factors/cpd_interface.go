@@ -0,0 +1,139 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// CPD is a conditional distribution of a discrete variable given its
+// parents. TabularCPD implements it; other parameterizations such as noisy-OR
+// or softmax CPDs implement it to take part in model checking, simulation
+// and inference without being expanded by hand.
+type CPD interface {
+	// GetVariable returns the child variable
+	GetVariable() string
+	// GetParents returns the parent variables
+	GetParents() []string
+	// GetCardinality returns the number of states of the child variable
+	GetCardinality() int
+	// ToFactor returns the full table P(variable | parents) as a factor whose
+	// Cardinality map covers the variable and every parent
+	ToFactor() (*DiscreteFactor, error)
+	// Sample draws a state of the variable given the parent states
+	Sample(parentValues map[string]int, rng *rand.Rand) (int, error)
+	// LogProb returns log P(variable=state | parent states)
+	LogProb(state int, parentValues map[string]int) (float64, error)
+}
+
+// ContinuousCPD is a conditional density of a continuous variable given its
+// parents. LinearGaussianCPD implements it; non-linear or non-Gaussian
+// parameterizations such as Gaussian process CPDs implement it to take part in
+// simulation and density evaluation. Parent values are float64 for
+// continuous parents and int for discrete ones.
+type ContinuousCPD interface {
+	// GetVariable returns the child variable
+	GetVariable() string
+	// GetParents returns the parent variables
+	GetParents() []string
+	// Sample draws a value of the variable given the parent values
+	Sample(parentValues map[string]interface{}, rng *rand.Rand) (float64, error)
+	// LogPDF returns log p(variable=x | parent values)
+	LogPDF(x float64, parentValues map[string]interface{}) (float64, error)
+}
+
+// HybridCPD is a conditional distribution of a discrete variable given
+// parents that may be continuous, such as an ordinal probit CPD. Parent
+// values are float64 for continuous parents and int for discrete ones, as
+// for ContinuousCPD.
+type HybridCPD interface {
+	// GetVariable returns the child variable
+	GetVariable() string
+	// GetParents returns the parent variables
+	GetParents() []string
+	// GetCardinality returns the number of states of the child variable
+	GetCardinality() int
+	// Sample draws a state of the variable given the parent values
+	Sample(parentValues map[string]interface{}, rng *rand.Rand) (int, error)
+	// LogProb returns log P(variable=state | parent values)
+	LogProb(state int, parentValues map[string]interface{}) (float64, error)
+}
+
+// GetVariable returns the CPD's variable
+func (cpd *TabularCPD) GetVariable() string {
+	return cpd.Variable
+}
+
+// GetParents returns a copy of the CPD's evidence variables
+func (cpd *TabularCPD) GetParents() []string {
+	return append([]string(nil), cpd.Evidence...)
+}
+
+// GetCardinality returns the number of states of the CPD's variable
+func (cpd *TabularCPD) GetCardinality() int {
+	return cpd.VariableCard
+}
+
+// Sample draws a state of the variable from the row selected by the parent states
+func (cpd *TabularCPD) Sample(parentValues map[string]int, rng *rand.Rand) (int, error) {
+	rowIdx := 0
+	stride := 1
+	for i := len(cpd.Evidence) - 1; i >= 0; i-- {
+		e := cpd.Evidence[i]
+		val, ok := parentValues[e]
+		if !ok {
+			return 0, fmt.Errorf("missing evidence value for %s", e)
+		}
+		rowIdx += val * stride
+		stride *= cpd.EvidenceCard[e]
+	}
+
+	u := rng.Float64()
+	cumSum := 0.0
+	row := cpd.Values[rowIdx]
+	for i, p := range row {
+		cumSum += p
+		if u <= cumSum {
+			return i, nil
+		}
+	}
+	return len(row) - 1, nil
+}
+
+// LogProb returns log P(variable=state | evidence)
+func (cpd *TabularCPD) LogProb(state int, parentValues map[string]int) (float64, error) {
+	p, err := cpd.GetValue(state, parentValues)
+	if err != nil {
+		return 0, err
+	}
+	return math.Log(p), nil
+}
+
+// GetVariable returns the CPD's variable
+func (cpd *LinearGaussianCPD) GetVariable() string {
+	return cpd.Variable
+}
+
+// GetParents returns a copy of the CPD's parents
+func (cpd *LinearGaussianCPD) GetParents() []string {
+	return append([]string(nil), cpd.Parents...)
+}
+
+var (
+	_ CPD = (*TabularCPD)(nil)
+	_ CPD = (*NoisyOrCPD)(nil)
+
+	_ ContinuousCPD = (*LinearGaussianCPD)(nil)
+	_ ContinuousCPD = (*GaussianProcessCPD)(nil)
+	_ ContinuousCPD = (*PoissonCPD)(nil)
+	_ ContinuousCPD = (*GammaCPD)(nil)
+	_ ContinuousCPD = (*BetaCPD)(nil)
+	_ ContinuousCPD = (*CopulaCPD)(nil)
+
+	_ Marginal = NormalMarginal{}
+	_ Marginal = LogNormalMarginal{}
+	_ Marginal = GammaMarginal{}
+	_ Marginal = (*EmpiricalMarginal)(nil)
+
+	_ HybridCPD = (*OrdinalProbitCPD)(nil)
+)
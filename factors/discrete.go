@@ -13,6 +13,8 @@ type DiscreteFactor struct {
 	Variables   []string
 	Cardinality map[string]int
 	Values      []float64
+
+	compiled *layout // stride table set by Compile
 }
 
 // NewDiscreteFactor creates a new discrete factor
@@ -24,7 +26,7 @@ func NewDiscreteFactor(variables []string, cardinality map[string]int, values []
 	}
 
 	if len(values) != size {
-		return nil, fmt.Errorf("values length %d does not match expected size %d", len(values), size)
+		return nil, fmt.Errorf("values length %d does not match expected size %d: %w", len(values), size, ErrCardinalityMismatch)
 	}
 
 	return &DiscreteFactor{
@@ -51,11 +53,56 @@ func (f *DiscreteFactor) Copy() *DiscreteFactor {
 		Variables:   varsCopy,
 		Cardinality: cardCopy,
 		Values:      valuesCopy,
+		compiled:    f.compiled,
 	}
 }
 
+// Equal reports whether two factors have the same scope and cardinalities and
+// values within tol of each other. Variable order may differ; entries are
+// compared by assignment.
+func (f *DiscreteFactor) Equal(other *DiscreteFactor, tol float64) bool {
+	if len(f.Variables) != len(other.Variables) || len(f.Values) != len(other.Values) {
+		return false
+	}
+	for _, v := range f.Variables {
+		card, ok := other.Cardinality[v]
+		if !ok || card != f.Cardinality[v] || !other.hasVariable(v) {
+			return false
+		}
+	}
+
+	assignment := make(map[string]int, len(f.Variables))
+	for idx, value := range f.Values {
+		// Decode idx into an assignment, last variable fastest
+		rem := idx
+		for i := len(f.Variables) - 1; i >= 0; i-- {
+			v := f.Variables[i]
+			assignment[v] = rem % f.Cardinality[v]
+			rem /= f.Cardinality[v]
+		}
+		if math.Abs(value-other.Values[other.projectAssignmentToIndex(assignment)]) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *DiscreteFactor) hasVariable(variable string) bool {
+	for _, v := range f.Variables {
+		if v == variable {
+			return true
+		}
+	}
+	return false
+}
+
 // Multiply multiplies this factor with another factor
 func (f *DiscreteFactor) Multiply(other *DiscreteFactor) (*DiscreteFactor, error) {
+	return f.combine(other, func(a, b float64) float64 { return a * b })
+}
+
+// combine applies op to the entries of both factors over the union of their scopes
+func (f *DiscreteFactor) combine(other *DiscreteFactor, op func(a, b float64) float64) (*DiscreteFactor, error) {
 	// Find union of variables
 	varSet := make(map[string]bool)
 	for _, v := range f.Variables {
@@ -78,7 +125,7 @@ func (f *DiscreteFactor) Multiply(other *DiscreteFactor) (*DiscreteFactor, error
 	}
 	for k, v := range other.Cardinality {
 		if existing, ok := newCard[k]; ok && existing != v {
-			return nil, fmt.Errorf("cardinality mismatch for variable %s", k)
+			return nil, fmt.Errorf("%w for variable %s", ErrCardinalityMismatch, k)
 		}
 		newCard[k] = v
 	}
@@ -88,30 +135,10 @@ func (f *DiscreteFactor) Multiply(other *DiscreteFactor) (*DiscreteFactor, error
 	for _, v := range newVars {
 		size *= newCard[v]
 	}
-	newValues := make([]float64, size)
-
-	// For each assignment of the new variables
-	assignment := make(map[string]int)
-	f.multiplyHelper(0, newVars, assignment, newCard, other, newValues)
+	newValues := getValues(size)
 
-	return NewDiscreteFactor(newVars, newCard, newValues)
-}
-
-func (f *DiscreteFactor) multiplyHelper(depth int, vars []string, assignment map[string]int,
-	cardinality map[string]int, other *DiscreteFactor, result []float64) {
-	if depth == len(vars) {
-		idx := f.assignmentToIndex(vars, assignment, cardinality)
-		idx1 := f.projectAssignmentToIndex(assignment)
-		idx2 := other.projectAssignmentToIndex(assignment)
-		result[idx] = f.Values[idx1] * other.Values[idx2]
-		return
-	}
-
-	v := vars[depth]
-	for i := 0; i < cardinality[v]; i++ {
-		assignment[v] = i
-		f.multiplyHelper(depth+1, vars, assignment, cardinality, other, result)
-	}
+	f.combineValues(other, newVars, newCard, op, newValues)
+	return newCompiledFactor(newVars, newCard, newValues)
 }
 
 func (f *DiscreteFactor) projectAssignmentToIndex(assignment map[string]int) int {
@@ -125,18 +152,6 @@ func (f *DiscreteFactor) projectAssignmentToIndex(assignment map[string]int) int
 	return idx
 }
 
-func (f *DiscreteFactor) assignmentToIndex(vars []string, assignment map[string]int,
-	cardinality map[string]int) int {
-	idx := 0
-	stride := 1
-	for i := len(vars) - 1; i >= 0; i-- {
-		v := vars[i]
-		idx += assignment[v] * stride
-		stride *= cardinality[v]
-	}
-	return idx
-}
-
 // Marginalize sums out variables from the factor
 func (f *DiscreteFactor) Marginalize(variables []string) (*DiscreteFactor, error) {
 	// Find remaining variables
@@ -172,29 +187,10 @@ func (f *DiscreteFactor) Marginalize(variables []string) (*DiscreteFactor, error
 	for _, v := range newVars {
 		size *= newCard[v]
 	}
-	newValues := make([]float64, size)
+	newValues := getValues(size)
 
-	// Sum over all assignments
-	assignment := make(map[string]int)
-	f.marginalizeHelper(0, f.Variables, assignment, newVars, newCard, newValues)
-
-	return NewDiscreteFactor(newVars, newCard, newValues)
-}
-
-func (f *DiscreteFactor) marginalizeHelper(depth int, vars []string, assignment map[string]int,
-	newVars []string, newCard map[string]int, result []float64) {
-	if depth == len(vars) {
-		oldIdx := f.projectAssignmentToIndex(assignment)
-		newIdx := f.assignmentToIndex(newVars, assignment, newCard)
-		result[newIdx] += f.Values[oldIdx]
-		return
-	}
-
-	v := vars[depth]
-	for i := 0; i < f.Cardinality[v]; i++ {
-		assignment[v] = i
-		f.marginalizeHelper(depth+1, vars, assignment, newVars, newCard, result)
-	}
+	f.marginalizeValues(newVars, false, newValues)
+	return newCompiledFactor(newVars, newCard, newValues)
 }
 
 // Reduce reduces the factor by fixing certain variables to specific values
@@ -238,32 +234,39 @@ func (f *DiscreteFactor) Reduce(evidence map[string]int) (*DiscreteFactor, error
 	for _, v := range newVars {
 		size *= newCard[v]
 	}
-	newValues := make([]float64, size)
+	newValues := getValues(size)
 
 	// Extract values matching evidence
-	assignment := make(map[string]int)
-	for k, v := range evidence {
-		assignment[k] = v
-	}
-	f.reduceHelper(0, newVars, assignment, newCard, newValues)
-
-	return NewDiscreteFactor(newVars, newCard, newValues)
+	f.reduceValues(newVars, evidence, newValues)
+	return newCompiledFactor(newVars, newCard, newValues)
 }
 
-func (f *DiscreteFactor) reduceHelper(depth int, vars []string, assignment map[string]int,
-	newCard map[string]int, result []float64) {
-	if depth == len(vars) {
-		oldIdx := f.projectAssignmentToIndex(assignment)
-		newIdx := f.assignmentToIndex(vars, assignment, newCard)
-		result[newIdx] = f.Values[oldIdx]
-		return
-	}
+// ReduceFactors reduces every factor in the slice by the same evidence.
+// Factors that mention no evidence variable are returned as-is rather than
+// copied, so the result may share factors with the input; callers must not
+// modify them in place.
+func ReduceFactors(factorList []*DiscreteFactor, evidence map[string]int) ([]*DiscreteFactor, error) {
+	result := make([]*DiscreteFactor, len(factorList))
+	for i, factor := range factorList {
+		touched := false
+		for _, v := range factor.Variables {
+			if _, ok := evidence[v]; ok {
+				touched = true
+				break
+			}
+		}
+		if !touched {
+			result[i] = factor
+			continue
+		}
 
-	v := vars[depth]
-	for i := 0; i < newCard[v]; i++ {
-		assignment[v] = i
-		f.reduceHelper(depth+1, vars, assignment, newCard, result)
+		reduced, err := factor.Reduce(evidence)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = reduced
 	}
+	return result, nil
 }
 
 // Normalize normalizes the factor so it sums to 1
@@ -274,7 +277,7 @@ func (f *DiscreteFactor) Normalize() error {
 	}
 
 	if sum == 0 {
-		return fmt.Errorf("cannot normalize factor with sum 0")
+		return fmt.Errorf("cannot normalize factor with sum 0: %w", ErrZeroProbability)
 	}
 
 	for i := range f.Values {
@@ -350,32 +353,9 @@ func (f *DiscreteFactor) MaxMarginalize(variables []string) (*DiscreteFactor, er
 	for _, v := range newVars {
 		size *= newCard[v]
 	}
-	newValues := make([]float64, size)
-	for i := range newValues {
-		newValues[i] = math.Inf(-1)
-	}
+	newValues := getValues(size)
 
 	// Take max over all assignments
-	assignment := make(map[string]int)
-	f.maxMarginalizeHelper(0, f.Variables, assignment, newVars, newCard, newValues)
-
-	return NewDiscreteFactor(newVars, newCard, newValues)
-}
-
-func (f *DiscreteFactor) maxMarginalizeHelper(depth int, vars []string, assignment map[string]int,
-	newVars []string, newCard map[string]int, result []float64) {
-	if depth == len(vars) {
-		oldIdx := f.projectAssignmentToIndex(assignment)
-		newIdx := f.assignmentToIndex(newVars, assignment, newCard)
-		if f.Values[oldIdx] > result[newIdx] {
-			result[newIdx] = f.Values[oldIdx]
-		}
-		return
-	}
-
-	v := vars[depth]
-	for i := 0; i < f.Cardinality[v]; i++ {
-		assignment[v] = i
-		f.maxMarginalizeHelper(depth+1, vars, assignment, newVars, newCard, result)
-	}
+	f.marginalizeValues(newVars, true, newValues)
+	return newCompiledFactor(newVars, newCard, newValues)
 }
@@ -108,3 +108,45 @@ func BenchmarkFactorMultiply_Large(b *testing.B) {
 		_, _ = factor1.Multiply(factor2)
 	}
 }
+
+func BenchmarkFactorMultiply_Parallel(b *testing.B) {
+	// Two 6-variable factors with cardinality 5 sharing three variables
+	factor1 := uniformFactor([]string{"A", "B", "C", "D", "E", "F"}, 5)
+	factor2 := uniformFactor([]string{"D", "E", "F", "G", "H", "I"}, 5)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = factor1.Multiply(factor2)
+	}
+}
+
+func BenchmarkEliminate(b *testing.B) {
+	// Three factors over B whose product has 4^4 entries
+	list := []*DiscreteFactor{
+		uniformFactor([]string{"A", "B"}, 4),
+		uniformFactor([]string{"B", "C", "D"}, 4),
+		uniformFactor([]string{"B", "D"}, 4),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		remaining, _ := Eliminate("B", list, nil)
+		remaining[len(remaining)-1].Release()
+	}
+}
+
+func uniformFactor(vars []string, card int) *DiscreteFactor {
+	cardinality := make(map[string]int, len(vars))
+	size := 1
+	for _, v := range vars {
+		cardinality[v] = card
+		size *= card
+	}
+	values := make([]float64, size)
+	for i := range values {
+		values[i] = 0.1
+	}
+	factor, _ := NewDiscreteFactor(vars, cardinality, values)
+	return factor
+}
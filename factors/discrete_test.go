@@ -2,6 +2,7 @@ package factors
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -121,3 +122,307 @@ func TestFactorNormalize(t *testing.T) {
 		t.Errorf("Expected sum=1.0, got %f", sum)
 	}
 }
+
+func TestEliminate(t *testing.T) {
+	// P(A) P(B | A) with A eliminated leaves P(B)
+	pa, _ := NewDiscreteFactor([]string{"A"}, map[string]int{"A": 2}, []float64{0.3, 0.7})
+	pba, _ := NewDiscreteFactor([]string{"A", "B"}, map[string]int{"A": 2, "B": 2}, []float64{0.9, 0.1, 0.2, 0.8})
+	pc, _ := NewDiscreteFactor([]string{"C"}, map[string]int{"C": 2}, []float64{0.5, 0.5})
+	products := 0
+	left, err := Eliminate("A", []*DiscreteFactor{pa, pba, pc}, func(*DiscreteFactor) { products++ })
+	if err != nil {
+		t.Fatalf("Eliminate failed: %v", err)
+	}
+	if len(left) != 2 || left[0] != pc || products != 1 {
+		t.Fatalf("Expected the untouched factor and one product, got %d factors and %d products", len(left), products)
+	}
+	if pb := left[1].Values; math.Abs(pb[0]-0.41) > 1e-12 || math.Abs(pb[1]-0.59) > 1e-12 {
+		t.Errorf("Expected P(B) = [0.41 0.59], got %v", pb)
+	}
+
+	// Max-product elimination keeps the best A for each B
+	maxed, _ := EliminateWith("A", []*DiscreteFactor{pa, pba}, (*DiscreteFactor).MaxMarginalize, nil)
+	if m := maxed[0].Values; math.Abs(m[0]-0.27) > 1e-12 || math.Abs(m[1]-0.56) > 1e-12 {
+		t.Errorf("Expected max-marginal [0.27 0.56], got %v", m)
+	}
+
+	// The same code integrates out a canonical Gaussian variable
+	gf := newBivariate(t)
+	cf, _ := NewCanonicalFromGaussian(gf)
+	integrated, err := Eliminate("Y", []*CanonicalFactor{cf}, nil)
+	if err != nil {
+		t.Fatalf("Eliminate failed: %v", err)
+	}
+	marginal, _ := integrated[0].ToGaussian()
+	if math.Abs(marginal.MeanOf("X")-1) > 1e-9 || math.Abs(marginal.CovarianceOf("X", "X")-2) > 1e-9 {
+		t.Errorf("Expected X ~ N(1, 2), got N(%.4f, %.4f)", marginal.MeanOf("X"), marginal.CovarianceOf("X", "X"))
+	}
+
+	// Normalizing a scaled canonical factor recovers the density
+	cf.G += 3
+	if err := cf.Normalize(); err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	point := map[string]float64{"X": 0.5, "Y": 2.5}
+	got, _ := cf.LogValue(point)
+	want, _ := gf.LogPDF(point)
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected normalized log density %.6f, got %.6f", want, got)
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	uniform, _ := NewDiscreteFactor([]string{"A"}, map[string]int{"A": 4}, []float64{1, 1, 1, 1})
+	if h := Entropy(uniform); math.Abs(h-math.Log(4)) > 1e-9 {
+		t.Errorf("Expected entropy log 4, got %f", h)
+	}
+
+	point, _ := NewDiscreteFactor([]string{"A"}, map[string]int{"A": 3}, []float64{0, 2, 0})
+	if h := Entropy(point); h != 0 {
+		t.Errorf("Expected entropy 0 for a point mass, got %f", h)
+	}
+}
+
+func TestLogDomainNormalize(t *testing.T) {
+	if got := LogSumExp([]float64{-1000, -1000}); math.Abs(got-(-1000+math.Log(2))) > 1e-9 {
+		t.Errorf("Expected LogSumExp %f, got %f", -1000+math.Log(2), got)
+	}
+
+	// 200 factors of 0.01 underflow in the probability domain
+	small, _ := NewDiscreteFactor([]string{"A"}, map[string]int{"A": 2}, []float64{0.01, 0.03})
+	logSmall := small.Log()
+	product := logSmall
+	for i := 1; i < 200; i++ {
+		next, err := product.MultiplyLog(logSmall)
+		if err != nil {
+			t.Fatalf("MultiplyLog failed: %v", err)
+		}
+		product = next
+	}
+	if err := product.NormalizeLog(); err != nil {
+		t.Fatalf("NormalizeLog failed: %v", err)
+	}
+
+	// P(A=0) = 1 / (1 + 3^200), so log P(A=0) = -log(1 + 3^200)
+	result := product.Exp()
+	if math.Abs(result.Values[1]-1) > 1e-12 {
+		t.Errorf("Expected P(A=1) ~ 1, got %g", result.Values[1])
+	}
+	if want := -200 * math.Log(3); math.Abs(product.Values[0]-want) > 1e-6 {
+		t.Errorf("Expected log P(A=0) %f, got %f", want, product.Values[0])
+	}
+}
+
+func TestFactorEqual(t *testing.T) {
+	f1, _ := NewDiscreteFactor([]string{"A", "B"}, map[string]int{"A": 2, "B": 3},
+		[]float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6})
+	// Same factor with B as the slowest-changing variable
+	f2, _ := NewDiscreteFactor([]string{"B", "A"}, map[string]int{"A": 2, "B": 3},
+		[]float64{0.1, 0.4, 0.2, 0.5, 0.3, 0.6000001})
+
+	if !f1.Equal(f2, 1e-6) || !f2.Equal(f1, 1e-6) {
+		t.Error("Expected factors with reordered variables to be equal")
+	}
+	if f1.Equal(f2, 1e-9) {
+		t.Error("Expected factors to differ at tolerance 1e-9")
+	}
+
+	f3, _ := NewDiscreteFactor([]string{"A", "C"}, map[string]int{"A": 2, "C": 3},
+		[]float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6})
+	if f1.Equal(f3, 1) {
+		t.Error("Expected factors over different variables to differ")
+	}
+}
+
+func TestReduceFactors(t *testing.T) {
+	fa, _ := NewDiscreteFactor([]string{"A"}, map[string]int{"A": 2}, []float64{0.3, 0.7})
+	fab, _ := NewDiscreteFactor([]string{"A", "B"}, map[string]int{"A": 2, "B": 2},
+		[]float64{0.1, 0.9, 0.4, 0.6})
+
+	reduced, err := ReduceFactors([]*DiscreteFactor{fa, fab}, map[string]int{"B": 1})
+	if err != nil {
+		t.Fatalf("ReduceFactors failed: %v", err)
+	}
+	if reduced[0] != fa {
+		t.Error("Expected factor without evidence variables to be passed through")
+	}
+	want, _ := NewDiscreteFactor([]string{"A"}, map[string]int{"A": 2}, []float64{0.9, 0.6})
+	if !reduced[1].Equal(want, 1e-12) {
+		t.Errorf("Unexpected reduced factor: %v", reduced[1])
+	}
+}
+
+func TestCPDTable(t *testing.T) {
+	cpd, _ := NewTabularCPD("Grade", 2,
+		[][]float64{{0.9, 0.1}, {0.25, 0.75}},
+		[]string{"Intelligence"},
+		map[string]int{"Intelligence": 2},
+	)
+	if err := cpd.SetStateNames("Intelligence", []string{"low", "high"}); err != nil {
+		t.Fatalf("SetStateNames failed: %v", err)
+	}
+	if err := cpd.SetStateNames("Grade", []string{"A"}); err == nil {
+		t.Error("Expected error for wrong number of state names")
+	}
+
+	want := "" +
+		"+--------------+---------+---------+\n" +
+		"| Intelligence | Grade=0 | Grade=1 |\n" +
+		"+--------------+---------+---------+\n" +
+		"| low          |  0.9000 |  0.1000 |\n" +
+		"| high         |  0.2500 |  0.7500 |\n" +
+		"+--------------+---------+---------+\n"
+	if got := cpd.Table(); got != want {
+		t.Errorf("Unexpected table:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNoisyOrCPD(t *testing.T) {
+	cpd, err := NewNoisyOrCPD("Fever", map[string]float64{"Flu": 0.8, "Cold": 0.4}, 0.1)
+	if err != nil {
+		t.Fatalf("Failed to create noisy-OR CPD: %v", err)
+	}
+
+	factor, err := cpd.ToFactor()
+	if err != nil {
+		t.Fatalf("ToFactor failed: %v", err)
+	}
+
+	// Variables are sorted: Cold, Fever, Flu
+	off := map[[2]int]float64{
+		{0, 0}: 0.9,
+		{0, 1}: 0.9 * 0.2,
+		{1, 0}: 0.9 * 0.6,
+		{1, 1}: 0.9 * 0.6 * 0.2,
+	}
+	for cold := 0; cold < 2; cold++ {
+		for flu := 0; flu < 2; flu++ {
+			p0 := factor.Values[cold*4+flu]
+			p1 := factor.Values[cold*4+2+flu]
+			if math.Abs(p0-off[[2]int{cold, flu}]) > 1e-12 || math.Abs(p0+p1-1) > 1e-12 {
+				t.Errorf("Cold=%d Flu=%d: got P(Fever)=(%f, %f)", cold, flu, p0, p1)
+			}
+
+			lp, _ := cpd.LogProb(1, map[string]int{"Cold": cold, "Flu": flu})
+			if math.Abs(math.Exp(lp)-p1) > 1e-12 {
+				t.Errorf("LogProb disagrees with factor for Cold=%d Flu=%d", cold, flu)
+			}
+		}
+	}
+
+	if _, err := NewNoisyOrCPD("X", map[string]float64{"Y": 1.5}, 0); err == nil {
+		t.Error("Expected error for weight above 1")
+	}
+}
+
+func TestFactorAccessors(t *testing.T) {
+	factor, _ := NewDiscreteFactor(
+		[]string{"A", "B"},
+		map[string]int{"A": 2, "B": 3},
+		[]float64{0.05, 0.1, 0.15, 0.3, 0.25, 0.15},
+	)
+
+	p, err := factor.ProbabilityOf(map[string]int{"A": 1, "B": 0})
+	if err != nil || math.Abs(p-0.3) > 1e-12 {
+		t.Errorf("P(A=1, B=0) = %v (err %v), want 0.3", p, err)
+	}
+	p, err = factor.ProbabilityOf(map[string]int{"A": 1})
+	if err != nil || math.Abs(p-0.7) > 1e-12 {
+		t.Errorf("P(A=1) = %v (err %v), want 0.7", p, err)
+	}
+	if _, err := factor.ProbabilityOf(map[string]int{"C": 0}); err == nil {
+		t.Error("Expected error for a variable outside the factor")
+	}
+	if _, err := factor.ProbabilityOf(map[string]int{"B": 3}); err == nil {
+		t.Error("Expected error for a state out of range")
+	}
+
+	marginal, err := factor.MarginalFor("B")
+	if err != nil {
+		t.Fatalf("MarginalFor failed: %v", err)
+	}
+	for i, want := range []float64{0.35, 0.35, 0.3} {
+		if math.Abs(marginal[i]-want) > 1e-12 {
+			t.Errorf("P(B=%d) = %v, want %v", i, marginal[i], want)
+		}
+	}
+
+	top := factor.TopStates(2)
+	if len(top) != 2 {
+		t.Fatalf("Expected 2 states, got %d", len(top))
+	}
+	if top[0].Assignment["A"] != 1 || top[0].Assignment["B"] != 0 || top[0].Probability != 0.3 {
+		t.Errorf("Unexpected most probable state %+v", top[0])
+	}
+	if top[1].Assignment["A"] != 1 || top[1].Assignment["B"] != 1 {
+		t.Errorf("Unexpected second state %+v", top[1])
+	}
+	if len(factor.TopStates(0)) != len(factor.Values) {
+		t.Error("Expected TopStates(0) to return every assignment")
+	}
+}
+
+func TestFactorSample(t *testing.T) {
+	factor, _ := NewDiscreteFactor(
+		[]string{"A", "B"},
+		map[string]int{"A": 2, "B": 2},
+		[]float64{1, 0, 3, 4}, // Unnormalized
+	)
+	samples, err := factor.Sample(80000, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Sample failed: %v", err)
+	}
+	counts := make([]float64, 4)
+	for _, s := range samples {
+		counts[2*s["A"]+s["B"]]++
+	}
+	for i, want := range []float64{0.125, 0, 0.375, 0.5} {
+		if got := counts[i] / float64(len(samples)); math.Abs(got-want) > 0.01 {
+			t.Errorf("Frequency of index %d = %v, want %v", i, got, want)
+		}
+	}
+
+	zero, _ := NewDiscreteFactor([]string{"A"}, map[string]int{"A": 2}, []float64{0, 0})
+	if _, err := zero.Sample(1, rand.New(rand.NewSource(1))); err == nil {
+		t.Error("Expected error for a factor with zero mass")
+	}
+}
+
+func TestOrdinalProbitCPD(t *testing.T) {
+	truth, err := NewOrdinalProbitCPD("Grade", []string{"Score"}, map[string]float64{"Score": 1.5}, []float64{-1, 0.5, 2})
+	if err != nil {
+		t.Fatalf("Failed to create CPD: %v", err)
+	}
+	probs, _ := truth.Probabilities(map[string]interface{}{"Score": 0.3})
+	total := 0.0
+	for _, p := range probs {
+		total += p
+	}
+	if len(probs) != 4 || math.Abs(total-1) > 1e-12 {
+		t.Errorf("Expected 4 state probabilities summing to 1, got %v", probs)
+	}
+	if _, err := NewOrdinalProbitCPD("Grade", nil, map[string]float64{}, []float64{1, 1}); err == nil {
+		t.Error("Expected an error for thresholds that do not increase")
+	}
+
+	rng := rand.New(rand.NewSource(4))
+	inputs := make([][]float64, 3000)
+	states := make([]int, len(inputs))
+	for i := range inputs {
+		y := rng.NormFloat64()
+		inputs[i] = []float64{y}
+		states[i], _ = truth.Sample(map[string]interface{}{"Score": y}, rng)
+	}
+	fitted, err := FitOrdinalProbitCPD("Grade", []string{"Score"}, inputs, states, nil, 4)
+	if err != nil {
+		t.Fatalf("FitOrdinalProbitCPD failed: %v", err)
+	}
+	if math.Abs(fitted.Coefficients["Score"]-1.5) > 0.1 {
+		t.Errorf("Expected coefficient 1.5, got %.3f", fitted.Coefficients["Score"])
+	}
+	for k, want := range truth.Thresholds {
+		if math.Abs(fitted.Thresholds[k]-want) > 0.1 {
+			t.Errorf("Expected threshold %d at %.2f, got %.3f", k, want, fitted.Thresholds[k])
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	randv2 "math/rand/v2"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Distribution is a univariate distribution: the small interface continuous
+// CPDs and copula marginals delegate sampling, densities and quantiles to.
+// The distributions of gonum's stat/distuv package implement it.
+type Distribution interface {
+	// LogProb returns the log density, or log mass for counts, at x
+	LogProb(x float64) float64
+	// CDF returns P(X ≤ x)
+	CDF(x float64) float64
+	// Quantile returns the smallest x with CDF(x) ≥ p for p in (0, 1)
+	Quantile(p float64) float64
+	// Rand draws a value from the distribution's random source
+	Rand() float64
+}
+
+// Family names a parametric family of a ParametricCPD
+type Family string
+
+// Location families take the linear predictor θ as their location; positive
+// families take it as the log of their mean, median or scale, as noted
+const (
+	FamilyNormal      Family = "normal"      // N(θ, Scale²)
+	FamilyLaplace     Family = "laplace"     // Laplace(θ, Scale)
+	FamilyStudentsT   Family = "studentst"   // Student's t of Shape degrees of freedom, location θ and scale Scale
+	FamilyGumbel      Family = "gumbel"      // Right-skewed Gumbel(θ, Scale)
+	FamilyLogNormal   Family = "lognormal"   // exp(N(θ, Scale²)), θ the log median
+	FamilyExponential Family = "exponential" // θ the log mean
+	FamilyGamma       Family = "gamma"       // Shape k, θ the log mean
+	FamilyWeibull     Family = "weibull"     // Shape k, θ the log scale
+)
+
+// ParametricCPD is a continuous CPD of any Family whose parameter θ is linear
+// in continuous parents:
+// X | y ~ Family(θ(y), Scale, Shape), θ(y) = β₀ + Σᵢ βᵢyᵢ
+// Sampling, densities and quantiles are delegated to gonum's distuv.
+type ParametricCPD struct {
+	Variable     string
+	Parents      []string
+	Intercept    float64            // β₀
+	Coefficients map[string]float64 // βᵢ for each parent
+	Family       Family
+	Scale        float64 // Scale of location families and the log-normal; unused otherwise
+	Shape        float64 // Degrees of freedom of Student's t, shape of Gamma and Weibull; unused otherwise
+}
+
+// NewParametricCPD creates a CPD of the given family, checking that the
+// parameters it uses are positive
+func NewParametricCPD(variable string, parents []string, intercept float64, coefficients map[string]float64,
+	family Family, scale, shape float64) (*ParametricCPD, error) {
+	switch family {
+	case FamilyNormal, FamilyLaplace, FamilyGumbel, FamilyLogNormal:
+		if !(scale > 0) {
+			return nil, fmt.Errorf("scale of %s must be positive, got %v: %w", variable, scale, ErrInvalidDistribution)
+		}
+	case FamilyStudentsT:
+		if !(scale > 0) || !(shape > 0) {
+			return nil, fmt.Errorf("scale and degrees of freedom of %s must be positive, got %v and %v: %w",
+				variable, scale, shape, ErrInvalidDistribution)
+		}
+	case FamilyGamma, FamilyWeibull:
+		if !(shape > 0) {
+			return nil, fmt.Errorf("shape of %s must be positive, got %v: %w", variable, shape, ErrInvalidDistribution)
+		}
+	case FamilyExponential:
+	default:
+		return nil, fmt.Errorf("unknown distribution family %q: %w", family, ErrInvalidDistribution)
+	}
+	for _, p := range parents {
+		if _, ok := coefficients[p]; !ok {
+			return nil, fmt.Errorf("missing coefficient for parent %s", p)
+		}
+	}
+	coefs := make(map[string]float64, len(coefficients))
+	for p, c := range coefficients {
+		coefs[p] = c
+	}
+	return &ParametricCPD{
+		Variable:     variable,
+		Parents:      append([]string(nil), parents...),
+		Intercept:    intercept,
+		Coefficients: coefs,
+		Family:       family,
+		Scale:        scale,
+		Shape:        shape,
+	}, nil
+}
+
+// GetVariable returns the child variable
+func (cpd *ParametricCPD) GetVariable() string {
+	return cpd.Variable
+}
+
+// GetParents returns a copy of the CPD's parents
+func (cpd *ParametricCPD) GetParents() []string {
+	return append([]string(nil), cpd.Parents...)
+}
+
+// Distribution returns the distribution of the variable given the parent
+// values, drawing from rng. rng may be nil when the result is not sampled.
+func (cpd *ParametricCPD) Distribution(parentValues map[string]interface{}, rng *rand.Rand) (Distribution, error) {
+	theta, err := linearPredictor(cpd.Intercept, cpd.Coefficients, cpd.Parents, parentValues)
+	if err != nil {
+		return nil, err
+	}
+	src := source(rng)
+	switch cpd.Family {
+	case FamilyNormal:
+		return distuv.Normal{Mu: theta, Sigma: cpd.Scale, Src: src}, nil
+	case FamilyLaplace:
+		return distuv.Laplace{Mu: theta, Scale: cpd.Scale, Src: src}, nil
+	case FamilyStudentsT:
+		return distuv.StudentsT{Mu: theta, Sigma: cpd.Scale, Nu: cpd.Shape, Src: src}, nil
+	case FamilyGumbel:
+		return distuv.GumbelRight{Mu: theta, Beta: cpd.Scale, Src: src}, nil
+	case FamilyLogNormal:
+		return distuv.LogNormal{Mu: theta, Sigma: cpd.Scale, Src: src}, nil
+	case FamilyExponential:
+		return distuv.Exponential{Rate: math.Exp(-theta), Src: src}, nil
+	case FamilyGamma:
+		return distuv.Gamma{Alpha: cpd.Shape, Beta: cpd.Shape * math.Exp(-theta), Src: src}, nil
+	case FamilyWeibull:
+		return distuv.Weibull{K: cpd.Shape, Lambda: math.Exp(theta), Src: src}, nil
+	}
+	return nil, fmt.Errorf("unknown distribution family %q: %w", cpd.Family, ErrInvalidDistribution)
+}
+
+// Sample draws a value given the parent values
+func (cpd *ParametricCPD) Sample(parentValues map[string]interface{}, rng *rand.Rand) (float64, error) {
+	d, err := cpd.Distribution(parentValues, rng)
+	if err != nil {
+		return 0, err
+	}
+	return d.Rand(), nil
+}
+
+// LogPDF evaluates the log density at x
+func (cpd *ParametricCPD) LogPDF(x float64, parentValues map[string]interface{}) (float64, error) {
+	d, err := cpd.Distribution(parentValues, nil)
+	if err != nil {
+		return 0, err
+	}
+	return d.LogProb(x), nil
+}
+
+// CDF returns P(X ≤ x | parents)
+func (cpd *ParametricCPD) CDF(x float64, parentValues map[string]interface{}) (float64, error) {
+	d, err := cpd.Distribution(parentValues, nil)
+	if err != nil {
+		return 0, err
+	}
+	return d.CDF(x), nil
+}
+
+// Quantile returns the p-quantile of X given the parents, for p in (0, 1)
+func (cpd *ParametricCPD) Quantile(p float64, parentValues map[string]interface{}) (float64, error) {
+	if !(p > 0 && p < 1) {
+		return 0, fmt.Errorf("quantile %v is not in (0, 1)", p)
+	}
+	d, err := cpd.Distribution(parentValues, nil)
+	if err != nil {
+		return 0, err
+	}
+	return d.Quantile(p), nil
+}
+
+// Copy creates a deep copy
+func (cpd *ParametricCPD) Copy() *ParametricCPD {
+	copied, _ := NewParametricCPD(cpd.Variable, cpd.Parents, cpd.Intercept, cpd.Coefficients, cpd.Family, cpd.Scale, cpd.Shape)
+	return copied
+}
+
+// String returns a string representation
+func (cpd *ParametricCPD) String() string {
+	return fmt.Sprintf("ParametricCPD(%s | %v, %s)", cpd.Variable, cpd.Parents, cpd.Family)
+}
+
+// source adapts rng as the random source of a distuv distribution, keeping
+// nil as nil so that distuv falls back to its global source
+func source(rng *rand.Rand) randv2.Source {
+	if rng == nil {
+		return nil
+	}
+	return rng
+}
+
+// poisson adds the quantile function distuv.Poisson lacks
+type poisson struct {
+	distuv.Poisson
+}
+
+// Quantile returns the smallest count k with CDF(k) ≥ p
+func (d poisson) Quantile(p float64) float64 {
+	if !(p > 0) {
+		return 0
+	}
+	if p >= 1 {
+		return math.Inf(1)
+	}
+	// Step from a normal approximation, then to the exact count
+	k := math.Max(0, math.Floor(d.Lambda+math.Sqrt(d.Lambda)*normalQuantile(p)))
+	for k > 0 && d.CDF(k-1) >= p {
+		k--
+	}
+	for d.CDF(k) < p {
+		k++
+	}
+	return k
+}
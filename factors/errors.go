@@ -0,0 +1,34 @@
+package factors
+
+import "errors"
+
+var (
+	// ErrCardinalityMismatch is returned when factors or CPDs disagree on the
+	// number of states of a variable
+	ErrCardinalityMismatch = errors.New("cardinality mismatch")
+
+	// ErrInvalidDistribution is returned when probabilities do not form a
+	// distribution or a variance is not positive
+	ErrInvalidDistribution = errors.New("invalid distribution")
+
+	// ErrZeroProbability is returned when a factor with zero total mass is
+	// normalized, typically because the evidence is impossible
+	ErrZeroProbability = errors.New("zero probability")
+
+	// ErrNotPositiveDefinite is returned when a covariance or precision matrix
+	// cannot be factorized
+	ErrNotPositiveDefinite = errors.New("matrix is not positive definite")
+
+	// ErrNotImplementedCLG is returned by operations that do not yet support
+	// conditional linear Gaussian CPDs with discrete parents
+	ErrNotImplementedCLG = errors.New("not implemented for conditional linear Gaussian CPDs")
+
+	// ErrHeteroscedastic is returned by operations that need a Gaussian CPD's
+	// variance not to depend on its parents, such as conversion to a
+	// canonical factor
+	ErrHeteroscedastic = errors.New("not implemented for heteroscedastic Gaussian CPDs")
+
+	// ErrNonlinearMean is returned by operations that need a Gaussian CPD's
+	// mean to be linear in its parents, which basis terms break
+	ErrNonlinearMean = errors.New("not implemented for basis-expanded Gaussian CPDs")
+)
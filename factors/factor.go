@@ -0,0 +1,209 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Factor is the algebra the factor types share, over which variable
+// elimination is written once. F is the factor type itself and E the type of
+// its evidence values: int states for discrete factors and float64 values for
+// Gaussian ones.
+type Factor[F any, E any] interface {
+	// Scope returns the factor's variables
+	Scope() []string
+	// Size returns the number of entries that store the factor
+	Size() int
+	// Multiply returns the product of the factor and another
+	Multiply(other F) (F, error)
+	// Marginalize sums or integrates the given variables out
+	Marginalize(variables []string) (F, error)
+	// Reduce fixes the observed variables at their values
+	Reduce(evidence map[string]E) (F, error)
+	// Normalize scales the factor in place to total mass one
+	Normalize() error
+}
+
+var (
+	_ Factor[*DiscreteFactor, int]      = (*DiscreteFactor)(nil)
+	_ Factor[*GaussianFactor, float64]  = (*GaussianFactor)(nil)
+	_ Factor[*CanonicalFactor, float64] = (*CanonicalFactor)(nil)
+)
+
+// Eliminate multiplies the factors of list that mention variable and sums or
+// integrates the variable out of their product, returning the other factors
+// followed by the result. observe, if not nil, is called with each product
+// and must not retain it, as temporary products are released for reuse.
+// Discrete factors whose product would reach ParallelThreshold entries are
+// eliminated by SumProduct instead, in parallel and without building the
+// product; observe is then called once, with the result.
+func Eliminate[F Factor[F, E], E any](variable string, list []F, observe func(F)) ([]F, error) {
+	relevant, irrelevant := partition(variable, list)
+	if len(relevant) == 0 {
+		return list, nil
+	}
+	if discrete, ok := any(relevant).([]*DiscreteFactor); ok && len(discrete) > 1 && useParallel(productSize(discrete)) {
+		result, err := SumProduct(discrete, []string{variable})
+		if err != nil {
+			return nil, fmt.Errorf("eliminating %s: %w", variable, err)
+		}
+		f := any(result).(F)
+		if observe != nil {
+			observe(f)
+		}
+		return append(irrelevant, f), nil
+	}
+	return eliminate(variable, relevant, irrelevant, F.Marginalize, observe)
+}
+
+// EliminateWith is Eliminate removing the variable from the product by the
+// given function, such as DiscreteFactor.MaxMarginalize for max-product
+// elimination
+func EliminateWith[F Factor[F, E], E any](variable string, list []F,
+	marginalize func(F, []string) (F, error), observe func(F)) ([]F, error) {
+	relevant, irrelevant := partition(variable, list)
+	if len(relevant) == 0 {
+		return list, nil
+	}
+	return eliminate(variable, relevant, irrelevant, marginalize, observe)
+}
+
+func eliminate[F Factor[F, E], E any](variable string, relevant, irrelevant []F,
+	marginalize func(F, []string) (F, error), observe func(F)) ([]F, error) {
+	product, err := Product(relevant, observe)
+	if err != nil {
+		return nil, fmt.Errorf("eliminating %s: %w", variable, err)
+	}
+	marginalized, err := marginalize(product, []string{variable})
+	if err != nil {
+		return nil, fmt.Errorf("eliminating %s: %w", variable, err)
+	}
+	if len(relevant) > 1 && any(marginalized) != any(product) {
+		release(product)
+	}
+	return append(irrelevant, marginalized), nil
+}
+
+// partition splits list into the factors that mention variable and the rest
+func partition[F Factor[F, E], E any](variable string, list []F) (relevant, irrelevant []F) {
+	irrelevant = make([]F, 0, len(list))
+	for _, f := range list {
+		if mentions(f.Scope(), variable) {
+			relevant = append(relevant, f)
+		} else {
+			irrelevant = append(irrelevant, f)
+		}
+	}
+	return relevant, irrelevant
+}
+
+// productSize returns the entries of the product of the factors
+func productSize(list []*DiscreteFactor) int {
+	cardinality := make(map[string]int)
+	for _, f := range list {
+		for _, v := range f.Variables {
+			cardinality[v] = f.Cardinality[v]
+		}
+	}
+	size := 1
+	for _, c := range cardinality {
+		size *= c
+	}
+	return size
+}
+
+// Product multiplies the factors of a non-empty list from left to right,
+// releasing the partial products. observe, if not nil, is called with each
+// product and must not retain it.
+func Product[F Factor[F, E], E any](list []F, observe func(F)) (F, error) {
+	if len(list) == 0 {
+		var zero F
+		return zero, fmt.Errorf("no factors to multiply")
+	}
+	product := list[0]
+	for i, f := range list[1:] {
+		next, err := product.Multiply(f)
+		if err != nil {
+			var zero F
+			return zero, err
+		}
+		if i > 0 {
+			release(product)
+		}
+		product = next
+		if observe != nil {
+			observe(product)
+		}
+	}
+	return product, nil
+}
+
+func mentions(scope []string, variable string) bool {
+	for _, v := range scope {
+		if v == variable {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope returns the factor's variables
+func (f *DiscreteFactor) Scope() []string {
+	return f.Variables
+}
+
+// Size returns the number of table entries
+func (f *DiscreteFactor) Size() int {
+	return len(f.Values)
+}
+
+// Scope returns the factor's variables
+func (gf *GaussianFactor) Scope() []string {
+	return gf.Variables
+}
+
+// Size returns the number of mean and covariance entries
+func (gf *GaussianFactor) Size() int {
+	n := len(gf.Variables)
+	return n*n + n
+}
+
+// Normalize does nothing: a moment-form Gaussian is always a normalized
+// density
+func (gf *GaussianFactor) Normalize() error {
+	return nil
+}
+
+// Scope returns the factor's variables
+func (cf *CanonicalFactor) Scope() []string {
+	return cf.Variables
+}
+
+// Size returns the number of K, h and g entries
+func (cf *CanonicalFactor) Size() int {
+	n := len(cf.Variables)
+	return n*n + n + 1
+}
+
+// Normalize sets g so that the factor integrates to one:
+// g = -½hᵀK⁻¹h - ½log det(2πK⁻¹)
+// K must be positive definite. A constant factor becomes the constant one.
+func (cf *CanonicalFactor) Normalize() error {
+	n := len(cf.Variables)
+	if n == 0 {
+		cf.G = 0
+		return nil
+	}
+	chol, err := cholesky(cf.K)
+	if err != nil {
+		return fmt.Errorf("factor is not a normalizable Gaussian: %w", err)
+	}
+	mean := mat.NewVecDense(n, nil)
+	if err := chol.SolveVecTo(mean, cf.H); err != nil {
+		return err
+	}
+	cf.G = -0.5*mat.Dot(cf.H, mean) - 0.5*float64(n)*math.Log(2*math.Pi) + 0.5*chol.LogDet()
+	return nil
+}
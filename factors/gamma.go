@@ -0,0 +1,114 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// GammaCPD represents a positive continuous variable with a Gamma
+// distribution whose mean is log-linear in continuous parents:
+// X | y ~ Gamma(k, μ(y)/k), log μ(y) = β₀ + Σᵢ βᵢyᵢ
+// The shape k fixes the coefficient of variation at 1/√k; k = 1 gives the
+// exponential distribution. Samples are always positive.
+type GammaCPD struct {
+	Variable     string
+	Parents      []string
+	Intercept    float64            // β₀
+	Coefficients map[string]float64 // βᵢ for each parent
+	Shape        float64            // k
+}
+
+// NewGammaCPD creates a Gamma CPD with a log-linear mean
+func NewGammaCPD(variable string, parents []string, intercept float64, coefficients map[string]float64, shape float64) (*GammaCPD, error) {
+	if shape <= 0 || math.IsNaN(shape) {
+		return nil, fmt.Errorf("shape of %s must be positive, got %v: %w", variable, shape, ErrInvalidDistribution)
+	}
+	for _, p := range parents {
+		if _, ok := coefficients[p]; !ok {
+			return nil, fmt.Errorf("missing coefficient for parent %s", p)
+		}
+	}
+	coefs := make(map[string]float64, len(coefficients))
+	for p, c := range coefficients {
+		coefs[p] = c
+	}
+	return &GammaCPD{
+		Variable:     variable,
+		Parents:      append([]string(nil), parents...),
+		Intercept:    intercept,
+		Coefficients: coefs,
+		Shape:        shape,
+	}, nil
+}
+
+// NewExponentialCPD creates an exponential CPD with a log-linear mean, the
+// Gamma CPD of shape one
+func NewExponentialCPD(variable string, parents []string, intercept float64, coefficients map[string]float64) (*GammaCPD, error) {
+	return NewGammaCPD(variable, parents, intercept, coefficients, 1)
+}
+
+// GetVariable returns the child variable
+func (cpd *GammaCPD) GetVariable() string {
+	return cpd.Variable
+}
+
+// GetParents returns a copy of the CPD's parents
+func (cpd *GammaCPD) GetParents() []string {
+	return append([]string(nil), cpd.Parents...)
+}
+
+// GetMean returns the conditional mean μ given the parent values
+func (cpd *GammaCPD) GetMean(parentValues map[string]interface{}) (float64, error) {
+	eta, err := linearPredictor(cpd.Intercept, cpd.Coefficients, cpd.Parents, parentValues)
+	if err != nil {
+		return 0, err
+	}
+	return math.Exp(eta), nil
+}
+
+// Distribution returns Gamma(k, μ/k) for the conditional mean μ given the
+// parent values, drawing from rng. rng may be nil when the result is not
+// sampled.
+func (cpd *GammaCPD) Distribution(parentValues map[string]interface{}, rng *rand.Rand) (Distribution, error) {
+	mean, err := cpd.GetMean(parentValues)
+	if err != nil {
+		return nil, err
+	}
+	return distuv.Gamma{Alpha: cpd.Shape, Beta: cpd.Shape / mean, Src: source(rng)}, nil
+}
+
+// Sample draws a positive value given the parent values
+func (cpd *GammaCPD) Sample(parentValues map[string]interface{}, rng *rand.Rand) (float64, error) {
+	d, err := cpd.Distribution(parentValues, rng)
+	if err != nil {
+		return 0, err
+	}
+	return d.Rand(), nil
+}
+
+// LogPDF evaluates the log density at x. Values that are not positive have
+// zero density.
+func (cpd *GammaCPD) LogPDF(x float64, parentValues map[string]interface{}) (float64, error) {
+	d, err := cpd.Distribution(parentValues, nil)
+	if err != nil {
+		return 0, err
+	}
+	if x <= 0 {
+		return math.Inf(-1), nil
+	}
+	return d.LogProb(x), nil
+}
+
+// Copy creates a deep copy
+func (cpd *GammaCPD) Copy() *GammaCPD {
+	copied, _ := NewGammaCPD(cpd.Variable, cpd.Parents, cpd.Intercept, cpd.Coefficients, cpd.Shape)
+	return copied
+}
+
+// String returns a string representation
+func (cpd *GammaCPD) String() string {
+	return fmt.Sprintf("GammaCPD(%s | %v, shape %g)", cpd.Variable, cpd.Parents, cpd.Shape)
+}
@@ -5,79 +5,181 @@ import (
 	"math"
 	"sort"
 	"strings"
+
+	"gonum.org/v1/gonum/mat"
 )
 
 // GaussianFactor represents a Gaussian (normal) distribution over continuous variables
 // Parameterized as: N(x; μ, Σ) = (2π)^(-k/2) |Σ|^(-1/2) exp(-0.5(x-μ)^T Σ^(-1) (x-μ))
-// For computational efficiency, we store in canonical form:
-// φ(x) = exp(-0.5 x^T K x + h^T x + g)
-// where K is the precision matrix (inverse covariance), h is the information vector
+// The mean and covariance are stored as gonum matrices indexed in the order of
+// Variables; inversions and determinants go through a Cholesky factorization.
 type GaussianFactor struct {
-	Variables  []string                      // Variable names
-	Mean       map[string]float64            // Mean for each variable (μ)
-	Covariance map[string]map[string]float64 // Covariance matrix (Σ)
+	Variables  []string       // Variable names, in matrix index order
+	Mean       *mat.VecDense  // Mean vector (μ)
+	Covariance *mat.SymDense  // Covariance matrix (Σ)
+	index      map[string]int // Variable name -> matrix index
 }
 
-// NewGaussianFactor creates a new Gaussian factor
+// NewGaussianFactor creates a new Gaussian factor from per-variable means and covariances
 func NewGaussianFactor(variables []string, mean map[string]float64, covariance map[string]map[string]float64) (*GaussianFactor, error) {
 	// Validate inputs
 	if len(variables) == 0 {
 		return nil, fmt.Errorf("variables cannot be empty")
 	}
 
-	// Check mean has all variables
-	for _, v := range variables {
-		if _, ok := mean[v]; !ok {
-			return nil, fmt.Errorf("mean missing for variable %s", v)
+	n := len(variables)
+	meanVec := mat.NewVecDense(n, nil)
+	cov := mat.NewSymDense(n, nil)
+
+	for i, v1 := range variables {
+		m, ok := mean[v1]
+		if !ok {
+			return nil, fmt.Errorf("mean missing for variable %s", v1)
 		}
-	}
+		meanVec.SetVec(i, m)
 
-	// Check covariance matrix
-	for _, v1 := range variables {
 		if _, ok := covariance[v1]; !ok {
 			return nil, fmt.Errorf("covariance missing for variable %s", v1)
 		}
-		for _, v2 := range variables {
-			if _, ok := covariance[v1][v2]; !ok {
+		for j, v2 := range variables {
+			c, ok := covariance[v1][v2]
+			if !ok {
 				return nil, fmt.Errorf("covariance missing for variables %s, %s", v1, v2)
 			}
 			// Check symmetry
-			if math.Abs(covariance[v1][v2]-covariance[v2][v1]) > 1e-9 {
+			if math.Abs(c-covariance[v2][v1]) > 1e-9 {
 				return nil, fmt.Errorf("covariance matrix not symmetric")
 			}
+			if j >= i {
+				cov.SetSym(i, j, c)
+			}
+		}
+	}
+
+	return NewGaussianFactorFromMatrix(variables, meanVec, cov)
+}
+
+// NewGaussianFactorFromMatrix creates a Gaussian factor from a mean vector and
+// covariance matrix whose rows follow the order of variables
+func NewGaussianFactorFromMatrix(variables []string, mean *mat.VecDense, covariance *mat.SymDense) (*GaussianFactor, error) {
+	if len(variables) == 0 {
+		return nil, fmt.Errorf("variables cannot be empty")
+	}
+	if mean.Len() != len(variables) || covariance.SymmetricDim() != len(variables) {
+		return nil, fmt.Errorf("mean length %d and covariance size %d do not match %d variables",
+			mean.Len(), covariance.SymmetricDim(), len(variables))
+	}
+
+	index := make(map[string]int, len(variables))
+	for i, v := range variables {
+		if _, dup := index[v]; dup {
+			return nil, fmt.Errorf("duplicate variable %s", v)
 		}
+		index[v] = i
 	}
 
 	return &GaussianFactor{
 		Variables:  variables,
 		Mean:       mean,
 		Covariance: covariance,
+		index:      index,
 	}, nil
 }
 
+// Index returns the matrix index of a variable
+func (gf *GaussianFactor) Index(variable string) (int, bool) {
+	i, ok := gf.index[variable]
+	return i, ok
+}
+
+// MeanOf returns the mean of a variable, or NaN if it is not in the factor
+func (gf *GaussianFactor) MeanOf(variable string) float64 {
+	i, ok := gf.index[variable]
+	if !ok {
+		return math.NaN()
+	}
+	return gf.Mean.AtVec(i)
+}
+
+// CovarianceOf returns Cov(v1, v2), or NaN if either is not in the factor
+func (gf *GaussianFactor) CovarianceOf(v1, v2 string) float64 {
+	i, ok1 := gf.index[v1]
+	j, ok2 := gf.index[v2]
+	if !ok1 || !ok2 {
+		return math.NaN()
+	}
+	return gf.Covariance.At(i, j)
+}
+
 // Copy creates a deep copy of the Gaussian factor
 func (gf *GaussianFactor) Copy() *GaussianFactor {
 	varsCopy := make([]string, len(gf.Variables))
 	copy(varsCopy, gf.Variables)
 
-	meanCopy := make(map[string]float64)
-	for k, v := range gf.Mean {
-		meanCopy[k] = v
-	}
+	meanCopy := mat.VecDenseCopyOf(gf.Mean)
+	covCopy := mat.NewSymDense(len(gf.Variables), nil)
+	covCopy.CopySym(gf.Covariance)
 
-	covCopy := make(map[string]map[string]float64)
-	for k1, v1 := range gf.Covariance {
-		covCopy[k1] = make(map[string]float64)
-		for k2, v2 := range v1 {
-			covCopy[k1][k2] = v2
-		}
+	indexCopy := make(map[string]int, len(gf.index))
+	for k, v := range gf.index {
+		indexCopy[k] = v
 	}
 
 	return &GaussianFactor{
 		Variables:  varsCopy,
 		Mean:       meanCopy,
 		Covariance: covCopy,
+		index:      indexCopy,
+	}
+}
+
+// indicesOf returns the matrix indices of the given variables
+func (gf *GaussianFactor) indicesOf(variables []string) []int {
+	idx := make([]int, len(variables))
+	for i, v := range variables {
+		idx[i] = gf.index[v]
 	}
+	return idx
+}
+
+// subMean extracts the mean entries at the given indices
+func (gf *GaussianFactor) subMean(idx []int) *mat.VecDense {
+	sub := mat.NewVecDense(len(idx), nil)
+	for i, k := range idx {
+		sub.SetVec(i, gf.Mean.AtVec(k))
+	}
+	return sub
+}
+
+// subCovariance extracts the symmetric covariance block at the given indices
+func (gf *GaussianFactor) subCovariance(idx []int) *mat.SymDense {
+	sub := mat.NewSymDense(len(idx), nil)
+	for i, ki := range idx {
+		for j := i; j < len(idx); j++ {
+			sub.SetSym(i, j, gf.Covariance.At(ki, idx[j]))
+		}
+	}
+	return sub
+}
+
+// crossCovariance extracts the covariance block Σ[rows, cols]
+func (gf *GaussianFactor) crossCovariance(rows, cols []int) *mat.Dense {
+	cross := mat.NewDense(len(rows), len(cols), nil)
+	for i, ri := range rows {
+		for j, cj := range cols {
+			cross.Set(i, j, gf.Covariance.At(ri, cj))
+		}
+	}
+	return cross
+}
+
+// cholesky factorizes a symmetric positive definite matrix
+func cholesky(sym *mat.SymDense) (*mat.Cholesky, error) {
+	var chol mat.Cholesky
+	if ok := chol.Factorize(sym); !ok {
+		return nil, ErrNotPositiveDefinite
+	}
+	return &chol, nil
 }
 
 // Marginalize marginalizes out specified variables
@@ -101,18 +203,8 @@ func (gf *GaussianFactor) Marginalize(variables []string) (*GaussianFactor, erro
 	}
 
 	// Extract sub-mean and sub-covariance
-	newMean := make(map[string]float64)
-	newCov := make(map[string]map[string]float64)
-
-	for _, v1 := range newVars {
-		newMean[v1] = gf.Mean[v1]
-		newCov[v1] = make(map[string]float64)
-		for _, v2 := range newVars {
-			newCov[v1][v2] = gf.Covariance[v1][v2]
-		}
-	}
-
-	return NewGaussianFactor(newVars, newMean, newCov)
+	idx := gf.indicesOf(newVars)
+	return NewGaussianFactorFromMatrix(newVars, gf.subMean(idx), gf.subCovariance(idx))
 }
 
 // Reduce conditions the Gaussian on observed values
@@ -144,115 +236,56 @@ func (gf *GaussianFactor) Reduce(evidence map[string]float64) (*GaussianFactor,
 	// X1 = unobserved, X2 = observed
 	// μ_1|2 = μ_1 + Σ_12 Σ_22^(-1) (x2 - μ_2)
 	// Σ_1|2 = Σ_11 - Σ_12 Σ_22^(-1) Σ_21
+	idx1 := gf.indicesOf(unobserved)
+	idx2 := gf.indicesOf(observed)
 
-	newMean := make(map[string]float64)
-	newCov := make(map[string]map[string]float64)
-
-	// Get Σ_22^(-1) (covariance of observed variables inverted)
-	sigma22Inv, err := gf.invertSubMatrix(observed)
+	chol, err := cholesky(gf.subCovariance(idx2))
 	if err != nil {
-		return nil, fmt.Errorf("failed to invert covariance matrix: %v", err)
+		return nil, fmt.Errorf("failed to factorize observed covariance: %w", err)
 	}
 
-	// Compute adjustment: Σ_12 Σ_22^(-1) (x2 - μ_2)
-	adjustment := make(map[string]float64)
-	for _, v1 := range unobserved {
-		sum := 0.0
-		for i, v2i := range observed {
-			for j, v2j := range observed {
-				sum += gf.Covariance[v1][v2i] * sigma22Inv[i][j] * (evidence[v2j] - gf.Mean[v2j])
-			}
-		}
-		adjustment[v1] = sum
+	// x2 - μ_2
+	diff := mat.NewVecDense(len(observed), nil)
+	for i, v := range observed {
+		diff.SetVec(i, evidence[v]-gf.Mean.AtVec(idx2[i]))
 	}
 
-	// Compute new mean: μ_1|2 = μ_1 + adjustment
-	for _, v := range unobserved {
-		newMean[v] = gf.Mean[v] + adjustment[v]
+	// Σ_22^(-1) (x2 - μ_2)
+	var solved mat.VecDense
+	if err := chol.SolveVecTo(&solved, diff); err != nil {
+		return nil, fmt.Errorf("failed to solve for conditional mean: %w", err)
 	}
 
-	// Compute new covariance: Σ_1|2 = Σ_11 - Σ_12 Σ_22^(-1) Σ_21
-	for _, v1 := range unobserved {
-		newCov[v1] = make(map[string]float64)
-		for _, v2 := range unobserved {
-			val := gf.Covariance[v1][v2]
-			// Subtract Σ_12 Σ_22^(-1) Σ_21
-			for i, obs1 := range observed {
-				for j, obs2 := range observed {
-					val -= gf.Covariance[v1][obs1] * sigma22Inv[i][j] * gf.Covariance[obs2][v2]
-				}
-			}
-			newCov[v1][v2] = val
-		}
-	}
-
-	return NewGaussianFactor(unobserved, newMean, newCov)
-}
-
-// invertSubMatrix inverts the covariance submatrix for given variables
-func (gf *GaussianFactor) invertSubMatrix(vars []string) ([][]float64, error) {
-	n := len(vars)
-
-	// Build matrix
-	matrix := make([][]float64, n)
-	for i := range matrix {
-		matrix[i] = make([]float64, n)
-		for j := range matrix[i] {
-			matrix[i][j] = gf.Covariance[vars[i]][vars[j]]
-		}
-	}
-
-	// Use Gaussian elimination with partial pivoting
-	inv := make([][]float64, n)
-	for i := range inv {
-		inv[i] = make([]float64, n)
-		inv[i][i] = 1.0
-	}
-
-	// Forward elimination
-	for i := 0; i < n; i++ {
-		// Find pivot
-		maxRow := i
-		for k := i + 1; k < n; k++ {
-			if math.Abs(matrix[k][i]) > math.Abs(matrix[maxRow][i]) {
-				maxRow = k
-			}
-		}
+	sigma12 := gf.crossCovariance(idx1, idx2)
 
-		// Swap rows
-		matrix[i], matrix[maxRow] = matrix[maxRow], matrix[i]
-		inv[i], inv[maxRow] = inv[maxRow], inv[i]
-
-		// Check for singular matrix
-		if math.Abs(matrix[i][i]) < 1e-10 {
-			return nil, fmt.Errorf("matrix is singular or nearly singular")
-		}
-
-		// Scale pivot row
-		pivot := matrix[i][i]
-		for j := 0; j < n; j++ {
-			matrix[i][j] /= pivot
-			inv[i][j] /= pivot
-		}
+	// μ_1|2 = μ_1 + Σ_12 Σ_22^(-1) (x2 - μ_2)
+	newMean := gf.subMean(idx1)
+	newMean.MulVec(sigma12, &solved)
+	newMean.AddVec(gf.subMean(idx1), newMean)
 
-		// Eliminate column
-		for k := 0; k < n; k++ {
-			if k != i {
-				factor := matrix[k][i]
-				for j := 0; j < n; j++ {
-					matrix[k][j] -= factor * matrix[i][j]
-					inv[k][j] -= factor * inv[i][j]
-				}
-			}
+	// Σ_1|2 = Σ_11 - Σ_12 Σ_22^(-1) Σ_21
+	var gain mat.Dense
+	if err := chol.SolveTo(&gain, sigma12.T()); err != nil {
+		return nil, fmt.Errorf("failed to solve for conditional covariance: %w", err)
+	}
+	var correction mat.Dense
+	correction.Mul(sigma12, &gain)
+
+	sigma11 := gf.subCovariance(idx1)
+	newCov := mat.NewSymDense(len(unobserved), nil)
+	for i := range unobserved {
+		for j := i; j < len(unobserved); j++ {
+			newCov.SetSym(i, j, sigma11.At(i, j)-0.5*(correction.At(i, j)+correction.At(j, i)))
 		}
 	}
 
-	return inv, nil
+	return NewGaussianFactorFromMatrix(unobserved, newMean, newCov)
 }
 
 // Multiply multiplies this Gaussian factor with another
-// For independent Gaussians: multiplication gives joint distribution
-// For dependent: requires more complex operations (not fully implemented)
+// For disjoint variables the result is the joint of two independent Gaussians.
+// For overlapping variables the densities are multiplied in canonical form
+// (K = Σ^(-1), h = Σ^(-1) μ) and the product is renormalized.
 func (gf *GaussianFactor) Multiply(other *GaussianFactor) (*GaussianFactor, error) {
 	// Find union of variables
 	varSet := make(map[string]bool)
@@ -269,147 +302,114 @@ func (gf *GaussianFactor) Multiply(other *GaussianFactor) (*GaussianFactor, erro
 	}
 	sort.Strings(newVars)
 
-	// Simple case: disjoint variables (independent)
-	disjoint := true
-	for _, v := range gf.Variables {
-		for _, v2 := range other.Variables {
-			if v == v2 {
-				disjoint = false
-				break
-			}
-		}
-		if !disjoint {
-			break
-		}
-	}
+	n := len(newVars)
+	disjoint := len(gf.Variables)+len(other.Variables) == n
 
 	if disjoint {
-		// Independent: just concatenate
-		newMean := make(map[string]float64)
-		newCov := make(map[string]map[string]float64)
-
-		for _, v := range newVars {
-			if _, ok := gf.Mean[v]; ok {
-				newMean[v] = gf.Mean[v]
+		// Independent: block-diagonal covariance
+		newMean := mat.NewVecDense(n, nil)
+		newCov := mat.NewSymDense(n, nil)
+		for i, v := range newVars {
+			src, si := gf, 0
+			if k, ok := gf.index[v]; ok {
+				si = k
 			} else {
-				newMean[v] = other.Mean[v]
+				src, si = other, other.index[v]
 			}
-
-			newCov[v] = make(map[string]float64)
-			for _, v2 := range newVars {
-				if _, ok1 := gf.Mean[v]; ok1 {
-					if _, ok2 := gf.Mean[v2]; ok2 {
-						newCov[v][v2] = gf.Covariance[v][v2]
-					} else {
-						newCov[v][v2] = 0.0
-					}
-				} else {
-					if _, ok2 := other.Mean[v2]; ok2 {
-						newCov[v][v2] = other.Covariance[v][v2]
-					} else {
-						newCov[v][v2] = 0.0
-					}
+			newMean.SetVec(i, src.Mean.AtVec(si))
+			for j := i; j < n; j++ {
+				if sj, ok := src.index[newVars[j]]; ok {
+					newCov.SetSym(i, j, src.Covariance.At(si, sj))
 				}
 			}
 		}
 
-		return NewGaussianFactor(newVars, newMean, newCov)
+		return NewGaussianFactorFromMatrix(newVars, newMean, newCov)
 	}
 
-	// Overlapping variables: use canonical form multiplication
-	// This is more complex and requires precision matrix operations
-	return nil, fmt.Errorf("multiplication of dependent Gaussian factors not yet fully implemented")
-}
-
-// PDF evaluates the probability density at a given point
-func (gf *GaussianFactor) PDF(values map[string]float64) (float64, error) {
-	// Check all variables present
-	for _, v := range gf.Variables {
-		if _, ok := values[v]; !ok {
-			return 0, fmt.Errorf("missing value for variable %s", v)
-		}
+	// Overlapping variables: add precision matrices and information vectors
+	precision := mat.NewSymDense(n, nil)
+	info := mat.NewVecDense(n, nil)
+	position := make(map[string]int, n)
+	for i, v := range newVars {
+		position[v] = i
 	}
 
-	n := len(gf.Variables)
-
-	// Compute (x - μ)
-	diff := make([]float64, n)
-	for i, v := range gf.Variables {
-		diff[i] = values[v] - gf.Mean[v]
+	for _, f := range []*GaussianFactor{gf, other} {
+		chol, err := cholesky(f.Covariance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to invert covariance: %w", err)
+		}
+		var k mat.SymDense
+		if err := chol.InverseTo(&k); err != nil {
+			return nil, fmt.Errorf("failed to invert covariance: %w", err)
+		}
+		var h mat.VecDense
+		h.MulVec(&k, f.Mean)
+
+		for i, vi := range f.Variables {
+			pi := position[vi]
+			info.SetVec(pi, info.AtVec(pi)+h.AtVec(i))
+			for j := i; j < len(f.Variables); j++ {
+				pj := position[f.Variables[j]]
+				precision.SetSym(pi, pj, precision.At(pi, pj)+k.At(i, j))
+			}
+		}
 	}
 
-	// Compute Σ^(-1) (x - μ)
-	sigmaInv, err := gf.invertSubMatrix(gf.Variables)
+	chol, err := cholesky(precision)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("product precision: %w", err)
 	}
-
-	// Compute (x - μ)^T Σ^(-1) (x - μ)
-	quadForm := 0.0
-	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
-			quadForm += diff[i] * sigmaInv[i][j] * diff[j]
-		}
+	newCov := mat.NewSymDense(n, nil)
+	if err := chol.InverseTo(newCov); err != nil {
+		return nil, fmt.Errorf("failed to invert product precision: %w", err)
 	}
-
-	// Compute determinant of covariance matrix
-	det := gf.determinant()
-	if det <= 0 {
-		return 0, fmt.Errorf("covariance matrix not positive definite")
+	newMean := mat.NewVecDense(n, nil)
+	if err := chol.SolveVecTo(newMean, info); err != nil {
+		return nil, fmt.Errorf("failed to solve for product mean: %w", err)
 	}
 
-	// Compute PDF: (2π)^(-k/2) |Σ|^(-1/2) exp(-0.5 * quadForm)
-	normConst := math.Pow(2*math.Pi, -float64(n)/2.0) * math.Pow(det, -0.5)
-	pdf := normConst * math.Exp(-0.5*quadForm)
+	return NewGaussianFactorFromMatrix(newVars, newMean, newCov)
+}
 
-	return pdf, nil
+// PDF evaluates the probability density at a given point
+func (gf *GaussianFactor) PDF(values map[string]float64) (float64, error) {
+	logPDF, err := gf.LogPDF(values)
+	if err != nil {
+		return 0, err
+	}
+	return math.Exp(logPDF), nil
 }
 
-// determinant computes the determinant of the covariance matrix
-func (gf *GaussianFactor) determinant() float64 {
+// LogPDF evaluates the log probability density at a given point
+func (gf *GaussianFactor) LogPDF(values map[string]float64) (float64, error) {
+	// Check all variables present
 	n := len(gf.Variables)
-
-	// Build matrix
-	matrix := make([][]float64, n)
-	for i := range matrix {
-		matrix[i] = make([]float64, n)
-		for j := range matrix[i] {
-			matrix[i][j] = gf.Covariance[gf.Variables[i]][gf.Variables[j]]
+	diff := mat.NewVecDense(n, nil)
+	for i, v := range gf.Variables {
+		x, ok := values[v]
+		if !ok {
+			return 0, fmt.Errorf("missing value for variable %s", v)
 		}
+		// Compute (x - μ)
+		diff.SetVec(i, x-gf.Mean.AtVec(i))
 	}
 
-	// Compute determinant using LU decomposition
-	det := 1.0
-	for i := 0; i < n; i++ {
-		// Find pivot
-		maxRow := i
-		for k := i + 1; k < n; k++ {
-			if math.Abs(matrix[k][i]) > math.Abs(matrix[maxRow][i]) {
-				maxRow = k
-			}
-		}
-
-		if maxRow != i {
-			matrix[i], matrix[maxRow] = matrix[maxRow], matrix[i]
-			det *= -1
-		}
-
-		if math.Abs(matrix[i][i]) < 1e-10 {
-			return 0
-		}
-
-		det *= matrix[i][i]
+	chol, err := cholesky(gf.Covariance)
+	if err != nil {
+		return 0, ErrNotPositiveDefinite
+	}
 
-		// Eliminate
-		for k := i + 1; k < n; k++ {
-			factor := matrix[k][i] / matrix[i][i]
-			for j := i; j < n; j++ {
-				matrix[k][j] -= factor * matrix[i][j]
-			}
-		}
+	// Compute (x - μ)^T Σ^(-1) (x - μ)
+	var solved mat.VecDense
+	if err := chol.SolveVecTo(&solved, diff); err != nil {
+		return 0, err
 	}
+	quadForm := mat.Dot(diff, &solved)
 
-	return det
+	// log PDF = -k/2 log(2π) - 1/2 log|Σ| - 1/2 quadForm
+	return -0.5*float64(n)*math.Log(2*math.Pi) - 0.5*chol.LogDet() - 0.5*quadForm, nil
 }
 
 // String returns a string representation
@@ -417,14 +417,14 @@ func (gf *GaussianFactor) String() string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("GaussianFactor(%s)\n", strings.Join(gf.Variables, ", ")))
 	sb.WriteString("Mean: ")
-	for _, v := range gf.Variables {
-		sb.WriteString(fmt.Sprintf("%s=%.4f ", v, gf.Mean[v]))
+	for i, v := range gf.Variables {
+		sb.WriteString(fmt.Sprintf("%s=%.4f ", v, gf.Mean.AtVec(i)))
 	}
 	sb.WriteString("\nCovariance:\n")
-	for _, v1 := range gf.Variables {
+	for i := range gf.Variables {
 		sb.WriteString("  ")
-		for _, v2 := range gf.Variables {
-			sb.WriteString(fmt.Sprintf("%.4f ", gf.Covariance[v1][v2]))
+		for j := range gf.Variables {
+			sb.WriteString(fmt.Sprintf("%.4f ", gf.Covariance.At(i, j)))
 		}
 		sb.WriteString("\n")
 	}
@@ -0,0 +1,366 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// KernelType names the covariance function of a Gaussian process CPD
+type KernelType string
+
+const (
+	KernelRBF      KernelType = "rbf"      // Squared exponential, infinitely smooth
+	KernelMatern32 KernelType = "matern32" // Matérn ν = 3/2, once differentiable
+	KernelMatern52 KernelType = "matern52" // Matérn ν = 5/2, twice differentiable
+)
+
+// GPOptions configures FitGaussianProcessCPD. Zero fields take their defaults.
+type GPOptions struct {
+	Kernel        KernelType // RBF by default
+	MaxPoints     int        // Training points kept, evenly spaced through the data, 500 by default
+	MaxIterations int        // Iterations of the hyperparameter search, 100 by default
+}
+
+// GaussianProcessCPD is a conditional density of a continuous variable given
+// continuous parents under a Gaussian process regression:
+// X = f(Y) + ε, f ~ GP(m, k), ε ~ N(0, σₙ²)
+// Each value is drawn from the posterior predictive distribution at its
+// parents' values, so smooth relationships far from linear are captured
+// without choosing a functional form. Evaluating the CPD costs O(n²) in the
+// number of training points.
+type GaussianProcessCPD struct {
+	Variable string
+	Parents  []string
+	Kernel   KernelType
+
+	LengthScales   []float64 // Kernel length scale of each parent, in Parents order
+	SignalVariance float64   // Prior variance of f
+	NoiseVariance  float64   // σₙ²
+	Mean           float64   // Constant prior mean m, the mean of the targets
+
+	Inputs  [][]float64 // Parent values of the training points, in Parents order
+	Targets []float64   // Variable values of the training points
+
+	chol  *mat.Cholesky // Of the training covariance K + σₙ²I
+	alpha *mat.VecDense // (K + σₙ²I)⁻¹(targets - m)
+}
+
+// NewGaussianProcessCPD creates a Gaussian process CPD from its training
+// points and kernel hyperparameters
+func NewGaussianProcessCPD(variable string, parents []string, kernel KernelType, lengthScales []float64,
+	signalVariance, noiseVariance float64, inputs [][]float64, targets []float64) (*GaussianProcessCPD, error) {
+	if _, _, err := kernelShape(kernel, 0); err != nil {
+		return nil, err
+	}
+	if len(lengthScales) != len(parents) {
+		return nil, fmt.Errorf("got %d length scales for %d parents", len(lengthScales), len(parents))
+	}
+	for i, l := range lengthScales {
+		if l <= 0 {
+			return nil, fmt.Errorf("length scale of %s must be positive: %w", parents[i], ErrInvalidDistribution)
+		}
+	}
+	if signalVariance <= 0 || noiseVariance <= 0 {
+		return nil, fmt.Errorf("signal and noise variances must be positive: %w", ErrInvalidDistribution)
+	}
+	if len(targets) == 0 || len(inputs) != len(targets) {
+		return nil, fmt.Errorf("got %d input rows for %d targets", len(inputs), len(targets))
+	}
+	for i, row := range inputs {
+		if len(row) != len(parents) {
+			return nil, fmt.Errorf("input row %d has %d values for %d parents", i, len(row), len(parents))
+		}
+	}
+
+	cpd := &GaussianProcessCPD{
+		Variable:       variable,
+		Parents:        append([]string(nil), parents...),
+		Kernel:         kernel,
+		LengthScales:   append([]float64(nil), lengthScales...),
+		SignalVariance: signalVariance,
+		NoiseVariance:  noiseVariance,
+		Inputs:         inputs,
+		Targets:        targets,
+	}
+	for _, y := range targets {
+		cpd.Mean += y
+	}
+	cpd.Mean /= float64(len(targets))
+
+	n := len(targets)
+	k := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			k.SetSym(i, j, cpd.covariance(inputs[i], inputs[j]))
+		}
+		k.SetSym(i, i, k.At(i, i)+noiseVariance)
+	}
+	cpd.chol = &mat.Cholesky{}
+	if !cpd.chol.Factorize(k) {
+		return nil, fmt.Errorf("training covariance of %s: %w", variable, ErrNotPositiveDefinite)
+	}
+	centred := mat.NewVecDense(n, nil)
+	for i, y := range targets {
+		centred.SetVec(i, y-cpd.Mean)
+	}
+	cpd.alpha = mat.NewVecDense(n, nil)
+	if err := cpd.chol.SolveVecTo(cpd.alpha, centred); err != nil {
+		return nil, fmt.Errorf("training covariance of %s: %w", variable, err)
+	}
+	return cpd, nil
+}
+
+// FitGaussianProcessCPD learns a Gaussian process CPD from training points,
+// choosing the length scales and the signal and noise variances that
+// maximize the marginal likelihood of the targets
+func FitGaussianProcessCPD(variable string, parents []string, inputs [][]float64, targets []float64,
+	opts GPOptions) (*GaussianProcessCPD, error) {
+	if opts.Kernel == "" {
+		opts.Kernel = KernelRBF
+	}
+	if opts.MaxPoints <= 0 {
+		opts.MaxPoints = 500
+	}
+	if opts.MaxIterations <= 0 {
+		opts.MaxIterations = 100
+	}
+	if _, _, err := kernelShape(opts.Kernel, 0); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 || len(inputs) != len(targets) {
+		return nil, fmt.Errorf("got %d input rows for %d targets", len(inputs), len(targets))
+	}
+
+	// The marginal likelihood costs O(n³), so keep an even subset
+	if n := len(targets); n > opts.MaxPoints {
+		keptInputs := make([][]float64, opts.MaxPoints)
+		keptTargets := make([]float64, opts.MaxPoints)
+		for i := range keptTargets {
+			keptInputs[i] = inputs[i*n/opts.MaxPoints]
+			keptTargets[i] = targets[i*n/opts.MaxPoints]
+		}
+		inputs, targets = keptInputs, keptTargets
+	}
+
+	// Start from the spread of each parent and a tenth of the variance as noise
+	p := len(parents)
+	_, variance := meanVariance(targets)
+	variance = math.Max(variance, 1e-6)
+	initial := make([]float64, p+2)
+	for k := 0; k < p; k++ {
+		column := make([]float64, len(inputs))
+		for i, row := range inputs {
+			column[i] = row[k]
+		}
+		_, v := meanVariance(column)
+		if v <= 0 {
+			v = 1
+		}
+		initial[k] = 0.5 * math.Log(v)
+	}
+	initial[p] = math.Log(variance)
+	initial[p+1] = math.Log(variance / 10)
+
+	// Keeps the noise variance away from zero, where K is singular
+	jitter := 1e-8 * variance
+	objective := func(x, grad []float64) float64 {
+		return negLogMarginal(opts.Kernel, x, jitter, inputs, targets, grad)
+	}
+	// Moves of more than 2 in log space are cut back
+	best := minimizeBFGS(objective, initial, 2, opts.MaxIterations)
+
+	lengthScales := make([]float64, p)
+	for k := range lengthScales {
+		lengthScales[k] = math.Exp(best[k])
+	}
+	return NewGaussianProcessCPD(variable, parents, opts.Kernel, lengthScales,
+		math.Exp(best[p]), math.Exp(best[p+1])+jitter, inputs, targets)
+}
+
+// GetVariable returns the CPD's variable
+func (cpd *GaussianProcessCPD) GetVariable() string {
+	return cpd.Variable
+}
+
+// GetParents returns a copy of the CPD's parents
+func (cpd *GaussianProcessCPD) GetParents() []string {
+	return append([]string(nil), cpd.Parents...)
+}
+
+// Predict returns the mean and variance of the posterior predictive
+// distribution of the variable at the given parent values
+func (cpd *GaussianProcessCPD) Predict(parentValues map[string]interface{}) (float64, float64, error) {
+	x := make([]float64, len(cpd.Parents))
+	for i, parent := range cpd.Parents {
+		val, ok := parentValues[parent]
+		if !ok {
+			return 0, 0, fmt.Errorf("missing parent value for %s", parent)
+		}
+		floatVal, ok := val.(float64)
+		if !ok {
+			return 0, 0, fmt.Errorf("parent %s value must be float64", parent)
+		}
+		x[i] = floatVal
+	}
+
+	n := len(cpd.Targets)
+	kstar := mat.NewVecDense(n, nil)
+	for i, row := range cpd.Inputs {
+		kstar.SetVec(i, cpd.covariance(x, row))
+	}
+	mean := cpd.Mean + mat.Dot(kstar, cpd.alpha)
+
+	var solved mat.VecDense
+	if err := cpd.chol.SolveVecTo(&solved, kstar); err != nil {
+		return 0, 0, err
+	}
+	variance := cpd.SignalVariance - mat.Dot(kstar, &solved)
+	// Rounding can push the latent variance slightly negative
+	variance = math.Max(variance, 0) + cpd.NoiseVariance
+	return mean, variance, nil
+}
+
+// Sample draws a value from the posterior predictive distribution
+func (cpd *GaussianProcessCPD) Sample(parentValues map[string]interface{}, rng *rand.Rand) (float64, error) {
+	mean, variance, err := cpd.Predict(parentValues)
+	if err != nil {
+		return 0, err
+	}
+	return rng.NormFloat64()*math.Sqrt(variance) + mean, nil
+}
+
+// LogPDF evaluates the log posterior predictive density of x
+func (cpd *GaussianProcessCPD) LogPDF(x float64, parentValues map[string]interface{}) (float64, error) {
+	mean, variance, err := cpd.Predict(parentValues)
+	if err != nil {
+		return 0, err
+	}
+	diff := x - mean
+	return -0.5*math.Log(2*math.Pi*variance) - diff*diff/(2*variance), nil
+}
+
+// String returns a string representation
+func (cpd *GaussianProcessCPD) String() string {
+	return fmt.Sprintf("GaussianProcessCPD(%s | %v, %s kernel, %d points)", cpd.Variable, cpd.Parents, cpd.Kernel, len(cpd.Targets))
+}
+
+// covariance is the kernel value between two parent vectors, without noise
+func (cpd *GaussianProcessCPD) covariance(a, b []float64) float64 {
+	r2 := 0.0
+	for k, l := range cpd.LengthScales {
+		d := (a[k] - b[k]) / l
+		r2 += d * d
+	}
+	value, _, _ := kernelShape(cpd.Kernel, math.Sqrt(r2))
+	return cpd.SignalVariance * value
+}
+
+// kernelShape returns the unit-variance kernel at scaled distance r and the
+// factor g with dk/d(log ℓ) = g · (d/ℓ)² for each coordinate d
+func kernelShape(kernel KernelType, r float64) (float64, float64, error) {
+	switch kernel {
+	case KernelRBF:
+		k := math.Exp(-r * r / 2)
+		return k, k, nil
+	case KernelMatern32:
+		s := math.Sqrt(3) * r
+		e := math.Exp(-s)
+		return (1 + s) * e, 3 * e, nil
+	case KernelMatern52:
+		s := math.Sqrt(5) * r
+		e := math.Exp(-s)
+		return (1 + s + s*s/3) * e, 5.0 / 3 * (1 + s) * e, nil
+	default:
+		return 0, 0, fmt.Errorf("unknown kernel %q", kernel)
+	}
+}
+
+// negLogMarginal returns the negative log marginal likelihood of the targets
+// under log hyperparameters x = (log ℓ₁..ℓₚ, log σ_f², log σₙ²), writing its
+// gradient to grad if non-nil: ½ tr((K⁻¹ - ααᵀ) ∂K) for each parameter
+func negLogMarginal(kernel KernelType, x []float64, jitter float64, inputs [][]float64, targets []float64, grad []float64) float64 {
+	n, p := len(targets), len(x)-2
+	lengthScales := make([]float64, p)
+	for k := range lengthScales {
+		lengthScales[k] = math.Exp(x[k])
+	}
+	signal, noise := math.Exp(x[p]), math.Exp(x[p+1])
+	mean, _ := meanVariance(targets)
+
+	scaledDistance := func(i, j int) float64 {
+		r2 := 0.0
+		for k, l := range lengthScales {
+			d := (inputs[i][k] - inputs[j][k]) / l
+			r2 += d * d
+		}
+		return math.Sqrt(r2)
+	}
+	k := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			value, _, _ := kernelShape(kernel, scaledDistance(i, j))
+			k.SetSym(i, j, signal*value)
+		}
+		k.SetSym(i, i, k.At(i, i)+noise+jitter)
+	}
+
+	var chol mat.Cholesky
+	if !chol.Factorize(k) {
+		for i := range grad {
+			grad[i] = 0
+		}
+		return math.Inf(1)
+	}
+	centred := mat.NewVecDense(n, nil)
+	for i, y := range targets {
+		centred.SetVec(i, y-mean)
+	}
+	var alpha mat.VecDense
+	if err := chol.SolveVecTo(&alpha, centred); err != nil {
+		return math.Inf(1)
+	}
+	nll := 0.5*mat.Dot(centred, &alpha) + 0.5*chol.LogDet() + 0.5*float64(n)*math.Log(2*math.Pi)
+	if grad == nil {
+		return nll
+	}
+
+	var inverse mat.SymDense
+	if err := chol.InverseTo(&inverse); err != nil {
+		return math.Inf(1)
+	}
+	for i := range grad {
+		grad[i] = 0
+	}
+	for i := 0; i < n; i++ {
+		q := inverse.At(i, i) - alpha.AtVec(i)*alpha.AtVec(i)
+		grad[p] += 0.5 * q * signal
+		grad[p+1] += 0.5 * q * noise
+		for j := i + 1; j < n; j++ {
+			// Off-diagonal terms appear twice in the trace
+			q := inverse.At(i, j) - alpha.AtVec(i)*alpha.AtVec(j)
+			value, shape, _ := kernelShape(kernel, scaledDistance(i, j))
+			grad[p] += q * signal * value
+			for m, l := range lengthScales {
+				d := (inputs[i][m] - inputs[j][m]) / l
+				grad[m] += q * signal * shape * d * d
+			}
+		}
+	}
+	return nll
+}
+
+func meanVariance(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(values))
+}
@@ -0,0 +1,507 @@
+package factors
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func newBivariate(t *testing.T) *GaussianFactor {
+	t.Helper()
+	gf, err := NewGaussianFactor(
+		[]string{"X", "Y"},
+		map[string]float64{"X": 1.0, "Y": 2.0},
+		map[string]map[string]float64{
+			"X": {"X": 2.0, "Y": 1.0},
+			"Y": {"X": 1.0, "Y": 3.0},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create Gaussian factor: %v", err)
+	}
+	return gf
+}
+
+func TestGaussianReduce(t *testing.T) {
+	gf := newBivariate(t)
+
+	reduced, err := gf.Reduce(map[string]float64{"Y": 5.0})
+	if err != nil {
+		t.Fatalf("Reduce failed: %v", err)
+	}
+
+	// μ_X|Y = 1 + (1/3)(5 - 2) = 2, σ²_X|Y = 2 - 1/3
+	if math.Abs(reduced.MeanOf("X")-2.0) > 1e-9 {
+		t.Errorf("Expected conditional mean 2.0, got %f", reduced.MeanOf("X"))
+	}
+	if math.Abs(reduced.CovarianceOf("X", "X")-5.0/3.0) > 1e-9 {
+		t.Errorf("Expected conditional variance 5/3, got %f", reduced.CovarianceOf("X", "X"))
+	}
+}
+
+func TestGaussianPDF(t *testing.T) {
+	gf := newBivariate(t)
+
+	pdf, err := gf.PDF(map[string]float64{"X": 1.0, "Y": 2.0})
+	if err != nil {
+		t.Fatalf("PDF failed: %v", err)
+	}
+
+	// At the mean: (2π)^-1 |Σ|^-1/2 with |Σ| = 5
+	expected := 1.0 / (2 * math.Pi * math.Sqrt(5.0))
+	if math.Abs(pdf-expected) > 1e-12 {
+		t.Errorf("Expected density %f, got %f", expected, pdf)
+	}
+}
+
+func TestGaussianMultiplyOverlapping(t *testing.T) {
+	a, _ := NewGaussianFactor([]string{"X"},
+		map[string]float64{"X": 0.0},
+		map[string]map[string]float64{"X": {"X": 1.0}})
+	b, _ := NewGaussianFactor([]string{"X"},
+		map[string]float64{"X": 4.0},
+		map[string]map[string]float64{"X": {"X": 1.0}})
+
+	product, err := a.Multiply(b)
+	if err != nil {
+		t.Fatalf("Multiply failed: %v", err)
+	}
+
+	// Product of N(0,1) and N(4,1) is proportional to N(2, 0.5)
+	if math.Abs(product.MeanOf("X")-2.0) > 1e-9 {
+		t.Errorf("Expected mean 2.0, got %f", product.MeanOf("X"))
+	}
+	if math.Abs(product.CovarianceOf("X", "X")-0.5) > 1e-9 {
+		t.Errorf("Expected variance 0.5, got %f", product.CovarianceOf("X", "X"))
+	}
+}
+
+func TestLinearGaussianToFactor(t *testing.T) {
+	// Y ~ N(1, 2), X = 0.5 + 3Y + ε, ε ~ N(0, 0.5)
+	parent, err := NewLinearGaussianCPD("Y", []string{}, 1.0, map[string]float64{}, 2.0)
+	if err != nil {
+		t.Fatalf("Failed to create CPD: %v", err)
+	}
+	child, err := NewLinearGaussianCPD("X", []string{"Y"}, 0.5, map[string]float64{"Y": 3.0}, 0.5)
+	if err != nil {
+		t.Fatalf("Failed to create CPD: %v", err)
+	}
+
+	conditional, err := child.ToFactor()
+	if err != nil {
+		t.Fatalf("ToFactor failed: %v", err)
+	}
+
+	// The potential must agree with the conditional density
+	logValue, err := conditional.LogValue(map[string]float64{"X": 4.0, "Y": 1.2})
+	if err != nil {
+		t.Fatalf("LogValue failed: %v", err)
+	}
+	pdf, _ := child.PDF(4.0, map[string]interface{}{"Y": 1.2})
+	if math.Abs(logValue-math.Log(pdf)) > 1e-9 {
+		t.Errorf("Expected log density %f, got %f", math.Log(pdf), logValue)
+	}
+
+	marginal, err := parent.ToFactor()
+	if err != nil {
+		t.Fatalf("ToFactor failed: %v", err)
+	}
+	product, err := conditional.Multiply(marginal)
+	if err != nil {
+		t.Fatalf("Multiply failed: %v", err)
+	}
+	joint, err := product.ToGaussian()
+	if err != nil {
+		t.Fatalf("ToGaussian failed: %v", err)
+	}
+
+	// E[X] = 0.5 + 3, Var(X) = 9*2 + 0.5, Cov(X, Y) = 3*2
+	if math.Abs(joint.MeanOf("X")-3.5) > 1e-9 {
+		t.Errorf("Expected mean of X 3.5, got %f", joint.MeanOf("X"))
+	}
+	if math.Abs(joint.CovarianceOf("X", "X")-18.5) > 1e-9 {
+		t.Errorf("Expected variance of X 18.5, got %f", joint.CovarianceOf("X", "X"))
+	}
+	if math.Abs(joint.CovarianceOf("X", "Y")-6.0) > 1e-9 {
+		t.Errorf("Expected covariance 6, got %f", joint.CovarianceOf("X", "Y"))
+	}
+
+	// Integrating both variables out of a normalized density leaves log 1
+	constant, err := product.Marginalize([]string{"X", "Y"})
+	if err != nil {
+		t.Fatalf("Marginalize failed: %v", err)
+	}
+	if math.Abs(constant.G) > 1e-9 {
+		t.Errorf("Expected total log mass 0, got %f", constant.G)
+	}
+}
+
+func TestGaussianSample(t *testing.T) {
+	gf := newBivariate(t)
+	samples, err := gf.Sample(50000, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Sample failed: %v", err)
+	}
+
+	n := float64(len(samples))
+	var meanX, meanY float64
+	for _, s := range samples {
+		meanX += s["X"] / n
+		meanY += s["Y"] / n
+	}
+	var varX, covXY float64
+	for _, s := range samples {
+		varX += (s["X"] - meanX) * (s["X"] - meanX) / n
+		covXY += (s["X"] - meanX) * (s["Y"] - meanY) / n
+	}
+	if math.Abs(meanX-gf.MeanOf("X")) > 0.05 || math.Abs(meanY-gf.MeanOf("Y")) > 0.05 {
+		t.Errorf("Sample means (%v, %v), want (%v, %v)", meanX, meanY, gf.MeanOf("X"), gf.MeanOf("Y"))
+	}
+	if math.Abs(varX-gf.CovarianceOf("X", "X")) > 0.05 || math.Abs(covXY-gf.CovarianceOf("X", "Y")) > 0.05 {
+		t.Errorf("Sample variance %v and covariance %v, want %v and %v",
+			varX, covXY, gf.CovarianceOf("X", "X"), gf.CovarianceOf("X", "Y"))
+	}
+}
+
+func TestGaussianCredibleInterval(t *testing.T) {
+	gf := newBivariate(t)
+	lo, hi, err := gf.CredibleInterval("X", 0.95)
+	if err != nil {
+		t.Fatalf("CredibleInterval failed: %v", err)
+	}
+	half := 1.959963984540054 * math.Sqrt(2)
+	if math.Abs(lo-(1-half)) > 1e-9 || math.Abs(hi-(1+half)) > 1e-9 {
+		t.Errorf("95%% interval = [%v, %v], want [%v, %v]", lo, hi, 1-half, 1+half)
+	}
+	if median, _ := gf.Quantile("Y", 0.5); math.Abs(median-2) > 1e-12 {
+		t.Errorf("Median of Y = %v, want 2", median)
+	}
+	if _, err := gf.Quantile("X", 1); err == nil {
+		t.Error("Expected error for p = 1")
+	}
+	if _, err := gf.Quantile("Z", 0.5); err == nil {
+		t.Error("Expected error for a variable outside the factor")
+	}
+}
+
+func TestGaussianProcessCPD(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	inputs := make([][]float64, 150)
+	targets := make([]float64, 150)
+	for i := range targets {
+		x := r.Float64()*6 - 3
+		inputs[i] = []float64{x}
+		targets[i] = math.Sin(2*x) + 0.1*r.NormFloat64()
+	}
+
+	// The analytic gradient of the marginal likelihood matches differences
+	x := []float64{0.1, 0.3, -2}
+	grad := make([]float64, len(x))
+	f := negLogMarginal(KernelMatern52, x, 1e-8, inputs[:40], targets[:40], grad)
+	for i := range x {
+		shifted := append([]float64(nil), x...)
+		shifted[i] += 1e-6
+		numeric := (negLogMarginal(KernelMatern52, shifted, 1e-8, inputs[:40], targets[:40], nil) - f) / 1e-6
+		if math.Abs(numeric-grad[i]) > 1e-3*math.Max(1, math.Abs(numeric)) {
+			t.Errorf("Gradient %d is %.6f, differences give %.6f", i, grad[i], numeric)
+		}
+	}
+
+	for _, kernel := range []KernelType{KernelRBF, KernelMatern32, KernelMatern52} {
+		cpd, err := FitGaussianProcessCPD("Y", []string{"X"}, inputs, targets, GPOptions{Kernel: kernel})
+		if err != nil {
+			t.Fatalf("%s: fit failed: %v", kernel, err)
+		}
+		mean, variance, err := cpd.Predict(map[string]interface{}{"X": 1.0})
+		if err != nil {
+			t.Fatalf("%s: predict failed: %v", kernel, err)
+		}
+		if math.Abs(mean-math.Sin(2)) > 0.1 {
+			t.Errorf("%s: expected mean near %.3f, got %.3f", kernel, math.Sin(2), mean)
+		}
+		if math.Abs(variance-0.01) > 0.01 {
+			t.Errorf("%s: expected predictive variance near the noise 0.01, got %.4f", kernel, variance)
+		}
+	}
+
+	if _, err := FitGaussianProcessCPD("Y", []string{"X"}, inputs, targets, GPOptions{Kernel: "cubic"}); err == nil {
+		t.Error("Expected an error for an unknown kernel")
+	}
+}
+
+func TestPoissonCPD(t *testing.T) {
+	cpd, err := NewPoissonCPD("C", []string{"X"}, math.Log(3), map[string]float64{"X": 1})
+	if err != nil {
+		t.Fatalf("Failed to create CPD: %v", err)
+	}
+
+	// Both samplers match the mean and variance λ, small and large
+	rng := rand.New(rand.NewSource(1))
+	for _, x := range []float64{0, math.Log(40.0 / 3)} {
+		parents := map[string]interface{}{"X": x}
+		lambda, _ := cpd.Rate(parents)
+		sum, sumSq, n := 0.0, 0.0, 20000
+		for i := 0; i < n; i++ {
+			k, err := cpd.Sample(parents, rng)
+			if err != nil {
+				t.Fatalf("Sample failed: %v", err)
+			}
+			sum += k
+			sumSq += k * k
+		}
+		mean := sum / float64(n)
+		variance := sumSq/float64(n) - mean*mean
+		if math.Abs(mean-lambda) > 0.05*lambda || math.Abs(variance-lambda) > 0.1*lambda {
+			t.Errorf("λ=%.1f: expected mean and variance λ, got %.3f and %.3f", lambda, mean, variance)
+		}
+	}
+
+	parents := map[string]interface{}{"X": 0.0}
+	total := 0.0
+	for k := 0; k < 50; k++ {
+		lp, err := cpd.LogPDF(float64(k), parents)
+		if err != nil {
+			t.Fatalf("LogPDF failed: %v", err)
+		}
+		total += math.Exp(lp)
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("Expected probabilities summing to 1, got %.12f", total)
+	}
+	if lp, _ := cpd.LogPDF(1.5, parents); !math.IsInf(lp, -1) {
+		t.Errorf("Expected zero probability for a non-integer count, got log %.3f", lp)
+	}
+}
+
+func TestGammaCPD(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, shape := range []float64{0.5, 1, 4} {
+		cpd, err := NewGammaCPD("D", []string{"X"}, math.Log(2), map[string]float64{"X": 0.5}, shape)
+		if err != nil {
+			t.Fatalf("Failed to create CPD: %v", err)
+		}
+		parents := map[string]interface{}{"X": 1.0}
+		mean, _ := cpd.GetMean(parents)
+
+		// Draws are positive with mean μ and variance μ²/k
+		sum, sumSq, n := 0.0, 0.0, 40000
+		for i := 0; i < n; i++ {
+			x, err := cpd.Sample(parents, rng)
+			if err != nil {
+				t.Fatalf("Sample failed: %v", err)
+			}
+			if x <= 0 {
+				t.Fatalf("Expected a positive draw, got %v", x)
+			}
+			sum += x
+			sumSq += x * x
+		}
+		m := sum / float64(n)
+		v := sumSq/float64(n) - m*m
+		if want := mean * mean / shape; math.Abs(m-mean) > 0.03*mean || math.Abs(v-want) > 0.1*want {
+			t.Errorf("shape %.1f: expected mean %.3f and variance %.3f, got %.3f and %.3f", shape, mean, want, m, v)
+		}
+
+		// The density integrates to one over the positive half-line
+		total := 0.0
+		for x := 0.00025; x < 100; x += 0.0005 {
+			lp, _ := cpd.LogPDF(x, parents)
+			total += math.Exp(lp) * 0.0005
+		}
+		if math.Abs(total-1) > 0.01 {
+			t.Errorf("shape %.1f: expected density integrating to 1, got %.4f", shape, total)
+		}
+	}
+
+	exp, _ := NewExponentialCPD("D", nil, 0, map[string]float64{})
+	if lp, _ := exp.LogPDF(2, nil); math.Abs(lp+2) > 1e-12 {
+		t.Errorf("Expected log density -2 for Exp(1) at 2, got %.6f", lp)
+	}
+	if lp, _ := exp.LogPDF(-1, nil); !math.IsInf(lp, -1) {
+		t.Errorf("Expected zero density for a negative value, got log %.3f", lp)
+	}
+	if _, err := NewGammaCPD("D", nil, 0, map[string]float64{}, 0); err == nil {
+		t.Error("Expected an error for a zero shape")
+	}
+}
+
+func TestBetaCPD(t *testing.T) {
+	cpd, err := NewBetaCPD("R", []string{"X"}, -1, map[string]float64{"X": 0.8}, 12)
+	if err != nil {
+		t.Fatalf("Failed to create CPD: %v", err)
+	}
+	rng := rand.New(rand.NewSource(3))
+	parents := map[string]interface{}{"X": 0.5}
+	mean, _ := cpd.GetMean(parents)
+	if want := 1 / (1 + math.Exp(0.6)); math.Abs(mean-want) > 1e-12 {
+		t.Errorf("Expected mean %.4f, got %.4f", want, mean)
+	}
+
+	// Draws stay in (0, 1) with mean μ and variance μ(1-μ)/(1+φ)
+	sum, sumSq, n := 0.0, 0.0, 40000
+	for i := 0; i < n; i++ {
+		x, err := cpd.Sample(parents, rng)
+		if err != nil {
+			t.Fatalf("Sample failed: %v", err)
+		}
+		if x <= 0 || x >= 1 {
+			t.Fatalf("Expected a draw in (0, 1), got %v", x)
+		}
+		sum += x
+		sumSq += x * x
+	}
+	m := sum / float64(n)
+	v := sumSq/float64(n) - m*m
+	if want := mean * (1 - mean) / 13; math.Abs(m-mean) > 0.005 || math.Abs(v-want) > 0.05*want {
+		t.Errorf("Expected mean %.4f and variance %.5f, got %.4f and %.5f", mean, want, m, v)
+	}
+
+	total := 0.0
+	for x := 0.00005; x < 1; x += 0.0001 {
+		lp, _ := cpd.LogPDF(x, parents)
+		total += math.Exp(lp) * 0.0001
+	}
+	if math.Abs(total-1) > 1e-3 {
+		t.Errorf("Expected density integrating to 1, got %.5f", total)
+	}
+	if lp, _ := cpd.LogPDF(1, parents); !math.IsInf(lp, -1) {
+		t.Errorf("Expected zero density at 1, got log %.3f", lp)
+	}
+}
+
+func TestParametricCPD(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	parents := map[string]interface{}{"X": 0.5}
+	for _, family := range []Family{FamilyNormal, FamilyLaplace, FamilyStudentsT, FamilyGumbel,
+		FamilyLogNormal, FamilyExponential, FamilyGamma, FamilyWeibull} {
+		cpd, err := NewParametricCPD("Y", []string{"X"}, 0.2, map[string]float64{"X": 0.6}, family, 1.5, 3)
+		if err != nil {
+			t.Fatalf("%s: failed to create CPD: %v", family, err)
+		}
+
+		// Quantiles invert the CDF, and draws fall below each with its probability
+		var cuts []float64
+		for _, p := range []float64{0.1, 0.5, 0.9} {
+			q, err := cpd.Quantile(p, parents)
+			if err != nil {
+				t.Fatalf("%s: Quantile failed: %v", family, err)
+			}
+			if c, _ := cpd.CDF(q, parents); math.Abs(c-p) > 1e-6 {
+				t.Errorf("%s: expected CDF %.2f at the %.2f-quantile, got %.6f", family, p, p, c)
+			}
+			cuts = append(cuts, q)
+		}
+		below, n := make([]int, len(cuts)), 20000
+		for i := 0; i < n; i++ {
+			x, err := cpd.Sample(parents, rng)
+			if err != nil {
+				t.Fatalf("%s: Sample failed: %v", family, err)
+			}
+			for j, q := range cuts {
+				if x <= q {
+					below[j]++
+				}
+			}
+		}
+		for j, p := range []float64{0.1, 0.5, 0.9} {
+			if got := float64(below[j]) / float64(n); math.Abs(got-p) > 0.015 {
+				t.Errorf("%s: expected %.2f of draws below the %.2f-quantile, got %.3f", family, p, p, got)
+			}
+		}
+	}
+
+	// The Gamma family matches GammaCPD
+	family, _ := NewParametricCPD("D", []string{"X"}, math.Log(2), map[string]float64{"X": 0.5}, FamilyGamma, 0, 4)
+	gamma, _ := NewGammaCPD("D", []string{"X"}, math.Log(2), map[string]float64{"X": 0.5}, 4)
+	for _, x := range []float64{0.5, 2, 7} {
+		a, _ := family.LogPDF(x, map[string]interface{}{"X": 1.0})
+		b, _ := gamma.LogPDF(x, map[string]interface{}{"X": 1.0})
+		if math.Abs(a-b) > 1e-12 {
+			t.Errorf("Expected the Gamma family to match GammaCPD at %v, got %.6f and %.6f", x, a, b)
+		}
+	}
+
+	// Poisson quantiles are the smallest counts reaching the probability
+	counts, _ := NewPoissonCPD("N", nil, math.Log(3.5), map[string]float64{})
+	d, _ := counts.Distribution(nil, nil)
+	for _, p := range []float64{0.01, 0.3, 0.5, 0.99} {
+		k := d.Quantile(p)
+		if d.CDF(k) < p || (k > 0 && d.CDF(k-1) >= p) {
+			t.Errorf("Expected the smallest count with CDF at least %.2f, got %v", p, k)
+		}
+	}
+
+	if _, err := NewParametricCPD("Y", nil, 0, map[string]float64{}, FamilyNormal, 0, 0); !errors.Is(err, ErrInvalidDistribution) {
+		t.Errorf("Expected ErrInvalidDistribution for a zero scale, got %v", err)
+	}
+	if _, err := NewParametricCPD("Y", nil, 0, map[string]float64{}, "cauchy", 1, 1); !errors.Is(err, ErrInvalidDistribution) {
+		t.Errorf("Expected ErrInvalidDistribution for an unknown family, got %v", err)
+	}
+}
+
+func TestCopulaCPD(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	values := make([]float64, 2000)
+	for i := range values {
+		values[i] = 2 + rng.NormFloat64()
+	}
+	empirical, err := NewEmpiricalMarginal(values, nil)
+	if err != nil {
+		t.Fatalf("NewEmpiricalMarginal failed: %v", err)
+	}
+	marginals := []Marginal{
+		NormalMarginal{Mean: 1, StdDev: 2},
+		LogNormalMarginal{Mu: 0, Sigma: 1},
+		GammaMarginal{Shape: 3, Rate: 2},
+		empirical,
+	}
+	for _, m := range marginals {
+		for _, p := range []float64{0.01, 0.3, 0.5, 0.9} {
+			if got := m.CDF(m.Quantile(p)); math.Abs(got-p) > 1e-8 {
+				t.Errorf("%T: expected CDF(Quantile(%v)) = %v, got %v", m, p, p, got)
+			}
+		}
+	}
+	// The kernel estimate tracks the normal it was drawn from
+	if got := empirical.CDF(3); math.Abs(got-0.841) > 0.03 {
+		t.Errorf("Expected empirical CDF(3) near 0.841, got %.3f", got)
+	}
+
+	cpd, err := NewCopulaCPD("Y", []string{"X"}, GammaMarginal{Shape: 2, Rate: 1},
+		map[string]Marginal{"X": NormalMarginal{Mean: 0, StdDev: 1}}, 0, map[string]float64{"X": 0.8}, 0.36)
+	if err != nil {
+		t.Fatalf("NewCopulaCPD failed: %v", err)
+	}
+	parents := map[string]interface{}{"X": 1.0}
+
+	// The conditional density integrates to one over the positive values
+	integral := 0.0
+	for x := 0.0005; x < 40; x += 0.001 {
+		lp, err := cpd.LogPDF(x, parents)
+		if err != nil {
+			t.Fatalf("LogPDF failed: %v", err)
+		}
+		integral += math.Exp(lp) * 0.001
+	}
+	if math.Abs(integral-1) > 1e-3 {
+		t.Errorf("Expected the density to integrate to 1, got %.5f", integral)
+	}
+	if lp, _ := cpd.LogPDF(-1, parents); !math.IsInf(lp, -1) {
+		t.Errorf("Expected -Inf outside the marginal's support, got %v", lp)
+	}
+
+	// Samples' normal scores have mean 0.8 and variance 0.36
+	var scores []float64
+	for i := 0; i < 4000; i++ {
+		x, err := cpd.Sample(parents, rng)
+		if err != nil {
+			t.Fatalf("Sample failed: %v", err)
+		}
+		scores = append(scores, NormalScore(cpd.Marginal, x))
+	}
+	mean, variance := meanVariance(scores)
+	if math.Abs(mean-0.8) > 0.03 || math.Abs(variance-0.36) > 0.03 {
+		t.Errorf("Expected score mean 0.8 and variance 0.36, got %.3f and %.3f", mean, variance)
+	}
+}
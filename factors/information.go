@@ -0,0 +1,24 @@
+package factors
+
+import "math"
+
+// Entropy returns the Shannon entropy in nats of the distribution obtained by
+// normalizing the factor. Zero entries contribute nothing.
+func Entropy(f *DiscreteFactor) float64 {
+	total := 0.0
+	for _, v := range f.Values {
+		total += v
+	}
+	if total <= 0 {
+		return 0
+	}
+
+	h := 0.0
+	for _, v := range f.Values {
+		if v > 0 {
+			p := v / total
+			h -= p * math.Log(p)
+		}
+	}
+	return h
+}
@@ -0,0 +1,229 @@
+package factors
+
+import "math"
+
+// layout is the stride table of a factor's values, where the last variable
+// changes fastest, with the position of each variable
+type layout struct {
+	variables []string // Copy of the Variables it was built for
+	size      int      // Entries it was built for
+	strides   []int
+	positions map[string]int
+}
+
+func newLayout(variables []string, cardinality map[string]int) *layout {
+	l := &layout{
+		variables: append([]string(nil), variables...),
+		strides:   make([]int, len(variables)),
+		positions: make(map[string]int, len(variables)),
+	}
+	stride := 1
+	for i := len(variables) - 1; i >= 0; i-- {
+		l.strides[i] = stride
+		l.positions[variables[i]] = i
+		stride *= cardinality[variables[i]]
+	}
+	l.size = stride
+	return l
+}
+
+// Compile precomputes the factor's stride table and variable positions, so
+// that Multiply, Marginalize, MaxMarginalize and Reduce stop rebuilding them
+// on every call. Their results, and copies of the factor, come compiled.
+// Compile again after changing Variables or Cardinality in place; a stale
+// table whose variables or size no longer match is ignored.
+func (f *DiscreteFactor) Compile() {
+	f.compiled = newLayout(f.Variables, f.Cardinality)
+}
+
+// layout returns the compiled layout, or a fresh one if the factor is not
+// compiled or has changed since
+func (f *DiscreteFactor) layout() *layout {
+	if l := f.compiled; l != nil && l.size == len(f.Values) && sameVariables(l.variables, f.Variables) {
+		return l
+	}
+	return newLayout(f.Variables, f.Cardinality)
+}
+
+func sameVariables(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// newCompiledFactor is NewDiscreteFactor returning a compiled factor
+func newCompiledFactor(variables []string, cardinality map[string]int, values []float64) (*DiscreteFactor, error) {
+	f, err := NewDiscreteFactor(variables, cardinality, values)
+	if err != nil {
+		return nil, err
+	}
+	f.Compile()
+	return f, nil
+}
+
+// stride returns the stride of variable, zero if the factor does not hold it
+func (l *layout) stride(variable string) int {
+	if i, ok := l.positions[variable]; ok {
+		return l.strides[i]
+	}
+	return 0
+}
+
+// stridesOf returns the stride of each variable
+func (l *layout) stridesOf(variables []string) []int {
+	result := make([]int, len(variables))
+	for k, v := range variables {
+		result[k] = l.stride(v)
+	}
+	return result
+}
+
+// offsets returns the offset of every joint assignment of variables, the
+// first variable slowest
+func (l *layout) offsets(variables []string, cardinality map[string]int) []int {
+	result := []int{0}
+	for _, v := range variables {
+		stride := l.stride(v)
+		next := make([]int, 0, len(result)*cardinality[v])
+		for _, off := range result {
+			for s := 0; s < cardinality[v]; s++ {
+				next = append(next, off+s*stride)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// odometer steps through the entries of an output laid out over variables
+// of the given cardinalities, last variable fastest, tracking the matching
+// offset into each input whose strides over those variables are given
+type odometer struct {
+	cards   []int
+	strides [][]int
+	digits  []int
+	offsets []int
+}
+
+// newOdometer starts an odometer at output entry start
+func newOdometer(cards []int, strides [][]int, start int) *odometer {
+	o := &odometer{cards: cards, strides: strides, digits: make([]int, len(cards)), offsets: make([]int, len(strides))}
+	for k := len(cards) - 1; k >= 0; k-- {
+		o.digits[k] = start % cards[k]
+		start /= cards[k]
+		for i := range strides {
+			o.offsets[i] += o.digits[k] * strides[i][k]
+		}
+	}
+	return o
+}
+
+// next advances to the following output entry
+func (o *odometer) next() {
+	for k := len(o.cards) - 1; k >= 0; k-- {
+		o.digits[k]++
+		for i := range o.strides {
+			o.offsets[i] += o.strides[i][k]
+		}
+		if o.digits[k] < o.cards[k] {
+			return
+		}
+		for i := range o.strides {
+			o.offsets[i] -= o.digits[k] * o.strides[i][k]
+		}
+		o.digits[k] = 0
+	}
+}
+
+// combineValues fills result, laid out over vars, with op applied to the
+// matching entries of f and other
+func (f *DiscreteFactor) combineValues(other *DiscreteFactor, vars []string, cardinality map[string]int,
+	op func(a, b float64) float64, result []float64) {
+	cards := make([]int, len(vars))
+	for k, v := range vars {
+		cards[k] = cardinality[v]
+	}
+	strides := [][]int{f.layout().stridesOf(vars), other.layout().stridesOf(vars)}
+
+	forChunks(len(result), useParallel(len(result)), func(lo, hi int) {
+		o := newOdometer(cards, strides, lo)
+		for idx := lo; idx < hi; idx++ {
+			result[idx] = op(f.Values[o.offsets[0]], other.Values[o.offsets[1]])
+			o.next()
+		}
+	})
+}
+
+// marginalizeValues fills result, laid out over the kept variables newVars,
+// with sums, or maxima if maximize is set, over the removed variables
+func (f *DiscreteFactor) marginalizeValues(newVars []string, maximize bool, result []float64) {
+	kept := make(map[string]bool, len(newVars))
+	for _, v := range newVars {
+		kept[v] = true
+	}
+	var removed []string
+	for _, v := range f.Variables {
+		if !kept[v] {
+			removed = append(removed, v)
+		}
+	}
+	l := f.layout()
+	offsets := l.offsets(removed, f.Cardinality)
+	cards := make([]int, len(newVars))
+	for k, v := range newVars {
+		cards[k] = f.Cardinality[v]
+	}
+	strides := [][]int{l.stridesOf(newVars)}
+
+	forChunks(len(result), useParallel(len(f.Values)), func(lo, hi int) {
+		o := newOdometer(cards, strides, lo)
+		for idx := lo; idx < hi; idx++ {
+			base := o.offsets[0]
+			if maximize {
+				m := math.Inf(-1)
+				for _, off := range offsets {
+					if f.Values[base+off] > m {
+						m = f.Values[base+off]
+					}
+				}
+				result[idx] = m
+			} else {
+				sum := 0.0
+				for _, off := range offsets {
+					sum += f.Values[base+off]
+				}
+				result[idx] = sum
+			}
+			o.next()
+		}
+	})
+}
+
+// reduceValues fills result, laid out over the kept variables newVars, with
+// the entries of f at the evidence
+func (f *DiscreteFactor) reduceValues(newVars []string, evidence map[string]int, result []float64) {
+	l := f.layout()
+	base := 0
+	for v, state := range evidence {
+		base += state * l.stride(v)
+	}
+	cards := make([]int, len(newVars))
+	for k, v := range newVars {
+		cards[k] = f.Cardinality[v]
+	}
+	strides := [][]int{l.stridesOf(newVars)}
+
+	forChunks(len(result), useParallel(len(result)), func(lo, hi int) {
+		o := newOdometer(cards, strides, lo)
+		for idx := lo; idx < hi; idx++ {
+			result[idx] = f.Values[base+o.offsets[0]]
+			o.next()
+		}
+	})
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
 )
 
 // LinearGaussianCPD represents a conditional Gaussian distribution
@@ -21,15 +24,33 @@ type LinearGaussianCPD struct {
 	Variance     float64            // σ²
 
 	// For discrete parents: different Gaussian for each parent state combination
-	// DiscreteStates[parent_config] = (mean, variance)
+	// DiscreteStates[parent_config] = (mean, variance), where parent_config
+	// joins the discrete parent values with commas in Parents order
 	DiscreteStates map[string]GaussianParams
 	Cardinality    map[string]int // Cardinality of discrete parents
+
+	// LogVariance makes the variance log-linear in the continuous parents:
+	// σ²(y) = σ² · exp(Σᵢ γᵢyᵢ) with γᵢ = LogVariance[parent], where σ² is
+	// Variance, or the state's variance with discrete parents. Nil gives a
+	// constant variance.
+	LogVariance map[string]float64
+
+	// Basis adds nonlinear features of the continuous parents to the mean,
+	// each with a coefficient in BasisCoefficients, or in the state's
+	// BasisCoefficients with discrete parents
+	Basis             []BasisTerm
+	BasisCoefficients []float64
 }
 
-// GaussianParams holds mean and variance for a Gaussian
+// GaussianParams holds mean and variance for a Gaussian. With continuous
+// parents alongside discrete ones, Mean is the intercept of a regression on
+// the continuous parents with the given Coefficients, and BasisCoefficients
+// weight the CPD's basis terms.
 type GaussianParams struct {
-	Mean     float64
-	Variance float64
+	Mean              float64
+	Variance          float64
+	Coefficients      map[string]float64
+	BasisCoefficients []float64
 }
 
 // NewLinearGaussianCPD creates a new linear Gaussian CPD
@@ -38,7 +59,7 @@ func NewLinearGaussianCPD(variable string, parents []string, intercept float64,
 	coefficients map[string]float64, variance float64) (*LinearGaussianCPD, error) {
 
 	if variance <= 0 {
-		return nil, fmt.Errorf("variance must be positive")
+		return nil, fmt.Errorf("variance must be positive: %w", ErrInvalidDistribution)
 	}
 
 	parentTypes := make(map[string]string)
@@ -56,6 +77,25 @@ func NewLinearGaussianCPD(variable string, parents []string, intercept float64,
 	}, nil
 }
 
+// NewHeteroscedasticGaussianCPD creates a linear Gaussian CPD whose variance
+// also depends on the continuous parents:
+// X | y ~ N(β₀ + Σᵢ βᵢyᵢ, σ² · exp(Σᵢ γᵢyᵢ))
+// variance is σ², the variance with every parent at zero
+func NewHeteroscedasticGaussianCPD(variable string, parents []string, intercept float64,
+	coefficients map[string]float64, variance float64, logVariance map[string]float64) (*LinearGaussianCPD, error) {
+	cpd, err := NewLinearGaussianCPD(variable, parents, intercept, coefficients, variance)
+	if err != nil {
+		return nil, err
+	}
+	for p := range logVariance {
+		if cpd.ParentTypes[p] != "continuous" {
+			return nil, fmt.Errorf("log-variance coefficient for %s is not a continuous parent", p)
+		}
+	}
+	cpd.LogVariance = logVariance
+	return cpd, nil
+}
+
 // NewDiscreteParentGaussianCPD creates a Gaussian CPD with discrete parents
 // Each parent state combination has different mean/variance
 func NewDiscreteParentGaussianCPD(variable string, parents []string, cardinality map[string]int,
@@ -68,7 +108,7 @@ func NewDiscreteParentGaussianCPD(variable string, parents []string, cardinality
 	}
 
 	if len(states) != expectedStates {
-		return nil, fmt.Errorf("expected %d state combinations, got %d", expectedStates, len(states))
+		return nil, fmt.Errorf("expected %d state combinations, got %d: %w", expectedStates, len(states), ErrCardinalityMismatch)
 	}
 
 	parentTypes := make(map[string]string)
@@ -85,6 +125,91 @@ func NewDiscreteParentGaussianCPD(variable string, parents []string, cardinality
 	}, nil
 }
 
+// NewCLGCPD creates a conditional linear Gaussian CPD with both discrete and
+// continuous parents. Each discrete parent configuration has its own linear
+// regression on the continuous parents:
+// X | d, y ~ N(β₀(d) + Σᵢ βᵢ(d)yᵢ, σ²(d))
+func NewCLGCPD(variable string, discreteParents, continuousParents []string,
+	cardinality map[string]int, states map[string]GaussianParams) (*LinearGaussianCPD, error) {
+
+	expectedStates := 1
+	for _, p := range discreteParents {
+		if cardinality[p] <= 0 {
+			return nil, fmt.Errorf("missing cardinality for discrete parent %s", p)
+		}
+		expectedStates *= cardinality[p]
+	}
+
+	if len(states) != expectedStates {
+		return nil, fmt.Errorf("expected %d state combinations, got %d: %w", expectedStates, len(states), ErrCardinalityMismatch)
+	}
+
+	parentTypes := make(map[string]string)
+	for _, p := range discreteParents {
+		parentTypes[p] = "discrete"
+	}
+	for _, p := range continuousParents {
+		if _, dup := parentTypes[p]; dup {
+			return nil, fmt.Errorf("parent %s listed as both discrete and continuous", p)
+		}
+		parentTypes[p] = "continuous"
+	}
+
+	for key, params := range states {
+		if params.Variance <= 0 {
+			return nil, fmt.Errorf("variance must be positive for state %s: %w", key, ErrInvalidDistribution)
+		}
+		for p := range params.Coefficients {
+			if parentTypes[p] != "continuous" {
+				return nil, fmt.Errorf("coefficient for %s in state %s is not a continuous parent", p, key)
+			}
+		}
+	}
+
+	parents := make([]string, 0, len(discreteParents)+len(continuousParents))
+	parents = append(parents, discreteParents...)
+	parents = append(parents, continuousParents...)
+
+	return &LinearGaussianCPD{
+		Variable:       variable,
+		Parents:        parents,
+		ParentTypes:    parentTypes,
+		DiscreteStates: states,
+		Cardinality:    cardinality,
+	}, nil
+}
+
+// IsHeteroscedastic reports whether the variance depends on the continuous
+// parents
+func (cpd *LinearGaussianCPD) IsHeteroscedastic() bool {
+	for _, g := range cpd.LogVariance {
+		if g != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscreteParents returns the discrete parents in Parents order
+func (cpd *LinearGaussianCPD) DiscreteParents() []string {
+	return cpd.parentsOfType("discrete")
+}
+
+// ContinuousParents returns the continuous parents in Parents order
+func (cpd *LinearGaussianCPD) ContinuousParents() []string {
+	return cpd.parentsOfType("continuous")
+}
+
+func (cpd *LinearGaussianCPD) parentsOfType(ptype string) []string {
+	result := make([]string, 0, len(cpd.Parents))
+	for _, p := range cpd.Parents {
+		if cpd.ParentTypes[p] == ptype {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
 // GetMean returns the conditional mean E[X | parents]
 func (cpd *LinearGaussianCPD) GetMean(parentValues map[string]interface{}) (float64, error) {
 	// Check if using discrete parents
@@ -97,13 +222,29 @@ func (cpd *LinearGaussianCPD) GetMean(parentValues map[string]interface{}) (floa
 	}
 
 	if hasDiscrete {
-		// All parents must be discrete
 		stateKey := cpd.getStateKey(parentValues)
 		params, ok := cpd.DiscreteStates[stateKey]
 		if !ok {
 			return 0, fmt.Errorf("no parameters for state %s", stateKey)
 		}
-		return params.Mean, nil
+
+		// Regression on any continuous parents within this configuration
+		mean := params.Mean
+		for parent, coef := range params.Coefficients {
+			floatVal, ok := parentValues[parent].(float64)
+			if !ok {
+				return 0, fmt.Errorf("parent %s value must be float64", parent)
+			}
+			mean += coef * floatVal
+		}
+		if len(cpd.Basis) > 0 {
+			extra, err := cpd.basisMean(params.BasisCoefficients, parentValues)
+			if err != nil {
+				return 0, err
+			}
+			mean += extra
+		}
+		return mean, nil
 	}
 
 	// Continuous parents: μ = β₀ + Σᵢ βᵢyᵢ
@@ -122,6 +263,13 @@ func (cpd *LinearGaussianCPD) GetMean(parentValues map[string]interface{}) (floa
 		coef := cpd.Coefficients[parent]
 		mean += coef * floatVal
 	}
+	if len(cpd.Basis) > 0 {
+		extra, err := cpd.basisMean(cpd.BasisCoefficients, parentValues)
+		if err != nil {
+			return 0, err
+		}
+		mean += extra
+	}
 
 	return mean, nil
 }
@@ -137,19 +285,50 @@ func (cpd *LinearGaussianCPD) GetVariance(parentValues map[string]interface{}) (
 		}
 	}
 
+	variance := cpd.Variance
 	if hasDiscrete {
 		stateKey := cpd.getStateKey(parentValues)
 		params, ok := cpd.DiscreteStates[stateKey]
 		if !ok {
 			return 0, fmt.Errorf("no parameters for state %s", stateKey)
 		}
-		return params.Variance, nil
+		variance = params.Variance
 	}
 
-	return cpd.Variance, nil
+	// Heteroscedastic: σ²(y) = σ² · exp(Σᵢ γᵢyᵢ)
+	if len(cpd.LogVariance) > 0 {
+		exponent := 0.0
+		for parent, g := range cpd.LogVariance {
+			floatVal, ok := parentValues[parent].(float64)
+			if !ok {
+				return 0, fmt.Errorf("parent %s value must be float64", parent)
+			}
+			exponent += g * floatVal
+		}
+		variance *= math.Exp(exponent)
+	}
+	return variance, nil
 }
 
-// Sample generates a sample from P(X | parents)
+// Distribution returns the normal distribution of X given the parent
+// values, drawing from rng. rng may be nil when the result is not sampled.
+func (cpd *LinearGaussianCPD) Distribution(parentValues map[string]interface{}, rng *rand.Rand) (Distribution, error) {
+	mean, err := cpd.GetMean(parentValues)
+	if err != nil {
+		return nil, err
+	}
+
+	variance, err := cpd.GetVariance(parentValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return distuv.Normal{Mu: mean, Sigma: math.Sqrt(variance), Src: source(rng)}, nil
+}
+
+// Sample generates a sample from P(X | parents). It draws rng.NormFloat64
+// directly rather than through Distribution, keeping the draws of the
+// columnar simulator, which samples linear Gaussian CPDs in bulk.
 func (cpd *LinearGaussianCPD) Sample(parentValues map[string]interface{}, rng *rand.Rand) (float64, error) {
 	mean, err := cpd.GetMean(parentValues)
 	if err != nil {
@@ -161,57 +340,108 @@ func (cpd *LinearGaussianCPD) Sample(parentValues map[string]interface{}, rng *r
 		return 0, err
 	}
 
-	// Sample from N(mean, variance)
-	stdDev := math.Sqrt(variance)
-	return rng.NormFloat64()*stdDev + mean, nil
+	return rng.NormFloat64()*math.Sqrt(variance) + mean, nil
 }
 
 // PDF evaluates the probability density P(x | parents)
 func (cpd *LinearGaussianCPD) PDF(x float64, parentValues map[string]interface{}) (float64, error) {
-	mean, err := cpd.GetMean(parentValues)
+	logPDF, err := cpd.LogPDF(x, parentValues)
 	if err != nil {
 		return 0, err
 	}
+	return math.Exp(logPDF), nil
+}
 
-	variance, err := cpd.GetVariance(parentValues)
+// LogPDF evaluates log P(x | parents), without underflow far in the tails
+func (cpd *LinearGaussianCPD) LogPDF(x float64, parentValues map[string]interface{}) (float64, error) {
+	d, err := cpd.Distribution(parentValues, nil)
 	if err != nil {
 		return 0, err
 	}
-
-	// Gaussian PDF: (1/√(2πσ²)) exp(-(x-μ)²/(2σ²))
-	stdDev := math.Sqrt(variance)
-	normConst := 1.0 / (stdDev * math.Sqrt(2*math.Pi))
-	diff := x - mean
-	exponent := -(diff * diff) / (2 * variance)
-
-	return normConst * math.Exp(exponent), nil
+	return d.LogProb(x), nil
 }
 
-// ToFactor converts the CPD to a Gaussian factor
-// Only works for continuous parents
-func (cpd *LinearGaussianCPD) ToFactor() (*GaussianFactor, error) {
+// ToFactor converts the CPD to a canonical-form potential over the variable
+// and its parents. For X = β₀ + βᵀY + ε, writing a = (1, -β):
+// K = aaᵀ/σ², h = (β₀/σ²)a, g = -β₀²/(2σ²) - ½log(2πσ²)
+// Only works for continuous parents, a constant variance and no basis terms
+func (cpd *LinearGaussianCPD) ToFactor() (*CanonicalFactor, error) {
+	if cpd.IsHeteroscedastic() {
+		return nil, fmt.Errorf("CPD of %s: %w", cpd.Variable, ErrHeteroscedastic)
+	}
+	if len(cpd.Basis) > 0 {
+		return nil, fmt.Errorf("CPD of %s: %w", cpd.Variable, ErrNonlinearMean)
+	}
 	// Check if has discrete parents
 	for _, ptype := range cpd.ParentTypes {
 		if ptype == "discrete" {
-			return nil, fmt.Errorf("cannot convert CPD with discrete parents to single Gaussian factor")
+			return nil, fmt.Errorf("cannot convert CPD with discrete parents to single Gaussian factor: %w", ErrNotImplementedCLG)
 		}
 	}
+	return canonicalRegression(cpd.Variable, cpd.Parents, cpd.Intercept, cpd.Coefficients, cpd.Variance)
+}
 
-	if len(cpd.Parents) == 0 {
-		// No parents: just a Gaussian over the variable
-		mean := map[string]float64{cpd.Variable: cpd.Intercept}
-		cov := map[string]map[string]float64{
-			cpd.Variable: {cpd.Variable: cpd.Variance},
+// ConditionalFactor converts the CPD to a canonical-form potential over the
+// variable and its continuous parents, with the discrete parents fixed to the
+// given states
+func (cpd *LinearGaussianCPD) ConditionalFactor(discreteValues map[string]int) (*CanonicalFactor, error) {
+	discreteParents := cpd.DiscreteParents()
+	if len(discreteParents) == 0 {
+		return cpd.ToFactor()
+	}
+	if cpd.IsHeteroscedastic() {
+		return nil, fmt.Errorf("CPD of %s: %w", cpd.Variable, ErrHeteroscedastic)
+	}
+	if len(cpd.Basis) > 0 {
+		return nil, fmt.Errorf("CPD of %s: %w", cpd.Variable, ErrNonlinearMean)
+	}
+
+	parentValues := make(map[string]interface{}, len(discreteParents))
+	for _, p := range discreteParents {
+		val, ok := discreteValues[p]
+		if !ok {
+			return nil, fmt.Errorf("missing state for discrete parent %s", p)
+		}
+		parentValues[p] = val
+	}
+
+	stateKey := cpd.getStateKey(parentValues)
+	params, ok := cpd.DiscreteStates[stateKey]
+	if !ok {
+		return nil, fmt.Errorf("no parameters for state %s", stateKey)
+	}
+	return canonicalRegression(cpd.Variable, cpd.ContinuousParents(), params.Mean, params.Coefficients, params.Variance)
+}
+
+// canonicalRegression builds the canonical potential of X = β₀ + βᵀY + ε
+func canonicalRegression(variable string, parents []string, intercept float64,
+	coefficients map[string]float64, variance float64) (*CanonicalFactor, error) {
+	if variance <= 0 {
+		return nil, fmt.Errorf("variance of %s must be positive, got %f: %w", variable, variance, ErrInvalidDistribution)
+	}
+
+	n := len(parents) + 1
+	vars := make([]string, 0, n)
+	vars = append(vars, variable)
+	vars = append(vars, parents...)
+
+	a := make([]float64, n)
+	a[0] = 1
+	for i, parent := range parents {
+		a[i+1] = -coefficients[parent]
+	}
+
+	k := mat.NewSymDense(n, nil)
+	h := mat.NewVecDense(n, nil)
+	for i := 0; i < n; i++ {
+		h.SetVec(i, intercept*a[i]/variance)
+		for j := i; j < n; j++ {
+			k.SetSym(i, j, a[i]*a[j]/variance)
 		}
-		return NewGaussianFactor([]string{cpd.Variable}, mean, cov)
 	}
+	g := -intercept*intercept/(2*variance) - 0.5*math.Log(2*math.Pi*variance)
 
-	// With continuous parents: build joint Gaussian
-	// This requires representing the conditional as a joint
-	// For X = β₀ + Σᵢ βᵢYᵢ + ε with ε ~ N(0, σ²)
-	// We need the joint P(X, Y₁, ..., Yₙ)
-	// This is complex and requires knowing the parent marginals
-	return nil, fmt.Errorf("converting linear Gaussian CPD to factor requires parent distributions")
+	return NewCanonicalFactor(vars, k, h, g)
 }
 
 // Copy creates a deep copy
@@ -231,6 +461,16 @@ func (cpd *LinearGaussianCPD) Copy() *LinearGaussianCPD {
 
 	statesCopy := make(map[string]GaussianParams)
 	for k, v := range cpd.DiscreteStates {
+		if v.Coefficients != nil {
+			coefs := make(map[string]float64, len(v.Coefficients))
+			for p, c := range v.Coefficients {
+				coefs[p] = c
+			}
+			v.Coefficients = coefs
+		}
+		if v.BasisCoefficients != nil {
+			v.BasisCoefficients = append([]float64(nil), v.BasisCoefficients...)
+		}
 		statesCopy[k] = v
 	}
 
@@ -239,15 +479,31 @@ func (cpd *LinearGaussianCPD) Copy() *LinearGaussianCPD {
 		cardCopy[k] = v
 	}
 
+	var logVarianceCopy map[string]float64
+	if cpd.LogVariance != nil {
+		logVarianceCopy = make(map[string]float64, len(cpd.LogVariance))
+		for k, v := range cpd.LogVariance {
+			logVarianceCopy[k] = v
+		}
+	}
+
+	var basisCoefficientsCopy []float64
+	if cpd.BasisCoefficients != nil {
+		basisCoefficientsCopy = append([]float64(nil), cpd.BasisCoefficients...)
+	}
+
 	return &LinearGaussianCPD{
-		Variable:       cpd.Variable,
-		Parents:        parentsCopy,
-		ParentTypes:    parentTypesCopy,
-		Intercept:      cpd.Intercept,
-		Coefficients:   coeffCopy,
-		Variance:       cpd.Variance,
-		DiscreteStates: statesCopy,
-		Cardinality:    cardCopy,
+		Variable:          cpd.Variable,
+		Parents:           parentsCopy,
+		ParentTypes:       parentTypesCopy,
+		Intercept:         cpd.Intercept,
+		Coefficients:      coeffCopy,
+		Variance:          cpd.Variance,
+		DiscreteStates:    statesCopy,
+		Cardinality:       cardCopy,
+		LogVariance:       logVarianceCopy,
+		Basis:             copyBasis(cpd.Basis),
+		BasisCoefficients: basisCoefficientsCopy,
 	}
 }
 
@@ -259,7 +515,7 @@ func (cpd *LinearGaussianCPD) String() string {
 // getStateKey creates a string key for discrete parent state combination
 func (cpd *LinearGaussianCPD) getStateKey(parentValues map[string]interface{}) string {
 	key := ""
-	for i, parent := range cpd.Parents {
+	for i, parent := range cpd.DiscreteParents() {
 		if i > 0 {
 			key += ","
 		}
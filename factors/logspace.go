@@ -0,0 +1,66 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+)
+
+// LogSumExp returns log(Σᵢ exp(xᵢ)) without overflow or underflow.
+// It returns -Inf for an empty slice or when every term is -Inf.
+func LogSumExp(values []float64) float64 {
+	maxVal := math.Inf(-1)
+	for _, v := range values {
+		if v > maxVal {
+			maxVal = v
+		}
+	}
+	if math.IsInf(maxVal, 0) {
+		return maxVal
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += math.Exp(v - maxVal)
+	}
+	return maxVal + math.Log(sum)
+}
+
+// Log returns a copy of the factor holding the logarithm of each value
+func (f *DiscreteFactor) Log() *DiscreteFactor {
+	result := f.Copy()
+	for i, v := range result.Values {
+		result.Values[i] = math.Log(v)
+	}
+	return result
+}
+
+// Exp returns a copy of a log-domain factor converted back to probabilities
+func (f *DiscreteFactor) Exp() *DiscreteFactor {
+	result := f.Copy()
+	for i, v := range result.Values {
+		result.Values[i] = math.Exp(v)
+	}
+	return result
+}
+
+// MultiplyLog multiplies two log-domain factors by adding their values
+func (f *DiscreteFactor) MultiplyLog(other *DiscreteFactor) (*DiscreteFactor, error) {
+	return f.combine(other, func(a, b float64) float64 { return a + b })
+}
+
+// NormalizeLog normalizes a log-domain factor in place so its values
+// exponentiate to a distribution that sums to 1
+func (f *DiscreteFactor) NormalizeLog() error {
+	logZ := LogSumExp(f.Values)
+	if math.IsInf(logZ, -1) {
+		return fmt.Errorf("cannot normalize factor with sum 0: %w", ErrZeroProbability)
+	}
+	if math.IsNaN(logZ) || math.IsInf(logZ, 1) {
+		return fmt.Errorf("cannot normalize factor with log sum %f", logZ)
+	}
+
+	for i := range f.Values {
+		f.Values[i] -= logZ
+	}
+	return nil
+}
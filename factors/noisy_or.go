@@ -0,0 +1,134 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// NoisyOrCPD is a binary CPD in which each active parent independently
+// fails to turn the variable on with probability 1 - Weights[parent]:
+// P(X=1 | parents) = 1 - (1 - Leak) * Π_{active i} (1 - Weights[i])
+// Parents are binary, with state 1 meaning active. It needs one parameter per
+// parent instead of a table that doubles in size with each parent.
+type NoisyOrCPD struct {
+	Variable string
+	Parents  []string
+	Weights  map[string]float64 // Activation probability of each parent
+	Leak     float64            // Probability that X=1 with no active parent
+}
+
+// NewNoisyOrCPD creates a noisy-OR CPD
+func NewNoisyOrCPD(variable string, weights map[string]float64, leak float64) (*NoisyOrCPD, error) {
+	if leak < 0 || leak > 1 {
+		return nil, fmt.Errorf("leak %f outside [0, 1]: %w", leak, ErrInvalidDistribution)
+	}
+
+	parents := make([]string, 0, len(weights))
+	w := make(map[string]float64, len(weights))
+	for parent, weight := range weights {
+		if weight < 0 || weight > 1 {
+			return nil, fmt.Errorf("weight %f for parent %s outside [0, 1]: %w", weight, parent, ErrInvalidDistribution)
+		}
+		parents = append(parents, parent)
+		w[parent] = weight
+	}
+	sort.Strings(parents)
+
+	return &NoisyOrCPD{Variable: variable, Parents: parents, Weights: w, Leak: leak}, nil
+}
+
+// GetVariable returns the CPD's variable
+func (cpd *NoisyOrCPD) GetVariable() string {
+	return cpd.Variable
+}
+
+// GetParents returns a copy of the CPD's parents
+func (cpd *NoisyOrCPD) GetParents() []string {
+	return append([]string(nil), cpd.Parents...)
+}
+
+// GetCardinality returns 2, the variable is binary
+func (cpd *NoisyOrCPD) GetCardinality() int {
+	return 2
+}
+
+// probOn returns P(X=1 | parent states)
+func (cpd *NoisyOrCPD) probOn(parentValues map[string]int) (float64, error) {
+	off := 1 - cpd.Leak
+	for _, parent := range cpd.Parents {
+		val, ok := parentValues[parent]
+		if !ok {
+			return 0, fmt.Errorf("missing evidence value for %s", parent)
+		}
+		if val != 0 && val != 1 {
+			return 0, fmt.Errorf("state %d of noisy-OR parent %s is not binary", val, parent)
+		}
+		if val == 1 {
+			off *= 1 - cpd.Weights[parent]
+		}
+	}
+	return 1 - off, nil
+}
+
+// ToFactor expands the CPD into a factor over the variable and its parents
+func (cpd *NoisyOrCPD) ToFactor() (*DiscreteFactor, error) {
+	vars := append(cpd.GetParents(), cpd.Variable)
+	sort.Strings(vars)
+
+	card := make(map[string]int, len(vars))
+	for _, v := range vars {
+		card[v] = 2
+	}
+
+	values := make([]float64, 1<<len(vars))
+	assignment := make(map[string]int, len(vars))
+	for idx := range values {
+		// The last variable varies fastest
+		rem := idx
+		for i := len(vars) - 1; i >= 0; i-- {
+			assignment[vars[i]] = rem % 2
+			rem /= 2
+		}
+		on, err := cpd.probOn(assignment)
+		if err != nil {
+			return nil, err
+		}
+		if assignment[cpd.Variable] == 1 {
+			values[idx] = on
+		} else {
+			values[idx] = 1 - on
+		}
+	}
+
+	return NewDiscreteFactor(vars, card, values)
+}
+
+// Sample draws a state of the variable given the parent states
+func (cpd *NoisyOrCPD) Sample(parentValues map[string]int, rng *rand.Rand) (int, error) {
+	on, err := cpd.probOn(parentValues)
+	if err != nil {
+		return 0, err
+	}
+	if rng.Float64() < on {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// LogProb returns log P(variable=state | parent states)
+func (cpd *NoisyOrCPD) LogProb(state int, parentValues map[string]int) (float64, error) {
+	on, err := cpd.probOn(parentValues)
+	if err != nil {
+		return 0, err
+	}
+	switch state {
+	case 1:
+		return math.Log(on), nil
+	case 0:
+		return math.Log(1 - on), nil
+	default:
+		return 0, fmt.Errorf("invalid variable state %d", state)
+	}
+}
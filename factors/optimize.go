@@ -0,0 +1,102 @@
+package factors
+
+import "math"
+
+// minimizeBFGS minimizes objective from x, which it updates in place, by BFGS
+// with a backtracking line search, cutting back steps longer than maxStep.
+// The objective returns its value at x and writes its gradient to grad when
+// grad is non-nil. It is meant for a few parameters, where the dense inverse
+// Hessian estimate is cheap.
+func minimizeBFGS(objective func(x, grad []float64) float64, x []float64, maxStep float64, maxIterations int) []float64 {
+	d := len(x)
+	grad := make([]float64, d)
+	f := objective(x, grad)
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return x
+	}
+	h := make([][]float64, d)
+	for i := range h {
+		h[i] = make([]float64, d)
+		h[i][i] = 1
+	}
+	direction := make([]float64, d)
+	candidate := make([]float64, d)
+	next := make([]float64, d)
+	s := make([]float64, d)
+	y := make([]float64, d)
+	for iter := 0; iter < maxIterations; iter++ {
+		for i := range direction {
+			direction[i] = -dot(h[i], grad)
+		}
+		slope := dot(direction, grad)
+		if slope >= 0 {
+			// Lost descent: restart from the gradient
+			for i := range h {
+				for j := range h[i] {
+					h[i][j] = 0
+				}
+				h[i][i] = 1
+				direction[i] = -grad[i]
+			}
+			slope = -dot(grad, grad)
+		}
+		if -slope < 1e-12 {
+			break
+		}
+		t := math.Min(1, maxStep/math.Sqrt(dot(direction, direction)))
+		accepted := false
+		var fc float64
+		for k := 0; k < 30; k++ {
+			for i := range x {
+				candidate[i] = x[i] + t*direction[i]
+			}
+			fc = objective(candidate, next)
+			if fc <= f+1e-4*t*slope {
+				accepted = true
+				break
+			}
+			t /= 2
+		}
+		if !accepted {
+			break
+		}
+
+		for i := range x {
+			s[i] = candidate[i] - x[i]
+			y[i] = next[i] - grad[i]
+		}
+		copy(x, candidate)
+		copy(grad, next)
+		converged := f-fc < 1e-9*math.Abs(f)
+		f = fc
+		if converged {
+			break
+		}
+
+		// H ← (I - ρsyᵀ) H (I - ρysᵀ) + ρssᵀ
+		sy := dot(s, y)
+		if sy <= 1e-12 {
+			continue
+		}
+		rho := 1 / sy
+		hy := make([]float64, d)
+		for i := range hy {
+			hy[i] = dot(h[i], y)
+		}
+		yhy := dot(y, hy)
+		for i := 0; i < d; i++ {
+			for j := 0; j < d; j++ {
+				h[i][j] += -rho*(hy[i]*s[j]+s[i]*hy[j]) + (rho*rho*yhy+rho)*s[i]*s[j]
+			}
+		}
+	}
+	return x
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,267 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// ordinalProbitIterations bounds the BFGS iterations of FitOrdinalProbitCPD
+const ordinalProbitIterations = 200
+
+// OrdinalProbitCPD represents an ordinal discrete variable driven by a latent
+// linear-Gaussian score of continuous parents:
+// z = Σᵢ βᵢyᵢ + ε, ε ~ N(0, 1), X = k when θₖ₋₁ < z ≤ θₖ
+// with θ₀ = -∞ and θ_K = +∞, so P(X = k | y) = Φ(θₖ - η) - Φ(θₖ₋₁ - η)
+// for η = Σᵢ βᵢyᵢ. The thresholds take the place of an intercept.
+type OrdinalProbitCPD struct {
+	Variable     string
+	Parents      []string
+	Coefficients map[string]float64 // βᵢ for each parent
+	Thresholds   []float64          // θ₁ < ... < θ_{K-1} for K states
+}
+
+// NewOrdinalProbitCPD creates an ordinal probit CPD with len(thresholds)+1
+// states
+func NewOrdinalProbitCPD(variable string, parents []string, coefficients map[string]float64, thresholds []float64) (*OrdinalProbitCPD, error) {
+	if len(thresholds) == 0 {
+		return nil, fmt.Errorf("ordinal CPD of %s needs at least one threshold", variable)
+	}
+	for k := 1; k < len(thresholds); k++ {
+		if !(thresholds[k] > thresholds[k-1]) {
+			return nil, fmt.Errorf("thresholds of %s must increase, got %v: %w", variable, thresholds, ErrInvalidDistribution)
+		}
+	}
+	for _, p := range parents {
+		if _, ok := coefficients[p]; !ok {
+			return nil, fmt.Errorf("missing coefficient for parent %s", p)
+		}
+	}
+	coefs := make(map[string]float64, len(coefficients))
+	for p, c := range coefficients {
+		coefs[p] = c
+	}
+	return &OrdinalProbitCPD{
+		Variable:     variable,
+		Parents:      append([]string(nil), parents...),
+		Coefficients: coefs,
+		Thresholds:   append([]float64(nil), thresholds...),
+	}, nil
+}
+
+// GetVariable returns the child variable
+func (cpd *OrdinalProbitCPD) GetVariable() string {
+	return cpd.Variable
+}
+
+// GetParents returns a copy of the CPD's parents
+func (cpd *OrdinalProbitCPD) GetParents() []string {
+	return append([]string(nil), cpd.Parents...)
+}
+
+// GetCardinality returns the number of ordinal states
+func (cpd *OrdinalProbitCPD) GetCardinality() int {
+	return len(cpd.Thresholds) + 1
+}
+
+// Probabilities returns P(X = k | parents) for every state k
+func (cpd *OrdinalProbitCPD) Probabilities(parentValues map[string]interface{}) ([]float64, error) {
+	eta, err := linearPredictor(0, cpd.Coefficients, cpd.Parents, parentValues)
+	if err != nil {
+		return nil, err
+	}
+	probs := make([]float64, cpd.GetCardinality())
+	for k := range probs {
+		probs[k] = cpd.stateProbability(k, eta)
+	}
+	return probs, nil
+}
+
+// Sample draws a state by thresholding the latent score
+func (cpd *OrdinalProbitCPD) Sample(parentValues map[string]interface{}, rng *rand.Rand) (int, error) {
+	eta, err := linearPredictor(0, cpd.Coefficients, cpd.Parents, parentValues)
+	if err != nil {
+		return 0, err
+	}
+	z := eta + rng.NormFloat64()
+	for k, theta := range cpd.Thresholds {
+		if z <= theta {
+			return k, nil
+		}
+	}
+	return len(cpd.Thresholds), nil
+}
+
+// LogProb returns log P(X = state | parents)
+func (cpd *OrdinalProbitCPD) LogProb(state int, parentValues map[string]interface{}) (float64, error) {
+	if state < 0 || state > len(cpd.Thresholds) {
+		return 0, fmt.Errorf("state %d of %s out of range [0, %d)", state, cpd.Variable, cpd.GetCardinality())
+	}
+	eta, err := linearPredictor(0, cpd.Coefficients, cpd.Parents, parentValues)
+	if err != nil {
+		return 0, err
+	}
+	return math.Log(cpd.stateProbability(state, eta)), nil
+}
+
+// Copy creates a deep copy
+func (cpd *OrdinalProbitCPD) Copy() *OrdinalProbitCPD {
+	copied, _ := NewOrdinalProbitCPD(cpd.Variable, cpd.Parents, cpd.Coefficients, cpd.Thresholds)
+	return copied
+}
+
+// String returns a string representation
+func (cpd *OrdinalProbitCPD) String() string {
+	return fmt.Sprintf("OrdinalProbitCPD(%s | %v, %d states)", cpd.Variable, cpd.Parents, cpd.GetCardinality())
+}
+
+// stateProbability returns Φ(θₖ - η) - Φ(θₖ₋₁ - η), taking the difference of
+// upper tails when both bounds are above η so it does not cancel to zero
+func (cpd *OrdinalProbitCPD) stateProbability(k int, eta float64) float64 {
+	lower, upper := math.Inf(-1), math.Inf(1)
+	if k > 0 {
+		lower = cpd.Thresholds[k-1]
+	}
+	if k < len(cpd.Thresholds) {
+		upper = cpd.Thresholds[k]
+	}
+	return intervalProbability(lower-eta, upper-eta)
+}
+
+// intervalProbability returns Φ(u) - Φ(l) for l < u
+func intervalProbability(l, u float64) float64 {
+	if l > 0 {
+		return 0.5 * (math.Erfc(l/math.Sqrt2) - math.Erfc(u/math.Sqrt2))
+	}
+	return 0.5 * (math.Erfc(-u/math.Sqrt2) - math.Erfc(-l/math.Sqrt2))
+}
+
+// FitOrdinalProbitCPD fits an ordinal probit CPD by maximum likelihood to
+// rows of parent values, in parents order, and states in [0, cardinality),
+// with nil weights counting each row once. The thresholds are kept in order
+// by fitting the first and the logs of the gaps between the rest.
+func FitOrdinalProbitCPD(variable string, parents []string, inputs [][]float64, states []int, weights []float64,
+	cardinality int) (*OrdinalProbitCPD, error) {
+	if cardinality < 2 {
+		return nil, fmt.Errorf("ordinal CPD of %s needs at least 2 states, got %d", variable, cardinality)
+	}
+	if len(inputs) != len(states) || (weights != nil && len(weights) != len(states)) {
+		return nil, fmt.Errorf("got %d input rows, %d states and %d weights", len(inputs), len(states), len(weights))
+	}
+	weight := func(i int) float64 {
+		if weights == nil {
+			return 1
+		}
+		return weights[i]
+	}
+
+	// Start from zero coefficients and the thresholds of the marginal
+	p := len(parents)
+	counts := make([]float64, cardinality)
+	total := 0.0
+	for i, s := range states {
+		if s < 0 || s >= cardinality {
+			return nil, fmt.Errorf("state %d of %s out of range [0, %d)", s, variable, cardinality)
+		}
+		counts[s] += weight(i)
+		total += weight(i)
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("no weight on %s", variable)
+	}
+	x := make([]float64, p+cardinality-1)
+	cumulative, previous := 0.0, 0.0
+	for k := 0; k < cardinality-1; k++ {
+		cumulative += counts[k] / total
+		c := math.Min(math.Max(cumulative, 1e-3), 1-1e-3)
+		theta := math.Sqrt2 * math.Erfinv(2*c-1)
+		if k == 0 {
+			x[p] = theta
+		} else {
+			x[p+k] = math.Log(math.Max(theta-previous, 1e-2))
+		}
+		previous = math.Max(theta, previous+1e-2)
+	}
+
+	thresholds := make([]float64, cardinality-1)
+	unpack := func(x []float64) {
+		thresholds[0] = x[p]
+		for k := 1; k < len(thresholds); k++ {
+			thresholds[k] = thresholds[k-1] + math.Exp(x[p+k])
+		}
+	}
+	gradTheta := make([]float64, cardinality-1)
+	objective := func(x, grad []float64) float64 {
+		unpack(x)
+		for k := range gradTheta {
+			gradTheta[k] = 0
+		}
+		if grad != nil {
+			for j := range grad {
+				grad[j] = 0
+			}
+		}
+		nll := 0.0
+		for i, row := range inputs {
+			eta := 0.0
+			for j := 0; j < p; j++ {
+				eta += x[j] * row[j]
+			}
+			s := states[i]
+			l, u := math.Inf(-1), math.Inf(1)
+			if s > 0 {
+				l = thresholds[s-1] - eta
+			}
+			if s < cardinality-1 {
+				u = thresholds[s] - eta
+			}
+			prob := math.Max(intervalProbability(l, u), 1e-300)
+			w := weight(i)
+			nll -= w * math.Log(prob)
+			if grad == nil {
+				continue
+			}
+			// d log P / dη = -(φ(u) - φ(l))/P, d/dθₛ = φ(u)/P, d/dθₛ₋₁ = -φ(l)/P
+			phiL, phiU := normalDensity(l), normalDensity(u)
+			for j := 0; j < p; j++ {
+				grad[j] += w * (phiU - phiL) / prob * row[j]
+			}
+			if s < cardinality-1 {
+				gradTheta[s] -= w * phiU / prob
+			}
+			if s > 0 {
+				gradTheta[s-1] += w * phiL / prob
+			}
+		}
+		if grad != nil {
+			// θₖ = x₀ + Σ_{m≤k} exp(xₘ), so x₀ moves every threshold and xₘ
+			// those from m on
+			for m := range gradTheta {
+				scale := 1.0
+				if m > 0 {
+					scale = math.Exp(x[p+m])
+				}
+				for k := m; k < len(gradTheta); k++ {
+					grad[p+m] += scale * gradTheta[k]
+				}
+			}
+		}
+		return nll
+	}
+
+	best := minimizeBFGS(objective, x, 1, ordinalProbitIterations)
+	unpack(best)
+	coefficients := make(map[string]float64, p)
+	for j, parent := range parents {
+		coefficients[parent] = best[j]
+	}
+	return NewOrdinalProbitCPD(variable, parents, coefficients, thresholds)
+}
+
+// normalDensity returns the standard normal density, zero at ±∞
+func normalDensity(z float64) float64 {
+	if math.IsInf(z, 0) {
+		return 0
+	}
+	return math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+}
@@ -0,0 +1,136 @@
+package factors
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// ParallelThreshold is the output size at or above which Multiply and
+// Marginalize split their output across goroutines. Zero or negative
+// disables the parallel path.
+var ParallelThreshold = 1 << 15
+
+// useParallel reports whether an operation producing size entries should take
+// the parallel path
+func useParallel(size int) bool {
+	return ParallelThreshold > 0 && size >= ParallelThreshold
+}
+
+// parallelFor calls fn on contiguous chunks covering [0, n), one per worker
+func parallelFor(n int, fn func(lo, hi int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+
+	chunk := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for lo := 0; lo < n; lo += chunk {
+		hi := lo + chunk
+		if hi > n {
+			hi = n
+		}
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			fn(lo, hi)
+		}(lo, hi)
+	}
+	wg.Wait()
+}
+
+// forChunks calls fn on [0, n), split across goroutines if parallel
+func forChunks(n int, parallel bool, fn func(lo, hi int)) {
+	if parallel {
+		parallelFor(n, fn)
+		return
+	}
+	fn(0, n)
+}
+
+// SumProduct multiplies the factors and sums the variables out of their
+// product in one pass, without building the product. The output is laid
+// out as Marginalize would lay out the product from Multiply, and is split
+// across goroutines when the product would reach ParallelThreshold entries.
+func SumProduct(list []*DiscreteFactor, variables []string) (*DiscreteFactor, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no factors to multiply")
+	}
+	remove := make(map[string]bool, len(variables))
+	for _, v := range variables {
+		remove[v] = true
+	}
+	cardinality := make(map[string]int)
+	for _, f := range list {
+		for _, v := range f.Variables {
+			if existing, ok := cardinality[v]; ok && existing != f.Cardinality[v] {
+				return nil, fmt.Errorf("%w for variable %s", ErrCardinalityMismatch, v)
+			}
+			cardinality[v] = f.Cardinality[v]
+		}
+	}
+	union := make([]string, 0, len(cardinality))
+	for v := range cardinality {
+		union = append(union, v)
+	}
+	sort.Strings(union)
+	var kept, summed []string
+	for _, v := range union {
+		if remove[v] {
+			summed = append(summed, v)
+		} else {
+			kept = append(kept, v)
+		}
+	}
+
+	size, productSize := 1, 1
+	keptCard := make(map[string]int, len(kept))
+	for _, v := range kept {
+		keptCard[v] = cardinality[v]
+		size *= cardinality[v]
+	}
+	for _, v := range union {
+		productSize *= cardinality[v]
+	}
+
+	// Per factor, the strides of the kept variables and the offsets of every
+	// assignment of the summed ones, in the same order for all factors
+	cards := make([]int, len(kept))
+	for k, v := range kept {
+		cards[k] = cardinality[v]
+	}
+	keptStrides := make([][]int, len(list))
+	offsets := make([][]int, len(list))
+	for i, f := range list {
+		l := f.layout()
+		keptStrides[i] = l.stridesOf(kept)
+		offsets[i] = l.offsets(summed, cardinality)
+	}
+
+	values := getValues(size)
+	forChunks(size, useParallel(productSize), func(lo, hi int) {
+		o := newOdometer(cards, keptStrides, lo)
+		for idx := lo; idx < hi; idx++ {
+			sum := 0.0
+			for j := range offsets[0] {
+				p := 1.0
+				for i, f := range list {
+					p *= f.Values[o.offsets[i]+offsets[i][j]]
+				}
+				sum += p
+			}
+			values[idx] = sum
+			o.next()
+		}
+	})
+	if kept == nil {
+		kept = []string{}
+	}
+	return newCompiledFactor(kept, keptCard, values)
+}
@@ -0,0 +1,224 @@
+package factors
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomFactor(r *rand.Rand, vars []string, cardinality map[string]int) *DiscreteFactor {
+	size := 1
+	for _, v := range vars {
+		size *= cardinality[v]
+	}
+	values := make([]float64, size)
+	for i := range values {
+		values[i] = r.Float64()
+	}
+	factor, _ := NewDiscreteFactor(vars, cardinality, values)
+	return factor
+}
+
+func TestParallelMatchesSequential(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	card := map[string]int{"A": 2, "B": 3, "C": 4, "D": 2, "E": 3}
+	f1 := randomFactor(r, []string{"C", "A", "D"}, card)
+	f2 := randomFactor(r, []string{"B", "D", "E", "A"}, card)
+
+	defer func(threshold int) { ParallelThreshold = threshold }(ParallelThreshold)
+
+	ParallelThreshold = 0
+	seqProduct, _ := f1.Multiply(f2)
+	seqMarginal, _ := seqProduct.Marginalize([]string{"B", "D"})
+
+	ParallelThreshold = 1
+	parProduct, err := f1.Multiply(f2)
+	if err != nil {
+		t.Fatalf("Multiply failed: %v", err)
+	}
+	parMarginal, err := parProduct.Marginalize([]string{"B", "D"})
+	if err != nil {
+		t.Fatalf("Marginalize failed: %v", err)
+	}
+
+	if !parProduct.Equal(seqProduct, 1e-12) {
+		t.Error("Parallel product differs from sequential product")
+	}
+	if !parMarginal.Equal(seqMarginal, 1e-12) {
+		t.Error("Parallel marginal differs from sequential marginal")
+	}
+}
+
+func TestSumProduct(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	card := map[string]int{"A": 3, "B": 4, "C": 2, "D": 5, "E": 3}
+	list := []*DiscreteFactor{
+		randomFactor(r, []string{"C", "A", "D"}, card),
+		randomFactor(r, []string{"B", "D", "E", "A"}, card),
+		randomFactor(r, []string{"E", "C"}, card),
+	}
+	product, _ := Product(list, nil)
+
+	defer func(threshold int) { ParallelThreshold = threshold }(ParallelThreshold)
+	for _, threshold := range []int{0, 1} {
+		ParallelThreshold = threshold
+		for _, summed := range [][]string{{"D"}, {"B", "D"}, {"A", "B", "C", "D", "E"}} {
+			want, _ := product.Marginalize(summed)
+			got, err := SumProduct(list, summed)
+			if err != nil {
+				t.Fatalf("SumProduct failed: %v", err)
+			}
+			if !got.Equal(want, 1e-12) {
+				t.Errorf("threshold %d: SumProduct over %v differs from Multiply and Marginalize", threshold, summed)
+			}
+		}
+
+		// Eliminate takes the fused path at threshold 1
+		eliminated, err := Eliminate("D", list, nil)
+		if err != nil {
+			t.Fatalf("Eliminate failed: %v", err)
+		}
+		want, _ := product.Marginalize([]string{"D"})
+		got, _ := Product(eliminated, nil)
+		if !got.Equal(want, 1e-12) {
+			t.Errorf("threshold %d: Eliminate differs from Multiply and Marginalize", threshold)
+		}
+	}
+
+	mismatched := randomFactor(r, []string{"A"}, map[string]int{"A": 2})
+	if _, err := SumProduct([]*DiscreteFactor{list[0], mismatched}, []string{"A"}); !errors.Is(err, ErrCardinalityMismatch) {
+		t.Errorf("SumProduct with mismatched cardinality returned %v", err)
+	}
+}
+
+// enumerated computes f op g over the union of their scopes by assignment,
+// as a reference for the strided kernels
+func enumerated(f, g *DiscreteFactor, vars []string, card map[string]int) *DiscreteFactor {
+	size := 1
+	for _, v := range vars {
+		size *= card[v]
+	}
+	result := &DiscreteFactor{Variables: vars, Cardinality: card, Values: make([]float64, size)}
+	for idx := range result.Values {
+		assignment := result.indexToAssignment(idx)
+		result.Values[idx] = f.Values[f.projectAssignmentToIndex(assignment)] * g.Values[g.projectAssignmentToIndex(assignment)]
+	}
+	return result
+}
+
+func TestCompiledFactor(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	card := map[string]int{"A": 3, "B": 2, "C": 4, "D": 2}
+	f := randomFactor(r, []string{"C", "A", "D"}, card)
+	g := randomFactor(r, []string{"B", "D"}, card)
+	want := enumerated(f, g, []string{"A", "B", "C", "D"}, card)
+
+	compiledF, compiledG := f.Copy(), g.Copy()
+	compiledF.Compile()
+	compiledG.Compile()
+	for name, pair := range map[string][2]*DiscreteFactor{"plain": {f, g}, "compiled": {compiledF, compiledG}} {
+		product, err := pair[0].Multiply(pair[1])
+		if err != nil {
+			t.Fatalf("%s: Multiply failed: %v", name, err)
+		}
+		if !product.Equal(want, 1e-12) {
+			t.Errorf("%s: product differs from enumeration", name)
+		}
+		if product.compiled == nil {
+			t.Errorf("%s: product is not compiled", name)
+		}
+
+		summed, _ := product.Marginalize([]string{"A", "D"})
+		maxed, _ := product.MaxMarginalize([]string{"A", "D"})
+		reduced, _ := product.Reduce(map[string]int{"A": 2, "C": 1})
+		for b := 0; b < 2; b++ {
+			for c := 0; c < 4; c++ {
+				sum, best := 0.0, 0.0
+				for a := 0; a < 3; a++ {
+					for d := 0; d < 2; d++ {
+						p, _ := want.ProbabilityOf(map[string]int{"A": a, "B": b, "C": c, "D": d})
+						sum += p
+						best = math.Max(best, p)
+					}
+				}
+				at := map[string]int{"B": b, "C": c}
+				if got, _ := summed.ProbabilityOf(at); math.Abs(got-sum) > 1e-12 {
+					t.Errorf("%s: sum at %v = %v, want %v", name, at, got, sum)
+				}
+				if got, _ := maxed.ProbabilityOf(at); got != best {
+					t.Errorf("%s: max at %v = %v, want %v", name, at, got, best)
+				}
+			}
+			for d := 0; d < 2; d++ {
+				at := map[string]int{"B": b, "D": d}
+				p, _ := want.ProbabilityOf(map[string]int{"A": 2, "B": b, "C": 1, "D": d})
+				if got, _ := reduced.ProbabilityOf(at); got != p {
+					t.Errorf("%s: reduced at %v = %v, want %v", name, at, got, p)
+				}
+			}
+		}
+	}
+
+	// A stale table is ignored once the scope changes in place
+	compiledG.Variables = []string{"B", "A"}
+	compiledG.Cardinality = map[string]int{"B": 2, "A": 2}
+	marginal, err := compiledG.Marginalize([]string{"B"})
+	if err != nil {
+		t.Fatalf("Marginalize failed: %v", err)
+	}
+	for a := 0; a < 2; a++ {
+		if want := compiledG.Values[a] + compiledG.Values[2+a]; math.Abs(marginal.Values[a]-want) > 1e-12 {
+			t.Errorf("stale marginal[%d] = %v, want %v", a, marginal.Values[a], want)
+		}
+	}
+}
+
+func TestValuePool(t *testing.T) {
+	for _, n := range []int{1, minPooled - 1, minPooled, 100, 1024} {
+		buf := getValues(n)
+		if len(buf) != n {
+			t.Errorf("getValues(%d) has %d entries", n, len(buf))
+		}
+		if n >= minPooled && cap(buf)&(cap(buf)-1) != 0 {
+			t.Errorf("getValues(%d) has capacity %d, want a power of two", n, cap(buf))
+		}
+		putValues(buf)
+	}
+	putValues(make([]float64, 100)) // not from the pool; ignored
+
+	// Elimination releases its temporaries but never its inputs
+	r := rand.New(rand.NewSource(4))
+	card := map[string]int{"A": 4, "B": 4, "C": 4, "D": 4}
+	list := []*DiscreteFactor{
+		randomFactor(r, []string{"A", "B"}, card),
+		randomFactor(r, []string{"B", "C", "D"}, card),
+		randomFactor(r, []string{"B", "D"}, card),
+	}
+	inputs := make([]*DiscreteFactor, len(list))
+	for i, f := range list {
+		inputs[i] = f.Copy()
+	}
+	product, _ := Product(list, nil)
+	want, _ := product.Marginalize([]string{"B"})
+	for i := 0; i < 3; i++ {
+		remaining, err := Eliminate("B", list, nil)
+		if err != nil {
+			t.Fatalf("Eliminate failed: %v", err)
+		}
+		if got := remaining[len(remaining)-1]; !got.Equal(want, 1e-12) {
+			t.Errorf("round %d: eliminating B differs from Multiply and Marginalize", i)
+		}
+		remaining[len(remaining)-1].Release()
+	}
+	for i, f := range list {
+		if !f.Equal(inputs[i], 0) {
+			t.Errorf("input factor %d changed by elimination", i)
+		}
+	}
+
+	product.Release()
+	if product.Values != nil {
+		t.Error("Release kept the factor's values")
+	}
+}
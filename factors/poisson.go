@@ -0,0 +1,118 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// PoissonCPD represents a count variable with a log-linear Poisson
+// distribution given continuous parents:
+// X | y ~ Poisson(λ(y)), log λ(y) = β₀ + Σᵢ βᵢyᵢ
+// Values are non-negative integers held as float64, like other continuous
+// CPDs' values.
+type PoissonCPD struct {
+	Variable     string
+	Parents      []string
+	Intercept    float64            // β₀
+	Coefficients map[string]float64 // βᵢ for each parent
+}
+
+// NewPoissonCPD creates a log-linear Poisson CPD
+func NewPoissonCPD(variable string, parents []string, intercept float64, coefficients map[string]float64) (*PoissonCPD, error) {
+	for _, p := range parents {
+		if _, ok := coefficients[p]; !ok {
+			return nil, fmt.Errorf("missing coefficient for parent %s", p)
+		}
+	}
+	coefs := make(map[string]float64, len(coefficients))
+	for p, c := range coefficients {
+		coefs[p] = c
+	}
+	return &PoissonCPD{
+		Variable:     variable,
+		Parents:      append([]string(nil), parents...),
+		Intercept:    intercept,
+		Coefficients: coefs,
+	}, nil
+}
+
+// GetVariable returns the count variable
+func (cpd *PoissonCPD) GetVariable() string {
+	return cpd.Variable
+}
+
+// GetParents returns a copy of the CPD's parents
+func (cpd *PoissonCPD) GetParents() []string {
+	return append([]string(nil), cpd.Parents...)
+}
+
+// Rate returns the conditional mean λ given the parent values
+func (cpd *PoissonCPD) Rate(parentValues map[string]interface{}) (float64, error) {
+	eta, err := linearPredictor(cpd.Intercept, cpd.Coefficients, cpd.Parents, parentValues)
+	if err != nil {
+		return 0, err
+	}
+	return math.Exp(eta), nil
+}
+
+// Distribution returns Poisson(λ) for the conditional mean λ given the
+// parent values, drawing from rng. rng may be nil when the result is not
+// sampled.
+func (cpd *PoissonCPD) Distribution(parentValues map[string]interface{}, rng *rand.Rand) (Distribution, error) {
+	lambda, err := cpd.Rate(parentValues)
+	if err != nil {
+		return nil, err
+	}
+	return poisson{distuv.Poisson{Lambda: lambda, Src: source(rng)}}, nil
+}
+
+// Sample draws a count given the parent values
+func (cpd *PoissonCPD) Sample(parentValues map[string]interface{}, rng *rand.Rand) (float64, error) {
+	d, err := cpd.Distribution(parentValues, rng)
+	if err != nil {
+		return 0, err
+	}
+	return d.Rand(), nil
+}
+
+// LogPDF returns log P(X = x | parents). Values that are not non-negative
+// integers have zero probability.
+func (cpd *PoissonCPD) LogPDF(x float64, parentValues map[string]interface{}) (float64, error) {
+	d, err := cpd.Distribution(parentValues, nil)
+	if err != nil {
+		return 0, err
+	}
+	return d.LogProb(x), nil
+}
+
+// Copy creates a deep copy
+func (cpd *PoissonCPD) Copy() *PoissonCPD {
+	copied, _ := NewPoissonCPD(cpd.Variable, cpd.Parents, cpd.Intercept, cpd.Coefficients)
+	return copied
+}
+
+// String returns a string representation
+func (cpd *PoissonCPD) String() string {
+	return fmt.Sprintf("PoissonCPD(%s | %v)", cpd.Variable, cpd.Parents)
+}
+
+// linearPredictor returns β₀ + Σᵢ βᵢyᵢ over continuous parent values
+func linearPredictor(intercept float64, coefficients map[string]float64, parents []string,
+	parentValues map[string]interface{}) (float64, error) {
+	eta := intercept
+	for _, parent := range parents {
+		val, ok := parentValues[parent]
+		if !ok {
+			return 0, fmt.Errorf("missing parent value for %s", parent)
+		}
+		floatVal, ok := val.(float64)
+		if !ok {
+			return 0, fmt.Errorf("parent %s value must be float64", parent)
+		}
+		eta += coefficients[parent] * floatVal
+	}
+	return eta, nil
+}
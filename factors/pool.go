@@ -0,0 +1,55 @@
+package factors
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// minPooled is the smallest buffer, in entries, worth pooling
+const minPooled = 64
+
+// valuePools holds released value buffers by power-of-two capacity
+var valuePools [bits.UintSize]sync.Pool
+
+// getValues returns a buffer of n entries, reused from the pool if one of
+// its size class was released. Its contents are arbitrary: callers
+// overwrite every entry.
+func getValues(n int) []float64 {
+	if n < minPooled {
+		return make([]float64, n)
+	}
+	class := bits.Len(uint(n - 1))
+	if buf, ok := valuePools[class].Get().(*[]float64); ok {
+		return (*buf)[:n]
+	}
+	return make([]float64, n, 1<<class)
+}
+
+// putValues returns a buffer from getValues to the pool; others are left
+// to the garbage collector
+func putValues(values []float64) {
+	c := cap(values)
+	if c < minPooled || c&(c-1) != 0 {
+		return
+	}
+	values = values[:0]
+	valuePools[bits.Len(uint(c-1))].Put(&values)
+}
+
+// Release hands the factor's values back for reuse by later factor
+// operations and clears them, so the factor must not be used again.
+// Elimination releases the temporary products it builds; callers own every
+// factor an operation returns and may release those they discard, but must
+// never release a factor that is still shared.
+func (f *DiscreteFactor) Release() {
+	putValues(f.Values)
+	f.Values = nil
+	f.compiled = nil
+}
+
+// release releases f if its type supports it
+func release[F any](f F) {
+	if r, ok := any(f).(interface{ Release() }); ok {
+		r.Release()
+	}
+}
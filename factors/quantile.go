@@ -0,0 +1,41 @@
+package factors
+
+import (
+	"fmt"
+	"math"
+)
+
+// Quantile returns the p-quantile of one variable's marginal, for p in (0, 1)
+func (gf *GaussianFactor) Quantile(variable string, p float64) (float64, error) {
+	if _, ok := gf.index[variable]; !ok {
+		return 0, fmt.Errorf("variable %s is not in the factor", variable)
+	}
+	if !(p > 0 && p < 1) {
+		return 0, fmt.Errorf("quantile %v is not in (0, 1)", p)
+	}
+	sd := math.Sqrt(gf.CovarianceOf(variable, variable))
+	return gf.MeanOf(variable) + sd*math.Sqrt2*math.Erfinv(2*p-1), nil
+}
+
+// CDF returns P(variable <= x) under the factor's marginal of variable
+func (gf *GaussianFactor) CDF(variable string, x float64) (float64, error) {
+	if _, ok := gf.index[variable]; !ok {
+		return 0, fmt.Errorf("variable %s is not in the factor", variable)
+	}
+	sd := math.Sqrt(gf.CovarianceOf(variable, variable))
+	return 0.5 * math.Erfc(-(x-gf.MeanOf(variable))/(sd*math.Sqrt2)), nil
+}
+
+// CredibleInterval returns the equal-tailed interval holding the given
+// probability mass of one variable, e.g. level 0.95 for the 2.5% and 97.5%
+// quantiles
+func (gf *GaussianFactor) CredibleInterval(variable string, level float64) (lo, hi float64, err error) {
+	if !(level > 0 && level < 1) {
+		return 0, 0, fmt.Errorf("credible level %v is not in (0, 1)", level)
+	}
+	if lo, err = gf.Quantile(variable, (1-level)/2); err != nil {
+		return 0, 0, err
+	}
+	hi, err = gf.Quantile(variable, (1+level)/2)
+	return lo, hi, err
+}
@@ -0,0 +1,64 @@
+package factors
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Sample draws n assignments of the factor's variables with probability
+// proportional to the factor's values, which need not be normalized
+func (f *DiscreteFactor) Sample(n int, rng *rand.Rand) ([]map[string]int, error) {
+	cumulative := make([]float64, len(f.Values))
+	total := 0.0
+	for i, v := range f.Values {
+		if v < 0 {
+			return nil, fmt.Errorf("negative value %v at index %d: %w", v, i, ErrInvalidDistribution)
+		}
+		total += v
+		cumulative[i] = total
+	}
+	if total <= 0 {
+		return nil, ErrZeroProbability
+	}
+
+	samples := make([]map[string]int, n)
+	for i := range samples {
+		u := rng.Float64() * total
+		idx := sort.Search(len(cumulative), func(k int) bool { return cumulative[k] > u })
+		samples[i] = f.indexToAssignment(min(idx, len(cumulative)-1))
+	}
+	return samples, nil
+}
+
+// Sample draws n values of the factor's variables from N(μ, Σ), as μ + Lz
+// with Σ = LLᵀ and z standard normal
+func (gf *GaussianFactor) Sample(n int, rng *rand.Rand) ([]map[string]float64, error) {
+	chol, err := cholesky(gf.Covariance)
+	if err != nil {
+		return nil, err
+	}
+	var lower mat.TriDense
+	chol.LTo(&lower)
+
+	k := len(gf.Variables)
+	z := mat.NewVecDense(k, nil)
+	x := mat.NewVecDense(k, nil)
+	samples := make([]map[string]float64, n)
+	for i := range samples {
+		for j := 0; j < k; j++ {
+			z.SetVec(j, rng.NormFloat64())
+		}
+		x.MulVec(&lower, z)
+		x.AddVec(x, gf.Mean)
+
+		sample := make(map[string]float64, k)
+		for j, v := range gf.Variables {
+			sample[v] = x.AtVec(j)
+		}
+		samples[i] = sample
+	}
+	return samples, nil
+}
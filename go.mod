@@ -1,3 +1,21 @@
 module github.com/JohnPierman/bngo
 
 go 1.23.1
+
+require (
+	github.com/parquet-go/parquet-go v0.25.1
+	gonum.org/v1/gonum v0.16.0
+	google.golang.org/grpc v1.67.3
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	golang.org/x/net v0.28.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240814211410-ddb44dafa142 // indirect
+)
@@ -49,7 +49,7 @@ func (d *DAG) AddEdge(parent, child string) error {
 
 	// Check if adding this edge would create a cycle
 	if d.wouldCreateCycle(parent, child) {
-		return fmt.Errorf("adding edge %s -> %s would create a cycle", parent, child)
+		return fmt.Errorf("adding edge %s -> %s: %w", parent, child, ErrCycle)
 	}
 
 	d.edges[parent][child] = true
@@ -67,6 +67,24 @@ func (d *DAG) RemoveEdge(parent, child string) {
 	}
 }
 
+// RemoveNode removes a node and all its incident edges
+func (d *DAG) RemoveNode(node string) {
+	for child := range d.edges[node] {
+		delete(d.parents[child], node)
+	}
+	for parent := range d.parents[node] {
+		delete(d.edges[parent], node)
+	}
+	delete(d.edges, node)
+	delete(d.parents, node)
+	delete(d.nodes, node)
+}
+
+// HasNode checks if a node exists
+func (d *DAG) HasNode(node string) bool {
+	return d.nodes[node]
+}
+
 // HasEdge checks if an edge exists
 func (d *DAG) HasEdge(parent, child string) bool {
 	if d.edges[parent] == nil {
@@ -213,7 +231,7 @@ func (d *DAG) TopologicalSort() ([]string, error) {
 	}
 
 	if len(result) != len(d.nodes) {
-		return nil, fmt.Errorf("cycle detected in graph")
+		return nil, ErrCycle
 	}
 
 	return result, nil
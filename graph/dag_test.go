@@ -104,3 +104,40 @@ func TestDAGAncestorsDescendants(t *testing.T) {
 		t.Errorf("Expected 3 descendants, got %d", len(descendants))
 	}
 }
+
+func TestDAGRemoveNode(t *testing.T) {
+	dag, _ := NewDAGFromEdges([][2]string{{"A", "B"}, {"B", "C"}, {"A", "C"}})
+	dag.RemoveNode("B")
+
+	if dag.HasNode("B") {
+		t.Error("B should be removed")
+	}
+	if len(dag.Children("A")) != 1 || len(dag.Parents("C")) != 1 {
+		t.Errorf("Edges through B should be removed, got %v", dag.Edges())
+	}
+	if !dag.HasEdge("A", "C") {
+		t.Error("Edge A->C should remain")
+	}
+}
+
+func TestDSeparation(t *testing.T) {
+	// A -> C <- B, C -> D
+	dag, _ := NewDAGFromEdges([][2]string{{"A", "C"}, {"B", "C"}, {"C", "D"}})
+
+	tests := []struct {
+		x, y      string
+		z         []string
+		separated bool
+	}{
+		{"A", "B", nil, true},
+		{"A", "B", []string{"C"}, false},
+		{"A", "B", []string{"D"}, false},
+		{"A", "D", nil, false},
+		{"A", "D", []string{"C"}, true},
+	}
+	for _, tt := range tests {
+		if got := dag.IsDSeparated(tt.x, tt.y, tt.z); got != tt.separated {
+			t.Errorf("IsDSeparated(%s, %s | %v) = %v, want %v", tt.x, tt.y, tt.z, got, tt.separated)
+		}
+	}
+}
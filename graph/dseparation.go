@@ -0,0 +1,72 @@
+package graph
+
+// IsDSeparated reports whether x and y are d-separated given z: every path
+// between them is blocked by z. It follows the reachability ("Bayes ball")
+// procedure, which runs in time linear in the size of the graph.
+func (d *DAG) IsDSeparated(x, y string, z []string) bool {
+	observed := make(map[string]bool, len(z))
+	for _, v := range z {
+		observed[v] = true
+	}
+	if observed[x] || observed[y] {
+		return true
+	}
+
+	// A collider passes the ball on when it or one of its descendants is
+	// observed, i.e. when it is in the ancestral set of z
+	activeCollider := make(map[string]bool, len(z))
+	for _, v := range z {
+		activeCollider[v] = true
+		for _, a := range d.Ancestors(v) {
+			activeCollider[a] = true
+		}
+	}
+
+	type visit struct {
+		node string
+		up   bool // reached from a child, moving against edge direction
+	}
+	visited := make(map[visit]bool)
+	queue := []visit{{x, true}}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		node := current.node
+		if node == y {
+			return false
+		}
+
+		if current.up {
+			if observed[node] {
+				continue
+			}
+			for parent := range d.parents[node] {
+				queue = append(queue, visit{parent, true})
+			}
+			for child := range d.edges[node] {
+				queue = append(queue, visit{child, false})
+			}
+			continue
+		}
+
+		// Reached from a parent
+		if !observed[node] {
+			for child := range d.edges[node] {
+				queue = append(queue, visit{child, false})
+			}
+		}
+		if activeCollider[node] {
+			for parent := range d.parents[node] {
+				queue = append(queue, visit{parent, true})
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,7 @@
+package graph
+
+import "errors"
+
+// ErrCycle is returned when an operation would make a DAG cyclic, or a graph
+// expected to be acyclic is not
+var ErrCycle = errors.New("cycle in graph")
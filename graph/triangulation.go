@@ -0,0 +1,285 @@
+package graph
+
+import "sort"
+
+// EliminationHeuristic selects the next node to eliminate when building an
+// elimination ordering
+type EliminationHeuristic int
+
+const (
+	// MinFill eliminates the node that adds the fewest fill-in edges
+	MinFill EliminationHeuristic = iota
+	// MinDegree eliminates the node with the fewest remaining neighbors
+	MinDegree
+)
+
+// EliminationOrder returns a greedy elimination ordering of the nodes.
+// Ties are broken lexicographically so the ordering is deterministic.
+func (g *UndirectedGraph) EliminationOrder(heuristic EliminationHeuristic) []string {
+	order, _ := g.eliminate(heuristic)
+	return order
+}
+
+// Triangulate returns a chordal copy of the graph obtained by adding the
+// fill-in edges of a greedy elimination ordering, along with that ordering
+func (g *UndirectedGraph) Triangulate(heuristic EliminationHeuristic) (*UndirectedGraph, []string) {
+	order, fill := g.eliminate(heuristic)
+
+	chordal := g.Copy()
+	for _, edge := range fill {
+		chordal.AddEdge(edge[0], edge[1])
+	}
+	return chordal, order
+}
+
+// eliminate simulates node elimination and returns the ordering and fill-in edges
+func (g *UndirectedGraph) eliminate(heuristic EliminationHeuristic) ([]string, [][2]string) {
+	work := g.Copy()
+	remaining := work.Nodes()
+	order := make([]string, 0, len(remaining))
+	fill := make([][2]string, 0)
+
+	for len(remaining) > 0 {
+		best := -1
+		bestScore := 0
+		for i, node := range remaining {
+			var score int
+			if heuristic == MinDegree {
+				score = len(work.edges[node])
+			} else {
+				score = work.fillIn(node)
+			}
+			if best == -1 || score < bestScore {
+				best = i
+				bestScore = score
+			}
+		}
+
+		node := remaining[best]
+		neighbors := work.Neighbors(node)
+		for i := 0; i < len(neighbors); i++ {
+			for j := i + 1; j < len(neighbors); j++ {
+				if !work.HasEdge(neighbors[i], neighbors[j]) {
+					work.AddEdge(neighbors[i], neighbors[j])
+					fill = append(fill, [2]string{neighbors[i], neighbors[j]})
+				}
+			}
+		}
+		work.removeNode(node)
+
+		order = append(order, node)
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+
+	return order, fill
+}
+
+// fillIn counts the edges needed to make a node's neighbors a clique
+func (g *UndirectedGraph) fillIn(node string) int {
+	neighbors := g.Neighbors(node)
+	count := 0
+	for i := 0; i < len(neighbors); i++ {
+		for j := i + 1; j < len(neighbors); j++ {
+			if !g.HasEdge(neighbors[i], neighbors[j]) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// removeNode removes a node and all its incident edges
+func (g *UndirectedGraph) removeNode(node string) {
+	for neighbor := range g.edges[node] {
+		delete(g.edges[neighbor], node)
+	}
+	delete(g.edges, node)
+	delete(g.nodes, node)
+}
+
+// IsChordal reports whether every cycle of length four or more has a chord.
+// It uses maximum cardinality search followed by a perfect elimination check.
+func (g *UndirectedGraph) IsChordal() bool {
+	nodes := g.Nodes()
+	weight := make(map[string]int, len(nodes))
+	numbered := make(map[string]bool, len(nodes))
+	position := make(map[string]int, len(nodes))
+	order := make([]string, 0, len(nodes))
+
+	// Maximum cardinality search: visit the node with most visited neighbors
+	for len(order) < len(nodes) {
+		best := ""
+		for _, node := range nodes {
+			if numbered[node] {
+				continue
+			}
+			if best == "" || weight[node] > weight[best] {
+				best = node
+			}
+		}
+		numbered[best] = true
+		position[best] = len(order)
+		order = append(order, best)
+		for neighbor := range g.edges[best] {
+			if !numbered[neighbor] {
+				weight[neighbor]++
+			}
+		}
+	}
+
+	// The reverse of the visit order must be a perfect elimination ordering:
+	// a node's earlier-visited neighbors must all be adjacent to the latest of them
+	for _, node := range order {
+		earlier := make([]string, 0)
+		for neighbor := range g.edges[node] {
+			if position[neighbor] < position[node] {
+				earlier = append(earlier, neighbor)
+			}
+		}
+		if len(earlier) < 2 {
+			continue
+		}
+
+		latest := earlier[0]
+		for _, n := range earlier {
+			if position[n] > position[latest] {
+				latest = n
+			}
+		}
+		for _, n := range earlier {
+			if n != latest && !g.HasEdge(n, latest) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// MaxCliques returns all maximal cliques of the graph, each sorted, using
+// Bron-Kerbosch with pivoting. On chordal graphs there are at most as many
+// cliques as nodes, so this is the usual step after Triangulate.
+func (g *UndirectedGraph) MaxCliques() [][]string {
+	cliques := make([][]string, 0)
+
+	candidates := make(map[string]bool, len(g.nodes))
+	for node := range g.nodes {
+		candidates[node] = true
+	}
+	g.bronKerbosch(map[string]bool{}, candidates, map[string]bool{}, &cliques)
+
+	sort.Slice(cliques, func(i, j int) bool {
+		a, b := cliques[i], cliques[j]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return cliques
+}
+
+func (g *UndirectedGraph) bronKerbosch(r, p, x map[string]bool, cliques *[][]string) {
+	if len(p) == 0 && len(x) == 0 {
+		clique := make([]string, 0, len(r))
+		for node := range r {
+			clique = append(clique, node)
+		}
+		sort.Strings(clique)
+		*cliques = append(*cliques, clique)
+		return
+	}
+
+	// Choose the pivot with most neighbors in P to minimize branching
+	pivot := ""
+	pivotCount := -1
+	for _, set := range []map[string]bool{p, x} {
+		for u := range set {
+			count := 0
+			for v := range g.edges[u] {
+				if p[v] {
+					count++
+				}
+			}
+			if count > pivotCount {
+				pivot = u
+				pivotCount = count
+			}
+		}
+	}
+
+	branch := make([]string, 0, len(p))
+	for v := range p {
+		if !g.edges[pivot][v] {
+			branch = append(branch, v)
+		}
+	}
+	sort.Strings(branch)
+
+	for _, v := range branch {
+		newR := make(map[string]bool, len(r)+1)
+		for n := range r {
+			newR[n] = true
+		}
+		newR[v] = true
+
+		newP := make(map[string]bool)
+		newX := make(map[string]bool)
+		for n := range g.edges[v] {
+			if p[n] {
+				newP[n] = true
+			}
+			if x[n] {
+				newX[n] = true
+			}
+		}
+
+		g.bronKerbosch(newR, newP, newX, cliques)
+		delete(p, v)
+		x[v] = true
+	}
+}
+
+// InducedWidth returns the width of eliminating the nodes in the given order:
+// the largest number of neighbors any node has at the time it is eliminated.
+// Nodes missing from the order are eliminated last in lexicographic order.
+func (g *UndirectedGraph) InducedWidth(order []string) int {
+	work := g.Copy()
+	seen := make(map[string]bool, len(order))
+	full := make([]string, 0, len(g.nodes))
+	for _, node := range order {
+		if g.nodes[node] && !seen[node] {
+			seen[node] = true
+			full = append(full, node)
+		}
+	}
+	for _, node := range g.Nodes() {
+		if !seen[node] {
+			full = append(full, node)
+		}
+	}
+
+	width := 0
+	for _, node := range full {
+		neighbors := work.Neighbors(node)
+		if len(neighbors) > width {
+			width = len(neighbors)
+		}
+		for i := 0; i < len(neighbors); i++ {
+			for j := i + 1; j < len(neighbors); j++ {
+				work.AddEdge(neighbors[i], neighbors[j])
+			}
+		}
+		work.removeNode(node)
+	}
+	return width
+}
+
+// EstimateTreewidth returns an upper bound on the treewidth of the DAG's
+// moral graph using a greedy min-fill elimination ordering. Exact inference
+// cost grows exponentially in this number.
+func (d *DAG) EstimateTreewidth() int {
+	moral := d.MoralGraph()
+	return moral.InducedWidth(moral.EliminationOrder(MinFill))
+}
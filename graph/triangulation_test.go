@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"testing"
+)
+
+func TestTriangulateCycle(t *testing.T) {
+	// A 4-cycle A-B-C-D-A is not chordal
+	ug := NewUndirectedGraph()
+	ug.AddEdge("A", "B")
+	ug.AddEdge("B", "C")
+	ug.AddEdge("C", "D")
+	ug.AddEdge("D", "A")
+
+	if ug.IsChordal() {
+		t.Error("4-cycle should not be chordal")
+	}
+
+	chordal, order := ug.Triangulate(MinFill)
+	if len(order) != 4 {
+		t.Errorf("Expected elimination order over 4 nodes, got %v", order)
+	}
+	if !chordal.IsChordal() {
+		t.Error("Triangulated graph should be chordal")
+	}
+	if len(chordal.Edges()) != 5 {
+		t.Errorf("Expected one fill-in edge, got %d edges", len(chordal.Edges()))
+	}
+
+	cliques := chordal.MaxCliques()
+	if len(cliques) != 2 {
+		t.Fatalf("Expected 2 maximal cliques, got %v", cliques)
+	}
+	for _, c := range cliques {
+		if len(c) != 3 {
+			t.Errorf("Expected cliques of size 3, got %v", c)
+		}
+	}
+}
+
+func TestMoralGraphCliques(t *testing.T) {
+	dag := NewDAG()
+	_ = dag.AddEdge("A", "C")
+	_ = dag.AddEdge("B", "C")
+	_ = dag.AddEdge("C", "D")
+
+	moral := dag.MoralGraph()
+	if !moral.IsChordal() {
+		t.Error("Moral graph of a v-structure chain should be chordal")
+	}
+
+	cliques := moral.MaxCliques()
+	if len(cliques) != 2 {
+		t.Fatalf("Expected cliques {A,B,C} and {C,D}, got %v", cliques)
+	}
+	if len(cliques[0]) != 3 || cliques[1][0] != "C" || cliques[1][1] != "D" {
+		t.Errorf("Unexpected cliques %v", cliques)
+	}
+}
+
+func TestEstimateTreewidth(t *testing.T) {
+	chain := NewDAG()
+	_ = chain.AddEdge("A", "B")
+	_ = chain.AddEdge("B", "C")
+	_ = chain.AddEdge("C", "D")
+	if w := chain.EstimateTreewidth(); w != 1 {
+		t.Errorf("Expected treewidth 1 for a chain, got %d", w)
+	}
+
+	// Three parents of one child are married into a 4-clique
+	collider := NewDAG()
+	_ = collider.AddEdge("A", "D")
+	_ = collider.AddEdge("B", "D")
+	_ = collider.AddEdge("C", "D")
+	if w := collider.EstimateTreewidth(); w != 3 {
+		t.Errorf("Expected treewidth 3, got %d", w)
+	}
+}
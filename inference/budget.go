@@ -0,0 +1,207 @@
+package inference
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// bytesPerEntry is the memory of one discrete factor table entry
+const bytesPerEntry = 8
+
+// BudgetExceededError reports a query whose elimination would build a factor
+// larger than MaxFactorSize or hold more factor memory than MaxMemory. The
+// sizes are predicted from the factor scopes before anything is allocated.
+type BudgetExceededError struct {
+	FactorSize    int   // Entries of the largest factor the elimination builds
+	MaxFactorSize int   // Limit on FactorSize, 0 if unlimited
+	Memory        int64 // Peak bytes of factor tables held at once
+	MaxMemory     int64 // Limit on Memory, 0 if unlimited
+}
+
+func (e *BudgetExceededError) Error() string {
+	if e.MaxFactorSize > 0 && e.FactorSize > e.MaxFactorSize {
+		return fmt.Sprintf("largest factor of %d entries exceeds limit %d", e.FactorSize, e.MaxFactorSize)
+	}
+	return fmt.Sprintf("peak factor memory of %d bytes exceeds limit %d", e.Memory, e.MaxMemory)
+}
+
+// ApproximateEngine answers discrete queries approximately. A
+// VariableElimination with a memory budget hands it the queries that would
+// exceed the budget.
+type ApproximateEngine interface {
+	// Query returns an estimate of P(variables | evidence)
+	Query(variables []string, evidence map[string]int) (*factors.DiscreteFactor, error)
+}
+
+var _ ApproximateEngine = (*LikelihoodWeightingEngine)(nil)
+
+// SetMemoryBudget bounds the entries of any factor a query builds and the
+// bytes of factor tables it holds at once; a limit of 0 disables that check.
+// A query over budget is answered by fallback, or fails with a
+// *BudgetExceededError if fallback is nil. MAP queries always fail.
+func (ve *VariableElimination) SetMemoryBudget(maxFactorSize int, maxMemory int64, fallback ApproximateEngine) {
+	ve.MaxFactorSize = maxFactorSize
+	ve.MaxMemory = maxMemory
+	ve.Fallback = fallback
+}
+
+// checkBudget predicts the cost of eliminating order from the factors and
+// then multiplying the rest, and compares it with the engine's budget
+func (ve *VariableElimination) checkBudget(factorList []*factors.DiscreteFactor, order []string) error {
+	if ve.MaxFactorSize <= 0 && ve.MaxMemory <= 0 {
+		return nil
+	}
+
+	largest, peak := eliminationCost(factorList, order)
+	memory := peak * bytesPerEntry
+	if (ve.MaxFactorSize <= 0 || largest <= float64(ve.MaxFactorSize)) &&
+		(ve.MaxMemory <= 0 || memory <= float64(ve.MaxMemory)) {
+		return nil
+	}
+	return &BudgetExceededError{
+		FactorSize:    int(math.Min(largest, math.MaxInt)),
+		MaxFactorSize: ve.MaxFactorSize,
+		Memory:        int64(math.Min(memory, math.MaxInt64)),
+		MaxMemory:     ve.MaxMemory,
+	}
+}
+
+// fallBack answers a query over budget with the fallback engine, if any
+func (ve *VariableElimination) fallBack(err error, variables []string, evidence map[string]int,
+	stats *QueryStats) (*factors.DiscreteFactor, error) {
+	var budget *BudgetExceededError
+	if ve.Fallback == nil || !errors.As(err, &budget) {
+		return nil, err
+	}
+	if stats != nil {
+		stats.Approximate = true
+	}
+	return ve.Fallback.Query(variables, evidence)
+}
+
+// eliminationCost simulates variable elimination on the factor scopes alone,
+// returning the entries of the largest product it builds and the peak
+// entries held at once. Counts are floats so that intractable queries
+// report huge sizes rather than overflow.
+func eliminationCost(factorList []*factors.DiscreteFactor, order []string) (largest, peak float64) {
+	type scope map[string]bool
+	cardinality := make(map[string]int)
+	scopes := make([]scope, len(factorList))
+	size := func(s scope) float64 {
+		n := 1.0
+		for v := range s {
+			n *= float64(cardinality[v])
+		}
+		return n
+	}
+	live := 0.0
+	for i, f := range factorList {
+		scopes[i] = make(scope, len(f.Variables))
+		for _, v := range f.Variables {
+			scopes[i][v] = true
+			cardinality[v] = f.Cardinality[v]
+		}
+		live += size(scopes[i])
+	}
+	largest, peak = 0, live
+
+	// product merges the given scopes, charging the product to the peak
+	product := func(merged []scope) scope {
+		union := make(scope)
+		for _, s := range merged {
+			live -= size(s)
+			for v := range s {
+				union[v] = true
+			}
+		}
+		n := size(union)
+		largest = math.Max(largest, n)
+		peak = math.Max(peak, live+n)
+		return union
+	}
+
+	for _, v := range order {
+		var relevant, rest []scope
+		for _, s := range scopes {
+			if s[v] {
+				relevant = append(relevant, s)
+			} else {
+				rest = append(rest, s)
+			}
+		}
+		if len(relevant) == 0 {
+			continue
+		}
+		result := product(relevant)
+		delete(result, v)
+		live += size(result)
+		scopes = append(rest, result)
+	}
+	if len(scopes) > 0 {
+		product(scopes)
+	}
+	return largest, peak
+}
+
+// LikelihoodWeightingEngine answers discrete queries approximately from
+// likelihood-weighted samples, in time linear in the network size
+type LikelihoodWeightingEngine struct {
+	Model   *models.BayesianNetwork
+	Samples int   // Weighted samples drawn per query
+	Seed    int64 // Seed of every query's sampler
+}
+
+// NewLikelihoodWeightingEngine creates an engine drawing the given number of
+// samples per query
+func NewLikelihoodWeightingEngine(model *models.BayesianNetwork, samples int, seed int64) (*LikelihoodWeightingEngine, error) {
+	if samples <= 0 {
+		return nil, fmt.Errorf("likelihood weighting needs a positive number of samples, got %d", samples)
+	}
+	if err := model.CheckModel(); err != nil {
+		return nil, err
+	}
+	return &LikelihoodWeightingEngine{Model: model, Samples: samples, Seed: seed}, nil
+}
+
+// Query estimates P(variables | evidence) as the weighted frequencies of
+// the joint states of variables among the samples
+func (e *LikelihoodWeightingEngine) Query(variables []string, evidence map[string]int) (*factors.DiscreteFactor, error) {
+	cardinality := make(map[string]int, len(variables))
+	size := 1
+	for _, v := range variables {
+		if !e.Model.DAG.HasNode(v) {
+			return nil, fmt.Errorf("query variable %s: %w", v, models.ErrUnknownVariable)
+		}
+		if !e.Model.IsDiscrete(v) {
+			return nil, fmt.Errorf("query variable %s is not discrete: %w", v, ErrUnsupportedVariable)
+		}
+		cardinality[v] = e.Model.Cardinality[v]
+		size *= cardinality[v]
+	}
+
+	samples, err := e.Model.LikelihoodWeighting(e.Samples, models.Sample{Discrete: evidence}, e.Seed)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float64, size)
+	for _, s := range samples {
+		index := 0
+		for _, v := range variables {
+			index = index*cardinality[v] + s.Discrete[v]
+		}
+		values[index] += s.Weight
+	}
+
+	result, err := factors.NewDiscreteFactor(append([]string(nil), variables...), cardinality, values)
+	if err != nil {
+		return nil, err
+	}
+	if err := result.Normalize(); err != nil {
+		return nil, fmt.Errorf("no sample is consistent with the evidence: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,417 @@
+package inference
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/JohnPierman/bngo/graph"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// circuitKind is the operation of an arithmetic circuit node
+type circuitKind uint8
+
+const (
+	circuitConstant  circuitKind = iota // A CPD parameter, or the constants 0 and 1
+	circuitIndicator                    // Evidence indicator λ of one state of one variable
+	circuitSum
+	circuitProduct
+)
+
+// circuitNode is one node of an arithmetic circuit. Its children are
+// children[start:end] of the circuit and always come before it.
+type circuitNode struct {
+	kind       circuitKind
+	value      float64 // Value of a constant
+	start, end int32
+}
+
+// ArithmeticCircuit is a discrete network compiled into its network
+// polynomial f(λ) = Σₓ Πᵥ λᵥ θᵥ|ᵤ, with an indicator λ for each state of each
+// variable. Setting the indicators from evidence and evaluating the circuit
+// gives P(e), and one backward pass of derivatives gives P(v=s, e) for every
+// variable and state at once, so each query costs time linear in the circuit
+// size however the evidence changes. Compiling costs as much as one variable
+// elimination query without evidence; the circuit is immutable afterwards and
+// safe to evaluate from many goroutines.
+type ArithmeticCircuit struct {
+	nodes       []circuitNode
+	children    []int32
+	root        int32
+	variables   []string           // Sorted
+	cardinality map[string]int     // By variable
+	indicators  map[string][]int32 // Indicator node of each state, by variable
+
+	scratch sync.Pool // *circuitScratch buffers for evaluation
+}
+
+// circuitScratch holds the values and derivatives of one evaluation
+type circuitScratch struct {
+	values, derivatives []float64
+}
+
+// CompileCircuit compiles a discrete network into an arithmetic circuit by
+// eliminating its variables symbolically in min-fill order. Later changes to
+// bn do not affect the circuit.
+func CompileCircuit(bn *models.BayesianNetwork) (*ArithmeticCircuit, error) {
+	if err := bn.CheckModel(); err != nil {
+		return nil, err
+	}
+	for _, node := range bn.Nodes() {
+		if !bn.IsDiscrete(node) {
+			return nil, fmt.Errorf("node %s: arithmetic circuits are discrete: %w", node, models.ErrContinuousVariables)
+		}
+	}
+
+	ac := &ArithmeticCircuit{
+		variables:   bn.Nodes(),
+		cardinality: make(map[string]int),
+		indicators:  make(map[string][]int32),
+	}
+	sort.Strings(ac.variables)
+	b := &circuitBuilder{ac: ac}
+	b.zero = b.add(circuitNode{kind: circuitConstant, value: 0})
+	b.one = b.add(circuitNode{kind: circuitConstant, value: 1})
+
+	// Each CPD factor multiplied by the indicators of its child
+	list := make([]*symbolicFactor, 0, len(ac.variables))
+	for _, v := range ac.variables {
+		factor, err := bn.NodeFactor(v)
+		if err != nil {
+			return nil, err
+		}
+		card := factor.Cardinality[v]
+		ac.cardinality[v] = card
+		indicator := &symbolicFactor{variables: []string{v}, cardinality: map[string]int{v: card}, entries: make([]int32, card)}
+		for s := range indicator.entries {
+			indicator.entries[s] = b.add(circuitNode{kind: circuitIndicator})
+		}
+		ac.indicators[v] = indicator.entries
+
+		cpd := &symbolicFactor{variables: factor.Variables, cardinality: factor.Cardinality, entries: make([]int32, len(factor.Values))}
+		for i, p := range factor.Values {
+			cpd.entries[i] = b.constant(p)
+		}
+		list = append(list, b.multiply([]*symbolicFactor{cpd, indicator}))
+	}
+
+	for _, v := range bn.DAG.MoralGraph().EliminationOrder(graph.MinFill) {
+		var relevant, rest []*symbolicFactor
+		for _, f := range list {
+			if _, ok := f.cardinality[v]; ok {
+				relevant = append(relevant, f)
+			} else {
+				rest = append(rest, f)
+			}
+		}
+		if len(relevant) == 0 {
+			continue
+		}
+		list = append(rest, b.sumOut(b.multiply(relevant), v))
+	}
+
+	// Every variable is summed out, leaving constants to multiply
+	ac.root = b.multiply(list).entries[0]
+	return ac, nil
+}
+
+// Size returns the number of nodes and edges of the circuit
+func (ac *ArithmeticCircuit) Size() (nodes, edges int) {
+	return len(ac.nodes), len(ac.children)
+}
+
+// Probability returns P(evidence)
+func (ac *ArithmeticCircuit) Probability(evidence map[string]int) (float64, error) {
+	s, err := ac.evaluate(evidence, false)
+	if err != nil {
+		return 0, err
+	}
+	defer ac.scratch.Put(s)
+	return s.values[ac.root], nil
+}
+
+// Marginals returns P(v | evidence) for every variable v of the network,
+// from a single forward and backward pass. Observed variables get a point
+// mass on their observed state.
+func (ac *ArithmeticCircuit) Marginals(evidence map[string]int) (map[string][]float64, error) {
+	s, err := ac.evaluate(evidence, true)
+	if err != nil {
+		return nil, err
+	}
+	defer ac.scratch.Put(s)
+	pe := s.values[ac.root]
+	if pe == 0 {
+		return nil, fmt.Errorf("evidence has zero probability: %w", ErrImpossibleEvidence)
+	}
+
+	marginals := make(map[string][]float64, len(ac.variables))
+	for _, v := range ac.variables {
+		marginal := make([]float64, ac.cardinality[v])
+		if state, ok := evidence[v]; ok {
+			marginal[state] = 1
+		} else {
+			// ∂f/∂λᵥₛ = P(v=s, e) for an unobserved v
+			for state, node := range ac.indicators[v] {
+				marginal[state] = s.derivatives[node] / pe
+			}
+		}
+		marginals[v] = marginal
+	}
+	return marginals, nil
+}
+
+// evaluate sets the indicators from evidence and computes every node's value,
+// and with derivatives also ∂f/∂n for every node n. The caller returns the
+// scratch buffers to the pool.
+func (ac *ArithmeticCircuit) evaluate(evidence map[string]int, derivatives bool) (*circuitScratch, error) {
+	for v, state := range evidence {
+		card, ok := ac.cardinality[v]
+		if !ok {
+			return nil, fmt.Errorf("evidence variable %s: %w", v, models.ErrUnknownVariable)
+		}
+		if state < 0 || state >= card {
+			return nil, fmt.Errorf("evidence %s=%d, but it has %d states: %w", v, state, card, ErrInvalidState)
+		}
+	}
+
+	s, _ := ac.scratch.Get().(*circuitScratch)
+	if s == nil {
+		s = &circuitScratch{values: make([]float64, len(ac.nodes)), derivatives: make([]float64, len(ac.nodes))}
+	}
+	values := s.values
+	for v, nodes := range ac.indicators {
+		state, observed := evidence[v]
+		for k, node := range nodes {
+			if !observed || k == state {
+				values[node] = 1
+			} else {
+				values[node] = 0
+			}
+		}
+	}
+	for i, n := range ac.nodes {
+		switch n.kind {
+		case circuitConstant:
+			values[i] = n.value
+		case circuitSum:
+			total := 0.0
+			for _, c := range ac.children[n.start:n.end] {
+				total += values[c]
+			}
+			values[i] = total
+		case circuitProduct:
+			product := 1.0
+			for _, c := range ac.children[n.start:n.end] {
+				product *= values[c]
+			}
+			values[i] = product
+		}
+	}
+	if !derivatives {
+		return s, nil
+	}
+
+	// Backward pass. The derivative of a product with respect to one child
+	// is the product of the others, found without dividing by zero from the
+	// product of the non-zero children and the number of zero ones.
+	d := s.derivatives
+	for i := range d {
+		d[i] = 0
+	}
+	d[ac.root] = 1
+	for i := len(ac.nodes) - 1; i >= 0; i-- {
+		n := ac.nodes[i]
+		if d[i] == 0 {
+			continue
+		}
+		switch n.kind {
+		case circuitSum:
+			for _, c := range ac.children[n.start:n.end] {
+				d[c] += d[i]
+			}
+		case circuitProduct:
+			nonZero, zeros := 1.0, 0
+			for _, c := range ac.children[n.start:n.end] {
+				if values[c] == 0 {
+					zeros++
+				} else {
+					nonZero *= values[c]
+				}
+			}
+			if zeros > 1 {
+				continue
+			}
+			for _, c := range ac.children[n.start:n.end] {
+				switch {
+				case zeros == 0:
+					d[c] += d[i] * nonZero / values[c]
+				case values[c] == 0:
+					d[c] += d[i] * nonZero
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+// symbolicFactor is a factor whose entries are circuit nodes
+type symbolicFactor struct {
+	variables   []string
+	cardinality map[string]int
+	entries     []int32
+}
+
+// circuitBuilder grows a circuit, folding multiplications by 0 and 1 and
+// additions of 0
+type circuitBuilder struct {
+	ac        *ArithmeticCircuit
+	zero, one int32
+}
+
+func (b *circuitBuilder) add(n circuitNode, children ...int32) int32 {
+	n.start = int32(len(b.ac.children))
+	b.ac.children = append(b.ac.children, children...)
+	n.end = int32(len(b.ac.children))
+	b.ac.nodes = append(b.ac.nodes, n)
+	return int32(len(b.ac.nodes) - 1)
+}
+
+func (b *circuitBuilder) constant(value float64) int32 {
+	switch value {
+	case 0:
+		return b.zero
+	case 1:
+		return b.one
+	}
+	return b.add(circuitNode{kind: circuitConstant, value: value})
+}
+
+func (b *circuitBuilder) product(children []int32) int32 {
+	kept := make([]int32, 0, len(children))
+	for _, c := range children {
+		if c == b.zero {
+			return b.zero
+		}
+		if c != b.one {
+			kept = append(kept, c)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return b.one
+	case 1:
+		return kept[0]
+	}
+	return b.add(circuitNode{kind: circuitProduct}, kept...)
+}
+
+func (b *circuitBuilder) sum(children []int32) int32 {
+	kept := make([]int32, 0, len(children))
+	for _, c := range children {
+		if c != b.zero {
+			kept = append(kept, c)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return b.zero
+	case 1:
+		return kept[0]
+	}
+	return b.add(circuitNode{kind: circuitSum}, kept...)
+}
+
+// multiply returns the symbolic product of the factors, one product node
+// per assignment of the union of their variables
+func (b *circuitBuilder) multiply(list []*symbolicFactor) *symbolicFactor {
+	if len(list) == 1 {
+		return list[0]
+	}
+	out := &symbolicFactor{cardinality: make(map[string]int)}
+	for _, f := range list {
+		for _, v := range f.variables {
+			if _, ok := out.cardinality[v]; !ok {
+				out.cardinality[v] = f.cardinality[v]
+				out.variables = append(out.variables, v)
+			}
+		}
+	}
+	size := 1
+	for _, v := range out.variables {
+		size *= out.cardinality[v]
+	}
+
+	// Stride of each output variable in each input factor, zero if absent
+	strides := make([][]int, len(list))
+	for k, f := range list {
+		strides[k] = make([]int, len(out.variables))
+		stride := 1
+		for i := len(f.variables) - 1; i >= 0; i-- {
+			for j, v := range out.variables {
+				if v == f.variables[i] {
+					strides[k][j] = stride
+				}
+			}
+			stride *= f.cardinality[f.variables[i]]
+		}
+	}
+
+	out.entries = make([]int32, size)
+	assignment := make([]int, len(out.variables))
+	children := make([]int32, len(list))
+	for idx := 0; idx < size; idx++ {
+		for k, f := range list {
+			offset := 0
+			for j, s := range assignment {
+				offset += s * strides[k][j]
+			}
+			children[k] = f.entries[offset]
+		}
+		out.entries[idx] = b.product(children)
+		for j := len(assignment) - 1; j >= 0; j-- {
+			assignment[j]++
+			if assignment[j] < out.cardinality[out.variables[j]] {
+				break
+			}
+			assignment[j] = 0
+		}
+	}
+	return out
+}
+
+// sumOut returns the symbolic factor with variable summed out, one sum node
+// per assignment of the remaining variables
+func (b *circuitBuilder) sumOut(f *symbolicFactor, variable string) *symbolicFactor {
+	pos := 0
+	for i, v := range f.variables {
+		if v == variable {
+			pos = i
+		}
+	}
+	card := f.cardinality[variable]
+	inner := 1
+	for _, v := range f.variables[pos+1:] {
+		inner *= f.cardinality[v]
+	}
+	outer := len(f.entries) / (card * inner)
+
+	out := &symbolicFactor{cardinality: make(map[string]int, len(f.variables)-1)}
+	for _, v := range f.variables {
+		if v != variable {
+			out.variables = append(out.variables, v)
+			out.cardinality[v] = f.cardinality[v]
+		}
+	}
+	out.entries = make([]int32, 0, outer*inner)
+	children := make([]int32, card)
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			for s := 0; s < card; s++ {
+				children[s] = f.entries[(o*card+s)*inner+i]
+			}
+			out.entries = append(out.entries, b.sum(children))
+		}
+	}
+	return out
+}
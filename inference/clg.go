@@ -0,0 +1,433 @@
+package inference
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+	"gonum.org/v1/gonum/mat"
+)
+
+// MixtureComponent is the posterior for one assignment of the discrete query
+// variables in a mixed query
+type MixtureComponent struct {
+	Discrete map[string]int          // Assignment of the discrete query variables
+	Weight   float64                 // Posterior probability of the assignment
+	Gaussian *factors.GaussianFactor // Continuous query variables given the assignment, nil if none were queried
+}
+
+// MixedQueryResult is the posterior of a query over discrete and continuous
+// variables, as a mixture with one component per discrete configuration
+type MixedQueryResult struct {
+	DiscreteVariables   []string
+	ContinuousVariables []string
+	Components          []MixtureComponent
+}
+
+// QueryMixed computes P(variables | evidence) in a conditional linear
+// Gaussian network, where variables may be discrete, continuous, or both.
+//
+// The posterior is exact for every configuration of the discrete variables
+// that remain after pruning. When discrete variables outside the query are
+// summed out, the Gaussians of each query configuration are collapsed to a
+// single Gaussian with the same mean and covariance. The cost grows with the
+// product of the hidden discrete cardinalities.
+func (ve *VariableElimination) QueryMixed(variables []string, evidence models.Sample) (*MixedQueryResult, error) {
+	return ve.queryMixed(variables, evidence, nil)
+}
+
+// queryMixed is QueryMixed recording its cost in stats when stats is not nil
+func (ve *VariableElimination) queryMixed(variables []string, evidence models.Sample, stats *QueryStats) (*MixedQueryResult, error) {
+	if len(variables) == 0 {
+		return nil, fmt.Errorf("no query variables")
+	}
+
+	result := &MixedQueryResult{
+		DiscreteVariables:   []string{},
+		ContinuousVariables: []string{},
+	}
+	for _, v := range variables {
+		_, dObs := evidence.Discrete[v]
+		_, cObs := evidence.Continuous[v]
+		if dObs || cObs {
+			return nil, fmt.Errorf("%s: %w", v, ErrObservedQuery)
+		}
+		switch ve.Model.VariableType[v] {
+		case models.Discrete:
+			result.DiscreteVariables = append(result.DiscreteVariables, v)
+		case models.Continuous:
+			result.ContinuousVariables = append(result.ContinuousVariables, v)
+		default:
+			return nil, fmt.Errorf("unknown query variable %s: %w", v, ErrUnsupportedVariable)
+		}
+	}
+	for v := range evidence.Discrete {
+		if !ve.Model.IsDiscrete(v) {
+			return nil, fmt.Errorf("evidence variable %s is not discrete: %w", v, ErrUnsupportedVariable)
+		}
+	}
+	if err := ve.Model.ValidateEvidence(evidence.Discrete); err != nil {
+		return nil, err
+	}
+	for v := range evidence.Continuous {
+		if !ve.Model.IsContinuous(v) {
+			return nil, fmt.Errorf("evidence variable %s is not continuous: %w", v, ErrUnsupportedVariable)
+		}
+	}
+
+	observed := observedSet(evidence.Discrete)
+	for v := range evidence.Continuous {
+		observed[v] = true
+	}
+	nodes := ve.Model.RequisiteNodes(variables, observed)
+
+	// Hidden discrete variables are enumerated, query variables first so the
+	// components come out grouped by query assignment
+	hidden := make([]string, 0)
+	hidden = append(hidden, result.DiscreteVariables...)
+	isQuery := make(map[string]bool, len(variables))
+	for _, v := range variables {
+		isQuery[v] = true
+	}
+	others := make([]string, 0)
+	for _, node := range nodes {
+		if ve.Model.IsDiscrete(node) && !isQuery[node] && !observed[node] {
+			others = append(others, node)
+		}
+	}
+	sort.Strings(others)
+	hidden = append(hidden, others...)
+
+	cardinality := make(map[string]int, len(hidden))
+	for _, v := range hidden {
+		cpd, ok := ve.Model.DiscreteCPD(v)
+		if !ok {
+			return nil, fmt.Errorf("no discrete CPD for node %s: %w", v, models.ErrMissingCPD)
+		}
+		cardinality[v] = cpd.GetCardinality()
+	}
+
+	type weighted struct {
+		key      string
+		discrete map[string]int
+		logW     float64
+		gaussian *factors.GaussianFactor
+	}
+	components := make([]weighted, 0)
+
+	assignment := make(map[string]int, len(hidden)+len(evidence.Discrete))
+	for v, val := range evidence.Discrete {
+		assignment[v] = val
+	}
+
+	var enumerate func(depth int) error
+	enumerate = func(depth int) error {
+		if depth < len(hidden) {
+			v := hidden[depth]
+			for state := 0; state < cardinality[v]; state++ {
+				assignment[v] = state
+				if err := enumerate(depth + 1); err != nil {
+					return err
+				}
+			}
+			delete(assignment, v)
+			return nil
+		}
+
+		if stats != nil {
+			stats.Configurations++
+		}
+		logW, gaussian, err := ve.configurationPosterior(nodes, assignment, evidence.Continuous,
+			result.ContinuousVariables, stats)
+		if err != nil {
+			return err
+		}
+		if math.IsInf(logW, -1) {
+			return nil
+		}
+
+		queryAssignment := make(map[string]int, len(result.DiscreteVariables))
+		keyParts := make([]string, len(result.DiscreteVariables))
+		for i, v := range result.DiscreteVariables {
+			queryAssignment[v] = assignment[v]
+			keyParts[i] = fmt.Sprintf("%d", assignment[v])
+		}
+		components = append(components, weighted{
+			key:      strings.Join(keyParts, ","),
+			discrete: queryAssignment,
+			logW:     logW,
+			gaussian: gaussian,
+		})
+		return nil
+	}
+	if err := enumerate(0); err != nil {
+		return nil, err
+	}
+
+	if len(components) == 0 {
+		return nil, fmt.Errorf("evidence has zero probability: %w", ErrImpossibleEvidence)
+	}
+
+	// Normalize the weights in log space
+	maxLog := math.Inf(-1)
+	for _, c := range components {
+		if c.logW > maxLog {
+			maxLog = c.logW
+		}
+	}
+	total := 0.0
+	for _, c := range components {
+		total += math.Exp(c.logW - maxLog)
+	}
+
+	// Collapse components that share a query assignment
+	for start := 0; start < len(components); {
+		end := start
+		for end < len(components) && components[end].key == components[start].key {
+			end++
+		}
+
+		group := components[start:end]
+		weights := make([]float64, len(group))
+		gaussians := make([]*factors.GaussianFactor, len(group))
+		weight := 0.0
+		for i, c := range group {
+			weights[i] = math.Exp(c.logW-maxLog) / total
+			gaussians[i] = c.gaussian
+			weight += weights[i]
+		}
+
+		component := MixtureComponent{Discrete: group[0].discrete, Weight: weight}
+		if len(result.ContinuousVariables) > 0 {
+			collapsed, err := collapseGaussians(gaussians, weights)
+			if err != nil {
+				return nil, err
+			}
+			component.Gaussian = collapsed
+		}
+		result.Components = append(result.Components, component)
+		start = end
+	}
+
+	return result, nil
+}
+
+// QueryRecord is QueryMixed with raw evidence, transformed by the model's
+// preprocessing pipeline as its training data was
+func (ve *VariableElimination) QueryRecord(variables []string, evidence models.Record) (*MixedQueryResult, error) {
+	sample, err := ve.Model.Preprocess(evidence)
+	if err != nil {
+		return nil, fmt.Errorf("evidence: %w", err)
+	}
+	return ve.QueryMixed(variables, sample)
+}
+
+// QueryDiscrete computes P(variables | evidence) for discrete variables in a
+// CLG network, weighting each discrete configuration by the Gaussian
+// likelihood of the continuous evidence. Without continuous evidence this is
+// the same as Query.
+func (ve *VariableElimination) QueryDiscrete(variables []string, evidence models.Sample) (*factors.DiscreteFactor, error) {
+	if len(evidence.Continuous) == 0 {
+		return ve.Query(variables, evidence.Discrete)
+	}
+
+	sorted := make([]string, len(variables))
+	copy(sorted, variables)
+	sort.Strings(sorted)
+
+	cardinality := make(map[string]int, len(sorted))
+	size := 1
+	for _, v := range sorted {
+		if !ve.Model.IsDiscrete(v) {
+			return nil, fmt.Errorf("query variable %s is not discrete: %w", v, ErrUnsupportedVariable)
+		}
+		cpd, ok := ve.Model.DiscreteCPD(v)
+		if !ok {
+			return nil, fmt.Errorf("no discrete CPD for node %s: %w", v, models.ErrMissingCPD)
+		}
+		cardinality[v] = cpd.GetCardinality()
+		size *= cpd.GetCardinality()
+	}
+
+	mixture, err := ve.QueryMixed(sorted, evidence)
+	if err != nil {
+		return nil, err
+	}
+
+	// Configurations with zero weight have no component and stay at zero
+	values := make([]float64, size)
+	for _, component := range mixture.Components {
+		idx := 0
+		stride := 1
+		for i := len(sorted) - 1; i >= 0; i-- {
+			idx += component.Discrete[sorted[i]] * stride
+			stride *= cardinality[sorted[i]]
+		}
+		values[idx] = component.Weight
+	}
+
+	return factors.NewDiscreteFactor(sorted, cardinality, values)
+}
+
+// configurationPosterior returns the log joint weight of a full discrete
+// assignment with the continuous evidence, and the Gaussian posterior over
+// the continuous query variables given that assignment
+func (ve *VariableElimination) configurationPosterior(nodes []string, assignment map[string]int,
+	continuousEvidence map[string]float64, continuousQuery []string, stats *QueryStats) (float64, *factors.GaussianFactor, error) {
+	logW := 0.0
+	canonical := make([]*factors.CanonicalFactor, 0, len(nodes))
+
+	for _, node := range nodes {
+		if _, hybrid := ve.Model.HybridCPDs[node]; hybrid {
+			return 0, nil, fmt.Errorf("discrete node %s has a hybrid CPD, which exact inference does not support: %w",
+				node, ErrUnsupportedVariable)
+		}
+		if cpd, ok := ve.Model.DiscreteCPD(node); ok {
+			lp, err := cpd.LogProb(assignment[node], assignment)
+			if err != nil {
+				return 0, nil, fmt.Errorf("node %s: %w", node, err)
+			}
+			if math.IsInf(lp, -1) {
+				return math.Inf(-1), nil, nil
+			}
+			logW += lp
+			continue
+		}
+
+		cpd, ok := ve.Model.GaussianCPDs[node]
+		if _, custom := ve.Model.ContinuousCPDs[node]; custom {
+			return 0, nil, fmt.Errorf("node %s has a custom continuous CPD, which exact inference does not support: %w",
+				node, ErrUnsupportedVariable)
+		}
+		if !ok {
+			return 0, nil, fmt.Errorf("node %s: %w", node, models.ErrMissingCPD)
+		}
+		factor, err := ve.gaussianFactor(node, cpd, assignment)
+		if err != nil {
+			return 0, nil, fmt.Errorf("node %s: %w", node, err)
+		}
+		reduced, err := factor.Reduce(continuousEvidence)
+		if err != nil {
+			return 0, nil, err
+		}
+		canonical = append(canonical, reduced)
+	}
+
+	if len(canonical) == 0 {
+		return logW, nil, nil
+	}
+
+	// Integrate out the continuous variables that are not queried
+	queryVars := make(map[string]bool, len(continuousQuery))
+	for _, v := range continuousQuery {
+		queryVars[v] = true
+	}
+	hiddenVars := make(map[string]bool)
+	for _, factor := range canonical {
+		for _, v := range factor.Variables {
+			if !queryVars[v] {
+				hiddenVars[v] = true
+			}
+		}
+	}
+	toEliminate := make([]string, 0, len(hiddenVars))
+	for v := range hiddenVars {
+		toEliminate = append(toEliminate, v)
+	}
+	ve.sortElimination(toEliminate)
+	if stats != nil {
+		stats.EliminationOrder = toEliminate
+		for _, f := range canonical {
+			stats.observe(f.Size())
+		}
+	}
+
+	observe := recordProducts[*factors.CanonicalFactor](stats)
+	for _, v := range toEliminate {
+		var err error
+		canonical, err = factors.Eliminate(v, canonical, observe)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	joint, err := factors.Product(canonical, observe)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if len(continuousQuery) == 0 {
+		return logW + joint.G, nil, nil
+	}
+
+	// The mass of the remaining potential is the likelihood of the evidence
+	mass, err := joint.Marginalize(continuousQuery)
+	if err != nil {
+		return 0, nil, err
+	}
+	gaussian, err := joint.ToGaussian()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Report the continuous query variables in query order
+	return logW + mass.G, reorderGaussian(gaussian, continuousQuery), nil
+}
+
+// collapseGaussians moment-matches a weighted mixture of Gaussians over the
+// same variables to a single Gaussian
+func collapseGaussians(gaussians []*factors.GaussianFactor, weights []float64) (*factors.GaussianFactor, error) {
+	if len(gaussians) == 1 {
+		return gaussians[0], nil
+	}
+
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+
+	variables := gaussians[0].Variables
+	n := len(variables)
+	mean := mat.NewVecDense(n, nil)
+	for i, g := range gaussians {
+		mean.AddScaledVec(mean, weights[i]/total, g.Mean)
+	}
+
+	// Σ = Σᵢ wᵢ (Σᵢ + (μᵢ - μ)(μᵢ - μ)ᵀ)
+	cov := mat.NewSymDense(n, nil)
+	diff := mat.NewVecDense(n, nil)
+	for i, g := range gaussians {
+		w := weights[i] / total
+		diff.SubVec(g.Mean, mean)
+		for r := 0; r < n; r++ {
+			for c := r; c < n; c++ {
+				cov.SetSym(r, c, cov.At(r, c)+w*(g.Covariance.At(r, c)+diff.AtVec(r)*diff.AtVec(c)))
+			}
+		}
+	}
+
+	vars := make([]string, n)
+	copy(vars, variables)
+	return factors.NewGaussianFactorFromMatrix(vars, mean, cov)
+}
+
+// reorderGaussian returns the Gaussian with its variables in the given order
+func reorderGaussian(g *factors.GaussianFactor, order []string) *factors.GaussianFactor {
+	n := len(order)
+	mean := mat.NewVecDense(n, nil)
+	cov := mat.NewSymDense(n, nil)
+	for i, vi := range order {
+		mean.SetVec(i, g.MeanOf(vi))
+		for j := i; j < n; j++ {
+			cov.SetSym(i, j, g.CovarianceOf(vi, order[j]))
+		}
+	}
+
+	vars := make([]string, n)
+	copy(vars, order)
+	result, _ := factors.NewGaussianFactorFromMatrix(vars, mean, cov)
+	return result
+}
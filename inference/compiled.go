@@ -0,0 +1,60 @@
+package inference
+
+import (
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// NewCompiledVariableElimination creates an engine over a compiled model. It
+// reuses the model's precomputed factors and eliminates variables in its
+// min-fill order. The engine never modifies shared state while answering
+// queries, so one engine may serve many goroutines at once.
+func NewCompiledVariableElimination(cm *models.CompiledModel) *VariableElimination {
+	order := cm.EliminationOrder()
+	rank := make(map[string]int, len(order))
+	for i, v := range order {
+		rank[v] = i
+	}
+	return &VariableElimination{
+		Model:            cm.Network(),
+		fixedFactors:     cm.Factors(),
+		canonicalFactors: cm.CanonicalFactors(),
+		eliminationRank:  rank,
+	}
+}
+
+// sortElimination orders variables for elimination: by the compiled
+// elimination order when there is one, lexicographically otherwise
+func (ve *VariableElimination) sortElimination(variables []string) {
+	if ve.eliminationRank == nil {
+		sort.Strings(variables)
+		return
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		ri, okI := ve.eliminationRank[variables[i]]
+		rj, okJ := ve.eliminationRank[variables[j]]
+		if okI != okJ {
+			return okI
+		}
+		if okI && ri != rj {
+			return ri < rj
+		}
+		return variables[i] < variables[j]
+	})
+}
+
+// gaussianFactor returns the canonical factor of a continuous CPD with its
+// discrete parents fixed by assignment, using the compiled factor if any. A
+// nil assignment requires a CPD without discrete parents.
+func (ve *VariableElimination) gaussianFactor(node string, cpd *factors.LinearGaussianCPD,
+	assignment map[string]int) (*factors.CanonicalFactor, error) {
+	if factor, ok := ve.canonicalFactors[node]; ok {
+		return factor, nil
+	}
+	if assignment == nil || len(cpd.DiscreteParents()) == 0 {
+		return cpd.ToFactor()
+	}
+	return cpd.ConditionalFactor(assignment)
+}
@@ -0,0 +1,71 @@
+package inference
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// WidthPolicy controls how an exact inference engine reacts when the induced
+// width of an elimination exceeds its configured limit
+type WidthPolicy int
+
+const (
+	// WidthWarn logs a warning and continues with the elimination
+	WidthWarn WidthPolicy = iota
+	// WidthError aborts the query with a *WidthExceededError
+	WidthError
+)
+
+// WidthExceededError reports an elimination whose induced width is above the limit
+type WidthExceededError struct {
+	Width int
+	Limit int
+}
+
+func (e *WidthExceededError) Error() string {
+	return fmt.Sprintf("induced width %d exceeds limit %d", e.Width, e.Limit)
+}
+
+// SetMaxInducedWidth bounds the induced width of queries. A limit of 0
+// disables the check.
+func (ve *VariableElimination) SetMaxInducedWidth(limit int, policy WidthPolicy) {
+	ve.MaxInducedWidth = limit
+	ve.WidthPolicy = policy
+}
+
+// checkInducedWidth computes the induced width of eliminating order from the
+// factor scopes and applies the engine's width policy
+func (ve *VariableElimination) checkInducedWidth(factorList []*factors.DiscreteFactor, order []string) error {
+	if ve.MaxInducedWidth <= 0 {
+		return nil
+	}
+
+	width := interactionGraph(factorList).InducedWidth(order)
+	if width <= ve.MaxInducedWidth {
+		return nil
+	}
+
+	err := &WidthExceededError{Width: width, Limit: ve.MaxInducedWidth}
+	if ve.WidthPolicy == WidthError {
+		return err
+	}
+	log.Printf("bngo: variable elimination: %v", err)
+	return nil
+}
+
+// interactionGraph connects every pair of variables that share a factor
+func interactionGraph(factorList []*factors.DiscreteFactor) *graph.UndirectedGraph {
+	ug := graph.NewUndirectedGraph()
+	for _, f := range factorList {
+		for i, v := range f.Variables {
+			ug.AddNode(v)
+			for _, w := range f.Variables[i+1:] {
+				ug.AddEdge(v, w)
+			}
+		}
+	}
+	return ug
+}
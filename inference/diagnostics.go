@@ -0,0 +1,141 @@
+package inference
+
+import (
+	"fmt"
+	"math"
+)
+
+// SamplerDiagnostics summarizes how far a Monte Carlo approximation can be
+// trusted. Engines fill it from the traces of a monitored statistic, such as
+// the indicator of a query state.
+type SamplerDiagnostics struct {
+	Samples             int     // Total draws across chains
+	EffectiveSampleSize float64 // Draws worth of independent samples
+	AcceptanceRate      float64 // Fraction of accepted proposals; 1 for samplers without rejection
+	RHat                float64 // Gelman-Rubin statistic; NaN with fewer than two chains
+}
+
+// NewSamplerDiagnostics computes diagnostics from per-chain traces and the
+// number of accepted and proposed moves. With proposed == 0 every draw is
+// counted as accepted.
+func NewSamplerDiagnostics(chains [][]float64, accepted, proposed int) SamplerDiagnostics {
+	d := SamplerDiagnostics{AcceptanceRate: 1, RHat: math.NaN()}
+	for _, chain := range chains {
+		d.Samples += len(chain)
+		d.EffectiveSampleSize += EffectiveSampleSize(chain)
+	}
+	if proposed > 0 {
+		d.AcceptanceRate = float64(accepted) / float64(proposed)
+	}
+	if rhat, err := GelmanRubin(chains); err == nil {
+		d.RHat = rhat
+	}
+	return d
+}
+
+// EffectiveSampleSize estimates n / (1 + 2 Σ ρ_k) for an autocorrelated
+// chain, truncating the autocorrelation sum with Geyer's initial positive
+// sequence: pairs ρ_{2k} + ρ_{2k+1} are added while they stay positive.
+// A constant chain has no information beyond its length and returns n.
+func EffectiveSampleSize(chain []float64) float64 {
+	n := len(chain)
+	if n < 2 {
+		return float64(n)
+	}
+
+	mean := 0.0
+	for _, x := range chain {
+		mean += x
+	}
+	mean /= float64(n)
+
+	autocovariance := func(lag int) float64 {
+		sum := 0.0
+		for i := 0; i+lag < n; i++ {
+			sum += (chain[i] - mean) * (chain[i+lag] - mean)
+		}
+		return sum / float64(n)
+	}
+
+	variance := autocovariance(0)
+	if variance == 0 {
+		return float64(n)
+	}
+
+	tau := 1.0
+	for lag := 1; lag+1 < n; lag += 2 {
+		pair := (autocovariance(lag) + autocovariance(lag+1)) / variance
+		if pair <= 0 {
+			break
+		}
+		tau += 2 * pair
+	}
+	return math.Min(float64(n)/tau, float64(n))
+}
+
+// WeightedEffectiveSampleSize returns Kish's (Σw)² / Σw², the number of
+// equally weighted samples carrying the same information as a set of
+// importance or likelihood weights
+func WeightedEffectiveSampleSize(weights []float64) float64 {
+	sum, sumSq := 0.0, 0.0
+	for _, w := range weights {
+		sum += w
+		sumSq += w * w
+	}
+	if sumSq == 0 {
+		return 0
+	}
+	return sum * sum / sumSq
+}
+
+// GelmanRubin returns the potential scale reduction factor R-hat of several
+// chains of equal length. Values near 1 indicate the chains have mixed;
+// above about 1.1 they have not yet converged to the same distribution.
+func GelmanRubin(chains [][]float64) (float64, error) {
+	m := len(chains)
+	if m < 2 {
+		return 0, fmt.Errorf("R-hat needs at least 2 chains, got %d", m)
+	}
+	n := len(chains[0])
+	if n < 2 {
+		return 0, fmt.Errorf("R-hat needs chains of at least 2 draws, got %d", n)
+	}
+
+	means := make([]float64, m)
+	grand := 0.0
+	within := 0.0
+	for j, chain := range chains {
+		if len(chain) != n {
+			return 0, fmt.Errorf("chain %d has %d draws, expected %d", j, len(chain), n)
+		}
+		for _, x := range chain {
+			means[j] += x
+		}
+		means[j] /= float64(n)
+		grand += means[j]
+
+		ss := 0.0
+		for _, x := range chain {
+			ss += (x - means[j]) * (x - means[j])
+		}
+		within += ss / float64(n-1)
+	}
+	grand /= float64(m)
+	within /= float64(m)
+
+	between := 0.0
+	for _, mean := range means {
+		between += (mean - grand) * (mean - grand)
+	}
+	between *= float64(n) / float64(m-1)
+
+	if within == 0 {
+		if between == 0 {
+			return 1, nil
+		}
+		return math.Inf(1), nil
+	}
+
+	pooled := float64(n-1)/float64(n)*within + between/float64(n)
+	return math.Sqrt(pooled / within), nil
+}
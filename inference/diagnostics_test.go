@@ -0,0 +1,58 @@
+package inference
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSamplerDiagnostics(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	// Independent draws have ESS close to n
+	iid := make([]float64, 5000)
+	for i := range iid {
+		iid[i] = r.NormFloat64()
+	}
+	if ess := EffectiveSampleSize(iid); ess < 4000 {
+		t.Errorf("ESS of independent draws = %f, expected near 5000", ess)
+	}
+
+	// An AR(1) chain with coefficient 0.9 has ESS ≈ n (1-0.9)/(1+0.9)
+	ar := make([]float64, 20000)
+	for i := 1; i < len(ar); i++ {
+		ar[i] = 0.9*ar[i-1] + r.NormFloat64()
+	}
+	expected := float64(len(ar)) * 0.1 / 1.9
+	if ess := EffectiveSampleSize(ar); math.Abs(ess-expected)/expected > 0.3 {
+		t.Errorf("ESS of AR(1) chain = %f, expected about %f", ess, expected)
+	}
+
+	if ess := WeightedEffectiveSampleSize([]float64{1, 1, 1, 1}); ess != 4 {
+		t.Errorf("Equal weights should give ESS 4, got %f", ess)
+	}
+	if ess := WeightedEffectiveSampleSize([]float64{1, 0, 0, 0}); ess != 1 {
+		t.Errorf("A single nonzero weight should give ESS 1, got %f", ess)
+	}
+
+	// Chains around different means have not mixed
+	mixed := [][]float64{iid[:2500], iid[2500:]}
+	shifted := make([]float64, 2500)
+	for i := range shifted {
+		shifted[i] = iid[i] + 3
+	}
+	if rhat, _ := GelmanRubin(mixed); math.Abs(rhat-1) > 0.01 {
+		t.Errorf("R-hat of mixed chains = %f, expected near 1", rhat)
+	}
+	if rhat, _ := GelmanRubin([][]float64{iid[:2500], shifted}); rhat < 1.5 {
+		t.Errorf("R-hat of separated chains = %f, expected well above 1", rhat)
+	}
+
+	d := NewSamplerDiagnostics(mixed, 30, 100)
+	if d.Samples != 5000 || d.AcceptanceRate != 0.3 || math.IsNaN(d.RHat) {
+		t.Errorf("Unexpected diagnostics: %+v", d)
+	}
+	if single := NewSamplerDiagnostics([][]float64{iid}, 0, 0); !math.IsNaN(single.RHat) || single.AcceptanceRate != 1 {
+		t.Errorf("Unexpected single-chain diagnostics: %+v", single)
+	}
+}
@@ -0,0 +1,26 @@
+package inference
+
+import (
+	"errors"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+var (
+	// ErrObservedQuery is returned when a query variable is also observed
+	ErrObservedQuery = errors.New("query variable is observed")
+
+	// ErrConflictingEvidence is returned when evidence contradicts fixed evidence
+	ErrConflictingEvidence = errors.New("conflicting evidence")
+
+	// ErrImpossibleEvidence is returned when the evidence has zero probability
+	ErrImpossibleEvidence = factors.ErrZeroProbability
+
+	// ErrInvalidState is returned for evidence outside a variable's states
+	ErrInvalidState = models.ErrInvalidState
+
+	// ErrUnsupportedVariable is returned when a variable has the wrong type for
+	// the requested query, or is not in the model
+	ErrUnsupportedVariable = errors.New("unsupported variable")
+)
@@ -0,0 +1,105 @@
+package inference
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// EvidenceImpact is the contribution of one finding to a query result
+type EvidenceImpact struct {
+	Variable string
+	State    int
+
+	// PosteriorWithout is the query probability with this finding removed
+	PosteriorWithout float64
+	// LogOddsChange is logit(P(q | all evidence)) - logit(P(q | evidence
+	// without this finding)), positive when the finding supports the query
+	LogOddsChange float64
+}
+
+// Explanation reports how the evidence moves a query probability
+type Explanation struct {
+	Variable  string
+	State     int
+	Prior     float64 // P(q) with no evidence
+	Posterior float64 // P(q | evidence)
+
+	// Impacts lists each finding, largest absolute log-odds change first
+	Impacts []EvidenceImpact
+}
+
+// Explain attributes P(variable=state | evidence) to the individual findings
+// by removing each one in turn and measuring the change in log-odds
+func (ve *VariableElimination) Explain(variable string, state int, evidence map[string]int) (*Explanation, error) {
+	if _, observed := evidence[variable]; observed {
+		return nil, fmt.Errorf("%s: %w", variable, ErrObservedQuery)
+	}
+
+	posterior, err := ve.stateProbability(variable, state, evidence)
+	if err != nil {
+		return nil, err
+	}
+	prior, err := ve.stateProbability(variable, state, map[string]int{})
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &Explanation{
+		Variable:  variable,
+		State:     state,
+		Prior:     prior,
+		Posterior: posterior,
+		Impacts:   make([]EvidenceImpact, 0, len(evidence)),
+	}
+
+	for finding, value := range evidence {
+		rest := make(map[string]int, len(evidence)-1)
+		for k, v := range evidence {
+			if k != finding {
+				rest[k] = v
+			}
+		}
+
+		without, err := ve.stateProbability(variable, state, rest)
+		if err != nil {
+			return nil, err
+		}
+		explanation.Impacts = append(explanation.Impacts, EvidenceImpact{
+			Variable:         finding,
+			State:            value,
+			PosteriorWithout: without,
+			LogOddsChange:    logit(posterior) - logit(without),
+		})
+	}
+
+	sort.Slice(explanation.Impacts, func(i, j int) bool {
+		a, b := math.Abs(explanation.Impacts[i].LogOddsChange), math.Abs(explanation.Impacts[j].LogOddsChange)
+		if a != b {
+			return a > b
+		}
+		return explanation.Impacts[i].Variable < explanation.Impacts[j].Variable
+	})
+
+	return explanation, nil
+}
+
+// stateProbability returns P(variable=state | evidence)
+func (ve *VariableElimination) stateProbability(variable string, state int, evidence map[string]int) (float64, error) {
+	result, err := ve.Query([]string{variable}, evidence)
+	if err != nil {
+		return 0, err
+	}
+	if state < 0 || state >= len(result.Values) {
+		return 0, fmt.Errorf("invalid state %d for variable %s", state, variable)
+	}
+	return result.Values[state], nil
+}
+
+// logit returns log(p / (1 - p)), clamped away from 0 and 1 so certain
+// findings give a large but finite change
+func logit(p float64) float64 {
+	const eps = 1e-12
+	p = math.Min(math.Max(p, eps), 1-eps)
+	return math.Log(p / (1 - p))
+}
@@ -0,0 +1,102 @@
+package inference
+
+import (
+	"fmt"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// WithFixedEvidence returns an engine that treats the given evidence as
+// always observed. Every CPD is reduced by it once up front, so later queries
+// only reduce by their own additional evidence. Query evidence that
+// contradicts the fixed evidence is an error.
+func (ve *VariableElimination) WithFixedEvidence(evidence map[string]int) (*VariableElimination, error) {
+	if err := ve.Model.ValidateEvidence(evidence); err != nil {
+		return nil, err
+	}
+	fixed := make(map[string]int, len(ve.fixedEvidence)+len(evidence))
+	for k, v := range ve.fixedEvidence {
+		fixed[k] = v
+	}
+	for k, v := range evidence {
+		if existing, ok := fixed[k]; ok && existing != v {
+			return nil, fmt.Errorf("fixed evidence for %s: %d and %d: %w", k, existing, v, ErrConflictingEvidence)
+		}
+		fixed[k] = v
+	}
+
+	fixedFactors := make(map[string]*factors.DiscreteFactor, len(ve.Model.CPDs))
+	for _, node := range ve.Model.Nodes() {
+		if _, ok := ve.Model.DiscreteCPD(node); !ok {
+			continue
+		}
+		factor, err := ve.Model.NodeFactor(node)
+		if err != nil {
+			return nil, err
+		}
+		reduced, err := factor.Reduce(fixed)
+		if err != nil {
+			return nil, err
+		}
+		fixedFactors[node] = reduced
+	}
+
+	engine := *ve
+	engine.fixedEvidence = fixed
+	engine.fixedFactors = fixedFactors
+	return &engine, nil
+}
+
+// FixedEvidence returns the evidence fixed with WithFixedEvidence
+func (ve *VariableElimination) FixedEvidence() map[string]int {
+	fixed := make(map[string]int, len(ve.fixedEvidence))
+	for k, v := range ve.fixedEvidence {
+		fixed[k] = v
+	}
+	return fixed
+}
+
+// effectiveEvidence validates query evidence and merges it with the
+// engine's fixed evidence
+func (ve *VariableElimination) effectiveEvidence(evidence map[string]int) (map[string]int, error) {
+	if err := ve.Model.ValidateEvidence(evidence); err != nil {
+		return nil, err
+	}
+	if len(ve.fixedEvidence) == 0 {
+		return evidence, nil
+	}
+
+	merged := make(map[string]int, len(ve.fixedEvidence)+len(evidence))
+	for k, v := range ve.fixedEvidence {
+		merged[k] = v
+	}
+	for k, v := range evidence {
+		if fixed, ok := merged[k]; ok && fixed != v {
+			return nil, fmt.Errorf("evidence %s=%d against fixed %s=%d: %w", k, v, k, fixed, ErrConflictingEvidence)
+		}
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+// reducedFactors converts the CPDs of the given nodes to factors reduced by
+// evidence, starting from the pre-reduced factors when evidence is fixed.
+// Factors untouched by the evidence may be shared with the engine's cache.
+func (ve *VariableElimination) reducedFactors(nodes []string, evidence map[string]int) ([]*factors.DiscreteFactor, error) {
+	factorList := make([]*factors.DiscreteFactor, 0, len(nodes))
+	for _, node := range nodes {
+		factor, ok := ve.fixedFactors[node]
+		if !ok {
+			if _, hasCPD := ve.Model.DiscreteCPD(node); !hasCPD {
+				continue
+			}
+			var err error
+			factor, err = ve.Model.NodeFactor(node)
+			if err != nil {
+				return nil, err
+			}
+		}
+		factorList = append(factorList, factor)
+	}
+	return factors.ReduceFactors(factorList, evidence)
+}
@@ -0,0 +1,91 @@
+package inference
+
+import (
+	"fmt"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// QueryGaussian computes the joint posterior P(variables | evidence) in a
+// linear-Gaussian network by eliminating canonical-form factors. The network
+// must be purely continuous, with no discrete parents.
+func (ve *VariableElimination) QueryGaussian(variables []string, evidence map[string]float64) (*factors.GaussianFactor, error) {
+	if len(variables) == 0 {
+		return nil, fmt.Errorf("no query variables")
+	}
+	for _, v := range variables {
+		if ve.Model.VariableType[v] != models.Continuous {
+			return nil, fmt.Errorf("query variable %s is not continuous: %w", v, ErrUnsupportedVariable)
+		}
+		if _, observed := evidence[v]; observed {
+			return nil, fmt.Errorf("%s: %w", v, ErrObservedQuery)
+		}
+	}
+	for v := range evidence {
+		if ve.Model.VariableType[v] != models.Continuous {
+			return nil, fmt.Errorf("evidence variable %s is not continuous: %w", v, ErrUnsupportedVariable)
+		}
+	}
+
+	// Convert the relevant CPDs to canonical factors reduced by evidence
+	nodes := ve.Model.RequisiteNodes(variables, observedSet(evidence))
+	currentFactors := make([]*factors.CanonicalFactor, 0, len(nodes))
+	for _, node := range nodes {
+		cpd, ok := ve.Model.GaussianCPDs[node]
+		if _, custom := ve.Model.ContinuousCPDs[node]; custom {
+			return nil, fmt.Errorf("node %s has a custom continuous CPD, which exact inference does not support: %w",
+				node, ErrUnsupportedVariable)
+		}
+		if !ok {
+			return nil, fmt.Errorf("no Gaussian CPD for node %s: %w", node, models.ErrMissingCPD)
+		}
+		factor, err := ve.gaussianFactor(node, cpd, nil)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", node, err)
+		}
+		reduced, err := factor.Reduce(evidence)
+		if err != nil {
+			return nil, err
+		}
+		currentFactors = append(currentFactors, reduced)
+	}
+
+	// Integrate out everything that is neither queried nor observed
+	queryVars := make(map[string]bool)
+	for _, v := range variables {
+		queryVars[v] = true
+	}
+	allVars := make(map[string]bool)
+	for _, factor := range currentFactors {
+		for _, v := range factor.Variables {
+			if !queryVars[v] {
+				allVars[v] = true
+			}
+		}
+	}
+	toEliminate := make([]string, 0, len(allVars))
+	for v := range allVars {
+		toEliminate = append(toEliminate, v)
+	}
+	ve.sortElimination(toEliminate)
+
+	for _, v := range toEliminate {
+		var err error
+		currentFactors, err = factors.Eliminate(v, currentFactors, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(currentFactors) == 0 {
+		return nil, fmt.Errorf("no factors remaining after elimination")
+	}
+	result, err := factors.Product(currentFactors, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Converting to moment form normalizes the posterior
+	return result.ToGaussian()
+}
@@ -0,0 +1,404 @@
+package inference
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/JohnPierman/bngo/examples"
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+func TestQueryGaussianLinearChain(t *testing.T) {
+	bn, err := examples.GetLinearChainModel()
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create inference engine: %v", err)
+	}
+
+	// X2 ~ N(0.5, 1.14), X3 ~ N(0.75, 0.535), Cov(X1, X3) = -0.4
+	marginal, err := ve.QueryGaussian([]string{"X3"}, nil)
+	if err != nil {
+		t.Fatalf("QueryGaussian failed: %v", err)
+	}
+	if math.Abs(marginal.MeanOf("X3")-0.75) > 1e-9 {
+		t.Errorf("Expected mean 0.75, got %f", marginal.MeanOf("X3"))
+	}
+	if math.Abs(marginal.CovarianceOf("X3", "X3")-0.535) > 1e-9 {
+		t.Errorf("Expected variance 0.535, got %f", marginal.CovarianceOf("X3", "X3"))
+	}
+
+	posterior, err := ve.QueryGaussian([]string{"X1"}, map[string]float64{"X3": 2.0})
+	if err != nil {
+		t.Fatalf("QueryGaussian failed: %v", err)
+	}
+	wantMean := -0.4 / 0.535 * (2.0 - 0.75)
+	wantVar := 1 - 0.16/0.535
+	if math.Abs(posterior.MeanOf("X1")-wantMean) > 1e-9 {
+		t.Errorf("Expected posterior mean %f, got %f", wantMean, posterior.MeanOf("X1"))
+	}
+	if math.Abs(posterior.CovarianceOf("X1", "X1")-wantVar) > 1e-9 {
+		t.Errorf("Expected posterior variance %f, got %f", wantVar, posterior.CovarianceOf("X1", "X1"))
+	}
+}
+
+func TestQueryMixedSeasonTemperature(t *testing.T) {
+	bn, err := examples.GetTemperatureModel()
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create inference engine: %v", err)
+	}
+
+	sales := 500.0
+	result, err := ve.QueryMixed([]string{"Season", "Temperature"},
+		models.Sample{Continuous: map[string]float64{"IceCreamSales": sales}})
+	if err != nil {
+		t.Fatalf("QueryMixed failed: %v", err)
+	}
+	if len(result.Components) != 4 {
+		t.Fatalf("Expected 4 components, got %d", len(result.Components))
+	}
+
+	// Sales | s ~ N(10μₛ - 200, 100vₛ + 2500), and Temperature | s, Sales is
+	// the usual Gaussian update of N(μₛ, vₛ)
+	means := []float64{30, 60, 85, 55}
+	variances := []float64{100, 100, 64, 100}
+	likelihoods := make([]float64, 4)
+	total := 0.0
+	for s := range means {
+		salesVar := 100*variances[s] + 2500
+		diff := sales - (10*means[s] - 200)
+		likelihoods[s] = 0.25 * math.Exp(-diff*diff/(2*salesVar)) / math.Sqrt(2*math.Pi*salesVar)
+		total += likelihoods[s]
+	}
+
+	for s, component := range result.Components {
+		if component.Discrete["Season"] != s {
+			t.Fatalf("Component %d has Season=%d", s, component.Discrete["Season"])
+		}
+		wantWeight := likelihoods[s] / total
+		if math.Abs(component.Weight-wantWeight) > 1e-9 {
+			t.Errorf("Season=%d: expected weight %f, got %f", s, wantWeight, component.Weight)
+		}
+
+		salesVar := 100*variances[s] + 2500
+		gain := 10 * variances[s] / salesVar
+		wantMean := means[s] + gain*(sales-(10*means[s]-200))
+		wantVar := variances[s] - gain*10*variances[s]
+		if math.Abs(component.Gaussian.MeanOf("Temperature")-wantMean) > 1e-9 {
+			t.Errorf("Season=%d: expected mean %f, got %f", s, wantMean, component.Gaussian.MeanOf("Temperature"))
+		}
+		if math.Abs(component.Gaussian.CovarianceOf("Temperature", "Temperature")-wantVar) > 1e-9 {
+			t.Errorf("Season=%d: expected variance %f, got %f", s, wantVar,
+				component.Gaussian.CovarianceOf("Temperature", "Temperature"))
+		}
+	}
+	// Summing out Season collapses the mixture to its first two moments
+	collapsed, err := ve.QueryMixed([]string{"Temperature"},
+		models.Sample{Continuous: map[string]float64{"IceCreamSales": sales}})
+	if err != nil {
+		t.Fatalf("QueryMixed failed: %v", err)
+	}
+	if len(collapsed.Components) != 1 || math.Abs(collapsed.Components[0].Weight-1) > 1e-9 {
+		t.Fatalf("Expected a single component of weight 1, got %+v", collapsed.Components)
+	}
+	wantMean := 0.0
+	for _, component := range result.Components {
+		wantMean += component.Weight * component.Gaussian.MeanOf("Temperature")
+	}
+	if got := collapsed.Components[0].Gaussian.MeanOf("Temperature"); math.Abs(got-wantMean) > 1e-9 {
+		t.Errorf("Expected collapsed mean %f, got %f", wantMean, got)
+	}
+}
+
+func TestMixedQueryResultAccessors(t *testing.T) {
+	bn, err := examples.GetTemperatureModel()
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create inference engine: %v", err)
+	}
+	result, err := ve.QueryMixed([]string{"Season", "Temperature"},
+		models.Sample{Continuous: map[string]float64{"IceCreamSales": 500}})
+	if err != nil {
+		t.Fatalf("QueryMixed failed: %v", err)
+	}
+
+	p, err := result.ProbabilityOf(map[string]int{"Season": 2})
+	if err != nil || math.Abs(p-result.Components[2].Weight) > 1e-12 {
+		t.Errorf("P(Season=2) = %v (err %v), want %v", p, err, result.Components[2].Weight)
+	}
+	if _, err := result.ProbabilityOf(map[string]int{"Temperature": 0}); !errors.Is(err, ErrUnsupportedVariable) {
+		t.Errorf("Expected ErrUnsupportedVariable for a continuous variable, got %v", err)
+	}
+
+	top := result.TopStates(1)
+	best := 0
+	for i, c := range result.Components {
+		if c.Weight > result.Components[best].Weight {
+			best = i
+		}
+	}
+	if len(top) != 1 || top[0].Assignment["Season"] != best {
+		t.Errorf("Expected Season=%d to be most probable, got %+v", best, top)
+	}
+
+	season, err := result.MarginalFor("Season")
+	if err != nil {
+		t.Fatalf("MarginalFor(Season) failed: %v", err)
+	}
+	if len(season.Components) != 4 || season.Components[1].Gaussian != nil {
+		t.Errorf("Unexpected Season marginal %+v", season.Components)
+	}
+
+	temperature, err := result.MarginalFor("Temperature")
+	if err != nil {
+		t.Fatalf("MarginalFor(Temperature) failed: %v", err)
+	}
+	if len(temperature.Components) != 4 || len(temperature.Components[0].Discrete) != 0 {
+		t.Fatalf("Unexpected Temperature marginal %+v", temperature.Components)
+	}
+	if got, want := temperature.Components[3].Gaussian.MeanOf("Temperature"),
+		result.Components[3].Gaussian.MeanOf("Temperature"); got != want {
+		t.Errorf("Component 3 mean = %v, want %v", got, want)
+	}
+	if _, err := result.MarginalFor("IceCreamSales"); err == nil {
+		t.Error("Expected error for a variable outside the query")
+	}
+}
+
+func TestMixedQueryResultSample(t *testing.T) {
+	bn, err := examples.GetTemperatureModel()
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create inference engine: %v", err)
+	}
+	result, err := ve.QueryMixed([]string{"Season", "Temperature"},
+		models.Sample{Continuous: map[string]float64{"IceCreamSales": 500}})
+	if err != nil {
+		t.Fatalf("QueryMixed failed: %v", err)
+	}
+
+	samples, err := result.Sample(40000, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Sample failed: %v", err)
+	}
+	if len(samples) != 40000 {
+		t.Fatalf("Expected 40000 samples, got %d", len(samples))
+	}
+	counts := make([]float64, 4)
+	mean := 0.0
+	for _, s := range samples {
+		counts[s.Discrete["Season"]]++
+		mean += s.Continuous["Temperature"] / float64(len(samples))
+	}
+	wantMean := 0.0
+	for s, c := range result.Components {
+		wantMean += c.Weight * c.Gaussian.MeanOf("Temperature")
+		if got := counts[s] / float64(len(samples)); math.Abs(got-c.Weight) > 0.01 {
+			t.Errorf("Frequency of Season=%d = %v, want %v", s, got, c.Weight)
+		}
+	}
+	if math.Abs(mean-wantMean) > 0.5 {
+		t.Errorf("Sample mean of Temperature = %v, want %v", mean, wantMean)
+	}
+}
+
+func TestMixedQueryResultCredibleInterval(t *testing.T) {
+	bn, err := examples.GetTemperatureModel()
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create inference engine: %v", err)
+	}
+
+	// Temperature ~ N(85, 64) in summer
+	summer, err := ve.QueryMixed([]string{"Temperature"}, models.Sample{Discrete: map[string]int{"Season": 2}})
+	if err != nil {
+		t.Fatalf("QueryMixed failed: %v", err)
+	}
+	lo, hi, err := summer.CredibleInterval("Temperature", 0.95)
+	if err != nil {
+		t.Fatalf("CredibleInterval failed: %v", err)
+	}
+	half := 1.959963984540054 * 8
+	if math.Abs(lo-(85-half)) > 1e-9 || math.Abs(hi-(85+half)) > 1e-9 {
+		t.Errorf("95%% interval = [%v, %v], want [%v, %v]", lo, hi, 85-half, 85+half)
+	}
+
+	// Over all seasons the posterior is a mixture; check the mixture CDF at
+	// the interval ends
+	mixture, err := ve.QueryMixed([]string{"Season", "Temperature"}, models.Sample{})
+	if err != nil {
+		t.Fatalf("QueryMixed failed: %v", err)
+	}
+	lo, hi, err = mixture.CredibleInterval("Temperature", 0.9)
+	if err != nil {
+		t.Fatalf("CredibleInterval failed: %v", err)
+	}
+	for _, tt := range []struct{ x, want float64 }{{lo, 0.05}, {hi, 0.95}} {
+		cdf := 0.0
+		for _, c := range mixture.Components {
+			f, _ := c.Gaussian.CDF("Temperature", tt.x)
+			cdf += c.Weight * f
+		}
+		if math.Abs(cdf-tt.want) > 1e-9 {
+			t.Errorf("Mixture CDF at %v = %v, want %v", tt.x, cdf, tt.want)
+		}
+	}
+	if _, err := mixture.Quantile("Season", 0.5); !errors.Is(err, ErrUnsupportedVariable) {
+		t.Errorf("Expected ErrUnsupportedVariable for a discrete variable, got %v", err)
+	}
+}
+
+func TestQueryDiscreteContinuousEvidence(t *testing.T) {
+	bn, err := examples.GetTemperatureModel()
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create inference engine: %v", err)
+	}
+
+	result, err := ve.QueryDiscrete([]string{"Season"},
+		models.Sample{Continuous: map[string]float64{"Temperature": 78}})
+	if err != nil {
+		t.Fatalf("QueryDiscrete failed: %v", err)
+	}
+
+	// Uniform prior, so the posterior is proportional to N(78; μₛ, vₛ)
+	means := []float64{30, 60, 85, 55}
+	variances := []float64{100, 100, 64, 100}
+	densities := make([]float64, 4)
+	total := 0.0
+	for s := range means {
+		diff := 78 - means[s]
+		densities[s] = math.Exp(-diff*diff/(2*variances[s])) / math.Sqrt(2*math.Pi*variances[s])
+		total += densities[s]
+	}
+	for s := range means {
+		if math.Abs(result.Values[s]-densities[s]/total) > 1e-9 {
+			t.Errorf("P(Season=%d | Temperature=78): expected %f, got %f", s, densities[s]/total, result.Values[s])
+		}
+	}
+}
+
+func TestQueryMixedWithStats(t *testing.T) {
+	bn, err := examples.GetTemperatureModel()
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create inference engine: %v", err)
+	}
+
+	_, stats, err := ve.QueryMixedWithStats([]string{"IceCreamSales"}, models.Sample{})
+	if err != nil {
+		t.Fatalf("QueryMixedWithStats failed: %v", err)
+	}
+	if stats.Configurations != 4 {
+		t.Errorf("Configurations = %d, want 4 seasons", stats.Configurations)
+	}
+	if len(stats.EliminationOrder) != 1 || stats.EliminationOrder[0] != "Temperature" {
+		t.Errorf("EliminationOrder = %v, want [Temperature]", stats.EliminationOrder)
+	}
+	// One product of the two CPD factors per season, over two variables
+	if stats.Multiplications != 4 || stats.MaxFactorSize != 7 {
+		t.Errorf("Multiplications = %d, MaxFactorSize = %d, want 4 and 7",
+			stats.Multiplications, stats.MaxFactorSize)
+	}
+}
+
+func TestGibbsSample(t *testing.T) {
+	bn, err := models.NewBayesianNetwork([][2]string{{"A", "B"}, {"A", "X"}, {"B", "Y"}, {"X", "Y"}})
+	if err != nil {
+		t.Fatalf("Failed to create network: %v", err)
+	}
+	cpdA, _ := factors.NewTabularCPD("A", 2, [][]float64{{0.7, 0.3}}, []string{}, map[string]int{})
+	cpdB, _ := factors.NewTabularCPD("B", 3, [][]float64{{0.6, 0.3, 0.1}, {0.1, 0.3, 0.6}},
+		[]string{"A"}, map[string]int{"A": 2})
+	cpdX, _ := factors.NewCLGCPD("X", []string{"A"}, []string{}, map[string]int{"A": 2},
+		map[string]factors.GaussianParams{
+			"0": {Mean: 0, Variance: 1, Coefficients: map[string]float64{}},
+			"1": {Mean: 3, Variance: 0.5, Coefficients: map[string]float64{}},
+		})
+	cpdY, _ := factors.NewCLGCPD("Y", []string{"B"}, []string{"X"}, map[string]int{"B": 3},
+		map[string]factors.GaussianParams{
+			"0": {Mean: 0, Variance: 0.5, Coefficients: map[string]float64{"X": 1}},
+			"1": {Mean: 1, Variance: 0.5, Coefficients: map[string]float64{"X": 1}},
+			"2": {Mean: 2, Variance: 0.5, Coefficients: map[string]float64{"X": -1}},
+		})
+	for _, err := range []error{bn.AddCPD(cpdA), bn.AddCPD(cpdB), bn.AddGaussianCPD(cpdX), bn.AddGaussianCPD(cpdY)} {
+		if err != nil {
+			t.Fatalf("Failed to add CPD: %v", err)
+		}
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create inference engine: %v", err)
+	}
+
+	evidence := models.Sample{Continuous: map[string]float64{"Y": 2.5}}
+	result, err := ve.GibbsSample(evidence, GibbsOptions{Samples: 2000, BurnIn: 100, Chains: 2, Seed: 1})
+	if err != nil {
+		t.Fatalf("GibbsSample failed: %v", err)
+	}
+	if len(result.Samples) != 4000 || result.Samples[0].Continuous["Y"] != 2.5 {
+		t.Fatalf("Expected 4000 draws holding the evidence, got %d", len(result.Samples))
+	}
+	if d := result.Diagnostics; d.Samples != 4000 || math.IsNaN(d.RHat) || d.RHat > 1.1 {
+		t.Errorf("Expected two converged chains of 2000 draws, got %+v", d)
+	}
+
+	// The Rao-Blackwellized estimates match exact inference
+	for _, v := range []string{"A", "B"} {
+		exact, err := ve.QueryDiscrete([]string{v}, evidence)
+		if err != nil {
+			t.Fatalf("QueryDiscrete failed: %v", err)
+		}
+		for state, want := range exact.Values {
+			if got := result.Marginals[v][state]; math.Abs(got-want) > 0.03 {
+				t.Errorf("P(%s=%d | Y): expected %.3f, got %.3f", v, state, want, got)
+			}
+		}
+	}
+	exact, err := ve.QueryMixed([]string{"X"}, evidence)
+	if err != nil {
+		t.Fatalf("QueryMixed failed: %v", err)
+	}
+	gaussian := exact.Components[0].Gaussian
+	if want := gaussian.MeanOf("X"); math.Abs(result.Means["X"]-want) > 0.05 {
+		t.Errorf("E[X | Y]: expected %.3f, got %.3f", want, result.Means["X"])
+	}
+	if want := gaussian.CovarianceOf("X", "X"); math.Abs(result.Variances["X"]-want) > 0.1*want {
+		t.Errorf("Var[X | Y]: expected %.3f, got %.3f", want, result.Variances["X"])
+	}
+	sum := 0.0
+	for _, s := range result.Samples {
+		sum += s.Continuous["X"]
+	}
+	if got := sum / float64(len(result.Samples)); math.Abs(got-gaussian.MeanOf("X")) > 0.15 {
+		t.Errorf("Expected sampled X to average %.3f, got %.3f", gaussian.MeanOf("X"), got)
+	}
+
+	if _, err := ve.GibbsSample(evidence, GibbsOptions{}); err == nil {
+		t.Error("Expected an error for zero samples")
+	}
+}
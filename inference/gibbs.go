@@ -0,0 +1,267 @@
+package inference
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// gibbsInitAttempts bounds the forward draws tried for a starting state of
+// positive probability under the evidence
+const gibbsInitAttempts = 100
+
+// GibbsOptions configures a blocked Gibbs sampler
+type GibbsOptions struct {
+	Variables []string // Variables the draws must cover; every node if empty, otherwise irrelevant nodes are pruned
+	Samples   int      // Sweeps kept per chain
+	BurnIn    int      // Sweeps discarded at the start of each chain
+	Thin      int      // Sweeps per kept draw, 1 if zero
+	Chains    int      // Independent chains, 1 if zero
+	Seed      int64    // Seed of the first chain; chain c uses Seed + c
+}
+
+// GibbsResult holds the draws of a blocked Gibbs sampler and its
+// Rao-Blackwellized estimates. Marginals, Means and Variances average the
+// exact conditional distributions met at each kept sweep rather than the
+// sampled values, so they have lower variance than averages of Samples.
+type GibbsResult struct {
+	// Samples holds the hidden states and values of each kept sweep, with the
+	// evidence, chain after chain
+	Samples []models.Sample
+	// Marginals holds the posterior distribution of each hidden discrete
+	// variable
+	Marginals map[string][]float64
+	// Means and Variances hold the posterior moments of each hidden
+	// continuous variable
+	Means     map[string]float64
+	Variances map[string]float64
+	// Diagnostics are computed from the traces of the log joint probability
+	// of the sampled discrete states with the evidence
+	Diagnostics SamplerDiagnostics
+}
+
+// GibbsSample draws from the posterior of a conditional linear Gaussian
+// network given mixed evidence by blocked, Rao-Blackwellized Gibbs sampling.
+// Each sweep draws every hidden discrete variable from its full conditional
+// with all continuous variables integrated out analytically, then draws the
+// hidden continuous variables jointly from their exact Gaussian posterior
+// given the discrete states. This mixes far better than single-site Gibbs
+// when continuous variables are strongly coupled to their discrete parents.
+// Each discrete update costs one Gaussian elimination per state, so the
+// sampler suits networks with many discrete variables of small cardinality,
+// where QueryMixed's enumeration of configurations is too large.
+func (ve *VariableElimination) GibbsSample(evidence models.Sample, opts GibbsOptions) (*GibbsResult, error) {
+	if opts.Samples <= 0 {
+		return nil, fmt.Errorf("gibbs sampling needs a positive number of samples, got %d", opts.Samples)
+	}
+	if opts.BurnIn < 0 || opts.Thin < 0 || opts.Chains < 0 {
+		return nil, fmt.Errorf("burn-in, thinning and chains cannot be negative")
+	}
+	thin, chains := max(opts.Thin, 1), max(opts.Chains, 1)
+
+	for v := range evidence.Discrete {
+		if !ve.Model.IsDiscrete(v) {
+			return nil, fmt.Errorf("evidence variable %s is not discrete: %w", v, ErrUnsupportedVariable)
+		}
+	}
+	if err := ve.Model.ValidateEvidence(evidence.Discrete); err != nil {
+		return nil, err
+	}
+	for v := range evidence.Continuous {
+		if !ve.Model.IsContinuous(v) {
+			return nil, fmt.Errorf("evidence variable %s is not continuous: %w", v, ErrUnsupportedVariable)
+		}
+	}
+	observed := observedSet(evidence.Discrete)
+	for v := range evidence.Continuous {
+		observed[v] = true
+	}
+	for _, v := range opts.Variables {
+		if !ve.Model.DAG.HasNode(v) {
+			return nil, fmt.Errorf("variable %s: %w", v, models.ErrUnknownVariable)
+		}
+	}
+
+	nodes := ve.Model.Nodes()
+	if len(opts.Variables) > 0 {
+		nodes = ve.Model.RequisiteNodes(opts.Variables, observed)
+	}
+	order, err := ve.Model.DAG.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+	inNodes := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		inNodes[node] = true
+	}
+	var discrete, continuous []string
+	for _, node := range order {
+		if !inNodes[node] || observed[node] {
+			continue
+		}
+		if ve.Model.IsDiscrete(node) {
+			discrete = append(discrete, node)
+		} else {
+			continuous = append(continuous, node)
+		}
+	}
+	cardinality := make(map[string]int, len(discrete))
+	for _, v := range discrete {
+		cpd, ok := ve.Model.DiscreteCPD(v)
+		if !ok {
+			return nil, fmt.Errorf("no discrete CPD for node %s: %w", v, models.ErrMissingCPD)
+		}
+		cardinality[v] = cpd.GetCardinality()
+	}
+
+	result := &GibbsResult{
+		Marginals: make(map[string][]float64, len(discrete)),
+		Means:     make(map[string]float64, len(continuous)),
+		Variances: make(map[string]float64, len(continuous)),
+	}
+	for _, v := range discrete {
+		result.Marginals[v] = make([]float64, cardinality[v])
+	}
+	secondMoments := make(map[string]float64, len(continuous))
+	traces := make([][]float64, chains)
+
+	for c := 0; c < chains; c++ {
+		rng := rand.New(rand.NewSource(opts.Seed + int64(c)))
+		assignment, logW, err := ve.gibbsStart(nodes, discrete, evidence, rng)
+		if err != nil {
+			return nil, err
+		}
+
+		for sweep := 0; sweep < opts.BurnIn+opts.Samples*thin; sweep++ {
+			kept := sweep >= opts.BurnIn && (sweep-opts.BurnIn)%thin == thin-1
+
+			// Discrete block, one variable at a time with the continuous
+			// variables integrated out
+			for _, v := range discrete {
+				logP := make([]float64, cardinality[v])
+				for state := range logP {
+					assignment[v] = state
+					if logP[state], _, err = ve.configurationPosterior(nodes, assignment, evidence.Continuous, nil, nil); err != nil {
+						return nil, err
+					}
+				}
+				probs := normalizeLog(logP)
+				assignment[v] = sampleIndex(probs, rng)
+				logW = logP[assignment[v]]
+				if kept {
+					for state, p := range probs {
+						result.Marginals[v][state] += p
+					}
+				}
+			}
+			if !kept {
+				continue
+			}
+
+			// Continuous block, jointly from the Gaussian posterior given the
+			// discrete states
+			sample := models.Sample{Discrete: make(map[string]int, len(assignment)), Continuous: make(map[string]float64)}
+			for v, s := range assignment {
+				sample.Discrete[v] = s
+			}
+			for v, x := range evidence.Continuous {
+				sample.Continuous[v] = x
+			}
+			if len(continuous) > 0 {
+				_, gaussian, err := ve.configurationPosterior(nodes, assignment, evidence.Continuous, continuous, nil)
+				if err != nil {
+					return nil, err
+				}
+				draws, err := gaussian.Sample(1, rng)
+				if err != nil {
+					return nil, err
+				}
+				for _, v := range continuous {
+					sample.Continuous[v] = draws[0][v]
+					mean := gaussian.MeanOf(v)
+					result.Means[v] += mean
+					secondMoments[v] += gaussian.CovarianceOf(v, v) + mean*mean
+				}
+			}
+			result.Samples = append(result.Samples, sample)
+			traces[c] = append(traces[c], logW)
+		}
+	}
+
+	n := float64(len(result.Samples))
+	for _, marginal := range result.Marginals {
+		for state := range marginal {
+			marginal[state] /= n
+		}
+	}
+	for _, v := range continuous {
+		result.Means[v] /= n
+		result.Variances[v] = secondMoments[v]/n - result.Means[v]*result.Means[v]
+	}
+	result.Diagnostics = NewSamplerDiagnostics(traces, 0, 0)
+	return result, nil
+}
+
+// gibbsStart draws the hidden discrete variables forward from their CPDs
+// until the state has positive probability under the evidence, returning it
+// with its log joint probability
+func (ve *VariableElimination) gibbsStart(nodes, discrete []string, evidence models.Sample,
+	rng *rand.Rand) (map[string]int, float64, error) {
+	assignment := make(map[string]int, len(discrete)+len(evidence.Discrete))
+	for v, s := range evidence.Discrete {
+		assignment[v] = s
+	}
+	for attempt := 0; attempt < gibbsInitAttempts; attempt++ {
+		for _, v := range discrete {
+			cpd, _ := ve.Model.DiscreteCPD(v)
+			state, err := cpd.Sample(assignment, rng)
+			if err != nil {
+				return nil, 0, fmt.Errorf("node %s: %w", v, err)
+			}
+			assignment[v] = state
+		}
+		logW, _, err := ve.configurationPosterior(nodes, assignment, evidence.Continuous, nil, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !math.IsInf(logW, -1) {
+			return assignment, logW, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("no starting state of positive probability in %d draws: %w",
+		gibbsInitAttempts, ErrImpossibleEvidence)
+}
+
+// normalizeLog returns the probabilities proportional to exp(logP)
+func normalizeLog(logP []float64) []float64 {
+	maxLog := math.Inf(-1)
+	for _, lp := range logP {
+		maxLog = math.Max(maxLog, lp)
+	}
+	probs := make([]float64, len(logP))
+	total := 0.0
+	for i, lp := range logP {
+		probs[i] = math.Exp(lp - maxLog)
+		total += probs[i]
+	}
+	for i := range probs {
+		probs[i] /= total
+	}
+	return probs
+}
+
+// sampleIndex draws an index with the given probabilities
+func sampleIndex(probs []float64, rng *rand.Rand) int {
+	cumulative := make([]float64, len(probs))
+	total := 0.0
+	for i, p := range probs {
+		total += p
+		cumulative[i] = total
+	}
+	u := rng.Float64() * total
+	idx := sort.Search(len(cumulative), func(k int) bool { return cumulative[k] > u })
+	return min(idx, len(probs)-1)
+}
@@ -0,0 +1,10 @@
+package inference
+
+// observedSet returns the set of variables an evidence map assigns
+func observedSet[T any](evidence map[string]T) map[string]bool {
+	observed := make(map[string]bool, len(evidence))
+	for v := range evidence {
+		observed[v] = true
+	}
+	return observed
+}
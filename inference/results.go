@@ -0,0 +1,250 @@
+package inference
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// hasVariable reports whether v is a variable of the result
+func hasVariable(variables []string, v string) bool {
+	for _, w := range variables {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ProbabilityOf returns the posterior probability of an assignment of the
+// discrete query variables, summing over those left out
+func (r *MixedQueryResult) ProbabilityOf(assignment map[string]int) (float64, error) {
+	for v := range assignment {
+		if !hasVariable(r.DiscreteVariables, v) {
+			return 0, fmt.Errorf("%s is not a discrete query variable: %w", v, ErrUnsupportedVariable)
+		}
+	}
+	total := 0.0
+	for _, c := range r.Components {
+		if matches(c.Discrete, assignment) {
+			total += c.Weight
+		}
+	}
+	return total, nil
+}
+
+// MarginalFor returns the posterior of one query variable. For a discrete
+// variable there is one component per state with positive probability; for a
+// continuous one the components keep their weights, with Gaussians over the
+// variable alone and no discrete assignment.
+func (r *MixedQueryResult) MarginalFor(variable string) (*MixedQueryResult, error) {
+	switch {
+	case hasVariable(r.DiscreteVariables, variable):
+		weights := make(map[int]float64)
+		for _, c := range r.Components {
+			weights[c.Discrete[variable]] += c.Weight
+		}
+		states := make([]int, 0, len(weights))
+		for s := range weights {
+			states = append(states, s)
+		}
+		sort.Ints(states)
+
+		marginal := &MixedQueryResult{DiscreteVariables: []string{variable}, ContinuousVariables: []string{}}
+		for _, s := range states {
+			marginal.Components = append(marginal.Components, MixtureComponent{
+				Discrete: map[string]int{variable: s},
+				Weight:   weights[s],
+			})
+		}
+		return marginal, nil
+
+	case hasVariable(r.ContinuousVariables, variable):
+		marginal := &MixedQueryResult{DiscreteVariables: []string{}, ContinuousVariables: []string{variable}}
+		others := make([]string, 0, len(r.ContinuousVariables)-1)
+		for _, v := range r.ContinuousVariables {
+			if v != variable {
+				others = append(others, v)
+			}
+		}
+		for _, c := range r.Components {
+			gaussian, err := c.Gaussian.Marginalize(others)
+			if err != nil {
+				return nil, err
+			}
+			marginal.Components = append(marginal.Components, MixtureComponent{
+				Discrete: map[string]int{},
+				Weight:   c.Weight,
+				Gaussian: gaussian,
+			})
+		}
+		return marginal, nil
+
+	default:
+		return nil, fmt.Errorf("%s is not a query variable: %w", variable, ErrUnsupportedVariable)
+	}
+}
+
+// TopStates returns the n most probable assignments of the discrete query
+// variables, highest first. n <= 0 returns every component.
+func (r *MixedQueryResult) TopStates(n int) []factors.StateProbability {
+	order := make([]int, len(r.Components))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return r.Components[order[a]].Weight > r.Components[order[b]].Weight
+	})
+	if n <= 0 || n > len(order) {
+		n = len(order)
+	}
+
+	top := make([]factors.StateProbability, n)
+	for i, idx := range order[:n] {
+		c := r.Components[idx]
+		assignment := make(map[string]int, len(c.Discrete))
+		for v, s := range c.Discrete {
+			assignment[v] = s
+		}
+		top[i] = factors.StateProbability{Assignment: assignment, Probability: c.Weight}
+	}
+	return top
+}
+
+// Sample draws n joint values of the query variables from the posterior. Each
+// draw picks a component by weight; the draws of a component are taken from
+// its Gaussian together and the result is shuffled.
+func (r *MixedQueryResult) Sample(n int, rng *rand.Rand) ([]models.Sample, error) {
+	if len(r.Components) == 0 {
+		return nil, fmt.Errorf("result has no components: %w", ErrImpossibleEvidence)
+	}
+	cumulative := make([]float64, len(r.Components))
+	total := 0.0
+	for i, c := range r.Components {
+		total += c.Weight
+		cumulative[i] = total
+	}
+	counts := make([]int, len(r.Components))
+	for i := 0; i < n; i++ {
+		u := rng.Float64() * total
+		idx := sort.Search(len(cumulative), func(k int) bool { return cumulative[k] > u })
+		counts[min(idx, len(cumulative)-1)]++
+	}
+
+	samples := make([]models.Sample, 0, n)
+	for i, c := range r.Components {
+		if counts[i] == 0 {
+			continue
+		}
+		var draws []map[string]float64
+		if c.Gaussian != nil {
+			var err error
+			if draws, err = c.Gaussian.Sample(counts[i], rng); err != nil {
+				return nil, err
+			}
+		}
+		for k := 0; k < counts[i]; k++ {
+			sample := models.Sample{
+				Discrete:   make(map[string]int, len(c.Discrete)),
+				Continuous: make(map[string]float64),
+			}
+			for v, s := range c.Discrete {
+				sample.Discrete[v] = s
+			}
+			if draws != nil {
+				sample.Continuous = draws[k]
+			}
+			samples = append(samples, sample)
+		}
+	}
+	rng.Shuffle(len(samples), func(a, b int) { samples[a], samples[b] = samples[b], samples[a] })
+	return samples, nil
+}
+
+// Quantile returns the p-quantile of a continuous query variable under the
+// mixture posterior, found by bisection on the mixture CDF
+func (r *MixedQueryResult) Quantile(variable string, p float64) (float64, error) {
+	if !hasVariable(r.ContinuousVariables, variable) {
+		return 0, fmt.Errorf("%s is not a continuous query variable: %w", variable, ErrUnsupportedVariable)
+	}
+	if !(p > 0 && p < 1) {
+		return 0, fmt.Errorf("quantile %v is not in (0, 1)", p)
+	}
+	if len(r.Components) == 1 {
+		return r.Components[0].Gaussian.Quantile(variable, p)
+	}
+
+	// Every component quantile brackets the mixture quantile
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, c := range r.Components {
+		q, err := c.Gaussian.Quantile(variable, p)
+		if err != nil {
+			return 0, err
+		}
+		lo, hi = math.Min(lo, q), math.Max(hi, q)
+	}
+	total := 0.0
+	for _, c := range r.Components {
+		total += c.Weight
+	}
+	cdf := func(x float64) float64 {
+		sum := 0.0
+		for _, c := range r.Components {
+			f, _ := c.Gaussian.CDF(variable, x) // variable is in every component
+			sum += c.Weight * f
+		}
+		return sum / total
+	}
+	for i := 0; i < 200 && hi-lo > 1e-12*math.Max(1, math.Abs(lo)); i++ {
+		mid := (lo + hi) / 2
+		if cdf(mid) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2, nil
+}
+
+// CredibleInterval returns the equal-tailed interval holding the given
+// probability mass of a continuous query variable, e.g. level 0.95 for the
+// 2.5% and 97.5% quantiles of the mixture
+func (r *MixedQueryResult) CredibleInterval(variable string, level float64) (lo, hi float64, err error) {
+	if !(level > 0 && level < 1) {
+		return 0, 0, fmt.Errorf("credible level %v is not in (0, 1)", level)
+	}
+	if lo, err = r.Quantile(variable, (1-level)/2); err != nil {
+		return 0, 0, err
+	}
+	hi, err = r.Quantile(variable, (1+level)/2)
+	return lo, hi, err
+}
+
+// matches reports whether a component's assignment agrees with every
+// variable in want
+func matches(assignment, want map[string]int) bool {
+	for v, s := range want {
+		if assignment[v] != s {
+			return false
+		}
+	}
+	return true
+}
+
+// QueryLabeled is Query that also returns the marginal of each query variable
+// keyed by the network's state names, as DiscreteFactor.LabeledMarginals does
+func (ve *VariableElimination) QueryLabeled(variables []string, evidence map[string]int) (*factors.DiscreteFactor, map[string]map[string]float64, error) {
+	result, err := ve.Query(variables, evidence)
+	if err != nil {
+		return nil, nil, err
+	}
+	states := make(map[string][]string, len(result.Variables))
+	for _, v := range result.Variables {
+		states[v] = ve.Model.StateNames(v)
+	}
+	return result, result.LabeledMarginals(states), nil
+}
@@ -0,0 +1,76 @@
+package inference
+
+import (
+	"time"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// QueryStats reports the work done by one query, for monitoring and tuning
+// inference cost
+type QueryStats struct {
+	// Multiplications counts the factor products computed
+	Multiplications int
+	// MaxFactorSize is the number of entries in the largest intermediate
+	// factor: table entries for discrete factors, and K, h and g entries for
+	// canonical Gaussian factors
+	MaxFactorSize int
+	// EliminationOrder lists the variables summed or integrated out, in order.
+	// Mixed queries enumerate their hidden discrete variables instead and
+	// report the continuous order only.
+	EliminationOrder []string
+	// Configurations counts the discrete configurations a mixed query
+	// enumerated
+	Configurations int
+	// Approximate reports that the query was over the memory budget and was
+	// answered by the fallback engine, which records no counts
+	Approximate bool
+	// Duration is the wall time of the query
+	Duration time.Duration
+}
+
+// QueryWithStats is Query that also reports the cost of the query
+func (ve *VariableElimination) QueryWithStats(variables []string, evidence map[string]int) (*factors.DiscreteFactor, *QueryStats, error) {
+	stats := &QueryStats{}
+	start := time.Now()
+	result, err := ve.query(variables, evidence, stats)
+	stats.Duration = time.Since(start)
+	return result, stats, err
+}
+
+// QueryMixedWithStats is QueryMixed that also reports the cost of the query
+func (ve *VariableElimination) QueryMixedWithStats(variables []string, evidence models.Sample) (*MixedQueryResult, *QueryStats, error) {
+	stats := &QueryStats{}
+	start := time.Now()
+	result, err := ve.queryMixed(variables, evidence, stats)
+	stats.Duration = time.Since(start)
+	return result, stats, err
+}
+
+// multiplied records a product of the given size; stats may be nil
+func (s *QueryStats) multiplied(size int) {
+	if s == nil {
+		return
+	}
+	s.Multiplications++
+	s.observe(size)
+}
+
+// observe records an intermediate factor of the given size; stats may be nil
+func (s *QueryStats) observe(size int) {
+	if s != nil && size > s.MaxFactorSize {
+		s.MaxFactorSize = size
+	}
+}
+
+// recordProducts returns a factor elimination callback recording each
+// product in stats, or nil if stats is nil
+func recordProducts[F interface{ Size() int }](stats *QueryStats) func(F) {
+	if stats == nil {
+		return nil
+	}
+	return func(f F) {
+		stats.multiplied(f.Size())
+	}
+}
@@ -3,7 +3,6 @@ package inference
 
 import (
 	"fmt"
-	"sort"
 
 	"github.com/JohnPierman/bngo/factors"
 	"github.com/JohnPierman/bngo/models"
@@ -12,6 +11,23 @@ import (
 // VariableElimination performs exact inference using variable elimination
 type VariableElimination struct {
 	Model *models.BayesianNetwork
+
+	// MaxInducedWidth, when positive, bounds the induced width of each query
+	MaxInducedWidth int
+	// WidthPolicy decides whether exceeding MaxInducedWidth warns or fails
+	WidthPolicy WidthPolicy
+	// MaxFactorSize and MaxMemory, when positive, bound the entries of any
+	// factor a query builds and the bytes of factor tables it holds at once
+	MaxFactorSize int
+	MaxMemory     int64
+	// Fallback, when set, answers the queries over the memory budget
+	Fallback ApproximateEngine
+
+	fixedEvidence map[string]int                     // evidence set by WithFixedEvidence
+	fixedFactors  map[string]*factors.DiscreteFactor // CPD factors pre-reduced by fixedEvidence
+
+	canonicalFactors map[string]*factors.CanonicalFactor // compiled continuous CPD factors
+	eliminationRank  map[string]int                      // compiled elimination order
 }
 
 // NewVariableElimination creates a new variable elimination inference engine
@@ -24,30 +40,31 @@ func NewVariableElimination(model *models.BayesianNetwork) (*VariableElimination
 
 // Query computes P(variables | evidence)
 func (ve *VariableElimination) Query(variables []string, evidence map[string]int) (*factors.DiscreteFactor, error) {
-	// Convert all CPDs to factors
-	factorList := make([]*factors.DiscreteFactor, 0)
-	for _, cpd := range ve.Model.GetCPDs() {
-		factor, err := cpd.ToFactor()
-		if err != nil {
-			return nil, err
-		}
-		factorList = append(factorList, factor)
+	return ve.query(variables, evidence, nil)
+}
+
+// query is Query recording its cost in stats when stats is not nil
+func (ve *VariableElimination) query(variables []string, evidence map[string]int, stats *QueryStats) (*factors.DiscreteFactor, error) {
+	if err := ve.checkQueryVariables(variables); err != nil {
+		return nil, err
+	}
+	evidence, err := ve.effectiveEvidence(evidence)
+	if err != nil {
+		return nil, err
 	}
 
-	// Reduce factors by evidence
-	reducedFactors := make([]*factors.DiscreteFactor, 0)
-	for _, factor := range factorList {
-		reduced, err := factor.Reduce(evidence)
-		if err != nil {
-			return nil, err
-		}
-		reducedFactors = append(reducedFactors, reduced)
+	// Convert the CPDs relevant to the query to factors reduced by evidence
+	reducedFactors, err := ve.reducedFactors(ve.Model.RequisiteNodes(variables, observedSet(evidence)), evidence)
+	if err != nil {
+		return nil, err
 	}
 
-	// Find variables to eliminate
+	// Find variables to eliminate among those left after pruning
 	allVars := make(map[string]bool)
-	for _, node := range ve.Model.Nodes() {
-		allVars[node] = true
+	for _, factor := range reducedFactors {
+		for _, v := range factor.Variables {
+			allVars[v] = true
+		}
 	}
 
 	queryVars := make(map[string]bool)
@@ -64,26 +81,41 @@ func (ve *VariableElimination) Query(variables []string, evidence map[string]int
 	for v := range allVars {
 		toEliminate = append(toEliminate, v)
 	}
-	sort.Strings(toEliminate)
+	ve.sortElimination(toEliminate)
+
+	if err := ve.checkInducedWidth(reducedFactors, toEliminate); err != nil {
+		return nil, err
+	}
+	if err := ve.checkBudget(reducedFactors, toEliminate); err != nil {
+		return ve.fallBack(err, variables, evidence, stats)
+	}
+	if stats != nil {
+		stats.EliminationOrder = toEliminate
+		for _, f := range reducedFactors {
+			stats.observe(f.Size())
+		}
+	}
 
 	// Eliminate variables one by one
+	observe := recordProducts[*factors.DiscreteFactor](stats)
 	currentFactors := reducedFactors
 	for _, v := range toEliminate {
-		currentFactors = ve.eliminateVariable(v, currentFactors)
+		if currentFactors, err = factors.Eliminate(v, currentFactors, observe); err != nil {
+			return nil, err
+		}
 	}
 
 	// Multiply remaining factors
 	if len(currentFactors) == 0 {
 		return nil, fmt.Errorf("no factors remaining after elimination")
 	}
-
-	result := currentFactors[0]
-	for i := 1; i < len(currentFactors); i++ {
-		newResult, err := result.Multiply(currentFactors[i])
-		if err != nil {
-			return nil, err
-		}
-		result = newResult
+	result, err := factors.Product(currentFactors, observe)
+	if err != nil {
+		return nil, err
+	}
+	if len(currentFactors) == 1 {
+		// Copy so normalizing cannot modify a factor shared with the cache
+		result = result.Copy()
 	}
 
 	// Normalize
@@ -94,73 +126,34 @@ func (ve *VariableElimination) Query(variables []string, evidence map[string]int
 	return result, nil
 }
 
-func (ve *VariableElimination) eliminateVariable(variable string, factorList []*factors.DiscreteFactor) []*factors.DiscreteFactor {
-	// Find factors containing the variable
-	relevant := make([]*factors.DiscreteFactor, 0)
-	irrelevant := make([]*factors.DiscreteFactor, 0)
-
-	for _, factor := range factorList {
-		contains := false
-		for _, v := range factor.Variables {
-			if v == variable {
-				contains = true
-				break
-			}
+// checkQueryVariables rejects query variables that are not discrete
+// variables of the model
+func (ve *VariableElimination) checkQueryVariables(variables []string) error {
+	for _, v := range variables {
+		if !ve.Model.DAG.HasNode(v) {
+			return fmt.Errorf("query variable %s: %w", v, models.ErrUnknownVariable)
 		}
-		if contains {
-			relevant = append(relevant, factor)
-		} else {
-			irrelevant = append(irrelevant, factor)
+		if !ve.Model.IsDiscrete(v) {
+			return fmt.Errorf("query variable %s is not discrete: %w", v, ErrUnsupportedVariable)
 		}
 	}
-
-	if len(relevant) == 0 {
-		return factorList
-	}
-
-	// Multiply relevant factors
-	product := relevant[0]
-	for i := 1; i < len(relevant); i++ {
-		newProduct, err := product.Multiply(relevant[i])
-		if err != nil {
-			// Skip on error
-			continue
-		}
-		product = newProduct
-	}
-
-	// Marginalize out the variable
-	marginalized, err := product.Marginalize([]string{variable})
-	if err != nil {
-		// If marginalization fails, return irrelevant factors
-		return irrelevant
-	}
-
-	// Return irrelevant + marginalized
-	result := append(irrelevant, marginalized)
-	return result
+	return nil
 }
 
 // MAP computes the maximum a posteriori assignment
 func (ve *VariableElimination) MAP(variables []string, evidence map[string]int) (map[string]int, error) {
-	// Convert all CPDs to factors
-	factorList := make([]*factors.DiscreteFactor, 0)
-	for _, cpd := range ve.Model.GetCPDs() {
-		factor, err := cpd.ToFactor()
-		if err != nil {
-			return nil, err
-		}
-		factorList = append(factorList, factor)
+	if err := ve.checkQueryVariables(variables); err != nil {
+		return nil, err
+	}
+	evidence, err := ve.effectiveEvidence(evidence)
+	if err != nil {
+		return nil, err
 	}
 
-	// Reduce factors by evidence
-	reducedFactors := make([]*factors.DiscreteFactor, 0)
-	for _, factor := range factorList {
-		reduced, err := factor.Reduce(evidence)
-		if err != nil {
-			return nil, err
-		}
-		reducedFactors = append(reducedFactors, reduced)
+	// Convert all CPDs to factors reduced by evidence
+	reducedFactors, err := ve.reducedFactors(ve.Model.Nodes(), evidence)
+	if err != nil {
+		return nil, err
 	}
 
 	// Find variables to eliminate (not in query or evidence)
@@ -183,26 +176,31 @@ func (ve *VariableElimination) MAP(variables []string, evidence map[string]int)
 	for v := range allVars {
 		toEliminate = append(toEliminate, v)
 	}
-	sort.Strings(toEliminate)
+	ve.sortElimination(toEliminate)
+
+	if err := ve.checkInducedWidth(reducedFactors, toEliminate); err != nil {
+		return nil, err
+	}
+	if err := ve.checkBudget(reducedFactors, toEliminate); err != nil {
+		return nil, err
+	}
 
 	// Eliminate variables using max-marginalization
 	currentFactors := reducedFactors
 	for _, v := range toEliminate {
-		currentFactors = ve.maxEliminateVariable(v, currentFactors)
+		currentFactors, err = factors.EliminateWith(v, currentFactors, (*factors.DiscreteFactor).MaxMarginalize, nil)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Multiply remaining factors
 	if len(currentFactors) == 0 {
 		return nil, fmt.Errorf("no factors remaining after elimination")
 	}
-
-	result := currentFactors[0]
-	for i := 1; i < len(currentFactors); i++ {
-		newResult, err := result.Multiply(currentFactors[i])
-		if err != nil {
-			return nil, err
-		}
-		result = newResult
+	result, err := factors.Product(currentFactors, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	// Find maximum assignment
@@ -227,48 +225,3 @@ func (ve *VariableElimination) MAP(variables []string, evidence map[string]int)
 
 	return assignment, nil
 }
-
-func (ve *VariableElimination) maxEliminateVariable(variable string, factorList []*factors.DiscreteFactor) []*factors.DiscreteFactor {
-	// Find factors containing the variable
-	relevant := make([]*factors.DiscreteFactor, 0)
-	irrelevant := make([]*factors.DiscreteFactor, 0)
-
-	for _, factor := range factorList {
-		contains := false
-		for _, v := range factor.Variables {
-			if v == variable {
-				contains = true
-				break
-			}
-		}
-		if contains {
-			relevant = append(relevant, factor)
-		} else {
-			irrelevant = append(irrelevant, factor)
-		}
-	}
-
-	if len(relevant) == 0 {
-		return factorList
-	}
-
-	// Multiply relevant factors
-	product := relevant[0]
-	for i := 1; i < len(relevant); i++ {
-		newProduct, err := product.Multiply(relevant[i])
-		if err != nil {
-			continue
-		}
-		product = newProduct
-	}
-
-	// Max-marginalize out the variable
-	marginalized, err := product.MaxMarginalize([]string{variable})
-	if err != nil {
-		return irrelevant
-	}
-
-	// Return irrelevant + marginalized
-	result := append(irrelevant, marginalized)
-	return result
-}
@@ -0,0 +1,484 @@
+package inference
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/JohnPierman/bngo/examples"
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// bruteForceMarginal computes P(variable | evidence) by enumerating the joint
+func bruteForceMarginal(bn *models.BayesianNetwork, variable string, evidence map[string]int) []float64 {
+	nodes := bn.Nodes()
+	result := make([]float64, bn.Cardinality[variable])
+	assignment := make(map[string]int)
+
+	var enumerate func(depth int)
+	enumerate = func(depth int) {
+		if depth == len(nodes) {
+			p := 1.0
+			for _, node := range nodes {
+				v, _ := bn.CPDs[node].GetValue(assignment[node], assignment)
+				p *= v
+			}
+			result[assignment[variable]] += p
+			return
+		}
+		node := nodes[depth]
+		if val, ok := evidence[node]; ok {
+			assignment[node] = val
+			enumerate(depth + 1)
+			return
+		}
+		for s := 0; s < bn.Cardinality[node]; s++ {
+			assignment[node] = s
+			enumerate(depth + 1)
+		}
+	}
+	enumerate(0)
+
+	sum := 0.0
+	for _, p := range result {
+		sum += p
+	}
+	for i := range result {
+		result[i] /= sum
+	}
+	return result
+}
+
+func TestQueryMatchesEnumeration(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("Failed to build model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		variable string
+		evidence map[string]int
+	}{
+		{"prior of leaf", "Letter", map[string]int{}},
+		{"barren descendants", "Intelligence", map[string]int{}},
+		{"d-separated evidence", "Difficulty", map[string]int{"SAT": 1}},
+		{"explaining away", "Difficulty", map[string]int{"Grade": 0, "SAT": 1}},
+		{"evidence on child", "Intelligence", map[string]int{"Letter": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ve.Query([]string{tt.variable}, tt.evidence)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			expected := bruteForceMarginal(bn, tt.variable, tt.evidence)
+			for i, p := range expected {
+				if math.Abs(result.Values[i]-p) > 1e-9 {
+					t.Errorf("State %d: expected %f, got %f", i, p, result.Values[i])
+				}
+			}
+		})
+	}
+}
+
+func TestArithmeticCircuit(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("Failed to build model: %v", err)
+	}
+	ac, err := CompileCircuit(bn)
+	if err != nil {
+		t.Fatalf("CompileCircuit failed: %v", err)
+	}
+	if nodes, edges := ac.Size(); nodes == 0 || edges == 0 {
+		t.Fatalf("Expected a non-empty circuit, got %d nodes and %d edges", nodes, edges)
+	}
+	if p, _ := ac.Probability(nil); math.Abs(p-1) > 1e-12 {
+		t.Errorf("Expected P() = 1, got %v", p)
+	}
+
+	// One evaluation gives every marginal, matching enumeration
+	for _, evidence := range []map[string]int{{}, {"Letter": 1}, {"Grade": 0, "SAT": 1}, {"Intelligence": 1, "Letter": 0}} {
+		marginals, err := ac.Marginals(evidence)
+		if err != nil {
+			t.Fatalf("Marginals failed: %v", err)
+		}
+		for _, v := range bn.Nodes() {
+			if _, observed := evidence[v]; observed {
+				continue
+			}
+			for s, want := range bruteForceMarginal(bn, v, evidence) {
+				if math.Abs(marginals[v][s]-want) > 1e-9 {
+					t.Errorf("P(%s=%d | %v): expected %f, got %f", v, s, evidence, want, marginals[v][s])
+				}
+			}
+		}
+	}
+
+	// P(e) is the normalizer of the joint over the evidence
+	ve, _ := NewVariableElimination(bn)
+	joint, _ := ve.Query([]string{"Grade", "SAT"}, nil)
+	want, _ := joint.ProbabilityOf(map[string]int{"Grade": 2, "SAT": 0})
+	if p, _ := ac.Probability(map[string]int{"Grade": 2, "SAT": 0}); math.Abs(p-want) > 1e-12 {
+		t.Errorf("Expected P(Grade=2, SAT=0) = %f, got %f", want, p)
+	}
+
+	// The circuit is safe to evaluate concurrently
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				evidence := map[string]int{"Letter": (g + i) % 2}
+				marginals, err := ac.Marginals(evidence)
+				if err != nil {
+					errs <- err
+					return
+				}
+				want := bruteForceMarginal(bn, "Intelligence", evidence)
+				if math.Abs(marginals["Intelligence"][1]-want[1]) > 1e-9 {
+					errs <- fmt.Errorf("concurrent P(Intelligence=1 | Letter=%d) = %f, expected %f",
+						evidence["Letter"], marginals["Intelligence"][1], want[1])
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if _, err := ac.Probability(map[string]int{"Grade": 7}); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState, got %v", err)
+	}
+	if _, err := ac.Probability(map[string]int{"Unknown": 0}); !errors.Is(err, models.ErrUnknownVariable) {
+		t.Errorf("Expected ErrUnknownVariable, got %v", err)
+	}
+	continuous, _ := examples.GetTemperatureModel()
+	if _, err := CompileCircuit(continuous); !errors.Is(err, models.ErrContinuousVariables) {
+		t.Errorf("Expected ErrContinuousVariables, got %v", err)
+	}
+}
+
+func TestWithFixedEvidence(t *testing.T) {
+	bn, _ := examples.GetStudentModel()
+	ve, _ := NewVariableElimination(bn)
+
+	fixed, err := ve.WithFixedEvidence(map[string]int{"Grade": 0})
+	if err != nil {
+		t.Fatalf("WithFixedEvidence failed: %v", err)
+	}
+
+	got, err := fixed.Query([]string{"Intelligence"}, map[string]int{"SAT": 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	want, _ := ve.Query([]string{"Intelligence"}, map[string]int{"Grade": 0, "SAT": 1})
+	for i := range want.Values {
+		if math.Abs(got.Values[i]-want.Values[i]) > 1e-9 {
+			t.Errorf("State %d: expected %f, got %f", i, want.Values[i], got.Values[i])
+		}
+	}
+
+	if _, err := fixed.Query([]string{"Intelligence"}, map[string]int{"Grade": 1}); err == nil {
+		t.Error("Expected error for evidence contradicting fixed evidence")
+	}
+}
+
+func TestExplain(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create inference engine: %v", err)
+	}
+
+	evidence := map[string]int{"Grade": 2, "SAT": 1}
+	explanation, err := ve.Explain("Intelligence", 1, evidence)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if len(explanation.Impacts) != 2 {
+		t.Fatalf("Expected 2 impacts, got %d", len(explanation.Impacts))
+	}
+
+	for _, impact := range explanation.Impacts {
+		rest := map[string]int{}
+		for k, v := range evidence {
+			if k != impact.Variable {
+				rest[k] = v
+			}
+		}
+		want := bruteForceMarginal(bn, "Intelligence", rest)[1]
+		if math.Abs(impact.PosteriorWithout-want) > 1e-9 {
+			t.Errorf("%s: expected posterior without finding %f, got %f", impact.Variable, want, impact.PosteriorWithout)
+		}
+		if impact.LogOddsChange <= 0 {
+			t.Errorf("%s: expected finding to support high intelligence, got %f", impact.Variable, impact.LogOddsChange)
+		}
+	}
+	first, second := explanation.Impacts[0], explanation.Impacts[1]
+	if math.Abs(first.LogOddsChange) < math.Abs(second.LogOddsChange) {
+		t.Errorf("Impacts not sorted by magnitude: %+v", explanation.Impacts)
+	}
+}
+
+func TestCompiledConcurrentQueries(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("Failed to build model: %v", err)
+	}
+	cm, err := bn.Compile()
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	ve := NewCompiledVariableElimination(cm)
+
+	// The compiled snapshot must not see later edits to the source network
+	delete(bn.CPDs, "Letter")
+
+	evidences := []map[string]int{
+		{},
+		{"Letter": 1},
+		{"Grade": 0, "SAT": 1},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 8*len(evidences))
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, evidence := range evidences {
+				result, err := ve.Query([]string{"Intelligence"}, evidence)
+				if err != nil {
+					errs <- err.Error()
+					return
+				}
+				expected := bruteForceMarginal(cm.Network(), "Intelligence", evidence)
+				for i, p := range expected {
+					if math.Abs(result.Values[i]-p) > 1e-9 {
+						errs <- "compiled query does not match enumeration"
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for msg := range errs {
+		t.Error(msg)
+	}
+}
+
+func TestQueryCustomCPD(t *testing.T) {
+	bn, _ := models.NewBayesianNetwork([][2]string{{"Flu", "Fever"}, {"Cold", "Fever"}})
+	flu, _ := factors.NewTabularCPD("Flu", 2, [][]float64{{0.7, 0.3}}, []string{}, map[string]int{})
+	cold, _ := factors.NewTabularCPD("Cold", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	fever, _ := factors.NewNoisyOrCPD("Fever", map[string]float64{"Flu": 0.8, "Cold": 0.4}, 0.1)
+	_ = bn.AddCPD(flu)
+	_ = bn.AddCPD(cold)
+	if err := bn.AddCustomCPD(fever); err != nil {
+		t.Fatalf("AddCustomCPD failed: %v", err)
+	}
+
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	result, err := ve.Query([]string{"Flu"}, map[string]int{"Fever": 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// P(Fever=1 | Flu) averaged over Cold
+	onGivenFlu := func(f float64) float64 {
+		return 0.5*(1-0.9*(1-0.8*f)) + 0.5*(1-0.9*(1-0.8*f)*0.6)
+	}
+	joint1 := 0.3 * onGivenFlu(1)
+	joint0 := 0.7 * onGivenFlu(0)
+	expected := joint1 / (joint0 + joint1)
+	if math.Abs(result.Values[1]-expected) > 1e-9 {
+		t.Errorf("P(Flu=1 | Fever=1) = %f, expected %f", result.Values[1], expected)
+	}
+}
+
+func TestQueryWithStats(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("Failed to build model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	want, err := ve.Query([]string{"Letter"}, map[string]int{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	got, stats, err := ve.QueryWithStats([]string{"Letter"}, map[string]int{})
+	if err != nil {
+		t.Fatalf("QueryWithStats failed: %v", err)
+	}
+	for i := range want.Values {
+		if math.Abs(got.Values[i]-want.Values[i]) > 1e-12 {
+			t.Errorf("P(Letter=%d) = %v, want %v", i, got.Values[i], want.Values[i])
+		}
+	}
+
+	// SAT is pruned as barren; the other ancestors of Letter are summed out
+	eliminated := make(map[string]bool)
+	for _, v := range stats.EliminationOrder {
+		eliminated[v] = true
+	}
+	if len(eliminated) != 3 || !eliminated["Difficulty"] || !eliminated["Intelligence"] || !eliminated["Grade"] {
+		t.Errorf("EliminationOrder = %v, want Difficulty, Intelligence and Grade", stats.EliminationOrder)
+	}
+	if stats.Multiplications == 0 {
+		t.Error("no multiplications recorded")
+	}
+	// P(Grade | Difficulty, Intelligence) alone has 12 entries
+	if stats.MaxFactorSize < 12 {
+		t.Errorf("MaxFactorSize = %d, want at least 12", stats.MaxFactorSize)
+	}
+	if stats.Duration <= 0 {
+		t.Errorf("Duration = %v, want positive", stats.Duration)
+	}
+}
+
+func TestQueryValidatesEvidence(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("Failed to build model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    []string
+		evidence map[string]int
+		want     error
+	}{
+		{"unknown evidence variable", []string{"Letter"}, map[string]int{"Grdae": 0}, models.ErrUnknownVariable},
+		{"state too large", []string{"Letter"}, map[string]int{"Grade": 3}, ErrInvalidState},
+		{"negative state", []string{"Letter"}, map[string]int{"SAT": -1}, ErrInvalidState},
+		{"unknown query variable", []string{"Lettre"}, map[string]int{}, models.ErrUnknownVariable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ve.Query(tt.query, tt.evidence); !errors.Is(err, tt.want) {
+				t.Errorf("Query error = %v, want %v", err, tt.want)
+			}
+			if _, err := ve.MAP(tt.query, tt.evidence); !errors.Is(err, tt.want) {
+				t.Errorf("MAP error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ve.WithFixedEvidence(map[string]int{"Grade": 5}); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("WithFixedEvidence error = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestQueryLabeled(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("Failed to build model: %v", err)
+	}
+	if err := bn.CPDs["Intelligence"].SetStateNames("Intelligence", []string{"low", "high"}); err != nil {
+		t.Fatalf("SetStateNames failed: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	result, labeled, err := ve.QueryLabeled([]string{"Intelligence", "Difficulty"}, map[string]int{"Grade": 0})
+	if err != nil {
+		t.Fatalf("QueryLabeled failed: %v", err)
+	}
+	intelligence, _ := result.MarginalFor("Intelligence")
+	if got := labeled["Intelligence"]; len(got) != 2 || got["low"] != intelligence[0] || got["high"] != intelligence[1] {
+		t.Errorf("Intelligence marginal = %v, want low=%v high=%v", got, intelligence[0], intelligence[1])
+	}
+	// Difficulty has no state names and is keyed by index
+	difficulty, _ := result.MarginalFor("Difficulty")
+	if got := labeled["Difficulty"]; len(got) != 2 || got["0"] != difficulty[0] || got["1"] != difficulty[1] {
+		t.Errorf("Difficulty marginal = %v, want 0=%v 1=%v", got, difficulty[0], difficulty[1])
+	}
+}
+
+func TestMemoryBudget(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("Failed to build model: %v", err)
+	}
+	ve, err := NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	want, stats, err := ve.QueryWithStats([]string{"Letter"}, map[string]int{"SAT": 1})
+	if err != nil {
+		t.Fatalf("QueryWithStats failed: %v", err)
+	}
+
+	// A budget of exactly the largest factor admits the query
+	ve.SetMemoryBudget(stats.MaxFactorSize, 0, nil)
+	if _, err := ve.Query([]string{"Letter"}, map[string]int{"SAT": 1}); err != nil {
+		t.Errorf("Query within budget failed: %v", err)
+	}
+
+	var budget *BudgetExceededError
+	ve.SetMemoryBudget(stats.MaxFactorSize-1, 0, nil)
+	if _, err := ve.Query([]string{"Letter"}, map[string]int{"SAT": 1}); !errors.As(err, &budget) {
+		t.Fatalf("Query over factor budget returned %v, want *BudgetExceededError", err)
+	}
+	if budget.FactorSize != stats.MaxFactorSize {
+		t.Errorf("predicted largest factor %d, measured %d", budget.FactorSize, stats.MaxFactorSize)
+	}
+	if _, err := ve.MAP([]string{"Letter"}, map[string]int{"SAT": 1}); !errors.As(err, &budget) {
+		t.Errorf("MAP over factor budget returned %v, want *BudgetExceededError", err)
+	}
+	ve.SetMemoryBudget(0, bytesPerEntry, nil)
+	if _, err := ve.Query([]string{"Letter"}, map[string]int{"SAT": 1}); !errors.As(err, &budget) {
+		t.Errorf("Query over memory budget returned %v, want *BudgetExceededError", err)
+	}
+
+	fallback, err := NewLikelihoodWeightingEngine(bn, 20000, 1)
+	if err != nil {
+		t.Fatalf("NewLikelihoodWeightingEngine failed: %v", err)
+	}
+	ve.SetMemoryBudget(1, 0, fallback)
+	got, stats, err := ve.QueryWithStats([]string{"Letter"}, map[string]int{"SAT": 1})
+	if err != nil {
+		t.Fatalf("Query with fallback failed: %v", err)
+	}
+	if !stats.Approximate {
+		t.Error("stats do not report the fallback")
+	}
+	for i := range want.Values {
+		if math.Abs(got.Values[i]-want.Values[i]) > 0.02 {
+			t.Errorf("P(Letter=%d | SAT=1) = %v, want about %v", i, got.Values[i], want.Values[i])
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package ml
+
+import (
+	"fmt"
+
+	"github.com/JohnPierman/bngo/estimators"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// BNClassifier classifies with a general Bayesian network over the features
+// and the class, predicting from the class posterior given every observed
+// feature. The structure is given, or learned from discrete data by hill
+// climbing on BIC.
+type BNClassifier struct {
+	network
+	Edges       [][2]string // Structure over the features and the target, learned by Fit if nil
+	MaxIndegree int         // Maximum parents per node when learning the structure, 0 for no limit
+}
+
+// NewBNClassifier creates an unfitted classifier with the given structure,
+// which must include the target; nil edges make Fit learn one
+func NewBNClassifier(edges [][2]string) *BNClassifier {
+	return &BNClassifier{Edges: edges}
+}
+
+// Fit learns the structure if none is given, then the CPDs. Features outside
+// the structure are ignored.
+func (c *BNClassifier) Fit(X []models.Sample, y []int) error {
+	target := c.target()
+	edges := c.Edges
+	var isolated []string
+	if edges == nil {
+		discrete, continuous, err := features(X)
+		if err != nil {
+			return err
+		}
+		if len(continuous) > 0 {
+			return fmt.Errorf("learning a structure needs discrete features, %s is continuous", continuous[0])
+		}
+		samples, _, err := withClasses(X, y, target)
+		if err != nil {
+			return err
+		}
+		data := make([]map[string]int, len(samples))
+		for i, s := range samples {
+			data[i] = s.Discrete
+		}
+		hc := estimators.NewHillClimb(data)
+		hc.SetMaxIndegree(c.MaxIndegree)
+		dag, err := hc.Estimate()
+		if err != nil {
+			return fmt.Errorf("structure learning: %w", err)
+		}
+		edges = dag.Edges()
+		isolated = append(discrete, target)
+	}
+
+	bn, err := models.NewBayesianNetwork(edges)
+	if err != nil {
+		return err
+	}
+	for _, v := range isolated {
+		bn.DAG.AddNode(v)
+	}
+	if !bn.DAG.HasNode(target) {
+		return fmt.Errorf("structure has no target %s: %w", target, models.ErrUnknownVariable)
+	}
+	return c.fit(bn, X, y)
+}
@@ -0,0 +1,184 @@
+// Package ml wraps Bayesian network models as classifiers with a common
+// Fit / Predict / PredictProba interface, so they can stand in for other
+// classifiers in Go machine learning pipelines
+package ml
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/JohnPierman/bngo/inference"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// DefaultTarget names the class node of a classifier's network unless its
+// Target is set
+const DefaultTarget = "class"
+
+// Classifier learns to predict a discrete class from features. Rows of X
+// hold the features of one sample by name, discrete features as states and
+// continuous ones as values; a feature missing from a row is unobserved.
+// Classes are the states 0, 1, ... of y.
+type Classifier interface {
+	// Fit learns the classifier from rows of features and their classes
+	Fit(X []models.Sample, y []int) error
+	// Predict returns the most probable class of each row
+	Predict(X []models.Sample) ([]int, error)
+	// PredictProba returns the probability of each class for each row
+	PredictProba(X []models.Sample) ([][]float64, error)
+}
+
+var (
+	_ Classifier = (*NaiveBayes)(nil)
+	_ Classifier = (*TAN)(nil)
+	_ Classifier = (*BNClassifier)(nil)
+)
+
+// network is the fitted state shared by the classifiers: a network with the
+// class as one node, queried for the class posterior of each row
+type network struct {
+	Target  string                  // Name of the class node, DefaultTarget if empty
+	Network *models.BayesianNetwork // Fitted network, nil before Fit
+	Classes int                     // Number of classes seen in training
+	engine  *inference.VariableElimination
+}
+
+func (n *network) target() string {
+	if n.Target == "" {
+		return DefaultTarget
+	}
+	return n.Target
+}
+
+// PredictProba returns the posterior probability of each class for each row.
+// Features the network does not hold are ignored.
+func (n *network) PredictProba(X []models.Sample) ([][]float64, error) {
+	if n.engine == nil {
+		return nil, fmt.Errorf("classifier is not fitted")
+	}
+	target := n.target()
+	proba := make([][]float64, len(X))
+	for i, row := range X {
+		evidence := models.Sample{Discrete: make(map[string]int), Continuous: make(map[string]float64)}
+		for v, s := range row.Discrete {
+			if v != target && n.Network.DAG.HasNode(v) {
+				evidence.Discrete[v] = s
+			}
+		}
+		for v, x := range row.Continuous {
+			if v != target && n.Network.DAG.HasNode(v) {
+				evidence.Continuous[v] = x
+			}
+		}
+		posterior, err := n.engine.QueryDiscrete([]string{target}, evidence)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		proba[i] = append([]float64(nil), posterior.Values...)
+	}
+	return proba, nil
+}
+
+// Predict returns the most probable class of each row, the lowest on ties
+func (n *network) Predict(X []models.Sample) ([]int, error) {
+	proba, err := n.PredictProba(X)
+	if err != nil {
+		return nil, err
+	}
+	predictions := make([]int, len(proba))
+	for i, p := range proba {
+		for k := range p {
+			if p[k] > p[predictions[i]] {
+				predictions[i] = k
+			}
+		}
+	}
+	return predictions, nil
+}
+
+// fit fits the network to the rows with their classes added under the
+// target and prepares it for queries
+func (n *network) fit(bn *models.BayesianNetwork, X []models.Sample, y []int) error {
+	samples, classes, err := withClasses(X, y, n.target())
+	if err != nil {
+		return err
+	}
+	if err := bn.DeclareCardinality(n.target(), classes); err != nil {
+		return err
+	}
+	if err := bn.FitMixed(samples); err != nil {
+		return err
+	}
+	engine, err := inference.NewVariableElimination(bn)
+	if err != nil {
+		return err
+	}
+	n.Network, n.Classes, n.engine = bn, classes, engine
+	return nil
+}
+
+// withClasses returns copies of the rows holding their class under target,
+// and the number of classes
+func withClasses(X []models.Sample, y []int, target string) ([]models.Sample, int, error) {
+	if len(X) != len(y) {
+		return nil, 0, fmt.Errorf("got %d rows and %d classes", len(X), len(y))
+	}
+	if len(X) == 0 {
+		return nil, 0, fmt.Errorf("no training rows")
+	}
+	classes := 0
+	samples := make([]models.Sample, len(X))
+	for i, row := range X {
+		if y[i] < 0 {
+			return nil, 0, fmt.Errorf("row %d has negative class %d", i, y[i])
+		}
+		if _, ok := row.Discrete[target]; ok {
+			return nil, 0, fmt.Errorf("row %d has a feature named like the target %s", i, target)
+		}
+		if _, ok := row.Continuous[target]; ok {
+			return nil, 0, fmt.Errorf("row %d has a feature named like the target %s", i, target)
+		}
+		classes = max(classes, y[i]+1)
+		s := models.Sample{Discrete: map[string]int{target: y[i]}, Continuous: make(map[string]float64, len(row.Continuous))}
+		for v, state := range row.Discrete {
+			s.Discrete[v] = state
+		}
+		for v, x := range row.Continuous {
+			s.Continuous[v] = x
+		}
+		samples[i] = s
+	}
+	if classes < 2 {
+		return nil, 0, fmt.Errorf("need at least 2 classes, got %d", classes)
+	}
+	return samples, classes, nil
+}
+
+// features returns the sorted discrete and continuous features of the rows
+func features(X []models.Sample) ([]string, []string, error) {
+	discrete, continuous := make(map[string]bool), make(map[string]bool)
+	for _, row := range X {
+		for v := range row.Discrete {
+			discrete[v] = true
+		}
+		for v := range row.Continuous {
+			continuous[v] = true
+		}
+	}
+	var d, c []string
+	for v := range discrete {
+		if continuous[v] {
+			return nil, nil, fmt.Errorf("feature %s is both discrete and continuous", v)
+		}
+		d = append(d, v)
+	}
+	for v := range continuous {
+		c = append(c, v)
+	}
+	sort.Strings(d)
+	sort.Strings(c)
+	if len(d)+len(c) == 0 {
+		return nil, nil, fmt.Errorf("no features")
+	}
+	return d, c, nil
+}
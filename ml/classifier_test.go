@@ -0,0 +1,118 @@
+package ml
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// xorData draws a class C with features A and B, where B is A xor C, so the
+// class shows only in the pair of features, a noisy copy D of the class and
+// a noisy continuous feature X
+func xorData(n int, seed int64) ([]models.Sample, []int) {
+	r := rand.New(rand.NewSource(seed))
+	X := make([]models.Sample, n)
+	y := make([]int, n)
+	for i := range X {
+		c, a := r.Intn(2), r.Intn(2)
+		b := a ^ c
+		if r.Float64() < 0.1 {
+			b = 1 - b
+		}
+		d := c
+		if r.Float64() < 0.25 {
+			d = 1 - d
+		}
+		X[i] = models.Sample{
+			Discrete:   map[string]int{"A": a, "B": b, "D": d},
+			Continuous: map[string]float64{"X": float64(c) + r.NormFloat64()},
+		}
+		y[i] = c
+	}
+	return X, y
+}
+
+func accuracy(t *testing.T, clf Classifier, X []models.Sample, y []int) float64 {
+	t.Helper()
+	predictions, err := clf.Predict(X)
+	if err != nil {
+		t.Fatalf("Predict failed: %v", err)
+	}
+	correct := 0
+	for i, p := range predictions {
+		if p == y[i] {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(y))
+}
+
+func TestClassifiers(t *testing.T) {
+	X, y := xorData(2000, 1)
+	testX, testY := xorData(500, 2)
+	discreteX := make([]models.Sample, len(X))
+	testDiscrete := make([]models.Sample, len(testX))
+	for i := range X {
+		discreteX[i] = models.Sample{Discrete: X[i].Discrete}
+	}
+	for i := range testX {
+		testDiscrete[i] = models.Sample{Discrete: testX[i].Discrete}
+	}
+
+	if _, err := NewNaiveBayes().Predict(testX); err == nil {
+		t.Error("Expected an error predicting before Fit")
+	}
+
+	// Naive Bayes cannot see the xor and leans on D and X
+	nb := NewNaiveBayes()
+	if err := nb.Fit(X, y); err != nil {
+		t.Fatalf("NaiveBayes Fit failed: %v", err)
+	}
+	proba, err := nb.PredictProba(testX)
+	if err != nil {
+		t.Fatalf("PredictProba failed: %v", err)
+	}
+	if len(proba[0]) != 2 || math.Abs(proba[0][0]+proba[0][1]-1) > 1e-9 {
+		t.Errorf("Expected two class probabilities summing to 1, got %v", proba[0])
+	}
+	if acc := accuracy(t, nb, testX, testY); acc < 0.7 || acc > 0.87 {
+		t.Errorf("Expected naive Bayes accuracy near 0.8, got %.3f", acc)
+	}
+
+	// TAN links A and B and recovers the xor
+	tan := NewTAN()
+	if err := tan.Fit(X, y); err == nil {
+		t.Error("Expected TAN to reject a continuous feature")
+	}
+	if err := tan.Fit(discreteX, y); err != nil {
+		t.Fatalf("TAN Fit failed: %v", err)
+	}
+	if !tan.Network.DAG.HasEdge("A", "B") {
+		t.Errorf("Expected the tree edge A -> B, got %v", tan.Network.Edges())
+	}
+	if acc := accuracy(t, tan, testDiscrete, testY); acc < 0.85 {
+		t.Errorf("Expected TAN accuracy near 0.9, got %.3f", acc)
+	}
+
+	// Greedy search from the empty graph finds at least the class's link to D
+	bnc := NewBNClassifier(nil)
+	if err := bnc.Fit(discreteX, y); err != nil {
+		t.Fatalf("BNClassifier Fit failed: %v", err)
+	}
+	if acc := accuracy(t, bnc, testDiscrete, testY); acc < 0.7 {
+		t.Errorf("Expected learned-structure accuracy of at least 0.7, got %.3f", acc)
+	}
+
+	given := NewBNClassifier([][2]string{{"A", "B"}, {"class", "B"}})
+	if err := given.Fit(discreteX, y); err != nil {
+		t.Fatalf("BNClassifier Fit failed: %v", err)
+	}
+	if acc := accuracy(t, given, testDiscrete, testY); acc < 0.85 {
+		t.Errorf("Expected given-structure accuracy near 0.9, got %.3f", acc)
+	}
+	if err := NewBNClassifier([][2]string{{"A", "B"}}).Fit(discreteX, y); err == nil {
+		t.Error("Expected an error for a structure without the target")
+	}
+}
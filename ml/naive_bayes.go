@@ -0,0 +1,34 @@
+package ml
+
+import "github.com/JohnPierman/bngo/models"
+
+// NaiveBayes is the naive Bayes classifier: a network in which the class is
+// the only parent of every feature. Discrete features get a CPT per class and
+// continuous ones a Gaussian per class.
+type NaiveBayes struct {
+	network
+}
+
+// NewNaiveBayes creates an unfitted naive Bayes classifier
+func NewNaiveBayes() *NaiveBayes {
+	return &NaiveBayes{}
+}
+
+// Fit learns the class prior and the distribution of each feature given the
+// class
+func (nb *NaiveBayes) Fit(X []models.Sample, y []int) error {
+	discrete, continuous, err := features(X)
+	if err != nil {
+		return err
+	}
+	target := nb.target()
+	var edges [][2]string
+	for _, v := range append(discrete, continuous...) {
+		edges = append(edges, [2]string{target, v})
+	}
+	bn, err := models.NewBayesianNetwork(edges)
+	if err != nil {
+		return err
+	}
+	return nb.fit(bn, X, y)
+}
@@ -0,0 +1,125 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// TAN is the tree-augmented naive Bayes classifier of Friedman, Geiger and
+// Goldszmidt: naive Bayes with the features also joined in a tree, the
+// maximum spanning tree of their conditional mutual information given the
+// class, so each feature may depend on one other. Features must be discrete.
+type TAN struct {
+	network
+	Root string // Feature at the root of the tree, the first in sorted order if empty
+}
+
+// NewTAN creates an unfitted tree-augmented naive Bayes classifier
+func NewTAN() *TAN {
+	return &TAN{}
+}
+
+// Fit learns the feature tree and then the CPDs of the network
+func (t *TAN) Fit(X []models.Sample, y []int) error {
+	discrete, continuous, err := features(X)
+	if err != nil {
+		return err
+	}
+	if len(continuous) > 0 {
+		return fmt.Errorf("TAN needs discrete features, %s is continuous", continuous[0])
+	}
+	if len(X) != len(y) {
+		return fmt.Errorf("got %d rows and %d classes", len(X), len(y))
+	}
+	root := 0
+	if t.Root != "" {
+		root = -1
+		for i, v := range discrete {
+			if v == t.Root {
+				root = i
+			}
+		}
+		if root < 0 {
+			return fmt.Errorf("root %s is not a feature: %w", t.Root, models.ErrUnknownVariable)
+		}
+	}
+
+	// Prim's algorithm grows the maximum spanning tree from the root
+	d := len(discrete)
+	weight := make([][]float64, d)
+	for i := range weight {
+		weight[i] = make([]float64, d)
+		for j := 0; j < i; j++ {
+			weight[i][j] = conditionalMI(X, y, discrete[i], discrete[j])
+			weight[j][i] = weight[i][j]
+		}
+	}
+	target := t.target()
+	var edges [][2]string
+	for _, v := range discrete {
+		edges = append(edges, [2]string{target, v})
+	}
+	inTree := make([]bool, d)
+	best := make([]float64, d)
+	parent := make([]int, d)
+	for i := range best {
+		best[i] = math.Inf(-1)
+	}
+	inTree[root] = true
+	for i := range best {
+		if !inTree[i] {
+			best[i], parent[i] = weight[root][i], root
+		}
+	}
+	for added := 1; added < d; added++ {
+		next := -1
+		for i := range best {
+			if !inTree[i] && (next < 0 || best[i] > best[next]) {
+				next = i
+			}
+		}
+		inTree[next] = true
+		edges = append(edges, [2]string{discrete[parent[next]], discrete[next]})
+		for i := range best {
+			if !inTree[i] && weight[next][i] > best[i] {
+				best[i], parent[i] = weight[next][i], next
+			}
+		}
+	}
+
+	bn, err := models.NewBayesianNetwork(edges)
+	if err != nil {
+		return err
+	}
+	return t.fit(bn, X, y)
+}
+
+// conditionalMI returns the plug-in estimate of I(a; b | class) from the rows
+// observing both features
+func conditionalMI(X []models.Sample, y []int, a, b string) float64 {
+	type key struct{ a, b, c int }
+	joint := make(map[key]float64)
+	ac := make(map[[2]int]float64)
+	bc := make(map[[2]int]float64)
+	c := make(map[int]float64)
+	n := 0.0
+	for i, row := range X {
+		va, okA := row.Discrete[a]
+		vb, okB := row.Discrete[b]
+		if !okA || !okB {
+			continue
+		}
+		joint[key{va, vb, y[i]}]++
+		ac[[2]int{va, y[i]}]++
+		bc[[2]int{vb, y[i]}]++
+		c[y[i]]++
+		n++
+	}
+	mi := 0.0
+	for k, count := range joint {
+		mi += count / n * math.Log(count*c[k.c]/(ac[[2]int{k.a, k.c}]*bc[[2]int{k.b, k.c}]))
+	}
+	return mi
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// SetBasis makes FitMixed and FitWeighted fit node's Gaussian CPD with an
+// extra coefficient for each basis term, such as factors.Power("X", 2),
+// factors.Interaction("X", "Z") or the terms of factors.SplineBasis, so the
+// mean can bend with the parents while the fit stays least squares. Terms
+// must read continuous parents of node when it is fitted. Nil terms clear
+// the declaration.
+func (bn *BayesianNetwork) SetBasis(node string, terms []factors.BasisTerm) error {
+	if !bn.DAG.HasNode(node) {
+		return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+	}
+	if bn.IsDiscrete(node) {
+		return fmt.Errorf("cannot add basis terms to discrete node %s", node)
+	}
+	if len(terms) == 0 {
+		delete(bn.basis, node)
+		return nil
+	}
+	if bn.basis == nil {
+		bn.basis = make(map[string][]factors.BasisTerm)
+	}
+	bn.basis[node] = append([]factors.BasisTerm(nil), terms...)
+	return nil
+}
+
+// Basis returns the basis terms declared for node by SetBasis
+func (bn *BayesianNetwork) Basis(node string) []factors.BasisTerm {
+	return append([]factors.BasisTerm(nil), bn.basis[node]...)
+}
+
+// checkBasisParents reports basis terms of variable that read a variable
+// other than its continuous parents
+func (bn *BayesianNetwork) checkBasisParents(variable string, terms []factors.BasisTerm) error {
+	for _, t := range terms {
+		for _, p := range t.Parents {
+			if !bn.DAG.HasEdge(p, variable) || bn.IsDiscrete(p) {
+				return fmt.Errorf("basis term %v of %s reads %s, which is not a continuous parent", t, variable, p)
+			}
+		}
+	}
+	return nil
+}
+
+// appendBasisFeatures appends the value of each term, computed from the
+// continuous values, to a regression row
+func appendBasisFeatures(row []float64, terms []factors.BasisTerm, continuous map[string]float64) []float64 {
+	for _, t := range terms {
+		values := make([]float64, len(t.Parents))
+		for i, p := range t.Parents {
+			values[i] = continuous[p]
+		}
+		row = append(row, t.Value(values))
+	}
+	return row
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/JohnPierman/bngo/factors"
 	"github.com/JohnPierman/bngo/graph"
@@ -24,11 +27,36 @@ const (
 
 // BayesianNetwork represents a Bayesian Network with discrete and/or continuous variables
 type BayesianNetwork struct {
-	DAG          *graph.DAG
-	CPDs         map[string]*factors.TabularCPD        // For discrete variables
-	GaussianCPDs map[string]*factors.LinearGaussianCPD // For continuous variables
-	VariableType map[string]VariableType               // Track variable types
-	Cardinality  map[string]int                        // For discrete variables only
+	DAG            *graph.DAG
+	CPDs           map[string]*factors.TabularCPD        // For discrete variables
+	GaussianCPDs   map[string]*factors.LinearGaussianCPD // For continuous variables
+	CustomCPDs     map[string]factors.CPD                // For discrete variables with non-tabular CPDs
+	ContinuousCPDs map[string]factors.ContinuousCPD      // For continuous variables with CPDs other than linear Gaussian
+	HybridCPDs     map[string]factors.HybridCPD          // For discrete variables with continuous parents
+	VariableType   map[string]VariableType               // Track variable types
+	Cardinality    map[string]int                        // For discrete variables only
+
+	declared map[string]int        // Cardinalities fixed by DeclareCardinality or AddNode
+	hidden   map[string]bool       // Nodes declared by SetHidden
+	tied     map[string]*TiedGroup // Group of each node tied by TieCPDs
+
+	shrinkage float64 // Strength of CPT row shrinkage toward the marginal, set by SetShrinkage
+	ridge     float64 // Ridge penalty of Gaussian CPD regressions, set by SetRidge
+	huber     float64 // Huber threshold of Gaussian CPD regressions, set by SetHuber
+
+	missing MissingStrategy // Treatment of missing values, set by SetMissingStrategy
+
+	heteroscedastic map[string]bool                 // Nodes declared by SetHeteroscedastic
+	gaussianProcess map[string]factors.GPOptions    // Nodes declared by SetGaussianProcess
+	basis           map[string][]factors.BasisTerm  // Basis terms declared by SetBasis
+	count           map[string]bool                 // Nodes declared by SetCount
+	gammaShape      map[string]float64              // Nodes declared by SetGamma, or SetExponential with shape 1
+	beta            map[string]bool                 // Nodes declared by SetBeta
+	ordinalProbit   map[string]bool                 // Nodes declared by SetOrdinalProbit
+	copula          map[string]factors.MarginalType // Marginal families declared by SetCopula
+
+	pipeline *Pipeline // Preprocessing attached by SetPipeline
+	schema   *Schema   // Variables declared by NewBayesianNetworkWithSchema
 }
 
 // NewBayesianNetwork creates a new Bayesian Network
@@ -42,6 +70,7 @@ func NewBayesianNetwork(edges [][2]string) (*BayesianNetwork, error) {
 		DAG:          dag,
 		CPDs:         make(map[string]*factors.TabularCPD),
 		GaussianCPDs: make(map[string]*factors.LinearGaussianCPD),
+		CustomCPDs:   make(map[string]factors.CPD),
 		VariableType: make(map[string]VariableType),
 		Cardinality:  make(map[string]int),
 	}, nil
@@ -58,7 +87,7 @@ func (bn *BayesianNetwork) AddCPD(cpd *factors.TabularCPD) error {
 		}
 	}
 	if !found {
-		return fmt.Errorf("variable %s not in network", cpd.Variable)
+		return fmt.Errorf("variable %s: %w", cpd.Variable, ErrUnknownVariable)
 	}
 
 	// Check if evidence matches parents
@@ -69,15 +98,26 @@ func (bn *BayesianNetwork) AddCPD(cpd *factors.TabularCPD) error {
 	sort.Strings(evidenceSorted)
 
 	if len(parents) != len(evidenceSorted) {
-		return fmt.Errorf("CPD evidence does not match parents for %s", cpd.Variable)
+		return fmt.Errorf("CPD evidence for %s: %w", cpd.Variable, ErrParentMismatch)
 	}
 	for i := range parents {
 		if parents[i] != evidenceSorted[i] {
-			return fmt.Errorf("CPD evidence does not match parents for %s", cpd.Variable)
+			return fmt.Errorf("CPD evidence for %s: %w", cpd.Variable, ErrParentMismatch)
+		}
+	}
+
+	if err := bn.checkCardinality(cpd.Variable, cpd.VariableCard, cpd.Variable); err != nil {
+		return fmt.Errorf("CPD for %s: %w", cpd.Variable, err)
+	}
+	for _, e := range cpd.Evidence {
+		if err := bn.checkCardinality(e, cpd.EvidenceCard[e], cpd.Variable); err != nil {
+			return fmt.Errorf("CPD for %s: %w", cpd.Variable, err)
 		}
 	}
 
 	bn.CPDs[cpd.Variable] = cpd
+	delete(bn.CustomCPDs, cpd.Variable)
+	delete(bn.HybridCPDs, cpd.Variable)
 	bn.VariableType[cpd.Variable] = Discrete
 	bn.Cardinality[cpd.Variable] = cpd.VariableCard
 	for k, v := range cpd.EvidenceCard {
@@ -88,6 +128,158 @@ func (bn *BayesianNetwork) AddCPD(cpd *factors.TabularCPD) error {
 	return nil
 }
 
+// AddCustomCPD adds a discrete CPD of any factors.CPD implementation, such as
+// a noisy-OR CPD. It replaces a tabular CPD for the same variable.
+func (bn *BayesianNetwork) AddCustomCPD(cpd factors.CPD) error {
+	variable := cpd.GetVariable()
+	found := false
+	for _, node := range bn.DAG.Nodes() {
+		if node == variable {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("variable %s: %w", variable, ErrUnknownVariable)
+	}
+
+	parents := bn.DAG.Parents(variable)
+	sort.Strings(parents)
+	if !sameNames(parents, cpd.GetParents()) {
+		return fmt.Errorf("CPD parents for %s: %w", variable, ErrParentMismatch)
+	}
+
+	// The factor carries the parent cardinalities
+	factor, err := cpd.ToFactor()
+	if err != nil {
+		return fmt.Errorf("CPD for %s: %w", variable, err)
+	}
+	if err := bn.checkCardinality(variable, cpd.GetCardinality(), variable); err != nil {
+		return fmt.Errorf("CPD for %s: %w", variable, err)
+	}
+	for _, parent := range cpd.GetParents() {
+		if err := bn.checkCardinality(parent, factor.Cardinality[parent], variable); err != nil {
+			return fmt.Errorf("CPD for %s: %w", variable, err)
+		}
+	}
+
+	if bn.CustomCPDs == nil {
+		bn.CustomCPDs = make(map[string]factors.CPD)
+	}
+	bn.CustomCPDs[variable] = cpd
+	delete(bn.CPDs, variable)
+	delete(bn.HybridCPDs, variable)
+	bn.VariableType[variable] = Discrete
+	bn.Cardinality[variable] = cpd.GetCardinality()
+	for _, parent := range cpd.GetParents() {
+		bn.Cardinality[parent] = factor.Cardinality[parent]
+		bn.VariableType[parent] = Discrete
+	}
+
+	return nil
+}
+
+// DiscreteCPD returns the CPD of a discrete variable, tabular or custom
+func (bn *BayesianNetwork) DiscreteCPD(variable string) (factors.CPD, bool) {
+	if cpd, ok := bn.CPDs[variable]; ok {
+		return cpd, true
+	}
+	cpd, ok := bn.CustomCPDs[variable]
+	return cpd, ok
+}
+
+// NodeFactor returns the factor of a discrete variable's CPD, tabular or custom
+func (bn *BayesianNetwork) NodeFactor(variable string) (*DiscreteFactor, error) {
+	cpd, ok := bn.DiscreteCPD(variable)
+	if !ok {
+		return nil, fmt.Errorf("no discrete CPD for node %s: %w", variable, ErrMissingCPD)
+	}
+	return cpd.ToFactor()
+}
+
+// AddContinuousCPD adds a continuous CPD of any factors.ContinuousCPD
+// implementation, such as a Gaussian process CPD. Its parents must be
+// continuous. It replaces a linear Gaussian CPD for the same variable;
+// linear Gaussian CPDs themselves are added as by AddGaussianCPD.
+func (bn *BayesianNetwork) AddContinuousCPD(cpd factors.ContinuousCPD) error {
+	if gcpd, ok := cpd.(*factors.LinearGaussianCPD); ok {
+		return bn.AddGaussianCPD(gcpd)
+	}
+	variable := cpd.GetVariable()
+	if !bn.DAG.HasNode(variable) {
+		return fmt.Errorf("variable %s: %w", variable, ErrUnknownVariable)
+	}
+
+	parents := bn.DAG.Parents(variable)
+	sort.Strings(parents)
+	if !sameNames(parents, cpd.GetParents()) {
+		return fmt.Errorf("CPD parents for %s: %w", variable, ErrParentMismatch)
+	}
+	if bn.IsDiscrete(variable) {
+		return fmt.Errorf("continuous CPD for discrete node %s", variable)
+	}
+	for _, parent := range parents {
+		if bn.IsDiscrete(parent) {
+			return fmt.Errorf("CPD for %s: parent %s is discrete", variable, parent)
+		}
+	}
+
+	if bn.ContinuousCPDs == nil {
+		bn.ContinuousCPDs = make(map[string]factors.ContinuousCPD)
+	}
+	bn.ContinuousCPDs[variable] = cpd
+	delete(bn.GaussianCPDs, variable)
+	delete(bn.HybridCPDs, variable)
+	bn.VariableType[variable] = Continuous
+	for _, parent := range parents {
+		bn.VariableType[parent] = Continuous
+	}
+	return nil
+}
+
+// AddHybridCPD adds the CPD of a discrete variable whose parents may be
+// continuous, such as an ordinal probit CPD. It replaces any other CPD for
+// the variable. Hybrid CPDs take part in simulation, density evaluation and
+// likelihood weighting but not in exact inference.
+func (bn *BayesianNetwork) AddHybridCPD(cpd factors.HybridCPD) error {
+	variable := cpd.GetVariable()
+	if !bn.DAG.HasNode(variable) {
+		return fmt.Errorf("variable %s: %w", variable, ErrUnknownVariable)
+	}
+
+	parents := bn.DAG.Parents(variable)
+	sort.Strings(parents)
+	if !sameNames(parents, cpd.GetParents()) {
+		return fmt.Errorf("CPD parents for %s: %w", variable, ErrParentMismatch)
+	}
+	if bn.IsContinuous(variable) {
+		return fmt.Errorf("discrete CPD for continuous node %s", variable)
+	}
+	if err := bn.checkCardinality(variable, cpd.GetCardinality(), variable); err != nil {
+		return fmt.Errorf("CPD for %s: %w", variable, err)
+	}
+
+	if bn.HybridCPDs == nil {
+		bn.HybridCPDs = make(map[string]factors.HybridCPD)
+	}
+	bn.HybridCPDs[variable] = cpd
+	delete(bn.CPDs, variable)
+	delete(bn.CustomCPDs, variable)
+	bn.VariableType[variable] = Discrete
+	bn.Cardinality[variable] = cpd.GetCardinality()
+	return nil
+}
+
+// ContinuousCPD returns the CPD of a continuous variable, linear Gaussian or
+// custom
+func (bn *BayesianNetwork) ContinuousCPD(variable string) (factors.ContinuousCPD, bool) {
+	if cpd, ok := bn.GaussianCPDs[variable]; ok {
+		return cpd, true
+	}
+	cpd, ok := bn.ContinuousCPDs[variable]
+	return cpd, ok
+}
+
 // AddGaussianCPD adds a continuous Gaussian CPD to the network
 func (bn *BayesianNetwork) AddGaussianCPD(cpd *factors.LinearGaussianCPD) error {
 	// Check if variable exists in DAG
@@ -99,7 +291,7 @@ func (bn *BayesianNetwork) AddGaussianCPD(cpd *factors.LinearGaussianCPD) error
 		}
 	}
 	if !found {
-		return fmt.Errorf("variable %s not in network", cpd.Variable)
+		return fmt.Errorf("variable %s: %w", cpd.Variable, ErrUnknownVariable)
 	}
 
 	// Check if parents match
@@ -110,15 +302,23 @@ func (bn *BayesianNetwork) AddGaussianCPD(cpd *factors.LinearGaussianCPD) error
 	sort.Strings(cpdParents)
 
 	if len(parents) != len(cpdParents) {
-		return fmt.Errorf("CPD parents do not match DAG parents for %s", cpd.Variable)
+		return fmt.Errorf("CPD parents for %s: %w", cpd.Variable, ErrParentMismatch)
 	}
 	for i := range parents {
 		if parents[i] != cpdParents[i] {
-			return fmt.Errorf("CPD parents do not match DAG parents for %s", cpd.Variable)
+			return fmt.Errorf("CPD parents for %s: %w", cpd.Variable, ErrParentMismatch)
+		}
+	}
+
+	for _, parent := range cpd.DiscreteParents() {
+		if err := bn.checkCardinality(parent, cpd.Cardinality[parent], cpd.Variable); err != nil {
+			return fmt.Errorf("CPD for %s: %w", cpd.Variable, err)
 		}
 	}
 
 	bn.GaussianCPDs[cpd.Variable] = cpd
+	delete(bn.ContinuousCPDs, cpd.Variable)
+	delete(bn.HybridCPDs, cpd.Variable)
 	bn.VariableType[cpd.Variable] = Continuous
 
 	// Set parent types based on CPD
@@ -144,7 +344,7 @@ func (bn *BayesianNetwork) AddGaussianCPD(cpd *factors.LinearGaussianCPD) error
 func (bn *BayesianNetwork) GetCPD(variable string) (*factors.TabularCPD, error) {
 	cpd, ok := bn.CPDs[variable]
 	if !ok {
-		return nil, fmt.Errorf("no discrete CPD found for variable %s", variable)
+		return nil, fmt.Errorf("no discrete CPD found for variable %s: %w", variable, ErrMissingCPD)
 	}
 	return cpd, nil
 }
@@ -153,7 +353,7 @@ func (bn *BayesianNetwork) GetCPD(variable string) (*factors.TabularCPD, error)
 func (bn *BayesianNetwork) GetGaussianCPD(variable string) (*factors.LinearGaussianCPD, error) {
 	cpd, ok := bn.GaussianCPDs[variable]
 	if !ok {
-		return nil, fmt.Errorf("no Gaussian CPD found for variable %s", variable)
+		return nil, fmt.Errorf("no Gaussian CPD found for variable %s: %w", variable, ErrMissingCPD)
 	}
 	return cpd, nil
 }
@@ -188,67 +388,6 @@ func (bn *BayesianNetwork) IsContinuous(variable string) bool {
 	return ok && vtype == Continuous
 }
 
-// CheckModel validates that the network is properly specified
-func (bn *BayesianNetwork) CheckModel() error {
-	// Check that all nodes have CPDs
-	for _, node := range bn.DAG.Nodes() {
-		hasDiscreteCPD := false
-		hasGaussianCPD := false
-
-		if _, ok := bn.CPDs[node]; ok {
-			hasDiscreteCPD = true
-		}
-		if _, ok := bn.GaussianCPDs[node]; ok {
-			hasGaussianCPD = true
-		}
-
-		if !hasDiscreteCPD && !hasGaussianCPD {
-			return fmt.Errorf("node %s has no CPD", node)
-		}
-		if hasDiscreteCPD && hasGaussianCPD {
-			return fmt.Errorf("node %s has both discrete and Gaussian CPD", node)
-		}
-	}
-
-	// Check that all discrete CPDs are consistent with structure
-	for variable, cpd := range bn.CPDs {
-		parents := bn.DAG.Parents(variable)
-		sort.Strings(parents)
-		evidenceSorted := make([]string, len(cpd.Evidence))
-		copy(evidenceSorted, cpd.Evidence)
-		sort.Strings(evidenceSorted)
-
-		if len(parents) != len(evidenceSorted) {
-			return fmt.Errorf("CPD evidence count mismatch for %s", variable)
-		}
-		for i := range parents {
-			if parents[i] != evidenceSorted[i] {
-				return fmt.Errorf("CPD evidence mismatch for %s", variable)
-			}
-		}
-	}
-
-	// Check that all Gaussian CPDs are consistent with structure
-	for variable, cpd := range bn.GaussianCPDs {
-		parents := bn.DAG.Parents(variable)
-		sort.Strings(parents)
-		cpdParents := make([]string, len(cpd.Parents))
-		copy(cpdParents, cpd.Parents)
-		sort.Strings(cpdParents)
-
-		if len(parents) != len(cpdParents) {
-			return fmt.Errorf("gaussian CPD parent count mismatch for %s", variable)
-		}
-		for i := range parents {
-			if parents[i] != cpdParents[i] {
-				return fmt.Errorf("gaussian CPD parent mismatch for %s", variable)
-			}
-		}
-	}
-
-	return nil
-}
-
 // Nodes returns all nodes in the network
 func (bn *BayesianNetwork) Nodes() []string {
 	return bn.DAG.Nodes()
@@ -261,16 +400,22 @@ func (bn *BayesianNetwork) Edges() [][2]string {
 
 // Sample represents a sample from the network with both discrete and continuous values
 type Sample struct {
-	Discrete   map[string]int
-	Continuous map[string]float64
+	Discrete   map[string]int     `json:"discrete,omitempty"`
+	Continuous map[string]float64 `json:"continuous,omitempty"`
 }
 
 // Simulate generates samples from the Bayesian Network (old discrete-only version, deprecated)
 func (bn *BayesianNetwork) Simulate(nSamples int, seed int64) ([]map[string]int, error) {
+	return bn.SimulateWithRand(nSamples, rand.New(rand.NewSource(seed)))
+}
+
+// SimulateWithRand is Simulate drawing from a caller-owned generator, which
+// may be shared with other samplers or backed by NewCryptoSource
+func (bn *BayesianNetwork) SimulateWithRand(nSamples int, r *rand.Rand) ([]map[string]int, error) {
 	// Check if all variables are discrete
 	for _, node := range bn.DAG.Nodes() {
 		if bn.IsContinuous(node) {
-			return nil, fmt.Errorf("network contains continuous variables, use SimulateMixed instead")
+			return nil, fmt.Errorf("%w, use SimulateMixed instead", ErrContinuousVariables)
 		}
 	}
 
@@ -278,43 +423,19 @@ func (bn *BayesianNetwork) Simulate(nSamples int, seed int64) ([]map[string]int,
 		return nil, err
 	}
 
-	r := rand.New(rand.NewSource(seed))
-
 	// Get topological order
 	order, err := bn.DAG.TopologicalSort()
 	if err != nil {
 		return nil, err
 	}
 
+	sampler := bn.newRowSampler(order, r)
 	samples := make([]map[string]int, nSamples)
-
-	for i := 0; i < nSamples; i++ {
-		sample := make(map[string]int)
-
-		for _, node := range order {
-			cpd := bn.CPDs[node]
-
-			// Get parent values
-			evidenceValues := make(map[string]int)
-			for _, parent := range cpd.Evidence {
-				evidenceValues[parent] = sample[parent]
-			}
-
-			// Calculate row index
-			rowIdx := 0
-			stride := 1
-			for j := len(cpd.Evidence) - 1; j >= 0; j-- {
-				e := cpd.Evidence[j]
-				rowIdx += evidenceValues[e] * stride
-				stride *= cpd.EvidenceCard[e]
-			}
-
-			// Sample from the distribution
-			probs := cpd.Values[rowIdx]
-			sample[node] = sampleCategorical(probs, r)
+	for i := range samples {
+		if err := sampler.draw(); err != nil {
+			return nil, err
 		}
-
-		samples[i] = sample
+		samples[i] = sampler.discreteSample()
 	}
 
 	return samples, nil
@@ -322,76 +443,110 @@ func (bn *BayesianNetwork) Simulate(nSamples int, seed int64) ([]map[string]int,
 
 // SimulateMixed generates samples from a Bayesian Network with mixed discrete/continuous variables
 func (bn *BayesianNetwork) SimulateMixed(nSamples int, seed int64) ([]Sample, error) {
+	return bn.SimulateMixedWithRand(nSamples, rand.New(rand.NewSource(seed)))
+}
+
+// SimulateMixedWithRand is SimulateMixed drawing from a caller-owned generator
+func (bn *BayesianNetwork) SimulateMixedWithRand(nSamples int, r *rand.Rand) ([]Sample, error) {
 	if err := bn.CheckModel(); err != nil {
 		return nil, err
 	}
 
-	r := rand.New(rand.NewSource(seed))
-
 	// Get topological order
 	order, err := bn.DAG.TopologicalSort()
 	if err != nil {
 		return nil, err
 	}
 
+	sampler := bn.newRowSampler(order, r)
 	samples := make([]Sample, nSamples)
+	for i := range samples {
+		if err := sampler.draw(); err != nil {
+			return nil, err
+		}
+		samples[i] = sampler.sample()
+	}
 
-	for i := 0; i < nSamples; i++ {
-		sample := Sample{
-			Discrete:   make(map[string]int),
-			Continuous: make(map[string]float64),
+	return samples, nil
+}
+
+// sampleNode draws node given the values its parents already have in sample
+func (bn *BayesianNetwork) sampleNode(node string, sample Sample, r *rand.Rand) error {
+	if bn.IsDiscrete(node) {
+		if hybrid, ok := bn.HybridCPDs[node]; ok {
+			val, err := hybrid.Sample(bn.parentValues(hybrid.GetParents(), sample), r)
+			if err != nil {
+				return fmt.Errorf("failed to sample %s: %w", node, err)
+			}
+			sample.Discrete[node] = val
+			return nil
 		}
 
-		for _, node := range order {
-			if bn.IsDiscrete(node) {
-				// Sample discrete variable
-				cpd := bn.CPDs[node]
+		// Sample discrete variable
+		cpd, ok := bn.CPDs[node]
+		if !ok {
+			val, err := bn.sampleCustom(node, sample.Discrete, r)
+			if err != nil {
+				return err
+			}
+			sample.Discrete[node] = val
+			return nil
+		}
 
-				// Get parent values
-				evidenceValues := make(map[string]int)
-				for _, parent := range cpd.Evidence {
-					evidenceValues[parent] = sample.Discrete[parent]
-				}
+		// Calculate row index
+		rowIdx := 0
+		stride := 1
+		for j := len(cpd.Evidence) - 1; j >= 0; j-- {
+			e := cpd.Evidence[j]
+			rowIdx += sample.Discrete[e] * stride
+			stride *= cpd.EvidenceCard[e]
+		}
 
-				// Calculate row index
-				rowIdx := 0
-				stride := 1
-				for j := len(cpd.Evidence) - 1; j >= 0; j-- {
-					e := cpd.Evidence[j]
-					rowIdx += evidenceValues[e] * stride
-					stride *= cpd.EvidenceCard[e]
-				}
+		// Sample from the distribution
+		probs := cpd.Values[rowIdx]
+		sample.Discrete[node] = sampleCategorical(probs, r)
+		return nil
+	}
 
-				// Sample from the distribution
-				probs := cpd.Values[rowIdx]
-				sample.Discrete[node] = sampleCategorical(probs, r)
-			} else {
-				// Sample continuous variable
-				cpd := bn.GaussianCPDs[node]
-
-				// Get parent values
-				parentValues := make(map[string]interface{})
-				for _, parent := range cpd.Parents {
-					if bn.IsDiscrete(parent) {
-						parentValues[parent] = sample.Discrete[parent]
-					} else {
-						parentValues[parent] = sample.Continuous[parent]
-					}
-				}
+	// Sample continuous variable
+	cpd, ok := bn.ContinuousCPD(node)
+	if !ok {
+		return fmt.Errorf("no continuous CPD for node %s: %w", node, ErrMissingCPD)
+	}
+	val, err := cpd.Sample(bn.parentValues(cpd.GetParents(), sample), r)
+	if err != nil {
+		return fmt.Errorf("failed to sample %s: %w", node, err)
+	}
+	sample.Continuous[node] = val
+	return nil
+}
 
-				// Sample from Gaussian
-				val, err := cpd.Sample(parentValues, r)
-				if err != nil {
-					return nil, fmt.Errorf("failed to sample %s: %v", node, err)
-				}
-				sample.Continuous[node] = val
-			}
+// parentValues collects the parents' values from sample as a continuous
+// CPD takes them: ints for discrete parents, float64s for continuous ones
+func (bn *BayesianNetwork) parentValues(parents []string, sample Sample) map[string]interface{} {
+	values := make(map[string]interface{}, len(parents))
+	for _, parent := range parents {
+		if bn.IsDiscrete(parent) {
+			values[parent] = sample.Discrete[parent]
+		} else {
+			values[parent] = sample.Continuous[parent]
 		}
-
-		samples[i] = sample
 	}
+	return values
+}
 
-	return samples, nil
+// sampleCustom draws a node with a custom CPD given the sampled parent states
+func (bn *BayesianNetwork) sampleCustom(node string, sample map[string]int, r *rand.Rand) (int, error) {
+	cpd := bn.CustomCPDs[node]
+	parentValues := make(map[string]int, len(cpd.GetParents()))
+	for _, parent := range cpd.GetParents() {
+		parentValues[parent] = sample[parent]
+	}
+	val, err := cpd.Sample(parentValues, r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sample %s: %w", node, err)
+	}
+	return val, nil
 }
 
 func sampleCategorical(probs []float64, r *rand.Rand) int {
@@ -406,11 +561,34 @@ func sampleCategorical(probs []float64, r *rand.Rand) int {
 	return len(probs) - 1
 }
 
-// Predict predicts missing values in partial observations
+// Predict predicts missing values in partial observations. Values given for
+// hidden nodes are ignored, so hidden nodes are always predicted.
 func (bn *BayesianNetwork) Predict(observations []map[string]int) (map[string][]int, error) {
+	return bn.predict(observations, false)
+}
+
+// PredictJoint is Predict computing, for each distinct pattern of observed
+// values, one joint posterior over all the missing variables and reading
+// every prediction off its marginals. Each prediction conditions on all of
+// the row's evidence; Predict reads a variable whose parents are observed
+// straight off its CPD. The joint posterior has one entry per joint state
+// of the missing variables, so rows missing many variables need memory
+// exponential in their number.
+func (bn *BayesianNetwork) PredictJoint(observations []map[string]int) (map[string][]int, error) {
+	return bn.predict(observations, true)
+}
+
+func (bn *BayesianNetwork) predict(observations []map[string]int, joint bool) (map[string][]int, error) {
 	if err := bn.CheckModel(); err != nil {
 		return nil, err
 	}
+	if len(bn.hidden) > 0 {
+		visible := make([]map[string]int, len(observations))
+		for i, obs := range observations {
+			visible[i] = bn.withoutHidden(obs)
+		}
+		observations = visible
+	}
 
 	allVars := bn.Nodes()
 
@@ -430,34 +608,100 @@ func (bn *BayesianNetwork) Predict(observations []map[string]int) (map[string][]
 	}
 	sort.Strings(toPredict)
 
+	for i, obs := range observations {
+		if err := bn.ValidateEvidence(obs); err != nil {
+			return nil, fmt.Errorf("observation %d: %w", i, err)
+		}
+	}
+
 	predictions := make(map[string][]int)
 	for _, v := range toPredict {
 		predictions[v] = make([]int, len(observations))
 	}
 
-	// For each observation
+	// Rows with the same observed values share one prediction
+	cache := make(map[string]map[string]int)
 	for i, obs := range observations {
-		for _, v := range toPredict {
-			if _, ok := obs[v]; ok {
-				predictions[v][i] = obs[v]
-				continue
+		key := observationKey(obs)
+		row, ok := cache[key]
+		if !ok {
+			var err error
+			if row, err = bn.predictRow(toPredict, obs, joint); err != nil {
+				return nil, err
 			}
+			cache[key] = row
+		}
+		for _, v := range toPredict {
+			predictions[v][i] = row[v]
+		}
+	}
+
+	return predictions, nil
+}
 
-			// Predict using MAP (maximum a posteriori)
+// predictRow predicts the variables of one observation, keeping observed
+// values, from one joint posterior or one query per missing variable
+func (bn *BayesianNetwork) predictRow(variables []string, obs map[string]int, joint bool) (map[string]int, error) {
+	row := make(map[string]int, len(variables))
+	var missing []string
+	for _, v := range variables {
+		if state, ok := obs[v]; ok {
+			row[v] = state
+		} else {
+			missing = append(missing, v)
+		}
+	}
+	if !joint {
+		for _, v := range missing {
 			pred, err := bn.predictSingle(v, obs)
 			if err != nil {
 				return nil, err
 			}
-			predictions[v][i] = pred
+			row[v] = pred
 		}
+		return row, nil
 	}
 
-	return predictions, nil
+	posterior, err := bn.jointPosterior(missing, obs)
+	if err != nil || posterior == nil {
+		return row, err
+	}
+	for _, v := range missing {
+		if !slices.Contains(posterior.Variables, v) {
+			continue // not discrete, predicted as 0 like Predict does
+		}
+		marginal, err := posterior.MarginalFor(v)
+		if err != nil {
+			return nil, err
+		}
+		row[v] = argmax(marginal)
+	}
+	return row, nil
+}
+
+// observationKey encodes an observation's values, in variable order
+func observationKey(obs map[string]int) string {
+	vars := make([]string, 0, len(obs))
+	for v := range obs {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	var sb strings.Builder
+	for _, v := range vars {
+		sb.WriteString(v)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Itoa(obs[v]))
+		sb.WriteByte(0)
+	}
+	return sb.String()
 }
 
 func (bn *BayesianNetwork) predictSingle(variable string, evidence map[string]int) (int, error) {
 	// Check if all parents are observed - fast path
-	cpd := bn.CPDs[variable]
+	cpd, ok := bn.CPDs[variable]
+	if !ok {
+		return bn.predictUsingInference(variable, evidence)
+	}
 
 	// Check if all parent variables are in evidence
 	allPresent := true
@@ -499,12 +743,37 @@ func (bn *BayesianNetwork) predictSingle(variable string, evidence map[string]in
 }
 
 func (bn *BayesianNetwork) predictUsingInference(variable string, evidence map[string]int) (int, error) {
+	posterior, err := bn.posterior(variable, evidence)
+	if err != nil {
+		return 0, err
+	}
+	return argmax(posterior), nil
+}
+
+// posterior computes P(variable | evidence) over the discrete nodes by
+// eliminating every other unobserved variable. The values are not normalized
+// and are nil if no factor mentions the variable.
+func (bn *BayesianNetwork) posterior(variable string, evidence map[string]int) ([]float64, error) {
+	result, err := bn.jointPosterior([]string{variable}, evidence)
+	if err != nil || result == nil || !slices.Contains(result.Variables, variable) {
+		return nil, err
+	}
+	return result.MarginalFor(variable)
+}
+
+// jointPosterior computes P(variables, evidence) over the discrete nodes by
+// eliminating every other unobserved variable. The factor is not normalized,
+// leaves out variables no factor mentions and is nil if no factors remain.
+func (bn *BayesianNetwork) jointPosterior(variables []string, evidence map[string]int) (*DiscreteFactor, error) {
 	// Convert all CPDs to factors
 	factorList := make([]*DiscreteFactor, 0)
-	for _, cpd := range bn.CPDs {
-		factor, err := cpd.ToFactor()
+	for _, node := range bn.DAG.Nodes() {
+		if !bn.IsDiscrete(node) {
+			continue
+		}
+		factor, err := bn.NodeFactor(node)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
 		factorList = append(factorList, factor)
 	}
@@ -514,135 +783,64 @@ func (bn *BayesianNetwork) predictUsingInference(variable string, evidence map[s
 	for _, factor := range factorList {
 		reduced, err := factor.Reduce(evidence)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
 		reducedFactors = append(reducedFactors, reduced)
 	}
 
-	// Find variables to eliminate (all except variable and evidence)
+	// Find variables to eliminate (all except variables and evidence)
 	toEliminate := make([]string, 0)
 	for _, node := range bn.Nodes() {
-		if node != variable {
-			if _, ok := evidence[node]; !ok {
-				toEliminate = append(toEliminate, node)
-			}
+		if slices.Contains(variables, node) {
+			continue
+		}
+		if _, ok := evidence[node]; !ok {
+			toEliminate = append(toEliminate, node)
 		}
 	}
 
 	// Eliminate variables one by one
 	currentFactors := reducedFactors
 	for _, v := range toEliminate {
-		currentFactors = bn.eliminateVariable(v, currentFactors)
+		var err error
+		if currentFactors, err = factors.Eliminate(v, currentFactors, nil); err != nil {
+			return nil, err
+		}
 	}
 
 	// Multiply remaining factors
 	if len(currentFactors) == 0 {
-		// Default to state 0
-		return 0, nil
-	}
-
-	result := currentFactors[0]
-	for i := 1; i < len(currentFactors); i++ {
-		newResult, err := result.Multiply(currentFactors[i])
-		if err != nil {
-			return 0, err
-		}
-		result = newResult
+		return nil, nil
 	}
+	return factors.Product(currentFactors, nil)
+}
 
-	// Find MAP assignment (argmax)
+// argmax returns the index of the largest value, the first on ties and 0 for
+// an empty slice
+func argmax(values []float64) int {
 	maxIdx := 0
-	maxVal := result.Values[0]
-	for i := 1; i < len(result.Values); i++ {
-		if result.Values[i] > maxVal {
-			maxVal = result.Values[i]
+	for i := 1; i < len(values); i++ {
+		if values[i] > values[maxIdx] {
 			maxIdx = i
 		}
 	}
-
-	// Convert index to variable state
-	// For single variable, index is just the state
-	if len(result.Variables) == 1 {
-		return maxIdx, nil
-	}
-
-	// Multiple variables - extract the one we want
-	varPos := -1
-	for i, v := range result.Variables {
-		if v == variable {
-			varPos = i
-			break
-		}
-	}
-
-	if varPos == -1 {
-		return 0, fmt.Errorf("variable %s not found in result", variable)
-	}
-
-	// Calculate stride for this variable
-	stride := 1
-	for i := len(result.Variables) - 1; i > varPos; i-- {
-		stride *= bn.Cardinality[result.Variables[i]]
-	}
-
-	// Extract state for this variable
-	varState := (maxIdx / stride) % bn.Cardinality[variable]
-	return varState, nil
-}
-
-func (bn *BayesianNetwork) eliminateVariable(variable string, factorList []*DiscreteFactor) []*DiscreteFactor {
-	// Find factors containing the variable
-	relevant := make([]*DiscreteFactor, 0)
-	irrelevant := make([]*DiscreteFactor, 0)
-
-	for _, factor := range factorList {
-		contains := false
-		for _, v := range factor.Variables {
-			if v == variable {
-				contains = true
-				break
-			}
-		}
-		if contains {
-			relevant = append(relevant, factor)
-		} else {
-			irrelevant = append(irrelevant, factor)
-		}
-	}
-
-	if len(relevant) == 0 {
-		return factorList
-	}
-
-	// Multiply relevant factors
-	product := relevant[0]
-	for i := 1; i < len(relevant); i++ {
-		newProduct, err := product.Multiply(relevant[i])
-		if err != nil {
-			continue
-		}
-		product = newProduct
-	}
-
-	// Marginalize out the variable
-	marginalized, err := product.Marginalize([]string{variable})
-	if err != nil {
-		return irrelevant
-	}
-
-	// Return irrelevant + marginalized
-	result := append(irrelevant, marginalized)
-	return result
+	return maxIdx
 }
 
-// Copy creates a deep copy of the Bayesian Network
+// Copy creates a deep copy of the Bayesian Network. Custom discrete and
+// continuous CPDs are shared with the original.
 func (bn *BayesianNetwork) Copy() *BayesianNetwork {
 	newBN := &BayesianNetwork{
 		DAG:          bn.DAG.Copy(),
 		CPDs:         make(map[string]*factors.TabularCPD),
 		GaussianCPDs: make(map[string]*factors.LinearGaussianCPD),
+		CustomCPDs:   make(map[string]factors.CPD, len(bn.CustomCPDs)),
 		VariableType: make(map[string]VariableType),
 		Cardinality:  make(map[string]int),
+		shrinkage:    bn.shrinkage,
+		ridge:        bn.ridge,
+		huber:        bn.huber,
+		missing:      bn.missing,
 	}
 
 	for k, v := range bn.CPDs {
@@ -653,6 +851,23 @@ func (bn *BayesianNetwork) Copy() *BayesianNetwork {
 		newBN.GaussianCPDs[k] = v.Copy()
 	}
 
+	// Custom CPDs have no generic copy and are shared
+	for k, v := range bn.CustomCPDs {
+		newBN.CustomCPDs[k] = v
+	}
+	if bn.ContinuousCPDs != nil {
+		newBN.ContinuousCPDs = make(map[string]factors.ContinuousCPD, len(bn.ContinuousCPDs))
+		for k, v := range bn.ContinuousCPDs {
+			newBN.ContinuousCPDs[k] = v
+		}
+	}
+	if bn.HybridCPDs != nil {
+		newBN.HybridCPDs = make(map[string]factors.HybridCPD, len(bn.HybridCPDs))
+		for k, v := range bn.HybridCPDs {
+			newBN.HybridCPDs[k] = v
+		}
+	}
+
 	for k, v := range bn.VariableType {
 		newBN.VariableType[k] = v
 	}
@@ -661,17 +876,110 @@ func (bn *BayesianNetwork) Copy() *BayesianNetwork {
 		newBN.Cardinality[k] = v
 	}
 
+	if bn.declared != nil {
+		newBN.declared = make(map[string]int, len(bn.declared))
+		for k, v := range bn.declared {
+			newBN.declared[k] = v
+		}
+	}
+
+	if bn.hidden != nil {
+		newBN.hidden = make(map[string]bool, len(bn.hidden))
+		for k, v := range bn.hidden {
+			newBN.hidden[k] = v
+		}
+	}
+	if bn.heteroscedastic != nil {
+		newBN.heteroscedastic = make(map[string]bool, len(bn.heteroscedastic))
+		for k, v := range bn.heteroscedastic {
+			newBN.heteroscedastic[k] = v
+		}
+	}
+	if bn.gaussianProcess != nil {
+		newBN.gaussianProcess = make(map[string]factors.GPOptions, len(bn.gaussianProcess))
+		for k, v := range bn.gaussianProcess {
+			newBN.gaussianProcess[k] = v
+		}
+	}
+	if bn.copula != nil {
+		newBN.copula = make(map[string]factors.MarginalType, len(bn.copula))
+		for k, v := range bn.copula {
+			newBN.copula[k] = v
+		}
+	}
+	if bn.ordinalProbit != nil {
+		newBN.ordinalProbit = make(map[string]bool, len(bn.ordinalProbit))
+		for k, v := range bn.ordinalProbit {
+			newBN.ordinalProbit[k] = v
+		}
+	}
+	if bn.beta != nil {
+		newBN.beta = make(map[string]bool, len(bn.beta))
+		for k, v := range bn.beta {
+			newBN.beta[k] = v
+		}
+	}
+	if bn.gammaShape != nil {
+		newBN.gammaShape = make(map[string]float64, len(bn.gammaShape))
+		for k, v := range bn.gammaShape {
+			newBN.gammaShape[k] = v
+		}
+	}
+	if bn.count != nil {
+		newBN.count = make(map[string]bool, len(bn.count))
+		for k, v := range bn.count {
+			newBN.count[k] = v
+		}
+	}
+	if bn.basis != nil {
+		newBN.basis = make(map[string][]factors.BasisTerm, len(bn.basis))
+		for k, v := range bn.basis {
+			newBN.basis[k] = append([]factors.BasisTerm(nil), v...)
+		}
+	}
+	if bn.pipeline != nil {
+		newBN.pipeline = bn.pipeline.Copy()
+	}
+	if bn.schema != nil {
+		newBN.schema = bn.schema.Copy()
+	}
+
+	for _, group := range bn.TiedGroups() {
+		if newBN.tied == nil {
+			newBN.tied = make(map[string]*TiedGroup, len(bn.tied))
+		}
+		g := group
+		for _, node := range g.Nodes {
+			newBN.tied[node] = &g
+		}
+	}
+
 	return newBN
 }
 
-// Fit learns the CPD parameters from data (discrete variables only)
+// Fit learns the CPD parameters from data (discrete variables only). A
+// network with hidden nodes is fitted by FitEM with default options.
 func (bn *BayesianNetwork) Fit(data []map[string]int) error {
 	// Check if all variables are discrete
 	for _, node := range bn.DAG.Nodes() {
 		if bn.IsContinuous(node) {
-			return fmt.Errorf("network contains continuous variables, use FitMixed instead")
+			return fmt.Errorf("%w, use FitMixed instead", ErrContinuousVariables)
+		}
+	}
+	if bn.schema != nil {
+		for i, row := range data {
+			if err := bn.schema.Check(Sample{Discrete: row}); err != nil {
+				return fmt.Errorf("sample %d: %w", i, err)
+			}
 		}
 	}
+	if len(bn.hidden) > 0 || bn.MissingStrategy() == MissingEM {
+		_, err := bn.FitEM(data, EMOptions{})
+		return err
+	}
+	if bn.MissingStrategy() == MissingListwise {
+		data = bn.completeDiscreteRows(data)
+	}
 
 	// For each node, learn its CPD from data
 	for _, node := range bn.Nodes() {
@@ -680,6 +988,8 @@ func (bn *BayesianNetwork) Fit(data []map[string]int) error {
 			return err
 		}
 		bn.CPDs[node] = cpd
+		delete(bn.CustomCPDs, node)
+		delete(bn.HybridCPDs, node)
 		bn.VariableType[node] = Discrete
 		bn.Cardinality[node] = cpd.VariableCard
 		for k, v := range cpd.EvidenceCard {
@@ -688,13 +998,37 @@ func (bn *BayesianNetwork) Fit(data []map[string]int) error {
 		}
 	}
 
+	if len(bn.tied) > 0 {
+		samples := make([]Sample, len(data))
+		for i, row := range data {
+			samples[i] = Sample{Discrete: row}
+		}
+		return bn.fitTied(samples, nil)
+	}
 	return nil
 }
 
-// FitMixed learns CPD parameters from mixed discrete/continuous data
+// FitMixed learns CPD parameters from mixed discrete/continuous data,
+// treating missing values by the strategy set by SetMissingStrategy
 func (bn *BayesianNetwork) FitMixed(data []Sample) error {
-	// Learn each node's CPD
-	for _, node := range bn.Nodes() {
+	_, err := bn.FitWithReport(data)
+	return err
+}
+
+// fitMixed learns CPD parameters with optional per-sample weights; nil
+// weights count every sample once
+func (bn *BayesianNetwork) fitMixed(data []Sample, weights []float64) error {
+	if err := bn.checkSchema(data); err != nil {
+		return err
+	}
+	// Learn CPDs in topological order so parent types and cardinalities are
+	// known by the time their children are fitted
+	order, err := bn.DAG.TopologicalSort()
+	if err != nil {
+		return err
+	}
+
+	for _, node := range order {
 		if bn.IsDiscrete(node) || bn.VariableType[node] == "" {
 			// Try to determine from data if not specified
 			hasIntData := false
@@ -709,34 +1043,79 @@ func (bn *BayesianNetwork) FitMixed(data []Sample) error {
 				}
 			}
 
-			if hasIntData {
-				cpd, err := bn.learnDiscreteCPDFromMixed(node, data)
+			if hasIntData && bn.ordinalProbit[node] {
+				if err := bn.fitOrdinalProbit(node, data, weights); err != nil {
+					return err
+				}
+			} else if hasIntData {
+				cpd, err := bn.learnDiscreteCPDFromMixed(node, data, weights)
 				if err != nil {
 					return err
 				}
 				bn.CPDs[node] = cpd
+				delete(bn.CustomCPDs, node)
+				delete(bn.HybridCPDs, node)
 				bn.VariableType[node] = Discrete
 				bn.Cardinality[node] = cpd.VariableCard
 				for k, v := range cpd.EvidenceCard {
 					bn.Cardinality[k] = v
 				}
 			} else if hasFloatData {
-				cpd, err := bn.learnGaussianCPDFromMixed(node, data)
-				if err != nil {
+				if err := bn.fitContinuousNode(node, data, weights); err != nil {
 					return err
 				}
-				bn.GaussianCPDs[node] = cpd
 				bn.VariableType[node] = Continuous
 			}
 		} else if bn.IsContinuous(node) {
-			cpd, err := bn.learnGaussianCPDFromMixed(node, data)
-			if err != nil {
+			if err := bn.fitContinuousNode(node, data, weights); err != nil {
 				return err
 			}
-			bn.GaussianCPDs[node] = cpd
 		}
 	}
 
+	return bn.fitTied(data, weights)
+}
+
+// fitContinuousNode learns the CPD of a continuous node: a Gaussian process
+// if declared by SetGaussianProcess, a Gaussian copula CPD if declared by
+// SetCopula, Poisson if declared a count by SetCount, Gamma if declared by
+// SetGamma or SetExponential, Beta if declared by SetBeta, linear Gaussian
+// otherwise
+func (bn *BayesianNetwork) fitContinuousNode(node string, data []Sample, weights []float64) error {
+	_, gp := bn.gaussianProcess[node]
+	_, gamma := bn.gammaShape[node]
+	_, copula := bn.copula[node]
+	if gp || copula || gamma || bn.beta[node] || bn.count[node] {
+		var cpd factors.ContinuousCPD
+		var err error
+		switch {
+		case gp:
+			cpd, err = bn.learnGaussianProcessCPD(node, data, weights)
+		case copula:
+			cpd, err = bn.learnCopulaCPD(node, data, weights)
+		case gamma:
+			cpd, err = bn.learnGammaCPD(node, data, weights)
+		case bn.beta[node]:
+			cpd, err = bn.learnBetaCPD(node, data, weights)
+		default:
+			cpd, err = bn.learnPoissonCPD(node, data, weights)
+		}
+		if err != nil {
+			return err
+		}
+		if bn.ContinuousCPDs == nil {
+			bn.ContinuousCPDs = make(map[string]factors.ContinuousCPD)
+		}
+		bn.ContinuousCPDs[node] = cpd
+		delete(bn.GaussianCPDs, node)
+		return nil
+	}
+	cpd, err := bn.learnGaussianCPDFromMixed(node, data, weights)
+	if err != nil {
+		return err
+	}
+	bn.GaussianCPDs[node] = cpd
+	delete(bn.ContinuousCPDs, node)
 	return nil
 }
 
@@ -762,6 +1141,15 @@ func (bn *BayesianNetwork) learnCPD(variable string, data []map[string]int) (*fa
 			}
 		}
 	}
+	var err error
+	if varCard, err = bn.fitCardinality(variable, varCard); err != nil {
+		return nil, err
+	}
+	for _, p := range parents {
+		if evidenceCard[p], err = bn.fitCardinality(p, evidenceCard[p]); err != nil {
+			return nil, err
+		}
+	}
 
 	// Count occurrences
 	numRows := 1
@@ -803,25 +1191,15 @@ func (bn *BayesianNetwork) learnCPD(variable string, data []map[string]int) (*fa
 		counts[rowIdx][val]++
 	}
 
-	// Normalize to get probabilities (with Laplace smoothing)
-	values := make([][]float64, numRows)
-	for i := range values {
-		values[i] = make([]float64, varCard)
-		sum := 0.0
-		for j := range values[i] {
-			counts[i][j] += 1.0 // Laplace smoothing
-			sum += counts[i][j]
-		}
-		for j := range values[i] {
-			values[i][j] = counts[i][j] / sum
-		}
-	}
+	// Normalize to get probabilities (with Laplace smoothing or shrinkage)
+	values := bn.normalizeCounts(counts, varCard)
 
 	return factors.NewTabularCPD(variable, varCard, values, parents, evidenceCard)
 }
 
-// learnDiscreteCPDFromMixed learns discrete CPD from mixed data
-func (bn *BayesianNetwork) learnDiscreteCPDFromMixed(variable string, data []Sample) (*factors.TabularCPD, error) {
+// learnDiscreteCPDFromMixed learns discrete CPD from mixed data, adding each
+// sample's weight to the counts
+func (bn *BayesianNetwork) learnDiscreteCPDFromMixed(variable string, data []Sample, weights []float64) (*factors.TabularCPD, error) {
 	parents := bn.DAG.Parents(variable)
 	sort.Strings(parents)
 
@@ -843,6 +1221,15 @@ func (bn *BayesianNetwork) learnDiscreteCPDFromMixed(variable string, data []Sam
 			}
 		}
 	}
+	var err error
+	if varCard, err = bn.fitCardinality(variable, varCard); err != nil {
+		return nil, err
+	}
+	for _, p := range parents {
+		if evidenceCard[p], err = bn.fitCardinality(p, evidenceCard[p]); err != nil {
+			return nil, err
+		}
+	}
 
 	// Count occurrences
 	numRows := 1
@@ -856,7 +1243,7 @@ func (bn *BayesianNetwork) learnDiscreteCPDFromMixed(variable string, data []Sam
 	}
 
 	// Count from data
-	for _, sample := range data {
+	for i, sample := range data {
 		// Calculate row index
 		rowIdx := 0
 		stride := 1
@@ -881,30 +1268,24 @@ func (bn *BayesianNetwork) learnDiscreteCPDFromMixed(variable string, data []Sam
 			continue
 		}
 
-		counts[rowIdx][val]++
+		counts[rowIdx][val] += sampleWeight(weights, i)
 	}
 
-	// Normalize to get probabilities (with Laplace smoothing)
-	values := make([][]float64, numRows)
-	for i := range values {
-		values[i] = make([]float64, varCard)
-		sum := 0.0
-		for j := range values[i] {
-			counts[i][j] += 1.0 // Laplace smoothing
-			sum += counts[i][j]
-		}
-		for j := range values[i] {
-			values[i][j] = counts[i][j] / sum
-		}
-	}
+	// Normalize to get probabilities (with Laplace smoothing or shrinkage)
+	values := bn.normalizeCounts(counts, varCard)
 
 	return factors.NewTabularCPD(variable, varCard, values, parents, evidenceCard)
 }
 
-// learnGaussianCPDFromMixed learns Gaussian CPD from mixed data using linear regression
-func (bn *BayesianNetwork) learnGaussianCPDFromMixed(variable string, data []Sample) (*factors.LinearGaussianCPD, error) {
+// learnGaussianCPDFromMixed learns Gaussian CPD from mixed data using
+// (weighted) linear regression
+func (bn *BayesianNetwork) learnGaussianCPDFromMixed(variable string, data []Sample, weights []float64) (*factors.LinearGaussianCPD, error) {
 	parents := bn.DAG.Parents(variable)
 	sort.Strings(parents)
+	basis := bn.basis[variable]
+	if err := bn.checkBasisParents(variable, basis); err != nil {
+		return nil, err
+	}
 
 	if len(parents) == 0 {
 		// No parents: just compute mean and variance
@@ -912,11 +1293,12 @@ func (bn *BayesianNetwork) learnGaussianCPDFromMixed(variable string, data []Sam
 		sumSq := 0.0
 		count := 0.0
 
-		for _, sample := range data {
+		for i, sample := range data {
 			if val, ok := sample.Continuous[variable]; ok {
-				sum += val
-				sumSq += val * val
-				count++
+				w := sampleWeight(weights, i)
+				sum += w * val
+				sumSq += w * val * val
+				count += w
 			}
 		}
 
@@ -947,10 +1329,10 @@ func (bn *BayesianNetwork) learnGaussianCPDFromMixed(variable string, data []Sam
 		// Using simple least squares
 
 		// Collect data points
-		var xVals []float64
+		var xVals, rowWeights []float64
 		var yMatrix [][]float64 // Each row is [1, y1, y2, ..., yn]
 
-		for _, sample := range data {
+		for i, sample := range data {
 			xVal, okX := sample.Continuous[variable]
 			if !okX {
 				continue
@@ -968,53 +1350,265 @@ func (bn *BayesianNetwork) learnGaussianCPDFromMixed(variable string, data []Sam
 			}
 
 			if valid {
+				row = appendBasisFeatures(row, basis, sample.Continuous)
 				xVals = append(xVals, xVal)
 				yMatrix = append(yMatrix, row)
+				rowWeights = append(rowWeights, sampleWeight(weights, i))
 			}
 		}
 
-		if len(xVals) < len(parents)+1 {
+		if len(xVals) < len(parents)+len(basis)+1 {
 			return nil, fmt.Errorf("insufficient data for learning Gaussian CPD for %s", variable)
 		}
 
-		// Solve using normal equations: β = (Y^T Y)^(-1) Y^T X
-		coeffs, err := solveLinearRegression(yMatrix, xVals)
-		if err != nil {
-			return nil, fmt.Errorf("failed to solve linear regression: %v", err)
+		var cpd *factors.LinearGaussianCPD
+		var coeffs []float64
+		if bn.heteroscedastic[variable] {
+			var gamma []float64
+			var err error
+			coeffs, gamma, err = bn.fitHeteroscedastic(yMatrix, xVals, rowWeights, len(parents)+1)
+			if err != nil {
+				return nil, err
+			}
+			parentCoeffs := make(map[string]float64, len(parents))
+			logVariance := make(map[string]float64, len(parents))
+			for i, p := range parents {
+				parentCoeffs[p] = coeffs[i+1]
+				logVariance[p] = gamma[i+1]
+			}
+			cpd, err = factors.NewHeteroscedasticGaussianCPD(variable, parents, coeffs[0], parentCoeffs,
+				math.Max(math.Exp(gamma[0]), 1e-6), logVariance)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			var variance float64
+			var err error
+			coeffs, variance, err = bn.fitRegression(yMatrix, xVals, rowWeights)
+			if err != nil {
+				return nil, err
+			}
+
+			intercept := coeffs[0]
+			parentCoeffs := make(map[string]float64)
+			for i, p := range parents {
+				parentCoeffs[p] = coeffs[i+1]
+			}
+
+			cpd, err = factors.NewLinearGaussianCPD(variable, parents, intercept, parentCoeffs, variance)
+			if err != nil {
+				return nil, err
+			}
 		}
+		if len(basis) > 0 {
+			if err := cpd.SetBasis(basis); err != nil {
+				return nil, err
+			}
+			cpd.BasisCoefficients = append([]float64(nil), coeffs[len(parents)+1:]...)
+		}
+		return cpd, nil
+	}
+
+	// Discrete parents, possibly alongside continuous ones: fit a separate
+	// regression on the continuous parents for each discrete configuration
+	if bn.heteroscedastic[variable] {
+		return nil, fmt.Errorf("heteroscedastic CPD for %s needs continuous parents only", variable)
+	}
+	var discreteParents, continuousParents []string
+	for _, p := range parents {
+		if bn.IsDiscrete(p) {
+			discreteParents = append(discreteParents, p)
+		} else {
+			continuousParents = append(continuousParents, p)
+		}
+	}
 
-		intercept := coeffs[0]
-		parentCoeffs := make(map[string]float64)
-		for i, p := range parents {
-			parentCoeffs[p] = coeffs[i+1]
+	cardinality := make(map[string]int)
+	for _, p := range discreteParents {
+		cardinality[p] = bn.Cardinality[p]
+	}
+
+	groupX := make(map[string][]float64)
+	groupY := make(map[string][][]float64)
+	groupW := make(map[string][]float64)
+	var pooledX, pooledW []float64
+	var pooledY [][]float64
+
+	for n, sample := range data {
+		xVal, okX := sample.Continuous[variable]
+		if !okX {
+			continue
 		}
 
-		// Compute residual variance
-		sumSqResid := 0.0
-		for i, row := range yMatrix {
-			predicted := intercept
-			for j, p := range parents {
-				predicted += parentCoeffs[p] * row[j+1]
+		key := ""
+		valid := true
+		for i, p := range discreteParents {
+			pVal, ok := sample.Discrete[p]
+			if !ok {
+				valid = false
+				break
+			}
+			if pVal+1 > cardinality[p] {
+				cardinality[p] = pVal + 1
+			}
+			if i > 0 {
+				key += ","
 			}
-			residual := xVals[i] - predicted
-			sumSqResid += residual * residual
+			key += fmt.Sprintf("%d", pVal)
 		}
-		variance := sumSqResid / float64(len(xVals))
-		if variance < 1e-6 {
-			variance = 1e-6
+		if !valid {
+			continue
 		}
 
-		return factors.NewLinearGaussianCPD(variable, parents, intercept, parentCoeffs, variance)
+		row := []float64{1.0} // Intercept
+		for _, p := range continuousParents {
+			pVal, ok := sample.Continuous[p]
+			if !ok {
+				valid = false
+				break
+			}
+			row = append(row, pVal)
+		}
+
+		if valid {
+			row = appendBasisFeatures(row, basis, sample.Continuous)
+			w := sampleWeight(weights, n)
+			groupX[key] = append(groupX[key], xVal)
+			groupY[key] = append(groupY[key], row)
+			groupW[key] = append(groupW[key], w)
+			pooledX = append(pooledX, xVal)
+			pooledY = append(pooledY, row)
+			pooledW = append(pooledW, w)
+		}
+	}
+
+	width := len(continuousParents) + len(basis) + 1
+	if len(pooledX) < width {
+		return nil, fmt.Errorf("insufficient data for learning Gaussian CPD for %s", variable)
+	}
+
+	// Configurations that are unseen or too sparse to fit fall back to the
+	// regression pooled over all configurations
+	pooledCoeffs, pooledVariance, err := bn.fitRegression(pooledY, pooledX, pooledW)
+	if err != nil {
+		return nil, err
 	}
 
-	// Mixed discrete/continuous parents or all discrete parents
-	// Create separate Gaussian for each discrete parent configuration
-	// This is more complex - simplified implementation
-	return nil, fmt.Errorf("learning Gaussian CPD with discrete parents not yet fully implemented")
+	states := make(map[string]factors.GaussianParams)
+	for _, config := range discreteConfigurations(discreteParents, cardinality) {
+		coeffs, variance := pooledCoeffs, pooledVariance
+		if len(groupX[config]) > width {
+			if c, v, err := bn.fitRegression(groupY[config], groupX[config], groupW[config]); err == nil {
+				coeffs, variance = c, v
+			}
+		}
+
+		params := factors.GaussianParams{Mean: coeffs[0], Variance: variance}
+		if len(continuousParents) > 0 {
+			params.Coefficients = make(map[string]float64, len(continuousParents))
+			for i, p := range continuousParents {
+				params.Coefficients[p] = coeffs[i+1]
+			}
+		}
+		if len(basis) > 0 {
+			params.BasisCoefficients = append([]float64(nil), coeffs[len(continuousParents)+1:]...)
+		}
+		states[config] = params
+	}
+
+	cpd, err := factors.NewCLGCPD(variable, discreteParents, continuousParents, cardinality, states)
+	if err != nil {
+		return nil, err
+	}
+	if len(basis) > 0 {
+		if err := cpd.SetBasis(basis); err != nil {
+			return nil, err
+		}
+	}
+	return cpd, nil
 }
 
-// solveLinearRegression solves β = (Y^T Y)^(-1) Y^T X using normal equations
-func solveLinearRegression(Y [][]float64, X []float64) ([]float64, error) {
+// fitGaussianRegression fits X = β₀ + Σᵢ βᵢYᵢ + ε by weighted least squares,
+// where each row of Y is [1, y1, ..., yn], and returns the coefficients and
+// the weighted residual variance. Nil weights give ordinary least squares; a
+// positive ridge adds ridge·Σᵢ βᵢ² to the loss, leaving β₀ unpenalized.
+func fitGaussianRegression(Y [][]float64, X []float64, W []float64, ridge float64) ([]float64, float64, error) {
+	// Weighted least squares is ordinary least squares on rows scaled by √w
+	scaledY, scaledX := Y, X
+	if W != nil {
+		scaledY = make([][]float64, len(Y))
+		scaledX = make([]float64, len(X))
+		for i, row := range Y {
+			r := math.Sqrt(W[i])
+			scaledY[i] = make([]float64, len(row))
+			for j, v := range row {
+				scaledY[i][j] = r * v
+			}
+			scaledX[i] = r * X[i]
+		}
+	}
+
+	// Solve using normal equations: β = (Y^T Y)^(-1) Y^T X
+	coeffs, err := solveLinearRegression(scaledY, scaledX, ridge)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to solve linear regression: %w", err)
+	}
+
+	// Compute residual variance
+	sumSqResid := 0.0
+	totalWeight := 0.0
+	for i, row := range Y {
+		predicted := 0.0
+		for j, c := range coeffs {
+			predicted += c * row[j]
+		}
+		residual := X[i] - predicted
+		w := sampleWeight(W, i)
+		sumSqResid += w * residual * residual
+		totalWeight += w
+	}
+	if totalWeight <= 0 {
+		return nil, 0, fmt.Errorf("regression rows have no weight")
+	}
+	variance := sumSqResid / totalWeight
+	if variance < 1e-6 {
+		variance = 1e-6
+	}
+
+	return coeffs, variance, nil
+}
+
+// sampleWeight returns the weight of sample i, or 1 when unweighted
+func sampleWeight(weights []float64, i int) float64 {
+	if weights == nil {
+		return 1
+	}
+	return weights[i]
+}
+
+// discreteConfigurations enumerates the comma-joined state keys of the given
+// variables, with the last variable changing fastest
+func discreteConfigurations(variables []string, cardinality map[string]int) []string {
+	configs := []string{""}
+	for i, v := range variables {
+		next := make([]string, 0, len(configs)*cardinality[v])
+		for _, prefix := range configs {
+			for state := 0; state < cardinality[v]; state++ {
+				if i > 0 {
+					next = append(next, fmt.Sprintf("%s,%d", prefix, state))
+				} else {
+					next = append(next, fmt.Sprintf("%d", state))
+				}
+			}
+		}
+		configs = next
+	}
+	return configs
+}
+
+// solveLinearRegression solves β = (Y^T Y + ridge·I)^(-1) Y^T X using normal
+// equations, with no penalty on the intercept in the first column
+func solveLinearRegression(Y [][]float64, X []float64, ridge float64) ([]float64, error) {
 	if len(Y) == 0 || len(Y) != len(X) {
 		return nil, fmt.Errorf("invalid input dimensions")
 	}
@@ -1033,6 +1627,9 @@ func solveLinearRegression(Y [][]float64, X []float64) ([]float64, error) {
 			}
 			YtY[i][j] = sum
 		}
+		if i > 0 {
+			YtY[i][i] += ridge
+		}
 	}
 
 	// Compute Y^T X
@@ -1066,7 +1663,7 @@ func solveLinearRegression(Y [][]float64, X []float64) ([]float64, error) {
 		augmented[i], augmented[maxRow] = augmented[maxRow], augmented[i]
 
 		if math.Abs(augmented[i][i]) < 1e-10 {
-			return nil, fmt.Errorf("singular matrix in linear regression")
+			return nil, fmt.Errorf("singular matrix in linear regression; SetRidge regularizes correlated parents")
 		}
 
 		// Eliminate
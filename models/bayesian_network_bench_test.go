@@ -108,3 +108,21 @@ func BenchmarkBayesianNetworkPredict(b *testing.B) {
 		_, _ = bn.Predict(testData)
 	}
 }
+
+func BenchmarkBayesianNetworkSimulateMixed(b *testing.B) {
+	bn := newFourNodeNetwork()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = bn.SimulateMixed(1000, 42)
+	}
+}
+
+func BenchmarkBayesianNetworkSimulateColumns(b *testing.B) {
+	bn := newFourNodeNetwork()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = bn.SimulateColumns(1000, 42)
+	}
+}
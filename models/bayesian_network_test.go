@@ -1,6 +1,13 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/JohnPierman/bngo/factors"
@@ -107,6 +114,88 @@ func TestBayesianNetworkSimulation(t *testing.T) {
 	}
 }
 
+func TestSimulateWithRand(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"A", "B"}})
+	cpdA, _ := factors.NewTabularCPD("A", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	cpdB, _ := factors.NewTabularCPD("B", 2, [][]float64{{0.8, 0.2}, {0.3, 0.7}},
+		[]string{"A"}, map[string]int{"A": 2})
+	_ = bn.AddCPD(cpdA)
+	_ = bn.AddCPD(cpdB)
+
+	seeded, _ := bn.Simulate(50, 7)
+	injected, err := bn.SimulateWithRand(50, rand.New(rand.NewSource(7)))
+	if err != nil {
+		t.Fatalf("SimulateWithRand failed: %v", err)
+	}
+	if !reflect.DeepEqual(seeded, injected) {
+		t.Error("SimulateWithRand with the same seed should match Simulate")
+	}
+
+	// A shared generator advances between calls instead of repeating draws
+	r := rand.New(rand.NewSource(7))
+	first, _ := bn.SimulateWithRand(50, r)
+	second, _ := bn.SimulateWithRand(50, r)
+	if reflect.DeepEqual(first, second) {
+		t.Error("Consecutive draws from a shared generator should differ")
+	}
+
+	secure, err := bn.SimulateWithRand(20, rand.New(NewCryptoSource()))
+	if err != nil || len(secure) != 20 {
+		t.Errorf("Crypto-backed simulation failed: %v", err)
+	}
+}
+
+func TestSimulateAllocations(t *testing.T) {
+	bn := newFourNodeNetwork()
+	const n = 1000
+
+	// Drawing reuses one scratch row, so each sample costs only its own map:
+	// a header and a bucket, plus setup shared by all samples
+	perSample := testing.AllocsPerRun(5, func() {
+		if _, err := bn.Simulate(n, 3); err != nil {
+			t.Fatalf("Simulate failed: %v", err)
+		}
+	}) / n
+	if perSample > 2.5 {
+		t.Errorf("Simulate made %.2f allocations per sample, want about 2", perSample)
+	}
+}
+
+func TestSimulateStream(t *testing.T) {
+	bn := newFourNodeNetwork()
+
+	want, err := bn.SimulateMixed(200, 11)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+	got := make([]Sample, 0, len(want))
+	for sample, err := range bn.SimulateStream(11) {
+		if err != nil {
+			t.Fatalf("SimulateStream failed: %v", err)
+		}
+		got = append(got, sample)
+		if len(got) == len(want) {
+			break
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Error("The first n streamed samples should match SimulateMixed(n, seed)")
+	}
+
+	// An invalid model yields its error once and ends the stream
+	delete(bn.CPDs, "C")
+	count := 0
+	for _, err := range bn.SimulateStream(11) {
+		count++
+		if err == nil {
+			t.Error("Expected an error from a model with a missing CPD")
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected a single error, got %d items", count)
+	}
+}
+
 func TestBayesianNetworkFit(t *testing.T) {
 	// Create synthetic data
 	samples := []map[string]int{
@@ -140,3 +229,868 @@ func TestBayesianNetworkFit(t *testing.T) {
 		t.Errorf("Expected cardinality 2 for A, got %d", cpdA.VariableCard)
 	}
 }
+
+func TestBayesianNetworkSummary(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"A", "C"}, {"B", "C"}, {"C", "X"}})
+
+	cpdA, _ := factors.NewTabularCPD("A", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	cpdC, _ := factors.NewTabularCPD("C", 3,
+		[][]float64{{0.2, 0.3, 0.5}, {0.1, 0.1, 0.8}, {0.3, 0.3, 0.4}, {0.6, 0.2, 0.2}},
+		[]string{"A", "B"}, map[string]int{"A": 2, "B": 2})
+	_ = bn.AddCPD(cpdA)
+	_ = bn.AddCPD(cpdC)
+	cpdX, _ := factors.NewDiscreteParentGaussianCPD("X", []string{"C"}, map[string]int{"C": 3},
+		map[string]factors.GaussianParams{"0": {Mean: 0, Variance: 1}, "1": {Mean: 1, Variance: 1}, "2": {Mean: 2, Variance: 1}})
+	_ = bn.AddGaussianCPD(cpdX)
+
+	summary := bn.Summary()
+	if summary.Nodes != 4 || summary.Edges != 3 {
+		t.Errorf("Expected 4 nodes and 3 edges, got %d and %d", summary.Nodes, summary.Edges)
+	}
+	if len(summary.MissingCPDs) != 1 || summary.MissingCPDs[0] != "B" {
+		t.Errorf("Expected B to be missing a CPD, got %v", summary.MissingCPDs)
+	}
+	// A: 1, C: 4 rows * 2, X: 3 configurations * (intercept + variance)
+	if summary.Parameters != 1+8+6 {
+		t.Errorf("Expected 15 parameters, got %d", summary.Parameters)
+	}
+	if summary.LargestClique != 3 {
+		t.Errorf("Expected largest clique 3, got %d", summary.LargestClique)
+	}
+	if summary.NodeDetails[2].Name != "C" || summary.NodeDetails[2].TableSize != 12 {
+		t.Errorf("Unexpected summary for C: %+v", summary.NodeDetails[2])
+	}
+}
+
+func TestCheckModelCollectsAllIssues(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"A", "B"}, {"A", "X"}})
+
+	cpdA, _ := factors.NewTabularCPD("A", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	_ = bn.AddCPD(cpdA)
+	// Corrupt A's table after construction and give X a bad variance
+	cpdA.Values[0][1] = 0.7
+	cpdX, _ := factors.NewDiscreteParentGaussianCPD("X", []string{"A"}, map[string]int{"A": 3},
+		map[string]factors.GaussianParams{"0": {Mean: 0, Variance: 1}, "1": {Mean: 1, Variance: 0}, "2": {Mean: 2, Variance: 1}})
+	// AddGaussianCPD rejects the conflicting cardinality of A, so bypass it
+	bn.GaussianCPDs["X"] = cpdX
+	bn.VariableType["X"] = Continuous
+
+	err := bn.CheckModel()
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Expected *ValidationError, got %v", err)
+	}
+
+	for _, kind := range []IssueKind{IssueRowSum, IssueMissingCPD, IssueCardinality, IssueNonPositiveVariance} {
+		if !validation.Has(kind) {
+			t.Errorf("Expected a %s issue in %v", kind, validation)
+		}
+	}
+	if !errors.Is(err, ErrMissingCPD) || !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("Expected error to match ErrMissingCPD and ErrInvalidModel")
+	}
+	if validation.Issues[0].Node != "A" {
+		t.Errorf("Expected issues ordered by node, got %v", validation.Issues)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"A", "B"}})
+	if err := bn.DAG.AddEdge("B", "A"); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected ErrCycle, got %v", err)
+	}
+	if _, err := bn.GetCPD("A"); !errors.Is(err, ErrMissingCPD) {
+		t.Errorf("Expected ErrMissingCPD, got %v", err)
+	}
+
+	cpd, _ := factors.NewTabularCPD("C", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	if err := bn.AddCPD(cpd); !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("Expected ErrUnknownVariable, got %v", err)
+	}
+	if _, err := factors.NewTabularCPD("A", 2, [][]float64{{0.5, 0.6}}, []string{}, map[string]int{}); !errors.Is(err, ErrInvalidDistribution) {
+		t.Errorf("Expected ErrInvalidDistribution, got %v", err)
+	}
+}
+
+func TestNetworkJSON(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"Flu", "Fever"}, {"Cold", "Fever"}})
+	flu, _ := factors.NewTabularCPD("Flu", 2, [][]float64{{0.7, 0.3}}, []string{}, map[string]int{})
+	_ = flu.SetStateNames("Flu", []string{"no", "yes"})
+	cold, _ := factors.NewTabularCPD("Cold", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	fever, _ := factors.NewNoisyOrCPD("Fever", map[string]float64{"Flu": 0.8, "Cold": 0.4}, 0.1)
+	_ = bn.AddCPD(flu)
+	_ = bn.AddCPD(cold)
+	_ = bn.AddCustomCPD(fever)
+
+	data, err := json.Marshal(bn)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded BayesianNetwork
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if err := decoded.CheckModel(); err != nil {
+		t.Fatalf("Decoded model is invalid: %v", err)
+	}
+
+	// The noisy-OR CPD comes back as its table
+	want, _ := bn.NodeFactor("Fever")
+	got, err := decoded.NodeFactor("Fever")
+	if err != nil {
+		t.Fatalf("NodeFactor failed: %v", err)
+	}
+	if !got.Equal(want, 1e-12) {
+		t.Errorf("Fever CPD changed in round trip: %v, expected %v", got.Values, want.Values)
+	}
+	if names := decoded.StateNames("Flu"); !reflect.DeepEqual(names, []string{"no", "yes"}) {
+		t.Errorf("Expected Flu states [no yes], got %v", names)
+	}
+
+	if err := json.Unmarshal([]byte(`{"nodes":[{"name":"A","type":"bogus"}]}`), &decoded); err == nil {
+		t.Error("Expected an error for an unknown variable type")
+	}
+}
+
+func TestCustomCPD(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"Flu", "Fever"}, {"Cold", "Fever"}})
+	flu, _ := factors.NewTabularCPD("Flu", 2, [][]float64{{0.7, 0.3}}, []string{}, map[string]int{})
+	cold, _ := factors.NewTabularCPD("Cold", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	_ = bn.AddCPD(flu)
+	_ = bn.AddCPD(cold)
+
+	if err := bn.CheckModel(); !errors.Is(err, ErrMissingCPD) {
+		t.Fatalf("Expected missing CPD before adding Fever, got %v", err)
+	}
+
+	bad, _ := factors.NewNoisyOrCPD("Fever", map[string]float64{"Flu": 0.8}, 0.1)
+	if err := bn.AddCustomCPD(bad); !errors.Is(err, ErrParentMismatch) {
+		t.Errorf("Expected parent mismatch, got %v", err)
+	}
+
+	fever, _ := factors.NewNoisyOrCPD("Fever", map[string]float64{"Flu": 0.8, "Cold": 0.4}, 0.1)
+	if err := bn.AddCustomCPD(fever); err != nil {
+		t.Fatalf("AddCustomCPD failed: %v", err)
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("CheckModel failed: %v", err)
+	}
+
+	// P(Fever=1) = 1 - 0.9 * (1 - 0.3*0.8) * (1 - 0.5*0.4)
+	exact := 1 - 0.9*(1-0.3*0.8)*(1-0.5*0.4)
+
+	marginal, err := bn.discreteMarginal([]string{"Fever"})
+	if err != nil {
+		t.Fatalf("discreteMarginal failed: %v", err)
+	}
+	if math.Abs(marginal.Values[1]-exact) > 1e-9 {
+		t.Errorf("P(Fever=1) = %f, expected %f", marginal.Values[1], exact)
+	}
+
+	samples, err := bn.Simulate(20000, 3)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	on := 0
+	for _, s := range samples {
+		on += s["Fever"]
+	}
+	if freq := float64(on) / float64(len(samples)); math.Abs(freq-exact) > 0.02 {
+		t.Errorf("Simulated P(Fever=1) = %f, expected %f", freq, exact)
+	}
+}
+
+func TestNetworkMutation(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"A", "B"}})
+	cpdA, _ := factors.NewTabularCPD("A", 2, [][]float64{{0.4, 0.6}}, []string{}, map[string]int{})
+	cpdB, _ := factors.NewTabularCPD("B", 2, [][]float64{{0.9, 0.1}, {0.2, 0.8}},
+		[]string{"A"}, map[string]int{"A": 2})
+	_ = bn.AddCPD(cpdA)
+	_ = bn.AddCPD(cpdB)
+
+	if err := bn.AddNode("C", Discrete, 3); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	cpdC, _ := factors.NewTabularCPD("C", 3, [][]float64{{0.2, 0.3, 0.5}}, []string{}, map[string]int{})
+	_ = bn.AddCPD(cpdC)
+
+	// Adding C -> B repeats B's rows, so B still only depends on A
+	if err := bn.AddEdge("C", "B"); err != nil {
+		t.Fatalf("AddEdge failed: %v", err)
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("Model invalid after AddEdge: %v", err)
+	}
+	if rows := len(bn.CPDs["B"].Values); rows != 6 {
+		t.Errorf("Expected 6 rows for B, got %d", rows)
+	}
+	if err := bn.AddEdge("B", "A"); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+
+	// B does not depend on C, so removing C keeps B's CPD
+	if err := bn.RemoveNode("C"); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("Model invalid after RemoveNode: %v", err)
+	}
+	if p, _ := bn.CPDs["B"].GetValue(1, map[string]int{"A": 1}); p != 0.8 {
+		t.Errorf("Expected P(B=1 | A=1) = 0.8, got %f", p)
+	}
+
+	// B depends on A, so removing the edge invalidates B's CPD
+	if err := bn.RemoveEdge("A", "B"); err != nil {
+		t.Fatalf("RemoveEdge failed: %v", err)
+	}
+	if err := bn.CheckModel(); !errors.Is(err, ErrMissingCPD) {
+		t.Errorf("Expected missing CPD for B, got %v", err)
+	}
+}
+
+func TestGaussianEdgeMutation(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"X", "Y"}})
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 0, map[string]float64{}, 1)
+	cpdY, _ := factors.NewLinearGaussianCPD("Y", []string{"X"}, 1, map[string]float64{"X": 2}, 0.5)
+	_ = bn.AddGaussianCPD(cpdX)
+	_ = bn.AddGaussianCPD(cpdY)
+	_ = bn.AddNode("S", Discrete, 2)
+	cpdS, _ := factors.NewTabularCPD("S", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	_ = bn.AddCPD(cpdS)
+
+	if err := bn.AddEdge("S", "Y"); err != nil {
+		t.Fatalf("AddEdge failed: %v", err)
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("Model invalid after adding discrete parent: %v", err)
+	}
+	mean, _ := bn.GaussianCPDs["Y"].GetMean(map[string]interface{}{"X": 1.5, "S": 1})
+	if math.Abs(mean-4) > 1e-12 {
+		t.Errorf("Expected mean 4 after adding S, got %f", mean)
+	}
+
+	// Y's parameters are the same for both states of S
+	if err := bn.RemoveEdge("S", "Y"); err != nil {
+		t.Fatalf("RemoveEdge failed: %v", err)
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("Model invalid after removing discrete parent: %v", err)
+	}
+	if cpd := bn.GaussianCPDs["Y"]; cpd.Intercept != 1 || cpd.Coefficients["X"] != 2 || cpd.Variance != 0.5 {
+		t.Errorf("Unexpected collapsed CPD: %v", cpd)
+	}
+}
+
+// newFourNodeNetwork builds A -> B -> C <- D
+func newFourNodeNetwork() *BayesianNetwork {
+	bn, _ := NewBayesianNetwork([][2]string{{"A", "B"}, {"B", "C"}, {"D", "C"}})
+	cpdA, _ := factors.NewTabularCPD("A", 2, [][]float64{{0.3, 0.7}}, []string{}, map[string]int{})
+	cpdB, _ := factors.NewTabularCPD("B", 2, [][]float64{{0.9, 0.1}, {0.4, 0.6}},
+		[]string{"A"}, map[string]int{"A": 2})
+	cpdD, _ := factors.NewTabularCPD("D", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	cpdC, _ := factors.NewTabularCPD("C", 2, [][]float64{{0.99, 0.01}, {0.7, 0.3}, {0.6, 0.4}, {0.1, 0.9}},
+		[]string{"B", "D"}, map[string]int{"B": 2, "D": 2})
+	for _, cpd := range []*factors.TabularCPD{cpdA, cpdB, cpdC, cpdD} {
+		_ = bn.AddCPD(cpd)
+	}
+	return bn
+}
+
+func TestSubnetwork(t *testing.T) {
+	bn := newFourNodeNetwork()
+
+	sub, err := bn.Subnetwork([]string{"B"})
+	if err != nil {
+		t.Fatalf("Subnetwork failed: %v", err)
+	}
+	if nodes := sub.Nodes(); len(nodes) != 2 || nodes[0] != "A" || nodes[1] != "B" {
+		t.Errorf("Expected nodes [A B], got %v", nodes)
+	}
+	if err := sub.CheckModel(); err != nil {
+		t.Errorf("Subnetwork is not self-contained: %v", err)
+	}
+
+	marginal, err := bn.MarginalSubnetwork([]string{"C", "A"})
+	if err != nil {
+		t.Fatalf("MarginalSubnetwork failed: %v", err)
+	}
+	if !marginal.DAG.HasEdge("A", "C") || len(marginal.Nodes()) != 2 {
+		t.Errorf("Expected A -> C, got edges %v", marginal.Edges())
+	}
+
+	want, _ := bn.discreteMarginal([]string{"A", "C"})
+	got, err := marginal.discreteMarginal([]string{"A", "C"})
+	if err != nil {
+		t.Fatalf("Marginal of subnetwork failed: %v", err)
+	}
+	if !got.Equal(want, 1e-12) {
+		t.Errorf("Marginal network joint %v, expected %v", got.Values, want.Values)
+	}
+
+	// A and D are marginally independent, so neither becomes the other's parent
+	three, err := bn.MarginalSubnetwork([]string{"A", "C", "D"})
+	if err != nil {
+		t.Fatalf("MarginalSubnetwork failed: %v", err)
+	}
+	if three.DAG.HasEdge("A", "D") || !three.DAG.HasEdge("A", "C") || !three.DAG.HasEdge("D", "C") {
+		t.Errorf("Expected A -> C <- D, got edges %v", three.Edges())
+	}
+
+	if _, err := bn.Subnetwork([]string{"Z"}); !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("Expected unknown variable error, got %v", err)
+	}
+}
+
+func TestRelevantSubnetwork(t *testing.T) {
+	bn := newFourNodeNetwork()
+
+	cases := []struct {
+		query    string
+		evidence map[string]int
+		nodes    []string
+	}{
+		{"C", map[string]int{"B": 1}, []string{"B", "C", "D"}}, // A is screened off by B
+		{"B", map[string]int{}, []string{"A", "B"}},            // C and D are barren
+		{"A", map[string]int{"D": 0}, []string{"A"}},           // D is d-separated from A
+		{"A", map[string]int{"C": 1}, []string{"A", "B", "C", "D"}},
+	}
+	for _, c := range cases {
+		evidence := make([]string, 0, len(c.evidence))
+		for v := range c.evidence {
+			evidence = append(evidence, v)
+		}
+		sub, err := bn.RelevantSubnetwork([]string{c.query}, evidence)
+		if err != nil {
+			t.Fatalf("RelevantSubnetwork failed: %v", err)
+		}
+		if nodes := sub.Nodes(); !reflect.DeepEqual(nodes, c.nodes) {
+			t.Errorf("P(%s | %v): expected nodes %v, got %v", c.query, c.evidence, c.nodes, nodes)
+		}
+		if err := sub.CheckModel(); err != nil {
+			t.Errorf("P(%s | %v): subnetwork is invalid: %v", c.query, c.evidence, err)
+		}
+
+		kept := make(map[string]int)
+		for v, s := range c.evidence {
+			if sub.DAG.HasNode(v) {
+				kept[v] = s
+			}
+		}
+		want, _ := bn.posterior(c.query, c.evidence)
+		got, err := sub.posterior(c.query, kept)
+		if err != nil {
+			t.Fatalf("Posterior on subnetwork failed: %v", err)
+		}
+		// Evidence screened off from the query drops out of the subnetwork,
+		// so only the normalized posteriors agree
+		wantTotal, gotTotal := 0.0, 0.0
+		for i := range want {
+			wantTotal += want[i]
+			gotTotal += got[i]
+		}
+		for i := range want {
+			if math.Abs(got[i]/gotTotal-want[i]/wantTotal) > 1e-12 {
+				t.Errorf("P(%s | %v) = %v on the subnetwork, want %v", c.query, c.evidence, got, want)
+				break
+			}
+		}
+	}
+
+	if _, err := bn.RelevantSubnetwork([]string{"Z"}, nil); !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("Expected unknown variable error, got %v", err)
+	}
+}
+
+func TestArcReversal(t *testing.T) {
+	bn := newFourNodeNetwork()
+	all := []string{"A", "B", "C", "D"}
+	before, _ := bn.discreteMarginal(all)
+
+	if err := bn.ReverseArc("B", "C"); err != nil {
+		t.Fatalf("ReverseArc failed: %v", err)
+	}
+	if !bn.DAG.HasEdge("C", "B") || !bn.DAG.HasEdge("A", "C") || !bn.DAG.HasEdge("D", "B") {
+		t.Errorf("Unexpected edges after reversal: %v", bn.Edges())
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("Model invalid after reversal: %v", err)
+	}
+	after, _ := bn.discreteMarginal(all)
+	if !after.Equal(before, 1e-12) {
+		t.Error("Arc reversal changed the joint distribution")
+	}
+
+	// A -> C -> B is now a second path from A to B
+	if err := bn.ReverseArc("A", "B"); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+}
+
+func TestEliminateNode(t *testing.T) {
+	bn := newFourNodeNetwork()
+	want, _ := bn.discreteMarginal([]string{"A", "C", "D"})
+
+	if err := bn.EliminateNode("B"); err != nil {
+		t.Fatalf("EliminateNode failed: %v", err)
+	}
+	if bn.DAG.HasNode("B") {
+		t.Error("B should be removed")
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("Model invalid after elimination: %v", err)
+	}
+	got, _ := bn.discreteMarginal([]string{"A", "C", "D"})
+	if !got.Equal(want, 1e-12) {
+		t.Errorf("Elimination changed the marginal: got %v, want %v", got.Values, want.Values)
+	}
+
+	barren := newFourNodeNetwork()
+	removed := barren.RemoveBarrenNodes([]string{"A"})
+	if len(removed) != 3 || removed[0] != "C" {
+		t.Errorf("Expected C then B and D removed, got %v", removed)
+	}
+	if nodes := barren.Nodes(); len(nodes) != 1 || nodes[0] != "A" {
+		t.Errorf("Expected only A to remain, got %v", nodes)
+	}
+}
+
+func TestAddCPDCardinalityConflicts(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"A", "B"}, {"A", "X"}})
+
+	cpdA, _ := factors.NewTabularCPD("A", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	if err := bn.AddCPD(cpdA); err != nil {
+		t.Fatalf("AddCPD(A) failed: %v", err)
+	}
+
+	// B's CPD assumes A has three states
+	cpdB, _ := factors.NewTabularCPD("B", 2, [][]float64{{0.5, 0.5}, {0.5, 0.5}, {0.5, 0.5}},
+		[]string{"A"}, map[string]int{"A": 3})
+	if err := bn.AddCPD(cpdB); !errors.Is(err, ErrCardinalityMismatch) {
+		t.Errorf("Expected ErrCardinalityMismatch for B, got %v", err)
+	}
+	cpdX, _ := factors.NewDiscreteParentGaussianCPD("X", []string{"A"}, map[string]int{"A": 3},
+		map[string]factors.GaussianParams{"0": {Mean: 0, Variance: 1}, "1": {Mean: 1, Variance: 1}, "2": {Mean: 2, Variance: 1}})
+	if err := bn.AddGaussianCPD(cpdX); !errors.Is(err, ErrCardinalityMismatch) {
+		t.Errorf("Expected ErrCardinalityMismatch for X, got %v", err)
+	}
+	if bn.Cardinality["A"] != 2 {
+		t.Errorf("Rejected CPDs changed the cardinality of A to %d", bn.Cardinality["A"])
+	}
+
+	// Replacing A's CPD may not change its cardinality under a child's CPD,
+	// but the same variable's CPD may be replaced freely
+	cpdB, _ = factors.NewTabularCPD("B", 2, [][]float64{{0.9, 0.1}, {0.2, 0.8}}, []string{"A"}, map[string]int{"A": 2})
+	if err := bn.AddCPD(cpdB); err != nil {
+		t.Fatalf("AddCPD(B) failed: %v", err)
+	}
+	cpdA3, _ := factors.NewTabularCPD("A", 3, [][]float64{{0.2, 0.3, 0.5}}, []string{}, map[string]int{})
+	if err := bn.AddCPD(cpdA3); !errors.Is(err, ErrCardinalityMismatch) {
+		t.Errorf("Expected ErrCardinalityMismatch replacing A, got %v", err)
+	}
+	cpdBWide, _ := factors.NewTabularCPD("B", 3, [][]float64{{0.2, 0.3, 0.5}, {0.2, 0.3, 0.5}}, []string{"A"}, map[string]int{"A": 2})
+	if err := bn.AddCPD(cpdBWide); err != nil {
+		t.Errorf("Replacing B with more states failed: %v", err)
+	}
+
+	// Declared cardinalities bind CPDs added later and must match existing ones
+	if err := bn.DeclareCardinality("A", 3); !errors.Is(err, ErrCardinalityMismatch) {
+		t.Errorf("Expected a declaration conflicting with A's CPD to fail, got %v", err)
+	}
+	if err := bn.AddNode("C", Discrete, 4); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if card, ok := bn.DeclaredCardinality("C"); !ok || card != 4 {
+		t.Errorf("Expected C declared with 4 states, got %d, %v", card, ok)
+	}
+	cpdC, _ := factors.NewTabularCPD("C", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	if err := bn.AddCPD(cpdC); !errors.Is(err, ErrCardinalityMismatch) {
+		t.Errorf("Expected ErrCardinalityMismatch for C, got %v", err)
+	}
+	if card, ok := bn.Copy().DeclaredCardinality("C"); !ok || card != 4 {
+		t.Errorf("Copy lost the declaration of C")
+	}
+}
+
+func TestPredictValidatesEvidence(t *testing.T) {
+	bn := newFourNodeNetwork()
+
+	// An out-of-range parent state would index past the CPD rows
+	_, err := bn.Predict([]map[string]int{{"A": 0, "B": 1, "D": 0}, {"A": 0, "B": 2, "D": 0}})
+	if !errors.Is(err, ErrInvalidState) || !strings.Contains(err.Error(), "observation 1") {
+		t.Errorf("Expected ErrInvalidState for observation 1, got %v", err)
+	}
+	if _, err := bn.Predict([]map[string]int{{"A": 0, "E": 1}}); !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("Expected ErrUnknownVariable, got %v", err)
+	}
+	if _, err := bn.Predict([]map[string]int{{"A": 0, "B": 1, "D": 0}}); err != nil {
+		t.Errorf("Valid observations failed: %v", err)
+	}
+}
+
+func TestPredictJoint(t *testing.T) {
+	bn := newFourNodeNetwork()
+	observations := []map[string]int{{"C": 1}, {"A": 0, "C": 1}, {"C": 1}, {"B": 1, "D": 0}, {"A": 1, "C": 0}}
+
+	predictions, err := bn.PredictJoint(observations)
+	if err != nil {
+		t.Fatalf("PredictJoint failed: %v", err)
+	}
+	for i, obs := range observations {
+		for _, v := range []string{"A", "B", "C", "D"} {
+			want, ok := obs[v]
+			if !ok {
+				posterior, err := bn.posterior(v, obs)
+				if err != nil {
+					t.Fatalf("posterior failed: %v", err)
+				}
+				want = argmax(posterior)
+			}
+			if predictions[v][i] != want {
+				t.Errorf("row %d: %s = %d, want the posterior mode %d", i, v, predictions[v][i], want)
+			}
+		}
+	}
+
+	// Rows whose missing variables lack observed parents agree with Predict
+	marginal, err := bn.Predict(observations)
+	if err != nil {
+		t.Fatalf("Predict failed: %v", err)
+	}
+	for _, v := range []string{"A", "D"} {
+		if !reflect.DeepEqual(marginal[v], predictions[v]) {
+			t.Errorf("Predict gives %s = %v, PredictJoint %v", v, marginal[v], predictions[v])
+		}
+	}
+
+	if _, err := bn.PredictJoint([]map[string]int{{"A": 0, "B": 2}}); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState, got %v", err)
+	}
+}
+
+func TestEnsemble(t *testing.T) {
+	first := newFourNodeNetwork()
+	second := newFourNodeNetwork()
+	cpdA, _ := factors.NewTabularCPD("A", 2, [][]float64{{0.9, 0.1}}, []string{}, map[string]int{})
+	if err := second.AddCPD(cpdA); err != nil {
+		t.Fatalf("AddCPD failed: %v", err)
+	}
+
+	ensemble, err := NewEnsemble([]*BayesianNetwork{first, second}, []float64{1, 3})
+	if err != nil {
+		t.Fatalf("NewEnsemble failed: %v", err)
+	}
+
+	// P(A | B=1) is 0.03:0.42 under the first member and 0.09:0.06 under the second
+	got, err := ensemble.Posterior("A", map[string]int{"B": 1})
+	if err != nil {
+		t.Fatalf("Posterior failed: %v", err)
+	}
+	want := []float64{0.25*0.03/0.45 + 0.75*0.09/0.15, 0.25*0.42/0.45 + 0.75*0.06/0.15}
+	for s := range want {
+		if math.Abs(got[s]-want[s]) > 1e-12 {
+			t.Errorf("P(A=%d | B=1) = %v, want %v", s, got[s], want[s])
+		}
+	}
+
+	predictions, err := ensemble.Predict([]map[string]int{{"B": 1, "C": 0, "D": 0}, {"A": 0, "B": 1, "C": 1}})
+	if err != nil {
+		t.Fatalf("Predict failed: %v", err)
+	}
+	if predictions["A"][0] != 1 || predictions["A"][1] != 0 {
+		t.Errorf("Unexpected predictions for A: %v", predictions["A"])
+	}
+	if _, ok := predictions["D"]; !ok {
+		t.Error("Expected predictions for D")
+	}
+
+	if _, err := ensemble.Posterior("A", map[string]int{"B": 2}); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState, got %v", err)
+	}
+
+	third := newFourNodeNetwork()
+	third.RemoveNode("D")
+	if _, err := NewEnsemble([]*BayesianNetwork{first, third}, nil); err == nil {
+		t.Error("Expected error for members with different variables")
+	}
+}
+
+func TestHiddenNodeEM(t *testing.T) {
+	edges := [][2]string{{"H", "X1"}, {"H", "X2"}, {"H", "X3"}}
+	truth, _ := NewBayesianNetwork(edges)
+	cpdH, _ := factors.NewTabularCPD("H", 2, [][]float64{{0.4, 0.6}}, []string{}, map[string]int{})
+	_ = truth.AddCPD(cpdH)
+	for _, x := range []string{"X1", "X2", "X3"} {
+		cpd, _ := factors.NewTabularCPD(x, 2, [][]float64{{0.9, 0.1}, {0.15, 0.85}},
+			[]string{"H"}, map[string]int{"H": 2})
+		_ = truth.AddCPD(cpd)
+	}
+	data, err := truth.Simulate(3000, 1)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+
+	bn, _ := NewBayesianNetwork(edges)
+	if err := bn.SetHidden("H", 2); err != nil {
+		t.Fatalf("SetHidden failed: %v", err)
+	}
+	if !bn.IsHidden("H") || len(bn.HiddenNodes()) != 1 {
+		t.Fatalf("Expected H hidden, got %v", bn.HiddenNodes())
+	}
+	result, err := bn.FitEM(data, EMOptions{Seed: 3})
+	if err != nil {
+		t.Fatalf("FitEM failed: %v", err)
+	}
+	if !result.Converged || result.Iterations == 0 {
+		t.Errorf("Expected convergence, got %+v", result)
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("Fitted model is invalid: %v", err)
+	}
+
+	// The true H values are ignored, so Predict recovers H from X alone;
+	// the learned states may be swapped
+	predictions, err := bn.Predict(data)
+	if err != nil {
+		t.Fatalf("Predict failed: %v", err)
+	}
+	agree := 0
+	for i, row := range data {
+		if predictions["H"][i] == row["H"] {
+			agree++
+		}
+	}
+	if accuracy := float64(max(agree, len(data)-agree)) / float64(len(data)); accuracy < 0.85 {
+		t.Errorf("Expected hidden states recovered, got accuracy %.3f", accuracy)
+	}
+
+	// Fit routes through EM and round-trips the hidden flag
+	refit := bn.Copy()
+	if err := refit.Fit(data); err != nil || !refit.IsHidden("H") {
+		t.Errorf("Fit with hidden node failed: %v", err)
+	}
+	encoded, _ := json.Marshal(bn)
+	var decoded BayesianNetwork
+	if err := json.Unmarshal(encoded, &decoded); err != nil || !decoded.IsHidden("H") {
+		t.Errorf("Hidden flag lost in JSON: %v", err)
+	}
+}
+
+func TestTiedCPDs(t *testing.T) {
+	edges := [][2]string{{"X0", "X1"}, {"X1", "X2"}, {"X2", "X3"}}
+	truth, _ := NewBayesianNetwork(edges)
+	prior, _ := factors.NewTabularCPD("X0", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	_ = truth.AddCPD(prior)
+	for i := 1; i <= 3; i++ {
+		child, parent := fmt.Sprintf("X%d", i), fmt.Sprintf("X%d", i-1)
+		cpd, _ := factors.NewTabularCPD(child, 2, [][]float64{{0.8, 0.2}, {0.3, 0.7}},
+			[]string{parent}, map[string]int{parent: 2})
+		_ = truth.AddCPD(cpd)
+	}
+	data, _ := truth.Simulate(50, 2)
+
+	bn, _ := NewBayesianNetwork(edges)
+	if err := bn.TieCPDs([]string{"X1", "X2", "X3"}, nil); err != nil {
+		t.Fatalf("TieCPDs failed: %v", err)
+	}
+	if err := bn.TieCPDs([]string{"X0", "X1"}, nil); err == nil {
+		t.Error("Expected an error tying a root with a child")
+	}
+	if err := bn.Fit(data); err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("Tied model is invalid: %v", err)
+	}
+
+	// One table from the pooled transition counts, Laplace-smoothed once
+	counts := [2][2]float64{}
+	for _, row := range data {
+		for i := 1; i <= 3; i++ {
+			counts[row[fmt.Sprintf("X%d", i-1)]][row[fmt.Sprintf("X%d", i)]]++
+		}
+	}
+	for _, node := range []string{"X1", "X2", "X3"} {
+		cpd, _ := bn.GetCPD(node)
+		for r := 0; r < 2; r++ {
+			want := (counts[r][1] + 1) / (counts[r][0] + counts[r][1] + 2)
+			if math.Abs(cpd.Values[r][1]-want) > 1e-12 {
+				t.Errorf("%s row %d: expected %.4f, got %.4f", node, r, want, cpd.Values[r][1])
+			}
+		}
+	}
+
+	changed, _ := factors.NewTabularCPD("X3", 2, [][]float64{{0.5, 0.5}, {0.5, 0.5}},
+		[]string{"X2"}, map[string]int{"X2": 2})
+	_ = bn.AddCPD(changed)
+	var verr *ValidationError
+	if err := bn.CheckModel(); !errors.As(err, &verr) || !verr.Has(IssueTiedCPD) {
+		t.Errorf("Expected a tied-mismatch issue, got %v", err)
+	}
+
+	_ = bn.RemoveEdge("X2", "X3")
+	if groups := bn.TiedGroups(); len(groups) != 1 || len(groups[0].Nodes) != 2 {
+		t.Errorf("Expected X3 untied, got %v", groups)
+	}
+}
+
+func TestShrinkage(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"A", "B"}})
+	var data []map[string]int
+	for i := 0; i < 100; i++ {
+		data = append(data, map[string]int{"A": i % 2, "B": i % 3 % 2})
+	}
+	data = append(data, map[string]int{"A": 2, "B": 1})
+
+	if err := bn.SetShrinkage(-1); err == nil {
+		t.Error("Expected an error for negative strength")
+	}
+	if err := bn.SetShrinkage(20); err != nil || bn.Shrinkage() != 20 {
+		t.Fatalf("SetShrinkage failed: %v", err)
+	}
+	if err := bn.Fit(data); err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	cpd, _ := bn.GetCPD("B")
+
+	ones := 0.0
+	for _, row := range data {
+		ones += float64(row["B"])
+	}
+	marginal := (ones + 1) / (float64(len(data)) + 2)
+	if want := (1 + 20*marginal) / 21; math.Abs(cpd.Values[2][1]-want) > 1e-12 {
+		t.Errorf("Sparse row: expected %.4f, got %.4f", want, cpd.Values[2][1])
+	}
+	for r := 0; r < 3; r++ {
+		if sum := cpd.Values[r][0] + cpd.Values[r][1]; math.Abs(sum-1) > 1e-12 {
+			t.Errorf("Row %d sums to %f", r, sum)
+		}
+	}
+	if bn.Copy().Shrinkage() != 20 {
+		t.Error("Copy lost the shrinkage strength")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := NewSchema(
+		VariableSpec{Name: "Weather", Type: Discrete, States: []string{"sun", "rain", "snow"}},
+		VariableSpec{Name: "Temp", Type: Continuous, Unit: "°C", Min: -50, Max: 60},
+		VariableSpec{Name: "Note", Type: Discrete, Cardinality: 2},
+	)
+	if err != nil {
+		t.Fatalf("NewSchema failed: %v", err)
+	}
+	if _, err := NewSchema(VariableSpec{Name: "A", Type: Discrete, Cardinality: 3, States: []string{"x"}}); !errors.Is(err, ErrCardinalityMismatch) {
+		t.Errorf("Expected ErrCardinalityMismatch for mismatched labels, got %v", err)
+	}
+	if _, err := NewBayesianNetworkWithSchema([][2]string{{"Weather", "Wind"}}, schema); !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("Expected ErrUnknownVariable for an undeclared node, got %v", err)
+	}
+
+	bn, err := NewBayesianNetworkWithSchema([][2]string{{"Weather", "Temp"}}, schema)
+	if err != nil {
+		t.Fatalf("NewBayesianNetworkWithSchema failed: %v", err)
+	}
+	if !bn.DAG.HasNode("Note") || !bn.IsDiscrete("Note") {
+		t.Error("Expected Note as an isolated discrete node")
+	}
+
+	// Snow never occurs, yet keeps its state
+	rng := rand.New(rand.NewSource(1))
+	var samples []Sample
+	for i := 0; i < 300; i++ {
+		w := rng.Intn(2)
+		samples = append(samples, Sample{
+			Discrete:   map[string]int{"Weather": w, "Note": rng.Intn(2)},
+			Continuous: map[string]float64{"Temp": 20 - 10*float64(w) + rng.NormFloat64()},
+		})
+	}
+	if err := bn.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	if card := bn.CPDs["Weather"].VariableCard; card != 3 {
+		t.Errorf("Expected 3 Weather states, got %d", card)
+	}
+	if names := bn.StateNames("Weather"); !reflect.DeepEqual(names, []string{"sun", "rain", "snow"}) {
+		t.Errorf("Expected state names from the schema, got %v", names)
+	}
+
+	bad := append(samples[:10:10], Sample{Continuous: map[string]float64{"Temp": 80}})
+	if err := bn.FitMixed(bad); err == nil || !strings.Contains(err.Error(), "sample 10") {
+		t.Errorf("Expected an out-of-bounds error for sample 10, got %v", err)
+	}
+	bad[10] = Sample{Discrete: map[string]int{"Weather": 3}}
+	if err := bn.FitMixed(bad); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState for an undeclared state, got %v", err)
+	}
+
+	data, err := json.Marshal(bn)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded BayesianNetwork
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if v, ok := decoded.Schema().Variable("Temp"); !ok || v.Unit != "°C" || v.Max != 60 {
+		t.Errorf("Expected the schema to survive JSON, got %+v", v)
+	}
+	if card, _ := decoded.DeclaredCardinality("Weather"); card != 3 {
+		t.Errorf("Expected Weather declared with 3 states after decoding, got %d", card)
+	}
+}
+
+func TestMissingStrategy(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"A", "B"}, {"C", "B"}})
+	if err := bn.SetMissingStrategy("drop"); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+
+	// B copies A; C is missing from every third row and never takes state 2
+	rng := rand.New(rand.NewSource(5))
+	var data []Sample
+	for i := 0; i < 300; i++ {
+		a := rng.Intn(2)
+		row := map[string]int{"A": a, "B": a}
+		if i%3 != 0 {
+			row["C"] = rng.Intn(2)
+		}
+		data = append(data, Sample{Discrete: row})
+	}
+	bn.DeclareCardinality("C", 3)
+
+	report, err := bn.FitWithReport(data)
+	if err != nil {
+		t.Fatalf("FitWithReport failed: %v", err)
+	}
+	if report.Strategy != MissingAvailableCase || report.CompleteRows != 200 || report.DroppedRows != 0 {
+		t.Errorf("Expected 200 complete rows and none dropped, got %+v", report)
+	}
+	if report.FamilyRows["A"] != 300 || report.FamilyRows["B"] != 200 {
+		t.Errorf("Expected 300 rows for A and 200 for B, got %v", report.FamilyRows)
+	}
+	if report.EmptyConfigurations["B"] != 2 {
+		t.Errorf("Expected the 2 configurations with C = 2 empty, got %d", report.EmptyConfigurations["B"])
+	}
+
+	bn.SetMissingStrategy(MissingListwise)
+	report, err = bn.FitWithReport(data)
+	if err != nil {
+		t.Fatalf("FitWithReport failed: %v", err)
+	}
+	if report.DroppedRows != 100 || report.FamilyRows["A"] != 200 {
+		t.Errorf("Expected 100 rows dropped and 200 left for A, got %+v", report)
+	}
+
+	bn.SetMissingStrategy(MissingEM)
+	report, err = bn.FitWithReport(data)
+	if err != nil {
+		t.Fatalf("FitWithReport failed: %v", err)
+	}
+	if report.EM == nil || !report.EM.Converged {
+		t.Errorf("Expected EM to converge, got %+v", report.EM)
+	}
+	cpd, _ := bn.GetCPD("B")
+	if p, _ := cpd.GetValue(1, map[string]int{"A": 1, "C": 0}); p < 0.95 {
+		t.Errorf("Expected P(B=1 | A=1, C=0) near 1, got %.3f", p)
+	}
+}
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+	"gonum.org/v1/gonum/mathext"
+)
+
+// betaIterations bounds the alternating scoring steps of a Beta regression
+const betaIterations = 100
+
+// SetBeta makes FitMixed and FitWeighted learn a Beta CPD for node, with its
+// mean logit-linear in the continuous parents and a fitted precision, for
+// proportions such as conversion rates that must stay inside (0, 1). The
+// node's values must lie strictly between zero and one and its parents be
+// continuous when it is fitted.
+func (bn *BayesianNetwork) SetBeta(node string) error {
+	if !bn.DAG.HasNode(node) {
+		return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+	}
+	if bn.IsDiscrete(node) {
+		return fmt.Errorf("cannot fit a Beta CPD for discrete node %s", node)
+	}
+	if bn.beta == nil {
+		bn.beta = make(map[string]bool)
+	}
+	bn.beta[node] = true
+	return nil
+}
+
+// learnBetaCPD fits logit μ = β₀ + Σᵢ βᵢYᵢ and the precision by maximum
+// likelihood to the rows that hold the variable and all its parents
+func (bn *BayesianNetwork) learnBetaCPD(variable string, data []Sample, weights []float64) (*factors.BetaCPD, error) {
+	parents := bn.DAG.Parents(variable)
+	sort.Strings(parents)
+	for _, p := range parents {
+		if bn.IsDiscrete(p) {
+			return nil, fmt.Errorf("Beta CPD for %s: parent %s is discrete", variable, p)
+		}
+	}
+
+	var values, rowWeights []float64
+	var rows [][]float64 // Each row is [1, y1, y2, ..., yn]
+	for i, sample := range data {
+		x, ok := sample.Continuous[variable]
+		if !ok {
+			continue
+		}
+		if x <= 0 || x >= 1 {
+			return nil, fmt.Errorf("sample %d: %s has value %v, expected a value strictly between 0 and 1", i, variable, x)
+		}
+		row := []float64{1}
+		valid := true
+		for _, p := range parents {
+			v, ok := sample.Continuous[p]
+			if !ok {
+				valid = false
+				break
+			}
+			row = append(row, v)
+		}
+		if valid {
+			values = append(values, x)
+			rows = append(rows, row)
+			rowWeights = append(rowWeights, sampleWeight(weights, i))
+		}
+	}
+	if len(values) < len(parents)+2 {
+		return nil, fmt.Errorf("insufficient data for learning Beta CPD for %s", variable)
+	}
+
+	coeffs, precision, err := bn.fitBetaRegression(rows, values, rowWeights)
+	if err != nil {
+		return nil, fmt.Errorf("Beta CPD for %s: %w", variable, err)
+	}
+	parentCoeffs := make(map[string]float64, len(parents))
+	for i, p := range parents {
+		parentCoeffs[p] = coeffs[i+1]
+	}
+	return factors.NewBetaCPD(variable, parents, coeffs[0], parentCoeffs, precision)
+}
+
+// fitBetaRegression maximizes the weighted Beta log-likelihood of
+// logit μ = Yβ with precision φ, alternating a Fisher scoring step for β,
+// as weighted least squares on a working response, with a Newton step for
+// log φ, each halved until the likelihood does not fall. It starts from
+// least squares on logit x and the moment estimate of φ.
+func (bn *BayesianNetwork) fitBetaRegression(Y [][]float64, X []float64, W []float64) ([]float64, float64, error) {
+	n := len(X)
+	logits := make([]float64, n)
+	logX := make([]float64, n)
+	log1mX := make([]float64, n)
+	total, mean := 0.0, 0.0
+	for i, x := range X {
+		logX[i], log1mX[i] = math.Log(x), math.Log1p(-x)
+		logits[i] = logX[i] - log1mX[i]
+		total += W[i]
+		mean += W[i] * x
+	}
+	mean /= total
+	variance := 0.0
+	for i, x := range X {
+		variance += W[i] * (x - mean) * (x - mean)
+	}
+	variance /= total
+
+	beta, _, err := fitGaussianRegression(Y, logits, W, bn.ridge)
+	if err != nil {
+		return nil, 0, err
+	}
+	phi := 1.0
+	if variance > 0 {
+		phi = math.Max(mean*(1-mean)/variance-1, 1)
+	}
+
+	mu := make([]float64, n)
+	eta := make([]float64, n)
+	predict := func(b []float64) {
+		for i, row := range Y {
+			eta[i] = 0
+			for j, v := range b {
+				eta[i] += v * row[j]
+			}
+			mu[i] = 1 / (1 + math.Exp(-eta[i]))
+		}
+	}
+	logLikelihood := func(phi float64) float64 {
+		ll := 0.0
+		lgPhi, _ := math.Lgamma(phi)
+		for i := range X {
+			lgA, _ := math.Lgamma(mu[i] * phi)
+			lgB, _ := math.Lgamma((1 - mu[i]) * phi)
+			ll += W[i] * (lgPhi - lgA - lgB + (mu[i]*phi-1)*logX[i] + ((1-mu[i])*phi-1)*log1mX[i])
+		}
+		return ll
+	}
+
+	working := make([]float64, n)
+	scoring := make([]float64, n)
+	predict(beta)
+	current := logLikelihood(phi)
+	for iter := 0; iter < betaIterations; iter++ {
+		previous := current
+
+		// Fisher scoring for β: regress η + (x* - μ*)/(φ v μ(1-μ)) on Y with
+		// weights φ v (μ(1-μ))², where x* = logit x, μ* = ψ(μφ) - ψ((1-μ)φ)
+		// and v = ψ'(μφ) + ψ'((1-μ)φ)
+		for i := range X {
+			a, b := mu[i]*phi, (1-mu[i])*phi
+			v := trigamma(a) + trigamma(b)
+			d := mu[i] * (1 - mu[i])
+			working[i] = eta[i] + (logits[i]-mathext.Digamma(a)+mathext.Digamma(b))/(phi*v*d)
+			scoring[i] = W[i] * phi * v * d * d
+		}
+		next, _, err := fitGaussianRegression(Y, working, scoring, bn.ridge)
+		if err != nil {
+			return nil, 0, err
+		}
+		step := make([]float64, len(beta))
+		for j := range step {
+			step[j] = next[j] - beta[j]
+		}
+		improved := false
+		for k := 0; k < 30 && !improved; k++ {
+			candidate := make([]float64, len(beta))
+			for j := range candidate {
+				candidate[j] = beta[j] + step[j]
+			}
+			predict(candidate)
+			if ll := logLikelihood(phi); ll >= current {
+				beta, current, improved = candidate, ll, true
+			}
+			for j := range step {
+				step[j] /= 2
+			}
+		}
+		if !improved {
+			predict(beta)
+		}
+
+		// Newton for log φ, falling back to a gradient step where the
+		// likelihood is not concave
+		grad, hess := 0.0, 0.0
+		for i := range X {
+			a, b := mu[i]*phi, (1-mu[i])*phi
+			d1 := mathext.Digamma(phi) - mu[i]*mathext.Digamma(a) - (1-mu[i])*mathext.Digamma(b) + mu[i]*logX[i] + (1-mu[i])*log1mX[i]
+			d2 := trigamma(phi) - mu[i]*mu[i]*trigamma(a) - (1-mu[i])*(1-mu[i])*trigamma(b)
+			grad += W[i] * phi * d1
+			hess += W[i] * (phi*phi*d2 + phi*d1)
+		}
+		logStep := math.Copysign(1, grad)
+		if hess < 0 {
+			logStep = -grad / hess
+		}
+		for k := 0; k < 30; k++ {
+			candidate := phi * math.Exp(logStep)
+			if ll := logLikelihood(candidate); ll >= current {
+				phi, current = candidate, ll
+				break
+			}
+			logStep /= 2
+		}
+
+		if current-previous < 1e-10*math.Abs(current) {
+			break
+		}
+	}
+	return beta, phi, nil
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// BootstrapOptions configures BootstrapParameters
+type BootstrapOptions struct {
+	Replicates int     // Bootstrap resamples refitted, 200 if zero
+	Level      float64 // Confidence level of the intervals, 0.95 if zero
+	Seed       int64
+}
+
+// ParameterInterval is the bootstrap confidence interval of one CPD
+// parameter, named by its column and parent configuration in the node's
+// CPDTable
+type ParameterInterval struct {
+	Node      string
+	Parameter string // The CPDTable column, such as "P(E=yes)" or "coef(X)"
+	Condition string // The discrete parent states of its row, such as "D=off,F=1", or "" without discrete parents
+	Estimate  float64
+	Lower     float64
+	Upper     float64
+	StdErr    float64 // Standard deviation over the replicates
+}
+
+// BootstrapParameters fits a copy of the network to data and to bootstrap
+// resamples of it, and returns a percentile confidence interval for every
+// CPT entry and every Gaussian coefficient and variance, node by node in
+// CPDTable order. Estimate is the parameter fitted to all of data; wide
+// intervals flag numbers the data barely determines. The network itself is
+// not changed. Nodes whose CPDs FitMixed does not learn are left out.
+func (bn *BayesianNetwork) BootstrapParameters(data []Sample, opts BootstrapOptions) ([]ParameterInterval, error) {
+	replicates, level := opts.Replicates, opts.Level
+	if replicates == 0 {
+		replicates = 200
+	}
+	if level == 0 {
+		level = 0.95
+	}
+	if replicates < 2 {
+		return nil, fmt.Errorf("bootstrap needs at least 2 replicates, got %d", replicates)
+	}
+	if level <= 0 || level >= 1 {
+		return nil, fmt.Errorf("confidence level must be in (0, 1), got %f", level)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no data to bootstrap")
+	}
+
+	fitted := bn.Copy()
+	if err := fitted.FitMixed(data); err != nil {
+		return nil, err
+	}
+	var nodes []string
+	for _, node := range fitted.Nodes() {
+		if _, ok := fitted.CPDs[node]; ok {
+			nodes = append(nodes, node)
+		} else if _, ok := fitted.GaussianCPDs[node]; ok {
+			nodes = append(nodes, node)
+		}
+	}
+	result, err := fitted.parameterEstimates(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replicates keep the full-data cardinalities even when a resample misses
+	// the highest state of a variable
+	template := fitted.Copy()
+	for _, node := range template.Nodes() {
+		if _, ok := template.DeclaredCardinality(node); !ok && template.IsDiscrete(node) {
+			if err := template.DeclareCardinality(node, template.Cardinality[node]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	draws := make([][]float64, len(result))
+	r := rand.New(rand.NewSource(opts.Seed))
+	resample := make([]Sample, len(data))
+	for b := 0; b < replicates; b++ {
+		for i := range resample {
+			resample[i] = data[r.Intn(len(data))]
+		}
+		replicate := template.Copy()
+		if err := replicate.FitMixed(resample); err != nil {
+			return nil, fmt.Errorf("bootstrap replicate %d: %w", b, err)
+		}
+		values, err := replicate.parameterEstimates(nodes)
+		if err != nil {
+			return nil, fmt.Errorf("bootstrap replicate %d: %w", b, err)
+		}
+		if len(values) != len(result) {
+			return nil, fmt.Errorf("bootstrap replicate %d has %d parameters, expected %d", b, len(values), len(result))
+		}
+		for k, v := range values {
+			draws[k] = append(draws[k], v.Estimate)
+		}
+	}
+
+	tail := (1 - level) / 2
+	for k := range result {
+		sort.Float64s(draws[k])
+		result[k].Lower = stat.Quantile(tail, stat.LinInterp, draws[k], nil)
+		result[k].Upper = stat.Quantile(1-tail, stat.LinInterp, draws[k], nil)
+		result[k].StdErr = math.Sqrt(stat.Variance(draws[k], nil))
+	}
+	return result, nil
+}
+
+// parameterEstimates lists the parameters of the nodes' CPD tables, with
+// intervals unset
+func (bn *BayesianNetwork) parameterEstimates(nodes []string) ([]ParameterInterval, error) {
+	var result []ParameterInterval
+	for _, node := range nodes {
+		table, err := bn.CPDTable(node)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range table.Rows {
+			var condition []string
+			for k, cell := range row {
+				value, ok := cell.(float64)
+				if !ok {
+					condition = append(condition, table.Columns[k]+"="+cell.(string))
+					continue
+				}
+				result = append(result, ParameterInterval{
+					Node:      node,
+					Parameter: table.Columns[k],
+					Condition: strings.Join(condition, ","),
+					Estimate:  value,
+				})
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package models
+
+import "fmt"
+
+// DeclareCardinality fixes the number of states of a discrete variable.
+// CPDs added later must agree with it, as must the CPDs already registered.
+func (bn *BayesianNetwork) DeclareCardinality(variable string, cardinality int) error {
+	if !bn.DAG.HasNode(variable) {
+		return fmt.Errorf("variable %s: %w", variable, ErrUnknownVariable)
+	}
+	if bn.IsContinuous(variable) {
+		return fmt.Errorf("cannot declare a cardinality for continuous variable %s", variable)
+	}
+	if cardinality < 1 {
+		return fmt.Errorf("variable %s needs a positive cardinality, got %d: %w",
+			variable, cardinality, ErrCardinalityMismatch)
+	}
+	if err := bn.checkCardinality(variable, cardinality, ""); err != nil {
+		return err
+	}
+
+	if bn.declared == nil {
+		bn.declared = make(map[string]int)
+	}
+	bn.declared[variable] = cardinality
+	bn.Cardinality[variable] = cardinality
+	bn.VariableType[variable] = Discrete
+	return nil
+}
+
+// DeclaredCardinality returns the cardinality fixed by DeclareCardinality or
+// AddNode, if any
+func (bn *BayesianNetwork) DeclaredCardinality(variable string) (int, bool) {
+	card, ok := bn.declared[variable]
+	return card, ok
+}
+
+// fitCardinality returns the cardinality to fit for variable given the
+// number of states seen in data: its declared cardinality, which the data
+// may not exceed, or else the states seen
+func (bn *BayesianNetwork) fitCardinality(variable string, observed int) (int, error) {
+	declared, ok := bn.declared[variable]
+	if !ok {
+		return observed, nil
+	}
+	if observed > declared {
+		return 0, fmt.Errorf("%s has state %d in the data, but %d states are declared: %w",
+			variable, observed-1, declared, ErrInvalidState)
+	}
+	return declared, nil
+}
+
+// checkCardinality reports whether giving variable the cardinality conflicts
+// with its declaration, its own CPD or the CPDs of its children. The CPD of
+// replacing is about to be replaced and is not consulted.
+func (bn *BayesianNetwork) checkCardinality(variable string, cardinality int, replacing string) error {
+	if declared, ok := bn.declared[variable]; ok && declared != cardinality {
+		return fmt.Errorf("%s has %d states, but %d are declared: %w",
+			variable, cardinality, declared, ErrCardinalityMismatch)
+	}
+
+	if variable != replacing {
+		if cpd, ok := bn.DiscreteCPD(variable); ok && cpd.GetCardinality() != cardinality {
+			return fmt.Errorf("%s has %d states, but its CPD has %d: %w",
+				variable, cardinality, cpd.GetCardinality(), ErrCardinalityMismatch)
+		}
+	}
+
+	for _, child := range bn.DAG.Children(variable) {
+		if child == replacing {
+			continue
+		}
+		if card, ok := bn.parentCardinality(child, variable); ok && card != cardinality {
+			return fmt.Errorf("%s has %d states, but the CPD of %s gives it %d: %w",
+				variable, cardinality, child, card, ErrCardinalityMismatch)
+		}
+	}
+	return nil
+}
+
+// parentCardinality returns the cardinality the CPD of child assumes for a
+// discrete parent
+func (bn *BayesianNetwork) parentCardinality(child, parent string) (int, bool) {
+	if cpd, ok := bn.CPDs[child]; ok {
+		card, ok := cpd.EvidenceCard[parent]
+		return card, ok
+	}
+	if cpd, ok := bn.CustomCPDs[child]; ok {
+		factor, err := cpd.ToFactor()
+		if err != nil {
+			return 0, false
+		}
+		card, ok := factor.Cardinality[parent]
+		return card, ok
+	}
+	if cpd, ok := bn.GaussianCPDs[child]; ok {
+		card, ok := cpd.Cardinality[parent]
+		return card, ok
+	}
+	return 0, false
+}
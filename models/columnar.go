@@ -0,0 +1,321 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// SampleColumns holds samples column by column: one slice per variable,
+// each of length Len(), with row i of every column forming sample i
+type SampleColumns struct {
+	Discrete   map[string][]int
+	Continuous map[string][]float64
+	n          int
+}
+
+// Len returns the number of samples
+func (c *SampleColumns) Len() int {
+	return c.n
+}
+
+// Row assembles sample i from the columns
+func (c *SampleColumns) Row(i int) Sample {
+	sample := Sample{
+		Discrete:   make(map[string]int, len(c.Discrete)),
+		Continuous: make(map[string]float64, len(c.Continuous)),
+	}
+	for v, col := range c.Discrete {
+		sample.Discrete[v] = col[i]
+	}
+	for v, col := range c.Continuous {
+		sample.Continuous[v] = col[i]
+	}
+	return sample
+}
+
+// Samples converts the columns to row-oriented samples
+func (c *SampleColumns) Samples() []Sample {
+	samples := make([]Sample, c.n)
+	for i := range samples {
+		samples[i] = c.Row(i)
+	}
+	return samples
+}
+
+// SimulateColumns draws n samples as SimulateMixed does, writing them straight
+// into one column per variable. It consumes the generator in the same order,
+// so the samples match SimulateMixed(n, seed), but it allocates no map per
+// sample.
+func (bn *BayesianNetwork) SimulateColumns(n int, seed int64) (*SampleColumns, error) {
+	return bn.SimulateColumnsWithRand(n, rand.New(rand.NewSource(seed)))
+}
+
+// SimulateColumnsWithRand is SimulateColumns drawing from a caller-owned
+// generator
+func (bn *BayesianNetwork) SimulateColumnsWithRand(n int, r *rand.Rand) (*SampleColumns, error) {
+	if err := bn.CheckModel(); err != nil {
+		return nil, err
+	}
+	order, err := bn.DAG.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+
+	cols, samplers := bn.columnSamplers(order, n, r)
+	for i := 0; i < n; i++ {
+		for _, s := range samplers {
+			if err := s(i); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return cols, nil
+}
+
+// columnSamplers allocates n rows of columns for the nodes in topological
+// order and returns them with each node's sampler, in the same order
+func (bn *BayesianNetwork) columnSamplers(order []string, n int, r *rand.Rand) (*SampleColumns, []func(i int) error) {
+	cols := &SampleColumns{
+		Discrete:   make(map[string][]int),
+		Continuous: make(map[string][]float64),
+		n:          n,
+	}
+	for _, node := range order {
+		if bn.IsDiscrete(node) {
+			cols.Discrete[node] = make([]int, n)
+		} else {
+			cols.Continuous[node] = make([]float64, n)
+		}
+	}
+
+	samplers := make([]func(i int) error, len(order))
+	for k, node := range order {
+		samplers[k] = bn.columnSampler(node, cols, r)
+	}
+	return cols, samplers
+}
+
+// rowSampler draws samples one at a time into a single scratch row whose
+// parent lookups and strides are resolved once, so a draw allocates nothing
+// per node. Each sample is then copied out into maps of exact size.
+type rowSampler struct {
+	samplers   []func(i int) error
+	discrete   []string
+	continuous []string
+	states     [][]int     // Scratch row of each discrete node
+	values     [][]float64 // Scratch row of each continuous node
+}
+
+func (bn *BayesianNetwork) newRowSampler(order []string, r *rand.Rand) *rowSampler {
+	row, samplers := bn.columnSamplers(order, 1, r)
+	s := &rowSampler{samplers: samplers}
+	for _, node := range order {
+		if col, ok := row.Discrete[node]; ok {
+			s.discrete = append(s.discrete, node)
+			s.states = append(s.states, col)
+		} else {
+			s.continuous = append(s.continuous, node)
+			s.values = append(s.values, row.Continuous[node])
+		}
+	}
+	return s
+}
+
+// draw samples every node into the scratch row
+func (s *rowSampler) draw() error {
+	for _, sampler := range s.samplers {
+		if err := sampler(0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discreteSample copies the discrete nodes of the scratch row into a map
+func (s *rowSampler) discreteSample() map[string]int {
+	sample := make(map[string]int, len(s.discrete))
+	for k, node := range s.discrete {
+		sample[node] = s.states[k][0]
+	}
+	return sample
+}
+
+// sample copies the scratch row into a Sample
+func (s *rowSampler) sample() Sample {
+	sample := Sample{Discrete: s.discreteSample(), Continuous: make(map[string]float64, len(s.continuous))}
+	for k, node := range s.continuous {
+		sample.Continuous[node] = s.values[k][0]
+	}
+	return sample
+}
+
+// columnSampler returns a function that draws row i of a node's column from
+// the rows already drawn for its parents. Parent columns and row strides are
+// resolved once here rather than per sample.
+func (bn *BayesianNetwork) columnSampler(node string, cols *SampleColumns, r *rand.Rand) func(i int) error {
+	if bn.IsDiscrete(node) {
+		out := cols.Discrete[node]
+
+		if hybrid, ok := bn.HybridCPDs[node]; ok {
+			parents := hybrid.GetParents()
+			parentValues := make(map[string]interface{}, len(parents))
+			return func(i int) error {
+				for _, p := range parents {
+					if col, ok := cols.Discrete[p]; ok {
+						parentValues[p] = col[i]
+					} else {
+						parentValues[p] = cols.Continuous[p][i]
+					}
+				}
+				val, err := hybrid.Sample(parentValues, r)
+				if err != nil {
+					return fmt.Errorf("failed to sample %s: %w", node, err)
+				}
+				out[i] = val
+				return nil
+			}
+		}
+
+		cpd, ok := bn.CPDs[node]
+		if !ok {
+			custom := bn.CustomCPDs[node]
+			parents := custom.GetParents()
+			parentValues := make(map[string]int, len(parents))
+			return func(i int) error {
+				for _, p := range parents {
+					parentValues[p] = cols.Discrete[p][i]
+				}
+				val, err := custom.Sample(parentValues, r)
+				if err != nil {
+					return fmt.Errorf("failed to sample %s: %w", node, err)
+				}
+				out[i] = val
+				return nil
+			}
+		}
+
+		// The last evidence variable varies fastest
+		evidence := make([][]int, len(cpd.Evidence))
+		strides := make([]int, len(cpd.Evidence))
+		stride := 1
+		for j := len(cpd.Evidence) - 1; j >= 0; j-- {
+			evidence[j] = cols.Discrete[cpd.Evidence[j]]
+			strides[j] = stride
+			stride *= cpd.EvidenceCard[cpd.Evidence[j]]
+		}
+		return func(i int) error {
+			row := 0
+			for j, col := range evidence {
+				row += col[i] * strides[j]
+			}
+			out[i] = sampleCategorical(cpd.Values[row], r)
+			return nil
+		}
+	}
+
+	out := cols.Continuous[node]
+	// Custom CPDs, and Gaussian CPDs whose basis terms make the mean
+	// nonlinear, sample row by row
+	var generic factors.ContinuousCPD
+	if custom, ok := bn.ContinuousCPDs[node]; ok {
+		generic = custom
+	} else if cpd := bn.GaussianCPDs[node]; len(cpd.Basis) > 0 {
+		generic = cpd
+	}
+	if generic != nil {
+		parents := generic.GetParents()
+		parentValues := make(map[string]interface{}, len(parents))
+		return func(i int) error {
+			for _, p := range parents {
+				if col, ok := cols.Discrete[p]; ok {
+					parentValues[p] = col[i]
+				} else {
+					parentValues[p] = cols.Continuous[p][i]
+				}
+			}
+			val, err := generic.Sample(parentValues, r)
+			if err != nil {
+				return fmt.Errorf("failed to sample %s: %w", node, err)
+			}
+			out[i] = val
+			return nil
+		}
+	}
+
+	cpd := bn.GaussianCPDs[node]
+	continuous := cpd.ContinuousParents()
+	parentCols := make([][]float64, len(continuous))
+	for j, p := range continuous {
+		parentCols[j] = cols.Continuous[p]
+	}
+
+	// One parameter set per discrete configuration, indexed like CPD rows
+	type params struct {
+		mean   float64
+		stdDev float64
+		coeffs []float64
+		ok     bool
+	}
+	discrete := cpd.DiscreteParents()
+	var table []params
+	if len(discrete) == 0 {
+		p := params{mean: cpd.Intercept, stdDev: math.Sqrt(cpd.Variance), coeffs: make([]float64, len(continuous)), ok: true}
+		for j, parent := range continuous {
+			p.coeffs[j] = cpd.Coefficients[parent]
+		}
+		table = []params{p}
+	} else {
+		for _, key := range discreteConfigurations(discrete, cpd.Cardinality) {
+			gp, ok := cpd.DiscreteStates[key]
+			p := params{mean: gp.Mean, stdDev: math.Sqrt(gp.Variance), coeffs: make([]float64, len(continuous)), ok: ok}
+			for j, parent := range continuous {
+				p.coeffs[j] = gp.Coefficients[parent]
+			}
+			table = append(table, p)
+		}
+	}
+
+	// Heteroscedastic CPDs scale the standard deviation by exp(½Σᵢ γᵢyᵢ)
+	logVariance := make([]float64, len(continuous))
+	for j, parent := range continuous {
+		logVariance[j] = cpd.LogVariance[parent]
+	}
+	heteroscedastic := cpd.IsHeteroscedastic()
+
+	discreteCols := make([][]int, len(discrete))
+	strides := make([]int, len(discrete))
+	stride := 1
+	for j := len(discrete) - 1; j >= 0; j-- {
+		discreteCols[j] = cols.Discrete[discrete[j]]
+		strides[j] = stride
+		stride *= cpd.Cardinality[discrete[j]]
+	}
+
+	return func(i int) error {
+		idx := 0
+		for j, col := range discreteCols {
+			idx += col[i] * strides[j]
+		}
+		if idx >= len(table) || !table[idx].ok {
+			return fmt.Errorf("failed to sample %s: no parameters for discrete parent configuration %d", node, idx)
+		}
+		p := table[idx]
+		mean := p.mean
+		for j, col := range parentCols {
+			mean += p.coeffs[j] * col[i]
+		}
+		stdDev := p.stdDev
+		if heteroscedastic {
+			exponent := 0.0
+			for j, col := range parentCols {
+				exponent += logVariance[j] * col[i]
+			}
+			stdDev *= math.Exp(exponent / 2)
+		}
+		out[i] = r.NormFloat64()*stdDev + mean
+		return nil
+	}
+}
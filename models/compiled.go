@@ -0,0 +1,106 @@
+package models
+
+import (
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/graph"
+)
+
+// CompiledModel is a read-only, inference-ready snapshot of a network. Its
+// CPDs are converted to factors once, and a min-fill elimination order is
+// computed up front, so inference engines built on it do no per-query
+// conversion. Nothing in a CompiledModel is modified after Compile returns,
+// which makes it safe to share across goroutines.
+type CompiledModel struct {
+	network          *BayesianNetwork
+	factors          map[string]*factors.DiscreteFactor
+	canonicalFactors map[string]*factors.CanonicalFactor
+	topologicalOrder []string
+	eliminationOrder []string
+}
+
+// Compile validates the network and snapshots it for concurrent inference.
+// Later changes to bn do not affect the compiled model.
+func (bn *BayesianNetwork) Compile() (*CompiledModel, error) {
+	if err := bn.CheckModel(); err != nil {
+		return nil, err
+	}
+
+	network := bn.Copy()
+	order, err := network.DAG.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+
+	cm := &CompiledModel{
+		network:          network,
+		factors:          make(map[string]*factors.DiscreteFactor, len(network.CPDs)),
+		canonicalFactors: make(map[string]*factors.CanonicalFactor),
+		topologicalOrder: order,
+		eliminationOrder: network.DAG.MoralGraph().EliminationOrder(graph.MinFill),
+	}
+
+	for _, node := range order {
+		if !network.IsDiscrete(node) {
+			continue
+		}
+		factor, err := network.NodeFactor(node)
+		if err != nil {
+			return nil, err
+		}
+		factor.Compile() // custom CPDs may return uncompiled factors
+		cm.factors[node] = factor
+	}
+
+	// CPDs with discrete parents have no single canonical form; they are
+	// converted per configuration at query time
+	for node, cpd := range network.GaussianCPDs {
+		if len(cpd.DiscreteParents()) > 0 {
+			continue
+		}
+		factor, err := cpd.ToFactor()
+		if err != nil {
+			return nil, err
+		}
+		cm.canonicalFactors[node] = factor
+	}
+
+	return cm, nil
+}
+
+// Network returns the compiled snapshot of the network. It is shared by
+// every engine built on the compiled model and must not be modified.
+func (cm *CompiledModel) Network() *BayesianNetwork {
+	return cm.network
+}
+
+// Factors returns the precomputed factor of every discrete CPD, keyed by node.
+// The map is a copy but the factors are shared and must not be modified.
+func (cm *CompiledModel) Factors() map[string]*factors.DiscreteFactor {
+	result := make(map[string]*factors.DiscreteFactor, len(cm.factors))
+	for node, f := range cm.factors {
+		result[node] = f
+	}
+	return result
+}
+
+// CanonicalFactors returns the precomputed canonical factor of every
+// continuous CPD without discrete parents. The factors are shared and must
+// not be modified.
+func (cm *CompiledModel) CanonicalFactors() map[string]*factors.CanonicalFactor {
+	result := make(map[string]*factors.CanonicalFactor, len(cm.canonicalFactors))
+	for node, f := range cm.canonicalFactors {
+		result[node] = f
+	}
+	return result
+}
+
+// TopologicalOrder returns the nodes in topological order
+func (cm *CompiledModel) TopologicalOrder() []string {
+	return append([]string(nil), cm.topologicalOrder...)
+}
+
+// EliminationOrder returns a min-fill elimination order over all nodes of
+// the moral graph
+func (cm *CompiledModel) EliminationOrder() []string {
+	return append([]string(nil), cm.eliminationOrder...)
+}
@@ -1,7 +1,13 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/JohnPierman/bngo/factors"
@@ -262,3 +268,1093 @@ func mean(values []float64) float64 {
 	}
 	return sum / float64(len(values))
 }
+
+func TestCLGParameterLearning(t *testing.T) {
+	// D -> Y <- X with a separate regression on X for each state of D
+	edges := [][2]string{
+		{"D", "Y"},
+		{"X", "Y"},
+	}
+
+	bn, err := NewBayesianNetwork(edges)
+	if err != nil {
+		t.Fatalf("Failed to create network: %v", err)
+	}
+
+	cpdD, err := factors.NewTabularCPD("D", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	if err != nil {
+		t.Fatalf("Failed to create CPD for D: %v", err)
+	}
+	if err := bn.AddCPD(cpdD); err != nil {
+		t.Fatalf("Failed to add CPD for D: %v", err)
+	}
+
+	cpdX, err := factors.NewLinearGaussianCPD("X", []string{}, 0.0, map[string]float64{}, 1.0)
+	if err != nil {
+		t.Fatalf("Failed to create CPD for X: %v", err)
+	}
+	if err := bn.AddGaussianCPD(cpdX); err != nil {
+		t.Fatalf("Failed to add CPD for X: %v", err)
+	}
+
+	// Y | D=0 ~ N(1 + 2X, 0.1), Y | D=1 ~ N(-3 - X, 0.2)
+	states := map[string]factors.GaussianParams{
+		"0": {Mean: 1.0, Variance: 0.1, Coefficients: map[string]float64{"X": 2.0}},
+		"1": {Mean: -3.0, Variance: 0.2, Coefficients: map[string]float64{"X": -1.0}},
+	}
+	cpdY, err := factors.NewCLGCPD("Y", []string{"D"}, []string{"X"}, map[string]int{"D": 2}, states)
+	if err != nil {
+		t.Fatalf("Failed to create CLG CPD: %v", err)
+	}
+	if err := bn.AddGaussianCPD(cpdY); err != nil {
+		t.Fatalf("Failed to add CPD for Y: %v", err)
+	}
+
+	samples, err := bn.SimulateMixed(4000, 7)
+	if err != nil {
+		t.Fatalf("Failed to simulate: %v", err)
+	}
+
+	learned, err := NewBayesianNetwork(edges)
+	if err != nil {
+		t.Fatalf("Failed to create network: %v", err)
+	}
+	if err := learned.FitMixed(samples); err != nil {
+		t.Fatalf("Failed to fit model: %v", err)
+	}
+
+	fitted, err := learned.GetGaussianCPD("Y")
+	if err != nil {
+		t.Fatalf("Failed to get CPD for Y: %v", err)
+	}
+	for key, want := range states {
+		got, ok := fitted.DiscreteStates[key]
+		if !ok {
+			t.Fatalf("Missing parameters for D=%s", key)
+		}
+		if math.Abs(got.Mean-want.Mean) > 0.05 {
+			t.Errorf("D=%s: intercept %.4f, expected ~%.1f", key, got.Mean, want.Mean)
+		}
+		if math.Abs(got.Coefficients["X"]-want.Coefficients["X"]) > 0.05 {
+			t.Errorf("D=%s: coefficient %.4f, expected ~%.1f", key, got.Coefficients["X"], want.Coefficients["X"])
+		}
+		if math.Abs(got.Variance-want.Variance) > 0.03 {
+			t.Errorf("D=%s: variance %.4f, expected ~%.1f", key, got.Variance, want.Variance)
+		}
+	}
+
+	condMean, err := fitted.GetMean(map[string]interface{}{"D": 1, "X": 2.0})
+	if err != nil {
+		t.Fatalf("GetMean failed: %v", err)
+	}
+	if math.Abs(condMean-(-5.0)) > 0.1 {
+		t.Errorf("Expected conditional mean ~-5, got %.4f", condMean)
+	}
+}
+
+func TestFitWeighted(t *testing.T) {
+	edges := [][2]string{
+		{"D", "Y"},
+		{"X", "Y"},
+	}
+
+	source, err := NewBayesianNetwork(edges)
+	if err != nil {
+		t.Fatalf("Failed to create network: %v", err)
+	}
+	cpdD, _ := factors.NewTabularCPD("D", 2, [][]float64{{0.3, 0.7}}, []string{}, map[string]int{})
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 1.0, map[string]float64{}, 2.0)
+	states := map[string]factors.GaussianParams{
+		"0": {Mean: 1.0, Variance: 0.5, Coefficients: map[string]float64{"X": 2.0}},
+		"1": {Mean: -1.0, Variance: 0.5, Coefficients: map[string]float64{"X": 0.5}},
+	}
+	cpdY, err := factors.NewCLGCPD("Y", []string{"D"}, []string{"X"}, map[string]int{"D": 2}, states)
+	if err != nil {
+		t.Fatalf("Failed to create CLG CPD: %v", err)
+	}
+	for _, err := range []error{source.AddCPD(cpdD), source.AddGaussianCPD(cpdX), source.AddGaussianCPD(cpdY)} {
+		if err != nil {
+			t.Fatalf("Failed to add CPD: %v", err)
+		}
+	}
+
+	samples, err := source.SimulateMixed(300, 3)
+	if err != nil {
+		t.Fatalf("Failed to simulate: %v", err)
+	}
+
+	// Integer weights must give the same fit as repeating each sample
+	weighted := make([]WeightedSample, len(samples))
+	var repeated []Sample
+	for i, s := range samples {
+		w := i%3 + 1
+		weighted[i] = WeightedSample{Sample: s, Weight: float64(w)}
+		for k := 0; k < w; k++ {
+			repeated = append(repeated, s)
+		}
+	}
+
+	byWeight, _ := NewBayesianNetwork(edges)
+	if err := byWeight.FitWeighted(weighted); err != nil {
+		t.Fatalf("FitWeighted failed: %v", err)
+	}
+	byRepeat, _ := NewBayesianNetwork(edges)
+	if err := byRepeat.FitMixed(repeated); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+
+	for s, p := range byRepeat.CPDs["D"].Values[0] {
+		if got := byWeight.CPDs["D"].Values[0][s]; math.Abs(got-p) > 1e-9 {
+			t.Errorf("P(D=%d) = %.6f, expected %.6f", s, got, p)
+		}
+	}
+	for _, node := range []string{"X", "Y"} {
+		got, want := byWeight.GaussianCPDs[node], byRepeat.GaussianCPDs[node]
+		if math.Abs(got.Intercept-want.Intercept) > 1e-9 || math.Abs(got.Variance-want.Variance) > 1e-9 {
+			t.Errorf("%s: weighted fit (%.6f, %.6f), repeated fit (%.6f, %.6f)",
+				node, got.Intercept, got.Variance, want.Intercept, want.Variance)
+		}
+		for key, params := range want.DiscreteStates {
+			g := got.DiscreteStates[key]
+			if math.Abs(g.Mean-params.Mean) > 1e-9 || math.Abs(g.Variance-params.Variance) > 1e-9 ||
+				math.Abs(g.Coefficients["X"]-params.Coefficients["X"]) > 1e-9 {
+				t.Errorf("%s, D=%s: weighted fit %+v, repeated fit %+v", node, key, g, params)
+			}
+		}
+	}
+
+	marginal, err := WeightedMarginal(weighted, "D", 2)
+	if err != nil {
+		t.Fatalf("WeightedMarginal failed: %v", err)
+	}
+	mu, variance, err := WeightedMean(weighted, "X")
+	if err != nil {
+		t.Fatalf("WeightedMean failed: %v", err)
+	}
+	if math.Abs(marginal[1]-0.7) > 0.1 {
+		t.Errorf("Weighted P(D=1) = %.4f, expected ~0.7", marginal[1])
+	}
+	if math.Abs(mu-1.0) > 0.3 || math.Abs(variance-2.0) > 0.5 {
+		t.Errorf("Weighted X moments (%.4f, %.4f), expected ~(1, 2)", mu, variance)
+	}
+
+	bad := []WeightedSample{{Sample: samples[0], Weight: -1}}
+	if err := byWeight.FitWeighted(bad); err == nil {
+		t.Error("Expected an error for a negative weight")
+	}
+}
+
+func TestSimulateColumns(t *testing.T) {
+	bn, err := NewBayesianNetwork([][2]string{{"D", "Y"}, {"X", "Y"}, {"D", "E"}})
+	if err != nil {
+		t.Fatalf("Failed to create network: %v", err)
+	}
+	cpdD, _ := factors.NewTabularCPD("D", 2, [][]float64{{0.4, 0.6}}, []string{}, map[string]int{})
+	cpdE, _ := factors.NewTabularCPD("E", 3, [][]float64{{0.2, 0.3, 0.5}, {0.6, 0.3, 0.1}},
+		[]string{"D"}, map[string]int{"D": 2})
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 1.0, map[string]float64{}, 2.0)
+	cpdY, err := factors.NewCLGCPD("Y", []string{"D"}, []string{"X"}, map[string]int{"D": 2},
+		map[string]factors.GaussianParams{
+			"0": {Mean: 1.0, Variance: 0.5, Coefficients: map[string]float64{"X": 2.0}},
+			"1": {Mean: -1.0, Variance: 0.1, Coefficients: map[string]float64{"X": 0.5}},
+		})
+	if err != nil {
+		t.Fatalf("Failed to create CLG CPD: %v", err)
+	}
+	for _, err := range []error{bn.AddCPD(cpdD), bn.AddCPD(cpdE), bn.AddGaussianCPD(cpdX), bn.AddGaussianCPD(cpdY)} {
+		if err != nil {
+			t.Fatalf("Failed to add CPD: %v", err)
+		}
+	}
+
+	cols, err := bn.SimulateColumns(500, 5)
+	if err != nil {
+		t.Fatalf("SimulateColumns failed: %v", err)
+	}
+	if cols.Len() != 500 || len(cols.Discrete["E"]) != 500 || len(cols.Continuous["Y"]) != 500 {
+		t.Fatalf("Expected 500 rows in every column")
+	}
+
+	// Same seed, same draws as the row-oriented simulator
+	rows, _ := bn.SimulateMixed(500, 5)
+	for i, want := range rows {
+		got := cols.Row(i)
+		for v, s := range want.Discrete {
+			if got.Discrete[v] != s {
+				t.Fatalf("Row %d: %s = %d, expected %d", i, v, got.Discrete[v], s)
+			}
+		}
+		for v, x := range want.Continuous {
+			if math.Abs(got.Continuous[v]-x) > 1e-9 {
+				t.Fatalf("Row %d: %s = %.6f, expected %.6f", i, v, got.Continuous[v], x)
+			}
+		}
+	}
+	if len(cols.Samples()) != 500 {
+		t.Error("Samples should return one sample per row")
+	}
+}
+
+func TestJointLogPDF(t *testing.T) {
+	// D -> X -> Y with X | D=d ~ N(5d, 1) and Y = X + N(0, 0.1)
+	bn, err := NewBayesianNetwork([][2]string{{"D", "X"}, {"X", "Y"}})
+	if err != nil {
+		t.Fatalf("Failed to create network: %v", err)
+	}
+	cpdD, _ := factors.NewTabularCPD("D", 2, [][]float64{{0.6, 0.4}}, []string{}, map[string]int{})
+	cpdX, _ := factors.NewDiscreteParentGaussianCPD("X", []string{"D"}, map[string]int{"D": 2},
+		map[string]factors.GaussianParams{"0": {Mean: 0, Variance: 1}, "1": {Mean: 5, Variance: 1}})
+	cpdY, _ := factors.NewLinearGaussianCPD("Y", []string{"X"}, 0, map[string]float64{"X": 1}, 0.1)
+	if err := bn.AddCPD(cpdD); err != nil {
+		t.Fatalf("Failed to add CPD for D: %v", err)
+	}
+	for _, cpd := range []*factors.LinearGaussianCPD{cpdX, cpdY} {
+		if err := bn.AddGaussianCPD(cpd); err != nil {
+			t.Fatalf("Failed to add CPD for %s: %v", cpd.Variable, err)
+		}
+	}
+
+	logNormal := func(x, mean, variance float64) float64 {
+		return -0.5*math.Log(2*math.Pi*variance) - (x-mean)*(x-mean)/(2*variance)
+	}
+	sample := Sample{Discrete: map[string]int{"D": 1}, Continuous: map[string]float64{"X": 4.5, "Y": 4.6}}
+	got, err := bn.JointLogPDF(sample)
+	if err != nil {
+		t.Fatalf("JointLogPDF failed: %v", err)
+	}
+	want := math.Log(0.4) + logNormal(4.5, 5, 1) + logNormal(4.6, 4.5, 0.1)
+	if math.Abs(got-want) > 1e-12 {
+		t.Errorf("JointLogPDF = %v, want %v", got, want)
+	}
+
+	if _, err := bn.JointLogPDF(Sample{Discrete: map[string]int{"D": 1}, Continuous: map[string]float64{"X": 4.5}}); err == nil {
+		t.Error("Expected error for a sample missing Y")
+	}
+	sample.Discrete["D"] = 2
+	if _, err := bn.JointLogPDF(sample); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState, got %v", err)
+	}
+	sample.Discrete["D"] = 0
+	sample.Continuous["Z"] = 1
+	if _, err := bn.JointLogPDF(sample); !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("Expected ErrUnknownVariable, got %v", err)
+	}
+}
+
+func TestRidgeRegression(t *testing.T) {
+	edges := [][2]string{{"X1", "Y"}, {"X2", "Y"}}
+	samples := make([]Sample, 200)
+	for i := range samples {
+		x := float64(i)/40 - 2.5
+		samples[i] = Sample{Continuous: map[string]float64{"X1": x, "X2": x, "Y": 3*x + 2 + 0.1*float64(i%5-2)}}
+	}
+
+	// Perfectly collinear parents make the normal equations singular
+	bn, _ := NewBayesianNetwork(edges)
+	if err := bn.FitMixed(samples); err == nil {
+		t.Fatal("Expected collinear parents to fail without a ridge penalty")
+	}
+
+	bn, _ = NewBayesianNetwork(edges)
+	if err := bn.SetRidge(-1); err == nil {
+		t.Error("Expected an error for a negative penalty")
+	}
+	if err := bn.SetRidge(1); err != nil {
+		t.Fatalf("SetRidge failed: %v", err)
+	}
+	if err := bn.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed with ridge failed: %v", err)
+	}
+	cpd, _ := bn.GetGaussianCPD("Y")
+	b1, b2 := cpd.Coefficients["X1"], cpd.Coefficients["X2"]
+	if math.Abs(b1-b2) > 1e-9 || math.Abs(b1+b2-3) > 0.05 {
+		t.Errorf("Expected the slope split evenly, got %.4f and %.4f", b1, b2)
+	}
+	if math.Abs(cpd.Intercept-2) > 0.05 {
+		t.Errorf("Expected an unpenalized intercept near 2, got %.4f", cpd.Intercept)
+	}
+}
+
+func TestHuberRegression(t *testing.T) {
+	edges := [][2]string{{"X", "Y"}}
+	r := rand.New(rand.NewSource(3))
+	samples := make([]Sample, 300)
+	for i := range samples {
+		x := r.NormFloat64()
+		y := 2*x + 1 + 0.5*r.NormFloat64()
+		if i%30 == 0 {
+			y = 50 // Gross outliers
+		}
+		samples[i] = Sample{Continuous: map[string]float64{"X": x, "Y": y}}
+	}
+
+	bn, _ := NewBayesianNetwork(edges)
+	if err := bn.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	ols, _ := bn.GetGaussianCPD("Y")
+
+	bn, _ = NewBayesianNetwork(edges)
+	if err := bn.SetHuber(-1); err == nil {
+		t.Error("Expected an error for a negative threshold")
+	}
+	if err := bn.SetHuber(1.345); err != nil {
+		t.Fatalf("SetHuber failed: %v", err)
+	}
+	if err := bn.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed with Huber loss failed: %v", err)
+	}
+	cpd, _ := bn.GetGaussianCPD("Y")
+	if math.Abs(cpd.Coefficients["X"]-2) > 0.15 || math.Abs(cpd.Intercept-1) > 0.15 {
+		t.Errorf("Expected slope 2 and intercept 1, got %.3f and %.3f", cpd.Coefficients["X"], cpd.Intercept)
+	}
+	if math.Abs(cpd.Variance-0.25) > 0.08 {
+		t.Errorf("Expected a robust variance near 0.25, got %.3f", cpd.Variance)
+	}
+	if ols.Variance < 10*cpd.Variance {
+		t.Errorf("Expected outliers to inflate the least-squares variance %.3f", ols.Variance)
+	}
+}
+
+func TestHeteroscedasticGaussianCPD(t *testing.T) {
+	// Y | X ~ N(1 + 2X, 0.2·exp(0.8X)) for X ~ N(0, 1)
+	bn, _ := NewBayesianNetwork([][2]string{{"X", "Y"}})
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 0, map[string]float64{}, 1)
+	cpdY, err := factors.NewHeteroscedasticGaussianCPD("Y", []string{"X"}, 1, map[string]float64{"X": 2}, 0.2,
+		map[string]float64{"X": 0.8})
+	if err != nil {
+		t.Fatalf("Failed to create CPD: %v", err)
+	}
+	if _, err := cpdY.ToFactor(); !errors.Is(err, factors.ErrHeteroscedastic) {
+		t.Errorf("Expected ErrHeteroscedastic from ToFactor, got %v", err)
+	}
+	if v, _ := cpdY.GetVariance(map[string]interface{}{"X": 1.0}); math.Abs(v-0.2*math.Exp(0.8)) > 1e-12 {
+		t.Errorf("Expected variance %.4f at X=1, got %.4f", 0.2*math.Exp(0.8), v)
+	}
+	bn.AddGaussianCPD(cpdX)
+	bn.AddGaussianCPD(cpdY)
+	samples, err := bn.SimulateMixed(5000, 11)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+
+	learned, _ := NewBayesianNetwork([][2]string{{"X", "Y"}})
+	if err := learned.SetHeteroscedastic("Y"); err != nil {
+		t.Fatalf("SetHeteroscedastic failed: %v", err)
+	}
+	if err := learned.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	cpd, _ := learned.GetGaussianCPD("Y")
+	if math.Abs(cpd.Coefficients["X"]-2) > 0.05 || math.Abs(cpd.Intercept-1) > 0.05 {
+		t.Errorf("Expected mean 1 + 2X, got %.3f + %.3fX", cpd.Intercept, cpd.Coefficients["X"])
+	}
+	if math.Abs(cpd.LogVariance["X"]-0.8) > 0.1 || math.Abs(cpd.Variance-0.2) > 0.03 {
+		t.Errorf("Expected variance 0.2·exp(0.8X), got %.3f·exp(%.3fX)", cpd.Variance, cpd.LogVariance["X"])
+	}
+
+	// The log-variance coefficients survive a JSON round trip
+	data, err := json.Marshal(learned)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded BayesianNetwork
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got := decoded.GaussianCPDs["Y"].LogVariance["X"]; got != cpd.LogVariance["X"] {
+		t.Errorf("Expected log-variance %.4f after round trip, got %.4f", cpd.LogVariance["X"], got)
+	}
+}
+
+func TestGaussianProcessCPD(t *testing.T) {
+	// Y = sin(2X) + ε is far from linear in X
+	r := rand.New(rand.NewSource(5))
+	samples := make([]Sample, 200)
+	for i := range samples {
+		x := r.Float64()*6 - 3
+		samples[i] = Sample{Continuous: map[string]float64{"X": x, "Y": math.Sin(2*x) + 0.1*r.NormFloat64()}}
+	}
+
+	bn, _ := NewBayesianNetwork([][2]string{{"X", "Y"}})
+	if err := bn.SetGaussianProcess("Y", factors.GPOptions{Kernel: factors.KernelMatern52}); err != nil {
+		t.Fatalf("SetGaussianProcess failed: %v", err)
+	}
+	if err := bn.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("CheckModel failed: %v", err)
+	}
+	cpd, ok := bn.ContinuousCPD("Y")
+	if _, isGP := cpd.(*factors.GaussianProcessCPD); !ok || !isGP {
+		t.Fatalf("Expected a Gaussian process CPD for Y, got %v", cpd)
+	}
+
+	// Simulated rows follow the curve, which a linear CPD cannot
+	simulated, err := bn.SimulateMixed(500, 1)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+	// X is simulated from a Gaussian, so skip rows outside the training range
+	mse, n := 0.0, 0
+	for _, s := range simulated {
+		if x := s.Continuous["X"]; math.Abs(x) < 2.8 {
+			d := s.Continuous["Y"] - math.Sin(2*x)
+			mse += d * d
+			n++
+		}
+	}
+	mse /= float64(n)
+	if mse > 0.03 {
+		t.Errorf("Expected simulated Y within the noise of sin(2X), mean squared error %.4f", mse)
+	}
+
+	on := Sample{Continuous: map[string]float64{"X": 1, "Y": math.Sin(2)}}
+	off := Sample{Continuous: map[string]float64{"X": 1, "Y": -math.Sin(2)}}
+	lpOn, err := bn.JointLogPDF(on)
+	if err != nil {
+		t.Fatalf("JointLogPDF failed: %v", err)
+	}
+	lpOff, _ := bn.JointLogPDF(off)
+	if lpOn < lpOff+10 {
+		t.Errorf("Expected a point on the curve to be far more likely: %.2f vs %.2f", lpOn, lpOff)
+	}
+
+	if _, err := json.Marshal(bn); err == nil {
+		t.Error("Expected JSON encoding of a Gaussian process CPD to fail")
+	}
+}
+
+func TestBasisGaussianCPD(t *testing.T) {
+	// Y | X ~ N(1 + X + 0.5X², 0.1) for X ~ N(0, 1)
+	bn, _ := NewBayesianNetwork([][2]string{{"X", "Y"}})
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 0, map[string]float64{}, 1)
+	basis := []factors.BasisTerm{factors.Power("X", 2)}
+	cpdY, err := factors.NewBasisGaussianCPD("Y", []string{"X"}, 1, map[string]float64{"X": 1}, basis, []float64{0.5}, 0.1)
+	if err != nil {
+		t.Fatalf("Failed to create CPD: %v", err)
+	}
+	if _, err := cpdY.ToFactor(); !errors.Is(err, factors.ErrNonlinearMean) {
+		t.Errorf("Expected ErrNonlinearMean from ToFactor, got %v", err)
+	}
+	if m, _ := cpdY.GetMean(map[string]interface{}{"X": 2.0}); math.Abs(m-5) > 1e-12 {
+		t.Errorf("Expected mean 5 at X=2, got %.4f", m)
+	}
+	bn.AddGaussianCPD(cpdX)
+	bn.AddGaussianCPD(cpdY)
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("CheckModel failed: %v", err)
+	}
+	samples, err := bn.SimulateMixed(3000, 4)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+
+	learned, _ := NewBayesianNetwork([][2]string{{"X", "Y"}})
+	if err := learned.SetBasis("Y", basis); err != nil {
+		t.Fatalf("SetBasis failed: %v", err)
+	}
+	if err := learned.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	cpd, _ := learned.GetGaussianCPD("Y")
+	if len(cpd.BasisCoefficients) != 1 || math.Abs(cpd.BasisCoefficients[0]-0.5) > 0.03 ||
+		math.Abs(cpd.Coefficients["X"]-1) > 0.03 || math.Abs(cpd.Intercept-1) > 0.03 {
+		t.Errorf("Expected mean 1 + X + 0.5X², got %.3f + %.3fX + %vX²", cpd.Intercept, cpd.Coefficients["X"], cpd.BasisCoefficients)
+	}
+
+	// A discrete parent gets basis coefficients for each of its states
+	clg, _ := NewBayesianNetwork([][2]string{{"X", "Y"}, {"D", "Y"}})
+	clg.SetBasis("Y", basis)
+	withD := make([]Sample, len(samples))
+	for i, s := range samples {
+		d := i % 2
+		y := s.Continuous["Y"]
+		if d == 1 {
+			y -= s.Continuous["X"] * s.Continuous["X"]
+		}
+		withD[i] = Sample{Discrete: map[string]int{"D": d}, Continuous: map[string]float64{"X": s.Continuous["X"], "Y": y}}
+	}
+	if err := clg.FitMixed(withD); err != nil {
+		t.Fatalf("FitMixed with a discrete parent failed: %v", err)
+	}
+	states := clg.GaussianCPDs["Y"].DiscreteStates
+	if math.Abs(states["0"].BasisCoefficients[0]-0.5) > 0.05 || math.Abs(states["1"].BasisCoefficients[0]+0.5) > 0.05 {
+		t.Errorf("Expected X² coefficients 0.5 and -0.5, got %v and %v",
+			states["0"].BasisCoefficients, states["1"].BasisCoefficients)
+	}
+
+	// The basis survives a JSON round trip
+	data, err := json.Marshal(learned)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded BayesianNetwork
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	got := decoded.GaussianCPDs["Y"]
+	if len(got.Basis) != 1 || got.Basis[0].String() != "X^2" || got.BasisCoefficients[0] != cpd.BasisCoefficients[0] {
+		t.Errorf("Expected basis X^2 after round trip, got %v %v", got.Basis, got.BasisCoefficients)
+	}
+
+	// Terms must read continuous parents
+	bad, _ := NewBayesianNetwork([][2]string{{"X", "Y"}})
+	bad.SetBasis("Y", []factors.BasisTerm{factors.Interaction("X", "Z")})
+	if err := bad.FitMixed(samples); err == nil {
+		t.Error("Expected FitMixed to reject a basis term reading a non-parent")
+	}
+}
+
+func TestPoissonCPD(t *testing.T) {
+	// C | X ~ Poisson(exp(0.5 + 0.7X)) for X ~ N(0, 1)
+	bn, _ := NewBayesianNetwork([][2]string{{"X", "C"}})
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 0, map[string]float64{}, 1)
+	cpdC, _ := factors.NewPoissonCPD("C", []string{"X"}, 0.5, map[string]float64{"X": 0.7})
+	bn.AddGaussianCPD(cpdX)
+	if err := bn.AddContinuousCPD(cpdC); err != nil {
+		t.Fatalf("AddContinuousCPD failed: %v", err)
+	}
+	samples, err := bn.SimulateMixed(4000, 8)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+	for _, s := range samples {
+		if c := s.Continuous["C"]; c < 0 || c != math.Floor(c) {
+			t.Fatalf("Expected non-negative integer counts, got %v", c)
+		}
+	}
+
+	learned, _ := NewBayesianNetwork([][2]string{{"X", "C"}})
+	if err := learned.SetCount("C"); err != nil {
+		t.Fatalf("SetCount failed: %v", err)
+	}
+	if err := learned.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	cpd, _ := learned.ContinuousCPD("C")
+	poisson, ok := cpd.(*factors.PoissonCPD)
+	if !ok {
+		t.Fatalf("Expected a Poisson CPD for C, got %v", cpd)
+	}
+	if math.Abs(poisson.Intercept-0.5) > 0.05 || math.Abs(poisson.Coefficients["X"]-0.7) > 0.05 {
+		t.Errorf("Expected log rate 0.5 + 0.7X, got %.3f + %.3fX", poisson.Intercept, poisson.Coefficients["X"])
+	}
+
+	// Likelihood weighting matches the posterior mean of X given C = 6,
+	// computed on a grid
+	weighted, err := bn.LikelihoodWeighting(20000, Sample{Continuous: map[string]float64{"C": 6}}, 3)
+	if err != nil {
+		t.Fatalf("LikelihoodWeighting failed: %v", err)
+	}
+	got, _, err := WeightedMean(weighted, "X")
+	if err != nil {
+		t.Fatalf("WeightedMean failed: %v", err)
+	}
+	num, den := 0.0, 0.0
+	for x := -6.0; x <= 6; x += 0.001 {
+		lp, _ := cpdC.LogPDF(6, map[string]interface{}{"X": x})
+		p := math.Exp(lp - x*x/2)
+		num += x * p
+		den += p
+	}
+	if want := num / den; math.Abs(got-want) > 0.03 {
+		t.Errorf("Expected posterior mean of X %.3f, got %.3f", want, got)
+	}
+
+	if _, err := learned.LikelihoodWeighting(10, Sample{Continuous: map[string]float64{"C": 0.5}}, 1); !errors.Is(err, ErrZeroProbability) {
+		t.Errorf("Expected ErrZeroProbability for a non-integer count, got %v", err)
+	}
+}
+
+func TestGammaCPD(t *testing.T) {
+	// Duration | X ~ Gamma(3, μ/3) with log μ = 1 + 0.4X, for X ~ N(0, 1)
+	bn, _ := NewBayesianNetwork([][2]string{{"X", "Duration"}})
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 0, map[string]float64{}, 1)
+	cpdD, _ := factors.NewGammaCPD("Duration", []string{"X"}, 1, map[string]float64{"X": 0.4}, 3)
+	bn.AddGaussianCPD(cpdX)
+	bn.AddContinuousCPD(cpdD)
+	cols, err := bn.SimulateColumns(5000, 6)
+	if err != nil {
+		t.Fatalf("SimulateColumns failed: %v", err)
+	}
+	for _, d := range cols.Continuous["Duration"] {
+		if d <= 0 {
+			t.Fatalf("Expected positive durations, got %v", d)
+		}
+	}
+	samples := cols.Samples()
+
+	learned, _ := NewBayesianNetwork([][2]string{{"X", "Duration"}})
+	if err := learned.SetGamma("Duration"); err != nil {
+		t.Fatalf("SetGamma failed: %v", err)
+	}
+	if err := learned.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	cpd, _ := learned.ContinuousCPD("Duration")
+	gamma, ok := cpd.(*factors.GammaCPD)
+	if !ok {
+		t.Fatalf("Expected a Gamma CPD for Duration, got %v", cpd)
+	}
+	if math.Abs(gamma.Intercept-1) > 0.03 || math.Abs(gamma.Coefficients["X"]-0.4) > 0.03 || math.Abs(gamma.Shape-3) > 0.2 {
+		t.Errorf("Expected log mean 1 + 0.4X and shape 3, got %.3f + %.3fX and %.3f",
+			gamma.Intercept, gamma.Coefficients["X"], gamma.Shape)
+	}
+
+	// An exponential fit keeps the shape at one
+	if err := learned.SetExponential("Duration"); err != nil {
+		t.Fatalf("SetExponential failed: %v", err)
+	}
+	if err := learned.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	cpd, _ = learned.ContinuousCPD("Duration")
+	if shape := cpd.(*factors.GammaCPD).Shape; shape != 1 {
+		t.Errorf("Expected shape 1 for an exponential CPD, got %v", shape)
+	}
+
+	samples[0].Continuous["Duration"] = -1
+	if err := learned.FitMixed(samples); err == nil {
+		t.Error("Expected FitMixed to reject a negative value")
+	}
+}
+
+func TestBetaCPD(t *testing.T) {
+	// Rate | X ~ Beta(μφ, (1-μ)φ) with logit μ = -0.5 + 1.2X and φ = 20
+	bn, _ := NewBayesianNetwork([][2]string{{"X", "Rate"}})
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 0, map[string]float64{}, 1)
+	cpdR, _ := factors.NewBetaCPD("Rate", []string{"X"}, -0.5, map[string]float64{"X": 1.2}, 20)
+	bn.AddGaussianCPD(cpdX)
+	bn.AddContinuousCPD(cpdR)
+	samples, err := bn.SimulateMixed(4000, 9)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+
+	learned, _ := NewBayesianNetwork([][2]string{{"X", "Rate"}})
+	if err := learned.SetBeta("Rate"); err != nil {
+		t.Fatalf("SetBeta failed: %v", err)
+	}
+	if err := learned.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	cpd, _ := learned.ContinuousCPD("Rate")
+	beta, ok := cpd.(*factors.BetaCPD)
+	if !ok {
+		t.Fatalf("Expected a Beta CPD for Rate, got %v", cpd)
+	}
+	if math.Abs(beta.Intercept+0.5) > 0.03 || math.Abs(beta.Coefficients["X"]-1.2) > 0.03 || math.Abs(beta.Precision-20) > 1.5 {
+		t.Errorf("Expected logit mean -0.5 + 1.2X and precision 20, got %.3f + %.3fX and %.2f",
+			beta.Intercept, beta.Coefficients["X"], beta.Precision)
+	}
+
+	samples[0].Continuous["Rate"] = 1
+	if err := learned.FitMixed(samples); err == nil {
+		t.Error("Expected FitMixed to reject a value of 1")
+	}
+}
+
+func TestOrdinalProbitCPD(t *testing.T) {
+	// Grade is 0, 1 or 2 as 0.8X + ε crosses -0.5 and 0.7
+	bn, _ := NewBayesianNetwork([][2]string{{"X", "Grade"}})
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 0, map[string]float64{}, 1)
+	cpdG, _ := factors.NewOrdinalProbitCPD("Grade", []string{"X"}, map[string]float64{"X": 0.8}, []float64{-0.5, 0.7})
+	bn.AddGaussianCPD(cpdX)
+	bn.AddHybridCPD(cpdG)
+	samples, err := bn.SimulateMixed(4000, 5)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+	if _, err := bn.JointLogPDF(samples[0]); err != nil {
+		t.Errorf("JointLogPDF failed: %v", err)
+	}
+
+	learned, _ := NewBayesianNetwork([][2]string{{"X", "Grade"}})
+	if err := learned.SetOrdinalProbit("Grade"); err != nil {
+		t.Fatalf("SetOrdinalProbit failed: %v", err)
+	}
+	if err := learned.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	if err := learned.CheckModel(); err != nil {
+		t.Fatalf("CheckModel failed: %v", err)
+	}
+	probit, ok := learned.HybridCPDs["Grade"].(*factors.OrdinalProbitCPD)
+	if !ok {
+		t.Fatalf("Expected an ordinal probit CPD for Grade, got %v", learned.HybridCPDs["Grade"])
+	}
+	if learned.Cardinality["Grade"] != 3 {
+		t.Errorf("Expected 3 grades, got %d", learned.Cardinality["Grade"])
+	}
+	if math.Abs(probit.Coefficients["X"]-0.8) > 0.06 ||
+		math.Abs(probit.Thresholds[0]+0.5) > 0.06 || math.Abs(probit.Thresholds[1]-0.7) > 0.06 {
+		t.Errorf("Expected coefficient 0.8 and thresholds [-0.5 0.7], got %.3f and %.3f",
+			probit.Coefficients["X"], probit.Thresholds)
+	}
+
+	if _, err := json.Marshal(learned); err == nil {
+		t.Error("Expected JSON encoding to reject an ordinal probit CPD")
+	}
+}
+
+func TestCopulaCPD(t *testing.T) {
+	// X ~ Gamma(3, 1) and Y log-normal, with normal scores correlated 0.7
+	bn, _ := NewBayesianNetwork([][2]string{{"X", "Y"}})
+	gamma := factors.GammaMarginal{Shape: 3, Rate: 1}
+	cpdX, _ := factors.NewCopulaCPD("X", []string{}, gamma, nil, 0, nil, 1)
+	cpdY, _ := factors.NewCopulaCPD("Y", []string{"X"}, factors.LogNormalMarginal{Mu: 1, Sigma: 0.5},
+		map[string]factors.Marginal{"X": gamma}, 0, map[string]float64{"X": 0.7}, 0.51)
+	bn.AddContinuousCPD(cpdX)
+	bn.AddContinuousCPD(cpdY)
+	samples, err := bn.SimulateMixed(3000, 8)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+	if _, err := bn.JointLogPDF(samples[0]); err != nil {
+		t.Errorf("JointLogPDF failed: %v", err)
+	}
+
+	learned, _ := NewBayesianNetwork([][2]string{{"X", "Y"}})
+	if err := learned.SetCopula("X", factors.MarginalGamma); err != nil {
+		t.Fatalf("SetCopula failed: %v", err)
+	}
+	if err := learned.SetCopula("Y", factors.MarginalLogNormal); err != nil {
+		t.Fatalf("SetCopula failed: %v", err)
+	}
+	if err := learned.FitMixed(samples); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	cpd, _ := learned.ContinuousCPD("Y")
+	copula, ok := cpd.(*factors.CopulaCPD)
+	if !ok {
+		t.Fatalf("Expected a copula CPD for Y, got %v", cpd)
+	}
+	if math.Abs(copula.Coefficients["X"]-0.7) > 0.03 || math.Abs(copula.Variance-0.51) > 0.03 {
+		t.Errorf("Expected score coefficient 0.7 and variance 0.51, got %.3f and %.3f",
+			copula.Coefficients["X"], copula.Variance)
+	}
+	marginal := copula.Marginal.(factors.LogNormalMarginal)
+	if math.Abs(marginal.Mu-1) > 0.03 || math.Abs(marginal.Sigma-0.5) > 0.03 {
+		t.Errorf("Expected log-normal marginal (1, 0.5), got (%.3f, %.3f)", marginal.Mu, marginal.Sigma)
+	}
+	cpd, _ = learned.ContinuousCPD("X")
+	if shape := cpd.(*factors.CopulaCPD).Marginal.(factors.GammaMarginal).Shape; math.Abs(shape-3) > 0.25 {
+		t.Errorf("Expected Gamma marginal shape 3, got %.3f", shape)
+	}
+
+	// An empirical marginal recovers the same dependence without a family
+	empirical, _ := NewBayesianNetwork([][2]string{{"X", "Y"}})
+	empirical.SetCopula("Y", factors.MarginalEmpirical)
+	if err := empirical.FitMixed(samples[:1000]); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	cpd, _ = empirical.ContinuousCPD("Y")
+	if c := cpd.(*factors.CopulaCPD).Coefficients["X"]; math.Abs(c-0.7) > 0.06 {
+		t.Errorf("Expected score coefficient 0.7 with empirical marginals, got %.3f", c)
+	}
+
+	if err := learned.SetCopula("X", "uniform"); err == nil {
+		t.Error("Expected SetCopula to reject an unknown marginal type")
+	}
+}
+
+func TestPreprocessingPipeline(t *testing.T) {
+	// Color is a label, Age a number cut into bins and Height a number in cm
+	rng := rand.New(rand.NewSource(3))
+	records := make([]Record, 2000)
+	for i := range records {
+		color := "blue"
+		if rng.Float64() < 0.4 {
+			color = "red"
+		}
+		height := 170 + rng.NormFloat64()*5
+		if color == "red" {
+			height += 10
+		}
+		records[i] = Record{"Color": color, "Height": height, "Age": 20 + rng.Float64()*40}
+		if i%10 == 0 {
+			delete(records[i], "Height")
+		}
+	}
+
+	bn, _ := NewBayesianNetwork([][2]string{{"Color", "Height"}, {"Age", "Height"}})
+	bn.SetPipeline(NewPipeline(
+		Impute(ImputeMedian, "Height"),
+		Standardize("Height"),
+		Discretize(3, "Age"),
+		Encode("Color"),
+	))
+	if err := bn.FitRecords(records); err != nil {
+		t.Fatalf("FitRecords failed: %v", err)
+	}
+	if names := bn.StateNames("Color"); len(names) != 2 || names[0] != "blue" || names[1] != "red" {
+		t.Errorf("Expected Color states [blue red], got %v", names)
+	}
+	if bn.Cardinality["Age"] != 3 {
+		t.Errorf("Expected 3 Age bins, got %d", bn.Cardinality["Age"])
+	}
+
+	sample, err := bn.Preprocess(Record{"Color": "red", "Height": 180.0, "Age": 100, "Name": "x"})
+	if err != nil {
+		t.Fatalf("Preprocess failed: %v", err)
+	}
+	if sample.Discrete["Color"] != 1 || sample.Discrete["Age"] != 2 || len(sample.Discrete) != 2 {
+		t.Errorf("Expected Color 1 and Age 2, got %v", sample.Discrete)
+	}
+	step := bn.Pipeline().Steps[1]
+	if want := (180 - step.Means["Height"]) / step.StdDevs["Height"]; math.Abs(sample.Continuous["Height"]-want) > 1e-12 {
+		t.Errorf("Expected standardized Height %.4f, got %.4f", want, sample.Continuous["Height"])
+	}
+	if _, err := bn.Preprocess(Record{"Color": "green"}); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState for an unseen label, got %v", err)
+	}
+
+	// The fitted pipeline travels with the model
+	data, err := json.Marshal(bn)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded BayesianNetwork
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	again, err := decoded.Preprocess(Record{"Color": "red", "Height": 180.0, "Age": 100})
+	if err != nil {
+		t.Fatalf("Preprocess after decoding failed: %v", err)
+	}
+	if again.Continuous["Height"] != sample.Continuous["Height"] || again.Discrete["Color"] != 1 {
+		t.Errorf("Expected the decoded pipeline to match, got %v", again)
+	}
+
+	if _, err := NewPipeline(Standardize("Height")).Transform(records[1]); err == nil {
+		t.Error("Expected an error transforming with an unfitted pipeline")
+	}
+}
+
+func TestExportCPDs(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"D", "E"}, {"D", "X"}, {"X", "Y"}})
+	d, _ := factors.NewTabularCPD("D", 2, [][]float64{{0.6, 0.4}}, []string{}, map[string]int{})
+	_ = d.SetStateNames("D", []string{"off", "on"})
+	e, _ := factors.NewTabularCPD("E", 2, [][]float64{{0.9, 0.1}, {0.25, 0.75}}, []string{"D"}, map[string]int{"D": 2})
+	x, _ := factors.NewDiscreteParentGaussianCPD("X", []string{"D"}, map[string]int{"D": 2},
+		map[string]factors.GaussianParams{"0": {Mean: 0, Variance: 1}, "1": {Mean: 5, Variance: 2}})
+	y, _ := factors.NewLinearGaussianCPD("Y", []string{"X"}, 0.5, map[string]float64{"X": 2}, 0.1)
+	_ = bn.AddCPD(d)
+	_ = bn.AddCPD(e)
+	_ = bn.AddGaussianCPD(x)
+	_ = bn.AddGaussianCPD(y)
+
+	dir := filepath.Join(t.TempDir(), "cpds")
+	if err := bn.ExportCPDs(dir, "csv"); err != nil {
+		t.Fatalf("ExportCPDs failed: %v", err)
+	}
+	want := map[string]string{
+		"D": "P(D=off),P(D=on)\n0.6,0.4\n",
+		"E": "D,P(E=0),P(E=1)\noff,0.9,0.1\non,0.25,0.75\n",
+		"X": "D,intercept,variance\noff,0,1\non,5,2\n",
+		"Y": "intercept,coef(X),variance\n0.5,2,0.1\n",
+	}
+	for node, content := range want {
+		data, err := os.ReadFile(filepath.Join(dir, node+".csv"))
+		if err != nil {
+			t.Fatalf("Reading table of %s: %v", node, err)
+		}
+		if string(data) != content {
+			t.Errorf("Table of %s:\n%s\nwant:\n%s", node, data, content)
+		}
+	}
+
+	if err := bn.ExportCPDs(dir, "json"); err != nil {
+		t.Fatalf("ExportCPDs failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "E.json"))
+	if err != nil {
+		t.Fatalf("Reading JSON table: %v", err)
+	}
+	var table CPDTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		t.Fatalf("Decoding JSON table: %v", err)
+	}
+	if table.Variable != "E" || len(table.Rows) != 2 || table.Rows[1][0] != "on" || table.Rows[1][2] != 0.75 {
+		t.Errorf("Unexpected JSON table %+v", table)
+	}
+
+	if err := bn.ExportCPDs(dir, "xlsx"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	incomplete, _ := NewBayesianNetwork([][2]string{{"A", "B"}})
+	if err := incomplete.ExportCPDs(dir, "csv"); !errors.Is(err, ErrMissingCPD) {
+		t.Errorf("Expected ErrMissingCPD, got %v", err)
+	}
+}
+
+func TestBootstrapParameters(t *testing.T) {
+	bn, _ := NewBayesianNetwork([][2]string{{"D", "X"}})
+	d, _ := factors.NewTabularCPD("D", 2, [][]float64{{0.6, 0.4}}, []string{}, map[string]int{})
+	x, _ := factors.NewDiscreteParentGaussianCPD("X", []string{"D"}, map[string]int{"D": 2},
+		map[string]factors.GaussianParams{"0": {Mean: 0, Variance: 1}, "1": {Mean: 5, Variance: 4}})
+	_ = bn.AddCPD(d)
+	_ = bn.AddGaussianCPD(x)
+	data, err := bn.SimulateMixed(400, 3)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+
+	intervals, err := bn.BootstrapParameters(data, BootstrapOptions{Replicates: 100, Seed: 1})
+	if err != nil {
+		t.Fatalf("BootstrapParameters failed: %v", err)
+	}
+	truth := map[string]float64{
+		"D P(D=1)":        0.4,
+		"X intercept D=0": 0,
+		"X intercept D=1": 5,
+		"X variance D=1":  4,
+		"X variance D=0":  1,
+		"D P(D=0)":        0.6,
+	}
+	if len(intervals) != len(truth) {
+		t.Fatalf("Expected %d parameters, got %d: %+v", len(truth), len(intervals), intervals)
+	}
+	for _, iv := range intervals {
+		name := strings.TrimSpace(iv.Node + " " + iv.Parameter + " " + iv.Condition)
+		want, ok := truth[name]
+		if !ok {
+			t.Errorf("Unexpected parameter %q", name)
+			continue
+		}
+		if !(iv.Lower <= iv.Estimate && iv.Estimate <= iv.Upper) || iv.StdErr <= 0 {
+			t.Errorf("%s: estimate %f outside [%f, %f] or standard error %f", name, iv.Estimate, iv.Lower, iv.Upper, iv.StdErr)
+		}
+		// Generous bounds: the 95% intervals should cover these true values
+		if want < iv.Lower-2*iv.StdErr || want > iv.Upper+2*iv.StdErr {
+			t.Errorf("%s: interval [%f, %f] far from true value %f", name, iv.Lower, iv.Upper, want)
+		}
+	}
+
+	if _, err := bn.BootstrapParameters(data, BootstrapOptions{Level: 1}); err == nil {
+		t.Error("Expected an error for confidence level 1")
+	}
+	if _, ok := bn.GaussianCPDs["X"]; !ok || bn.GaussianCPDs["X"].DiscreteStates["1"].Mean != 5 {
+		t.Error("BootstrapParameters changed the network")
+	}
+}
+
+func TestLikelihoodRatioTest(t *testing.T) {
+	truth, _ := NewBayesianNetwork([][2]string{{"A", "B"}, {"D", "X"}})
+	_ = truth.AddNode("C", Discrete, 3)
+	_ = truth.AddNode("Z", Continuous, 0)
+	a, _ := factors.NewTabularCPD("A", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	b, _ := factors.NewTabularCPD("B", 2, [][]float64{{0.8, 0.2}, {0.3, 0.7}}, []string{"A"}, map[string]int{"A": 2})
+	c, _ := factors.NewTabularCPD("C", 3, [][]float64{{0.2, 0.3, 0.5}}, []string{}, map[string]int{})
+	d, _ := factors.NewTabularCPD("D", 2, [][]float64{{0.6, 0.4}}, []string{}, map[string]int{})
+	x, _ := factors.NewDiscreteParentGaussianCPD("X", []string{"D"}, map[string]int{"D": 2},
+		map[string]factors.GaussianParams{"0": {Mean: 0, Variance: 1}, "1": {Mean: 1, Variance: 1}})
+	z, _ := factors.NewLinearGaussianCPD("Z", nil, 0, nil, 1)
+	_ = truth.AddCPD(a)
+	_ = truth.AddCPD(b)
+	_ = truth.AddCPD(c)
+	_ = truth.AddCPD(d)
+	_ = truth.AddGaussianCPD(x)
+	_ = truth.AddGaussianCPD(z)
+	data, err := truth.SimulateMixed(2000, 5)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+
+	// Fit a network with a spurious edge into each child
+	bn, _ := NewBayesianNetwork([][2]string{{"A", "B"}, {"C", "B"}, {"D", "X"}, {"Z", "X"}})
+	if err := bn.FitMixed(data); err != nil {
+		t.Fatalf("FitMixed failed: %v", err)
+	}
+	tests, err := bn.EdgeTests(data)
+	if err != nil {
+		t.Fatalf("EdgeTests failed: %v", err)
+	}
+	wantDF := map[string]int{"A->B": 3, "C->B": 4, "D->X": 3, "Z->X": 2}
+	for _, test := range tests {
+		edge := test.Parent + "->" + test.Child
+		if test.DF != wantDF[edge] || test.Samples != len(data) {
+			t.Errorf("%s: DF %d with %d samples, want %d with %d", edge, test.DF, test.Samples, wantDF[edge], len(data))
+		}
+		real := edge == "A->B" || edge == "D->X"
+		if real && test.PValue > 1e-6 {
+			t.Errorf("%s: real edge has p-value %g", edge, test.PValue)
+		}
+		if !real && test.PValue < 0.001 {
+			t.Errorf("%s: spurious edge has p-value %g", edge, test.PValue)
+		}
+	}
+	if len(tests) != len(wantDF) {
+		t.Errorf("Expected %d tests, got %d", len(wantDF), len(tests))
+	}
+
+	if _, err := bn.LikelihoodRatioTest("B", "A", data); err == nil {
+		t.Error("Expected an error for a missing edge")
+	}
+}
+
+func TestFitDiagnostics(t *testing.T) {
+	truth, _ := NewBayesianNetwork([][2]string{{"D", "E"}, {"D", "X"}, {"X", "Y"}})
+	d, _ := factors.NewTabularCPD("D", 2, [][]float64{{0.6, 0.4}}, []string{}, map[string]int{})
+	e, _ := factors.NewTabularCPD("E", 2, [][]float64{{0.9, 0.1}, {0.25, 0.75}}, []string{"D"}, map[string]int{"D": 2})
+	x, _ := factors.NewDiscreteParentGaussianCPD("X", []string{"D"}, map[string]int{"D": 2},
+		map[string]factors.GaussianParams{"0": {Mean: 0, Variance: 1}, "1": {Mean: 5, Variance: 2}})
+	y, _ := factors.NewLinearGaussianCPD("Y", []string{"X"}, 0.5, map[string]float64{"X": 2}, 1)
+	_ = truth.AddCPD(d)
+	_ = truth.AddCPD(e)
+	_ = truth.AddGaussianCPD(x)
+	_ = truth.AddGaussianCPD(y)
+	heldOut, err := truth.SimulateMixed(2000, 8)
+	if err != nil {
+		t.Fatalf("SimulateMixed failed: %v", err)
+	}
+
+	fits, err := truth.FitDiagnostics(heldOut)
+	if err != nil {
+		t.Fatalf("FitDiagnostics failed: %v", err)
+	}
+	if len(fits) != 4 {
+		t.Fatalf("Expected 4 CPD fits, got %d", len(fits))
+	}
+	for _, fit := range fits {
+		if fit.Samples != len(heldOut) {
+			t.Errorf("%s: diagnosed %d samples, want %d", fit.Node, fit.Samples, len(heldOut))
+		}
+		switch fit.Node {
+		case "E":
+			if len(fit.Configurations) != 2 || fit.Configurations[1].Condition != "D=1" || fit.DF != 2 {
+				t.Errorf("Unexpected configurations %+v", fit.Configurations)
+			}
+			if fit.PValue < 0.001 {
+				t.Errorf("True CPD of E rejected with p-value %g", fit.PValue)
+			}
+		case "Y":
+			g := fit.Gaussian
+			if g == nil || g.RSquared < 0.9 || math.Abs(g.StandardizedSD-1) > 0.1 || g.NormalityP < 0.001 {
+				t.Errorf("Unexpected residual analysis of Y: %+v", g)
+			}
+		}
+	}
+
+	// A wrong CPT and a wrong variance are caught
+	wrong := truth.Copy()
+	e2, _ := factors.NewTabularCPD("E", 2, [][]float64{{0.5, 0.5}, {0.25, 0.75}}, []string{"D"}, map[string]int{"D": 2})
+	_ = wrong.AddCPD(e2)
+	wrong.GaussianCPDs["Y"].Variance = 0.25
+	fit, err := wrong.DiagnoseCPD("E", heldOut)
+	if err != nil {
+		t.Fatalf("DiagnoseCPD failed: %v", err)
+	}
+	if fit.Configurations[0].PValue > 1e-6 || fit.Configurations[1].PValue < 0.001 {
+		t.Errorf("Expected only the changed configuration rejected: %+v", fit.Configurations)
+	}
+	fit, err = wrong.DiagnoseCPD("Y", heldOut)
+	if err != nil {
+		t.Fatalf("DiagnoseCPD failed: %v", err)
+	}
+	if fit.Gaussian.StandardizedSD < 1.5 {
+		t.Errorf("Expected standardized residuals too spread, got SD %f", fit.Gaussian.StandardizedSD)
+	}
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// SetCopula makes FitMixed and FitWeighted learn a Gaussian copula CPD for
+// node: its values keep a marginal of the given family, fitted to the data,
+// while a linear Gaussian regression on normal scores ties them to the
+// continuous parents. Declaring every continuous node a copula node gives a
+// Gaussian copula network, which keeps non-Gaussian marginals intact and
+// learns the correlation structure among them. Parents that are not copula
+// nodes are mapped to scores through an empirical marginal.
+func (bn *BayesianNetwork) SetCopula(node string, marginal factors.MarginalType) error {
+	if !bn.DAG.HasNode(node) {
+		return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+	}
+	if bn.IsDiscrete(node) {
+		return fmt.Errorf("cannot fit a copula CPD for discrete node %s", node)
+	}
+	switch marginal {
+	case factors.MarginalEmpirical, factors.MarginalNormal, factors.MarginalLogNormal, factors.MarginalGamma:
+	default:
+		return fmt.Errorf("unknown marginal type %q", marginal)
+	}
+	if bn.copula == nil {
+		bn.copula = make(map[string]factors.MarginalType)
+	}
+	bn.copula[node] = marginal
+	return nil
+}
+
+// learnCopulaCPD fits the marginals of the variable and its parents, each to
+// every row holding it, then regresses the variable's normal scores on its
+// parents' over the rows that hold the variable and all its parents
+func (bn *BayesianNetwork) learnCopulaCPD(variable string, data []Sample, weights []float64) (*factors.CopulaCPD, error) {
+	parents := bn.DAG.Parents(variable)
+	sort.Strings(parents)
+	for _, p := range parents {
+		if bn.IsDiscrete(p) {
+			return nil, fmt.Errorf("copula CPD for %s: parent %s is discrete", variable, p)
+		}
+	}
+
+	marginal, err := bn.fitMarginal(variable, data, weights)
+	if err != nil {
+		return nil, err
+	}
+	parentMarginals := make(map[string]factors.Marginal, len(parents))
+	for _, p := range parents {
+		if parentMarginals[p], err = bn.fitMarginal(p, data, weights); err != nil {
+			return nil, err
+		}
+	}
+
+	var scores, rowWeights []float64
+	var rows [][]float64 // Each row is [1, z1, z2, ..., zn]
+	for i, sample := range data {
+		x, ok := sample.Continuous[variable]
+		if !ok {
+			continue
+		}
+		row := []float64{1}
+		valid := true
+		for _, p := range parents {
+			v, ok := sample.Continuous[p]
+			if !ok {
+				valid = false
+				break
+			}
+			row = append(row, factors.NormalScore(parentMarginals[p], v))
+		}
+		if valid {
+			scores = append(scores, factors.NormalScore(marginal, x))
+			rows = append(rows, row)
+			rowWeights = append(rowWeights, sampleWeight(weights, i))
+		}
+	}
+	if len(scores) < len(parents)+2 {
+		return nil, fmt.Errorf("insufficient data for learning copula CPD for %s", variable)
+	}
+
+	coeffs, variance, err := fitGaussianRegression(rows, scores, rowWeights, 0)
+	if err != nil {
+		return nil, fmt.Errorf("copula CPD for %s: %w", variable, err)
+	}
+	parentCoeffs := make(map[string]float64, len(parents))
+	for i, p := range parents {
+		parentCoeffs[p] = coeffs[i+1]
+	}
+	return factors.NewCopulaCPD(variable, parents, marginal, parentMarginals, coeffs[0], parentCoeffs, variance)
+}
+
+// fitMarginal fits the marginal family declared for node by SetCopula, or an
+// empirical marginal, by weighted maximum likelihood on every row holding it
+func (bn *BayesianNetwork) fitMarginal(node string, data []Sample, weights []float64) (factors.Marginal, error) {
+	var values, rowWeights []float64
+	for i, sample := range data {
+		if x, ok := sample.Continuous[node]; ok {
+			values = append(values, x)
+			rowWeights = append(rowWeights, sampleWeight(weights, i))
+		}
+	}
+	if len(values) < 2 {
+		return nil, fmt.Errorf("insufficient data for learning the marginal of %s", node)
+	}
+
+	kind, ok := bn.copula[node]
+	if !ok {
+		kind = factors.MarginalEmpirical
+	}
+	if kind == factors.MarginalLogNormal || kind == factors.MarginalGamma {
+		for _, x := range values {
+			if x <= 0 {
+				return nil, fmt.Errorf("%s has value %v, expected a positive value for a %s marginal", node, x, kind)
+			}
+		}
+	}
+
+	total, mean, logMean := 0.0, 0.0, 0.0
+	for i, x := range values {
+		total += rowWeights[i]
+		mean += rowWeights[i] * x
+		if kind == factors.MarginalLogNormal {
+			logMean += rowWeights[i] * math.Log(x)
+		}
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("marginal of %s: weights sum to zero", node)
+	}
+	mean /= total
+	logMean /= total
+	variance := 0.0
+	for i, x := range values {
+		d := x - mean
+		if kind == factors.MarginalLogNormal {
+			d = math.Log(x) - logMean
+		}
+		variance += rowWeights[i] * d * d
+	}
+	variance /= total
+	if variance <= 0 && kind != factors.MarginalEmpirical {
+		return nil, fmt.Errorf("marginal of %s: values have zero variance", node)
+	}
+
+	switch kind {
+	case factors.MarginalNormal:
+		return factors.NormalMarginal{Mean: mean, StdDev: math.Sqrt(variance)}, nil
+	case factors.MarginalLogNormal:
+		return factors.LogNormalMarginal{Mu: logMean, Sigma: math.Sqrt(variance)}, nil
+	case factors.MarginalGamma:
+		ones := make([][]float64, len(values))
+		for i := range ones {
+			ones[i] = []float64{1}
+		}
+		shape := fitGammaShape(ones, values, rowWeights, []float64{math.Log(mean)})
+		return factors.GammaMarginal{Shape: shape, Rate: shape / mean}, nil
+	default:
+		m, err := factors.NewEmpiricalMarginal(values, rowWeights)
+		if err != nil {
+			return nil, fmt.Errorf("marginal of %s: %w", node, err)
+		}
+		return m, nil
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// JointLogPDF returns the log joint density of a complete assignment, the sum
+// of log P(x | parents) over every node: log probabilities for discrete nodes
+// and log densities for continuous ones. Every node must be assigned, each in
+// the map of its type. Assignments with zero probability give -Inf.
+func (bn *BayesianNetwork) JointLogPDF(sample Sample) (float64, error) {
+	if err := bn.ValidateEvidence(sample.Discrete); err != nil {
+		return 0, err
+	}
+	names := make([]string, 0, len(sample.Continuous))
+	for v := range sample.Continuous {
+		names = append(names, v)
+	}
+	sort.Strings(names)
+	for _, v := range names {
+		if !bn.DAG.HasNode(v) {
+			return 0, fmt.Errorf("sample variable %s: %w", v, ErrUnknownVariable)
+		}
+		if !bn.IsContinuous(v) {
+			return 0, fmt.Errorf("sample variable %s is discrete but has a continuous value", v)
+		}
+	}
+
+	nodes := bn.Nodes()
+	for _, node := range nodes {
+		_, dOK := sample.Discrete[node]
+		_, cOK := sample.Continuous[node]
+		if !dOK && !cOK {
+			return 0, fmt.Errorf("sample has no value for %s", node)
+		}
+	}
+
+	logP := 0.0
+	for _, node := range nodes {
+		if bn.IsContinuous(node) {
+			x := sample.Continuous[node]
+			cpd, ok := bn.ContinuousCPD(node)
+			if !ok {
+				return 0, fmt.Errorf("no continuous CPD for node %s: %w", node, ErrMissingCPD)
+			}
+			lp, err := cpd.LogPDF(x, bn.parentValues(cpd.GetParents(), sample))
+			if err != nil {
+				return 0, fmt.Errorf("density of %s: %w", node, err)
+			}
+			logP += lp
+			continue
+		}
+
+		if hybrid, ok := bn.HybridCPDs[node]; ok {
+			lp, err := hybrid.LogProb(sample.Discrete[node], bn.parentValues(hybrid.GetParents(), sample))
+			if err != nil {
+				return 0, fmt.Errorf("probability of %s: %w", node, err)
+			}
+			logP += lp
+			continue
+		}
+
+		cpd, ok := bn.DiscreteCPD(node)
+		if !ok {
+			return 0, fmt.Errorf("no discrete CPD for node %s: %w", node, ErrMissingCPD)
+		}
+		lp, err := cpd.LogProb(sample.Discrete[node], sample.Discrete)
+		if err != nil {
+			return 0, fmt.Errorf("probability of %s: %w", node, err)
+		}
+		logP += lp
+	}
+	return logP, nil
+}
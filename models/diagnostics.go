@@ -0,0 +1,216 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// CPDFit compares a node's CPD with data. Discrete nodes get a chi-square
+// comparison of observed and expected state counts in each parent
+// configuration; linear Gaussian nodes get a residual analysis. Diagnose
+// held-out data: on the data a CPD was fitted to, the maximum-likelihood
+// CPT reproduces the frequencies up to smoothing.
+type CPDFit struct {
+	Node    string
+	Samples int // Rows with the node and all its parents observed
+
+	// Configurations holds the parent configurations of a discrete node
+	// seen in the data, and ChiSquare, DF and PValue total their tests
+	Configurations []ConfigurationFit
+	ChiSquare      float64
+	DF             int
+	PValue         float64
+
+	// Gaussian holds the residual analysis of a linear Gaussian node
+	Gaussian *GaussianFit
+}
+
+// ConfigurationFit compares the observed state counts of a discrete node in
+// one parent configuration with the counts its CPD predicts
+type ConfigurationFit struct {
+	Condition string // Parent states, such as "D=off,F=1", or "" without parents
+	Count     int
+	Observed  []float64
+	Expected  []float64 // Count · P(state | configuration)
+	Residuals []float64 // Pearson residuals (observed - expected) / √expected
+	ChiSquare float64
+	DF        int
+	PValue    float64
+}
+
+// GaussianFit is the residual analysis of a linear Gaussian CPD, with
+// residuals x - E[x | parents] standardized by the CPD's conditional
+// standard deviation
+type GaussianFit struct {
+	RSquared       float64 // 1 - RSS / TSS of the conditional means
+	MeanResidual   float64
+	StandardizedSD float64 // Near 1 when the CPD's variance fits
+	Skewness       float64 // Of the standardized residuals
+	ExcessKurtosis float64 // Of the standardized residuals
+	JarqueBera     float64 // Jarque-Bera statistic of the standardized residuals
+	NormalityP     float64 // P-value of JarqueBera, small when residuals are not normal
+}
+
+// FitDiagnostics returns the CPDFit of every node with a discrete CPD over
+// discrete parents or a linear Gaussian CPD, in node order
+func (bn *BayesianNetwork) FitDiagnostics(data []Sample) ([]CPDFit, error) {
+	var result []CPDFit
+	for _, node := range bn.Nodes() {
+		_, gaussian := bn.GaussianCPDs[node]
+		_, discrete := bn.DiscreteCPD(node)
+		if !gaussian && !discrete {
+			continue
+		}
+		fit, err := bn.DiagnoseCPD(node, data)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *fit)
+	}
+	return result, nil
+}
+
+// DiagnoseCPD compares the CPD of node with the rows of data observing the
+// node and all its parents
+func (bn *BayesianNetwork) DiagnoseCPD(node string, data []Sample) (*CPDFit, error) {
+	if !bn.DAG.HasNode(node) {
+		return nil, fmt.Errorf("node %s: %w", node, ErrUnknownVariable)
+	}
+	family := append([]string{node}, bn.DAG.Parents(node)...)
+	var rows []Sample
+	for _, s := range data {
+		if bn.observesAll(s, family) {
+			rows = append(rows, s)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no sample observes %s and all its parents", node)
+	}
+	if _, ok := bn.GaussianCPDs[node]; ok {
+		return bn.diagnoseGaussian(node, rows)
+	}
+	if _, ok := bn.DiscreteCPD(node); ok {
+		return bn.diagnoseDiscrete(node, rows)
+	}
+	if _, ok := bn.HybridCPDs[node]; ok || bn.ContinuousCPDs[node] != nil {
+		return nil, fmt.Errorf("CPD of %s is neither tabular nor linear Gaussian", node)
+	}
+	return nil, fmt.Errorf("CPD for %s: %w", node, ErrMissingCPD)
+}
+
+func (bn *BayesianNetwork) diagnoseDiscrete(node string, rows []Sample) (*CPDFit, error) {
+	cpd, err := bn.AsTabularCPD(node)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([][]float64, len(cpd.Values))
+	for i := range counts {
+		counts[i] = make([]float64, cpd.VariableCard)
+	}
+	for _, s := range rows {
+		if err := bn.ValidateEvidence(s.Discrete); err != nil {
+			return nil, err
+		}
+		index := 0
+		for _, p := range cpd.Evidence {
+			index = index*cpd.EvidenceCard[p] + s.Discrete[p]
+		}
+		counts[index][s.Discrete[node]]++
+	}
+
+	fit := &CPDFit{Node: node, Samples: len(rows)}
+	parentLabels := bn.parentLabels(cpd.Evidence, cpd.EvidenceCard)
+	for i, config := range parentConfigurations(cpd.Evidence, cpd.EvidenceCard) {
+		total := 0.0
+		for _, n := range counts[i] {
+			total += n
+		}
+		if total == 0 {
+			continue
+		}
+		condition := make([]string, len(config))
+		for k, label := range labelConfiguration(parentLabels, config) {
+			condition[k] = cpd.Evidence[k] + "=" + label.(string)
+		}
+		c := ConfigurationFit{
+			Condition: strings.Join(condition, ","),
+			Count:     int(total),
+			Observed:  counts[i],
+			Expected:  make([]float64, cpd.VariableCard),
+			Residuals: make([]float64, cpd.VariableCard),
+			DF:        cpd.VariableCard - 1,
+		}
+		for state, observed := range counts[i] {
+			expected := total * cpd.Values[i][state]
+			c.Expected[state] = expected
+			switch {
+			case expected > 0:
+				c.Residuals[state] = (observed - expected) / math.Sqrt(expected)
+			case observed > 0:
+				c.Residuals[state] = math.Inf(1)
+			}
+			c.ChiSquare += c.Residuals[state] * c.Residuals[state]
+		}
+		c.PValue = chiSquareSurvival(c.ChiSquare, c.DF)
+		fit.Configurations = append(fit.Configurations, c)
+		fit.ChiSquare += c.ChiSquare
+		fit.DF += c.DF
+	}
+	fit.PValue = chiSquareSurvival(fit.ChiSquare, fit.DF)
+	return fit, nil
+}
+
+func (bn *BayesianNetwork) diagnoseGaussian(node string, rows []Sample) (*CPDFit, error) {
+	cpd := bn.GaussianCPDs[node]
+	x := make([]float64, len(rows))
+	residuals := make([]float64, len(rows))
+	standardized := make([]float64, len(rows))
+	for i, s := range rows {
+		parents := bn.parentValues(cpd.GetParents(), s)
+		mean, err := cpd.GetMean(parents)
+		if err != nil {
+			return nil, fmt.Errorf("mean of %s: %w", node, err)
+		}
+		variance, err := cpd.GetVariance(parents)
+		if err != nil {
+			return nil, fmt.Errorf("variance of %s: %w", node, err)
+		}
+		x[i] = s.Continuous[node]
+		residuals[i] = x[i] - mean
+		standardized[i] = residuals[i] / math.Sqrt(variance)
+	}
+
+	g := &GaussianFit{MeanResidual: stat.Mean(residuals, nil)}
+	rss, tss := 0.0, 0.0
+	xMean := stat.Mean(x, nil)
+	for i := range x {
+		rss += residuals[i] * residuals[i]
+		tss += (x[i] - xMean) * (x[i] - xMean)
+	}
+	if tss > 0 {
+		g.RSquared = 1 - rss/tss
+	}
+	if len(rows) > 1 {
+		g.StandardizedSD = stat.StdDev(standardized, nil)
+	}
+	if len(rows) > 3 {
+		g.Skewness = stat.Skew(standardized, nil)
+		g.ExcessKurtosis = stat.ExKurtosis(standardized, nil)
+		g.JarqueBera = float64(len(rows)) / 6 * (g.Skewness*g.Skewness + g.ExcessKurtosis*g.ExcessKurtosis/4)
+		g.NormalityP = chiSquareSurvival(g.JarqueBera, 2)
+	}
+	return &CPDFit{Node: node, Samples: len(rows), Gaussian: g}, nil
+}
+
+// chiSquareSurvival returns P(χ²(df) > statistic), 1 without degrees of
+// freedom
+func chiSquareSurvival(statistic float64, df int) float64 {
+	if df <= 0 {
+		return 1
+	}
+	return distuv.ChiSquared{K: float64(df)}.Survival(statistic)
+}
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// maxCompletions bounds the joint states of the unobserved variables of one
+// row that the E-step enumerates
+const maxCompletions = 1 << 20
+
+// EMOptions configures FitEM. Zero fields take their defaults.
+type EMOptions struct {
+	MaxIterations int     // Maximum EM iterations, 100 by default
+	Tolerance     float64 // Relative log-likelihood gain below which EM stops, 1e-6 by default
+	Seed          int64   // Seeds the random initial CPDs of nodes that have none
+}
+
+// EMResult reports how FitEM finished
+type EMResult struct {
+	Iterations    int     // M-steps taken
+	LogLikelihood float64 // Log-likelihood of the observed values under the final CPDs
+	Converged     bool
+}
+
+// FitEM learns the CPDs of a discrete network from data with unobserved
+// values by expectation-maximization. Hidden nodes and variables missing from
+// a row are filled in with every state, weighted by their posterior given the
+// row, and the CPDs are refitted from the weighted completions as by
+// FitWeighted. Existing CPDs are the starting point; nodes without one start
+// from a random CPD, which breaks the symmetry between the states of hidden
+// nodes. Values given for hidden nodes are ignored.
+func (bn *BayesianNetwork) FitEM(data []map[string]int, opts EMOptions) (*EMResult, error) {
+	for _, node := range bn.DAG.Nodes() {
+		if bn.IsContinuous(node) {
+			return nil, fmt.Errorf("EM: %w", ErrContinuousVariables)
+		}
+	}
+	if opts.MaxIterations <= 0 {
+		opts.MaxIterations = 100
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 1e-6
+	}
+
+	nodes, err := bn.DAG.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+	cardinality := make(map[string]int, len(nodes))
+	for _, node := range nodes {
+		card := bn.Cardinality[node]
+		if declared, ok := bn.declared[node]; ok {
+			card = declared
+		}
+		for _, row := range data {
+			if state, ok := row[node]; ok && !bn.hidden[node] && state+1 > card {
+				card = state + 1
+			}
+		}
+		if card < 1 {
+			return nil, fmt.Errorf("node %s has no data and no declared cardinality", node)
+		}
+		cardinality[node] = card
+	}
+
+	// Identical rows are expanded once and weighted by their count
+	rows, counts := bn.emRows(data, nodes)
+
+	r := rand.New(rand.NewSource(opts.Seed))
+	for _, node := range nodes {
+		if _, ok := bn.DiscreteCPD(node); ok {
+			continue
+		}
+		if err := bn.AddCPD(randomCPD(node, bn.DAG.Parents(node), cardinality, r)); err != nil {
+			return nil, fmt.Errorf("initial CPD for %s: %w", node, err)
+		}
+	}
+
+	result := &EMResult{}
+	previous := math.Inf(-1)
+	for {
+		completions, ll, err := bn.expectedCompletions(nodes, rows, counts, cardinality)
+		if err != nil {
+			return nil, err
+		}
+		result.LogLikelihood = ll
+		if ll-previous < opts.Tolerance*math.Abs(ll) {
+			result.Converged = true
+			break
+		}
+		if result.Iterations == opts.MaxIterations {
+			break
+		}
+		previous = ll
+
+		if err := bn.FitWeighted(completions); err != nil {
+			return nil, fmt.Errorf("EM iteration %d: %w", result.Iterations+1, err)
+		}
+		result.Iterations++
+	}
+	return result, nil
+}
+
+// emRows keeps the network's observed variables of each row, dropping hidden
+// values, and merges identical rows
+func (bn *BayesianNetwork) emRows(data []map[string]int, nodes []string) ([]map[string]int, []float64) {
+	index := make(map[string]int)
+	var rows []map[string]int
+	var counts []float64
+	var key strings.Builder
+	for _, row := range data {
+		observed := make(map[string]int, len(nodes))
+		key.Reset()
+		for _, node := range nodes {
+			if state, ok := row[node]; ok && !bn.hidden[node] {
+				observed[node] = state
+				key.WriteString(node)
+				key.WriteByte('=')
+				key.WriteString(strconv.Itoa(state))
+				key.WriteByte(';')
+			}
+		}
+		if i, ok := index[key.String()]; ok {
+			counts[i]++
+			continue
+		}
+		index[key.String()] = len(rows)
+		rows = append(rows, observed)
+		counts = append(counts, 1)
+	}
+	return rows, counts
+}
+
+// expectedCompletions is the E-step: it expands each row into every
+// assignment of its unobserved variables, weighted by count times posterior,
+// and returns them with the observed-data log-likelihood
+func (bn *BayesianNetwork) expectedCompletions(nodes []string, rows []map[string]int, counts []float64,
+	cardinality map[string]int) ([]WeightedSample, float64, error) {
+	cpds := make(map[string]*factors.TabularCPD, len(nodes))
+	for _, node := range nodes {
+		cpd, err := bn.AsTabularCPD(node)
+		if err != nil {
+			return nil, 0, err
+		}
+		cpds[node] = cpd
+	}
+	joint := func(assignment map[string]int) float64 {
+		p := 1.0
+		for _, node := range nodes {
+			cpd := cpds[node]
+			row, stride := 0, 1
+			for j := len(cpd.Evidence) - 1; j >= 0; j-- {
+				e := cpd.Evidence[j]
+				row += assignment[e] * stride
+				stride *= cpd.EvidenceCard[e]
+			}
+			state := assignment[node]
+			if row >= len(cpd.Values) || state >= len(cpd.Values[row]) {
+				return 0 // Outside the CPD's states
+			}
+			p *= cpd.Values[row][state]
+		}
+		return p
+	}
+
+	var completions []WeightedSample
+	ll := 0.0
+	for i, row := range rows {
+		var missing []string
+		total := 1
+		for _, node := range nodes {
+			if _, ok := row[node]; !ok {
+				missing = append(missing, node)
+				total *= cardinality[node]
+				if total > maxCompletions {
+					return nil, 0, fmt.Errorf("row %d leaves more than %d joint states unobserved", i, maxCompletions)
+				}
+			}
+		}
+
+		assignment := make(map[string]int, len(nodes))
+		for v, state := range row {
+			assignment[v] = state
+		}
+		probs := make([]float64, total)
+		z := 0.0
+		for k := 0; k < total; k++ {
+			decodeStates(k, missing, cardinality, assignment)
+			probs[k] = joint(assignment)
+			z += probs[k]
+		}
+		if z == 0 {
+			return nil, 0, fmt.Errorf("row %d has zero probability: %w", i, factors.ErrZeroProbability)
+		}
+		ll += counts[i] * math.Log(z)
+
+		for k, p := range probs {
+			if p == 0 {
+				continue
+			}
+			decodeStates(k, missing, cardinality, assignment)
+			completion := make(map[string]int, len(assignment))
+			for v, state := range assignment {
+				completion[v] = state
+			}
+			completions = append(completions, WeightedSample{
+				Sample: Sample{Discrete: completion},
+				Weight: counts[i] * p / z,
+			})
+		}
+	}
+
+	// Every state of every node must appear for FitWeighted to size the CPDs,
+	// so add a weightless completion at the top state of each
+	top := make(map[string]int, len(nodes))
+	for _, node := range nodes {
+		top[node] = cardinality[node] - 1
+	}
+	completions = append(completions, WeightedSample{Sample: Sample{Discrete: top}})
+	return completions, ll, nil
+}
+
+// decodeStates writes the k-th joint state of variables into assignment, the
+// last variable varying fastest
+func decodeStates(k int, variables []string, cardinality map[string]int, assignment map[string]int) {
+	for j := len(variables) - 1; j >= 0; j-- {
+		card := cardinality[variables[j]]
+		assignment[variables[j]] = k % card
+		k /= card
+	}
+}
+
+// randomCPD draws each row of a CPD uniformly from the simplex
+func randomCPD(node string, parents []string, cardinality map[string]int, r *rand.Rand) *factors.TabularCPD {
+	sort.Strings(parents)
+	evidenceCard := make(map[string]int, len(parents))
+	rows := 1
+	for _, p := range parents {
+		evidenceCard[p] = cardinality[p]
+		rows *= cardinality[p]
+	}
+	values := make([][]float64, rows)
+	for i := range values {
+		values[i] = make([]float64, cardinality[node])
+		sum := 0.0
+		for j := range values[i] {
+			values[i][j] = r.ExpFloat64()
+			sum += values[i][j]
+		}
+		for j := range values[i] {
+			values[i][j] /= sum
+		}
+	}
+	cpd, _ := factors.NewTabularCPD(node, cardinality[node], values, parents, evidenceCard) // Rows sum to one
+	return cpd
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// Ensemble averages the posteriors of several discrete networks over the same
+// variables, such as networks learned from bootstrap replicates of one data
+// set. Each member's posterior is weighted by its share of the weights.
+type Ensemble struct {
+	Members []*BayesianNetwork
+	Weights []float64 // Normalized to sum to 1
+}
+
+// NewEnsemble checks that the members are valid discrete networks with the
+// same variables and cardinalities. Nil weights weight the members equally;
+// otherwise weights must be non-negative with a positive sum.
+func NewEnsemble(members []*BayesianNetwork, weights []float64) (*Ensemble, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("ensemble has no members")
+	}
+	if weights == nil {
+		weights = make([]float64, len(members))
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	if len(weights) != len(members) {
+		return nil, fmt.Errorf("%d weights for %d members", len(weights), len(members))
+	}
+	total := 0.0
+	for i, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("member %d has negative weight %v", i, w)
+		}
+		total += w
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("ensemble weights sum to %v", total)
+	}
+
+	nodes := members[0].Nodes()
+	for i, bn := range members {
+		if err := bn.CheckModel(); err != nil {
+			return nil, fmt.Errorf("member %d: %w", i, err)
+		}
+		if got := bn.Nodes(); strings.Join(got, ",") != strings.Join(nodes, ",") {
+			return nil, fmt.Errorf("member %d has variables %v, member 0 has %v", i, got, nodes)
+		}
+		for _, node := range nodes {
+			if !bn.IsDiscrete(node) {
+				return nil, fmt.Errorf("member %d: %s: %w", i, node, ErrContinuousVariables)
+			}
+			if bn.Cardinality[node] != members[0].Cardinality[node] {
+				return nil, fmt.Errorf("member %d gives %s %d states, member 0 gives %d: %w",
+					i, node, bn.Cardinality[node], members[0].Cardinality[node], ErrCardinalityMismatch)
+			}
+		}
+	}
+
+	normalized := make([]float64, len(weights))
+	for i, w := range weights {
+		normalized[i] = w / total
+	}
+	return &Ensemble{Members: members, Weights: normalized}, nil
+}
+
+// Posterior returns the weighted average of the members' P(variable |
+// evidence). Members under which the evidence is impossible are left out and
+// the remaining weights renormalized.
+func (e *Ensemble) Posterior(variable string, evidence map[string]int) ([]float64, error) {
+	first := e.Members[0]
+	if !first.IsDiscrete(variable) {
+		return nil, fmt.Errorf("query variable %s: %w", variable, ErrUnknownVariable)
+	}
+	if err := first.ValidateEvidence(evidence); err != nil {
+		return nil, err
+	}
+
+	averaged := make([]float64, first.Cardinality[variable])
+	if state, ok := evidence[variable]; ok {
+		averaged[state] = 1
+		return averaged, nil
+	}
+	total := 0.0
+	for i, bn := range e.Members {
+		if e.Weights[i] == 0 {
+			continue
+		}
+		values, err := bn.posterior(variable, evidence)
+		if err != nil {
+			return nil, fmt.Errorf("member %d: %w", i, err)
+		}
+		mass := 0.0
+		for _, p := range values {
+			mass += p
+		}
+		if mass <= 0 {
+			continue
+		}
+		for s, p := range values {
+			averaged[s] += e.Weights[i] * p / mass
+		}
+		total += e.Weights[i]
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("evidence has zero probability under every member: %w", factors.ErrZeroProbability)
+	}
+	for s := range averaged {
+		averaged[s] /= total
+	}
+	return averaged, nil
+}
+
+// Predict fills in the variables missing from each observation with the most
+// probable state of the averaged posterior, as BayesianNetwork.Predict does
+// for a single network
+func (e *Ensemble) Predict(observations []map[string]int) (map[string][]int, error) {
+	toPredictMap := make(map[string]bool)
+	for _, obs := range observations {
+		for _, v := range e.Members[0].Nodes() {
+			if _, ok := obs[v]; !ok {
+				toPredictMap[v] = true
+			}
+		}
+	}
+	toPredict := make([]string, 0, len(toPredictMap))
+	for v := range toPredictMap {
+		toPredict = append(toPredict, v)
+	}
+	sort.Strings(toPredict)
+
+	predictions := make(map[string][]int, len(toPredict))
+	for _, v := range toPredict {
+		predictions[v] = make([]int, len(observations))
+	}
+	for i, obs := range observations {
+		for _, v := range toPredict {
+			posterior, err := e.Posterior(v, obs)
+			if err != nil {
+				return nil, fmt.Errorf("observation %d: %w", i, err)
+			}
+			predictions[v][i] = argmax(posterior)
+		}
+	}
+	return predictions, nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/graph"
+)
+
+var (
+	// ErrMissingCPD is returned when a node has no CPD
+	ErrMissingCPD = errors.New("missing CPD")
+
+	// ErrParentMismatch is returned when a CPD's parents differ from the DAG
+	ErrParentMismatch = errors.New("CPD parents do not match graph")
+
+	// ErrUnknownVariable is returned for variables that are not in the network
+	ErrUnknownVariable = errors.New("unknown variable")
+
+	// ErrContinuousVariables is returned by discrete-only operations on a
+	// network with continuous variables
+	ErrContinuousVariables = errors.New("network contains continuous variables")
+
+	// ErrInvalidState is returned for a discrete state outside a variable's
+	// cardinality
+	ErrInvalidState = errors.New("state out of range")
+
+	// ErrInvalidModel is matched by every *ValidationError from CheckModel
+	ErrInvalidModel = errors.New("invalid model")
+
+	// Re-exported from the packages that produce them, so callers working
+	// with models can test for them without extra imports
+	ErrCycle               = graph.ErrCycle
+	ErrCardinalityMismatch = factors.ErrCardinalityMismatch
+	ErrInvalidDistribution = factors.ErrInvalidDistribution
+	ErrNotImplementedCLG   = factors.ErrNotImplementedCLG
+	ErrZeroProbability     = factors.ErrZeroProbability
+)
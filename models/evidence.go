@@ -0,0 +1,38 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ValidateEvidence checks that every observed variable is a discrete
+// variable of the network and that its state is within its cardinality.
+// Variables are checked in name order, so the first problem reported is
+// deterministic.
+func (bn *BayesianNetwork) ValidateEvidence(evidence map[string]int) error {
+	names := make([]string, 0, len(evidence))
+	for v := range evidence {
+		names = append(names, v)
+	}
+	sort.Strings(names)
+
+	for _, v := range names {
+		state := evidence[v]
+		if !bn.DAG.HasNode(v) {
+			return fmt.Errorf("evidence variable %s: %w", v, ErrUnknownVariable)
+		}
+		if bn.IsContinuous(v) {
+			return fmt.Errorf("evidence variable %s is continuous and cannot take state %d: %w",
+				v, state, ErrContinuousVariables)
+		}
+		card := bn.Cardinality[v]
+		if cpd, ok := bn.DiscreteCPD(v); ok {
+			card = cpd.GetCardinality()
+		}
+		if card > 0 && (state < 0 || state >= card) {
+			return fmt.Errorf("evidence %s=%d, but %s has states 0 to %d: %w",
+				v, state, v, card-1, ErrInvalidState)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package models
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// CPDTable is the tidy table of one node's CPD: a row per configuration of
+// its discrete parents, holding the parents' state labels followed by the
+// parameters. Discrete nodes have a probability column "P(X=state)" per
+// state. Gaussian nodes have "intercept", "coef(Y)" per continuous parent or
+// basis term, "variance" and, if heteroscedastic, "log_variance(Y)".
+type CPDTable struct {
+	Variable string          `json:"variable"`
+	Columns  []string        `json:"columns"`
+	Rows     [][]interface{} `json:"rows"` // State labels are strings, parameters are float64
+}
+
+// ExportCPDs writes the CPD table of every node to dir, one file named after
+// the node per table, in format "csv" or "json", creating dir if needed. It
+// fails for nodes without a CPD and for custom continuous and hybrid CPDs,
+// which have no table form.
+func (bn *BayesianNetwork) ExportCPDs(dir, format string) error {
+	format = strings.ToLower(format)
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown CPD table format %q, expected csv or json", format)
+	}
+	tables := make([]*CPDTable, 0, len(bn.Nodes()))
+	for _, node := range bn.Nodes() {
+		if filepath.Base(node) != node || node == ".." {
+			return fmt.Errorf("node name %q cannot be used as a file name", node)
+		}
+		table, err := bn.CPDTable(node)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, table)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		path := filepath.Join(dir, table.Variable+"."+format)
+		var err error
+		if format == "csv" {
+			err = table.writeCSV(path)
+		} else {
+			err = table.writeJSON(path)
+		}
+		if err != nil {
+			return fmt.Errorf("exporting CPD of %s: %w", table.Variable, err)
+		}
+	}
+	return nil
+}
+
+// CPDTable returns the tidy table of a node's CPD, as written by ExportCPDs
+func (bn *BayesianNetwork) CPDTable(variable string) (*CPDTable, error) {
+	if !bn.DAG.HasNode(variable) {
+		return nil, fmt.Errorf("node %s: %w", variable, ErrUnknownVariable)
+	}
+	if cpd, ok := bn.GaussianCPDs[variable]; ok {
+		return bn.gaussianTable(cpd), nil
+	}
+	if _, ok := bn.DiscreteCPD(variable); ok {
+		cpd, err := bn.AsTabularCPD(variable)
+		if err != nil {
+			return nil, err
+		}
+		return bn.tabularTable(cpd), nil
+	}
+	if _, ok := bn.HybridCPDs[variable]; ok {
+		return nil, fmt.Errorf("hybrid CPD of %s has no table form", variable)
+	}
+	if _, ok := bn.ContinuousCPDs[variable]; ok {
+		return nil, fmt.Errorf("custom continuous CPD of %s has no table form", variable)
+	}
+	return nil, fmt.Errorf("CPD for %s: %w", variable, ErrMissingCPD)
+}
+
+func (bn *BayesianNetwork) tabularTable(cpd *factors.TabularCPD) *CPDTable {
+	table := &CPDTable{Variable: cpd.Variable, Columns: append([]string(nil), cpd.Evidence...)}
+	labels := bn.stateLabels(cpd.Variable, cpd.VariableCard)
+	for _, label := range labels {
+		table.Columns = append(table.Columns, fmt.Sprintf("P(%s=%s)", cpd.Variable, label))
+	}
+	parentLabels := bn.parentLabels(cpd.Evidence, cpd.EvidenceCard)
+	for i, config := range parentConfigurations(cpd.Evidence, cpd.EvidenceCard) {
+		row := labelConfiguration(parentLabels, config)
+		for _, p := range cpd.Values[i] {
+			row = append(row, p)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+func (bn *BayesianNetwork) gaussianTable(cpd *factors.LinearGaussianCPD) *CPDTable {
+	discrete, continuous := cpd.DiscreteParents(), cpd.ContinuousParents()
+	table := &CPDTable{Variable: cpd.Variable, Columns: append([]string(nil), discrete...)}
+	table.Columns = append(table.Columns, "intercept")
+	for _, p := range continuous {
+		table.Columns = append(table.Columns, "coef("+p+")")
+	}
+	for _, t := range cpd.Basis {
+		table.Columns = append(table.Columns, "coef("+basisLabel(t)+")")
+	}
+	table.Columns = append(table.Columns, "variance")
+	for _, p := range continuous {
+		if _, ok := cpd.LogVariance[p]; ok {
+			table.Columns = append(table.Columns, "log_variance("+p+")")
+		}
+	}
+
+	params := func(mean, variance float64, coefficients map[string]float64, basis []float64) []interface{} {
+		row := []interface{}{mean}
+		for _, p := range continuous {
+			row = append(row, coefficients[p])
+		}
+		for k := range cpd.Basis {
+			coef := 0.0
+			if k < len(basis) {
+				coef = basis[k]
+			}
+			row = append(row, coef)
+		}
+		row = append(row, variance)
+		for _, p := range continuous {
+			if gamma, ok := cpd.LogVariance[p]; ok {
+				row = append(row, gamma)
+			}
+		}
+		return row
+	}
+
+	if len(discrete) == 0 {
+		table.Rows = [][]interface{}{params(cpd.Intercept, cpd.Variance, cpd.Coefficients, cpd.BasisCoefficients)}
+		return table
+	}
+	parentLabels := bn.parentLabels(discrete, cpd.Cardinality)
+	for _, config := range parentConfigurations(discrete, cpd.Cardinality) {
+		key := make([]string, len(config))
+		for i, s := range config {
+			key[i] = strconv.Itoa(s)
+		}
+		state := cpd.DiscreteStates[strings.Join(key, ",")]
+		row := labelConfiguration(parentLabels, config)
+		table.Rows = append(table.Rows, append(row, params(state.Mean, state.Variance, state.Coefficients, state.BasisCoefficients)...))
+	}
+	return table
+}
+
+// parentConfigurations returns every joint state of the parents, the last
+// parent fastest as in the rows of a TabularCPD
+func parentConfigurations(parents []string, cardinality map[string]int) [][]int {
+	configs := [][]int{{}}
+	for _, p := range parents {
+		next := make([][]int, 0, len(configs)*cardinality[p])
+		for _, config := range configs {
+			for s := 0; s < cardinality[p]; s++ {
+				next = append(next, append(append([]int(nil), config...), s))
+			}
+		}
+		configs = next
+	}
+	return configs
+}
+
+// labelConfiguration returns the state labels of a parent configuration
+func labelConfiguration(parentLabels [][]string, config []int) []interface{} {
+	row := make([]interface{}, len(config))
+	for i, s := range config {
+		row[i] = parentLabels[i][s]
+	}
+	return row
+}
+
+// parentLabels returns the state labels of each parent
+func (bn *BayesianNetwork) parentLabels(parents []string, cardinality map[string]int) [][]string {
+	labels := make([][]string, len(parents))
+	for i, p := range parents {
+		labels[i] = bn.stateLabels(p, cardinality[p])
+	}
+	return labels
+}
+
+// stateLabels returns the state names of a discrete variable, or its state
+// numbers where unlabeled
+func (bn *BayesianNetwork) stateLabels(variable string, cardinality int) []string {
+	labels := bn.StateNames(variable)
+	for len(labels) < cardinality {
+		labels = append(labels, strconv.Itoa(len(labels)))
+	}
+	return labels[:cardinality]
+}
+
+// basisLabel names a basis term by its formula
+func basisLabel(t factors.BasisTerm) string {
+	if t.Kind == factors.BasisHinge && len(t.Parents) == 1 {
+		label := "max(0," + t.Parents[0] + "-" + strconv.FormatFloat(t.Knot, 'g', -1, 64) + ")"
+		if t.Degree != 1 {
+			label += "^" + strconv.Itoa(t.Degree)
+		}
+		return label
+	}
+	return strings.Join(t.Parents, "*")
+}
+
+func (t *CPDTable) writeCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(file)
+	_ = w.Write(t.Columns)
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			switch v := cell.(type) {
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'g', -1, 64)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (t *CPDTable) writeJSON(path string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+	"gonum.org/v1/gonum/mathext"
+)
+
+// SetGamma makes FitMixed and FitWeighted learn a Gamma CPD for node, with
+// its mean log-linear in the continuous parents and a fitted shape, for
+// durations, amounts and other positive-only quantities that a Gaussian
+// would simulate below zero. The node's values must be positive and its
+// parents continuous when it is fitted.
+func (bn *BayesianNetwork) SetGamma(node string) error {
+	return bn.setGammaShape(node, 0)
+}
+
+// SetExponential is SetGamma with the shape fixed at one, so the node is
+// exponential given its parents
+func (bn *BayesianNetwork) SetExponential(node string) error {
+	return bn.setGammaShape(node, 1)
+}
+
+// setGammaShape declares a Gamma node with a fixed shape, or zero to fit it
+func (bn *BayesianNetwork) setGammaShape(node string, shape float64) error {
+	if !bn.DAG.HasNode(node) {
+		return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+	}
+	if bn.IsDiscrete(node) {
+		return fmt.Errorf("cannot fit a Gamma CPD for discrete node %s", node)
+	}
+	if bn.gammaShape == nil {
+		bn.gammaShape = make(map[string]float64)
+	}
+	bn.gammaShape[node] = shape
+	return nil
+}
+
+// learnGammaCPD fits log μ = β₀ + Σᵢ βᵢYᵢ and, unless fixed, the shape by
+// maximum likelihood to the rows that hold the variable and all its parents
+func (bn *BayesianNetwork) learnGammaCPD(variable string, data []Sample, weights []float64) (*factors.GammaCPD, error) {
+	parents := bn.DAG.Parents(variable)
+	sort.Strings(parents)
+	for _, p := range parents {
+		if bn.IsDiscrete(p) {
+			return nil, fmt.Errorf("Gamma CPD for %s: parent %s is discrete", variable, p)
+		}
+	}
+
+	var values, rowWeights []float64
+	var rows [][]float64 // Each row is [1, y1, y2, ..., yn]
+	for i, sample := range data {
+		x, ok := sample.Continuous[variable]
+		if !ok {
+			continue
+		}
+		if x <= 0 {
+			return nil, fmt.Errorf("sample %d: %s has value %v, expected a positive value", i, variable, x)
+		}
+		row := []float64{1}
+		valid := true
+		for _, p := range parents {
+			v, ok := sample.Continuous[p]
+			if !ok {
+				valid = false
+				break
+			}
+			row = append(row, v)
+		}
+		if valid {
+			values = append(values, x)
+			rows = append(rows, row)
+			rowWeights = append(rowWeights, sampleWeight(weights, i))
+		}
+	}
+	if len(values) < len(parents)+2 {
+		return nil, fmt.Errorf("insufficient data for learning Gamma CPD for %s", variable)
+	}
+
+	coeffs, err := bn.fitLogLink(rows, values, rowWeights, gammaFamily)
+	if err != nil {
+		return nil, fmt.Errorf("Gamma CPD for %s: %w", variable, err)
+	}
+	shape := bn.gammaShape[variable]
+	if shape == 0 {
+		shape = fitGammaShape(rows, values, rowWeights, coeffs)
+	}
+
+	parentCoeffs := make(map[string]float64, len(parents))
+	for i, p := range parents {
+		parentCoeffs[p] = coeffs[i+1]
+	}
+	return factors.NewGammaCPD(variable, parents, coeffs[0], parentCoeffs, shape)
+}
+
+// fitGammaShape returns the maximum likelihood shape k given the fitted
+// means, the root of log k - ψ(k) = D with D the weighted mean of
+// (x - μ)/μ - log(x/μ), found by Newton's method from Minka's approximation
+func fitGammaShape(Y [][]float64, X []float64, W []float64, beta []float64) float64 {
+	d, total := 0.0, 0.0
+	for i, row := range Y {
+		eta := 0.0
+		for j, b := range beta {
+			eta += b * row[j]
+		}
+		mu := math.Exp(eta)
+		d += W[i] * ((X[i]-mu)/mu - math.Log(X[i]/mu))
+		total += W[i]
+	}
+	d /= total
+	if d < 1e-12 {
+		// Values match their means exactly: the distribution is degenerate
+		return 1e12
+	}
+
+	k := (3 - d + math.Sqrt((d-3)*(d-3)+24*d)) / (12 * d)
+	for iter := 0; iter < 50; iter++ {
+		// Newton on log k in place of k keeps the shape positive
+		f := math.Log(k) - mathext.Digamma(k) - d
+		df := 1 - k*trigamma(k)
+		next := k * math.Exp(-f/df)
+		if math.Abs(next-k) < 1e-10*k {
+			return next
+		}
+		k = next
+	}
+	return k
+}
+
+// trigamma returns ψ'(x), the Hurwitz zeta ζ(2, x), for x > 0
+func trigamma(x float64) float64 {
+	return mathext.Zeta(2, x)
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// SetGaussianProcess makes FitMixed learn a Gaussian process CPD for node
+// instead of a linear Gaussian one, with the kernel and limits of opts, for
+// relationships that are smooth but far from linear. The node's parents must
+// be continuous. Gaussian process CPDs take part in simulation and density
+// evaluation but not in exact Gaussian inference.
+func (bn *BayesianNetwork) SetGaussianProcess(node string, opts factors.GPOptions) error {
+	if !bn.DAG.HasNode(node) {
+		return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+	}
+	if bn.IsDiscrete(node) {
+		return fmt.Errorf("cannot fit a Gaussian process for discrete node %s", node)
+	}
+	if bn.gaussianProcess == nil {
+		bn.gaussianProcess = make(map[string]factors.GPOptions)
+	}
+	bn.gaussianProcess[node] = opts
+	return nil
+}
+
+// learnGaussianProcessCPD fits a Gaussian process CPD to the rows that hold
+// the variable and all its parents. The fit is unweighted, so weighted rows
+// are only accepted with unit weights.
+func (bn *BayesianNetwork) learnGaussianProcessCPD(variable string, data []Sample, weights []float64) (*factors.GaussianProcessCPD, error) {
+	parents := bn.DAG.Parents(variable)
+	sort.Strings(parents)
+	for _, p := range parents {
+		if bn.IsDiscrete(p) {
+			return nil, fmt.Errorf("Gaussian process CPD for %s: parent %s is discrete", variable, p)
+		}
+	}
+
+	var inputs [][]float64
+	var targets []float64
+	for i, sample := range data {
+		y, ok := sample.Continuous[variable]
+		if !ok {
+			continue
+		}
+		if w := sampleWeight(weights, i); w != 1 {
+			return nil, fmt.Errorf("Gaussian process CPD for %s cannot use sample weight %v", variable, w)
+		}
+		row := make([]float64, len(parents))
+		valid := true
+		for j, p := range parents {
+			if row[j], valid = sample.Continuous[p]; !valid {
+				break
+			}
+		}
+		if valid {
+			inputs = append(inputs, row)
+			targets = append(targets, y)
+		}
+	}
+	if len(targets) < 2 {
+		return nil, fmt.Errorf("insufficient data for learning Gaussian process CPD for %s", variable)
+	}
+
+	cpd, err := factors.FitGaussianProcessCPD(variable, parents, inputs, targets, bn.gaussianProcess[variable])
+	if err != nil {
+		return nil, fmt.Errorf("Gaussian process CPD for %s: %w", variable, err)
+	}
+	return cpd, nil
+}
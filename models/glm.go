@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// glmIterations bounds the iteratively reweighted least squares steps of a
+// log-link regression
+const glmIterations = 100
+
+// logLinkFamily is a response distribution for fitLogLink, whose mean μ
+// satisfies log μ = Yβ
+type logLinkFamily struct {
+	// weight is the IRLS working weight of a row with mean mu, (dμ/dη)² / Var
+	weight func(mu float64) float64
+	// logLikelihood is a row's log-likelihood in β, up to terms free of β
+	logLikelihood func(x, eta float64) float64
+}
+
+var (
+	// Poisson counts: Var = μ
+	poissonFamily = logLinkFamily{
+		weight:        func(mu float64) float64 { return mu },
+		logLikelihood: func(x, eta float64) float64 { return x*eta - math.Exp(eta) },
+	}
+	// Gamma with any fixed shape: Var ∝ μ², so the working weights are flat
+	gammaFamily = logLinkFamily{
+		weight:        func(float64) float64 { return 1 },
+		logLikelihood: func(x, eta float64) float64 { return -x*math.Exp(-eta) - eta },
+	}
+)
+
+// fitLogLink maximizes the weighted log-likelihood of log μ = Yβ, where each
+// row of Y is [1, y1, ..., yn], by iteratively reweighted least squares: each
+// step regresses the working response η + (x - μ)/μ on Y with the family's
+// working weights, halving the step until the likelihood does not fall. The
+// ridge penalty of SetRidge applies.
+func (bn *BayesianNetwork) fitLogLink(Y [][]float64, X []float64, W []float64, family logLinkFamily) ([]float64, error) {
+	total, sum := 0.0, 0.0
+	for i, x := range X {
+		total += W[i]
+		sum += W[i] * x
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("regression rows have no weight")
+	}
+	beta := make([]float64, len(Y[0]))
+	beta[0] = math.Log(math.Max(sum/total, 1e-6))
+
+	eta := make([]float64, len(X))
+	working := make([]float64, len(X))
+	irls := make([]float64, len(X))
+	predict := func(b []float64) {
+		for i, row := range Y {
+			eta[i] = 0
+			for j, v := range b {
+				eta[i] += v * row[j]
+			}
+		}
+	}
+	// Log-likelihood up to a constant, for the current eta
+	logLikelihood := func() float64 {
+		ll := 0.0
+		for i, x := range X {
+			ll += W[i] * family.logLikelihood(x, eta[i])
+		}
+		return ll
+	}
+
+	predict(beta)
+	current := logLikelihood()
+	for iter := 0; iter < glmIterations; iter++ {
+		for i, x := range X {
+			mu := math.Exp(eta[i])
+			working[i] = eta[i] + (x-mu)/mu
+			irls[i] = W[i] * family.weight(mu)
+		}
+		next, _, err := fitGaussianRegression(Y, working, irls, bn.ridge)
+		if err != nil {
+			return nil, err
+		}
+
+		step := make([]float64, len(beta))
+		for j := range step {
+			step[j] = next[j] - beta[j]
+		}
+		previous := current
+		improved := false
+		for k := 0; k < 30 && !improved; k++ {
+			candidate := make([]float64, len(beta))
+			for j := range candidate {
+				candidate[j] = beta[j] + step[j]
+			}
+			predict(candidate)
+			if ll := logLikelihood(); ll >= current {
+				beta, current, improved = candidate, ll, true
+			}
+			for j := range step {
+				step[j] /= 2
+			}
+		}
+		if !improved {
+			predict(beta)
+			break
+		}
+		if current-previous < 1e-10*math.Abs(current) {
+			break
+		}
+	}
+	return beta, nil
+}
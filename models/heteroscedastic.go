@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// heteroscedasticIterations bounds the alternating updates of the mean and
+// log-variance regressions
+const heteroscedasticIterations = 100
+
+// SetHeteroscedastic makes FitMixed and FitWeighted learn a Gaussian CPD for
+// node whose log-variance, as well as its mean, is linear in the continuous
+// parents, for noise that grows or shrinks with the parents' level. The node
+// must have only continuous parents when it is fitted. The ridge penalty of
+// SetRidge applies to the mean; the Huber loss of SetHuber does not.
+func (bn *BayesianNetwork) SetHeteroscedastic(node string) error {
+	if !bn.DAG.HasNode(node) {
+		return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+	}
+	if bn.IsDiscrete(node) {
+		return fmt.Errorf("cannot make discrete node %s heteroscedastic", node)
+	}
+	if bn.heteroscedastic == nil {
+		bn.heteroscedastic = make(map[string]bool)
+	}
+	bn.heteroscedastic[node] = true
+	return nil
+}
+
+// IsHeteroscedastic reports whether node was declared by SetHeteroscedastic
+func (bn *BayesianNetwork) IsHeteroscedastic(node string) bool {
+	return bn.heteroscedastic[node]
+}
+
+// fitHeteroscedastic fits X = β₀ + Σᵢ βᵢYᵢ + ε with ε ~ N(0, exp(γ₀ + Σᵢ γᵢYᵢ))
+// by maximum likelihood, where each row of Y is [1, y1, ..., yn]. It
+// alternates weighted least squares for β, with weights inverse to the
+// current variances, and a Fisher scoring step for γ, and returns β and γ.
+// Only the first varianceColumns columns of Y enter the log-variance, so
+// basis features after them shape the mean alone.
+func (bn *BayesianNetwork) fitHeteroscedastic(Y [][]float64, X []float64, W []float64, varianceColumns int) ([]float64, []float64, error) {
+	coeffs, variance, err := fitGaussianRegression(Y, X, W, bn.ridge)
+	if err != nil {
+		return nil, nil, err
+	}
+	Z := make([][]float64, len(Y))
+	for i, row := range Y {
+		Z[i] = row[:varianceColumns]
+	}
+	gamma := make([]float64, varianceColumns)
+	gamma[0] = math.Log(variance)
+
+	residuals := make([]float64, len(X))
+	eta := make([]float64, len(X))
+	weights := make([]float64, len(X))
+	working := make([]float64, len(X))
+	predict := func(c []float64, row []float64) float64 {
+		sum := 0.0
+		for j, v := range c {
+			sum += v * row[j]
+		}
+		return sum
+	}
+	// Log-likelihood up to a constant, given the residuals
+	logLikelihood := func(g []float64) float64 {
+		ll := 0.0
+		for i, row := range Z {
+			e := predict(g, row)
+			ll -= 0.5 * sampleWeight(W, i) * (e + residuals[i]*residuals[i]*math.Exp(-e))
+		}
+		return ll
+	}
+
+	previous := math.Inf(-1)
+	for iter := 0; iter < heteroscedasticIterations; iter++ {
+		for i, row := range Y {
+			residuals[i] = X[i] - predict(coeffs, row)
+			eta[i] = predict(gamma, Z[i])
+		}
+		current := logLikelihood(gamma)
+
+		// Scoring for the Gamma-like regression of r² with log link: regress
+		// the working response η + r²/exp(η) - 1 on the parents
+		for i := range working {
+			working[i] = eta[i] + residuals[i]*residuals[i]*math.Exp(-eta[i]) - 1
+		}
+		next, _, err := fitGaussianRegression(Z, working, W, 0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("log-variance regression: %w", err)
+		}
+		// Halve the step until the likelihood does not fall
+		step := make([]float64, len(gamma))
+		for j := range step {
+			step[j] = next[j] - gamma[j]
+		}
+		for k := 0; k < 30; k++ {
+			candidate := make([]float64, len(gamma))
+			for j := range candidate {
+				candidate[j] = gamma[j] + step[j]
+			}
+			if ll := logLikelihood(candidate); ll >= current {
+				gamma, current = candidate, ll
+				break
+			}
+			for j := range step {
+				step[j] /= 2
+			}
+		}
+
+		for i, row := range Z {
+			weights[i] = sampleWeight(W, i) * math.Exp(-predict(gamma, row))
+		}
+		coeffs, _, err = fitGaussianRegression(Y, X, weights, bn.ridge)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if current-previous < 1e-10*math.Abs(current) {
+			break
+		}
+		previous = current
+	}
+	return coeffs, gamma, nil
+}
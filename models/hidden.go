@@ -0,0 +1,55 @@
+package models
+
+import "sort"
+
+// SetHidden declares node a latent discrete variable with the given number
+// of states, fixed as by DeclareCardinality. Hidden nodes are never
+// observed: Fit learns their CPDs by EM, Predict ignores any value given for
+// them, and Simulate generates them like any other node.
+func (bn *BayesianNetwork) SetHidden(node string, cardinality int) error {
+	if err := bn.DeclareCardinality(node, cardinality); err != nil {
+		return err
+	}
+	if bn.hidden == nil {
+		bn.hidden = make(map[string]bool)
+	}
+	bn.hidden[node] = true
+	return nil
+}
+
+// IsHidden reports whether node was declared hidden
+func (bn *BayesianNetwork) IsHidden(node string) bool {
+	return bn.hidden[node]
+}
+
+// HiddenNodes returns the hidden nodes in sorted order
+func (bn *BayesianNetwork) HiddenNodes() []string {
+	nodes := make([]string, 0, len(bn.hidden))
+	for node := range bn.hidden {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// withoutHidden returns the observation with any hidden values dropped, or
+// the observation itself if it has none
+func (bn *BayesianNetwork) withoutHidden(observation map[string]int) map[string]int {
+	found := false
+	for node := range bn.hidden {
+		if _, ok := observation[node]; ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return observation
+	}
+	out := make(map[string]int, len(observation))
+	for v, state := range observation {
+		if !bn.hidden[v] {
+			out[v] = state
+		}
+	}
+	return out
+}
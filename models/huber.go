@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// huberIterations bounds the reweighting rounds of a Huber regression
+const huberIterations = 50
+
+// SetHuber makes the regressions FitMixed and FitWeighted use for Gaussian
+// CPDs robust to outlying rows. Coefficients minimize the Huber loss, which
+// is squared for residuals within delta robust standard deviations and
+// linear beyond, and the variance is the squared robust scale 1.4826·MAD of
+// the residuals, so a few gross outliers move neither much. 1.345 keeps 95%
+// of the efficiency of least squares on Gaussian data. Zero, the default,
+// gives least squares.
+func (bn *BayesianNetwork) SetHuber(delta float64) error {
+	if delta < 0 {
+		return fmt.Errorf("huber threshold must be non-negative, got %v", delta)
+	}
+	bn.huber = delta
+	return nil
+}
+
+// Huber returns the threshold set by SetHuber
+func (bn *BayesianNetwork) Huber() float64 {
+	return bn.huber
+}
+
+// fitRegression fits a Gaussian CPD regression with the network's ridge
+// penalty, by iteratively reweighted least squares under the Huber loss when
+// SetHuber is in effect
+func (bn *BayesianNetwork) fitRegression(Y [][]float64, X []float64, W []float64) ([]float64, float64, error) {
+	coeffs, variance, err := fitGaussianRegression(Y, X, W, bn.ridge)
+	if err != nil || bn.huber == 0 {
+		return coeffs, variance, err
+	}
+
+	residuals := make([]float64, len(X))
+	weights := make([]float64, len(X))
+	scale := 0.0
+	for iter := 0; iter < huberIterations; iter++ {
+		for i, row := range Y {
+			predicted := 0.0
+			for j, c := range coeffs {
+				predicted += c * row[j]
+			}
+			residuals[i] = X[i] - predicted
+		}
+		scale = robustScale(residuals, W)
+		if scale == 0 {
+			break // An exact fit to at least half the weight
+		}
+
+		// Rows beyond the threshold are downweighted to give linear loss
+		cutoff := bn.huber * scale
+		for i, r := range residuals {
+			weights[i] = sampleWeight(W, i)
+			if math.Abs(r) > cutoff {
+				weights[i] *= cutoff / math.Abs(r)
+			}
+		}
+		next, _, err := fitGaussianRegression(Y, X, weights, bn.ridge)
+		if err != nil {
+			return nil, 0, err
+		}
+		change := 0.0
+		for j := range coeffs {
+			change = math.Max(change, math.Abs(next[j]-coeffs[j])/(math.Abs(coeffs[j])+1e-8))
+		}
+		coeffs = next
+		if change < 1e-8 {
+			break
+		}
+	}
+
+	variance = scale * scale
+	if variance < 1e-6 {
+		variance = 1e-6
+	}
+	return coeffs, variance, nil
+}
+
+// robustScale estimates the standard deviation of residuals by 1.4826 times
+// their weighted median absolute value, which is consistent under Gaussian
+// noise
+func robustScale(residuals, weights []float64) float64 {
+	idx := make([]int, len(residuals))
+	total := 0.0
+	for i := range idx {
+		idx[i] = i
+		total += sampleWeight(weights, i)
+	}
+	sort.Slice(idx, func(a, b int) bool { return math.Abs(residuals[idx[a]]) < math.Abs(residuals[idx[b]]) })
+	cumulative := 0.0
+	for _, i := range idx {
+		cumulative += sampleWeight(weights, i)
+		if cumulative >= total/2 {
+			return 1.4826 * math.Abs(residuals[i])
+		}
+	}
+	return 0
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// mutualInformationSamples is the number of samples drawn to estimate mutual
+// information involving continuous variables
+const mutualInformationSamples = 20000
+
+// MutualInformation returns I(X; Y) in nats under the network's distribution
+func MutualInformation(bn *BayesianNetwork, x, y string) (float64, error) {
+	return ConditionalMutualInformation(bn, x, y, nil)
+}
+
+// ConditionalMutualInformation returns I(X; Y | Z) in nats.
+//
+// For discrete variables it is computed exactly from the joint marginal
+// P(X, Y, Z). For continuous variables it is estimated from samples drawn from
+// the network, using the Gaussian formula on their covariance; this is exact
+// in the limit for linear-Gaussian networks. Mixing discrete and continuous
+// variables in one query is not supported.
+func ConditionalMutualInformation(bn *BayesianNetwork, x, y string, z []string) (float64, error) {
+	vars := append([]string{x, y}, z...)
+	seen := make(map[string]bool, len(vars))
+	discrete := 0
+	for _, v := range vars {
+		if seen[v] {
+			return 0, fmt.Errorf("variable %s appears more than once", v)
+		}
+		seen[v] = true
+
+		switch bn.VariableType[v] {
+		case Discrete:
+			discrete++
+		case Continuous:
+		default:
+			return 0, fmt.Errorf("variable %s: %w", v, ErrUnknownVariable)
+		}
+	}
+
+	switch discrete {
+	case len(vars):
+		return bn.discreteConditionalMI(x, y, z)
+	case 0:
+		return bn.gaussianConditionalMI(x, y, z)
+	default:
+		return 0, fmt.Errorf("mutual information between discrete and continuous variables: %w", ErrNotImplementedCLG)
+	}
+}
+
+// discreteConditionalMI computes H(XZ) + H(YZ) - H(XYZ) - H(Z)
+func (bn *BayesianNetwork) discreteConditionalMI(x, y string, z []string) (float64, error) {
+	joint, err := bn.discreteMarginal(append([]string{x, y}, z...))
+	if err != nil {
+		return 0, err
+	}
+
+	xz, err := joint.Marginalize([]string{y})
+	if err != nil {
+		return 0, err
+	}
+	yz, err := joint.Marginalize([]string{x})
+	if err != nil {
+		return 0, err
+	}
+
+	hz := 0.0
+	if len(z) > 0 {
+		zOnly, err := joint.Marginalize([]string{x, y})
+		if err != nil {
+			return 0, err
+		}
+		hz = factors.Entropy(zOnly)
+	}
+
+	mi := factors.Entropy(xz) + factors.Entropy(yz) - factors.Entropy(joint) - hz
+	return math.Max(mi, 0), nil
+}
+
+// discreteMarginal computes the joint marginal of discrete variables by
+// eliminating every other variable from the CPDs of their ancestors
+func (bn *BayesianNetwork) discreteMarginal(variables []string) (*DiscreteFactor, error) {
+	keep := make(map[string]bool, len(variables))
+	ancestral := make(map[string]bool)
+	for _, v := range variables {
+		keep[v] = true
+		ancestral[v] = true
+		for _, a := range bn.DAG.Ancestors(v) {
+			ancestral[a] = true
+		}
+	}
+
+	nodes := make([]string, 0, len(ancestral))
+	for v := range ancestral {
+		nodes = append(nodes, v)
+	}
+	sort.Strings(nodes)
+
+	factorList := make([]*DiscreteFactor, 0, len(nodes))
+	toEliminate := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		factor, err := bn.NodeFactor(node)
+		if err != nil {
+			return nil, err
+		}
+		factorList = append(factorList, factor)
+		if !keep[node] {
+			toEliminate = append(toEliminate, node)
+		}
+	}
+
+	for _, v := range toEliminate {
+		var err error
+		if factorList, err = factors.Eliminate(v, factorList, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := factors.Product(factorList, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := result.Normalize(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// gaussianConditionalMI estimates ½ log(|Σ_XZ| |Σ_YZ| / (|Σ_XYZ| |Σ_Z|)) from
+// simulated samples
+func (bn *BayesianNetwork) gaussianConditionalMI(x, y string, z []string) (float64, error) {
+	samples, err := bn.SimulateMixed(mutualInformationSamples, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	vars := append([]string{x, y}, z...)
+	data := mat.NewDense(len(samples), len(vars), nil)
+	for i, s := range samples {
+		for j, v := range vars {
+			data.Set(i, j, s.Continuous[v])
+		}
+	}
+
+	logDet := func(columns []int) (float64, error) {
+		if len(columns) == 0 {
+			return 0, nil
+		}
+		sub := mat.NewDense(len(samples), len(columns), nil)
+		for i := range samples {
+			for j, c := range columns {
+				sub.Set(i, j, data.At(i, c))
+			}
+		}
+		cov := mat.NewSymDense(len(columns), nil)
+		stat.CovarianceMatrix(cov, sub, nil)
+
+		var chol mat.Cholesky
+		if ok := chol.Factorize(cov); !ok {
+			return 0, fmt.Errorf("sample covariance is singular")
+		}
+		return chol.LogDet(), nil
+	}
+
+	zCols := make([]int, len(z))
+	for i := range z {
+		zCols[i] = i + 2
+	}
+
+	xz, err := logDet(append([]int{0}, zCols...))
+	if err != nil {
+		return 0, err
+	}
+	yz, err := logDet(append([]int{1}, zCols...))
+	if err != nil {
+		return 0, err
+	}
+	xyz, err := logDet(append([]int{0, 1}, zCols...))
+	if err != nil {
+		return 0, err
+	}
+	zOnly, err := logDet(zCols)
+	if err != nil {
+		return 0, err
+	}
+
+	return math.Max(0.5*(xz+yz-xyz-zOnly), 0), nil
+}
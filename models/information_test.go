@@ -0,0 +1,75 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+func TestDiscreteMutualInformation(t *testing.T) {
+	// A -> B -> C with B a noisy copy of A and C a noisy copy of B
+	bn, err := NewBayesianNetwork([][2]string{{"A", "B"}, {"B", "C"}})
+	if err != nil {
+		t.Fatalf("Failed to create network: %v", err)
+	}
+	cpdA, _ := factors.NewTabularCPD("A", 2, [][]float64{{0.5, 0.5}}, []string{}, map[string]int{})
+	cpdB, _ := factors.NewTabularCPD("B", 2, [][]float64{{0.9, 0.1}, {0.1, 0.9}}, []string{"A"}, map[string]int{"A": 2})
+	cpdC, _ := factors.NewTabularCPD("C", 2, [][]float64{{0.8, 0.2}, {0.2, 0.8}}, []string{"B"}, map[string]int{"B": 2})
+	for _, cpd := range []*factors.TabularCPD{cpdA, cpdB, cpdC} {
+		if err := bn.AddCPD(cpd); err != nil {
+			t.Fatalf("Failed to add CPD: %v", err)
+		}
+	}
+
+	// I(A; B) = log 2 - H(0.9) for a uniform input to a binary symmetric channel
+	h := func(p float64) float64 { return -p*math.Log(p) - (1-p)*math.Log(1-p) }
+	mi, err := MutualInformation(bn, "A", "B")
+	if err != nil {
+		t.Fatalf("MutualInformation failed: %v", err)
+	}
+	if want := math.Log(2) - h(0.9); math.Abs(mi-want) > 1e-9 {
+		t.Errorf("Expected I(A; B) = %f, got %f", want, mi)
+	}
+
+	// A and C are independent given B
+	cmi, err := ConditionalMutualInformation(bn, "A", "C", []string{"B"})
+	if err != nil {
+		t.Fatalf("ConditionalMutualInformation failed: %v", err)
+	}
+	if math.Abs(cmi) > 1e-9 {
+		t.Errorf("Expected I(A; C | B) = 0, got %f", cmi)
+	}
+}
+
+func TestGaussianMutualInformation(t *testing.T) {
+	bn, err := NewBayesianNetwork([][2]string{{"X", "Y"}, {"Y", "Z"}})
+	if err != nil {
+		t.Fatalf("Failed to create network: %v", err)
+	}
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 0, map[string]float64{}, 1)
+	cpdY, _ := factors.NewLinearGaussianCPD("Y", []string{"X"}, 0, map[string]float64{"X": 1}, 1)
+	cpdZ, _ := factors.NewLinearGaussianCPD("Z", []string{"Y"}, 0, map[string]float64{"Y": 1}, 1)
+	for _, cpd := range []*factors.LinearGaussianCPD{cpdX, cpdY, cpdZ} {
+		if err := bn.AddGaussianCPD(cpd); err != nil {
+			t.Fatalf("Failed to add CPD: %v", err)
+		}
+	}
+
+	// Corr(X, Y)² = 1/2, so I(X; Y) = ½ log 2
+	mi, err := MutualInformation(bn, "X", "Y")
+	if err != nil {
+		t.Fatalf("MutualInformation failed: %v", err)
+	}
+	if want := 0.5 * math.Log(2); math.Abs(mi-want) > 0.02 {
+		t.Errorf("Expected I(X; Y) ~ %f, got %f", want, mi)
+	}
+
+	cmi, err := ConditionalMutualInformation(bn, "X", "Z", []string{"Y"})
+	if err != nil {
+		t.Fatalf("ConditionalMutualInformation failed: %v", err)
+	}
+	if cmi > 0.01 {
+		t.Errorf("Expected I(X; Z | Y) ~ 0, got %f", cmi)
+	}
+}
@@ -0,0 +1,317 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// networkJSON is the on-disk form of a network. Parent cardinalities and
+// types are taken from the node list, so CPDs only name their parents.
+type networkJSON struct {
+	Nodes        []nodeJSON     `json:"nodes"`
+	Edges        [][2]string    `json:"edges"`
+	CPDs         []tabularJSON  `json:"cpds,omitempty"`
+	GaussianCPDs []gaussianJSON `json:"gaussian_cpds,omitempty"`
+	Ties         []tieJSON      `json:"ties,omitempty"`
+	Pipeline     *Pipeline      `json:"preprocessing,omitempty"`
+	Schema       *Schema        `json:"schema,omitempty"`
+}
+
+type nodeJSON struct {
+	Name        string       `json:"name"`
+	Type        VariableType `json:"type,omitempty"`
+	Cardinality int          `json:"cardinality,omitempty"`
+	States      []string     `json:"states,omitempty"`
+	Hidden      bool         `json:"hidden,omitempty"`
+}
+
+type tieJSON struct {
+	Nodes   []string   `json:"nodes"`
+	Parents [][]string `json:"parents"`
+}
+
+type tabularJSON struct {
+	Variable string      `json:"variable"`
+	Evidence []string    `json:"evidence,omitempty"`
+	Values   [][]float64 `json:"values"`
+}
+
+type gaussianJSON struct {
+	Variable     string                        `json:"variable"`
+	Parents      []string                      `json:"parents,omitempty"`
+	Intercept    float64                       `json:"intercept,omitempty"`
+	Coefficients map[string]float64            `json:"coefficients,omitempty"`
+	Variance     float64                       `json:"variance,omitempty"`
+	States       map[string]gaussianParamsJSON `json:"states,omitempty"`
+	LogVariance  map[string]float64            `json:"log_variance,omitempty"`
+	Basis        []basisTermJSON               `json:"basis,omitempty"`
+	BasisCoefs   []float64                     `json:"basis_coefficients,omitempty"`
+}
+
+type gaussianParamsJSON struct {
+	Mean              float64            `json:"mean"`
+	Variance          float64            `json:"variance"`
+	Coefficients      map[string]float64 `json:"coefficients,omitempty"`
+	BasisCoefficients []float64          `json:"basis_coefficients,omitempty"`
+}
+
+type basisTermJSON struct {
+	Kind    factors.BasisKind `json:"kind"`
+	Parents []string          `json:"parents"`
+	Knot    float64           `json:"knot,omitempty"`
+	Degree  int               `json:"degree,omitempty"`
+}
+
+// MarshalJSON encodes the structure, variable types, state names, CPDs,
+// preprocessing pipeline and schema. Custom discrete CPDs are written as
+// their tabular form; custom continuous and hybrid CPDs have no encoding and
+// make it fail. The network need not be complete: nodes without CPDs are
+// written with structure only.
+func (bn *BayesianNetwork) MarshalJSON() ([]byte, error) {
+	out := networkJSON{Edges: bn.DAG.Edges()}
+	sort.Slice(out.Edges, func(i, j int) bool {
+		if out.Edges[i][0] != out.Edges[j][0] {
+			return out.Edges[i][0] < out.Edges[j][0]
+		}
+		return out.Edges[i][1] < out.Edges[j][1]
+	})
+
+	for _, node := range bn.DAG.Nodes() {
+		n := nodeJSON{Name: node, Type: bn.VariableType[node], Hidden: bn.hidden[node]}
+		if n.Type == Discrete {
+			n.Cardinality = bn.Cardinality[node]
+			n.States = bn.StateNames(node)
+		}
+		out.Nodes = append(out.Nodes, n)
+
+		if _, ok := bn.DiscreteCPD(node); ok {
+			cpd, err := bn.AsTabularCPD(node)
+			if err != nil {
+				return nil, err
+			}
+			out.CPDs = append(out.CPDs, tabularJSON{
+				Variable: node,
+				Evidence: cpd.Evidence,
+				Values:   cpd.Values,
+			})
+		}
+
+		if _, ok := bn.HybridCPDs[node]; ok {
+			return nil, fmt.Errorf("hybrid CPD of %s has no JSON encoding", node)
+		}
+		if _, ok := bn.ContinuousCPDs[node]; ok {
+			return nil, fmt.Errorf("custom continuous CPD of %s has no JSON encoding", node)
+		}
+		if cpd, ok := bn.GaussianCPDs[node]; ok {
+			g := gaussianJSON{Variable: node, Parents: cpd.Parents, LogVariance: cpd.LogVariance}
+			for _, t := range cpd.Basis {
+				g.Basis = append(g.Basis, basisTermJSON(t))
+			}
+			if len(cpd.DiscreteParents()) == 0 {
+				g.Intercept = cpd.Intercept
+				g.Coefficients = cpd.Coefficients
+				g.Variance = cpd.Variance
+				g.BasisCoefs = cpd.BasisCoefficients
+			} else {
+				g.States = make(map[string]gaussianParamsJSON, len(cpd.DiscreteStates))
+				for key, params := range cpd.DiscreteStates {
+					g.States[key] = gaussianParamsJSON(params)
+				}
+			}
+			out.GaussianCPDs = append(out.GaussianCPDs, g)
+		}
+	}
+
+	for _, group := range bn.TiedGroups() {
+		out.Ties = append(out.Ties, tieJSON(group))
+	}
+	if bn.pipeline != nil {
+		out.Pipeline = bn.pipeline.Copy()
+	}
+	if bn.schema != nil {
+		out.Schema = bn.schema.Copy()
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON replaces the network with one decoded from MarshalJSON's
+// format, validating each CPD against the structure as AddCPD does
+func (bn *BayesianNetwork) UnmarshalJSON(data []byte) error {
+	var in networkJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	decoded, err := NewBayesianNetwork(in.Edges)
+	if err != nil {
+		return err
+	}
+
+	nodes := make(map[string]nodeJSON, len(in.Nodes))
+	for _, n := range in.Nodes {
+		decoded.DAG.AddNode(n.Name)
+		nodes[n.Name] = n
+		switch n.Type {
+		case Discrete:
+			decoded.Cardinality[n.Name] = n.Cardinality
+			decoded.VariableType[n.Name] = Discrete
+		case Continuous:
+			decoded.VariableType[n.Name] = Continuous
+		case "":
+		default:
+			return fmt.Errorf("unknown variable type %q for node %s", n.Type, n.Name)
+		}
+	}
+
+	for _, c := range in.CPDs {
+		evidenceCard := make(map[string]int, len(c.Evidence))
+		for _, e := range c.Evidence {
+			evidenceCard[e] = nodes[e].Cardinality
+		}
+		cpd, err := factors.NewTabularCPD(c.Variable, nodes[c.Variable].Cardinality, c.Values, c.Evidence, evidenceCard)
+		if err != nil {
+			return fmt.Errorf("CPD for %s: %w", c.Variable, err)
+		}
+		for _, v := range append([]string{c.Variable}, c.Evidence...) {
+			if states := nodes[v].States; len(states) > 0 {
+				if err := cpd.SetStateNames(v, states); err != nil {
+					return fmt.Errorf("CPD for %s: %w", c.Variable, err)
+				}
+			}
+		}
+		if err := decoded.AddCPD(cpd); err != nil {
+			return err
+		}
+	}
+
+	for _, g := range in.GaussianCPDs {
+		var discrete, continuous []string
+		cardinality := make(map[string]int)
+		for _, p := range g.Parents {
+			if nodes[p].Type == Discrete {
+				discrete = append(discrete, p)
+				cardinality[p] = nodes[p].Cardinality
+			} else {
+				continuous = append(continuous, p)
+			}
+		}
+
+		var cpd *factors.LinearGaussianCPD
+		if len(discrete) == 0 {
+			cpd, err = factors.NewLinearGaussianCPD(g.Variable, g.Parents, g.Intercept, g.Coefficients, g.Variance)
+		} else {
+			states := make(map[string]factors.GaussianParams, len(g.States))
+			for key, params := range g.States {
+				states[key] = factors.GaussianParams(params)
+			}
+			cpd, err = factors.NewCLGCPD(g.Variable, discrete, continuous, cardinality, states)
+		}
+		if err != nil {
+			return fmt.Errorf("CPD for %s: %w", g.Variable, err)
+		}
+		if cpd.Coefficients == nil {
+			cpd.Coefficients = make(map[string]float64)
+		}
+		for p := range g.LogVariance {
+			if cpd.ParentTypes[p] != "continuous" {
+				return fmt.Errorf("CPD for %s: log-variance coefficient for %s is not a continuous parent", g.Variable, p)
+			}
+		}
+		cpd.LogVariance = g.LogVariance
+		if len(g.Basis) > 0 {
+			basis := make([]factors.BasisTerm, len(g.Basis))
+			for i, t := range g.Basis {
+				basis[i] = factors.BasisTerm(t)
+			}
+			if err := cpd.SetBasis(basis); err != nil {
+				return err
+			}
+			cpd.BasisCoefficients = g.BasisCoefs
+			if err := cpd.CheckBasis(); err != nil {
+				return fmt.Errorf("CPD for %s: %w", g.Variable, err)
+			}
+		}
+		if err := decoded.AddGaussianCPD(cpd); err != nil {
+			return err
+		}
+	}
+
+	for _, n := range in.Nodes {
+		if n.Hidden {
+			if err := decoded.SetHidden(n.Name, n.Cardinality); err != nil {
+				return fmt.Errorf("hidden node %s: %w", n.Name, err)
+			}
+		}
+	}
+
+	for _, tie := range in.Ties {
+		if err := decoded.TieCPDs(tie.Nodes, tie.Parents); err != nil {
+			return err
+		}
+	}
+
+	decoded.pipeline = in.Pipeline
+	if in.Schema != nil {
+		schema, err := NewSchema(in.Schema.Variables...)
+		if err != nil {
+			return fmt.Errorf("schema: %w", err)
+		}
+		for _, v := range schema.Variables {
+			if v.Type == Discrete && decoded.DAG.HasNode(v.Name) {
+				if decoded.declared == nil {
+					decoded.declared = make(map[string]int)
+				}
+				decoded.declared[v.Name] = v.Cardinality
+			}
+		}
+		decoded.schema = schema
+	}
+
+	*bn = *decoded
+	return nil
+}
+
+// StateNames returns the state labels of a discrete variable from the first
+// CPD that declares them, else from the schema, or nil if its states are
+// unlabeled
+func (bn *BayesianNetwork) StateNames(variable string) []string {
+	if cpd, ok := bn.CPDs[variable]; ok {
+		if names, ok := cpd.StateNames[variable]; ok {
+			return append([]string(nil), names...)
+		}
+	}
+	for _, node := range bn.DAG.Nodes() {
+		if cpd, ok := bn.CPDs[node]; ok {
+			if names, ok := cpd.StateNames[variable]; ok {
+				return append([]string(nil), names...)
+			}
+		}
+	}
+	if bn.schema != nil {
+		if v, ok := bn.schema.Variable(variable); ok && len(v.States) > 0 {
+			return append([]string(nil), v.States...)
+		}
+	}
+	return nil
+}
+
+// AsTabularCPD returns the CPD of a discrete variable as a table. Tabular
+// CPDs are returned as is; custom CPDs are expanded over their parents.
+func (bn *BayesianNetwork) AsTabularCPD(variable string) (*factors.TabularCPD, error) {
+	if cpd, ok := bn.CPDs[variable]; ok {
+		return cpd, nil
+	}
+	custom, ok := bn.CustomCPDs[variable]
+	if !ok {
+		return nil, fmt.Errorf("discrete CPD for %s: %w", variable, ErrMissingCPD)
+	}
+	factor, err := custom.ToFactor()
+	if err != nil {
+		return nil, fmt.Errorf("CPD for %s: %w", variable, err)
+	}
+	return bn.conditionalCPD(variable, custom.GetParents(), factor)
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// LikelihoodWeighting draws n weighted samples from the posterior given the
+// evidence by forward sampling the unobserved nodes and weighting each
+// sample by the probability, or density, of the observed values given their
+// sampled parents. It works with every CPD type, including Poisson and
+// Gaussian process CPDs, so it answers queries exact inference cannot;
+// WeightedMarginal and WeightedMean turn the samples into posteriors.
+// Weights are relative, scaled so the largest is one.
+func (bn *BayesianNetwork) LikelihoodWeighting(n int, evidence Sample, seed int64) ([]WeightedSample, error) {
+	if err := bn.CheckModel(); err != nil {
+		return nil, err
+	}
+	if err := bn.ValidateEvidence(evidence.Discrete); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(evidence.Continuous))
+	for v := range evidence.Continuous {
+		names = append(names, v)
+	}
+	sort.Strings(names)
+	for _, v := range names {
+		if !bn.DAG.HasNode(v) {
+			return nil, fmt.Errorf("evidence variable %s: %w", v, ErrUnknownVariable)
+		}
+		if !bn.IsContinuous(v) {
+			return nil, fmt.Errorf("evidence variable %s is discrete but has a continuous value", v)
+		}
+	}
+
+	order, err := bn.DAG.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+	r := rand.New(rand.NewSource(seed))
+
+	samples := make([]WeightedSample, n)
+	maxLogWeight := math.Inf(-1)
+	for i := range samples {
+		sample := Sample{Discrete: make(map[string]int), Continuous: make(map[string]float64)}
+		logWeight := 0.0
+		for _, node := range order {
+			if state, ok := evidence.Discrete[node]; ok {
+				var lp float64
+				var err error
+				if hybrid, isHybrid := bn.HybridCPDs[node]; isHybrid {
+					lp, err = hybrid.LogProb(state, bn.parentValues(hybrid.GetParents(), sample))
+				} else {
+					cpd, _ := bn.DiscreteCPD(node)
+					lp, err = cpd.LogProb(state, sample.Discrete)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("probability of %s: %w", node, err)
+				}
+				sample.Discrete[node] = state
+				logWeight += lp
+				continue
+			}
+			if x, ok := evidence.Continuous[node]; ok {
+				cpd, _ := bn.ContinuousCPD(node)
+				lp, err := cpd.LogPDF(x, bn.parentValues(cpd.GetParents(), sample))
+				if err != nil {
+					return nil, fmt.Errorf("density of %s: %w", node, err)
+				}
+				sample.Continuous[node] = x
+				logWeight += lp
+				continue
+			}
+			if err := bn.sampleNode(node, sample, r); err != nil {
+				return nil, err
+			}
+		}
+		samples[i] = WeightedSample{Sample: sample, Weight: logWeight}
+		maxLogWeight = math.Max(maxLogWeight, logWeight)
+	}
+
+	if math.IsInf(maxLogWeight, -1) {
+		return nil, fmt.Errorf("every sample has zero weight: %w", ErrZeroProbability)
+	}
+	for i := range samples {
+		samples[i].Weight = math.Exp(samples[i].Weight - maxLogWeight)
+	}
+	return samples, nil
+}
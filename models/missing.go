@@ -0,0 +1,184 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MissingStrategy chooses how Fit and FitMixed treat rows with missing values
+type MissingStrategy string
+
+const (
+	// MissingAvailableCase fits each CPD from the rows that observe its node
+	// and parents, the default
+	MissingAvailableCase MissingStrategy = "available-case"
+	// MissingListwise drops every row that misses a node before fitting
+	MissingListwise MissingStrategy = "listwise"
+	// MissingEM fills missing values in with fractional counts by FitEM, for
+	// discrete networks only
+	MissingEM MissingStrategy = "em"
+)
+
+// MissingReport summarizes the missing values met by FitWithReport. Row
+// counts describe the data as given; under MissingEM the CPDs are fitted from
+// completed rows, so FamilyRows and EmptyConfigurations describe only the
+// observed values behind them.
+type MissingReport struct {
+	Strategy     MissingStrategy
+	Rows         int // Rows given
+	CompleteRows int // Rows observing every node that is not hidden
+	DroppedRows  int // Rows dropped by listwise deletion
+
+	// FamilyRows counts, for each node, the rows fitted that observe it and
+	// all its parents
+	FamilyRows map[string]int
+	// EmptyConfigurations counts, for each node with discrete parents, the
+	// configurations of those parents that no such row shows, whose
+	// distributions come from smoothing or pooling alone
+	EmptyConfigurations map[string]int
+
+	EM *EMResult // Result of EM under MissingEM
+}
+
+// SetMissingStrategy sets how Fit and FitMixed treat rows with missing
+// values. FitWeighted always uses available cases.
+func (bn *BayesianNetwork) SetMissingStrategy(strategy MissingStrategy) error {
+	switch strategy {
+	case MissingAvailableCase, MissingListwise, MissingEM:
+	default:
+		return fmt.Errorf("unknown missing-data strategy %q", strategy)
+	}
+	bn.missing = strategy
+	return nil
+}
+
+// MissingStrategy returns the strategy set by SetMissingStrategy
+func (bn *BayesianNetwork) MissingStrategy() MissingStrategy {
+	if bn.missing == "" {
+		return MissingAvailableCase
+	}
+	return bn.missing
+}
+
+// FitWithReport is FitMixed that also reports how missing values affected
+// the fit
+func (bn *BayesianNetwork) FitWithReport(data []Sample) (*MissingReport, error) {
+	if err := bn.checkSchema(data); err != nil {
+		return nil, err
+	}
+	report := &MissingReport{Strategy: bn.MissingStrategy(), Rows: len(data)}
+	complete := bn.completeRows(data)
+	report.CompleteRows = len(complete)
+	fitted := data
+	if report.Strategy == MissingListwise {
+		fitted = complete
+		report.DroppedRows = len(data) - len(complete)
+	}
+
+	if report.Strategy == MissingEM {
+		em, err := bn.fitMissingEM(data)
+		if err != nil {
+			return nil, err
+		}
+		report.EM = em
+	} else if err := bn.fitMixed(fitted, nil); err != nil {
+		return nil, err
+	}
+
+	report.FamilyRows = make(map[string]int)
+	report.EmptyConfigurations = make(map[string]int)
+	for _, node := range bn.DAG.Nodes() {
+		parents := bn.DAG.Parents(node)
+		sort.Strings(parents)
+		var discrete []string
+		configurations := 1
+		for _, p := range parents {
+			if bn.IsDiscrete(p) {
+				discrete = append(discrete, p)
+				configurations *= bn.Cardinality[p]
+			}
+		}
+		seen := make(map[string]bool)
+		for _, sample := range fitted {
+			if !sample.observes(node) {
+				continue
+			}
+			key, ok := "", true
+			for _, p := range parents {
+				if !sample.observes(p) {
+					ok = false
+					break
+				}
+				if bn.IsDiscrete(p) {
+					key += fmt.Sprintf("%d,", sample.Discrete[p])
+				}
+			}
+			if ok {
+				report.FamilyRows[node]++
+				seen[key] = true
+			}
+		}
+		if len(discrete) > 0 {
+			report.EmptyConfigurations[node] = configurations - len(seen)
+		}
+	}
+	return report, nil
+}
+
+// observes reports whether the sample holds a value of the variable
+func (s Sample) observes(variable string) bool {
+	if _, ok := s.Discrete[variable]; ok {
+		return true
+	}
+	_, ok := s.Continuous[variable]
+	return ok
+}
+
+// completeRows returns the rows that observe every node that is not hidden
+func (bn *BayesianNetwork) completeRows(data []Sample) []Sample {
+	nodes := bn.DAG.Nodes()
+	var complete []Sample
+	for _, sample := range data {
+		ok := true
+		for _, node := range nodes {
+			if !bn.hidden[node] && !sample.observes(node) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			complete = append(complete, sample)
+		}
+	}
+	return complete
+}
+
+// completeDiscreteRows is completeRows for discrete rows
+func (bn *BayesianNetwork) completeDiscreteRows(data []map[string]int) []map[string]int {
+	var complete []map[string]int
+	for _, row := range data {
+		ok := true
+		for _, node := range bn.DAG.Nodes() {
+			if _, observed := row[node]; !observed && !bn.hidden[node] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			complete = append(complete, row)
+		}
+	}
+	return complete
+}
+
+// fitMissingEM fits a discrete network from mixed samples by FitEM
+func (bn *BayesianNetwork) fitMissingEM(data []Sample) (*EMResult, error) {
+	rows := make([]map[string]int, len(data))
+	for i, sample := range data {
+		if len(sample.Continuous) > 0 {
+			return nil, fmt.Errorf("missing-data strategy %s: %w", MissingEM, ErrContinuousVariables)
+		}
+		rows[i] = sample.Discrete
+	}
+	return bn.FitEM(rows, EMOptions{})
+}
@@ -0,0 +1,431 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// cpdTolerance bounds the difference between CPD entries treated as equal
+// when deciding whether a CPD depends on a removed parent
+const cpdTolerance = 1e-12
+
+// AddNode adds an isolated variable to the network. Discrete variables need
+// a positive cardinality, which is declared as by DeclareCardinality; it is
+// ignored for continuous ones. A network with a schema declares the node in
+// it too.
+func (bn *BayesianNetwork) AddNode(node string, vtype VariableType, cardinality int) error {
+	if bn.DAG.HasNode(node) {
+		return fmt.Errorf("node %s already exists", node)
+	}
+
+	switch vtype {
+	case Discrete:
+		if cardinality < 1 {
+			return fmt.Errorf("discrete node %s needs a positive cardinality, got %d: %w",
+				node, cardinality, ErrCardinalityMismatch)
+		}
+		bn.Cardinality[node] = cardinality
+		if bn.declared == nil {
+			bn.declared = make(map[string]int)
+		}
+		bn.declared[node] = cardinality
+	case Continuous:
+	default:
+		return fmt.Errorf("unknown variable type %q for node %s", vtype, node)
+	}
+
+	bn.DAG.AddNode(node)
+	bn.VariableType[node] = vtype
+	if bn.schema != nil {
+		spec := VariableSpec{Name: node, Type: vtype}
+		if vtype == Discrete {
+			spec.Cardinality = cardinality
+		}
+		bn.schema.Variables = append(bn.schema.Variables, spec)
+		bn.schema.reindex()
+	}
+	return nil
+}
+
+// RemoveNode removes a variable, its CPD and its edges. The CPDs of its
+// children are updated as by RemoveEdge.
+func (bn *BayesianNetwork) RemoveNode(node string) error {
+	if !bn.DAG.HasNode(node) {
+		return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+	}
+
+	for _, child := range bn.DAG.Children(node) {
+		if err := bn.RemoveEdge(node, child); err != nil {
+			return err
+		}
+	}
+
+	bn.DAG.RemoveNode(node)
+	delete(bn.CPDs, node)
+	delete(bn.GaussianCPDs, node)
+	delete(bn.CustomCPDs, node)
+	delete(bn.ContinuousCPDs, node)
+	delete(bn.HybridCPDs, node)
+	delete(bn.VariableType, node)
+	delete(bn.Cardinality, node)
+	delete(bn.declared, node)
+	delete(bn.hidden, node)
+	delete(bn.heteroscedastic, node)
+	delete(bn.gaussianProcess, node)
+	delete(bn.basis, node)
+	delete(bn.count, node)
+	delete(bn.gammaShape, node)
+	delete(bn.beta, node)
+	delete(bn.ordinalProbit, node)
+	delete(bn.copula, node)
+	if bn.schema != nil {
+		bn.schema = bn.schema.restrict(func(v string) bool { return v != node })
+	}
+	bn.untie(node)
+	return nil
+}
+
+// AddEdge adds the edge parent -> child. The child's CPD is re-shaped to
+// take the new parent without changing its distribution: tabular rows and
+// Gaussian parameters are repeated for each state of a discrete parent, and
+// a continuous parent gets a zero coefficient. CPDs that cannot take the
+// parent (a tabular CPD with a continuous parent, a parent of unknown type,
+// or any custom CPD) are removed and must be specified again.
+func (bn *BayesianNetwork) AddEdge(parent, child string) error {
+	for _, node := range []string{parent, child} {
+		if !bn.DAG.HasNode(node) {
+			return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+		}
+	}
+	if bn.DAG.HasEdge(parent, child) {
+		return nil
+	}
+	if err := bn.DAG.AddEdge(parent, child); err != nil {
+		return err
+	}
+
+	ptype, known := bn.VariableType[parent]
+	card := bn.Cardinality[parent]
+	discreteParent := known && ptype == Discrete && card > 0
+
+	if cpd, ok := bn.CPDs[child]; ok {
+		if discreteParent {
+			bn.CPDs[child] = expandTabular(cpd, parent, card)
+		} else {
+			delete(bn.CPDs, child)
+		}
+	}
+	if cpd, ok := bn.GaussianCPDs[child]; ok {
+		switch {
+		case discreteParent:
+			bn.GaussianCPDs[child] = expandGaussianDiscrete(cpd, parent, card)
+		case known && ptype == Continuous:
+			expanded := cpd.Copy()
+			expanded.Parents = append(expanded.Parents, parent)
+			expanded.ParentTypes[parent] = "continuous"
+			bn.GaussianCPDs[child] = expanded
+		default:
+			delete(bn.GaussianCPDs, child)
+		}
+	}
+	delete(bn.CustomCPDs, child)
+	delete(bn.ContinuousCPDs, child)
+	delete(bn.HybridCPDs, child)
+	bn.untie(child)
+
+	return nil
+}
+
+// RemoveEdge removes the edge parent -> child. If the child's CPD does not
+// depend on the parent it is kept with the parent dropped; otherwise it is
+// removed, and CheckModel reports the missing CPD until it is specified
+// again or re-fitted.
+func (bn *BayesianNetwork) RemoveEdge(parent, child string) error {
+	if !bn.DAG.HasEdge(parent, child) {
+		return fmt.Errorf("no edge %s -> %s", parent, child)
+	}
+	bn.DAG.RemoveEdge(parent, child)
+
+	if cpd, ok := bn.CPDs[child]; ok {
+		if collapsed, ok := collapseTabular(cpd, parent); ok {
+			bn.CPDs[child] = collapsed
+		} else {
+			delete(bn.CPDs, child)
+		}
+	}
+	if cpd, ok := bn.GaussianCPDs[child]; ok {
+		if collapsed, ok := collapseGaussian(cpd, parent); ok {
+			bn.GaussianCPDs[child] = collapsed
+		} else {
+			delete(bn.GaussianCPDs, child)
+		}
+	}
+	delete(bn.CustomCPDs, child)
+	delete(bn.ContinuousCPDs, child)
+	delete(bn.HybridCPDs, child)
+	bn.untie(child)
+
+	return nil
+}
+
+// expandTabular appends a discrete parent as the fastest-varying evidence
+// variable, repeating each row for every parent state
+func expandTabular(cpd *factors.TabularCPD, parent string, card int) *factors.TabularCPD {
+	expanded := cpd.Copy()
+	values := make([][]float64, 0, len(cpd.Values)*card)
+	for _, row := range cpd.Values {
+		for s := 0; s < card; s++ {
+			values = append(values, append([]float64(nil), row...))
+		}
+	}
+	expanded.Evidence = append(expanded.Evidence, parent)
+	expanded.EvidenceCard[parent] = card
+	expanded.Values = values
+	return expanded
+}
+
+// collapseTabular drops an evidence variable whose state never changes the
+// row, reporting false if some row depends on it
+func collapseTabular(cpd *factors.TabularCPD, parent string) (*factors.TabularCPD, bool) {
+	pos := -1
+	for i, e := range cpd.Evidence {
+		if e == parent {
+			pos = i
+		}
+	}
+	if pos < 0 {
+		return cpd, true
+	}
+
+	newCount := len(cpd.Values) / cpd.EvidenceCard[parent]
+	values := make([][]float64, newCount)
+	states := make([]int, len(cpd.Evidence))
+	for r, row := range cpd.Values {
+		// Decode the row index; the last evidence variable varies fastest
+		rem := r
+		for j := len(cpd.Evidence) - 1; j >= 0; j-- {
+			card := cpd.EvidenceCard[cpd.Evidence[j]]
+			states[j] = rem % card
+			rem /= card
+		}
+		newIdx := 0
+		for j, e := range cpd.Evidence {
+			if j != pos {
+				newIdx = newIdx*cpd.EvidenceCard[e] + states[j]
+			}
+		}
+
+		if values[newIdx] == nil {
+			values[newIdx] = append([]float64(nil), row...)
+			continue
+		}
+		for k, p := range row {
+			if math.Abs(p-values[newIdx][k]) > cpdTolerance {
+				return nil, false
+			}
+		}
+	}
+
+	collapsed := cpd.Copy()
+	collapsed.Evidence = append(collapsed.Evidence[:pos:pos], collapsed.Evidence[pos+1:]...)
+	delete(collapsed.EvidenceCard, parent)
+	delete(collapsed.StateNames, parent)
+	collapsed.Values = values
+	return collapsed, true
+}
+
+// expandGaussianDiscrete appends a discrete parent, copying each parameter
+// set to every state of the parent
+func expandGaussianDiscrete(cpd *factors.LinearGaussianCPD, parent string, card int) *factors.LinearGaussianCPD {
+	expanded := cpd.Copy()
+
+	states := make(map[string]factors.GaussianParams)
+	if len(cpd.DiscreteParents()) == 0 {
+		for s := 0; s < card; s++ {
+			states[strconv.Itoa(s)] = factors.GaussianParams{
+				Mean:              cpd.Intercept,
+				Variance:          cpd.Variance,
+				Coefficients:      copyCoefficients(cpd.Coefficients),
+				BasisCoefficients: append([]float64(nil), cpd.BasisCoefficients...),
+			}
+		}
+	} else {
+		for key, params := range cpd.DiscreteStates {
+			for s := 0; s < card; s++ {
+				params.Coefficients = copyCoefficients(params.Coefficients)
+				params.BasisCoefficients = append([]float64(nil), params.BasisCoefficients...)
+				states[key+","+strconv.Itoa(s)] = params
+			}
+		}
+	}
+
+	expanded.Parents = append(expanded.Parents, parent)
+	expanded.ParentTypes[parent] = "discrete"
+	expanded.Cardinality[parent] = card
+	expanded.DiscreteStates = states
+	expanded.BasisCoefficients = nil
+	return expanded
+}
+
+// collapseGaussian drops a parent the CPD does not depend on: a continuous
+// parent with zero coefficients, including those of the basis terms that
+// read it, or a discrete parent whose states all share the same parameters.
+// It reports false if the CPD depends on the parent.
+func collapseGaussian(cpd *factors.LinearGaussianCPD, parent string) (*factors.LinearGaussianCPD, bool) {
+	ptype, ok := cpd.ParentTypes[parent]
+	if !ok {
+		return cpd, true
+	}
+
+	collapsed := cpd.Copy()
+	for i, p := range collapsed.Parents {
+		if p == parent {
+			collapsed.Parents = append(collapsed.Parents[:i:i], collapsed.Parents[i+1:]...)
+			break
+		}
+	}
+	delete(collapsed.ParentTypes, parent)
+
+	if ptype == "continuous" {
+		if math.Abs(cpd.Coefficients[parent]) > cpdTolerance || math.Abs(cpd.LogVariance[parent]) > cpdTolerance {
+			return nil, false
+		}
+		for _, params := range cpd.DiscreteStates {
+			if math.Abs(params.Coefficients[parent]) > cpdTolerance {
+				return nil, false
+			}
+		}
+		if !dropBasisTerms(collapsed, parent) {
+			return nil, false
+		}
+		delete(collapsed.Coefficients, parent)
+		delete(collapsed.LogVariance, parent)
+		for key, params := range collapsed.DiscreteStates {
+			delete(params.Coefficients, parent)
+			collapsed.DiscreteStates[key] = params
+		}
+		return collapsed, true
+	}
+
+	pos := 0
+	for i, p := range cpd.DiscreteParents() {
+		if p == parent {
+			pos = i
+		}
+	}
+
+	states := make(map[string]factors.GaussianParams)
+	for key, params := range cpd.DiscreteStates {
+		parts := strings.Split(key, ",")
+		newKey := strings.Join(append(parts[:pos:pos], parts[pos+1:]...), ",")
+		if existing, seen := states[newKey]; seen {
+			if !sameGaussianParams(existing, params) {
+				return nil, false
+			}
+			continue
+		}
+		states[newKey] = params
+	}
+	delete(collapsed.Cardinality, parent)
+
+	if len(collapsed.DiscreteParents()) > 0 {
+		collapsed.DiscreteStates = states
+		return collapsed, true
+	}
+
+	// No discrete parents remain, so the single parameter set moves to the
+	// plain linear Gaussian fields
+	params := states[""]
+	collapsed.Intercept = params.Mean
+	collapsed.Variance = params.Variance
+	collapsed.Coefficients = copyCoefficients(params.Coefficients)
+	collapsed.BasisCoefficients = params.BasisCoefficients
+	collapsed.DiscreteStates = nil
+	return collapsed, true
+}
+
+// dropBasisTerms removes the basis terms of cpd that read parent, reporting
+// false if any of them has a non-zero coefficient
+func dropBasisTerms(cpd *factors.LinearGaussianCPD, parent string) bool {
+	discrete := len(cpd.DiscreteParents()) > 0
+	var kept []int
+	for k, t := range cpd.Basis {
+		reads := false
+		for _, p := range t.Parents {
+			reads = reads || p == parent
+		}
+		if !reads {
+			kept = append(kept, k)
+			continue
+		}
+		if !discrete && math.Abs(cpd.BasisCoefficients[k]) > cpdTolerance {
+			return false
+		}
+		for _, params := range cpd.DiscreteStates {
+			if math.Abs(params.BasisCoefficients[k]) > cpdTolerance {
+				return false
+			}
+		}
+	}
+	if len(kept) == len(cpd.Basis) {
+		return true
+	}
+
+	pick := func(coefficients []float64) []float64 {
+		out := make([]float64, len(kept))
+		for i, k := range kept {
+			out[i] = coefficients[k]
+		}
+		return out
+	}
+	basis := make([]factors.BasisTerm, len(kept))
+	for i, k := range kept {
+		basis[i] = cpd.Basis[k]
+	}
+	cpd.Basis = basis
+	if !discrete {
+		cpd.BasisCoefficients = pick(cpd.BasisCoefficients)
+	}
+	for key, params := range cpd.DiscreteStates {
+		params.BasisCoefficients = pick(params.BasisCoefficients)
+		cpd.DiscreteStates[key] = params
+	}
+	return true
+}
+
+func sameGaussianParams(a, b factors.GaussianParams) bool {
+	if math.Abs(a.Mean-b.Mean) > cpdTolerance || math.Abs(a.Variance-b.Variance) > cpdTolerance {
+		return false
+	}
+	for p, c := range a.Coefficients {
+		if math.Abs(c-b.Coefficients[p]) > cpdTolerance {
+			return false
+		}
+	}
+	for p, c := range b.Coefficients {
+		if math.Abs(c-a.Coefficients[p]) > cpdTolerance {
+			return false
+		}
+	}
+	if len(a.BasisCoefficients) != len(b.BasisCoefficients) {
+		return false
+	}
+	for k, c := range a.BasisCoefficients {
+		if math.Abs(c-b.BasisCoefficients[k]) > cpdTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func copyCoefficients(coefficients map[string]float64) map[string]float64 {
+	result := make(map[string]float64, len(coefficients))
+	for p, c := range coefficients {
+		result[p] = c
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// SetOrdinalProbit makes FitMixed and FitWeighted learn an ordinal probit CPD
+// for node: its states are ordered grades cut from a latent linear-Gaussian
+// score of the continuous parents at learned thresholds. The node's values
+// are held in Sample.Discrete and its parents must be continuous when it is
+// fitted. The number of states is the cardinality fixed by
+// DeclareCardinality, or one more than the largest state seen.
+func (bn *BayesianNetwork) SetOrdinalProbit(node string) error {
+	if !bn.DAG.HasNode(node) {
+		return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+	}
+	if bn.IsContinuous(node) {
+		return fmt.Errorf("cannot fit an ordinal CPD for continuous node %s", node)
+	}
+	if bn.ordinalProbit == nil {
+		bn.ordinalProbit = make(map[string]bool)
+	}
+	bn.ordinalProbit[node] = true
+	return nil
+}
+
+// fitOrdinalProbit learns the ordinal probit CPD of node from the rows that
+// hold it and all its parents
+func (bn *BayesianNetwork) fitOrdinalProbit(node string, data []Sample, weights []float64) error {
+	parents := bn.DAG.Parents(node)
+	sort.Strings(parents)
+	for _, p := range parents {
+		if bn.IsDiscrete(p) {
+			return fmt.Errorf("ordinal probit CPD for %s: parent %s is discrete", node, p)
+		}
+	}
+
+	var inputs [][]float64
+	var states []int
+	var rowWeights []float64
+	cardinality := bn.declared[node]
+	for i, sample := range data {
+		s, ok := sample.Discrete[node]
+		if !ok {
+			continue
+		}
+		row := make([]float64, len(parents))
+		valid := true
+		for j, p := range parents {
+			if row[j], valid = sample.Continuous[p]; !valid {
+				break
+			}
+		}
+		if !valid {
+			continue
+		}
+		if s < 0 || (bn.declared[node] > 0 && s >= bn.declared[node]) {
+			return fmt.Errorf("sample %d: state %d of %s out of range: %w", i, s, node, ErrInvalidState)
+		}
+		if s >= cardinality {
+			cardinality = s + 1
+		}
+		inputs = append(inputs, row)
+		states = append(states, s)
+		rowWeights = append(rowWeights, sampleWeight(weights, i))
+	}
+	if len(states) < len(parents)+cardinality {
+		return fmt.Errorf("insufficient data for learning ordinal probit CPD for %s", node)
+	}
+
+	cpd, err := factors.FitOrdinalProbitCPD(node, parents, inputs, states, rowWeights, cardinality)
+	if err != nil {
+		return fmt.Errorf("ordinal probit CPD for %s: %w", node, err)
+	}
+	if bn.HybridCPDs == nil {
+		bn.HybridCPDs = make(map[string]factors.HybridCPD)
+	}
+	bn.HybridCPDs[node] = cpd
+	delete(bn.CPDs, node)
+	delete(bn.CustomCPDs, node)
+	bn.VariableType[node] = Discrete
+	bn.Cardinality[node] = cardinality
+	return nil
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// SetCount declares node a count variable: its values, held in
+// Sample.Continuous, are non-negative integers, and FitMixed and FitWeighted
+// learn a log-linear Poisson CPD for it instead of a Gaussian one. The
+// node's parents must be continuous when it is fitted. Poisson CPDs take
+// part in simulation, density evaluation and likelihood weighting but not in
+// exact Gaussian inference.
+func (bn *BayesianNetwork) SetCount(node string) error {
+	if !bn.DAG.HasNode(node) {
+		return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+	}
+	if bn.IsDiscrete(node) {
+		return fmt.Errorf("cannot make discrete node %s a count", node)
+	}
+	if bn.count == nil {
+		bn.count = make(map[string]bool)
+	}
+	bn.count[node] = true
+	return nil
+}
+
+// IsCount reports whether node was declared a count by SetCount
+func (bn *BayesianNetwork) IsCount(node string) bool {
+	return bn.count[node]
+}
+
+// learnPoissonCPD fits log λ = β₀ + Σᵢ βᵢYᵢ by maximum likelihood to the rows
+// that hold the variable and all its parents
+func (bn *BayesianNetwork) learnPoissonCPD(variable string, data []Sample, weights []float64) (*factors.PoissonCPD, error) {
+	parents := bn.DAG.Parents(variable)
+	sort.Strings(parents)
+	for _, p := range parents {
+		if bn.IsDiscrete(p) {
+			return nil, fmt.Errorf("Poisson CPD for %s: parent %s is discrete", variable, p)
+		}
+	}
+
+	var counts, rowWeights []float64
+	var rows [][]float64 // Each row is [1, y1, y2, ..., yn]
+	for i, sample := range data {
+		c, ok := sample.Continuous[variable]
+		if !ok {
+			continue
+		}
+		if c < 0 || c != math.Floor(c) {
+			return nil, fmt.Errorf("sample %d: count %s has value %v, expected a non-negative integer", i, variable, c)
+		}
+		row := []float64{1}
+		valid := true
+		for _, p := range parents {
+			v, ok := sample.Continuous[p]
+			if !ok {
+				valid = false
+				break
+			}
+			row = append(row, v)
+		}
+		if valid {
+			counts = append(counts, c)
+			rows = append(rows, row)
+			rowWeights = append(rowWeights, sampleWeight(weights, i))
+		}
+	}
+	if len(counts) < len(parents)+1 {
+		return nil, fmt.Errorf("insufficient data for learning Poisson CPD for %s", variable)
+	}
+
+	coeffs, err := bn.fitLogLink(rows, counts, rowWeights, poissonFamily)
+	if err != nil {
+		return nil, fmt.Errorf("Poisson CPD for %s: %w", variable, err)
+	}
+	parentCoeffs := make(map[string]float64, len(parents))
+	for i, p := range parents {
+		parentCoeffs[p] = coeffs[i+1]
+	}
+	return factors.NewPoissonCPD(variable, parents, coeffs[0], parentCoeffs)
+}
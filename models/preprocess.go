@@ -0,0 +1,579 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Record is a raw observation before preprocessing, keyed by column. Values
+// are numbers (float64 or any integer or float type) or category labels
+// (string); a nil, NaN or absent value is missing.
+type Record map[string]interface{}
+
+// PreprocessKind names the transform of a PreprocessStep
+type PreprocessKind string
+
+const (
+	PreprocessImpute      PreprocessKind = "impute"      // Fill missing values
+	PreprocessStandardize PreprocessKind = "standardize" // Shift and scale numbers to mean 0 and variance 1
+	PreprocessDiscretize  PreprocessKind = "discretize"  // Cut numbers into equal-frequency bins
+	PreprocessEncode      PreprocessKind = "encode"      // Map category labels to states
+)
+
+// ImputeStrategy chooses the value an impute step fills in
+type ImputeStrategy string
+
+const (
+	ImputeMean   ImputeStrategy = "mean"   // Mean of the observed numbers
+	ImputeMedian ImputeStrategy = "median" // Median of the observed numbers
+	ImputeMode   ImputeStrategy = "mode"   // Most frequent observed value, number or label
+)
+
+// PreprocessStep is one transform of a Pipeline, applied to the given
+// columns. The constructors set what the step does; Pipeline.Fit learns
+// its parameters from training records.
+type PreprocessStep struct {
+	Kind     PreprocessKind `json:"kind"`
+	Columns  []string       `json:"columns"`
+	Strategy ImputeStrategy `json:"strategy,omitempty"` // Impute
+	Bins     int            `json:"bins,omitempty"`     // Discretize
+
+	// Fitted parameters
+	FillNumbers map[string]float64   `json:"fill_numbers,omitempty"` // Impute: numbers filled in
+	FillLabels  map[string]string    `json:"fill_labels,omitempty"`  // Impute: labels filled in
+	Means       map[string]float64   `json:"means,omitempty"`        // Standardize
+	StdDevs     map[string]float64   `json:"std_devs,omitempty"`     // Standardize
+	Cuts        map[string][]float64 `json:"cuts,omitempty"`         // Discretize: inner bin boundaries, ascending
+	Labels      map[string][]string  `json:"labels,omitempty"`       // Encode: labels ordered by state
+}
+
+// Impute fills missing values of the columns by the given strategy
+func Impute(strategy ImputeStrategy, columns ...string) PreprocessStep {
+	return PreprocessStep{Kind: PreprocessImpute, Strategy: strategy, Columns: columns}
+}
+
+// Standardize shifts and scales numeric columns to mean 0 and variance 1
+func Standardize(columns ...string) PreprocessStep {
+	return PreprocessStep{Kind: PreprocessStandardize, Columns: columns}
+}
+
+// Discretize cuts numeric columns into at most bins equal-frequency bins,
+// making them discrete. Values beyond the training range fall in the end
+// bins.
+func Discretize(bins int, columns ...string) PreprocessStep {
+	return PreprocessStep{Kind: PreprocessDiscretize, Bins: bins, Columns: columns}
+}
+
+// Encode maps the category labels of the columns to states, assigning codes
+// in sorted label order, making them discrete. Labels not seen in training
+// are rejected.
+func Encode(columns ...string) PreprocessStep {
+	return PreprocessStep{Kind: PreprocessEncode, Columns: columns}
+}
+
+// Pipeline is a sequence of preprocessing steps that turns raw records into
+// samples. Each step is fitted on the output of the steps before it, and the
+// same fitted steps are replayed on every record afterwards. After the
+// steps, integers become discrete values and other numbers continuous ones.
+type Pipeline struct {
+	Steps  []PreprocessStep `json:"steps"`
+	Fitted bool             `json:"fitted"`
+}
+
+// NewPipeline creates an unfitted pipeline of the given steps
+func NewPipeline(steps ...PreprocessStep) *Pipeline {
+	return &Pipeline{Steps: steps}
+}
+
+// Fit learns the parameters of every step from training records
+func (p *Pipeline) Fit(records []Record) error {
+	current := make([]Record, len(records))
+	for i, r := range records {
+		current[i] = copyRecord(r)
+	}
+	for k := range p.Steps {
+		step := &p.Steps[k]
+		if err := step.fit(current); err != nil {
+			return fmt.Errorf("preprocessing step %d (%s): %w", k, step.Kind, err)
+		}
+		for i, r := range current {
+			if err := step.apply(r); err != nil {
+				return fmt.Errorf("preprocessing step %d (%s), record %d: %w", k, step.Kind, i, err)
+			}
+		}
+	}
+	p.Fitted = true
+	return nil
+}
+
+// Transform applies the fitted steps to a record and converts the result to
+// a sample
+func (p *Pipeline) Transform(record Record) (Sample, error) {
+	if !p.Fitted {
+		return Sample{}, fmt.Errorf("preprocessing pipeline is not fitted")
+	}
+	r := copyRecord(record)
+	for k := range p.Steps {
+		if err := p.Steps[k].apply(r); err != nil {
+			return Sample{}, fmt.Errorf("preprocessing step %d (%s): %w", k, p.Steps[k].Kind, err)
+		}
+	}
+	return recordSample(r, nil)
+}
+
+// TransformAll applies Transform to every record
+func (p *Pipeline) TransformAll(records []Record) ([]Sample, error) {
+	samples := make([]Sample, len(records))
+	for i, r := range records {
+		s, err := p.Transform(r)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		samples[i] = s
+	}
+	return samples, nil
+}
+
+// Copy creates a deep copy
+func (p *Pipeline) Copy() *Pipeline {
+	out := &Pipeline{Steps: make([]PreprocessStep, len(p.Steps)), Fitted: p.Fitted}
+	for k, s := range p.Steps {
+		c := s
+		c.Columns = append([]string(nil), s.Columns...)
+		c.FillNumbers = copyFloatMap(s.FillNumbers)
+		c.Means = copyFloatMap(s.Means)
+		c.StdDevs = copyFloatMap(s.StdDevs)
+		if s.FillLabels != nil {
+			c.FillLabels = make(map[string]string, len(s.FillLabels))
+			for col, v := range s.FillLabels {
+				c.FillLabels[col] = v
+			}
+		}
+		if s.Cuts != nil {
+			c.Cuts = make(map[string][]float64, len(s.Cuts))
+			for col, v := range s.Cuts {
+				c.Cuts[col] = append([]float64(nil), v...)
+			}
+		}
+		if s.Labels != nil {
+			c.Labels = make(map[string][]string, len(s.Labels))
+			for col, v := range s.Labels {
+				c.Labels[col] = append([]string(nil), v...)
+			}
+		}
+		out.Steps[k] = c
+	}
+	return out
+}
+
+// fit learns the step's parameters from the records as transformed by the
+// steps before it
+func (s *PreprocessStep) fit(records []Record) error {
+	switch s.Kind {
+	case PreprocessImpute:
+		s.FillNumbers = make(map[string]float64)
+		s.FillLabels = make(map[string]string)
+		for _, col := range s.Columns {
+			if err := s.fitImpute(col, records); err != nil {
+				return err
+			}
+		}
+	case PreprocessStandardize:
+		s.Means = make(map[string]float64)
+		s.StdDevs = make(map[string]float64)
+		for _, col := range s.Columns {
+			values, err := numericColumn(records, col)
+			if err != nil {
+				return err
+			}
+			mean, variance := 0.0, 0.0
+			for _, v := range values {
+				mean += v
+			}
+			mean /= float64(len(values))
+			for _, v := range values {
+				variance += (v - mean) * (v - mean)
+			}
+			sd := math.Sqrt(variance / float64(len(values)))
+			if sd == 0 {
+				sd = 1
+			}
+			s.Means[col] = mean
+			s.StdDevs[col] = sd
+		}
+	case PreprocessDiscretize:
+		if s.Bins < 2 {
+			return fmt.Errorf("need at least 2 bins, got %d", s.Bins)
+		}
+		s.Cuts = make(map[string][]float64)
+		for _, col := range s.Columns {
+			values, err := numericColumn(records, col)
+			if err != nil {
+				return err
+			}
+			sort.Float64s(values)
+			var cuts []float64
+			for b := 1; b < s.Bins; b++ {
+				cut := values[b*len(values)/s.Bins]
+				// Repeated values can make quantiles coincide
+				if cut > values[0] && (len(cuts) == 0 || cut > cuts[len(cuts)-1]) {
+					cuts = append(cuts, cut)
+				}
+			}
+			s.Cuts[col] = cuts
+		}
+	case PreprocessEncode:
+		s.Labels = make(map[string][]string)
+		for _, col := range s.Columns {
+			seen := make(map[string]bool)
+			var labels []string
+			for i, r := range records {
+				v, ok := r[col]
+				if !ok || isMissing(v) {
+					continue
+				}
+				label, ok := v.(string)
+				if !ok {
+					return fmt.Errorf("record %d: %s has value %v, expected a label", i, col, v)
+				}
+				if !seen[label] {
+					seen[label] = true
+					labels = append(labels, label)
+				}
+			}
+			if len(labels) == 0 {
+				return fmt.Errorf("column %s has no labels", col)
+			}
+			sort.Strings(labels)
+			s.Labels[col] = labels
+		}
+	default:
+		return fmt.Errorf("unknown preprocessing step %q", s.Kind)
+	}
+	return nil
+}
+
+// fitImpute learns the value filled into a column
+func (s *PreprocessStep) fitImpute(col string, records []Record) error {
+	if s.Strategy == ImputeMode {
+		// Count numbers and labels apart so 1 and "1" stay distinct
+		numbers := make(map[float64]int)
+		labels := make(map[string]int)
+		for _, r := range records {
+			v, ok := r[col]
+			if !ok || isMissing(v) {
+				continue
+			}
+			if label, ok := v.(string); ok {
+				labels[label]++
+			} else if x, ok := toFloat(v); ok {
+				numbers[x]++
+			}
+		}
+		best, bestCount, bestLabel, isLabel := 0.0, 0, "", false
+		for x, c := range numbers {
+			if c > bestCount || (c == bestCount && x < best) {
+				best, bestCount = x, c
+			}
+		}
+		for label, c := range labels {
+			if c > bestCount || (c == bestCount && isLabel && label < bestLabel) {
+				bestLabel, bestCount, isLabel = label, c, true
+			}
+		}
+		switch {
+		case bestCount == 0:
+			return fmt.Errorf("column %s has no observed values", col)
+		case isLabel:
+			s.FillLabels[col] = bestLabel
+		default:
+			s.FillNumbers[col] = best
+		}
+		return nil
+	}
+
+	values, err := numericColumn(records, col)
+	if err != nil {
+		return err
+	}
+	switch s.Strategy {
+	case ImputeMean:
+		mean := 0.0
+		for _, v := range values {
+			mean += v
+		}
+		s.FillNumbers[col] = mean / float64(len(values))
+	case ImputeMedian:
+		sort.Float64s(values)
+		m := len(values) / 2
+		if len(values)%2 == 0 {
+			s.FillNumbers[col] = (values[m-1] + values[m]) / 2
+		} else {
+			s.FillNumbers[col] = values[m]
+		}
+	default:
+		return fmt.Errorf("unknown impute strategy %q", s.Strategy)
+	}
+	return nil
+}
+
+// apply transforms a record in place with the fitted step
+func (s *PreprocessStep) apply(r Record) error {
+	for _, col := range s.Columns {
+		v, ok := r[col]
+		missing := !ok || isMissing(v)
+		if s.Kind == PreprocessImpute {
+			if !missing {
+				continue
+			}
+			if label, ok := s.FillLabels[col]; ok {
+				r[col] = label
+			} else if x, ok := s.FillNumbers[col]; ok {
+				r[col] = x
+			}
+			continue
+		}
+		if missing {
+			delete(r, col)
+			continue
+		}
+
+		switch s.Kind {
+		case PreprocessStandardize:
+			x, ok := toFloat(v)
+			if !ok {
+				return fmt.Errorf("%s has value %v, expected a number", col, v)
+			}
+			r[col] = (x - s.Means[col]) / s.StdDevs[col]
+		case PreprocessDiscretize:
+			x, ok := toFloat(v)
+			if !ok {
+				return fmt.Errorf("%s has value %v, expected a number", col, v)
+			}
+			cuts := s.Cuts[col]
+			r[col] = sort.Search(len(cuts), func(b int) bool { return x < cuts[b] })
+		case PreprocessEncode:
+			label, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("%s has value %v, expected a label", col, v)
+			}
+			state := sort.SearchStrings(s.Labels[col], label)
+			if state == len(s.Labels[col]) || s.Labels[col][state] != label {
+				return fmt.Errorf("unknown label %q of %s: %w", label, col, ErrInvalidState)
+			}
+			r[col] = state
+		}
+	}
+	return nil
+}
+
+// SetPipeline attaches a preprocessing pipeline to the network. FitRecords
+// fits it and the network together, and Preprocess, PredictRecords and
+// record queries replay it, so training and serving data are transformed
+// alike. A nil pipeline removes it.
+func (bn *BayesianNetwork) SetPipeline(p *Pipeline) {
+	bn.pipeline = p
+}
+
+// Pipeline returns the network's preprocessing pipeline, or nil
+func (bn *BayesianNetwork) Pipeline() *Pipeline {
+	return bn.pipeline
+}
+
+// FitRecords fits the network's pipeline to raw records, transforms them and
+// fits the CPDs with FitMixed. Encoded columns keep their labels as state
+// names.
+func (bn *BayesianNetwork) FitRecords(records []Record) error {
+	if bn.pipeline == nil {
+		samples, err := bn.preprocessAll(records)
+		if err != nil {
+			return err
+		}
+		return bn.FitMixed(samples)
+	}
+	if err := bn.pipeline.Fit(records); err != nil {
+		return err
+	}
+	samples, err := bn.preprocessAll(records)
+	if err != nil {
+		return err
+	}
+	if err := bn.FitMixed(samples); err != nil {
+		return err
+	}
+
+	for _, step := range bn.pipeline.Steps {
+		for col, labels := range step.Labels {
+			if cpd, ok := bn.CPDs[col]; ok && cpd.VariableCard == len(labels) {
+				if err := cpd.SetStateNames(col, append([]string(nil), labels...)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Preprocess turns a raw record into a sample with the network's fitted
+// pipeline, or directly when it has none. Whole numbers given for discrete
+// nodes become states, labels the pipeline leaves are resolved against the
+// state names, and columns that are not nodes are dropped.
+func (bn *BayesianNetwork) Preprocess(record Record) (Sample, error) {
+	r := copyRecord(record)
+	if bn.pipeline != nil {
+		if !bn.pipeline.Fitted {
+			return Sample{}, fmt.Errorf("preprocessing pipeline is not fitted")
+		}
+		for k := range bn.pipeline.Steps {
+			if err := bn.pipeline.Steps[k].apply(r); err != nil {
+				return Sample{}, fmt.Errorf("preprocessing step %d (%s): %w", k, bn.pipeline.Steps[k].Kind, err)
+			}
+		}
+	}
+	for col, v := range r {
+		if !bn.DAG.HasNode(col) {
+			delete(r, col)
+			continue
+		}
+		if label, ok := v.(string); ok && bn.IsDiscrete(col) {
+			state := -1
+			for i, name := range bn.StateNames(col) {
+				if name == label {
+					state = i
+					break
+				}
+			}
+			if state < 0 {
+				return Sample{}, fmt.Errorf("unknown label %q of %s: %w", label, col, ErrInvalidState)
+			}
+			r[col] = state
+		}
+	}
+	return recordSample(r, bn.VariableType)
+}
+
+// PredictRecords preprocesses partial raw records and predicts their missing
+// discrete values as Predict does
+func (bn *BayesianNetwork) PredictRecords(records []Record) (map[string][]int, error) {
+	observations := make([]map[string]int, len(records))
+	for i, r := range records {
+		s, err := bn.Preprocess(r)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		if len(s.Continuous) > 0 {
+			return nil, fmt.Errorf("record %d: Predict takes discrete observations: %w", i, ErrContinuousVariables)
+		}
+		observations[i] = s.Discrete
+	}
+	return bn.Predict(observations)
+}
+
+func (bn *BayesianNetwork) preprocessAll(records []Record) ([]Sample, error) {
+	samples := make([]Sample, len(records))
+	for i, r := range records {
+		s, err := bn.Preprocess(r)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		samples[i] = s
+	}
+	return samples, nil
+}
+
+// recordSample converts a transformed record to a sample: integers are
+// discrete and other numbers continuous, except that whole numbers for
+// variables the types declare discrete are states
+func recordSample(r Record, types map[string]VariableType) (Sample, error) {
+	s := Sample{Discrete: make(map[string]int), Continuous: make(map[string]float64)}
+	for col, v := range r {
+		if isMissing(v) {
+			continue
+		}
+		switch x := v.(type) {
+		case int:
+			s.Discrete[col] = x
+		case int64:
+			s.Discrete[col] = int(x)
+		case int32:
+			s.Discrete[col] = int(x)
+		case string:
+			return Sample{}, fmt.Errorf("%s has label %q; encode it first", col, x)
+		default:
+			f, ok := toFloat(v)
+			if !ok {
+				return Sample{}, fmt.Errorf("%s has unsupported value %v of type %T", col, v, v)
+			}
+			if types[col] == Discrete && f == math.Trunc(f) {
+				s.Discrete[col] = int(f)
+			} else {
+				s.Continuous[col] = f
+			}
+		}
+	}
+	return s, nil
+}
+
+// numericColumn returns the observed numbers of a column
+func numericColumn(records []Record, col string) ([]float64, error) {
+	var values []float64
+	for i, r := range records {
+		v, ok := r[col]
+		if !ok || isMissing(v) {
+			continue
+		}
+		x, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("record %d: %s has value %v, expected a number", i, col, v)
+		}
+		values = append(values, x)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("column %s has no observed values", col)
+	}
+	return values, nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	}
+	return 0, false
+}
+
+func isMissing(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	x, ok := v.(float64)
+	return ok && math.IsNaN(x)
+}
+
+func copyRecord(r Record) Record {
+	out := make(Record, len(r))
+	for k, v := range r {
+		out[k] = v
+	}
+	return out
+}
+
+func copyFloatMap(m map[string]float64) map[string]float64 {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]float64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
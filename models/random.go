@@ -0,0 +1,34 @@
+package models
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+)
+
+// CryptoSource is a rand.Source64 backed by crypto/rand. It cannot be seeded,
+// so runs using it are not reproducible; use it when draws must not be
+// predictable. Wrap it with rand.New to pass it to SimulateWithRand.
+type CryptoSource struct{}
+
+// NewCryptoSource returns a cryptographically secure random source
+func NewCryptoSource() rand.Source64 {
+	return CryptoSource{}
+}
+
+// Uint64 returns a uniformly distributed 64-bit value
+func (CryptoSource) Uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic("crypto/rand: " + err.Error())
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// Int63 returns a non-negative 63-bit value
+func (s CryptoSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Seed is a no-op: a cryptographic source has no reproducible state
+func (CryptoSource) Seed(int64) {}
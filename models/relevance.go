@@ -0,0 +1,201 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// RequisiteNodes returns the nodes whose CPDs are needed to compute
+// P(query | evidence), in sorted order, where observed holds the evidence
+// variables.
+//
+// Two pruning rules are applied. Barren nodes, i.e. nodes that are neither
+// query or evidence variables nor their ancestors, sum out to one and are
+// dropped. Of the remaining CPDs, only those whose evidence-reduced scope is
+// connected to a query variable are kept; the rest are d-separated from the
+// query given the evidence and contribute a constant that normalization
+// removes.
+func (bn *BayesianNetwork) RequisiteNodes(query []string, observed map[string]bool) []string {
+	dag := bn.DAG
+
+	// Ancestral set of query and evidence variables
+	ancestral := make(map[string]bool)
+	targets := make([]string, 0, len(query)+len(observed))
+	targets = append(targets, query...)
+	for v := range observed {
+		targets = append(targets, v)
+	}
+	for _, v := range targets {
+		ancestral[v] = true
+		for _, a := range dag.Ancestors(v) {
+			ancestral[a] = true
+		}
+	}
+
+	// Union-find over the reduced family scopes
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(v string) string {
+		if p, ok := parent[v]; ok && p != v {
+			root := find(p)
+			parent[v] = root
+			return root
+		}
+		parent[v] = v
+		return v
+	}
+	union := func(a, b string) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[ra] = rb
+		}
+	}
+
+	nodes := make([]string, 0, len(ancestral))
+	for v := range ancestral {
+		nodes = append(nodes, v)
+	}
+	sort.Strings(nodes)
+
+	scopes := make(map[string][]string, len(nodes))
+	for _, node := range nodes {
+		family := append([]string{node}, dag.Parents(node)...)
+		scope := make([]string, 0, len(family))
+		for _, v := range family {
+			if !observed[v] {
+				scope = append(scope, v)
+			}
+		}
+		for i := 1; i < len(scope); i++ {
+			union(scope[0], scope[i])
+		}
+		if len(scope) > 0 {
+			find(scope[0])
+		}
+		scopes[node] = scope
+	}
+
+	queryRoots := make(map[string]bool)
+	for _, q := range query {
+		if !observed[q] {
+			queryRoots[find(q)] = true
+		}
+	}
+
+	relevant := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		scope := scopes[node]
+		if len(scope) > 0 && queryRoots[find(scope[0])] {
+			relevant = append(relevant, node)
+		}
+	}
+	return relevant
+}
+
+// RelevantSubnetwork returns the part of the network that can affect
+// P(query | evidence) for any values of the evidence variables: the
+// RequisiteNodes, after pruning barren nodes and nodes d-separated from the
+// query, together with the evidence nodes among their parents. Evidence
+// variables left out cannot change the answer, which explains which inputs
+// matter; inference on the subnetwork with the same evidence gives the same
+// posterior and is cheaper. Evidence nodes kept only as parents lose their
+// own parents and become roots with a uniform CPD if discrete, or a
+// standard normal one if continuous: as they are observed, their priors
+// cancel in the posterior.
+func (bn *BayesianNetwork) RelevantSubnetwork(query, evidence []string) (*BayesianNetwork, error) {
+	observed := make(map[string]bool, len(evidence))
+	for _, v := range append(append([]string(nil), query...), evidence...) {
+		if !bn.DAG.HasNode(v) {
+			return nil, fmt.Errorf("variable %s: %w", v, ErrUnknownVariable)
+		}
+	}
+	for _, v := range evidence {
+		observed[v] = true
+	}
+
+	requisite := bn.RequisiteNodes(query, observed)
+	inSet := make(map[string]bool, len(requisite))
+	for _, node := range requisite {
+		inSet[node] = true
+	}
+	keep := append([]string(nil), requisite...)
+	var boundary []string
+	for _, node := range requisite {
+		for _, p := range bn.DAG.Parents(node) {
+			if !inSet[p] {
+				inSet[p] = true
+				keep = append(keep, p)
+				boundary = append(boundary, p)
+			}
+		}
+	}
+	for _, q := range query {
+		if !inSet[q] {
+			inSet[q] = true
+			keep = append(keep, q)
+			boundary = append(boundary, q)
+		}
+	}
+	sort.Strings(keep)
+
+	sub, err := bn.induced(keep)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range boundary {
+		if len(sub.DAG.Parents(node)) == len(bn.DAG.Parents(node)) {
+			continue
+		}
+		if err := sub.makeRoot(node); err != nil {
+			return nil, err
+		}
+	}
+	return sub, nil
+}
+
+// makeRoot removes the edges into node and replaces its CPD by a uniform
+// one if discrete, or a standard normal one if continuous
+func (bn *BayesianNetwork) makeRoot(node string) error {
+	for _, p := range bn.DAG.Parents(node) {
+		bn.DAG.RemoveEdge(p, node)
+	}
+	delete(bn.basis, node)
+	if bn.IsContinuous(node) {
+		delete(bn.ContinuousCPDs, node)
+		cpd, err := factors.NewLinearGaussianCPD(node, []string{}, 0, map[string]float64{}, 1)
+		if err != nil {
+			return err
+		}
+		bn.GaussianCPDs[node] = cpd
+		return nil
+	}
+
+	names := bn.StateNames(node)
+	card := bn.Cardinality[node]
+	if cpd, ok := bn.DiscreteCPD(node); ok {
+		card = cpd.GetCardinality()
+	}
+	delete(bn.CustomCPDs, node)
+	delete(bn.HybridCPDs, node)
+	delete(bn.CPDs, node)
+	if card == 0 {
+		return nil
+	}
+	row := make([]float64, card)
+	for i := range row {
+		row[i] = 1 / float64(card)
+	}
+	cpd, err := factors.NewTabularCPD(node, card, [][]float64{row}, []string{}, map[string]int{})
+	if err != nil {
+		return err
+	}
+	if len(names) == card {
+		if err := cpd.SetStateNames(node, names); err != nil {
+			return err
+		}
+	}
+	bn.CPDs[node] = cpd
+	return nil
+}
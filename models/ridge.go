@@ -0,0 +1,23 @@
+package models
+
+import "fmt"
+
+// SetRidge sets the ridge penalty of the linear regressions FitMixed and
+// FitWeighted use for Gaussian CPDs. Coefficients minimize the weighted
+// squared error plus penalty·Σ coefficient², with the intercept left
+// unpenalized, so correlated continuous parents no longer make the normal
+// equations singular. Data is not standardized, so the penalty is in units
+// of the parents' squared scale. Zero, the default, gives ordinary least
+// squares.
+func (bn *BayesianNetwork) SetRidge(penalty float64) error {
+	if penalty < 0 {
+		return fmt.Errorf("ridge penalty must be non-negative, got %v", penalty)
+	}
+	bn.ridge = penalty
+	return nil
+}
+
+// Ridge returns the penalty set by SetRidge
+func (bn *BayesianNetwork) Ridge() float64 {
+	return bn.ridge
+}
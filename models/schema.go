@@ -0,0 +1,192 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// VariableSpec declares one variable of a Schema
+type VariableSpec struct {
+	Name        string       `json:"name"`
+	Type        VariableType `json:"type"`
+	Cardinality int          `json:"cardinality,omitempty"` // Discrete; the number of States if zero
+	States      []string     `json:"states,omitempty"`      // Discrete state labels, in state order
+	Unit        string       `json:"unit,omitempty"`        // Continuous unit of measurement, for display
+	Min         float64      `json:"min,omitempty"`         // Continuous lower bound, inclusive
+	Max         float64      `json:"max,omitempty"`         // Continuous upper bound, inclusive; unbounded unless Min < Max
+}
+
+// Bounded reports whether the spec bounds continuous values
+func (v VariableSpec) Bounded() bool {
+	return v.Min < v.Max
+}
+
+// Schema declares the variables of a network, their types and domains, ahead
+// of any CPD or data
+type Schema struct {
+	Variables []VariableSpec `json:"variables"`
+
+	index map[string]int
+}
+
+// NewSchema creates a schema of the given variables, checking that each has a
+// known type and that discrete domains are consistent
+func NewSchema(variables ...VariableSpec) (*Schema, error) {
+	s := &Schema{Variables: make([]VariableSpec, len(variables)), index: make(map[string]int, len(variables))}
+	for i, v := range variables {
+		if v.Name == "" {
+			return nil, fmt.Errorf("variable %d has no name", i)
+		}
+		if _, ok := s.index[v.Name]; ok {
+			return nil, fmt.Errorf("variable %s is declared twice", v.Name)
+		}
+		switch v.Type {
+		case Discrete:
+			if v.Cardinality == 0 {
+				v.Cardinality = len(v.States)
+			}
+			if v.Cardinality < 1 {
+				return nil, fmt.Errorf("discrete variable %s needs a positive cardinality or states: %w",
+					v.Name, ErrCardinalityMismatch)
+			}
+			if len(v.States) > 0 && len(v.States) != v.Cardinality {
+				return nil, fmt.Errorf("variable %s has %d states but %d labels: %w",
+					v.Name, v.Cardinality, len(v.States), ErrCardinalityMismatch)
+			}
+			v.States = append([]string(nil), v.States...)
+		case Continuous:
+			if v.Cardinality != 0 || len(v.States) > 0 {
+				return nil, fmt.Errorf("continuous variable %s cannot have states", v.Name)
+			}
+			if v.Min > v.Max {
+				return nil, fmt.Errorf("variable %s has minimum %v above maximum %v", v.Name, v.Min, v.Max)
+			}
+		default:
+			return nil, fmt.Errorf("unknown variable type %q for %s", v.Type, v.Name)
+		}
+		s.Variables[i] = v
+		s.index[v.Name] = i
+	}
+	return s, nil
+}
+
+// Variable returns the spec of a variable, if declared
+func (s *Schema) Variable(name string) (VariableSpec, bool) {
+	if s.index == nil {
+		s.reindex()
+	}
+	i, ok := s.index[name]
+	if !ok {
+		return VariableSpec{}, false
+	}
+	return s.Variables[i], true
+}
+
+// Check reports the first value of the sample that falls outside its
+// variable's declared type or domain. Undeclared variables are not checked.
+func (s *Schema) Check(sample Sample) error {
+	for name, state := range sample.Discrete {
+		v, ok := s.Variable(name)
+		if !ok {
+			continue
+		}
+		if v.Type != Discrete {
+			return fmt.Errorf("continuous variable %s has discrete value %d", name, state)
+		}
+		if state < 0 || state >= v.Cardinality {
+			return fmt.Errorf("%s has state %d, but %d are declared: %w", name, state, v.Cardinality, ErrInvalidState)
+		}
+	}
+	for name, x := range sample.Continuous {
+		v, ok := s.Variable(name)
+		if !ok {
+			continue
+		}
+		if v.Type != Continuous {
+			return fmt.Errorf("discrete variable %s has continuous value %v", name, x)
+		}
+		if math.IsNaN(x) || (v.Bounded() && (x < v.Min || x > v.Max)) {
+			return fmt.Errorf("%s has value %v outside [%v, %v]", name, x, v.Min, v.Max)
+		}
+	}
+	return nil
+}
+
+// Copy creates a deep copy
+func (s *Schema) Copy() *Schema {
+	out := &Schema{Variables: make([]VariableSpec, len(s.Variables))}
+	for i, v := range s.Variables {
+		v.States = append([]string(nil), v.States...)
+		out.Variables[i] = v
+	}
+	out.reindex()
+	return out
+}
+
+// restrict returns a copy of the schema keeping only the given variables
+func (s *Schema) restrict(keep func(string) bool) *Schema {
+	out := &Schema{}
+	for _, v := range s.Variables {
+		if keep(v.Name) {
+			v.States = append([]string(nil), v.States...)
+			out.Variables = append(out.Variables, v)
+		}
+	}
+	out.reindex()
+	return out
+}
+
+func (s *Schema) reindex() {
+	s.index = make(map[string]int, len(s.Variables))
+	for i, v := range s.Variables {
+		s.index[v.Name] = i
+	}
+}
+
+// NewBayesianNetworkWithSchema creates a network over the edges whose
+// variables are fixed by the schema: every endpoint of an edge must be
+// declared, declared variables outside the edges become isolated nodes, and
+// discrete cardinalities are declared as by DeclareCardinality, so fitting
+// keeps states that the data never shows.
+func NewBayesianNetworkWithSchema(edges [][2]string, schema *Schema) (*BayesianNetwork, error) {
+	bn, err := NewBayesianNetwork(edges)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range bn.DAG.Nodes() {
+		if _, ok := schema.Variable(node); !ok {
+			return nil, fmt.Errorf("variable %s is not in the schema: %w", node, ErrUnknownVariable)
+		}
+	}
+	for _, v := range schema.Variables {
+		bn.DAG.AddNode(v.Name)
+		bn.VariableType[v.Name] = v.Type
+		if v.Type == Discrete {
+			bn.Cardinality[v.Name] = v.Cardinality
+			if bn.declared == nil {
+				bn.declared = make(map[string]int)
+			}
+			bn.declared[v.Name] = v.Cardinality
+		}
+	}
+	bn.schema = schema.Copy()
+	return bn, nil
+}
+
+// Schema returns the network's schema, or nil if it was built without one
+func (bn *BayesianNetwork) Schema() *Schema {
+	return bn.schema
+}
+
+// checkSchema reports the first sample that violates the network's schema
+func (bn *BayesianNetwork) checkSchema(data []Sample) error {
+	if bn.schema == nil {
+		return nil
+	}
+	for i, sample := range data {
+		if err := bn.schema.Check(sample); err != nil {
+			return fmt.Errorf("sample %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import "fmt"
+
+// SetShrinkage sets how strongly Fit pulls each CPT row toward the marginal
+// distribution of the child. A row with n samples gets the estimate
+// (counts + s·marginal) / (n + s) for strength s, so sparse parent
+// configurations back off to the marginal while well-populated rows follow
+// their own counts; configurations never seen get the marginal itself. The
+// marginal is Laplace-smoothed. Strength 0, the default, applies Laplace
+// smoothing to every row instead.
+func (bn *BayesianNetwork) SetShrinkage(strength float64) error {
+	if strength < 0 {
+		return fmt.Errorf("shrinkage strength must be non-negative, got %v", strength)
+	}
+	bn.shrinkage = strength
+	return nil
+}
+
+// Shrinkage returns the strength set by SetShrinkage
+func (bn *BayesianNetwork) Shrinkage() float64 {
+	return bn.shrinkage
+}
+
+// normalizeCounts turns one count row per parent configuration into CPT
+// rows, with Laplace smoothing or, if set, shrinkage toward the marginal
+func (bn *BayesianNetwork) normalizeCounts(counts [][]float64, varCard int) [][]float64 {
+	values := make([][]float64, len(counts))
+	if bn.shrinkage <= 0 {
+		for i := range values {
+			values[i] = make([]float64, varCard)
+			sum := 0.0
+			for j := range values[i] {
+				sum += counts[i][j] + 1 // Laplace smoothing
+			}
+			for j := range values[i] {
+				values[i][j] = (counts[i][j] + 1) / sum
+			}
+		}
+		return values
+	}
+
+	marginal := make([]float64, varCard)
+	total := 0.0
+	for _, row := range counts {
+		for j, n := range row {
+			marginal[j] += n
+			total += n
+		}
+	}
+	for j := range marginal {
+		marginal[j] = (marginal[j] + 1) / (total + float64(varCard))
+	}
+
+	for i, row := range counts {
+		values[i] = make([]float64, varCard)
+		n := 0.0
+		for _, c := range row {
+			n += c
+		}
+		for j := range values[i] {
+			values[i][j] = (row[j] + bn.shrinkage*marginal[j]) / (n + bn.shrinkage)
+		}
+	}
+	return values
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EdgeTest is the likelihood-ratio test of one edge against the network
+// without it. Only the child's CPD changes, so the statistic compares the
+// maximized log-likelihoods of the child given its parents, with and
+// without parent, and is asymptotically chi-squared with DF degrees of
+// freedom when the edge is not needed.
+type EdgeTest struct {
+	Parent    string
+	Child     string
+	Statistic float64 // Twice the log-likelihood gain from the edge
+	DF        int     // Parameters the edge adds to the child's CPD
+	PValue    float64
+	Samples   int // Rows with the child and all its parents observed
+}
+
+// LikelihoodRatioTest tests whether removing the edge parent -> child
+// significantly worsens the likelihood of data. Both fits use the rows
+// observing the child and all its current parents. The child must be a
+// discrete node with discrete parents or a linear Gaussian node.
+func (bn *BayesianNetwork) LikelihoodRatioTest(parent, child string, data []Sample) (*EdgeTest, error) {
+	if !bn.DAG.HasEdge(parent, child) {
+		return nil, fmt.Errorf("no edge %s -> %s", parent, child)
+	}
+	parents := bn.DAG.Parents(child)
+	sort.Strings(parents)
+	family := append([]string{child}, parents...)
+	var rows []Sample
+	for _, s := range data {
+		if bn.observesAll(s, family) {
+			rows = append(rows, s)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no sample observes %s and all its parents", child)
+	}
+	reduced := make([]string, 0, len(parents)-1)
+	for _, p := range parents {
+		if p != parent {
+			reduced = append(reduced, p)
+		}
+	}
+
+	var gain float64
+	var df int
+	var err error
+	if bn.IsDiscrete(child) {
+		gain, df, err = bn.discreteRatio(child, parents, reduced, rows)
+	} else {
+		gain, df, err = bn.gaussianRatio(parent, child, rows)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	statistic := math.Max(2*gain, 0)
+	return &EdgeTest{
+		Parent:    parent,
+		Child:     child,
+		Statistic: statistic,
+		DF:        df,
+		PValue:    chiSquareSurvival(statistic, df),
+		Samples:   len(rows),
+	}, nil
+}
+
+// EdgeTests runs LikelihoodRatioTest on every edge, sorted by parent and
+// then child
+func (bn *BayesianNetwork) EdgeTests(data []Sample) ([]EdgeTest, error) {
+	edges := bn.DAG.Edges()
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	tests := make([]EdgeTest, 0, len(edges))
+	for _, e := range edges {
+		test, err := bn.LikelihoodRatioTest(e[0], e[1], data)
+		if err != nil {
+			return nil, fmt.Errorf("edge %s -> %s: %w", e[0], e[1], err)
+		}
+		tests = append(tests, *test)
+	}
+	return tests, nil
+}
+
+func (bn *BayesianNetwork) observesAll(s Sample, variables []string) bool {
+	for _, v := range variables {
+		if bn.IsContinuous(v) {
+			if _, ok := s.Continuous[v]; !ok {
+				return false
+			}
+		} else if _, ok := s.Discrete[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// discreteRatio returns the gain in the maximum-likelihood log-likelihood of
+// a discrete child from the full parents over the reduced ones, with the
+// parameters it costs. The maxima come from the counts directly, as fitted
+// CPTs are smoothed.
+func (bn *BayesianNetwork) discreteRatio(child string, full, reduced []string, rows []Sample) (float64, int, error) {
+	for _, p := range full {
+		if !bn.IsDiscrete(p) {
+			return 0, 0, fmt.Errorf("discrete %s has continuous parent %s, which the test does not support", child, p)
+		}
+	}
+	configs := func(parents []string) int {
+		n := 1
+		for _, p := range parents {
+			n *= bn.Cardinality[p]
+		}
+		return n
+	}
+	df := (bn.Cardinality[child] - 1) * (configs(full) - configs(reduced))
+	return familyLogLikelihood(child, full, rows) - familyLogLikelihood(child, reduced, rows), df, nil
+}
+
+// familyLogLikelihood returns Σ n(x, u) log(n(x, u) / n(u)) over the states x
+// of child and u of parents
+func familyLogLikelihood(child string, parents []string, rows []Sample) float64 {
+	type cell struct {
+		config string
+		state  int
+	}
+	counts := make(map[cell]float64)
+	totals := make(map[string]float64)
+	key := make([]string, len(parents))
+	for _, s := range rows {
+		for i, p := range parents {
+			key[i] = strconv.Itoa(s.Discrete[p])
+		}
+		config := strings.Join(key, ",")
+		counts[cell{config, s.Discrete[child]}]++
+		totals[config]++
+	}
+	ll := 0.0
+	for c, n := range counts {
+		ll += n * math.Log(n/totals[c.config])
+	}
+	return ll
+}
+
+// gaussianRatio refits the linear Gaussian CPD of child with and without the
+// edge and returns the gain in log-likelihood of the rows with the
+// parameters it costs
+func (bn *BayesianNetwork) gaussianRatio(parent, child string, rows []Sample) (float64, int, error) {
+	with := bn.Copy()
+	without := bn.Copy()
+	if err := without.RemoveEdge(parent, child); err != nil {
+		return 0, 0, err
+	}
+	var ll [2]float64
+	var params [2]int
+	for k, m := range []*BayesianNetwork{with, without} {
+		if err := m.fitContinuousNode(child, rows, nil); err != nil {
+			return 0, 0, err
+		}
+		cpd, ok := m.GaussianCPDs[child]
+		if !ok {
+			return 0, 0, fmt.Errorf("%s does not have a linear Gaussian CPD, which the test needs", child)
+		}
+		for _, s := range rows {
+			lp, err := cpd.LogPDF(s.Continuous[child], m.parentValues(cpd.GetParents(), s))
+			if err != nil {
+				return 0, 0, fmt.Errorf("density of %s: %w", child, err)
+			}
+			ll[k] += lp
+		}
+		configs := 1
+		for _, p := range cpd.DiscreteParents() {
+			configs *= cpd.Cardinality[p]
+		}
+		params[k] = configs*(len(cpd.ContinuousParents())+len(cpd.Basis)+2) + len(cpd.LogVariance)
+	}
+	return ll[0] - ll[1], params[0] - params[1], nil
+}
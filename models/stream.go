@@ -0,0 +1,51 @@
+package models
+
+import (
+	"iter"
+	"math/rand"
+)
+
+// SimulateStream returns an endless stream of samples drawn as by
+// SimulateMixed, without holding them in memory. The consumer decides how
+// many to take by breaking out of the loop:
+//
+//	for sample, err := range bn.SimulateStream(42) {
+//		if err != nil {
+//			return err
+//		}
+//		// ...
+//	}
+//
+// The first n samples equal SimulateMixed(n, seed). If the model is invalid
+// or a draw fails, the error is yielded once and the stream ends. The
+// network must not be modified while the stream is consumed.
+func (bn *BayesianNetwork) SimulateStream(seed int64) iter.Seq2[Sample, error] {
+	return bn.SimulateStreamWithRand(rand.New(rand.NewSource(seed)))
+}
+
+// SimulateStreamWithRand is SimulateStream drawing from a caller-owned
+// generator. The generator is used lazily as samples are consumed.
+func (bn *BayesianNetwork) SimulateStreamWithRand(r *rand.Rand) iter.Seq2[Sample, error] {
+	return func(yield func(Sample, error) bool) {
+		if err := bn.CheckModel(); err != nil {
+			yield(Sample{}, err)
+			return
+		}
+		order, err := bn.DAG.TopologicalSort()
+		if err != nil {
+			yield(Sample{}, err)
+			return
+		}
+
+		sampler := bn.newRowSampler(order, r)
+		for {
+			if err := sampler.draw(); err != nil {
+				yield(Sample{}, err)
+				return
+			}
+			if !yield(sampler.sample(), nil) {
+				return
+			}
+		}
+	}
+}
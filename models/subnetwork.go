@@ -0,0 +1,306 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// Subnetwork returns the network over the targets and their ancestors. An
+// ancestral set is closed under parents, so its CPDs need no change and the
+// joint distribution over the kept variables is exactly that of bn.
+func (bn *BayesianNetwork) Subnetwork(targets []string) (*BayesianNetwork, error) {
+	keep, err := bn.ancestralSet(targets)
+	if err != nil {
+		return nil, err
+	}
+	return bn.induced(keep)
+}
+
+// induced returns the network over the given nodes and the edges among them,
+// with their CPDs and settings. Nodes whose parents are not all kept keep
+// CPDs that no longer match the graph, for the caller to replace.
+func (bn *BayesianNetwork) induced(keep []string) (*BayesianNetwork, error) {
+	sub, err := NewBayesianNetwork(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make(map[string]bool, len(keep))
+	for _, node := range keep {
+		kept[node] = true
+		sub.DAG.AddNode(node)
+	}
+	for _, node := range keep {
+		for _, parent := range bn.DAG.Parents(node) {
+			if !kept[parent] {
+				continue
+			}
+			if err := sub.DAG.AddEdge(parent, node); err != nil {
+				return nil, err
+			}
+		}
+
+		if cpd, ok := bn.CPDs[node]; ok {
+			sub.CPDs[node] = cpd.Copy()
+		}
+		if cpd, ok := bn.GaussianCPDs[node]; ok {
+			sub.GaussianCPDs[node] = cpd.Copy()
+		}
+		if cpd, ok := bn.CustomCPDs[node]; ok {
+			sub.CustomCPDs[node] = cpd
+		}
+		if cpd, ok := bn.HybridCPDs[node]; ok {
+			if sub.HybridCPDs == nil {
+				sub.HybridCPDs = make(map[string]factors.HybridCPD)
+			}
+			sub.HybridCPDs[node] = cpd
+		}
+		if cpd, ok := bn.ContinuousCPDs[node]; ok {
+			if sub.ContinuousCPDs == nil {
+				sub.ContinuousCPDs = make(map[string]factors.ContinuousCPD)
+			}
+			sub.ContinuousCPDs[node] = cpd
+		}
+		if vtype, ok := bn.VariableType[node]; ok {
+			sub.VariableType[node] = vtype
+		}
+		if card, ok := bn.Cardinality[node]; ok {
+			sub.Cardinality[node] = card
+		}
+		if card, ok := bn.declared[node]; ok {
+			if sub.declared == nil {
+				sub.declared = make(map[string]int)
+			}
+			sub.declared[node] = card
+		}
+		if bn.hidden[node] {
+			if sub.hidden == nil {
+				sub.hidden = make(map[string]bool)
+			}
+			sub.hidden[node] = true
+		}
+		if bn.heteroscedastic[node] {
+			if sub.heteroscedastic == nil {
+				sub.heteroscedastic = make(map[string]bool)
+			}
+			sub.heteroscedastic[node] = true
+		}
+		if opts, ok := bn.gaussianProcess[node]; ok {
+			if sub.gaussianProcess == nil {
+				sub.gaussianProcess = make(map[string]factors.GPOptions)
+			}
+			sub.gaussianProcess[node] = opts
+		}
+		if marginal, ok := bn.copula[node]; ok {
+			if sub.copula == nil {
+				sub.copula = make(map[string]factors.MarginalType)
+			}
+			sub.copula[node] = marginal
+		}
+		if bn.ordinalProbit[node] {
+			if sub.ordinalProbit == nil {
+				sub.ordinalProbit = make(map[string]bool)
+			}
+			sub.ordinalProbit[node] = true
+		}
+		if bn.beta[node] {
+			if sub.beta == nil {
+				sub.beta = make(map[string]bool)
+			}
+			sub.beta[node] = true
+		}
+		if shape, ok := bn.gammaShape[node]; ok {
+			if sub.gammaShape == nil {
+				sub.gammaShape = make(map[string]float64)
+			}
+			sub.gammaShape[node] = shape
+		}
+		if bn.count[node] {
+			if sub.count == nil {
+				sub.count = make(map[string]bool)
+			}
+			sub.count[node] = true
+		}
+		if terms, ok := bn.basis[node]; ok {
+			if sub.basis == nil {
+				sub.basis = make(map[string][]factors.BasisTerm)
+			}
+			sub.basis[node] = append([]factors.BasisTerm(nil), terms...)
+		}
+	}
+	if bn.pipeline != nil {
+		sub.pipeline = bn.pipeline.Copy()
+	}
+	if bn.schema != nil {
+		sub.schema = bn.schema.restrict(sub.DAG.HasNode)
+	}
+
+	return sub, nil
+}
+
+// MarginalSubnetwork returns a network over the targets alone whose joint
+// distribution is the marginal P(targets) of bn. Targets keep their
+// topological order; each one's parents are the earlier targets it is not
+// d-separated from given the others, and its CPD is computed by exact
+// inference. Only discrete networks are supported.
+func (bn *BayesianNetwork) MarginalSubnetwork(targets []string) (*BayesianNetwork, error) {
+	ancestral, err := bn.ancestralSet(targets)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range ancestral {
+		if !bn.IsDiscrete(node) {
+			return nil, fmt.Errorf("node %s: %w", node, ErrContinuousVariables)
+		}
+	}
+
+	order, err := bn.DAG.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+	isTarget := make(map[string]bool, len(targets))
+	for _, v := range targets {
+		isTarget[v] = true
+	}
+	ordered := make([]string, 0, len(targets))
+	for _, node := range order {
+		if isTarget[node] {
+			ordered = append(ordered, node)
+			delete(isTarget, node)
+		}
+	}
+
+	sub, err := NewBayesianNetwork(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, node := range ordered {
+		predecessors := ordered[:i]
+		parents := make([]string, 0, len(predecessors))
+		for _, p := range predecessors {
+			others := make([]string, 0, len(predecessors)-1)
+			for _, o := range predecessors {
+				if o != p {
+					others = append(others, o)
+				}
+			}
+			if !bn.DAG.IsDSeparated(node, p, others) {
+				parents = append(parents, p)
+			}
+		}
+
+		cpd, err := bn.marginalCPD(node, parents)
+		if err != nil {
+			return nil, err
+		}
+
+		sub.DAG.AddNode(node)
+		for _, p := range parents {
+			if err := sub.DAG.AddEdge(p, node); err != nil {
+				return nil, err
+			}
+		}
+		if err := sub.AddCPD(cpd); err != nil {
+			return nil, err
+		}
+	}
+
+	return sub, nil
+}
+
+// ancestralSet returns the sorted targets and their ancestors
+func (bn *BayesianNetwork) ancestralSet(targets []string) ([]string, error) {
+	set := make(map[string]bool)
+	for _, v := range targets {
+		if !bn.DAG.HasNode(v) {
+			return nil, fmt.Errorf("variable %s: %w", v, ErrUnknownVariable)
+		}
+		set[v] = true
+		for _, a := range bn.DAG.Ancestors(v) {
+			set[a] = true
+		}
+	}
+
+	nodes := make([]string, 0, len(set))
+	for v := range set {
+		nodes = append(nodes, v)
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
+
+// marginalCPD computes P(node | parents) from the joint marginal of the family
+func (bn *BayesianNetwork) marginalCPD(node string, parents []string) (*factors.TabularCPD, error) {
+	joint, err := bn.discreteMarginal(append([]string{node}, parents...))
+	if err != nil {
+		return nil, err
+	}
+	return bn.conditionalCPD(node, parents, joint)
+}
+
+// conditionalCPD builds P(node | parents) from a factor over the family that
+// is proportional to the joint, normalizing over node in each parent
+// configuration. Configurations with zero mass get a uniform row.
+func (bn *BayesianNetwork) conditionalCPD(node string, parents []string, joint *DiscreteFactor) (*factors.TabularCPD, error) {
+	card := joint.Cardinality[node]
+	evidenceCard := make(map[string]int, len(parents))
+	rows := 1
+	for _, p := range parents {
+		evidenceCard[p] = joint.Cardinality[p]
+		rows *= joint.Cardinality[p]
+	}
+
+	values := make([][]float64, rows)
+	assignment := make(map[string]int, len(parents)+1)
+	for r := range values {
+		// The last parent varies fastest
+		rem := r
+		for j := len(parents) - 1; j >= 0; j-- {
+			assignment[parents[j]] = rem % evidenceCard[parents[j]]
+			rem /= evidenceCard[parents[j]]
+		}
+
+		row := make([]float64, card)
+		sum := 0.0
+		for s := 0; s < card; s++ {
+			assignment[node] = s
+			row[s] = joint.Values[factorIndex(joint, assignment)]
+			sum += row[s]
+		}
+		for s := range row {
+			if sum > 0 {
+				row[s] /= sum
+			} else {
+				row[s] = 1 / float64(card)
+			}
+		}
+		values[r] = row
+	}
+
+	cpd, err := factors.NewTabularCPD(node, card, values, append([]string(nil), parents...), evidenceCard)
+	if err != nil {
+		return nil, err
+	}
+
+	// Carry over state labels from the original CPDs
+	for _, v := range append([]string{node}, parents...) {
+		if original, ok := bn.CPDs[v]; ok && original.StateNames != nil {
+			if names, ok := original.StateNames[v]; ok {
+				_ = cpd.SetStateNames(v, names)
+			}
+		}
+	}
+	return cpd, nil
+}
+
+// factorIndex returns the position of a full assignment in a factor's values
+func factorIndex(f *DiscreteFactor, assignment map[string]int) int {
+	idx := 0
+	for _, v := range f.Variables {
+		idx = idx*f.Cardinality[v] + assignment[v]
+	}
+	return idx
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NodeSummary describes one node of a network
+type NodeSummary struct {
+	Name        string
+	Type        VariableType // Empty when the node has no CPD yet
+	Parents     int
+	Cardinality int // Number of states, 0 for continuous nodes
+	TableSize   int // Entries in the CPT, or parameter sets of a Gaussian CPD
+	Parameters  int // Free parameters of the CPD
+}
+
+// NetworkSummary is a quick structural and parametric overview of a network
+type NetworkSummary struct {
+	Nodes         int
+	Edges         int
+	NodeDetails   []NodeSummary // Sorted by node name
+	Treewidth     int           // Greedy upper bound on the moral graph treewidth
+	LargestClique int           // Treewidth + 1, the largest clique exact inference must handle
+	Parameters    int           // Total free parameters
+	MissingCPDs   []string      // Nodes without a CPD
+}
+
+// Summary reports node and edge counts, per-node CPD sizes, an estimate of
+// the largest clique and the total parameter count of the network
+func (bn *BayesianNetwork) Summary() *NetworkSummary {
+	nodes := bn.Nodes()
+	summary := &NetworkSummary{
+		Nodes:       len(nodes),
+		Edges:       len(bn.Edges()),
+		NodeDetails: make([]NodeSummary, 0, len(nodes)),
+		MissingCPDs: []string{},
+	}
+
+	for _, node := range nodes {
+		detail := NodeSummary{
+			Name:    node,
+			Parents: len(bn.DAG.Parents(node)),
+		}
+
+		if cpd, ok := bn.CPDs[node]; ok {
+			detail.Type = Discrete
+			detail.Cardinality = cpd.VariableCard
+			detail.TableSize = len(cpd.Values) * cpd.VariableCard
+			// Each row sums to one
+			detail.Parameters = len(cpd.Values) * (cpd.VariableCard - 1)
+		} else if cpd, ok := bn.HybridCPDs[node]; ok {
+			// A hybrid CPD has no table over its continuous parents, and its
+			// parameterization is opaque, so it counts no parameters
+			detail.Type = Discrete
+			detail.Cardinality = cpd.GetCardinality()
+		} else if cpd, ok := bn.CustomCPDs[node]; ok {
+			detail.Type = Discrete
+			detail.Cardinality = cpd.GetCardinality()
+			rows := 1
+			for _, p := range cpd.GetParents() {
+				rows *= bn.Cardinality[p]
+			}
+			detail.TableSize = rows * cpd.GetCardinality()
+			// A custom CPD's parameterization is opaque; count the table it encodes
+			detail.Parameters = rows * (cpd.GetCardinality() - 1)
+		} else if cpd, ok := bn.GaussianCPDs[node]; ok {
+			detail.Type = Continuous
+			configs := 1
+			for _, p := range cpd.DiscreteParents() {
+				configs *= cpd.Cardinality[p]
+			}
+			detail.TableSize = configs
+			// Intercept, one coefficient per continuous parent and basis term,
+			// and a variance
+			detail.Parameters = configs * (len(cpd.ContinuousParents()) + len(cpd.Basis) + 2)
+		} else if _, ok := bn.ContinuousCPDs[node]; ok {
+			// A custom continuous CPD's parameterization is opaque and has no
+			// table, so it counts no parameters
+			detail.Type = Continuous
+		} else {
+			detail.Type = bn.VariableType[node]
+			summary.MissingCPDs = append(summary.MissingCPDs, node)
+		}
+
+		summary.Parameters += detail.Parameters
+		summary.NodeDetails = append(summary.NodeDetails, detail)
+	}
+
+	if len(nodes) > 0 {
+		summary.Treewidth = bn.DAG.EstimateTreewidth()
+		summary.LargestClique = summary.Treewidth + 1
+	}
+
+	return summary
+}
+
+// String returns the summary as a readable report
+func (s *NetworkSummary) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Nodes: %d, Edges: %d\n", s.Nodes, s.Edges))
+	sb.WriteString(fmt.Sprintf("Parameters: %d\n", s.Parameters))
+	sb.WriteString(fmt.Sprintf("Estimated treewidth: %d (largest clique %d)\n", s.Treewidth, s.LargestClique))
+	if len(s.MissingCPDs) > 0 {
+		sb.WriteString(fmt.Sprintf("Missing CPDs: %s\n", strings.Join(s.MissingCPDs, ", ")))
+	}
+
+	nameWidth := len("Node")
+	for _, d := range s.NodeDetails {
+		if len(d.Name) > nameWidth {
+			nameWidth = len(d.Name)
+		}
+	}
+	sb.WriteString(fmt.Sprintf("%-*s  %-10s  %7s  %6s  %6s  %6s\n",
+		nameWidth, "Node", "Type", "Parents", "States", "Table", "Params"))
+	for _, d := range s.NodeDetails {
+		vtype := string(d.Type)
+		if vtype == "" {
+			vtype = "-"
+		}
+		sb.WriteString(fmt.Sprintf("%-*s  %-10s  %7d  %6d  %6d  %6d\n",
+			nameWidth, d.Name, vtype, d.Parents, d.Cardinality, d.TableSize, d.Parameters))
+	}
+	return sb.String()
+}
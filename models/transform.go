@@ -0,0 +1,162 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ReverseArc reverses the edge x -> y by Shachter's arc reversal, keeping
+// the joint distribution unchanged. Both nodes end up with the union of
+// their parents, with y also a parent of x:
+// P(y | A) = Σ_x P(x | Pa(x)) P(y | Pa(y))
+// P(x | y, A) = P(x | Pa(x)) P(y | Pa(y)) / P(y | A)
+// where A = Pa(x) ∪ Pa(y) \ {x}. The edge must be the only directed path
+// from x to y, otherwise reversing it would create a cycle. Both variables
+// must be discrete; their CPDs become tabular.
+func (bn *BayesianNetwork) ReverseArc(x, y string) error {
+	if !bn.DAG.HasEdge(x, y) {
+		return fmt.Errorf("no edge %s -> %s", x, y)
+	}
+	if !bn.IsDiscrete(x) || !bn.IsDiscrete(y) {
+		return fmt.Errorf("reversing %s -> %s: %w", x, y, ErrContinuousVariables)
+	}
+
+	// Any other path x ~> y would close a cycle through the reversed edge
+	for _, child := range bn.DAG.Children(x) {
+		if child == y {
+			continue
+		}
+		for _, d := range bn.DAG.Descendants(child) {
+			if d == y {
+				return fmt.Errorf("reversing %s -> %s: another path exists: %w", x, y, ErrCycle)
+			}
+		}
+	}
+
+	fx, err := bn.NodeFactor(x)
+	if err != nil {
+		return err
+	}
+	fy, err := bn.NodeFactor(y)
+	if err != nil {
+		return err
+	}
+	joint, err := fx.Multiply(fy)
+	if err != nil {
+		return err
+	}
+	marginal, err := joint.Marginalize([]string{x})
+	if err != nil {
+		return err
+	}
+
+	shared := make(map[string]bool)
+	for _, p := range bn.DAG.Parents(x) {
+		shared[p] = true
+	}
+	for _, p := range bn.DAG.Parents(y) {
+		if p != x {
+			shared[p] = true
+		}
+	}
+	parents := make([]string, 0, len(shared))
+	for p := range shared {
+		parents = append(parents, p)
+	}
+	sort.Strings(parents)
+
+	cpdY, err := bn.conditionalCPD(y, parents, marginal)
+	if err != nil {
+		return err
+	}
+	cpdX, err := bn.conditionalCPD(x, append(append([]string(nil), parents...), y), joint)
+	if err != nil {
+		return err
+	}
+
+	bn.DAG.RemoveEdge(x, y)
+	for _, p := range parents {
+		if err := bn.DAG.AddEdge(p, x); err != nil {
+			return err
+		}
+		if err := bn.DAG.AddEdge(p, y); err != nil {
+			return err
+		}
+	}
+	if err := bn.DAG.AddEdge(y, x); err != nil {
+		return err
+	}
+
+	bn.CPDs[x] = cpdX
+	bn.CPDs[y] = cpdY
+	delete(bn.CustomCPDs, x)
+	delete(bn.CustomCPDs, y)
+	delete(bn.HybridCPDs, x)
+	delete(bn.HybridCPDs, y)
+	bn.untie(x)
+	bn.untie(y)
+	return nil
+}
+
+// RemoveBarrenNodes repeatedly removes leaf nodes that are not in keep. Such
+// barren nodes sum out to one, so the joint distribution of the remaining
+// nodes is unchanged. It returns the removed nodes in removal order.
+func (bn *BayesianNetwork) RemoveBarrenNodes(keep []string) []string {
+	kept := make(map[string]bool, len(keep))
+	for _, v := range keep {
+		kept[v] = true
+	}
+
+	removed := make([]string, 0)
+	for {
+		barren := ""
+		for _, node := range bn.DAG.Nodes() {
+			if !kept[node] && len(bn.DAG.Children(node)) == 0 {
+				barren = node
+				break
+			}
+		}
+		if barren == "" {
+			return removed
+		}
+		_ = bn.RemoveNode(barren) // Cannot fail: the node exists and has no children
+		removed = append(removed, barren)
+	}
+}
+
+// EliminateNode removes a node while preserving the joint distribution of
+// the others, following Shachter: arcs to its children are reversed, the
+// topologically first child each time, until the node is barren, and it is
+// then dropped. The node and its children must be discrete.
+func (bn *BayesianNetwork) EliminateNode(node string) error {
+	if !bn.DAG.HasNode(node) {
+		return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+	}
+
+	for {
+		children := bn.DAG.Children(node)
+		if len(children) == 0 {
+			break
+		}
+
+		order, err := bn.DAG.TopologicalSort()
+		if err != nil {
+			return err
+		}
+		isChild := make(map[string]bool, len(children))
+		for _, c := range children {
+			isChild[c] = true
+		}
+		// The topologically first child cannot be reached through another child
+		for _, v := range order {
+			if isChild[v] {
+				if err := bn.ReverseArc(node, v); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	return bn.RemoveNode(node)
+}
@@ -0,0 +1,288 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// TiedGroup is a set of discrete nodes sharing one CPD. Parents[i] lists the
+// parents of Nodes[i] in corresponding order, so the k-th parent of every
+// node plays the same role.
+type TiedGroup struct {
+	Nodes   []string
+	Parents [][]string
+}
+
+// TieCPDs declares that nodes share one CPD, as the repeated slices of an
+// unrolled temporal model do. parents lists each node's parents in
+// corresponding order; nil matches them in sorted order. Fit and FitWeighted
+// then pool the counts of all the nodes into a single table.
+func (bn *BayesianNetwork) TieCPDs(nodes []string, parents [][]string) error {
+	if len(nodes) < 2 {
+		return fmt.Errorf("tying needs at least 2 nodes, got %d", len(nodes))
+	}
+	if parents != nil && len(parents) != len(nodes) {
+		return fmt.Errorf("got parent lists for %d of %d tied nodes", len(parents), len(nodes))
+	}
+
+	group := &TiedGroup{Nodes: append([]string(nil), nodes...), Parents: make([][]string, len(nodes))}
+	seen := make(map[string]bool, len(nodes))
+	for i, node := range nodes {
+		if !bn.DAG.HasNode(node) {
+			return fmt.Errorf("variable %s: %w", node, ErrUnknownVariable)
+		}
+		if bn.IsContinuous(node) {
+			return fmt.Errorf("cannot tie continuous node %s", node)
+		}
+		if seen[node] {
+			return fmt.Errorf("node %s is listed twice", node)
+		}
+		seen[node] = true
+		if other, ok := bn.tied[node]; ok {
+			return fmt.Errorf("node %s is already tied to %v", node, other.Nodes)
+		}
+
+		sorted := bn.DAG.Parents(node)
+		sort.Strings(sorted)
+		if parents == nil {
+			group.Parents[i] = sorted
+		} else {
+			if !sameNames(sorted, parents[i]) {
+				return fmt.Errorf("parents %v of %s do not match %v: %w", parents[i], node, sorted, ErrParentMismatch)
+			}
+			group.Parents[i] = append([]string(nil), parents[i]...)
+		}
+		if len(group.Parents[i]) != len(group.Parents[0]) {
+			return fmt.Errorf("%s has %d parents, but %s has %d: %w",
+				node, len(group.Parents[i]), nodes[0], len(group.Parents[0]), ErrParentMismatch)
+		}
+	}
+
+	// Known cardinalities must agree role by role
+	check := func(role string, names []string) error {
+		card := 0
+		for _, v := range names {
+			if c, ok := bn.Cardinality[v]; ok && c > 0 {
+				if card > 0 && c != card {
+					return fmt.Errorf("tied %s %v have different cardinalities: %w", role, names, ErrCardinalityMismatch)
+				}
+				card = c
+			}
+		}
+		return nil
+	}
+	if err := check("nodes", group.Nodes); err != nil {
+		return err
+	}
+	for k := range group.Parents[0] {
+		role := make([]string, len(nodes))
+		for i := range nodes {
+			role[i] = group.Parents[i][k]
+		}
+		if err := check(fmt.Sprintf("parents at position %d", k), role); err != nil {
+			return err
+		}
+	}
+
+	if bn.tied == nil {
+		bn.tied = make(map[string]*TiedGroup)
+	}
+	for _, node := range nodes {
+		bn.tied[node] = group
+	}
+	return nil
+}
+
+// TiedGroups returns the groups declared by TieCPDs, ordered by first node
+func (bn *BayesianNetwork) TiedGroups() []TiedGroup {
+	var groups []TiedGroup
+	seen := make(map[*TiedGroup]bool)
+	for _, group := range bn.tied {
+		if !seen[group] {
+			seen[group] = true
+			groups = append(groups, TiedGroup{
+				Nodes:   append([]string(nil), group.Nodes...),
+				Parents: copyParents(group.Parents),
+			})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Nodes[0] < groups[j].Nodes[0] })
+	return groups
+}
+
+// untie removes node from its group, dropping the group once fewer than two
+// nodes remain
+func (bn *BayesianNetwork) untie(node string) {
+	group, ok := bn.tied[node]
+	if !ok {
+		return
+	}
+	delete(bn.tied, node)
+	for i, n := range group.Nodes {
+		if n == node {
+			group.Nodes = append(group.Nodes[:i:i], group.Nodes[i+1:]...)
+			group.Parents = append(group.Parents[:i:i], group.Parents[i+1:]...)
+			break
+		}
+	}
+	if len(group.Nodes) < 2 {
+		for _, n := range group.Nodes {
+			delete(bn.tied, n)
+		}
+	}
+}
+
+// fitTied replaces the CPDs of every tied group with one table learned from
+// the pooled, optionally weighted, counts of its nodes
+func (bn *BayesianNetwork) fitTied(data []Sample, weights []float64) error {
+	for _, group := range bn.TiedGroups() {
+		k := len(group.Parents[0])
+
+		// Size the shared table from every member's states
+		varCard := 0
+		parentCard := make([]int, k)
+		for i, node := range group.Nodes {
+			for _, sample := range data {
+				if val, ok := sample.Discrete[node]; ok && val+1 > varCard {
+					varCard = val + 1
+				}
+				for j, p := range group.Parents[i] {
+					if val, ok := sample.Discrete[p]; ok && val+1 > parentCard[j] {
+						parentCard[j] = val + 1
+					}
+				}
+			}
+		}
+		for i, node := range group.Nodes {
+			varCard = max(varCard, bn.declared[node])
+			for j, p := range group.Parents[i] {
+				parentCard[j] = max(parentCard[j], bn.declared[p])
+			}
+		}
+		if varCard == 0 {
+			return fmt.Errorf("no data for tied nodes %v", group.Nodes)
+		}
+		numRows := 1
+		for _, c := range parentCard {
+			numRows *= c
+		}
+
+		counts := make([][]float64, numRows)
+		for r := range counts {
+			counts[r] = make([]float64, varCard)
+		}
+		for i, node := range group.Nodes {
+			for s, sample := range data {
+				val, ok := sample.Discrete[node]
+				if !ok {
+					continue
+				}
+				rowIdx, stride, valid := 0, 1, true
+				for j := k - 1; j >= 0; j-- {
+					pv, ok := sample.Discrete[group.Parents[i][j]]
+					if !ok {
+						valid = false
+						break
+					}
+					rowIdx += pv * stride
+					stride *= parentCard[j]
+				}
+				if valid {
+					counts[rowIdx][val] += sampleWeight(weights, s)
+				}
+			}
+		}
+
+		// Smoothed once for the shared table, as for a single node
+		values := bn.normalizeCounts(counts, varCard)
+
+		for i, node := range group.Nodes {
+			evidenceCard := make(map[string]int, k)
+			for j, p := range group.Parents[i] {
+				evidenceCard[p] = parentCard[j]
+			}
+			cpd, err := factors.NewTabularCPD(node, varCard, copyRows(values), group.Parents[i], evidenceCard)
+			if err != nil {
+				return fmt.Errorf("tied CPD for %s: %w", node, err)
+			}
+			bn.CPDs[node] = cpd
+			delete(bn.CustomCPDs, node)
+			delete(bn.HybridCPDs, node)
+			bn.VariableType[node] = Discrete
+			bn.Cardinality[node] = varCard
+			for p, c := range evidenceCard {
+				bn.Cardinality[p] = c
+				bn.VariableType[p] = Discrete
+			}
+		}
+	}
+	return nil
+}
+
+// checkTied reports tied nodes whose tables differ from the first member's,
+// matching parents by role
+func (bn *BayesianNetwork) checkTied(add func(string, IssueKind, string, ...interface{})) {
+	for _, group := range bn.TiedGroups() {
+		first, ok := bn.CPDs[group.Nodes[0]]
+		if !ok || !sameOrder(first.Evidence, group.Parents[0]) {
+			continue
+		}
+		for i, node := range group.Nodes[1:] {
+			cpd, ok := bn.CPDs[node]
+			if !ok {
+				continue
+			}
+			if !sameOrder(cpd.Evidence, group.Parents[i+1]) || !sameRows(cpd.Values, first.Values) {
+				add(node, IssueTiedCPD, "CPD differs from the CPD of %s it is tied to", group.Nodes[0])
+			}
+		}
+	}
+}
+
+func sameOrder(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameRows(a, b [][]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if math.Abs(a[i][j]-b[i][j]) > cpdTolerance {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func copyRows(values [][]float64) [][]float64 {
+	out := make([][]float64, len(values))
+	for i, row := range values {
+		out[i] = append([]float64(nil), row...)
+	}
+	return out
+}
+
+func copyParents(parents [][]string) [][]string {
+	out := make([][]string, len(parents))
+	for i, p := range parents {
+		out[i] = append([]string(nil), p...)
+	}
+	return out
+}
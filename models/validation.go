@@ -0,0 +1,298 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/JohnPierman/bngo/factors"
+)
+
+// IssueKind classifies a problem found by CheckModel
+type IssueKind string
+
+const (
+	IssueMissingCPD          IssueKind = "missing-cpd"
+	IssueDuplicateCPD        IssueKind = "duplicate-cpd"
+	IssueParentMismatch      IssueKind = "parent-mismatch"
+	IssueTableShape          IssueKind = "table-shape"
+	IssueRowSum              IssueKind = "row-sum"
+	IssueNonPositiveVariance IssueKind = "non-positive-variance"
+	IssueCardinality         IssueKind = "cardinality-mismatch"
+	IssueHiddenNode          IssueKind = "hidden-node"
+	IssueTiedCPD             IssueKind = "tied-mismatch"
+)
+
+// rowSumTolerance is how far a CPT row may sum from one, matching NewTabularCPD
+const rowSumTolerance = 1e-3
+
+// ValidationIssue is a single problem with one node of a network
+type ValidationIssue struct {
+	Node    string
+	Kind    IssueKind
+	Message string
+}
+
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Node, i.Message)
+}
+
+// ValidationError collects every problem CheckModel found, ordered by node
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Issues) == 1 {
+		return e.Issues[0].String()
+	}
+	parts := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		parts[i] = issue.String()
+	}
+	return fmt.Sprintf("%d model problems: %s", len(e.Issues), strings.Join(parts, "; "))
+}
+
+// Unwrap exposes ErrInvalidModel and the sentinel error of each issue kind,
+// so errors.Is(err, ErrMissingCPD) and similar checks work
+func (e *ValidationError) Unwrap() []error {
+	errs := []error{ErrInvalidModel}
+	seen := make(map[error]bool)
+	for _, issue := range e.Issues {
+		if err := issue.Kind.sentinel(); err != nil && !seen[err] {
+			seen[err] = true
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// sentinel returns the package error matching the issue kind, if any
+func (k IssueKind) sentinel() error {
+	switch k {
+	case IssueMissingCPD:
+		return ErrMissingCPD
+	case IssueParentMismatch:
+		return ErrParentMismatch
+	case IssueTableShape, IssueCardinality:
+		return ErrCardinalityMismatch
+	case IssueRowSum, IssueNonPositiveVariance:
+		return ErrInvalidDistribution
+	}
+	return nil
+}
+
+// Has reports whether any issue is of the given kind
+func (e *ValidationError) Has(kind IssueKind) bool {
+	for _, issue := range e.Issues {
+		if issue.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckModel checks that the network is consistent and fully specified. It
+// reports every problem it finds as a *ValidationError rather than stopping
+// at the first one.
+func (bn *BayesianNetwork) CheckModel() error {
+	issues := make([]ValidationIssue, 0)
+	add := func(node string, kind IssueKind, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Node: node, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, node := range bn.DAG.Nodes() {
+		cpd, hasDiscrete := bn.CPDs[node]
+		gcpd, hasGaussian := bn.GaussianCPDs[node]
+		custom, hasCustom := bn.CustomCPDs[node]
+		continuous, hasContinuous := bn.ContinuousCPDs[node]
+		hybrid, hasHybrid := bn.HybridCPDs[node]
+		parents := bn.DAG.Parents(node)
+		sort.Strings(parents)
+
+		switch {
+		case !hasDiscrete && !hasGaussian && !hasCustom && !hasContinuous && !hasHybrid:
+			add(node, IssueMissingCPD, "node %s has no CPD", node)
+		case hasHybrid && (hasDiscrete || hasGaussian || hasCustom || hasContinuous):
+			add(node, IssueDuplicateCPD, "node %s has both a hybrid and another CPD", node)
+		case hasDiscrete && hasGaussian, hasCustom && hasGaussian:
+			add(node, IssueDuplicateCPD, "node %s has both discrete and Gaussian CPD", node)
+		case hasDiscrete && hasContinuous, hasCustom && hasContinuous:
+			add(node, IssueDuplicateCPD, "node %s has both discrete and continuous CPD", node)
+		case hasGaussian && hasContinuous:
+			add(node, IssueDuplicateCPD, "node %s has both Gaussian and custom continuous CPD", node)
+		case hasDiscrete && hasCustom:
+			add(node, IssueDuplicateCPD, "node %s has both tabular and custom CPD", node)
+		}
+		if bn.hidden[node] && (hasGaussian || hasContinuous || bn.IsContinuous(node)) {
+			add(node, IssueHiddenNode, "hidden node %s must be discrete", node)
+		}
+
+		if hasDiscrete {
+			if !sameNames(parents, cpd.Evidence) {
+				add(node, IssueParentMismatch, "CPD evidence %v does not match parents %v", cpd.Evidence, parents)
+			}
+
+			rows := 1
+			for _, e := range cpd.Evidence {
+				rows *= cpd.EvidenceCard[e]
+				if parentCPD, ok := bn.DiscreteCPD(e); ok && parentCPD.GetCardinality() != cpd.EvidenceCard[e] {
+					add(node, IssueCardinality, "evidence %s has cardinality %d, but its CPD has %d states",
+						e, cpd.EvidenceCard[e], parentCPD.GetCardinality())
+				}
+			}
+			if card, ok := bn.Cardinality[node]; ok && card != cpd.VariableCard {
+				add(node, IssueCardinality, "network cardinality %d does not match CPD cardinality %d",
+					card, cpd.VariableCard)
+			}
+
+			if len(cpd.Values) != rows {
+				add(node, IssueTableShape, "CPD has %d rows, expected %d", len(cpd.Values), rows)
+			}
+			for r, row := range cpd.Values {
+				if len(row) != cpd.VariableCard {
+					add(node, IssueTableShape, "row %d has %d columns, expected %d", r, len(row), cpd.VariableCard)
+					continue
+				}
+				sum := 0.0
+				negative := false
+				for _, p := range row {
+					sum += p
+					negative = negative || p < 0
+				}
+				if negative || math.Abs(sum-1) > rowSumTolerance {
+					add(node, IssueRowSum, "row %d is not a distribution (sum %f)", r, sum)
+				}
+			}
+		}
+
+		if hasCustom {
+			if !sameNames(parents, custom.GetParents()) {
+				add(node, IssueParentMismatch, "CPD parents %v do not match parents %v", custom.GetParents(), parents)
+			}
+			bn.checkCustomCPD(node, custom, add)
+		}
+
+		if hasHybrid {
+			if !sameNames(parents, hybrid.GetParents()) {
+				add(node, IssueParentMismatch, "CPD parents %v do not match parents %v", hybrid.GetParents(), parents)
+			}
+			if card, ok := bn.Cardinality[node]; ok && card != hybrid.GetCardinality() {
+				add(node, IssueCardinality, "network cardinality %d does not match CPD cardinality %d",
+					card, hybrid.GetCardinality())
+			}
+		}
+
+		if hasContinuous {
+			if !sameNames(parents, continuous.GetParents()) {
+				add(node, IssueParentMismatch, "CPD parents %v do not match parents %v", continuous.GetParents(), parents)
+			}
+		}
+
+		if hasGaussian {
+			if !sameNames(parents, gcpd.Parents) {
+				add(node, IssueParentMismatch, "Gaussian CPD parents %v do not match parents %v", gcpd.Parents, parents)
+			}
+			if len(gcpd.Basis) > 0 {
+				if err := gcpd.CheckBasis(); err != nil {
+					add(node, IssueTableShape, "Gaussian CPD basis: %v", err)
+				}
+			}
+
+			discreteParents := gcpd.DiscreteParents()
+			if len(discreteParents) == 0 {
+				if gcpd.Variance <= 0 {
+					add(node, IssueNonPositiveVariance, "variance %f is not positive", gcpd.Variance)
+				}
+			} else {
+				configs := 1
+				for _, p := range discreteParents {
+					configs *= gcpd.Cardinality[p]
+					if parentCPD, ok := bn.DiscreteCPD(p); ok && parentCPD.GetCardinality() != gcpd.Cardinality[p] {
+						add(node, IssueCardinality, "discrete parent %s has cardinality %d, but its CPD has %d states",
+							p, gcpd.Cardinality[p], parentCPD.GetCardinality())
+					}
+				}
+				if len(gcpd.DiscreteStates) != configs {
+					add(node, IssueTableShape, "Gaussian CPD has %d parameter sets, expected %d",
+						len(gcpd.DiscreteStates), configs)
+				}
+
+				keys := make([]string, 0, len(gcpd.DiscreteStates))
+				for key := range gcpd.DiscreteStates {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				for _, key := range keys {
+					if v := gcpd.DiscreteStates[key].Variance; v <= 0 {
+						add(node, IssueNonPositiveVariance, "variance %f for state %s is not positive", v, key)
+					}
+				}
+			}
+		}
+	}
+
+	bn.checkTied(add)
+
+	if len(issues) == 0 {
+		return nil
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Node < issues[j].Node })
+	return &ValidationError{Issues: issues}
+}
+
+// checkCustomCPD checks the table a custom CPD encodes: its parent
+// cardinalities and that each parent configuration gives a distribution
+func (bn *BayesianNetwork) checkCustomCPD(node string, cpd factors.CPD,
+	add func(string, IssueKind, string, ...interface{})) {
+	factor, err := cpd.ToFactor()
+	if err != nil {
+		add(node, IssueTableShape, "CPD cannot be expanded to a factor: %v", err)
+		return
+	}
+
+	for _, parent := range cpd.GetParents() {
+		if parentCPD, ok := bn.DiscreteCPD(parent); ok && parentCPD.GetCardinality() != factor.Cardinality[parent] {
+			add(node, IssueCardinality, "parent %s has cardinality %d, but its CPD has %d states",
+				parent, factor.Cardinality[parent], parentCPD.GetCardinality())
+		}
+	}
+	if factor.Cardinality[node] != cpd.GetCardinality() {
+		add(node, IssueCardinality, "factor cardinality %d does not match CPD cardinality %d",
+			factor.Cardinality[node], cpd.GetCardinality())
+	}
+
+	sums, err := factor.Marginalize([]string{node})
+	if err != nil {
+		add(node, IssueTableShape, "CPD factor does not contain %s", node)
+		return
+	}
+	for r, sum := range sums.Values {
+		if math.Abs(sum-1) > rowSumTolerance {
+			add(node, IssueRowSum, "parent configuration %d is not a distribution (sum %f)", r, sum)
+		}
+	}
+	for _, p := range factor.Values {
+		if p < 0 {
+			add(node, IssueRowSum, "CPD has negative probability %f", p)
+			break
+		}
+	}
+}
+
+// sameNames reports whether two lists hold the same names in any order
+func sameNames(sorted []string, other []string) bool {
+	if len(sorted) != len(other) {
+		return false
+	}
+	otherSorted := make([]string, len(other))
+	copy(otherSorted, other)
+	sort.Strings(otherSorted)
+	for i := range sorted {
+		if sorted[i] != otherSorted[i] {
+			return false
+		}
+	}
+	return true
+}
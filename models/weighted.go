@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// WeightedSample is a sample with a non-negative weight, as produced by
+// likelihood weighting, importance sampling or the E-step of EM. A weight of
+// w counts as w copies of the sample.
+type WeightedSample struct {
+	Sample
+	Weight float64
+}
+
+// WeightedMarginal returns the normalized distribution of a discrete
+// variable over the weighted samples. Samples without the variable are
+// skipped.
+func WeightedMarginal(samples []WeightedSample, variable string, cardinality int) ([]float64, error) {
+	if cardinality < 1 {
+		return nil, fmt.Errorf("variable %s needs a positive cardinality, got %d: %w",
+			variable, cardinality, ErrCardinalityMismatch)
+	}
+
+	dist := make([]float64, cardinality)
+	total := 0.0
+	for i, s := range samples {
+		state, ok := s.Discrete[variable]
+		if !ok {
+			continue
+		}
+		if state < 0 || state >= cardinality {
+			return nil, fmt.Errorf("sample %d: state %d of %s out of range [0, %d): %w",
+				i, state, variable, cardinality, ErrCardinalityMismatch)
+		}
+		if err := checkWeight(i, s.Weight); err != nil {
+			return nil, err
+		}
+		dist[state] += s.Weight
+		total += s.Weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("no weight on variable %s", variable)
+	}
+
+	for k := range dist {
+		dist[k] /= total
+	}
+	return dist, nil
+}
+
+// WeightedMean returns the weighted mean and variance of a continuous
+// variable. Samples without the variable are skipped.
+func WeightedMean(samples []WeightedSample, variable string) (mean, variance float64, err error) {
+	sum, sumSq, total := 0.0, 0.0, 0.0
+	for i, s := range samples {
+		x, ok := s.Continuous[variable]
+		if !ok {
+			continue
+		}
+		if err := checkWeight(i, s.Weight); err != nil {
+			return 0, 0, err
+		}
+		sum += s.Weight * x
+		sumSq += s.Weight * x * x
+		total += s.Weight
+	}
+	if total == 0 {
+		return 0, 0, fmt.Errorf("no weight on variable %s", variable)
+	}
+
+	mean = sum / total
+	variance = math.Max(sumSq/total-mean*mean, 0)
+	return mean, variance, nil
+}
+
+// FitWeighted learns CPD parameters from weighted samples, as FitMixed does
+// from unweighted ones: counts and regression sums are accumulated with each
+// sample's weight, so integer weights give the same fit as repeated samples.
+func (bn *BayesianNetwork) FitWeighted(samples []WeightedSample) error {
+	data := make([]Sample, len(samples))
+	weights := make([]float64, len(samples))
+	for i, s := range samples {
+		if err := checkWeight(i, s.Weight); err != nil {
+			return err
+		}
+		data[i] = s.Sample
+		weights[i] = s.Weight
+	}
+	return bn.fitMixed(data, weights)
+}
+
+// checkWeight rejects negative and non-finite weights
+func checkWeight(i int, w float64) error {
+	if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+		return fmt.Errorf("sample %d has invalid weight %v", i, w)
+	}
+	return nil
+}
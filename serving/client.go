@@ -0,0 +1,86 @@
+package serving
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/JohnPierman/bngo/api/bngopb"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// Client is a typed client of the inference service that converts between
+// bngo types and the wire schema
+type Client struct {
+	conn *grpc.ClientConn
+	rpc  bngopb.InferenceServiceClient
+}
+
+// NewClient connects to an inference service. Pass
+// grpc.WithTransportCredentials to choose TLS or insecure transport.
+func NewClient(target string, opts ...grpc.DialOption) (*Client, error) {
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, rpc: bngopb.NewInferenceServiceClient(conn)}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// RPC returns the generated client for calls not wrapped here
+func (c *Client) RPC() bngopb.InferenceServiceClient {
+	return c.rpc
+}
+
+// LoadModel uploads a network as the next version of name and returns the
+// version number
+func (c *Client) LoadModel(ctx context.Context, name string, bn *models.BayesianNetwork) (int64, error) {
+	m, err := ModelToProto(bn)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.rpc.LoadModel(ctx, &bngopb.LoadModelRequest{Name: name, Model: m})
+	if err != nil {
+		return 0, err
+	}
+	return resp.GetVersion(), nil
+}
+
+// GetModel downloads a version of the network stored under name, the
+// current one for version 0
+func (c *Client) GetModel(ctx context.Context, name string, version int64) (*models.BayesianNetwork, error) {
+	m, err := c.rpc.GetModel(ctx, &bngopb.GetModelRequest{Name: name, Version: version})
+	if err != nil {
+		return nil, err
+	}
+	return ModelFromProto(m)
+}
+
+// ListModels describes every stored version of every network
+func (c *Client) ListModels(ctx context.Context) ([]*bngopb.ModelInfo, error) {
+	resp, err := c.rpc.ListModels(ctx, &bngopb.ListModelsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetModels(), nil
+}
+
+// Query computes P(variables | evidence) on the current version of the
+// named model
+func (c *Client) Query(ctx context.Context, model string, variables []string, evidence models.Sample) (*bngopb.QueryResponse, error) {
+	return c.QueryVersion(ctx, model, 0, variables, evidence)
+}
+
+// QueryVersion is Query pinned to a version of the model
+func (c *Client) QueryVersion(ctx context.Context, model string, version int64, variables []string, evidence models.Sample) (*bngopb.QueryResponse, error) {
+	return c.rpc.Query(ctx, &bngopb.QueryRequest{
+		Model:     model,
+		Variables: variables,
+		Evidence:  EvidenceToProto(evidence),
+		Version:   version,
+	})
+}
@@ -0,0 +1,292 @@
+package serving
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/JohnPierman/bngo/api/bngopb"
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/inference"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// ModelToProto encodes a network, writing custom CPDs as tables. Networks
+// with a preprocessing pipeline have no protobuf encoding; serve them from
+// JSON files instead.
+func ModelToProto(bn *models.BayesianNetwork) (*bngopb.Model, error) {
+	if bn.Pipeline() != nil {
+		return nil, fmt.Errorf("preprocessing pipeline has no protobuf encoding")
+	}
+	m := &bngopb.Model{}
+
+	edges := bn.Edges()
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	for _, e := range edges {
+		m.Edges = append(m.Edges, &bngopb.Edge{Parent: e[0], Child: e[1]})
+	}
+
+	for _, node := range bn.Nodes() {
+		v := &bngopb.Variable{Name: node}
+		switch bn.VariableType[node] {
+		case models.Discrete:
+			v.Type = bngopb.VariableType_VARIABLE_TYPE_DISCRETE
+			v.Cardinality = int32(bn.Cardinality[node])
+			v.States = bn.StateNames(node)
+		case models.Continuous:
+			v.Type = bngopb.VariableType_VARIABLE_TYPE_CONTINUOUS
+		}
+		m.Variables = append(m.Variables, v)
+
+		if _, ok := bn.DiscreteCPD(node); ok {
+			cpd, err := bn.AsTabularCPD(node)
+			if err != nil {
+				return nil, err
+			}
+			t := &bngopb.TabularCPD{Variable: node, Evidence: cpd.Evidence}
+			for _, row := range cpd.Values {
+				t.Values = append(t.Values, row...)
+			}
+			m.Cpds = append(m.Cpds, t)
+		}
+
+		if _, ok := bn.HybridCPDs[node]; ok {
+			return nil, fmt.Errorf("hybrid CPD of %s has no protobuf encoding", node)
+		}
+		if _, ok := bn.ContinuousCPDs[node]; ok {
+			return nil, fmt.Errorf("custom continuous CPD of %s has no protobuf encoding", node)
+		}
+		if cpd, ok := bn.GaussianCPDs[node]; ok {
+			if cpd.IsHeteroscedastic() {
+				// The protobuf format has no log-variance coefficients
+				return nil, fmt.Errorf("CPD of %s: %w", node, factors.ErrHeteroscedastic)
+			}
+			if len(cpd.Basis) > 0 {
+				// Nor has it basis terms
+				return nil, fmt.Errorf("CPD of %s: %w", node, factors.ErrNonlinearMean)
+			}
+			g := &bngopb.GaussianCPD{Variable: node, Parents: cpd.Parents}
+			if len(cpd.DiscreteParents()) == 0 {
+				g.Intercept = cpd.Intercept
+				g.Coefficients = cpd.Coefficients
+				g.Variance = cpd.Variance
+			} else {
+				g.States = make(map[string]*bngopb.GaussianParams, len(cpd.DiscreteStates))
+				for key, params := range cpd.DiscreteStates {
+					g.States[key] = &bngopb.GaussianParams{
+						Mean:         params.Mean,
+						Variance:     params.Variance,
+						Coefficients: params.Coefficients,
+					}
+				}
+			}
+			m.GaussianCpds = append(m.GaussianCpds, g)
+		}
+	}
+
+	return m, nil
+}
+
+// ModelFromProto decodes a network, validating each CPD against the
+// structure as AddCPD does
+func ModelFromProto(m *bngopb.Model) (*models.BayesianNetwork, error) {
+	edges := make([][2]string, len(m.GetEdges()))
+	for i, e := range m.GetEdges() {
+		edges[i] = [2]string{e.GetParent(), e.GetChild()}
+	}
+	bn, err := models.NewBayesianNetwork(edges)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := make(map[string]*bngopb.Variable, len(m.GetVariables()))
+	for _, v := range m.GetVariables() {
+		bn.DAG.AddNode(v.GetName())
+		variables[v.GetName()] = v
+		switch v.GetType() {
+		case bngopb.VariableType_VARIABLE_TYPE_DISCRETE:
+			bn.VariableType[v.GetName()] = models.Discrete
+			bn.Cardinality[v.GetName()] = int(v.GetCardinality())
+		case bngopb.VariableType_VARIABLE_TYPE_CONTINUOUS:
+			bn.VariableType[v.GetName()] = models.Continuous
+		}
+	}
+
+	for _, c := range m.GetCpds() {
+		card := int(variables[c.GetVariable()].GetCardinality())
+		if card < 1 {
+			return nil, fmt.Errorf("CPD for %s: variable has no cardinality: %w", c.GetVariable(), models.ErrCardinalityMismatch)
+		}
+		evidenceCard := make(map[string]int, len(c.GetEvidence()))
+		for _, e := range c.GetEvidence() {
+			evidenceCard[e] = int(variables[e].GetCardinality())
+		}
+		flat := c.GetValues()
+		if len(flat)%card != 0 {
+			return nil, fmt.Errorf("CPD for %s has %d values, not a multiple of %d: %w",
+				c.GetVariable(), len(flat), card, models.ErrCardinalityMismatch)
+		}
+		values := make([][]float64, len(flat)/card)
+		for r := range values {
+			values[r] = flat[r*card : (r+1)*card]
+		}
+
+		cpd, err := factors.NewTabularCPD(c.GetVariable(), card, values, c.GetEvidence(), evidenceCard)
+		if err != nil {
+			return nil, fmt.Errorf("CPD for %s: %w", c.GetVariable(), err)
+		}
+		for _, v := range append([]string{c.GetVariable()}, c.GetEvidence()...) {
+			if states := variables[v].GetStates(); len(states) > 0 {
+				if err := cpd.SetStateNames(v, states); err != nil {
+					return nil, fmt.Errorf("CPD for %s: %w", c.GetVariable(), err)
+				}
+			}
+		}
+		if err := bn.AddCPD(cpd); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, g := range m.GetGaussianCpds() {
+		var discrete, continuous []string
+		cardinality := make(map[string]int)
+		for _, p := range g.GetParents() {
+			if variables[p].GetType() == bngopb.VariableType_VARIABLE_TYPE_DISCRETE {
+				discrete = append(discrete, p)
+				cardinality[p] = int(variables[p].GetCardinality())
+			} else {
+				continuous = append(continuous, p)
+			}
+		}
+
+		var cpd *factors.LinearGaussianCPD
+		if len(discrete) == 0 {
+			coefficients := g.GetCoefficients()
+			if coefficients == nil {
+				coefficients = make(map[string]float64)
+			}
+			cpd, err = factors.NewLinearGaussianCPD(g.GetVariable(), g.GetParents(), g.GetIntercept(), coefficients, g.GetVariance())
+		} else {
+			states := make(map[string]factors.GaussianParams, len(g.GetStates()))
+			for key, params := range g.GetStates() {
+				states[key] = factors.GaussianParams{
+					Mean:         params.GetMean(),
+					Variance:     params.GetVariance(),
+					Coefficients: params.GetCoefficients(),
+				}
+			}
+			cpd, err = factors.NewCLGCPD(g.GetVariable(), discrete, continuous, cardinality, states)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CPD for %s: %w", g.GetVariable(), err)
+		}
+		if err := bn.AddGaussianCPD(cpd); err != nil {
+			return nil, err
+		}
+	}
+
+	return bn, nil
+}
+
+// EvidenceFromProto resolves state labels against the network's state names.
+// A network with a preprocessing pipeline takes the evidence as a raw record
+// and transforms it first.
+func EvidenceFromProto(bn *models.BayesianNetwork, e *bngopb.Evidence) (models.Sample, error) {
+	if bn.Pipeline() != nil {
+		record := make(models.Record, len(e.GetDiscrete())+len(e.GetLabels())+len(e.GetContinuous()))
+		for v, s := range e.GetDiscrete() {
+			record[v] = int(s)
+		}
+		for v, label := range e.GetLabels() {
+			record[v] = label
+		}
+		for v, x := range e.GetContinuous() {
+			record[v] = x
+		}
+		return bn.Preprocess(record)
+	}
+	evidence := models.Sample{
+		Discrete:   make(map[string]int, len(e.GetDiscrete())+len(e.GetLabels())),
+		Continuous: make(map[string]float64, len(e.GetContinuous())),
+	}
+	for v, s := range e.GetDiscrete() {
+		evidence.Discrete[v] = int(s)
+	}
+	for v, label := range e.GetLabels() {
+		state := -1
+		for i, name := range bn.StateNames(v) {
+			if name == label {
+				state = i
+				break
+			}
+		}
+		if state < 0 {
+			return evidence, fmt.Errorf("unknown state %q of %s", label, v)
+		}
+		evidence.Discrete[v] = state
+	}
+	for v, x := range e.GetContinuous() {
+		evidence.Continuous[v] = x
+	}
+	return evidence, nil
+}
+
+// EvidenceToProto encodes evidence by state index
+func EvidenceToProto(evidence models.Sample) *bngopb.Evidence {
+	e := &bngopb.Evidence{
+		Discrete:   make(map[string]int32, len(evidence.Discrete)),
+		Continuous: evidence.Continuous,
+	}
+	for v, s := range evidence.Discrete {
+		e.Discrete[v] = int32(s)
+	}
+	return e
+}
+
+func distributionToProto(f *factors.DiscreteFactor) *bngopb.DiscreteDistribution {
+	d := &bngopb.DiscreteDistribution{Variables: f.Variables, Values: f.Values}
+	for _, v := range f.Variables {
+		d.Cardinality = append(d.Cardinality, int32(f.Cardinality[v]))
+	}
+	return d
+}
+
+func mixtureToProto(result *inference.MixedQueryResult) []*bngopb.MixtureComponent {
+	components := make([]*bngopb.MixtureComponent, 0, len(result.Components))
+	for _, c := range result.Components {
+		pc := &bngopb.MixtureComponent{
+			Discrete: make(map[string]int32, len(c.Discrete)),
+			Weight:   c.Weight,
+		}
+		for v, s := range c.Discrete {
+			pc.Discrete[v] = int32(s)
+		}
+		if c.Gaussian != nil {
+			pc.Variables = result.ContinuousVariables
+			for _, v := range result.ContinuousVariables {
+				pc.Mean = append(pc.Mean, c.Gaussian.MeanOf(v))
+				for _, w := range result.ContinuousVariables {
+					pc.Covariance = append(pc.Covariance, c.Gaussian.CovarianceOf(v, w))
+				}
+			}
+		}
+		components = append(components, pc)
+	}
+	return components
+}
+
+func statsToProto(stats *inference.QueryStats) *bngopb.QueryStats {
+	return &bngopb.QueryStats{
+		Multiplications:  int64(stats.Multiplications),
+		MaxFactorSize:    int64(stats.MaxFactorSize),
+		EliminationOrder: stats.EliminationOrder,
+		Configurations:   int64(stats.Configurations),
+		WallTime:         durationpb.New(stats.Duration),
+	}
+}
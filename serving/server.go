@@ -0,0 +1,292 @@
+// Package serving exposes bngo inference over gRPC, using the schema in
+// api/bngopb
+package serving
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/JohnPierman/bngo/api/bngopb"
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/inference"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// DefaultRetainedVersions is the number of versions of each model a new
+// server keeps, so clients pinned to the previous version survive a swap
+const DefaultRetainedVersions = 2
+
+// Server implements bngopb.InferenceServiceServer. Models are stored by name
+// as numbered versions; loading a name again adds a version and swaps it in
+// atomically, while queries already running finish on the version they
+// started with. Each version is compiled when loaded, so queries run
+// concurrently without locking the model.
+type Server struct {
+	bngopb.UnimplementedInferenceServiceServer
+
+	mu       sync.RWMutex
+	models   map[string]*modelVersions
+	retained int
+}
+
+// modelVersions holds the retained versions of one name, oldest first
+type modelVersions struct {
+	versions []*entry
+	next     int64
+}
+
+// entry is one loaded version of a model and the engine serving it
+type entry struct {
+	version  int64
+	source   string
+	loadedAt time.Time
+	compiled *models.CompiledModel
+	engine   *inference.VariableElimination
+}
+
+// NewServer creates a server with no models loaded
+func NewServer() *Server {
+	return &Server{models: make(map[string]*modelVersions), retained: DefaultRetainedVersions}
+}
+
+// SetRetainedVersions sets how many versions of each model are kept. Older
+// versions are dropped on the next load; n below 1 keeps only the current one.
+func (s *Server) SetRetainedVersions(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retained = max(n, 1)
+}
+
+// Register attaches the service to a gRPC server
+func (s *Server) Register(g *grpc.Server) {
+	bngopb.RegisterInferenceServiceServer(g, s)
+}
+
+// AddModel compiles a network and makes it the current version of name,
+// returning the new version number. Later changes to bn are not seen by the
+// server.
+func (s *Server) AddModel(name string, bn *models.BayesianNetwork) (int64, error) {
+	return s.add(name, bn, "")
+}
+
+// add stores bn as the next version of name, recording where it came from
+func (s *Server) add(name string, bn *models.BayesianNetwork, source string) (int64, error) {
+	compiled, err := bn.Compile()
+	if err != nil {
+		return 0, err
+	}
+	e := &entry{
+		source:   source,
+		loadedAt: time.Now(),
+		compiled: compiled,
+		engine:   inference.NewCompiledVariableElimination(compiled),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mv, ok := s.models[name]
+	if !ok {
+		mv = &modelVersions{}
+		s.models[name] = mv
+	}
+	mv.next++
+	e.version = mv.next
+	mv.versions = append(mv.versions, e)
+	if drop := len(mv.versions) - s.retained; drop > 0 {
+		mv.versions = append([]*entry(nil), mv.versions[drop:]...)
+	}
+	return e.version, nil
+}
+
+// RemoveModel drops every version of name. Version numbers keep counting
+// from where they were, so a client pinned to a removed version gets
+// NotFound rather than a later model loaded under the same name.
+func (s *Server) RemoveModel(name string) {
+	s.mu.Lock()
+	if mv, ok := s.models[name]; ok {
+		mv.versions = nil
+	}
+	s.mu.Unlock()
+}
+
+// lookup finds a version of a model, the current one for version 0
+func (s *Server) lookup(name string, version int64) (*entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mv, ok := s.models[name]
+	if !ok || len(mv.versions) == 0 {
+		return nil, status.Errorf(codes.NotFound, "model %q is not loaded", name)
+	}
+	if version == 0 {
+		return mv.versions[len(mv.versions)-1], nil
+	}
+	for _, e := range mv.versions {
+		if e.version == version {
+			return e, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "model %q has no version %d", name, version)
+}
+
+// Models describes every retained version, sorted by name and version
+func (s *Server) Models() []*bngopb.ModelInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.models))
+	for name, mv := range s.models {
+		if len(mv.versions) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var infos []*bngopb.ModelInfo
+	for _, name := range names {
+		versions := s.models[name].versions
+		for i, e := range versions {
+			info := &bngopb.ModelInfo{
+				Name:     name,
+				Version:  e.version,
+				Current:  i == len(versions)-1,
+				Source:   e.source,
+				LoadedAt: timestamppb.New(e.loadedAt),
+			}
+			bn := e.compiled.Network()
+			for _, node := range bn.Nodes() {
+				if bn.IsContinuous(node) {
+					info.ContinuousVariables++
+				} else {
+					info.DiscreteVariables++
+				}
+			}
+			info.Edges = int32(len(bn.Edges()))
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// LoadModel decodes, validates and stores a model
+func (s *Server) LoadModel(_ context.Context, req *bngopb.LoadModelRequest) (*bngopb.LoadModelResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "model name is required")
+	}
+	bn, err := ModelFromProto(req.GetModel())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	version, err := s.add(req.GetName(), bn, "")
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &bngopb.LoadModelResponse{Version: version}, nil
+}
+
+// GetModel returns a stored model
+func (s *Server) GetModel(_ context.Context, req *bngopb.GetModelRequest) (*bngopb.Model, error) {
+	e, err := s.lookup(req.GetName(), req.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	m, err := ModelToProto(e.compiled.Network())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return m, nil
+}
+
+// ListModels describes the stored models
+func (s *Server) ListModels(context.Context, *bngopb.ListModelsRequest) (*bngopb.ListModelsResponse, error) {
+	return &bngopb.ListModelsResponse{Models: s.Models()}, nil
+}
+
+// Query answers a posterior query. Purely discrete queries return a joint
+// distribution; queries with continuous variables or evidence return a
+// mixture. The query's cost is included when the request asks for stats.
+func (s *Server) Query(_ context.Context, req *bngopb.QueryRequest) (*bngopb.QueryResponse, error) {
+	e, err := s.lookup(req.GetModel(), req.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	if len(req.GetVariables()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no query variables")
+	}
+
+	bn := e.compiled.Network()
+	evidence, err := EvidenceFromProto(bn, req.GetEvidence())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	mixed := len(evidence.Continuous) > 0
+	for _, v := range req.GetVariables() {
+		if !bn.IsDiscrete(v) && !bn.IsContinuous(v) {
+			return nil, status.Errorf(codes.InvalidArgument, "query variable %s: %v", v, models.ErrUnknownVariable)
+		}
+		mixed = mixed || bn.IsContinuous(v)
+	}
+
+	resp := &bngopb.QueryResponse{Version: e.version}
+	var stats *inference.QueryStats
+	if !mixed {
+		var result *factors.DiscreteFactor
+		if req.GetStats() {
+			result, stats, err = e.engine.QueryWithStats(req.GetVariables(), evidence.Discrete)
+		} else {
+			result, err = e.engine.Query(req.GetVariables(), evidence.Discrete)
+		}
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		resp.Distribution = distributionToProto(result)
+	} else {
+		var result *inference.MixedQueryResult
+		if req.GetStats() {
+			result, stats, err = e.engine.QueryMixedWithStats(req.GetVariables(), evidence)
+		} else {
+			result, err = e.engine.QueryMixed(req.GetVariables(), evidence)
+		}
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		resp.Components = mixtureToProto(result)
+	}
+	if stats != nil {
+		resp.Stats = statsToProto(stats)
+	}
+	return resp, nil
+}
+
+// toStatus maps library errors to gRPC codes: problems with the caller's
+// model, variables or evidence are InvalidArgument, impossible evidence is
+// FailedPrecondition, and anything else is Internal
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, inference.ErrImpossibleEvidence):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, models.ErrInvalidModel),
+		errors.Is(err, models.ErrMissingCPD),
+		errors.Is(err, models.ErrParentMismatch),
+		errors.Is(err, models.ErrUnknownVariable),
+		errors.Is(err, models.ErrCycle),
+		errors.Is(err, models.ErrCardinalityMismatch),
+		errors.Is(err, models.ErrInvalidDistribution),
+		errors.Is(err, models.ErrInvalidState),
+		errors.Is(err, models.ErrContinuousVariables),
+		errors.Is(err, inference.ErrObservedQuery),
+		errors.Is(err, inference.ErrConflictingEvidence),
+		errors.Is(err, inference.ErrUnsupportedVariable):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,320 @@
+package serving
+
+import (
+	"context"
+	"math"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/JohnPierman/bngo/api/bngopb"
+	"github.com/JohnPierman/bngo/examples"
+	"github.com/JohnPierman/bngo/inference"
+	"github.com/JohnPierman/bngo/models"
+	"github.com/JohnPierman/bngo/utils"
+)
+
+// startServer serves s over an in-memory listener and returns a client
+func startServer(t *testing.T, s *Server) *Client {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	s.Register(g)
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+
+	c, err := NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestServerDiscreteQuery(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("GetStudentModel failed: %v", err)
+	}
+	ve, err := inference.NewVariableElimination(bn)
+	if err != nil {
+		t.Fatalf("NewVariableElimination failed: %v", err)
+	}
+	want, err := ve.Query([]string{"Intelligence"}, map[string]int{"Grade": 0})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	c := startServer(t, NewServer())
+	ctx := context.Background()
+	if _, err := c.LoadModel(ctx, "student", bn); err != nil {
+		t.Fatalf("LoadModel failed: %v", err)
+	}
+
+	resp, err := c.Query(ctx, "student", []string{"Intelligence"},
+		models.Sample{Discrete: map[string]int{"Grade": 0}})
+	if err != nil {
+		t.Fatalf("Query RPC failed: %v", err)
+	}
+	got := resp.GetDistribution().GetValues()
+	if len(got) != len(want.Values) {
+		t.Fatalf("got %d values, want %d", len(got), len(want.Values))
+	}
+	for i := range got {
+		if math.Abs(got[i]-want.Values[i]) > 1e-12 {
+			t.Errorf("P(Intelligence=%d | Grade=0) = %v, want %v", i, got[i], want.Values[i])
+		}
+	}
+
+	// The stored model round-trips through the schema
+	stored, err := c.GetModel(ctx, "student", 0)
+	if err != nil {
+		t.Fatalf("GetModel failed: %v", err)
+	}
+	if len(stored.Nodes()) != len(bn.Nodes()) || len(stored.Edges()) != len(bn.Edges()) {
+		t.Errorf("stored model has %d nodes and %d edges, want %d and %d",
+			len(stored.Nodes()), len(stored.Edges()), len(bn.Nodes()), len(bn.Edges()))
+	}
+}
+
+func TestServerMixedQuery(t *testing.T) {
+	bn, err := examples.GetTemperatureModel()
+	if err != nil {
+		t.Fatalf("GetTemperatureModel failed: %v", err)
+	}
+	s := NewServer()
+	if _, err := s.AddModel("weather", bn); err != nil {
+		t.Fatalf("AddModel failed: %v", err)
+	}
+	c := startServer(t, s)
+
+	resp, err := c.Query(context.Background(), "weather", []string{"IceCreamSales"},
+		models.Sample{Discrete: map[string]int{"Season": 2}})
+	if err != nil {
+		t.Fatalf("Query RPC failed: %v", err)
+	}
+	components := resp.GetComponents()
+	if len(components) != 1 {
+		t.Fatalf("got %d components, want 1", len(components))
+	}
+	// Sales = 10*Temperature - 200 with Temperature ~ N(85, 64) in summer
+	comp := components[0]
+	if math.Abs(comp.GetMean()[0]-650) > 1e-9 {
+		t.Errorf("mean = %v, want 650", comp.GetMean()[0])
+	}
+	if math.Abs(comp.GetCovariance()[0]-8900) > 1e-9 {
+		t.Errorf("variance = %v, want 8900", comp.GetCovariance()[0])
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("GetStudentModel failed: %v", err)
+	}
+	s := NewServer()
+	if _, err := s.AddModel("student", bn); err != nil {
+		t.Fatalf("AddModel failed: %v", err)
+	}
+	c := startServer(t, s)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		model     string
+		variables []string
+		want      codes.Code
+	}{
+		{"unknown model", "missing", []string{"Grade"}, codes.NotFound},
+		{"unknown variable", "student", []string{"Nope"}, codes.InvalidArgument},
+		{"no variables", "student", nil, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.Query(ctx, tt.model, tt.variables, models.Sample{})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("code = %v, want %v (err %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestServerVersions(t *testing.T) {
+	student, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("GetStudentModel failed: %v", err)
+	}
+	sprinkler, err := examples.GetSprinklerModel()
+	if err != nil {
+		t.Fatalf("GetSprinklerModel failed: %v", err)
+	}
+	c := startServer(t, NewServer())
+	ctx := context.Background()
+
+	for i, bn := range []*models.BayesianNetwork{student, sprinkler, student} {
+		version, err := c.LoadModel(ctx, "m", bn)
+		if err != nil {
+			t.Fatalf("LoadModel failed: %v", err)
+		}
+		if version != int64(i+1) {
+			t.Errorf("version = %d, want %d", version, i+1)
+		}
+	}
+
+	infos, err := c.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(infos) != DefaultRetainedVersions {
+		t.Fatalf("got %d versions, want %d", len(infos), DefaultRetainedVersions)
+	}
+	if infos[0].GetVersion() != 2 || infos[0].GetCurrent() || infos[1].GetVersion() != 3 || !infos[1].GetCurrent() {
+		t.Errorf("unexpected versions %v", infos)
+	}
+	if got := infos[0].GetDiscreteVariables(); got != int32(len(sprinkler.Nodes())) {
+		t.Errorf("version 2 has %d discrete variables, want %d", got, len(sprinkler.Nodes()))
+	}
+
+	// Version 2 is the sprinkler network and still answers pinned queries
+	resp, err := c.QueryVersion(ctx, "m", 2, []string{"Rain"}, models.Sample{})
+	if err != nil {
+		t.Fatalf("QueryVersion failed: %v", err)
+	}
+	if resp.GetVersion() != 2 {
+		t.Errorf("answered by version %d, want 2", resp.GetVersion())
+	}
+	if _, err := c.QueryVersion(ctx, "m", 1, []string{"Grade"}, models.Sample{}); status.Code(err) != codes.NotFound {
+		t.Errorf("dropped version: code = %v, want NotFound", status.Code(err))
+	}
+}
+
+func TestServerRemoveModel(t *testing.T) {
+	student, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("GetStudentModel failed: %v", err)
+	}
+	sprinkler, err := examples.GetSprinklerModel()
+	if err != nil {
+		t.Fatalf("GetSprinklerModel failed: %v", err)
+	}
+	s := NewServer()
+	c := startServer(t, s)
+	ctx := context.Background()
+
+	if _, err := c.LoadModel(ctx, "m", student); err != nil {
+		t.Fatalf("LoadModel failed: %v", err)
+	}
+	s.RemoveModel("m")
+	if _, err := c.Query(ctx, "m", []string{"Grade"}, models.Sample{}); status.Code(err) != codes.NotFound {
+		t.Errorf("removed model: code = %v, want NotFound", status.Code(err))
+	}
+	infos, err := c.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(infos) != 0 {
+		t.Errorf("removed model is still listed: %v", infos)
+	}
+
+	// Reloading the name continues the numbering, so a client pinned to the
+	// removed version 1 is not served the sprinkler network
+	version, err := c.LoadModel(ctx, "m", sprinkler)
+	if err != nil {
+		t.Fatalf("LoadModel failed: %v", err)
+	}
+	if version != 2 {
+		t.Errorf("version after removal = %d, want 2", version)
+	}
+	if _, err := c.QueryVersion(ctx, "m", 1, []string{"Rain"}, models.Sample{}); status.Code(err) != codes.NotFound {
+		t.Errorf("removed version: code = %v, want NotFound", status.Code(err))
+	}
+	if _, err := c.QueryVersion(ctx, "m", 2, []string{"Rain"}, models.Sample{}); err != nil {
+		t.Errorf("QueryVersion failed: %v", err)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	student, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("GetStudentModel failed: %v", err)
+	}
+	sprinkler, err := examples.GetSprinklerModel()
+	if err != nil {
+		t.Fatalf("GetSprinklerModel failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := utils.SaveModelJSON(path, student); err != nil {
+		t.Fatalf("SaveModelJSON failed: %v", err)
+	}
+
+	s := NewServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.WatchFile(ctx, "m", path, 5*time.Millisecond); err != nil {
+		t.Fatalf("WatchFile failed: %v", err)
+	}
+	if err := utils.SaveModelJSON(path, sprinkler); err != nil {
+		t.Fatalf("SaveModelJSON failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		e, err := s.lookup("m", 0)
+		if err != nil {
+			t.Fatalf("lookup failed: %v", err)
+		}
+		if e.version == 2 {
+			if e.source != path || !e.compiled.Network().IsDiscrete("Rain") {
+				t.Errorf("version 2 was not loaded from the changed file")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("changed file was not reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServerQueryStats(t *testing.T) {
+	bn, err := examples.GetStudentModel()
+	if err != nil {
+		t.Fatalf("GetStudentModel failed: %v", err)
+	}
+	s := NewServer()
+	if _, err := s.AddModel("student", bn); err != nil {
+		t.Fatalf("AddModel failed: %v", err)
+	}
+	c := startServer(t, s)
+	ctx := context.Background()
+
+	for _, want := range []bool{false, true} {
+		resp, err := c.RPC().Query(ctx, &bngopb.QueryRequest{
+			Model:     "student",
+			Variables: []string{"Letter"},
+			Stats:     want,
+		})
+		if err != nil {
+			t.Fatalf("Query RPC failed: %v", err)
+		}
+		stats := resp.GetStats()
+		if (stats != nil) != want {
+			t.Fatalf("stats = %v with stats requested %v", stats, want)
+		}
+		if want && (stats.GetMultiplications() == 0 || len(stats.GetEliminationOrder()) != 3 || stats.GetWallTime() == nil) {
+			t.Errorf("incomplete stats %v", stats)
+		}
+	}
+}
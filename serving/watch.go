@@ -0,0 +1,60 @@
+package serving
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/JohnPierman/bngo/utils"
+)
+
+// LoadFile reads a .bif or .json model and makes it the current version of
+// name, returning the new version number
+func (s *Server) LoadFile(name, path string) (int64, error) {
+	bn, err := utils.LoadModel(path)
+	if err != nil {
+		return 0, err
+	}
+	return s.add(name, bn, path)
+}
+
+// WatchFile loads a model file as name and then polls it every interval
+// until ctx is done, loading a new version whenever the file's size or
+// modification time changes. Only the initial load error is returned; a
+// reload that fails is logged and the previous version keeps serving.
+func (s *Server) WatchFile(ctx context.Context, name, path string, interval time.Duration) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if _, err := s.LoadFile(name, path); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			next, err := os.Stat(path)
+			if err != nil {
+				log.Printf("bngo: serving: watching %s: %v", path, err)
+				continue
+			}
+			if next.Size() == info.Size() && next.ModTime().Equal(info.ModTime()) {
+				continue
+			}
+			info = next
+			if _, err := s.LoadFile(name, path); err != nil {
+				log.Printf("bngo: serving: reloading %s from %s: %v", name, path, err)
+			}
+		}
+	}()
+	return nil
+}
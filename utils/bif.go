@@ -0,0 +1,462 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// LoadBIF reads a discrete network from a Bayesian Interchange Format file
+func LoadBIF(filename string) (*models.BayesianNetwork, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return ReadBIF(file)
+}
+
+// SaveBIF writes a discrete network to a Bayesian Interchange Format file
+func SaveBIF(filename string, bn *models.BayesianNetwork) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := WriteBIF(file, bn); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadBIF parses a network in the Bayesian Interchange Format. Variables must
+// be discrete; their state labels become the CPDs' state names. Probability
+// blocks may list one row per parent configuration, a default row, or a
+// single table in which the child varies slowest and the last parent
+// fastest. Properties are ignored.
+func ReadBIF(r io.Reader) (*models.BayesianNetwork, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &bifParser{tokens: tokenize(string(src), bifPunctuation), format: "BIF"}
+
+	var order []string
+	states := make(map[string][]string)
+	type block struct {
+		child   string
+		parents []string
+		rows    map[int][]float64
+		def     []float64
+		table   []float64
+	}
+	var blocks []block
+
+	for !p.done() {
+		switch keyword := p.next(); keyword {
+		case "network":
+			p.next()
+			if err := p.skipBlock(); err != nil {
+				return nil, err
+			}
+		case "variable":
+			name := p.next()
+			if err := p.expect("{"); err != nil {
+				return nil, err
+			}
+			for p.peek() != "}" && !p.done() {
+				if p.next() != "type" {
+					p.skipStatement()
+					continue
+				}
+				if vtype := p.next(); vtype != "discrete" {
+					return nil, fmt.Errorf("variable %s has type %s: %w", name, vtype, models.ErrContinuousVariables)
+				}
+				if err := p.expect("["); err != nil {
+					return nil, err
+				}
+				card, err := strconv.Atoi(p.next())
+				if err != nil {
+					return nil, fmt.Errorf("variable %s: invalid cardinality: %w", name, err)
+				}
+				if err := p.expect("]"); err != nil {
+					return nil, err
+				}
+				list, err := p.list("{", "}")
+				if err != nil {
+					return nil, err
+				}
+				if len(list) != card {
+					return nil, fmt.Errorf("variable %s declares %d states but lists %d: %w",
+						name, card, len(list), models.ErrCardinalityMismatch)
+				}
+				states[name] = list
+				p.skipStatement()
+			}
+			if err := p.expect("}"); err != nil {
+				return nil, err
+			}
+			order = append(order, name)
+		case "probability":
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			b := block{child: p.next(), rows: make(map[int][]float64)}
+			if p.peek() == "|" {
+				p.next()
+				for p.peek() != ")" && !p.done() {
+					if tok := p.next(); tok != "," {
+						b.parents = append(b.parents, tok)
+					}
+				}
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			if err := p.expect("{"); err != nil {
+				return nil, err
+			}
+			for p.peek() != "}" && !p.done() {
+				switch p.peek() {
+				case "table":
+					p.next()
+					if b.table, err = p.numbers(); err != nil {
+						return nil, fmt.Errorf("probability of %s: %w", b.child, err)
+					}
+				case "default":
+					p.next()
+					if b.def, err = p.numbers(); err != nil {
+						return nil, fmt.Errorf("probability of %s: %w", b.child, err)
+					}
+				case "(":
+					config, err := p.list("(", ")")
+					if err != nil {
+						return nil, err
+					}
+					row, err := bifRowIndex(config, b.parents, states)
+					if err != nil {
+						return nil, fmt.Errorf("probability of %s: %w", b.child, err)
+					}
+					if b.rows[row], err = p.numbers(); err != nil {
+						return nil, fmt.Errorf("probability of %s: %w", b.child, err)
+					}
+				default:
+					p.skipStatement()
+				}
+			}
+			if err := p.expect("}"); err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, b)
+		default:
+			return nil, fmt.Errorf("unexpected %q in BIF input", keyword)
+		}
+	}
+
+	var edges [][2]string
+	for _, b := range blocks {
+		for _, parent := range b.parents {
+			edges = append(edges, [2]string{parent, b.child})
+		}
+	}
+	bn, err := models.NewBayesianNetwork(edges)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range order {
+		bn.DAG.AddNode(name)
+	}
+
+	for _, b := range blocks {
+		card := len(states[b.child])
+		if card == 0 {
+			return nil, fmt.Errorf("probability of undeclared variable %s: %w", b.child, models.ErrUnknownVariable)
+		}
+		evidenceCard := make(map[string]int, len(b.parents))
+		numRows := 1
+		for _, parent := range b.parents {
+			if len(states[parent]) == 0 {
+				return nil, fmt.Errorf("parent %s of %s is undeclared: %w", parent, b.child, models.ErrUnknownVariable)
+			}
+			evidenceCard[parent] = len(states[parent])
+			numRows *= len(states[parent])
+		}
+
+		values := make([][]float64, numRows)
+		if b.table != nil {
+			if len(b.table) != numRows*card {
+				return nil, fmt.Errorf("table for %s has %d entries, expected %d: %w",
+					b.child, len(b.table), numRows*card, models.ErrCardinalityMismatch)
+			}
+			for r := range values {
+				values[r] = make([]float64, card)
+				for s := 0; s < card; s++ {
+					values[r][s] = b.table[s*numRows+r]
+				}
+			}
+		}
+		for r := range values {
+			if row, ok := b.rows[r]; ok {
+				values[r] = row
+			} else if values[r] == nil {
+				if b.def == nil {
+					return nil, fmt.Errorf("probability of %s is missing parent configuration %d", b.child, r)
+				}
+				values[r] = append([]float64(nil), b.def...)
+			}
+		}
+
+		cpd, err := factors.NewTabularCPD(b.child, card, values, b.parents, evidenceCard)
+		if err != nil {
+			return nil, fmt.Errorf("probability of %s: %w", b.child, err)
+		}
+		for _, v := range append([]string{b.child}, b.parents...) {
+			if err := cpd.SetStateNames(v, states[v]); err != nil {
+				return nil, err
+			}
+		}
+		if err := bn.AddCPD(cpd); err != nil {
+			return nil, err
+		}
+	}
+
+	return bn, nil
+}
+
+// WriteBIF writes a discrete network in the Bayesian Interchange Format.
+// Unlabeled states are written as their indices, and custom CPDs as tables.
+func WriteBIF(w io.Writer, bn *models.BayesianNetwork) error {
+	for _, node := range bn.Nodes() {
+		if bn.IsContinuous(node) {
+			return fmt.Errorf("BIF export of %s: %w", node, models.ErrContinuousVariables)
+		}
+	}
+	if err := bn.CheckModel(); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "network unknown {")
+	fmt.Fprintln(bw, "}")
+
+	nodes := bn.Nodes()
+	names := make(map[string][]string, len(nodes))
+	for _, node := range nodes {
+		labels := bn.StateNames(node)
+		if labels == nil {
+			labels = make([]string, bn.Cardinality[node])
+			for i := range labels {
+				labels[i] = strconv.Itoa(i)
+			}
+		}
+		names[node] = labels
+		fmt.Fprintf(bw, "variable %s {\n  type discrete [ %d ] { %s };\n}\n",
+			node, len(labels), strings.Join(labels, ", "))
+	}
+
+	for _, node := range nodes {
+		cpd, err := bn.AsTabularCPD(node)
+		if err != nil {
+			return err
+		}
+		if len(cpd.Evidence) == 0 {
+			fmt.Fprintf(bw, "probability ( %s ) {\n  table %s;\n}\n", node, formatBIFRow(cpd.Values[0]))
+			continue
+		}
+
+		fmt.Fprintf(bw, "probability ( %s | %s ) {\n", node, strings.Join(cpd.Evidence, ", "))
+		config := make([]string, len(cpd.Evidence))
+		for r, row := range cpd.Values {
+			// The last evidence variable varies fastest
+			rem := r
+			for j := len(cpd.Evidence) - 1; j >= 0; j-- {
+				e := cpd.Evidence[j]
+				config[j] = names[e][rem%cpd.EvidenceCard[e]]
+				rem /= cpd.EvidenceCard[e]
+			}
+			fmt.Fprintf(bw, "  (%s) %s;\n", strings.Join(config, ", "), formatBIFRow(row))
+		}
+		fmt.Fprintln(bw, "}")
+	}
+
+	return bw.Flush()
+}
+
+func formatBIFRow(row []float64) string {
+	parts := make([]string, len(row))
+	for i, p := range row {
+		parts[i] = strconv.FormatFloat(p, 'g', -1, 64)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// bifRowIndex maps parent state labels to a CPD row, last parent fastest
+func bifRowIndex(config, parents []string, states map[string][]string) (int, error) {
+	if len(config) != len(parents) {
+		return 0, fmt.Errorf("configuration %v does not match parents %v", config, parents)
+	}
+	row := 0
+	for i, parent := range parents {
+		idx := -1
+		for s, label := range states[parent] {
+			if label == config[i] {
+				idx = s
+				break
+			}
+		}
+		if idx < 0 {
+			return 0, fmt.Errorf("unknown state %q of %s", config[i], parent)
+		}
+		row = row*len(states[parent]) + idx
+	}
+	return row, nil
+}
+
+// bifPunctuation lists the single-character tokens of BIF
+const bifPunctuation = "{}()[];,|"
+
+// tokenize splits BIF-like source into words, quoted strings and the
+// characters of punct, dropping C-style comments. A backslash escapes the
+// next character of a quoted string.
+func tokenize(src, punct string) []string {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '"':
+			var b strings.Builder
+			for i++; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				b.WriteByte(src[i])
+			}
+			tokens = append(tokens, b.String())
+			i++
+		case strings.IndexByte(punct, c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			start := i
+			for i < len(src) && !unicode.IsSpace(rune(src[i])) && src[i] != '"' && strings.IndexByte(punct, src[i]) < 0 {
+				i++
+			}
+			tokens = append(tokens, src[start:i])
+		}
+	}
+	return tokens
+}
+
+// bifParser walks the token stream of a BIF or DNE file
+type bifParser struct {
+	tokens []string
+	pos    int
+	format string // Named in error messages
+}
+
+func (p *bifParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *bifParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *bifParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *bifParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		return fmt.Errorf("expected %q in %s input, got %q", tok, p.format, got)
+	}
+	return nil
+}
+
+// skipStatement skips to just past the next semicolon
+func (p *bifParser) skipStatement() {
+	for !p.done() && p.next() != ";" {
+	}
+}
+
+// skipBlock skips a brace-delimited block, including nested blocks
+func (p *bifParser) skipBlock() error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; {
+		if p.done() {
+			return fmt.Errorf("unterminated block in %s input", p.format)
+		}
+		switch p.next() {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+	}
+	return nil
+}
+
+// list reads comma-separated words between open and close
+func (p *bifParser) list(open, close string) ([]string, error) {
+	if err := p.expect(open); err != nil {
+		return nil, err
+	}
+	var items []string
+	for p.peek() != close {
+		if p.done() {
+			return nil, fmt.Errorf("unterminated %s list in %s input", open, p.format)
+		}
+		if tok := p.next(); tok != "," {
+			items = append(items, tok)
+		}
+	}
+	p.next()
+	return items, nil
+}
+
+// numbers reads probabilities up to the closing semicolon
+func (p *bifParser) numbers() ([]float64, error) {
+	var values []float64
+	for p.peek() != ";" {
+		if p.done() {
+			return nil, fmt.Errorf("unterminated probability list in %s input", p.format)
+		}
+		tok := p.next()
+		if tok == "," {
+			continue
+		}
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid probability %q", tok)
+		}
+		values = append(values, v)
+	}
+	p.next()
+	return values, nil
+}
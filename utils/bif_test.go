@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+const sprinklerBIF = `// Sprinkler network
+network sprinkler {
+  property "source" "test";
+}
+variable Cloudy {
+  type discrete [ 2 ] { no, yes };
+}
+variable Rain {
+  type discrete [ 2 ] { no, yes };
+  property "note";
+}
+variable Wet {
+  type discrete [2] {dry, wet};
+}
+/* Parents may be listed row by row, by default, or as one table */
+probability ( Cloudy ) {
+  table 0.5, 0.5;
+}
+probability ( Rain | Cloudy ) {
+  (no) 0.8, 0.2;
+  (yes) 0.2, 0.8;
+}
+probability ( Wet | Rain, Cloudy ) {
+  table 0.9, 0.8, 0.2, 0.1, 0.1, 0.2, 0.8, 0.9;
+}
+`
+
+func TestReadBIF(t *testing.T) {
+	bn, err := ReadBIF(strings.NewReader(sprinklerBIF))
+	if err != nil {
+		t.Fatalf("ReadBIF failed: %v", err)
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("Parsed model is invalid: %v", err)
+	}
+
+	if got := bn.StateNames("Wet"); !reflect.DeepEqual(got, []string{"dry", "wet"}) {
+		t.Errorf("Expected Wet states [dry wet], got %v", got)
+	}
+	// The child varies slowest in a table, so row (Rain=no, Cloudy=yes) is
+	// the second entry of each child state
+	wet := bn.CPDs["Wet"]
+	if !reflect.DeepEqual(wet.Values[1], []float64{0.8, 0.2}) {
+		t.Errorf("Expected P(Wet | Rain=no, Cloudy=yes) = [0.8 0.2], got %v", wet.Values[1])
+	}
+
+	// Writing and re-reading keeps structure, labels and parameters
+	var buf bytes.Buffer
+	if err := WriteBIF(&buf, bn); err != nil {
+		t.Fatalf("WriteBIF failed: %v", err)
+	}
+	again, err := ReadBIF(&buf)
+	if err != nil {
+		t.Fatalf("Re-reading written BIF failed: %v", err)
+	}
+	for _, node := range bn.Nodes() {
+		if !reflect.DeepEqual(again.CPDs[node].Values, bn.CPDs[node].Values) {
+			t.Errorf("%s: values changed in round trip", node)
+		}
+		if !reflect.DeepEqual(again.StateNames(node), bn.StateNames(node)) {
+			t.Errorf("%s: state names changed in round trip", node)
+		}
+	}
+
+	if _, err := ReadBIF(strings.NewReader("variable X { type continuous; }")); err == nil {
+		t.Error("Expected an error for a continuous variable")
+	}
+	if _, err := ReadBIF(strings.NewReader(strings.Replace(sprinklerBIF, "(yes) 0.2, 0.8;", "", 1))); err == nil {
+		t.Error("Expected an error for a missing parent configuration")
+	}
+}
+
+func TestSaveModel(t *testing.T) {
+	bn, err := ReadBIF(strings.NewReader(sprinklerBIF))
+	if err != nil {
+		t.Fatalf("ReadBIF failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"model.bif", "model.json"} {
+		path := filepath.Join(dir, name)
+		if err := SaveModel(path, bn); err != nil {
+			t.Fatalf("SaveModel(%s) failed: %v", name, err)
+		}
+		loaded, err := LoadModel(path)
+		if err != nil {
+			t.Fatalf("LoadModel(%s) failed: %v", name, err)
+		}
+		for _, node := range bn.Nodes() {
+			if !reflect.DeepEqual(loaded.CPDs[node].Values, bn.CPDs[node].Values) {
+				t.Errorf("%s: CPD of %s changed", name, node)
+			}
+		}
+	}
+
+	if err := SaveModel(filepath.Join(dir, "model.txt"), bn); err == nil {
+		t.Error("Expected an error for an unknown extension")
+	}
+}
+
+func TestModelJSONMixed(t *testing.T) {
+	bn, _ := models.NewBayesianNetwork([][2]string{{"D", "Y"}, {"X", "Y"}})
+	cpdD, _ := factors.NewTabularCPD("D", 2, [][]float64{{0.4, 0.6}}, []string{}, map[string]int{})
+	cpdX, _ := factors.NewLinearGaussianCPD("X", []string{}, 1.0, map[string]float64{}, 2.0)
+	cpdY, _ := factors.NewCLGCPD("Y", []string{"D"}, []string{"X"}, map[string]int{"D": 2},
+		map[string]factors.GaussianParams{
+			"0": {Mean: 1.0, Variance: 0.5, Coefficients: map[string]float64{"X": 2.0}},
+			"1": {Mean: -1.0, Variance: 0.1, Coefficients: map[string]float64{"X": 0.5}},
+		})
+	_ = bn.AddCPD(cpdD)
+	_ = bn.AddGaussianCPD(cpdX)
+	_ = bn.AddGaussianCPD(cpdY)
+
+	path := filepath.Join(t.TempDir(), "mixed.json")
+	if err := SaveModelJSON(path, bn); err != nil {
+		t.Fatalf("SaveModelJSON failed: %v", err)
+	}
+	loaded, err := LoadModelJSON(path)
+	if err != nil {
+		t.Fatalf("LoadModelJSON failed: %v", err)
+	}
+	if err := loaded.CheckModel(); err != nil {
+		t.Fatalf("Loaded model is invalid: %v", err)
+	}
+
+	got := loaded.GaussianCPDs["Y"]
+	mean, err := got.GetMean(map[string]interface{}{"D": 1, "X": 2.0})
+	if err != nil || math.Abs(mean-0.0) > 1e-12 {
+		t.Errorf("Expected E[Y | D=1, X=2] = 0, got %v (%v)", mean, err)
+	}
+	if !loaded.IsContinuous("X") || loaded.GaussianCPDs["X"].Variance != 2.0 {
+		t.Error("Continuous root X was not restored")
+	}
+
+	if err := SaveBIF(filepath.Join(t.TempDir(), "mixed.bif"), bn); err == nil {
+		t.Error("Expected an error writing continuous variables to BIF")
+	}
+}
+
+func TestWritePgmpyJSON(t *testing.T) {
+	bn, err := ReadBIF(strings.NewReader(sprinklerBIF))
+	if err != nil {
+		t.Fatalf("ReadBIF failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WritePgmpyJSON(&buf, bn); err != nil {
+		t.Fatalf("WritePgmpyJSON failed: %v", err)
+	}
+
+	var m struct {
+		Nodes []string
+		Edges [][2]string
+		CPDs  []struct {
+			Variable     string
+			VariableCard int `json:"variable_card"`
+			Values       [][]float64
+			Evidence     []string
+			EvidenceCard []int            `json:"evidence_card"`
+			StateNames   map[string][]any `json:"state_names"`
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if len(m.Nodes) != 3 || len(m.Edges) != 3 || len(m.CPDs) != 3 {
+		t.Fatalf("Expected 3 nodes, edges and CPDs, got %d, %d and %d", len(m.Nodes), len(m.Edges), len(m.CPDs))
+	}
+
+	for _, cpd := range m.CPDs {
+		if cpd.Variable != "Wet" {
+			continue
+		}
+		// pgmpy has one row per state and one column per parent configuration
+		want := [][]float64{{0.9, 0.8, 0.2, 0.1}, {0.1, 0.2, 0.8, 0.9}}
+		if !reflect.DeepEqual(cpd.Values, want) {
+			t.Errorf("Expected Wet values %v, got %v", want, cpd.Values)
+		}
+		if !reflect.DeepEqual(cpd.Evidence, []string{"Rain", "Cloudy"}) || !reflect.DeepEqual(cpd.EvidenceCard, []int{2, 2}) {
+			t.Errorf("Unexpected evidence %v with cardinalities %v", cpd.Evidence, cpd.EvidenceCard)
+		}
+		if !reflect.DeepEqual(cpd.StateNames["Wet"], []any{"dry", "wet"}) || len(cpd.StateNames) != 3 {
+			t.Errorf("Unexpected state names %v", cpd.StateNames)
+		}
+	}
+
+	// Unlabeled states are written as indices
+	unlabeled, err := models.NewBayesianNetwork(nil)
+	if err != nil {
+		t.Fatalf("NewBayesianNetwork failed: %v", err)
+	}
+	cpd, err := factors.NewTabularCPD("X", 3, [][]float64{{0.2, 0.3, 0.5}}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewTabularCPD failed: %v", err)
+	}
+	unlabeled.DAG.AddNode("X")
+	if err := unlabeled.AddCPD(cpd); err != nil {
+		t.Fatalf("AddCPD failed: %v", err)
+	}
+	buf.Reset()
+	if err := WritePgmpyJSON(&buf, unlabeled); err != nil {
+		t.Fatalf("WritePgmpyJSON failed: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if got := m.CPDs[0].StateNames["X"]; !reflect.DeepEqual(got, []any{0.0, 1.0, 2.0}) {
+		t.Errorf("Expected integer state names, got %v", got)
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// CSVScanner reads a CSV file one sample at a time so files larger than
+// memory can be processed incrementally. Usage follows bufio.Scanner:
+//
+//	scanner, err := utils.NewCSVScanner("data.csv", nil)
+//	defer scanner.Close()
+//	for scanner.Scan() {
+//		sample := scanner.Sample()
+//	}
+//	if err := scanner.Err(); err != nil { ... }
+type CSVScanner struct {
+	file   *os.File
+	reader *csv.Reader
+	header []string
+	parser *recordParser
+	sample models.Sample
+	row    int
+	err    error
+}
+
+// NewCSVScanner opens a CSV file for streaming. With a nil schema every
+// column is read as an integer-coded discrete variable, like LoadCSV. With a
+// schema, discrete columns must either declare their states up front or hold
+// integer codes, since labels cannot be inferred without reading the whole file.
+func NewCSVScanner(filename string, schema *MixedSchema) (*CSVScanner, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+
+	// Read header
+	header, err := reader.Read()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	header = append([]string(nil), header...)
+
+	if schema == nil {
+		types := make(map[string]models.VariableType, len(header))
+		for _, col := range header {
+			types[col] = models.Discrete
+		}
+		schema = NewMixedSchema(types)
+	} else if err := schema.checkColumns(header); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return &CSVScanner{
+		file:   file,
+		reader: reader,
+		header: header,
+		parser: newRecordParser(header, schema),
+	}, nil
+}
+
+// Header returns the column names of the file
+func (s *CSVScanner) Header() []string {
+	return s.header
+}
+
+// Scan advances to the next sample. It returns false at the end of the file
+// or on the first error, which is then reported by Err.
+func (s *CSVScanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	record, err := s.reader.Read()
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		s.err = err
+		return false
+	}
+
+	s.row++
+	s.sample, s.err = s.parser.parse(record, s.row)
+	return s.err == nil
+}
+
+// Sample returns the most recent sample read by Scan
+func (s *CSVScanner) Sample() models.Sample {
+	return s.sample
+}
+
+// Err returns the first error encountered while scanning
+func (s *CSVScanner) Err() error {
+	return s.err
+}
+
+// NextChunk reads up to n samples. It returns io.EOF once the file is
+// exhausted and no samples were read.
+func (s *CSVScanner) NextChunk(n int) ([]models.Sample, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive")
+	}
+
+	chunk := make([]models.Sample, 0, n)
+	for len(chunk) < n && s.Scan() {
+		chunk = append(chunk, s.sample)
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(chunk) == 0 {
+		return nil, io.EOF
+	}
+	return chunk, nil
+}
+
+// Close closes the underlying file
+func (s *CSVScanner) Close() error {
+	return s.file.Close()
+}
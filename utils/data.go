@@ -41,7 +41,9 @@ func (df *DataFrame) Len() int {
 	return len(df.Data)
 }
 
-// LoadCSV loads a CSV file into a DataFrame
+// LoadCSV loads a CSV file of integer states into a DataFrame. It keeps
+// failing on float and text columns because a DataFrame holds only integers;
+// LoadCSVAuto infers the column types of such files into a MixedDataFrame.
 func LoadCSV(filename string) (*DataFrame, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -70,7 +72,8 @@ func LoadCSV(filename string) (*DataFrame, error) {
 		for i, value := range record {
 			intValue, err := strconv.Atoi(value)
 			if err != nil {
-				return nil, fmt.Errorf("invalid integer value %s in column %s", value, header[i])
+				return nil, fmt.Errorf("invalid integer value %s in column %s; LoadCSVAuto reads float and text columns",
+					value, header[i])
 			}
 			row[header[i]] = intValue
 		}
@@ -122,3 +125,131 @@ func DataFrameFromSamples(samples []map[string]int, columns []string) *DataFrame
 func (df *DataFrame) ToSamples() []map[string]int {
 	return df.Data
 }
+
+// Select returns a DataFrame of the given columns, in that order. Rows are
+// copied, so the result can be modified independently.
+func (df *DataFrame) Select(columns ...string) (*DataFrame, error) {
+	if err := df.checkColumns(columns); err != nil {
+		return nil, err
+	}
+	result := NewDataFrame(append([]string(nil), columns...))
+	for _, row := range df.Data {
+		selected := make(map[string]int, len(columns))
+		for _, col := range columns {
+			if value, ok := row[col]; ok {
+				selected[col] = value
+			}
+		}
+		result.AddRow(selected)
+	}
+	return result, nil
+}
+
+// Filter returns a DataFrame of the rows for which keep returns true. The
+// rows are shared with df.
+func (df *DataFrame) Filter(keep func(row map[string]int) bool) *DataFrame {
+	result := NewDataFrame(df.Columns)
+	for _, row := range df.Data {
+		if keep(row) {
+			result.AddRow(row)
+		}
+	}
+	return result
+}
+
+// Join returns the inner join of df and other on the given key columns: one
+// row for each pair of rows with equal keys, in the order of df's rows and
+// then other's. Columns are df's followed by other's non-key columns, which
+// must not also be columns of df.
+func (df *DataFrame) Join(other *DataFrame, on ...string) (*DataFrame, error) {
+	if len(on) == 0 {
+		return nil, fmt.Errorf("join needs at least one key column")
+	}
+	if err := df.checkColumns(on); err != nil {
+		return nil, err
+	}
+	if err := other.checkColumns(on); err != nil {
+		return nil, err
+	}
+	isKey := make(map[string]bool, len(on))
+	for _, col := range on {
+		isKey[col] = true
+	}
+	inDF := make(map[string]bool, len(df.Columns))
+	for _, col := range df.Columns {
+		inDF[col] = true
+	}
+	columns := append([]string(nil), df.Columns...)
+	var extra []string
+	for _, col := range other.Columns {
+		if isKey[col] {
+			continue
+		}
+		if inDF[col] {
+			return nil, fmt.Errorf("column %s is in both data frames but is not a key", col)
+		}
+		extra = append(extra, col)
+	}
+	columns = append(columns, extra...)
+
+	// Index other's rows by key; rows missing a key match nothing
+	index := make(map[string][]map[string]int)
+	for _, row := range other.Data {
+		if key, ok := joinKey(row, on); ok {
+			index[key] = append(index[key], row)
+		}
+	}
+
+	result := NewDataFrame(columns)
+	for _, row := range df.Data {
+		key, ok := joinKey(row, on)
+		if !ok {
+			continue
+		}
+		for _, match := range index[key] {
+			joined := make(map[string]int, len(columns))
+			for col, value := range row {
+				joined[col] = value
+			}
+			for _, col := range extra {
+				if value, ok := match[col]; ok {
+					joined[col] = value
+				}
+			}
+			result.AddRow(joined)
+		}
+	}
+	return result, nil
+}
+
+// checkColumns verifies that every column is a column of the data frame
+func (df *DataFrame) checkColumns(columns []string) error {
+	for _, col := range columns {
+		found := false
+		for _, c := range df.Columns {
+			if c == col {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("column %s not found", col)
+		}
+	}
+	return nil
+}
+
+// joinKey encodes the row's values of the key columns, reporting false if
+// the row lacks one
+func joinKey(row map[string]int, on []string) (string, bool) {
+	key := make([]byte, 0, 8*len(on))
+	for _, col := range on {
+		value, ok := row[col]
+		if !ok {
+			return "", false
+		}
+		key = strconv.AppendInt(key, int64(value), 10)
+		key = append(key, ',')
+	}
+	return string(key), true
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDataFrameRelational(t *testing.T) {
+	df := DataFrameFromSamples([]map[string]int{
+		{"ID": 1, "A": 0, "B": 1},
+		{"ID": 2, "A": 1, "B": 1},
+		{"ID": 3, "A": 1, "B": 0},
+		{"ID": 2, "A": 0, "B": 0},
+	}, []string{"ID", "A", "B"})
+
+	selected, err := df.Select("B", "A")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if !reflect.DeepEqual(selected.Columns, []string{"B", "A"}) || len(selected.Data[0]) != 2 {
+		t.Errorf("Select kept columns %v and row %v", selected.Columns, selected.Data[0])
+	}
+	selected.Data[0]["A"] = 9
+	if df.Data[0]["A"] != 0 {
+		t.Error("Select shares rows with the original")
+	}
+	if _, err := df.Select("C"); err == nil {
+		t.Error("Expected an error selecting an unknown column")
+	}
+
+	filtered := df.Filter(func(row map[string]int) bool { return row["A"] == 1 })
+	if filtered.Len() != 2 || filtered.Data[0]["ID"] != 2 || filtered.Data[1]["ID"] != 3 {
+		t.Errorf("Filter kept %v", filtered.Data)
+	}
+
+	labels := DataFrameFromSamples([]map[string]int{
+		{"ID": 2, "Label": 1},
+		{"ID": 3, "Label": 0},
+		{"ID": 2, "Label": 2},
+		{"Label": 1},
+	}, []string{"ID", "Label"})
+	joined, err := df.Join(labels, "ID")
+	if err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	want := []map[string]int{
+		{"ID": 2, "A": 1, "B": 1, "Label": 1},
+		{"ID": 2, "A": 1, "B": 1, "Label": 2},
+		{"ID": 3, "A": 1, "B": 0, "Label": 0},
+		{"ID": 2, "A": 0, "B": 0, "Label": 1},
+		{"ID": 2, "A": 0, "B": 0, "Label": 2},
+	}
+	if !reflect.DeepEqual(joined.Columns, []string{"ID", "A", "B", "Label"}) || !reflect.DeepEqual(joined.Data, want) {
+		t.Errorf("Join gave columns %v and rows %v", joined.Columns, joined.Data)
+	}
+
+	if _, err := df.Join(df, "ID"); err == nil {
+		t.Error("Expected an error joining frames that share non-key columns")
+	}
+	if _, err := df.Join(labels, "A"); err == nil {
+		t.Error("Expected an error joining on a column missing from one frame")
+	}
+}
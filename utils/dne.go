@@ -0,0 +1,291 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/JohnPierman/bngo/factors"
+	"github.com/JohnPierman/bngo/models"
+)
+
+// dnePunctuation lists the single-character tokens of Netica's DNE format
+const dnePunctuation = "{}()[];,="
+
+// LoadDNE reads a discrete network from a Netica .dne file
+func LoadDNE(filename string) (*models.BayesianNetwork, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return ReadDNE(file)
+}
+
+// ReadDNE parses a network in Netica's DNE format. Nodes must be discrete
+// nature nodes with a probs table, or a functable for deterministic nodes;
+// probs nest the parents in order, first parent outermost, with the node's
+// states innermost. State names become the CPDs' state names. Visual
+// settings, titles and other attributes are ignored.
+func ReadDNE(r io.Reader) (*models.BayesianNetwork, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &bifParser{tokens: tokenize(string(src), dnePunctuation), format: "DNE"}
+
+	var nodes []*dneNode
+	for !p.done() {
+		switch p.peek() {
+		case "bnet":
+		case "}":
+			p.next()
+			continue
+		default:
+			p.skipNested()
+			continue
+		}
+		p.next()
+		p.next() // Network name
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		for p.peek() != "}" {
+			if p.done() {
+				return nil, fmt.Errorf("unterminated bnet in DNE input")
+			}
+			if p.peek() != "node" {
+				p.skipNested()
+				continue
+			}
+			p.next()
+			node, err := p.dneNode()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		}
+		p.next()
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no bnet nodes in DNE input")
+	}
+
+	var edges [][2]string
+	byName := make(map[string]*dneNode, len(nodes))
+	for _, node := range nodes {
+		byName[node.name] = node
+		for _, parent := range node.parents {
+			edges = append(edges, [2]string{parent, node.name})
+		}
+	}
+	bn, err := models.NewBayesianNetwork(edges)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		bn.DAG.AddNode(node.name)
+	}
+
+	for _, node := range nodes {
+		evidenceCard := make(map[string]int, len(node.parents))
+		numRows := 1
+		for _, parent := range node.parents {
+			pn, ok := byName[parent]
+			if !ok {
+				return nil, fmt.Errorf("parent %s of %s is undeclared: %w", parent, node.name, models.ErrUnknownVariable)
+			}
+			evidenceCard[parent] = pn.card
+			numRows *= pn.card
+		}
+
+		values, err := node.table(numRows)
+		if err != nil {
+			return nil, err
+		}
+		cpd, err := factors.NewTabularCPD(node.name, node.card, values, node.parents, evidenceCard)
+		if err != nil {
+			return nil, fmt.Errorf("probability of %s: %w", node.name, err)
+		}
+		for _, v := range append([]string{node.name}, node.parents...) {
+			if labels := byName[v].states; labels != nil {
+				if err := cpd.SetStateNames(v, labels); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := bn.AddCPD(cpd); err != nil {
+			return nil, err
+		}
+	}
+
+	return bn, nil
+}
+
+// dneNode is a parsed node block
+type dneNode struct {
+	name      string
+	card      int
+	states    []string
+	parents   []string
+	probs     []float64
+	functable []string
+}
+
+// table returns the CPD rows of the node, one per parent configuration
+func (n *dneNode) table(numRows int) ([][]float64, error) {
+	values := make([][]float64, numRows)
+	switch {
+	case n.probs != nil:
+		if len(n.probs) != numRows*n.card {
+			return nil, fmt.Errorf("probs of %s has %d entries, expected %d: %w",
+				n.name, len(n.probs), numRows*n.card, models.ErrCardinalityMismatch)
+		}
+		for r := range values {
+			values[r] = n.probs[r*n.card : (r+1)*n.card]
+		}
+	case n.functable != nil:
+		if len(n.functable) != numRows {
+			return nil, fmt.Errorf("functable of %s has %d entries, expected %d: %w",
+				n.name, len(n.functable), numRows, models.ErrCardinalityMismatch)
+		}
+		for r, state := range n.functable {
+			s, err := n.stateIndex(state)
+			if err != nil {
+				return nil, err
+			}
+			values[r] = make([]float64, n.card)
+			values[r][s] = 1
+		}
+	default:
+		return nil, fmt.Errorf("node %s has no probs or functable: %w", n.name, models.ErrMissingCPD)
+	}
+	return values, nil
+}
+
+func (n *dneNode) stateIndex(state string) (int, error) {
+	for i, label := range n.states {
+		if label == state {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(state); err == nil && n.states == nil && i >= 0 && i < n.card {
+		return i, nil
+	}
+	return 0, fmt.Errorf("unknown state %q of %s", state, n.name)
+}
+
+// dneNode parses a node block after the node keyword
+func (p *bifParser) dneNode() (*dneNode, error) {
+	node := &dneNode{name: p.next()}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for p.peek() != "}" {
+		if p.done() {
+			return nil, fmt.Errorf("unterminated node %s in DNE input", node.name)
+		}
+		key := p.next()
+		if p.peek() != "=" {
+			p.skipNested() // Blocks such as visual V1 { ... };
+			continue
+		}
+		p.next()
+
+		var err error
+		switch key {
+		case "kind":
+			if kind := p.peek(); kind != "NATURE" {
+				return nil, fmt.Errorf("node %s has unsupported kind %s", node.name, kind)
+			}
+		case "discrete":
+			if p.peek() == "FALSE" {
+				return nil, fmt.Errorf("node %s: %w", node.name, models.ErrContinuousVariables)
+			}
+		case "states":
+			node.states, err = p.list("(", ")")
+			node.card = len(node.states)
+		case "numstates":
+			if node.card, err = strconv.Atoi(p.peek()); err != nil {
+				err = fmt.Errorf("node %s: invalid numstates %q", node.name, p.peek())
+			}
+		case "parents":
+			node.parents, err = p.list("(", ")")
+		case "probs":
+			node.probs = []float64{}
+			for _, tok := range p.nestedWords() {
+				v, perr := strconv.ParseFloat(tok, 64)
+				if perr != nil {
+					return nil, fmt.Errorf("node %s: invalid probability %q", node.name, tok)
+				}
+				node.probs = append(node.probs, v)
+			}
+		case "functable":
+			node.functable = p.nestedWords()
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.skipNested()
+	}
+	p.next()
+	if p.peek() == ";" {
+		p.next()
+	}
+	if node.card < 1 {
+		return nil, fmt.Errorf("node %s declares no states: %w", node.name, models.ErrCardinalityMismatch)
+	}
+	if node.states != nil && len(node.states) != node.card {
+		return nil, fmt.Errorf("node %s declares %d states but lists %d: %w",
+			node.name, node.card, len(node.states), models.ErrCardinalityMismatch)
+	}
+	return node, nil
+}
+
+// nestedWords reads the words of a parenthesized, possibly nested value up
+// to the closing semicolon, leaving the semicolon
+func (p *bifParser) nestedWords() []string {
+	var words []string
+	for depth := 0; !p.done(); {
+		switch tok := p.peek(); tok {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+		case ";", "}":
+			if depth <= 0 {
+				return words
+			}
+		default:
+			words = append(words, tok)
+		}
+		p.next()
+	}
+	return words
+}
+
+// skipNested skips to just past the next semicolon outside brackets, or up
+// to a closing brace that ends the enclosing block
+func (p *bifParser) skipNested() {
+	for depth := 0; !p.done(); {
+		switch p.peek() {
+		case "{", "(":
+			depth++
+		case ")":
+			depth--
+		case "}":
+			if depth == 0 {
+				return
+			}
+			depth--
+		case ";":
+			if depth == 0 {
+				p.next()
+				return
+			}
+		}
+		p.next()
+	}
+}
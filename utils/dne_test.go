@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const asiaDNE = `// ~->[DNET-1]->~
+
+bnet Asia {
+autoupdate = TRUE;
+whenchanged = 1036366076;
+
+visual V1 {
+	defdispform = BELIEFBARS;
+	nodelabeling = TITLE;
+	};
+
+node VisitAsia {
+	kind = NATURE;
+	discrete = TRUE;
+	chance = CHANCE;
+	states = (visit, no_visit);
+	parents = ();
+	probs = 
+		// visit        no_visit     
+		  (0.01,        0.99);
+	title = "Visit To Asia?";
+	whenchanged = 1036366076;
+	visual V1 {
+		center = (78, 48);
+		height = 1;
+		};
+	};
+
+node Smoking {
+	kind = NATURE;
+	discrete = TRUE;
+	states = (smoker, nonsmoker);
+	parents = ();
+	probs = (0.5, 0.5);
+	title = "Smoker? \"yes\"";
+	};
+
+node Tuberculosis {
+	kind = NATURE;
+	discrete = TRUE;
+	states = (present, absent);
+	parents = (VisitAsia);
+	probs = 
+		// present      absent         // VisitAsia 
+		 ((0.05,        0.95),         // visit     
+		  (0.01,        0.99));        // no_visit  
+	};
+
+node Cancer {
+	discrete = TRUE;
+	numstates = 2;
+	parents = (Smoking);
+	probs = ((0.1, 0.9), (0.01, 0.99));
+	};
+
+node TbOrCa {
+	kind = NATURE;
+	discrete = TRUE;
+	chance = DETERMIN;
+	states = (true, false);
+	parents = (Tuberculosis, Cancer);
+	functable = 
+		((true, true),
+		 (true, false));
+	};
+ElimOrder = (VisitAsia, Tuberculosis, Smoking, Cancer, TbOrCa);
+};
+`
+
+func TestReadDNE(t *testing.T) {
+	bn, err := ReadDNE(strings.NewReader(asiaDNE))
+	if err != nil {
+		t.Fatalf("ReadDNE failed: %v", err)
+	}
+	if err := bn.CheckModel(); err != nil {
+		t.Fatalf("Parsed model is invalid: %v", err)
+	}
+	if len(bn.Nodes()) != 5 || len(bn.Edges()) != 4 {
+		t.Fatalf("Expected 5 nodes and 4 edges, got %d and %d", len(bn.Nodes()), len(bn.Edges()))
+	}
+
+	if got := bn.StateNames("Tuberculosis"); !reflect.DeepEqual(got, []string{"present", "absent"}) {
+		t.Errorf("Expected Tuberculosis states [present absent], got %v", got)
+	}
+	if got := bn.CPDs["Tuberculosis"].Values[1]; !reflect.DeepEqual(got, []float64{0.01, 0.99}) {
+		t.Errorf("Expected P(Tuberculosis | no_visit) = [0.01 0.99], got %v", got)
+	}
+	// Nodes with numstates only have no labels
+	if got := bn.StateNames("Cancer"); got != nil {
+		t.Errorf("Expected no Cancer state names, got %v", got)
+	}
+
+	// The functable is one-hot, the last parent varying fastest
+	want := [][]float64{{1, 0}, {1, 0}, {1, 0}, {0, 1}}
+	if got := bn.CPDs["TbOrCa"].Values; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected TbOrCa table %v, got %v", want, got)
+	}
+
+	if _, err := ReadDNE(strings.NewReader(strings.Replace(asiaDNE, "(0.5, 0.5)", "(0.5, 0.3, 0.2)", 1))); err == nil {
+		t.Error("Expected an error for a probs table of the wrong size")
+	}
+	if _, err := ReadDNE(strings.NewReader(strings.Replace(asiaDNE, "numstates = 2;", "discrete = FALSE;", 1))); err == nil {
+		t.Error("Expected an error for a continuous node")
+	}
+}
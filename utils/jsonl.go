@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// LoadJSONL loads samples from a JSON-lines file. Each line is an object of the
+// form {"discrete": {...}, "continuous": {...}}, so variable types are kept
+// without a separate schema. Blank lines are skipped.
+func LoadJSONL(filename string) ([]models.Sample, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	samples := make([]models.Sample, 0)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var sample models.Sample
+		if err := json.Unmarshal(text, &sample); err != nil {
+			return nil, fmt.Errorf("invalid sample on line %d: %w", line, err)
+		}
+		if sample.Discrete == nil {
+			sample.Discrete = make(map[string]int)
+		}
+		if sample.Continuous == nil {
+			sample.Continuous = make(map[string]float64)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+// SaveJSONL saves samples to a JSON-lines file, one sample per line
+func SaveJSONL(filename string, samples []models.Sample) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, sample := range samples {
+		if err := encoder.Encode(sample); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+func TestJSONLRoundTrip(t *testing.T) {
+	// Weights travel as a continuous column; 2 and 40 look like integers
+	// but must stay continuous
+	samples := []models.Sample{
+		{
+			Discrete:   map[string]int{"Season": 2, "Rain": 0},
+			Continuous: map[string]float64{"Temperature": 40, "weight": 2},
+		},
+		{
+			Discrete:   map[string]int{"Season": 0, "Rain": 1},
+			Continuous: map[string]float64{"Temperature": 12.25, "weight": 0.5},
+		},
+		{
+			Discrete:   map[string]int{"Rain": 1},
+			Continuous: map[string]float64{},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "samples.jsonl")
+	if err := SaveJSONL(path, samples); err != nil {
+		t.Fatalf("SaveJSONL failed: %v", err)
+	}
+	loaded, err := LoadJSONL(path)
+	if err != nil {
+		t.Fatalf("LoadJSONL failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, samples) {
+		t.Errorf("Round trip changed the samples:\n got %v\nwant %v", loaded, samples)
+	}
+
+	weights := make([]float64, len(loaded))
+	for i, s := range loaded {
+		w, ok := s.Continuous["weight"]
+		if !ok && i < 2 {
+			t.Errorf("Sample %d lost its weight", i)
+		}
+		weights[i] = w
+		if _, ok := s.Discrete["weight"]; ok {
+			t.Errorf("Sample %d read its weight back as discrete", i)
+		}
+	}
+	if want := []float64{2, 0.5, 0}; !reflect.DeepEqual(weights, want) {
+		t.Errorf("Expected weights %v, got %v", want, weights)
+	}
+}
+
+func TestLoadJSONLInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.jsonl")
+	content := "{\"discrete\": {\"A\": 1}}\n\n{\"discrete\": {\"A\": 1.5}}\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write JSONL: %v", err)
+	}
+	if _, err := LoadJSONL(path); err == nil {
+		t.Error("Expected an error for a fractional discrete value")
+	}
+}
+
+func TestSaveJSONLError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "samples.jsonl")
+	if err := SaveJSONL(path, nil); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
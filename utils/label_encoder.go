@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// LabelEncoder maps string categories to contiguous integer states per column
+// and back again. Labels are assigned codes in sorted order when fitted.
+type LabelEncoder struct {
+	Classes map[string][]string `json:"classes"` // column -> labels ordered by code
+
+	index map[string]map[string]int
+}
+
+// NewLabelEncoder creates an empty label encoder
+func NewLabelEncoder() *LabelEncoder {
+	return &LabelEncoder{
+		Classes: make(map[string][]string),
+		index:   make(map[string]map[string]int),
+	}
+}
+
+// Fit learns the labels of the given columns from string-valued rows
+func (le *LabelEncoder) Fit(rows []map[string]string, columns []string) {
+	for _, col := range columns {
+		values := make([]string, 0, len(rows))
+		for _, row := range rows {
+			if v, ok := row[col]; ok {
+				values = append(values, v)
+			}
+		}
+		le.FitColumn(col, values)
+	}
+}
+
+// FitColumn learns the labels of a single column, replacing any previous fit
+func (le *LabelEncoder) FitColumn(column string, values []string) {
+	seen := make(map[string]bool)
+	labels := make([]string, 0)
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			labels = append(labels, v)
+		}
+	}
+	sort.Strings(labels)
+
+	if le.Classes == nil {
+		le.Classes = make(map[string][]string)
+	}
+	if le.index == nil {
+		le.index = make(map[string]map[string]int)
+	}
+	le.Classes[column] = labels
+	le.buildIndex(column)
+}
+
+func (le *LabelEncoder) buildIndex(column string) {
+	idx := make(map[string]int, len(le.Classes[column]))
+	for i, label := range le.Classes[column] {
+		idx[label] = i
+	}
+	le.index[column] = idx
+}
+
+// Cardinality returns the number of known labels for a column
+func (le *LabelEncoder) Cardinality(column string) int {
+	return len(le.Classes[column])
+}
+
+// Transform returns the integer state for a label. It only reads the
+// encoder, so a fitted encoder is safe for concurrent use.
+func (le *LabelEncoder) Transform(column, label string) (int, error) {
+	labels, ok := le.Classes[column]
+	if !ok {
+		return 0, fmt.Errorf("column %s not fitted", column)
+	}
+	if idx, ok := le.index[column]; ok {
+		if code, ok := idx[label]; ok {
+			return code, nil
+		}
+	} else {
+		// Classes set directly rather than fitted
+		for code, l := range labels {
+			if l == label {
+				return code, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown label %q for column %s", label, column)
+}
+
+// InverseTransform returns the label for an integer state
+func (le *LabelEncoder) InverseTransform(column string, code int) (string, error) {
+	labels, ok := le.Classes[column]
+	if !ok {
+		return "", fmt.Errorf("column %s not fitted", column)
+	}
+	if code < 0 || code >= len(labels) {
+		return "", fmt.Errorf("state %d out of range for column %s", code, column)
+	}
+	return labels[code], nil
+}
+
+// TransformRows encodes string-valued rows into integer samples.
+// Columns that were not fitted are dropped.
+func (le *LabelEncoder) TransformRows(rows []map[string]string) ([]map[string]int, error) {
+	samples := make([]map[string]int, len(rows))
+	for i, row := range rows {
+		sample := make(map[string]int)
+		for col, label := range row {
+			if _, ok := le.Classes[col]; !ok {
+				continue
+			}
+			code, err := le.Transform(col, label)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", i, err)
+			}
+			sample[col] = code
+		}
+		samples[i] = sample
+	}
+	return samples, nil
+}
+
+// InverseTransformRows decodes integer samples back into string-valued rows
+func (le *LabelEncoder) InverseTransformRows(samples []map[string]int) ([]map[string]string, error) {
+	rows := make([]map[string]string, len(samples))
+	for i, sample := range samples {
+		row := make(map[string]string)
+		for col, code := range sample {
+			label, err := le.InverseTransform(col, code)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", i, err)
+			}
+			row[col] = label
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// DecodePredictions converts the output of BayesianNetwork.Predict to labels
+func (le *LabelEncoder) DecodePredictions(predictions map[string][]int) (map[string][]string, error) {
+	decoded := make(map[string][]string, len(predictions))
+	for col, codes := range predictions {
+		labels := make([]string, len(codes))
+		for i, code := range codes {
+			label, err := le.InverseTransform(col, code)
+			if err != nil {
+				return nil, err
+			}
+			labels[i] = label
+		}
+		decoded[col] = labels
+	}
+	return decoded, nil
+}
+
+// UnmarshalJSON restores an encoder and rebuilds its lookup tables
+func (le *LabelEncoder) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Classes map[string][]string `json:"classes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	le.Classes = raw.Classes
+	if le.Classes == nil {
+		le.Classes = make(map[string][]string)
+	}
+	le.index = make(map[string]map[string]int)
+	for col := range le.Classes {
+		le.buildIndex(col)
+	}
+	return nil
+}
+
+// Save writes the encoder to a JSON file
+func (le *LabelEncoder) Save(filename string) error {
+	data, err := json.MarshalIndent(le, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
+
+// LoadLabelEncoder reads an encoder from a JSON file
+func LoadLabelEncoder(filename string) (*LabelEncoder, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	le := NewLabelEncoder()
+	if err := json.Unmarshal(data, le); err != nil {
+		return nil, fmt.Errorf("failed to parse label encoder: %w", err)
+	}
+	return le, nil
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func weatherRows() []map[string]string {
+	return []map[string]string{
+		{"Season": "Summer", "Rain": "no", "ID": "1"},
+		{"Season": "Winter", "Rain": "yes", "ID": "2"},
+		{"Season": "Fall", "Rain": "yes", "ID": "3"},
+		{"Season": "Summer", "Rain": "no", "ID": "4"},
+	}
+}
+
+func TestLabelEncoder(t *testing.T) {
+	le := NewLabelEncoder()
+	le.Fit(weatherRows(), []string{"Season", "Rain"})
+
+	if want := []string{"Fall", "Summer", "Winter"}; !reflect.DeepEqual(le.Classes["Season"], want) {
+		t.Errorf("Expected Season classes %v, got %v", want, le.Classes["Season"])
+	}
+	if le.Cardinality("Rain") != 2 {
+		t.Errorf("Expected 2 Rain labels, got %d", le.Cardinality("Rain"))
+	}
+
+	code, err := le.Transform("Season", "Winter")
+	if err != nil || code != 2 {
+		t.Errorf("Expected Winter -> 2, got %d (%v)", code, err)
+	}
+	label, err := le.InverseTransform("Season", 1)
+	if err != nil || label != "Summer" {
+		t.Errorf("Expected 1 -> Summer, got %q (%v)", label, err)
+	}
+
+	if _, err := le.Transform("Season", "Spring"); err == nil {
+		t.Error("Expected an error for an unknown label")
+	}
+	if _, err := le.Transform("ID", "1"); err == nil {
+		t.Error("Expected an error for a column that was not fitted")
+	}
+	if _, err := le.InverseTransform("Season", 3); err == nil {
+		t.Error("Expected an error for an out-of-range state")
+	}
+
+	samples, err := le.TransformRows(weatherRows())
+	if err != nil {
+		t.Fatalf("TransformRows failed: %v", err)
+	}
+	if want := map[string]int{"Season": 1, "Rain": 0}; !reflect.DeepEqual(samples[0], want) {
+		t.Errorf("Expected the unfitted ID column to be dropped, got %v", samples[0])
+	}
+	rows, err := le.InverseTransformRows(samples)
+	if err != nil {
+		t.Fatalf("InverseTransformRows failed: %v", err)
+	}
+	if want := map[string]string{"Season": "Fall", "Rain": "yes"}; !reflect.DeepEqual(rows[2], want) {
+		t.Errorf("Expected row %v, got %v", want, rows[2])
+	}
+}
+
+func TestLabelEncoderZeroValue(t *testing.T) {
+	var le LabelEncoder
+	le.FitColumn("Rain", []string{"yes", "no"})
+	if code, err := le.Transform("Rain", "yes"); err != nil || code != 1 {
+		t.Errorf("Expected yes -> 1, got %d (%v)", code, err)
+	}
+
+	// Classes set by hand have no index yet
+	manual := LabelEncoder{Classes: map[string][]string{"Rain": {"no", "yes"}}}
+	if code, err := manual.Transform("Rain", "yes"); err != nil || code != 1 {
+		t.Errorf("Expected yes -> 1 without an index, got %d (%v)", code, err)
+	}
+	if _, err := manual.Transform("Rain", "maybe"); err == nil {
+		t.Error("Expected an error for an unknown label without an index")
+	}
+}
+
+func TestLabelEncoderConcurrentTransform(t *testing.T) {
+	le := NewLabelEncoder()
+	le.Fit(weatherRows(), []string{"Season", "Rain"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if code, err := le.Transform("Season", "Summer"); err != nil || code != 1 {
+					t.Errorf("Expected Summer -> 1, got %d (%v)", code, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLabelEncoderSaveLoad(t *testing.T) {
+	le := NewLabelEncoder()
+	le.Fit(weatherRows(), []string{"Season", "Rain"})
+
+	path := filepath.Join(t.TempDir(), "encoder.json")
+	if err := le.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadLabelEncoder(path)
+	if err != nil {
+		t.Fatalf("LoadLabelEncoder failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Classes, le.Classes) {
+		t.Errorf("Expected classes %v, got %v", le.Classes, loaded.Classes)
+	}
+	if code, err := loaded.Transform("Rain", "yes"); err != nil || code != 1 {
+		t.Errorf("Expected yes -> 1 after loading, got %d (%v)", code, err)
+	}
+
+	if _, err := LoadLabelEncoder(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestDecodePredictions(t *testing.T) {
+	le := NewLabelEncoder()
+	le.Fit(weatherRows(), []string{"Season", "Rain"})
+
+	decoded, err := le.DecodePredictions(map[string][]int{"Rain": {1, 0, 1}})
+	if err != nil {
+		t.Fatalf("DecodePredictions failed: %v", err)
+	}
+	if want := []string{"yes", "no", "yes"}; !reflect.DeepEqual(decoded["Rain"], want) {
+		t.Errorf("Expected %v, got %v", want, decoded["Rain"])
+	}
+
+	if _, err := le.DecodePredictions(map[string][]int{"Rain": {2}}); err == nil {
+		t.Error("Expected an error for an out-of-range prediction")
+	}
+	if _, err := le.DecodePredictions(map[string][]int{"Wind": {0}}); err == nil {
+		t.Error("Expected an error for a column that was not fitted")
+	}
+}
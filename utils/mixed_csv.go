@@ -0,0 +1,306 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// MixedSchema describes how the columns of a mixed CSV file are parsed
+type MixedSchema struct {
+	// Types maps column name to variable type. Columns not listed are ignored.
+	Types map[string]models.VariableType
+
+	// States lists the ordered category labels for discrete columns. A label's
+	// position is its state index. Discrete columns without declared states
+	// are filled in by LoadMixedCSV.
+	States map[string][]string
+
+	// MissingValues are the tokens treated as missing. Defaults to "" and "NA".
+	MissingValues []string
+}
+
+// NewMixedSchema creates a schema with the given column types
+func NewMixedSchema(types map[string]models.VariableType) *MixedSchema {
+	return &MixedSchema{
+		Types:  types,
+		States: make(map[string][]string),
+	}
+}
+
+// missingTokens returns the configured missing-value tokens or the defaults
+func (s *MixedSchema) missingTokens() []string {
+	if len(s.MissingValues) == 0 {
+		return []string{"", "NA"}
+	}
+	return s.MissingValues
+}
+
+// checkColumns verifies that every schema column is present in the header
+func (s *MixedSchema) checkColumns(header []string) error {
+	for col := range s.Types {
+		found := false
+		for _, h := range header {
+			if h == col {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("column %s not found", col)
+		}
+	}
+	return nil
+}
+
+// isMissing reports whether a token should be treated as a missing value
+func (s *MixedSchema) isMissing(value string) bool {
+	value = strings.TrimSpace(value)
+	for _, t := range s.missingTokens() {
+		if value == t {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadMixedCSV loads a CSV file with discrete and continuous columns into samples.
+// Continuous columns are parsed as floats. Discrete columns are mapped to state
+// indices using the schema's declared states; when none are declared, integer
+// columns are used as-is and other columns get their labels in sorted order.
+// Missing values are left out of the sample maps.
+func LoadMixedCSV(filename string, schema *MixedSchema) ([]models.Sample, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("schema cannot be nil")
+	}
+
+	header, records, err := readCSV(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.checkColumns(header); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return parseMixedRecords(header, records, schema)
+}
+
+// LoadCSVAuto loads a CSV file of integer, float and text columns into a
+// MixedDataFrame, inferring each column's type from its values: columns of
+// non-negative integers are discrete with the integers as states, other
+// numeric columns are continuous, and text columns are discrete with their
+// labels as states in sorted order. The frame's Schema reports the result.
+// "" and "NA" are missing values.
+func LoadCSVAuto(filename string) (*MixedDataFrame, error) {
+	header, records, err := readCSV(filename)
+	if err != nil {
+		return nil, err
+	}
+	schema := NewMixedSchema(make(map[string]models.VariableType, len(header)))
+	schema.inferTypes(header, records)
+	samples, err := parseMixedRecords(header, records, schema)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return newMixedDataFrame(schema, samples), nil
+}
+
+// InferMixedSchema reads a CSV file and returns the schema LoadCSVAuto
+// would infer for it, with the state labels of its discrete columns
+func InferMixedSchema(filename string) (*MixedSchema, error) {
+	df, err := LoadCSVAuto(filename)
+	if err != nil {
+		return nil, err
+	}
+	return df.Schema(), nil
+}
+
+// readCSV reads the header and records of a CSV file
+func readCSV(filename string) ([]string, [][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, records, nil
+}
+
+// inferTypes types every header column the schema does not already type
+func (s *MixedSchema) inferTypes(header []string, records [][]string) {
+	for i, col := range header {
+		if _, ok := s.Types[col]; !ok {
+			s.Types[col] = s.inferType(records, i)
+		}
+	}
+}
+
+// inferType returns Discrete for columns of non-negative integer codes or
+// text, and Continuous for other numeric columns
+func (s *MixedSchema) inferType(records [][]string, col int) models.VariableType {
+	codes := true
+	for _, record := range records {
+		if col >= len(record) || s.isMissing(record[col]) {
+			continue
+		}
+		value := strings.TrimSpace(record[col])
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return models.Discrete
+		}
+		if n, err := strconv.Atoi(value); err != nil || n < 0 || strconv.Itoa(n) != value {
+			codes = false
+		}
+	}
+	if codes {
+		return models.Discrete
+	}
+	return models.Continuous
+}
+
+// parseMixedRecords converts string-valued records into samples according to the schema
+func parseMixedRecords(header []string, records [][]string, schema *MixedSchema) ([]models.Sample, error) {
+	if schema.States == nil {
+		schema.States = make(map[string][]string)
+	}
+	for i, col := range header {
+		if schema.Types[col] != models.Discrete {
+			continue
+		}
+		if _, ok := schema.States[col]; !ok {
+			schema.States[col] = schema.inferStates(records, i)
+		}
+	}
+
+	parser := newRecordParser(header, schema)
+	samples := make([]models.Sample, 0, len(records))
+	for r, record := range records {
+		sample, err := parser.parse(record, r+1)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+// recordParser converts single records into samples
+type recordParser struct {
+	header     []string
+	schema     *MixedSchema
+	stateIndex map[string]map[string]int // label -> state index per declared discrete column
+}
+
+func newRecordParser(header []string, schema *MixedSchema) *recordParser {
+	// Build label -> state index lookup for each discrete column with declared states
+	stateIndex := make(map[string]map[string]int)
+	for _, col := range header {
+		states, ok := schema.States[col]
+		if schema.Types[col] != models.Discrete || !ok {
+			continue
+		}
+		stateIndex[col] = make(map[string]int)
+		for idx, label := range states {
+			stateIndex[col][label] = idx
+		}
+	}
+
+	return &recordParser{header: header, schema: schema, stateIndex: stateIndex}
+}
+
+// parse converts a record; discrete columns without declared states must hold
+// non-negative integer codes
+func (p *recordParser) parse(record []string, row int) (models.Sample, error) {
+	sample := models.Sample{
+		Discrete:   make(map[string]int),
+		Continuous: make(map[string]float64),
+	}
+
+	for i, value := range record {
+		if i >= len(p.header) {
+			break
+		}
+		col := p.header[i]
+		vtype, ok := p.schema.Types[col]
+		if !ok || p.schema.isMissing(value) {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch vtype {
+		case models.Discrete:
+			if index, declared := p.stateIndex[col]; declared {
+				idx, ok := index[value]
+				if !ok {
+					return sample, fmt.Errorf("unknown state %q in column %s (row %d)", value, col, row)
+				}
+				sample.Discrete[col] = idx
+				continue
+			}
+			intValue, err := strconv.Atoi(value)
+			if err != nil || intValue < 0 {
+				return sample, fmt.Errorf("invalid integer value %s in column %s (row %d)", value, col, row)
+			}
+			sample.Discrete[col] = intValue
+		case models.Continuous:
+			floatValue, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return sample, fmt.Errorf("invalid float value %s in column %s (row %d)", value, col, row)
+			}
+			sample.Continuous[col] = floatValue
+		default:
+			return sample, fmt.Errorf("unknown variable type %q for column %s", vtype, col)
+		}
+	}
+
+	return sample, nil
+}
+
+// inferStates derives the state labels of a discrete column from its values.
+// Non-negative integer columns keep their integer codes as state indices.
+func (s *MixedSchema) inferStates(records [][]string, col int) []string {
+	seen := make(map[string]bool)
+	allInts := true
+	maxInt := -1
+	for _, record := range records {
+		if col >= len(record) || s.isMissing(record[col]) {
+			continue
+		}
+		value := strings.TrimSpace(record[col])
+		seen[value] = true
+		if n, err := strconv.Atoi(value); err != nil || n < 0 || strconv.Itoa(n) != value {
+			allInts = false
+		} else if n > maxInt {
+			maxInt = n
+		}
+	}
+
+	if allInts {
+		states := make([]string, maxInt+1)
+		for i := range states {
+			states[i] = strconv.Itoa(i)
+		}
+		return states
+	}
+
+	states := make([]string, 0, len(seen))
+	for v := range seen {
+		states = append(states, v)
+	}
+	sort.Strings(states)
+	return states
+}
@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+func writeTempCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	return path
+}
+
+func TestLoadMixedCSV(t *testing.T) {
+	path := writeTempCSV(t, "ID,Season,Temperature,Rain\n"+
+		"1,Summer,85.5,0\n"+
+		"2,Winter,NA,1\n"+
+		"3,Summer,,\n"+
+		"4,Fall,55,1\n")
+
+	schema := NewMixedSchema(map[string]models.VariableType{
+		"Season":      models.Discrete,
+		"Temperature": models.Continuous,
+		"Rain":        models.Discrete,
+	})
+	schema.States["Season"] = []string{"Winter", "Spring", "Summer", "Fall"}
+
+	samples, err := LoadMixedCSV(path, schema)
+	if err != nil {
+		t.Fatalf("LoadMixedCSV failed: %v", err)
+	}
+
+	if len(samples) != 4 {
+		t.Fatalf("Expected 4 samples, got %d", len(samples))
+	}
+
+	if samples[0].Discrete["Season"] != 2 || samples[3].Discrete["Season"] != 3 {
+		t.Errorf("Unexpected season states: %v, %v", samples[0].Discrete, samples[3].Discrete)
+	}
+	if math.Abs(samples[0].Continuous["Temperature"]-85.5) > 1e-9 {
+		t.Errorf("Expected temperature 85.5, got %f", samples[0].Continuous["Temperature"])
+	}
+	if _, ok := samples[1].Continuous["Temperature"]; ok {
+		t.Error("NA temperature should be missing")
+	}
+	if _, ok := samples[2].Discrete["Rain"]; ok {
+		t.Error("Empty rain value should be missing")
+	}
+	if _, ok := samples[0].Discrete["ID"]; ok {
+		t.Error("Columns not in the schema should be ignored")
+	}
+	if len(schema.States["Rain"]) != 2 {
+		t.Errorf("Expected 2 inferred states for Rain, got %v", schema.States["Rain"])
+	}
+}
+
+func TestLoadMixedCSVUnknownState(t *testing.T) {
+	path := writeTempCSV(t, "Season\nSummer\nMonsoon\n")
+
+	schema := NewMixedSchema(map[string]models.VariableType{"Season": models.Discrete})
+	schema.States["Season"] = []string{"Winter", "Summer"}
+
+	if _, err := LoadMixedCSV(path, schema); err == nil {
+		t.Error("Expected error for undeclared state")
+	}
+}
+
+func TestCSVScannerChunks(t *testing.T) {
+	path := writeTempCSV(t, "A,B\n0,1\n1,1\n1,0\n0,0\n1,2\n")
+
+	scanner, err := NewCSVScanner(path, nil)
+	if err != nil {
+		t.Fatalf("NewCSVScanner failed: %v", err)
+	}
+	defer func() { _ = scanner.Close() }()
+
+	sizes := []int{}
+	total := 0
+	for {
+		chunk, err := scanner.NextChunk(2)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextChunk failed: %v", err)
+		}
+		sizes = append(sizes, len(chunk))
+		for _, s := range chunk {
+			total += s.Discrete["B"]
+		}
+	}
+
+	if len(sizes) != 3 || sizes[2] != 1 {
+		t.Errorf("Expected chunks of 2, 2, 1, got %v", sizes)
+	}
+	if total != 4 {
+		t.Errorf("Expected sum of B = 4, got %d", total)
+	}
+}
+
+func TestMixedDataFrameRoundTrip(t *testing.T) {
+	samples := []models.Sample{
+		{Discrete: map[string]int{"A": 1, "B": 12}, Continuous: map[string]float64{"X": 0.1, "Y": -3e-9}},
+		{Discrete: map[string]int{"A": 0}, Continuous: map[string]float64{"X": math.Pi}},
+		{Discrete: map[string]int{"B": 3}, Continuous: map[string]float64{"Y": 1e300}},
+	}
+	df, err := DataFrameFromMixedSamples(samples)
+	if err != nil {
+		t.Fatalf("DataFrameFromMixedSamples failed: %v", err)
+	}
+	if !reflect.DeepEqual(df.Columns, []string{"A", "B", "X", "Y"}) || df.Types["B"] != models.Discrete || df.Types["Y"] != models.Continuous {
+		t.Fatalf("Unexpected columns %v with types %v", df.Columns, df.Types)
+	}
+
+	path := filepath.Join(t.TempDir(), "mixed.csv")
+	if err := df.SaveCSV(path); err != nil {
+		t.Fatalf("SaveCSV failed: %v", err)
+	}
+	loaded, err := LoadMixedDataFrame(path, df.Schema())
+	if err != nil {
+		t.Fatalf("LoadMixedDataFrame failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Columns, df.Columns) || !reflect.DeepEqual(loaded.Types, df.Types) {
+		t.Errorf("Loaded columns %v with types %v, want %v with %v", loaded.Columns, loaded.Types, df.Columns, df.Types)
+	}
+	if !reflect.DeepEqual(loaded.ToSamples(), samples) {
+		t.Errorf("Round trip changed the samples:\n got %v\nwant %v", loaded.ToSamples(), samples)
+	}
+
+	conflicting := []models.Sample{{Discrete: map[string]int{"A": 0}}, {Continuous: map[string]float64{"A": 0.5}}}
+	if _, err := DataFrameFromMixedSamples(conflicting); err == nil {
+		t.Error("Expected an error for a column of both types")
+	}
+}
+
+func TestLoadCSVAuto(t *testing.T) {
+	path := writeTempCSV(t, "Count,Temp,Season,Delta,Empty\n"+
+		"2,21.5,Summer,-1,\n"+
+		"0,NA,Winter,3,NA\n"+
+		"5,7,Summer,0,\n")
+
+	if _, err := LoadCSV(path); err == nil {
+		t.Fatal("Expected LoadCSV to reject float and text columns")
+	}
+	df, err := LoadCSVAuto(path)
+	if err != nil {
+		t.Fatalf("LoadCSVAuto failed: %v", err)
+	}
+	want := map[string]models.VariableType{
+		"Count": models.Discrete, "Temp": models.Continuous, "Season": models.Discrete,
+		"Delta": models.Continuous, "Empty": models.Discrete,
+	}
+	schema := df.Schema()
+	if !reflect.DeepEqual(schema.Types, want) {
+		t.Errorf("Inferred types %v, want %v", schema.Types, want)
+	}
+	if !reflect.DeepEqual(schema.States["Season"], []string{"Summer", "Winter"}) {
+		t.Errorf("Season states = %v, want [Summer Winter]", schema.States["Season"])
+	}
+	if df.Data[1].Discrete["Season"] != 1 || df.Data[2].Discrete["Count"] != 5 || df.Data[0].Continuous["Delta"] != -1 {
+		t.Errorf("Unexpected samples %v", df.Data)
+	}
+	if _, ok := df.Data[1].Continuous["Temp"]; ok {
+		t.Error("Expected the missing temperature to be left out")
+	}
+
+	saved := filepath.Join(t.TempDir(), "saved.csv")
+	if err := df.SaveCSV(saved); err != nil {
+		t.Fatalf("SaveCSV failed: %v", err)
+	}
+	inferred, err := InferMixedSchema(saved)
+	if err != nil {
+		t.Fatalf("InferMixedSchema failed: %v", err)
+	}
+	if !reflect.DeepEqual(inferred.Types, want) {
+		t.Errorf("Saved file inferred as %v, want %v", inferred.Types, want)
+	}
+	loaded, err := LoadMixedDataFrame(saved, schema)
+	if err != nil {
+		t.Fatalf("LoadMixedDataFrame failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.ToSamples(), df.ToSamples()) {
+		t.Errorf("Round trip changed the samples:\n got %v\nwant %v", loaded.ToSamples(), df.ToSamples())
+	}
+}
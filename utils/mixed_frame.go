@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// missingToken marks missing values in saved CSV files
+const missingToken = "NA"
+
+// MixedDataFrame is a data frame of discrete and continuous columns, such as
+// the output of SimulateMixed. A variable missing from a row is a missing
+// value.
+type MixedDataFrame struct {
+	Columns []string
+	Types   map[string]models.VariableType
+	States  map[string][]string // Labels of discrete columns by state index, where known
+	Data    []models.Sample
+}
+
+// DataFrameFromMixedSamples creates a MixedDataFrame from samples, typing
+// each column by whether its values are discrete or continuous. Columns are
+// sorted; a variable that is discrete in one sample and continuous in
+// another is an error.
+func DataFrameFromMixedSamples(samples []models.Sample) (*MixedDataFrame, error) {
+	types := make(map[string]models.VariableType)
+	for i, sample := range samples {
+		for v := range sample.Discrete {
+			if types[v] == models.Continuous {
+				return nil, fmt.Errorf("column %s is both discrete and continuous (row %d)", v, i+1)
+			}
+			types[v] = models.Discrete
+		}
+		for v := range sample.Continuous {
+			if types[v] == models.Discrete {
+				return nil, fmt.Errorf("column %s is both discrete and continuous (row %d)", v, i+1)
+			}
+			types[v] = models.Continuous
+		}
+	}
+
+	columns := make([]string, 0, len(types))
+	for v := range types {
+		columns = append(columns, v)
+	}
+	sort.Strings(columns)
+	return &MixedDataFrame{Columns: columns, Types: types, States: make(map[string][]string), Data: samples}, nil
+}
+
+// LoadMixedDataFrame loads a mixed CSV file as LoadMixedCSV does, keeping
+// the schema's column types and state labels
+func LoadMixedDataFrame(filename string, schema *MixedSchema) (*MixedDataFrame, error) {
+	samples, err := LoadMixedCSV(filename, schema)
+	if err != nil {
+		return nil, err
+	}
+	return newMixedDataFrame(schema, samples), nil
+}
+
+// newMixedDataFrame wraps samples parsed with schema
+func newMixedDataFrame(schema *MixedSchema, samples []models.Sample) *MixedDataFrame {
+	df := &MixedDataFrame{
+		Types:  make(map[string]models.VariableType, len(schema.Types)),
+		States: make(map[string][]string, len(schema.States)),
+		Data:   samples,
+	}
+	for col, vtype := range schema.Types {
+		df.Columns = append(df.Columns, col)
+		df.Types[col] = vtype
+		if labels, ok := schema.States[col]; ok && vtype == models.Discrete {
+			df.States[col] = labels
+		}
+	}
+	sort.Strings(df.Columns)
+	return df
+}
+
+// Len returns the number of rows
+func (df *MixedDataFrame) Len() int {
+	return len(df.Data)
+}
+
+// ToSamples converts a MixedDataFrame to sample format
+func (df *MixedDataFrame) ToSamples() []models.Sample {
+	return df.Data
+}
+
+// Schema returns the schema that reads the frame's CSV file back with its
+// column types and state labels
+func (df *MixedDataFrame) Schema() *MixedSchema {
+	schema := NewMixedSchema(make(map[string]models.VariableType, len(df.Types)))
+	for col, vtype := range df.Types {
+		schema.Types[col] = vtype
+	}
+	for col, labels := range df.States {
+		schema.States[col] = append([]string(nil), labels...)
+	}
+	return schema
+}
+
+// SaveCSV saves a MixedDataFrame to a CSV file: discrete columns as their
+// state labels or, without labels, state indices, continuous ones in the
+// shortest form that parses back exactly, and missing values as NA
+func (df *MixedDataFrame) SaveCSV(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	writer := csv.NewWriter(file)
+
+	// Write header
+	if err := writer.Write(df.Columns); err != nil {
+		return err
+	}
+
+	// Write data
+	record := make([]string, len(df.Columns))
+	for _, row := range df.Data {
+		for i, col := range df.Columns {
+			record[i] = missingToken
+			if df.Types[col] == models.Discrete {
+				if state, ok := row.Discrete[col]; ok {
+					record[i] = strconv.Itoa(state)
+					if labels := df.States[col]; state >= 0 && state < len(labels) {
+						record[i] = labels[state]
+					}
+				}
+			} else if x, ok := row.Continuous[col]; ok {
+				record[i] = strconv.FormatFloat(x, 'g', -1, 64)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// LoadModelJSON reads a network written by SaveModelJSON
+func LoadModelJSON(filename string) (*models.BayesianNetwork, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	bn := &models.BayesianNetwork{}
+	if err := json.Unmarshal(data, bn); err != nil {
+		return nil, fmt.Errorf("failed to parse model: %w", err)
+	}
+	return bn, nil
+}
+
+// SaveModelJSON writes a network, including its CPDs, to a JSON file
+func SaveModelJSON(filename string, bn *models.BayesianNetwork) error {
+	data, err := json.MarshalIndent(bn, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
+
+// LoadModel reads a network, choosing the format from the file extension:
+// .bif for the Bayesian Interchange Format, .dne for Netica, .json for
+// bngo's JSON format
+func LoadModel(filename string) (*models.BayesianNetwork, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".bif":
+		return LoadBIF(filename)
+	case ".dne":
+		return LoadDNE(filename)
+	case ".json":
+		return LoadModelJSON(filename)
+	default:
+		return nil, fmt.Errorf("unknown model format for %s, expected .bif, .dne or .json", filename)
+	}
+}
+
+// SaveModel writes a network as .bif or .json, chosen by the file extension
+// as for LoadModel. DNE files are read only.
+func SaveModel(filename string, bn *models.BayesianNetwork) error {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".bif":
+		return SaveBIF(filename, bn)
+	case ".json":
+		return SaveModelJSON(filename, bn)
+	default:
+		return fmt.Errorf("unknown model format for %s, expected .bif or .json", filename)
+	}
+}
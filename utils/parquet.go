@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/JohnPierman/bngo/models"
+	"github.com/parquet-go/parquet-go"
+)
+
+// LoadParquet loads a Parquet file of integer-coded columns into a DataFrame.
+// Boolean columns are read as 0/1. Null values are left out of the rows.
+func LoadParquet(filename string) (*DataFrame, error) {
+	header, records, err := readParquetRecords(filename, "")
+	if err != nil {
+		return nil, err
+	}
+
+	df := NewDataFrame(header)
+	for r, record := range records {
+		row := make(map[string]int)
+		for i, value := range record {
+			if value == "" {
+				continue
+			}
+			intValue, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer value %s in column %s (row %d)", value, header[i], r+1)
+			}
+			row[header[i]] = intValue
+		}
+		df.AddRow(row)
+	}
+
+	return df, nil
+}
+
+// LoadMixedParquet loads a Parquet file with discrete and continuous columns
+// into samples. Columns are interpreted exactly as in LoadMixedCSV; Parquet
+// nulls are treated as missing values.
+func LoadMixedParquet(filename string, schema *MixedSchema) ([]models.Sample, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("schema cannot be nil")
+	}
+
+	header, records, err := readParquetRecords(filename, schema.missingTokens()[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.checkColumns(header); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return parseMixedRecords(header, records, schema)
+}
+
+// readParquetRecords reads a flat Parquet file into string-valued records,
+// writing nullToken for null values
+func readParquetRecords(filename string, nullToken string) ([]string, [][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pf, err := parquet.OpenFile(file, info.Size())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open parquet file: %w", err)
+	}
+
+	columns := pf.Schema().Columns()
+	header := make([]string, len(columns))
+	for i, path := range columns {
+		if len(path) != 1 {
+			return nil, nil, fmt.Errorf("nested column %v is not supported", path)
+		}
+		header[i] = path[0]
+	}
+
+	reader := parquet.NewReader(pf)
+	defer func() { _ = reader.Close() }()
+
+	records := make([][]string, 0, pf.NumRows())
+	rows := make([]parquet.Row, 128)
+	for {
+		n, err := reader.ReadRows(rows)
+		for _, row := range rows[:n] {
+			record := make([]string, len(header))
+			for i := range record {
+				record[i] = nullToken
+			}
+			for _, value := range row {
+				col := value.Column()
+				if col < 0 || col >= len(record) || value.IsNull() {
+					continue
+				}
+				token, err := parquetValueString(value)
+				if err != nil {
+					return nil, nil, fmt.Errorf("column %s: %w", header[col], err)
+				}
+				record[col] = token
+			}
+			records = append(records, record)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return header, records, nil
+}
+
+// parquetValueString formats a Parquet leaf value as a token
+func parquetValueString(value parquet.Value) (string, error) {
+	switch value.Kind() {
+	case parquet.Boolean:
+		if value.Boolean() {
+			return "1", nil
+		}
+		return "0", nil
+	case parquet.Int32:
+		return strconv.FormatInt(int64(value.Int32()), 10), nil
+	case parquet.Int64:
+		return strconv.FormatInt(value.Int64(), 10), nil
+	case parquet.Float:
+		return strconv.FormatFloat(float64(value.Float()), 'g', -1, 32), nil
+	case parquet.Double:
+		return strconv.FormatFloat(value.Double(), 'g', -1, 64), nil
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		return string(value.ByteArray()), nil
+	default:
+		return "", fmt.Errorf("unsupported parquet type %v", value.Kind())
+	}
+}
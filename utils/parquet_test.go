@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/JohnPierman/bngo/models"
+	"github.com/parquet-go/parquet-go"
+)
+
+type parquetTestRow struct {
+	Season      string   `parquet:"Season"`
+	Temperature *float64 `parquet:"Temperature,optional"`
+	Rain        int64    `parquet:"Rain"`
+}
+
+func TestLoadMixedParquet(t *testing.T) {
+	temp := 85.5
+	rows := []parquetTestRow{
+		{Season: "Summer", Temperature: &temp, Rain: 0},
+		{Season: "Winter", Temperature: nil, Rain: 1},
+	}
+
+	path := filepath.Join(t.TempDir(), "data.parquet")
+	if err := parquet.WriteFile(path, rows); err != nil {
+		t.Fatalf("Failed to write parquet file: %v", err)
+	}
+
+	schema := NewMixedSchema(map[string]models.VariableType{
+		"Season":      models.Discrete,
+		"Temperature": models.Continuous,
+		"Rain":        models.Discrete,
+	})
+	schema.States["Season"] = []string{"Winter", "Summer"}
+
+	samples, err := LoadMixedParquet(path, schema)
+	if err != nil {
+		t.Fatalf("LoadMixedParquet failed: %v", err)
+	}
+
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+	if samples[0].Discrete["Season"] != 1 || samples[1].Discrete["Rain"] != 1 {
+		t.Errorf("Unexpected discrete values: %v, %v", samples[0].Discrete, samples[1].Discrete)
+	}
+	if math.Abs(samples[0].Continuous["Temperature"]-85.5) > 1e-9 {
+		t.Errorf("Expected temperature 85.5, got %f", samples[0].Continuous["Temperature"])
+	}
+	if _, ok := samples[1].Continuous["Temperature"]; ok {
+		t.Error("Null temperature should be missing")
+	}
+
+	df, err := LoadParquet(path)
+	if err == nil {
+		t.Errorf("Expected error loading string column as integers, got %d rows", df.Len())
+	}
+}
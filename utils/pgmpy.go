@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// pgmpyModel mirrors the constructor arguments of pgmpy's
+// DiscreteBayesianNetwork and TabularCPD, so a model can be rebuilt with
+//
+//	model = DiscreteBayesianNetwork(d["edges"])
+//	model.add_nodes_from(d["nodes"])
+//	model.add_cpds(*(TabularCPD(**c) for c in d["cpds"]))
+type pgmpyModel struct {
+	Model string      `json:"model"`
+	Nodes []string    `json:"nodes"`
+	Edges [][2]string `json:"edges"`
+	CPDs  []pgmpyCPD  `json:"cpds"`
+}
+
+// pgmpyCPD is a TabularCPD: values has one row per state of the variable and
+// one column per evidence configuration, the last evidence variable varying
+// fastest
+type pgmpyCPD struct {
+	Variable     string           `json:"variable"`
+	VariableCard int              `json:"variable_card"`
+	Values       [][]float64      `json:"values"`
+	Evidence     []string         `json:"evidence,omitempty"`
+	EvidenceCard []int            `json:"evidence_card,omitempty"`
+	StateNames   map[string][]any `json:"state_names"`
+}
+
+// SavePgmpyJSON writes a discrete network to a file in pgmpy's layout, see
+// WritePgmpyJSON
+func SavePgmpyJSON(filename string, bn *models.BayesianNetwork) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := WritePgmpyJSON(file, bn); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WritePgmpyJSON writes a discrete network as JSON whose CPD objects are the
+// keyword arguments of pgmpy's TabularCPD, including state names. Unlabeled
+// states are written as their indices, as pgmpy names them by default, and
+// custom CPDs as tables.
+func WritePgmpyJSON(w io.Writer, bn *models.BayesianNetwork) error {
+	for _, node := range bn.Nodes() {
+		if bn.IsContinuous(node) {
+			return fmt.Errorf("pgmpy export of %s: %w", node, models.ErrContinuousVariables)
+		}
+	}
+	if err := bn.CheckModel(); err != nil {
+		return err
+	}
+
+	m := pgmpyModel{
+		Model: "DiscreteBayesianNetwork",
+		Nodes: bn.Nodes(),
+		Edges: bn.Edges(),
+	}
+	if m.Edges == nil {
+		m.Edges = [][2]string{}
+	}
+
+	states := make(map[string][]any, len(m.Nodes))
+	for _, node := range m.Nodes {
+		labels := bn.StateNames(node)
+		names := make([]any, bn.Cardinality[node])
+		for i := range names {
+			if labels != nil {
+				names[i] = labels[i]
+			} else {
+				names[i] = i
+			}
+		}
+		states[node] = names
+	}
+
+	for _, node := range m.Nodes {
+		cpd, err := bn.AsTabularCPD(node)
+		if err != nil {
+			return err
+		}
+		card := cpd.GetCardinality()
+		c := pgmpyCPD{
+			Variable:     node,
+			VariableCard: card,
+			Values:       make([][]float64, card),
+			Evidence:     cpd.Evidence,
+			StateNames:   map[string][]any{node: states[node]},
+		}
+		// bngo stores one row per evidence configuration; pgmpy one per state
+		for s := range c.Values {
+			c.Values[s] = make([]float64, len(cpd.Values))
+			for r, row := range cpd.Values {
+				c.Values[s][r] = row[s]
+			}
+		}
+		for _, e := range cpd.Evidence {
+			c.EvidenceCard = append(c.EvidenceCard, cpd.EvidenceCard[e])
+			c.StateNames[e] = states[e]
+		}
+		m.CPDs = append(m.CPDs, c)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
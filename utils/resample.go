@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// Bootstrap draws n rows from data uniformly with replacement.
+// If n <= 0, a resample of the same size as data is drawn. Rows are not
+// copied: for map or pointer rows the resample shares them with data, and a
+// row drawn twice appears twice as the same value, so copy rows before
+// changing them.
+func Bootstrap[T any](data []T, n int, seed int64) []T {
+	return BootstrapWithRand(data, n, rand.New(rand.NewSource(seed)))
+}
+
+// BootstrapWithRand is Bootstrap drawing from a caller-owned generator
+func BootstrapWithRand[T any](data []T, n int, r *rand.Rand) []T {
+	if n <= 0 {
+		n = len(data)
+	}
+	if len(data) == 0 {
+		return []T{}
+	}
+
+	resample := make([]T, n)
+	for i := range resample {
+		resample[i] = data[r.Intn(len(data))]
+	}
+	return resample
+}
+
+// StratifiedSample resamples discrete data within each state of byVar.
+// fractions gives the target size of each stratum relative to its current
+// size: 0.5 keeps a random half, 2.0 doubles it by drawing extra rows with
+// replacement. States without an entry keep fraction 1. Rows with no value
+// for byVar are dropped. Like Bootstrap, the result shares its rows with
+// data.
+func StratifiedSample(data []map[string]int, byVar string, fractions map[int]float64,
+	seed int64) ([]map[string]int, error) {
+	return StratifiedSampleWithRand(data, byVar, fractions, rand.New(rand.NewSource(seed)))
+}
+
+// StratifiedSampleWithRand is StratifiedSample drawing from a caller-owned generator
+func StratifiedSampleWithRand(data []map[string]int, byVar string, fractions map[int]float64,
+	r *rand.Rand) ([]map[string]int, error) {
+	return stratifiedSample(data, func(row map[string]int) (int, bool) {
+		v, ok := row[byVar]
+		return v, ok
+	}, fractions, r)
+}
+
+// StratifiedSampleMixed is StratifiedSample for mixed samples, stratifying on
+// the discrete variable byVar
+func StratifiedSampleMixed(data []models.Sample, byVar string, fractions map[int]float64,
+	seed int64) ([]models.Sample, error) {
+	return StratifiedSampleMixedWithRand(data, byVar, fractions, rand.New(rand.NewSource(seed)))
+}
+
+// StratifiedSampleMixedWithRand is StratifiedSampleMixed drawing from a
+// caller-owned generator
+func StratifiedSampleMixedWithRand(data []models.Sample, byVar string, fractions map[int]float64,
+	r *rand.Rand) ([]models.Sample, error) {
+	return stratifiedSample(data, func(s models.Sample) (int, bool) {
+		v, ok := s.Discrete[byVar]
+		return v, ok
+	}, fractions, r)
+}
+
+func stratifiedSample[T any](data []T, stateOf func(T) (int, bool), fractions map[int]float64,
+	r *rand.Rand) ([]T, error) {
+	for state, f := range fractions {
+		if f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("invalid fraction %f for state %d", f, state)
+		}
+	}
+
+	// Group row indices by stratum
+	strata := make(map[int][]int)
+	for i, row := range data {
+		if state, ok := stateOf(row); ok {
+			strata[state] = append(strata[state], i)
+		}
+	}
+
+	states := make([]int, 0, len(strata))
+	for state := range strata {
+		states = append(states, state)
+	}
+	sort.Ints(states)
+
+	kept := make([]int, 0, len(data))
+	extra := make([]int, 0)
+
+	for _, state := range states {
+		indices := strata[state]
+		fraction, ok := fractions[state]
+		if !ok {
+			fraction = 1.0
+		}
+		target := int(math.Round(fraction * float64(len(indices))))
+
+		if target <= len(indices) {
+			// Partial Fisher-Yates shuffle to pick target rows without replacement
+			perm := make([]int, len(indices))
+			copy(perm, indices)
+			for i := 0; i < target; i++ {
+				j := i + r.Intn(len(perm)-i)
+				perm[i], perm[j] = perm[j], perm[i]
+			}
+			kept = append(kept, perm[:target]...)
+			continue
+		}
+
+		// Oversample: keep every row, then draw the rest with replacement
+		kept = append(kept, indices...)
+		for i := len(indices); i < target; i++ {
+			extra = append(extra, indices[r.Intn(len(indices))])
+		}
+	}
+
+	// Preserve the original row order for the selected rows
+	sort.Ints(kept)
+
+	result := make([]T, 0, len(kept)+len(extra))
+	for _, i := range kept {
+		result = append(result, data[i])
+	}
+	for _, i := range extra {
+		result = append(result, data[i])
+	}
+	return result, nil
+}
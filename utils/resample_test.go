@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/JohnPierman/bngo/models"
+)
+
+// classRows returns 10 rows of class 0, 4 of class 1 and 2 rows without a
+// class, each tagged with its position in "ID"
+func classRows() []map[string]int {
+	var rows []map[string]int
+	for i := 0; i < 16; i++ {
+		row := map[string]int{"ID": i}
+		switch {
+		case i < 10:
+			row["Class"] = 0
+		case i < 14:
+			row["Class"] = 1
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func countClasses(rows []map[string]int) map[int]int {
+	counts := make(map[int]int)
+	for _, row := range rows {
+		counts[row["Class"]]++
+	}
+	return counts
+}
+
+func TestBootstrap(t *testing.T) {
+	data := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name string
+		n    int
+		want int
+	}{
+		{"same size", 0, 5},
+		{"negative size", -3, 5},
+		{"smaller", 3, 3},
+		{"larger", 12, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Bootstrap(data, tt.n, 7)
+			if len(got) != tt.want {
+				t.Fatalf("Expected %d rows, got %d", tt.want, len(got))
+			}
+			for _, v := range got {
+				if v < 1 || v > 5 {
+					t.Errorf("Row %d is not in the data", v)
+				}
+			}
+			if again := Bootstrap(data, tt.n, 7); !reflect.DeepEqual(got, again) {
+				t.Errorf("Same seed gave %v and %v", got, again)
+			}
+			if viaRand := BootstrapWithRand(data, tt.n, rand.New(rand.NewSource(7))); !reflect.DeepEqual(got, viaRand) {
+				t.Errorf("BootstrapWithRand gave %v, Bootstrap %v", viaRand, got)
+			}
+		})
+	}
+
+	if got := Bootstrap([]int{}, 4, 1); len(got) != 0 {
+		t.Errorf("Expected an empty resample of empty data, got %v", got)
+	}
+}
+
+func TestStratifiedSample(t *testing.T) {
+	tests := []struct {
+		name      string
+		fractions map[int]float64
+		want      map[int]int
+	}{
+		{"default keeps every row", nil, map[int]int{0: 10, 1: 4}},
+		{"undersample", map[int]float64{0: 0.5}, map[int]int{0: 5, 1: 4}},
+		{"oversample", map[int]float64{1: 2.5}, map[int]int{0: 10, 1: 10}},
+		{"drop a stratum", map[int]float64{0: 0, 1: 1}, map[int]int{1: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := classRows()
+			got, err := StratifiedSample(data, "Class", tt.fractions, 3)
+			if err != nil {
+				t.Fatalf("StratifiedSample failed: %v", err)
+			}
+			if counts := countClasses(got); !reflect.DeepEqual(counts, tt.want) {
+				t.Errorf("Expected class counts %v, got %v", tt.want, counts)
+			}
+			for _, row := range got {
+				if _, ok := row["Class"]; !ok {
+					t.Errorf("Row %d without a class was kept", row["ID"])
+				}
+			}
+			again, _ := StratifiedSample(data, "Class", tt.fractions, 3)
+			if !reflect.DeepEqual(got, again) {
+				t.Error("Same seed gave different samples")
+			}
+			viaRand, _ := StratifiedSampleWithRand(data, "Class", tt.fractions, rand.New(rand.NewSource(3)))
+			if !reflect.DeepEqual(got, viaRand) {
+				t.Error("StratifiedSampleWithRand differs from StratifiedSample")
+			}
+		})
+	}
+}
+
+func TestStratifiedSampleWithoutReplacement(t *testing.T) {
+	got, err := StratifiedSample(classRows(), "Class", map[int]float64{0: 0.7, 1: 0.5}, 5)
+	if err != nil {
+		t.Fatalf("StratifiedSample failed: %v", err)
+	}
+	seen := make(map[int]bool)
+	last := -1
+	for _, row := range got {
+		id := row["ID"]
+		if seen[id] {
+			t.Errorf("Row %d drawn twice when undersampling", id)
+		}
+		if id < last {
+			t.Errorf("Row %d after row %d breaks the original order", id, last)
+		}
+		seen[id], last = true, id
+	}
+}
+
+func TestStratifiedSampleInvalidFraction(t *testing.T) {
+	for _, f := range []float64{-0.5, math.NaN(), math.Inf(1)} {
+		if _, err := StratifiedSample(classRows(), "Class", map[int]float64{1: f}, 1); err == nil {
+			t.Errorf("Expected an error for fraction %v", f)
+		}
+	}
+}
+
+func TestStratifiedSampleMixed(t *testing.T) {
+	data := make([]models.Sample, 0, 12)
+	for i := 0; i < 12; i++ {
+		s := models.Sample{Discrete: map[string]int{}, Continuous: map[string]float64{"X": float64(i)}}
+		if i < 9 {
+			s.Discrete["Class"] = i % 3
+		}
+		data = append(data, s)
+	}
+
+	got, err := StratifiedSampleMixed(data, "Class", map[int]float64{0: 2, 2: 1.0 / 3}, 4)
+	if err != nil {
+		t.Fatalf("StratifiedSampleMixed failed: %v", err)
+	}
+	counts := make(map[int]int)
+	for _, s := range got {
+		state, ok := s.Discrete["Class"]
+		if !ok {
+			t.Fatalf("Sample %v without a class was kept", s.Continuous["X"])
+		}
+		counts[state]++
+	}
+	if want := map[int]int{0: 6, 1: 3, 2: 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("Expected class counts %v, got %v", want, counts)
+	}
+
+	again, _ := StratifiedSampleMixedWithRand(data, "Class", map[int]float64{0: 2, 2: 1.0 / 3}, rand.New(rand.NewSource(4)))
+	if !reflect.DeepEqual(got, again) {
+		t.Error("StratifiedSampleMixedWithRand differs from StratifiedSampleMixed")
+	}
+	if _, err := StratifiedSampleMixed(data, "Class", map[int]float64{0: -1}, 4); err == nil {
+		t.Error("Expected an error for a negative fraction")
+	}
+}